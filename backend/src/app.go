@@ -30,10 +30,9 @@ import (
 
 	// standard
 	"fmt"
-	"time"
-
-	// "os"
+	"os"
 	"sync"
+	"time"
 
 	// external
 
@@ -44,6 +43,7 @@ import (
 	"renderhive/ipfs"
 	"renderhive/jsonrpc"
 	"renderhive/logger"
+	"renderhive/metrics"
 	"renderhive/node"
 )
 
@@ -52,15 +52,22 @@ type AppManager struct {
 
 	// Managers
 	LoggerManager  *logger.PackageManager
+	MetricsManager *metrics.PackageManager
 	NodeManager    *node.PackageManager
 	HederaManager  *hedera.PackageManager
 	IPFSManager    *ipfs.PackageManager
 	JsonRpcManager *jsonrpc.PackageManager
 	CLIManager     *cli.PackageManager
 
+	// True, if the operator account was loaded from the environment
+	EnvironmentAccount bool
+
 	// Signaling channels
 	Quit chan bool
 	WG   sync.WaitGroup
+
+	// Shutdown
+	stopped sync.Once
 }
 
 // FUNCTIONS
@@ -87,6 +94,13 @@ func (service *AppManager) Init() error {
 	logger.Manager.Package["logger"].Debug().Msg("Initialized the logger manager.")
 	logger.Manager.Package["logger"].Debug().Msg(fmt.Sprintf(" [#] The log file is located at '%s'", logger.Manager.FileWriter.Name()))
 
+	// initialize the metrics manager
+	service.MetricsManager = &metrics.Manager
+	err = service.MetricsManager.Init()
+	if err != nil {
+		return err
+	}
+
 	// initialize the Hedera manager
 	service.HederaManager = &hedera.Manager
 	err = service.HederaManager.Init(hedera.NETWORK_TYPE_TESTNET)
@@ -102,7 +116,9 @@ func (service *AppManager) Init() error {
 	}
 
 	// initialize the node manager
+	// NOTE: The requester-only mode decides which parts of the node are initialized.
 	service.NodeManager = &node.Manager
+	service.NodeManager.RequesterOnly = cli.RequesterOnlyFlag(os.Args[1:])
 	err = service.NodeManager.Init()
 	if err != nil {
 		return err
@@ -122,6 +138,17 @@ func (service *AppManager) Init() error {
 		return err
 	}
 
+	// OPERATOR ACCOUNT
+	// *************************************************************************
+	// load the operator account from the environment (e.g., in a container)
+	// NOTE: Interactive sessions sign in with the passphrase instead.
+	if !service.CLIManager.Commands.MainFlags.Interactive {
+		err = service.loadEnvironmentAccount()
+		if err != nil {
+			return err
+		}
+	}
+
 	// HIVE CYCLE
 	// *************************************************************************
 	if RENDERHIVE_TESTNET_TOPIC_HIVE_CYCLE_SYNCHRONIZATION != "" {
@@ -151,6 +178,42 @@ func (service *AppManager) Init() error {
 						logger.Manager.Main.Error().Msg(fmt.Sprintf("Error in hive cycle synchronization: %v", err))
 					}
 
+					// release render jobs, which exceeded their render deadline
+					err = service.NodeManager.CheckRenderJobDeadlines()
+					if err != nil {
+						logger.Manager.Main.Error().Msg(fmt.Sprintf("Error in render job deadline check: %v", err))
+					}
+
+					// archive the closed render documents after their retention window
+					err = service.NodeManager.CheckRenderDocumentRetention()
+					if err != nil {
+						logger.Manager.Main.Error().Msg(fmt.Sprintf("Error in render document sweep: %v", err))
+					}
+
+					// apply the state changes of the confirmed transactions, which were signed by the wallet
+					err = service.NodeManager.CheckPendingTransactions()
+					if err != nil {
+						logger.Manager.Main.Error().Msg(fmt.Sprintf("Error in pending transaction check: %v", err))
+					}
+
+					// re-announce the active render offer of this node (in the background)
+					err = service.NodeManager.CheckRenderOfferAnnouncements()
+					if err != nil {
+						logger.Manager.Main.Error().Msg(fmt.Sprintf("Error in render offer announcement: %v", err))
+					}
+
+					// publish the balance changes of the operator account (in the background)
+					err = service.NodeManager.CheckOperatorBalance()
+					if err != nil {
+						logger.Manager.Main.Error().Msg(fmt.Sprintf("Error in operator balance check: %v", err))
+					}
+
+					// republish the IPNS records of this node before they expire (in the background)
+					err = service.IPFSManager.CheckIPNSRepublish()
+					if err != nil {
+						logger.Manager.Main.Error().Msg(fmt.Sprintf("Error in IPNS republish: %v", err))
+					}
+
 					// wait for 100 milliseconds to next check
 					time.Sleep(100 * time.Millisecond)
 
@@ -160,6 +223,23 @@ func (service *AppManager) Init() error {
 
 	}
 
+	// RENDER WORKER
+	// *************************************************************************
+	// claim and render the render jobs of the render hive queue in the background
+	// NOTE: The worker stops, when the node manager is canceled on shutdown.
+	if !service.NodeManager.IsRequesterOnly() {
+
+		// add call to wait group
+		service.WG.Add(1)
+
+		go func() {
+			service.NodeManager.RunRenderWorker(service.NodeManager.Context())
+			logger.Manager.Main.Debug().Msg("Stopped render worker loop.")
+			service.WG.Done()
+		}()
+
+	}
+
 	// STATE CHECKS
 	// *************************************************************************
 	// perform important state checks
@@ -177,25 +257,84 @@ func (service *AppManager) Init() error {
 	logger.Manager.Main.Info().Msg(fmt.Sprintf(" [#] Hive Cycle Application Topic: %s", RENDERHIVE_TESTNET_TOPIC_HIVE_CYCLE_APPLICATION))
 	logger.Manager.Main.Info().Msg(fmt.Sprintf(" [#] Hive Cycle Validation Topic: %s", RENDERHIVE_TESTNET_TOPIC_HIVE_CYCLE_VALIDATION))
 	// Render jobs
-	logger.Manager.Main.Info().Msg(fmt.Sprintf(" [#] Render Job Topic: %s", RENDERHIVE_TESTNET_RENDER_JOB_QUEUE))
+	logger.Manager.Main.Info().Msg(fmt.Sprintf(" [#] Render Job Topic: %s", node.Manager.JobQueueTopicID()))
+
+	return nil
+
+}
+
+// helper function to load the operator account from the environment variables
+func (service *AppManager) loadEnvironmentAccount() error {
+
+	credentials, err := hedera.ReadAccountCredentials(os.LookupEnv)
+
+	// the secrets must not be inherited by child processes (e.g., Blender)
+	hedera.ClearAccountSecrets(os.Unsetenv)
+	if err != nil {
+		return err
+	}
+	if credentials == nil {
+		return nil
+	}
+
+	// the account must be the account of the node configuration
+	account := service.NodeManager.Node.HederaAccount
+	if account.AccountID != "" && account.AccountID != credentials.AccountID {
+		return fmt.Errorf("The account %v of the environment is not the account %v of this node.", credentials.AccountID, account.AccountID)
+	}
+	if credentials.PublicKey == "" {
+		credentials.PublicKey = account.PublicKey
+	}
+
+	err = service.HederaManager.LoadAccountFromCredentials(credentials)
+	if err != nil {
+		return fmt.Errorf("Could not load the operator account from the environment: %w", err)
+	}
+	service.EnvironmentAccount = true
 
 	return nil
 
 }
 
 // Deinitialize the Renderhive Service App session
+// NOTE: The app is only deinitialized once (e.g., on an interrupt signal and at
+// the end of the main function).
 func (service *AppManager) DeInit() error {
 	var err error
 
+	service.stopped.Do(func() {
+		err = service._deInit()
+	})
+
+	return err
+
+}
+
+// helper function to deinitialize the Renderhive Service App session
+func (service *AppManager) _deInit() error {
+	var err error
+
 	// log event
 	logger.Manager.Main.Info().Msg("Stopping Renderhive service app ... ")
 
+	// stop the long-running operations of the node (e.g., benchmarks)
+	service.NodeManager.Cancel()
+
 	// send the Quit signal to all concurrent go functions
 	service.Quit <- true
 
 	// log event
 	logger.Manager.Main.Info().Msg("Waiting for background operations to terminate ... ")
-	service.WG.Wait()
+	done := make(chan struct{})
+	go func() {
+		service.WG.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(RENDERHIVE_CONFIG_SHUTDOWN_TIMEOUT):
+		logger.Manager.Main.Warn().Msg("Background operations did not terminate in time.")
+	}
 
 	// DEINITIALIZE INTERNAL MANAGERS
 	// *************************************************************************
@@ -212,6 +351,15 @@ func (service *AppManager) DeInit() error {
 		return err
 	}
 
+	// deinitialize the node manager
+	// NOTE: This withdraws the active render offer, so it needs to happen before
+	// the Hedera manager is deinitialized.
+	service.NodeManager.KeepOffersOnShutdown = service.CLIManager.Commands.MainFlags.KeepOffers
+	err = service.NodeManager.DeInit()
+	if err != nil {
+		return err
+	}
+
 	// deinitialize the IPFS manager
 	service.IPFSManager.DeInit()
 	if err != nil {
@@ -224,8 +372,8 @@ func (service *AppManager) DeInit() error {
 		return err
 	}
 
-	// deinitialize the node manager
-	err = service.NodeManager.DeInit()
+	// deinitialize the metrics manager
+	err = service.MetricsManager.DeInit()
 	if err != nil {
 		return err
 	}
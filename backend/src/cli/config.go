@@ -0,0 +1,391 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package cli
+
+/*
+
+This file contains the effective configuration of the service app.
+
+The settings of the app come from the command line flags, the optional JSON
+files in the configuration directory, and the built-in defaults. The 'config
+show' command lists the values, which are actually in effect, together with
+their source:
+
+  - "default": the built-in default value
+  - "file":    a value of a configuration file
+  - "env":     a value of an environment variable
+  - "flag":    a value of a command line flag
+
+The values of the configuration files are taken from the loaded settings of the
+packages and compared with the file content, so that a value is only reported
+from the file, if the file was actually applied. Secrets (e.g., access tokens)
+are redacted. The private key and the passphrase of the operator are never part
+of the configuration.
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	// external
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/hedera"
+	"renderhive/ipfs"
+	"renderhive/jsonrpc"
+	"renderhive/logger"
+	"renderhive/node"
+	. "renderhive/utility"
+)
+
+// sources of the configuration values
+const (
+	CONFIG_SOURCE_DEFAULT = "default"
+	CONFIG_SOURCE_FILE    = "file"
+	CONFIG_SOURCE_ENV     = "env"
+	CONFIG_SOURCE_FLAG    = "flag"
+)
+
+// redacted value of a secret
+const CONFIG_REDACTED = "<redacted>"
+
+// names of the settings, which contain secrets
+var configSecretNames = []string{"token", "secret", "password", "passphrase", "private_key"}
+
+// settings, whose values are all redacted (e.g., arbitrary environment variables
+// of Blender like API keys)
+var configSecretPrefixes = []string{"environment.variables."}
+
+// environment variables of the operator account, which are shown
+// NOTE: The private key and the passphrase are never shown.
+var configEnvironmentVariables = []string{RENDERHIVE_ENV_ACCOUNT_ID, RENDERHIVE_ENV_KEYSTORE, RENDERHIVE_ENV_PUBLIC_KEY}
+
+// Value of the effective configuration
+type ConfigValue struct {
+	Key    string      `json:"key"`    // name of the setting (e.g., "limits.max_memory")
+	Value  interface{} `json:"value"`  // effective value of the setting
+	Source string      `json:"source"` // source of the value ("default", "file", "env", or "flag")
+}
+
+// EFFECTIVE CONFIGURATION
+// #############################################################################
+// Get the effective configuration of the service app ordered by the key
+func (clim *PackageManager) EffectiveConfiguration() []ConfigValue {
+	var values []ConfigValue
+
+	// command line flags
+	if clim.Commands.Main != nil {
+		clim.Commands.Main.Flags().VisitAll(func(flag *pflag.Flag) {
+			source := CONFIG_SOURCE_DEFAULT
+			if flag.Changed {
+				source = CONFIG_SOURCE_FLAG
+			}
+			values = append(values, ConfigValue{Key: "flags." + flag.Name, Value: flag.Value.String(), Source: source})
+		})
+	}
+
+	// Hedera network
+	// NOTE: The network is selected by the app, there is no flag or environment
+	// variable to change it.
+	values = append(values,
+		ConfigValue{Key: "hedera.network", Value: hedera.Manager.NetworkName(), Source: CONFIG_SOURCE_DEFAULT},
+		ConfigValue{Key: "hedera.mirror_node", Value: hedera.Manager.MirrorNode.URL, Source: CONFIG_SOURCE_DEFAULT},
+		ConfigValue{Key: "app.data_directory", Value: GetAppDataPath(), Source: CONFIG_SOURCE_DEFAULT},
+		ConfigValue{Key: "app.config_directory", Value: RENDERHIVE_APP_DIRECTORY_CONFIG, Source: CONFIG_SOURCE_DEFAULT},
+	)
+
+	// environment variables
+	values = append(values, _environmentConfigValues()...)
+
+	// configuration files
+	accounting := hedera.AccountingSettings{}
+	if accounting.Read() != nil {
+		accounting = hedera.AccountingSettings{}
+	}
+	var rateLimits interface{} = jsonrpc.DefaultRateLimits()
+	if jsonrpc.Manager.RateLimiter != nil {
+		rateLimits = jsonrpc.Manager.RateLimiter.Limits
+	}
+	var pinning interface{} = ipfs.RemotePinningService{}
+	if ipfs.Manager.RemotePinning != nil {
+		pinning = *ipfs.Manager.RemotePinning
+	}
+	files := []struct {
+		name     string
+		settings interface{}
+	}{
+		{"accounting.json", accounting},
+		{"cors.json", jsonrpc.Manager.Cors},
+		{"environment.json", node.Manager.GetBlenderEnvironment()},
+		{"fees.json", hedera.Manager.Fees},
+		{"ipfs.json", ipfs.Manager.NodeConfig},
+		{"jobqueue.json", node.Manager.JobQueueSettings},
+		{"limits.json", node.Manager.GetRenderLimits()},
+		{"offers.json", node.Manager.GetRenderOfferAnnouncementSettings()},
+		{"pinning.json", pinning},
+		{"ranking.json", node.Manager.GetRankingWeights()},
+		{"ratelimits.json", rateLimits},
+		{"repository.json", node.Manager.RepositoryConfig},
+		{"restarts.json", node.Manager.GetBlenderRestartPolicy()},
+		{"scripts.json", node.Manager.GetTrustedScriptSettings()},
+	}
+	for _, file := range files {
+		values = append(values, _configFileValues(file.name, file.settings)...)
+	}
+
+	// redact the secrets
+	for i := range values {
+		if _isConfigSecret(values[i].Key) && values[i].Value != nil && values[i].Value != "" {
+			values[i].Value = CONFIG_REDACTED
+		}
+	}
+
+	sort.SliceStable(values, func(i, j int) bool {
+		return values[i].Key < values[j].Key
+	})
+
+	return values
+
+}
+
+// helper function to get the values of the environment variables
+func _environmentConfigValues() []ConfigValue {
+	var values []ConfigValue
+
+	for _, name := range configEnvironmentVariables {
+		for _, key := range []string{name, name + RENDERHIVE_ENV_FILE_SUFFIX} {
+			value, ok := os.LookupEnv(key)
+			if ok {
+				values = append(values, ConfigValue{Key: "env." + key, Value: value, Source: CONFIG_SOURCE_ENV})
+			}
+		}
+	}
+
+	return values
+
+}
+
+// helper function to get the values of the settings of a configuration file
+func _configFileValues(name string, settings interface{}) []ConfigValue {
+	var values []ConfigValue
+
+	// flatten the effective settings
+	effective := map[string]interface{}{}
+	data, err := json.Marshal(settings)
+	if err == nil {
+		var object interface{}
+		if json.Unmarshal(data, &object) == nil {
+			_flattenConfig("", object, effective)
+		}
+	}
+
+	// flatten the content of the configuration file (if any)
+	configured := map[string]interface{}{}
+	data, err = os.ReadFile(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, name))
+	if err == nil {
+		var object interface{}
+		if json.Unmarshal(data, &object) == nil {
+			_flattenConfig("", object, configured)
+		}
+	}
+
+	// a value is from the file, if the file sets the same value
+	prefix := strings.TrimSuffix(name, filepath.Ext(name))
+	for key, value := range effective {
+		source := CONFIG_SOURCE_DEFAULT
+		if fileValue, ok := configured[key]; ok && _sameConfigValue(fileValue, value) {
+			source = CONFIG_SOURCE_FILE
+		}
+		values = append(values, ConfigValue{Key: prefix + "." + key, Value: value, Source: source})
+	}
+
+	return values
+
+}
+
+// helper function to compare a value of a configuration file with an effective value
+func _sameConfigValue(fileValue interface{}, value interface{}) bool {
+
+	if reflect.DeepEqual(fileValue, value) {
+		return true
+	}
+
+	// durations are normalized by the settings (e.g., "30m" becomes "30m0s")
+	fileString, ok1 := fileValue.(string)
+	valueString, ok2 := value.(string)
+	if ok1 && ok2 {
+		fileDuration, err1 := time.ParseDuration(fileString)
+		valueDuration, err2 := time.ParseDuration(valueString)
+		return err1 == nil && err2 == nil && fileDuration == valueDuration
+	}
+
+	return false
+
+}
+
+// helper function to flatten nested JSON objects into dotted keys
+func _flattenConfig(prefix string, object interface{}, values map[string]interface{}) {
+
+	fields, ok := object.(map[string]interface{})
+	if !ok || (len(fields) == 0 && prefix != "") {
+		if prefix != "" {
+			values[prefix] = object
+		}
+		return
+	}
+
+	for key, value := range fields {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		_flattenConfig(key, value, values)
+	}
+
+}
+
+// helper function to check if a setting contains a secret
+func _isConfigSecret(key string) bool {
+
+	for _, prefix := range configSecretPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	parts := strings.Split(strings.ToLower(key), ".")
+	name := parts[len(parts)-1]
+	for _, secret := range configSecretNames {
+		if strings.Contains(name, secret) {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// COMMAND LINE INTERFACE - CONFIGURATION
+// #############################################################################
+// Create the CLI command to show the effective configuration
+func (clim *PackageManager) CreateCommandConfig() *cobra.Command {
+
+	// create a 'config' command
+	command := &cobra.Command{
+		Use:   "config",
+		Short: "Show the configuration of the service app",
+		Long:  "This command shows the configuration of the Renderhive Service App.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			return nil
+
+		},
+	}
+
+	// add the subcommands
+	command.AddCommand(clim.CreateCommandConfigShow())
+
+	return command
+
+}
+
+// Create the CLI command to print the effective configuration
+func (clim *PackageManager) CreateCommandConfigShow() *cobra.Command {
+
+	// flags for the 'show' command
+	var output string
+
+	// create a 'show' command
+	command := &cobra.Command{
+		Use:   "show",
+		Short: "Print the effective configuration",
+		Long:  "This command prints the configuration values, which are in effect, together with their source (default, file, env, or flag). Secrets are redacted and the private key and passphrase of the operator are never shown.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			values := clim.EffectiveConfiguration()
+
+			switch output {
+			case "json":
+
+				data, err := json.MarshalIndent(values, "", "  ")
+				if err != nil {
+
+					logger.Manager.Println("")
+					return err
+
+				}
+				logger.Manager.Resultln(string(data))
+
+			case "text":
+
+				logger.Manager.Println("")
+				logger.Manager.Println("Effective configuration:")
+				for _, value := range values {
+					logger.Manager.Resultf(" [#] %v = %v (%v)\n", value.Key, _formatConfigValue(value.Value), value.Source)
+				}
+				logger.Manager.Println("")
+
+			default:
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Invalid output format '%v' (expected 'text' or 'json').", output)
+
+			}
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().StringVarP(&output, "output", "o", "text", "The output format ('text' or 'json')")
+
+	return command
+
+}
+
+// helper function to format a configuration value for the text output
+func _formatConfigValue(value interface{}) string {
+
+	switch value.(type) {
+	case string:
+		return fmt.Sprintf("%q", value)
+	case []interface{}, map[string]interface{}:
+		data, err := json.Marshal(value)
+		if err == nil {
+			return string(data)
+		}
+	}
+
+	return fmt.Sprintf("%v", value)
+
+}
@@ -0,0 +1,68 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package cli
+
+import (
+	// standard
+	"testing"
+
+	// internal
+	. "renderhive/globals"
+)
+
+func TestIsConfigSecret(t *testing.T) {
+	cases := map[string]bool{
+		"pinning.access_token":                    true,
+		"environment.variables.API_KEY":           true,
+		"environment.variables.AWS_ACCESS_KEY_ID": true,
+		"environment.variables.PATH":              true,
+		"environment.scratch_directory":           false,
+		"environment.gpus":                        false,
+		"limits.max_memory":                       false,
+	}
+	for key, want := range cases {
+		if got := _isConfigSecret(key); got != want {
+			t.Errorf("_isConfigSecret(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestEffectiveConfigurationEnvironment(t *testing.T) {
+	t.Setenv(RENDERHIVE_ENV_ACCOUNT_ID, "0.0.1234")
+	t.Setenv(RENDERHIVE_ENV_PRIVATE_KEY, "302e0201")
+	t.Setenv(RENDERHIVE_ENV_PASSPHRASE+RENDERHIVE_ENV_FILE_SUFFIX, "/run/secrets/passphrase")
+
+	found := false
+	for _, value := range _environmentConfigValues() {
+		switch value.Key {
+		case "env." + RENDERHIVE_ENV_ACCOUNT_ID:
+			found = true
+			if value.Value != "0.0.1234" || value.Source != CONFIG_SOURCE_ENV {
+				t.Errorf("got %+v, want the account ID from the environment", value)
+			}
+		case "env." + RENDERHIVE_ENV_PRIVATE_KEY, "env." + RENDERHIVE_ENV_PASSPHRASE + RENDERHIVE_ENV_FILE_SUFFIX:
+			t.Errorf("secret variable %v is shown", value.Key)
+		}
+	}
+	if !found {
+		t.Error("the account ID of the environment is missing")
+	}
+}
@@ -0,0 +1,456 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package cli
+
+/*
+
+This file contains the diagnosis of the external dependencies of the service app.
+
+The node depends on external tools and resources, whose failures otherwise
+surface deep in the operations (e.g., when a render job starts). The 'doctor'
+command checks each of them and prints a report with a remediation hint for
+each failed check:
+
+  - the Blender binaries (installed versions and their build info)
+  - the Blender benchmark launcher
+  - the w3 CLI and its agent identity
+  - the writability of the app data and configuration directories
+  - the IPFS repository and the connection to the IPFS network
+  - the reachability of the Hedera network
+  - the presence of the operator key
+
+A check is either critical or not. A failed critical check fails the report
+(and the command exits with a nonzero status), while a failed non-critical
+check is only reported as a warning.
+
+*/
+
+import (
+
+	// standard
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+	"github.com/spf13/cobra"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/hedera"
+	"renderhive/ipfs"
+	"renderhive/logger"
+	"renderhive/node"
+	. "renderhive/utility"
+)
+
+// status of a diagnostic check
+const (
+	DOCTOR_STATUS_PASS = "PASS"
+	DOCTOR_STATUS_WARN = "WARN"
+	DOCTOR_STATUS_FAIL = "FAIL"
+)
+
+// Diagnostic check of an external dependency
+type DoctorCheck struct {
+	Name     string // Name of the checked dependency
+	Critical bool   // True, if the node cannot operate without the dependency
+	Status   string // DOCTOR_STATUS_PASS, DOCTOR_STATUS_WARN, or DOCTOR_STATUS_FAIL
+	Message  string // Result of the check (e.g., the found version or the error)
+	Hint     string // Remediation hint (empty, if the check passed)
+}
+
+// Report of all diagnostic checks
+type DoctorReport struct {
+	Checks []DoctorCheck
+}
+
+// DIAGNOSTIC REPORT
+// #############################################################################
+// Add the result of a check to the report
+// NOTE: A failed check is a failure, if it is critical, and a warning otherwise.
+func (report *DoctorReport) Add(name string, critical bool, message string, err error, hint string) {
+
+	check := DoctorCheck{Name: name, Critical: critical, Status: DOCTOR_STATUS_PASS, Message: message}
+	if err != nil {
+		check.Status = DOCTOR_STATUS_WARN
+		if critical {
+			check.Status = DOCTOR_STATUS_FAIL
+		}
+		check.Message = err.Error()
+		check.Hint = hint
+	}
+	report.Checks = append(report.Checks, check)
+
+}
+
+// Get the number of passed checks, warnings, and failures
+func (report *DoctorReport) Count() (int, int, int) {
+
+	passed, warnings, failures := 0, 0, 0
+	for _, check := range report.Checks {
+		switch check.Status {
+		case DOCTOR_STATUS_PASS:
+			passed++
+		case DOCTOR_STATUS_WARN:
+			warnings++
+		default:
+			failures++
+		}
+	}
+
+	return passed, warnings, failures
+
+}
+
+// Check if no critical check failed
+func (report *DoctorReport) Passed() bool {
+
+	_, _, failures := report.Count()
+	return failures == 0
+
+}
+
+// Run all diagnostic checks
+func (clim *PackageManager) Diagnose() *DoctorReport {
+
+	report := &DoctorReport{}
+
+	// external tools
+	message, err := _doctorBlender()
+	report.Add("Blender binaries", node.Manager.Node.RenderNode && !node.Manager.IsRequesterOnly(), message, err, "Install a supported Blender version with 'node blender versions install -v <version>' (see 'node blender supported').")
+	message, err = _doctorBenchmarkLauncher()
+	report.Add("Blender benchmark launcher", false, message, err, fmt.Sprintf("Download the benchmark launcher CLI from https://opendata.blender.org and place it at '%v'.", node.BenchmarkLauncherPath()))
+	message, err = _doctorW3()
+	report.Add("w3 CLI", true, message, err, "Install the w3 CLI (e.g., 'npm install -g @web3-storage/w3cli'), make sure it is in the PATH, and log in with 'w3 login <email>'.")
+
+	// local directories
+	message, err = _doctorWritable(GetAppDataPath())
+	report.Add("App data directory", true, message, err, "Make sure the user running the service app owns the app data directory.")
+	message, err = _doctorWritable(RENDERHIVE_APP_DIRECTORY_CONFIG)
+	report.Add("Configuration directory", true, message, err, "Start the service app from its installation directory or make the 'config' directory writable.")
+
+	// networks
+	message, err = _doctorIPFSRepository()
+	report.Add("IPFS repository", true, message, err, "Check the log for the IPFS start error. If another IPFS node uses the repository, stop it or remove a stale 'repo.lock' file.")
+	message, err = _doctorIPFSNetwork()
+	report.Add("IPFS network", false, message, err, "Check the internet connection and allow the IPFS swarm port (4001) in the firewall. Peers may take a minute to connect after the start.")
+	message, err = _doctorHedera()
+	report.Add("Hedera network", true, message, err, "Check the internet connection and the status of the Hedera network (https://status.hedera.com).")
+	message, err = _doctorOperatorKey()
+	report.Add("Operator key", true, message, err, fmt.Sprintf("Sign in with the passphrase, set the %v and %v environment variables, or create a keystore with 'hedera account create'.", RENDERHIVE_ENV_ACCOUNT_ID, RENDERHIVE_ENV_PRIVATE_KEY))
+
+	return report
+
+}
+
+// helper function to check the installed Blender versions
+func _doctorBlender() (string, error) {
+
+	registry := node.NewBlenderRegistry(RENDERHIVE_APP_DIRECTORY_BLENDER_BINARIES)
+	err := registry.Scan()
+	if err != nil {
+		return "", err
+	}
+	installations := registry.List()
+	if len(installations) == 0 {
+		return "", fmt.Errorf("No Blender version is installed in '%v'.", registry.Directory)
+	}
+
+	versions, errs := []string{}, []error{}
+	for _, installation := range installations {
+		blender := node.BlenderAppData{Path: installation.Path}
+		err := blender.ProbeVersion(RENDERHIVE_CONFIG_BLENDER_PROBE_TIMEOUT)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("v%v: %v", installation.Version, err))
+			continue
+		}
+		if !blender.MatchesArchive(installation.Version) {
+			errs = append(errs, fmt.Errorf("v%v: The binary reports v%v (build hash: %v), which is not the build of the Renderhive archive.", installation.Version, blender.BuildVersion, blender.BuildHash))
+			continue
+		}
+		versions = append(versions, "v"+installation.Version)
+	}
+	if len(versions) == 0 {
+		return "", errors.Join(errs...)
+	}
+	message := fmt.Sprintf("Installed: %v", strings.Join(versions, ", "))
+	if len(errs) > 0 {
+		message += fmt.Sprintf(" (%v version(s) could not be verified)", len(errs))
+	}
+
+	return message, nil
+
+}
+
+// helper function to check the Blender benchmark launcher
+func _doctorBenchmarkLauncher() (string, error) {
+
+	path, ok := node.FindBenchmarkLauncher()
+	if !ok {
+		return "", fmt.Errorf("The benchmark launcher was not found.")
+	}
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		if info.Mode().Perm()&0111 == 0 {
+			return "", fmt.Errorf("The benchmark launcher '%v' is not executable.", path)
+		}
+	}
+
+	// the launcher does not need to support a version flag
+	version, err := _doctorVersion(path, "--version")
+	if err != nil {
+		version = "unknown version"
+	}
+
+	return fmt.Sprintf("%v (%v)", path, version), nil
+
+}
+
+// helper function to check the w3 CLI and its agent
+func _doctorW3() (string, error) {
+
+	name := ipfs.Manager.W3Agent.Path
+	if name == "" {
+		name = "w3"
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("The w3 CLI '%v' was not found: %v", name, err)
+	}
+	version, err := _doctorVersion(path, "--version")
+	if err != nil {
+		return "", fmt.Errorf("The w3 CLI '%v' does not run: %v", path, err)
+	}
+	if ipfs.Manager.W3Agent.DIDkey == "" {
+		return "", fmt.Errorf("The w3 CLI '%v' (%v) has no agent identity.", path, version)
+	}
+
+	return fmt.Sprintf("%v (%v, agent: %v)", path, version, ipfs.Manager.W3Agent.DIDkey), nil
+
+}
+
+// helper function to check if files can be written to a directory
+func _doctorWritable(directory string) (string, error) {
+
+	if directory == "" {
+		return "", fmt.Errorf("The directory is not known.")
+	}
+	err := os.MkdirAll(directory, 0700)
+	if err != nil {
+		return "", fmt.Errorf("The directory '%v' cannot be created: %v", directory, err)
+	}
+	file, err := os.CreateTemp(directory, ".doctor-*")
+	if err != nil {
+		return "", fmt.Errorf("The directory '%v' is not writable: %v", directory, err)
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	path, err := filepath.Abs(directory)
+	if err != nil {
+		path = directory
+	}
+
+	return path, nil
+
+}
+
+// helper function to check the repository of the local IPFS node
+func _doctorIPFSRepository() (string, error) {
+
+	if ipfs.Manager.IpfsRepoPath == "" {
+		return "", fmt.Errorf("The IPFS repository was not opened.")
+	}
+	if info, err := os.Stat(ipfs.Manager.IpfsRepoPath); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("The IPFS repository '%v' does not exist.", ipfs.Manager.IpfsRepoPath)
+	}
+	if ipfs.Manager.IpfsNode == nil || ipfs.Manager.IpfsAPI == nil {
+		return "", fmt.Errorf("The IPFS node of the repository '%v' is not running.", ipfs.Manager.IpfsRepoPath)
+	}
+
+	return ipfs.Manager.IpfsRepoPath, nil
+
+}
+
+// helper function to check the connection to the IPFS network
+func _doctorIPFSNetwork() (string, error) {
+
+	if ipfs.Manager.IpfsNode == nil || !ipfs.Manager.IpfsNode.IsOnline {
+		return "", fmt.Errorf("The IPFS node is not online.")
+	}
+	peers, err := ipfs.Manager.GetConnectedPeers()
+	if err != nil {
+		return "", err
+	}
+	if len(peers) == 0 {
+		return "", fmt.Errorf("The IPFS node is not connected to any peers.")
+	}
+
+	return fmt.Sprintf("%v peer(s) connected", len(peers)), nil
+
+}
+
+// helper function to check the reachability of the Hedera network
+func _doctorHedera() (string, error) {
+
+	client := hedera.Manager.NetworkClient
+	if client == nil {
+		return "", fmt.Errorf("The Hedera client was not initialized.")
+	}
+	var nodes []string
+	for _, nodeAccountID := range client.GetNetwork() {
+		nodes = append(nodes, nodeAccountID.String())
+	}
+	reachable, err := _pingAnyNode(nodes, RENDERHIVE_CONFIG_DOCTOR_HEDERA_PING_NODES, func(node string) error {
+		nodeAccountID, err := hederasdk.AccountIDFromString(node)
+		if err != nil {
+			return err
+		}
+		return client.Ping(nodeAccountID)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%v reachable (node %v)", hedera.Manager.NetworkName(), reachable), nil
+
+}
+
+// helper function to ping the network nodes until one of them is reachable
+// NOTE: A single unreachable node (e.g., under maintenance) does not fail the
+// check. At most 'limit' nodes are pinged, so that the check does not wait for
+// the timeouts of the whole network.
+func _pingAnyNode(nodes []string, limit int, ping func(node string) error) (string, error) {
+
+	if len(nodes) == 0 {
+		return "", fmt.Errorf("The Hedera client has no network nodes.")
+	}
+
+	// ping the nodes in a fixed order
+	sort.Strings(nodes)
+	var errs []string
+	for i, node := range nodes {
+		if limit > 0 && i >= limit {
+			break
+		}
+		err := ping(node)
+		if err == nil {
+			return node, nil
+		}
+		errs = append(errs, fmt.Sprintf("%v: %v", node, err))
+	}
+
+	return "", fmt.Errorf("No Hedera network node is reachable (%v).", strings.Join(errs, "; "))
+
+}
+
+// helper function to check the presence of the operator key
+func _doctorOperatorKey() (string, error) {
+
+	if client := hedera.Manager.NetworkClient; client != nil && client.GetOperatorAccountID().String() != "0.0.0" {
+		return fmt.Sprintf("Loaded for account %v", client.GetOperatorAccountID()), nil
+	}
+
+	// the key may be in the keystore of the node account, which is unlocked on sign-in
+	accountID := node.Manager.Node.HederaAccount.AccountID
+	if accountID != "" {
+		keystore := hedera.Manager.KeystorePath(accountID)
+		if ok, _ := IsFile(keystore); ok {
+			return fmt.Sprintf("Keystore '%v' of account %v found (not unlocked)", keystore, accountID), nil
+		}
+		return "", fmt.Errorf("No operator key is loaded and the keystore '%v' of account %v does not exist.", keystore, accountID)
+	}
+
+	return "", fmt.Errorf("No operator key is loaded and no account is configured for this node.")
+
+}
+
+// helper function to get the first output line of a version command
+func _doctorVersion(path string, args ...string) (string, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, args...).CombinedOutput()
+	if err != nil {
+		return "", err
+	}
+	line, _, _ := strings.Cut(string(bytes.TrimSpace(output)), "\n")
+	if line == "" {
+		return "", fmt.Errorf("no version output")
+	}
+
+	return strings.TrimSpace(line), nil
+
+}
+
+// COMMAND LINE INTERFACE - DOCTOR
+// #############################################################################
+// Create the CLI command to check the external dependencies
+func (clim *PackageManager) CreateCommandDoctor() *cobra.Command {
+
+	// create a 'doctor' command
+	command := &cobra.Command{
+		Use:     "doctor",
+		Aliases: []string{"diagnose"},
+		Short:   "Check the external dependencies of the service app",
+		Long:    "This command checks the Blender binaries, the Blender benchmark launcher, the w3 CLI, the writability of the app data and configuration directories, the IPFS repository, the IPFS and Hedera networks, and the operator key. It prints a report with a remediation hint for each failed check and fails, if a critical check failed.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			report := clim.Diagnose()
+
+			logger.Manager.Println("")
+			logger.Manager.Println("Diagnosis of the service app dependencies:")
+			for _, check := range report.Checks {
+				logger.Manager.Resultf(" [%v] %v: %v\n", check.Status, check.Name, check.Message)
+				if check.Hint != "" {
+					logger.Manager.Resultf("        Hint: %v\n", check.Hint)
+				}
+			}
+			passed, warnings, failures := report.Count()
+			logger.Manager.Println("")
+			logger.Manager.Printf("%v passed, %v warning(s), %v failure(s). \n", passed, warnings, failures)
+			if !report.Passed() {
+				return fmt.Errorf("A critical check failed.")
+			}
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	return command
+
+}
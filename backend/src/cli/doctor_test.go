@@ -0,0 +1,104 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package cli
+
+import (
+
+	// standard
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDoctorReportAdd(t *testing.T) {
+	report := &DoctorReport{}
+	report.Add("Blender", true, "4.1.0", nil, "install Blender")
+	report.Add("IPFS", true, "", errors.New("not running"), "start the IPFS daemon")
+	report.Add("Balance", false, "", errors.New("low balance"), "top up the account")
+
+	if len(report.Checks) != 3 {
+		t.Fatalf("expected 3 checks, got %v", len(report.Checks))
+	}
+	if check := report.Checks[0]; check.Status != DOCTOR_STATUS_PASS || check.Message != "4.1.0" {
+		t.Errorf("unexpected passed check: %+v", check)
+	}
+	if check := report.Checks[1]; check.Status != DOCTOR_STATUS_FAIL || check.Hint != "start the IPFS daemon" {
+		t.Errorf("unexpected critical check: %+v", check)
+	}
+	if check := report.Checks[2]; check.Status != DOCTOR_STATUS_WARN {
+		t.Errorf("unexpected non-critical check: %+v", check)
+	}
+
+	passed, warnings, failures := report.Count()
+	if passed != 1 || warnings != 1 || failures != 1 {
+		t.Errorf("expected 1/1/1, got %v/%v/%v", passed, warnings, failures)
+	}
+	if report.Passed() {
+		t.Error("expected the report with a failure not to pass")
+	}
+}
+
+func TestDoctorReportPassedWithWarnings(t *testing.T) {
+	report := &DoctorReport{}
+	report.Add("Blender", true, "4.1.0", nil, "")
+	report.Add("Balance", false, "", errors.New("low balance"), "")
+
+	if !report.Passed() {
+		t.Error("expected the report with only warnings to pass")
+	}
+}
+
+func TestPingAnyNode(t *testing.T) {
+	var pinged []string
+	ping := func(reachable string) func(string) error {
+		return func(node string) error {
+			pinged = append(pinged, node)
+			if node == reachable {
+				return nil
+			}
+			return errors.New("timeout")
+		}
+	}
+
+	// the first node fails, but the second one responds
+	node, err := _pingAnyNode([]string{"0.0.4", "0.0.3"}, 3, ping("0.0.4"))
+	if err != nil || node != "0.0.4" {
+		t.Fatalf("expected node 0.0.4, got %q (%v)", node, err)
+	}
+	if strings.Join(pinged, ",") != "0.0.3,0.0.4" {
+		t.Errorf("unexpected ping order: %v", pinged)
+	}
+
+	// at most 'limit' nodes are pinged
+	pinged = nil
+	_, err = _pingAnyNode([]string{"0.0.3", "0.0.4", "0.0.5", "0.0.6"}, 2, ping(""))
+	if err == nil {
+		t.Fatal("expected an error, if no node is reachable")
+	}
+	if len(pinged) != 2 {
+		t.Errorf("expected 2 pings, got %v", len(pinged))
+	}
+
+	// no nodes
+	if _, err = _pingAnyNode(nil, 3, ping("")); err == nil {
+		t.Error("expected an error without network nodes")
+	}
+}
@@ -33,6 +33,7 @@ import (
 	// standard
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -43,7 +44,7 @@ import (
 	"github.com/spf13/pflag"
 
 	// internal
-	// . "renderhive/globals"
+	. "renderhive/globals"
 	"renderhive/hedera"
 	"renderhive/ipfs"
 	"renderhive/jsonrpc"
@@ -58,7 +59,14 @@ type Commands struct {
 	// renderhive main commands
 	Main      *cobra.Command
 	MainFlags struct {
-		Interactive bool
+		Interactive    bool
+		Quiet          bool
+		Debug          bool
+		HealthAddress  string
+		Metrics        bool
+		MetricsAddress string
+		KeepOffers     bool
+		RequesterOnly  bool
 	}
 
 	// subcommands
@@ -92,6 +100,8 @@ func (clim *PackageManager) Init() error {
 	clim.AddPackageCommand(hedera.Manager.CreateCommand())
 	clim.AddPackageCommand(ipfs.Manager.CreateCommand())
 	clim.AddPackageCommand(jsonrpc.Manager.CreateCommand())
+	clim.AddPackageCommand(clim.CreateCommandConfig())
+	clim.AddPackageCommand(clim.CreateCommandDoctor())
 
 	return err
 }
@@ -120,33 +130,50 @@ func (clim *PackageManager) CreateMainCommand() *cobra.Command {
 		Use:   "renderhive",
 		Short: "Renderhive is a crowdrendering plattform for Blender based on Web3 technologies",
 		Long:  "This command line interface gives you complete control over the Renderhive Service App backend, which is the main software package for participating in the Renderhive network – the first crowdrendering platform for Blender built on Web3 technologies.",
-		Run: func(cmd *cobra.Command, args []string) {
+		// NOTE: The errors of all commands are printed by the CLI manager.
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *cobra.Command, args []string) error {
 
-			return
+			return nil
 
 		},
 	}
 
 	// add command flags
 	clim.Commands.Main.Flags().BoolVarP(&clim.Commands.MainFlags.Interactive, "interactive", "i", false, "Run the Renderhive Service App in an interactive session")
+	clim.Commands.Main.Flags().BoolVarP(&clim.Commands.MainFlags.Quiet, "quiet", "q", false, "Only log errors and only print the results and errors of commands")
+	clim.Commands.Main.Flags().BoolVarP(&clim.Commands.MainFlags.Debug, "debug", "", false, "Enable trace logging of all packages")
+	clim.Commands.Main.Flags().StringVarP(&clim.Commands.MainFlags.HealthAddress, "health-address", "", RENDERHIVE_CONFIG_HEALTH_ADDRESS, "Bind address of the health-check endpoint (an empty string disables the endpoint)")
+	clim.Commands.Main.Flags().BoolVarP(&clim.Commands.MainFlags.Metrics, "metrics", "", false, "Export Prometheus metrics on the metrics endpoint")
+	clim.Commands.Main.Flags().StringVarP(&clim.Commands.MainFlags.MetricsAddress, "metrics-address", "", RENDERHIVE_CONFIG_METRICS_ADDRESS, "Bind address of the metrics endpoint")
+	clim.Commands.Main.Flags().BoolVarP(&clim.Commands.MainFlags.KeepOffers, "keep-offers", "", false, "Do not withdraw the active render offer on shutdown (e.g., for quick restarts)")
+	clim.Commands.Main.Flags().BoolVarP(&clim.Commands.MainFlags.RequesterOnly, "requester-only", "", false, "Only request renderings and retrieve their results (without rendering for the render hive)")
 
 	// Create an 'exit' command for the CLI session
 	clim.Commands.Exit = &cobra.Command{
 		Use:   "exit",
 		Short: "Exit the Renderhive command line interface session",
 		Long:  "This command will close the command line interface session and shutdown the Renderhive Service App",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
 			// quit the session
 			clim.Quit = true
 
-			return
+			return nil
 		},
 	}
 
 	// Parse the flags passed to the CLI
 	clim.Commands.Main.ParseFlags(os.Args[1:])
 
+	// set the verbosity of the loggers and the CLI output
+	if clim.Commands.MainFlags.Debug {
+		logger.Manager.SetVerbosity(logger.VERBOSITY_DEBUG)
+	} else if clim.Commands.MainFlags.Quiet {
+		logger.Manager.SetVerbosity(logger.VERBOSITY_QUIET)
+	}
+
 	// add the command
 	clim.Commands.Main.AddCommand(clim.Commands.Exit)
 
@@ -154,6 +181,22 @@ func (clim *PackageManager) CreateMainCommand() *cobra.Command {
 
 }
 
+// Check if the '--requester-only' flag was passed on the command line
+// NOTE: The node manager is initialized before the CLI manager parses the flags,
+// so this flag is looked up in advance (other flags are ignored).
+func RequesterOnlyFlag(args []string) bool {
+
+	var requesterOnly bool
+	flags := pflag.NewFlagSet("renderhive", pflag.ContinueOnError)
+	flags.ParseErrorsWhitelist.UnknownFlags = true
+	flags.SetOutput(io.Discard)
+	flags.BoolVar(&requesterOnly, "requester-only", false, "")
+	flags.Parse(args)
+
+	return requesterOnly
+
+}
+
 // Create the package command for the command line interface
 func (clim *PackageManager) AddPackageCommand(command *cobra.Command) *cobra.Command {
 	var packageCommands *cobra.Command
@@ -168,20 +211,52 @@ func (clim *PackageManager) AddPackageCommand(command *cobra.Command) *cobra.Com
 
 }
 
+// Execute a single command passed on the command line (non-interactive mode)
+// NOTE: Returns the error of a failed command, so the app can exit with a nonzero status.
+func (clim *PackageManager) ExecuteCommand(args []string) error {
+
+	clim.Commands.Main.SetArgs(args)
+	err := clim.Commands.Main.Execute()
+	if err != nil {
+		clim.PrintError(err)
+	}
+
+	return err
+
+}
+
+// Print the error of a failed command (to stderr)
+func (clim *PackageManager) PrintError(err error) {
+
+	logger.Manager.Errorln(fmt.Errorf("Error: %v", err))
+
+}
+
+// Get the exit status of the app for the error of a command (nonzero, if the command failed)
+func ExitCode(err error) int {
+
+	if err != nil {
+		return 1
+	}
+
+	return 0
+
+}
+
 // Start the command line interface in interactive mode
 func (clim *PackageManager) StartInteractive() {
 
-	fmt.Println("------------------------------------------------------------")
-	fmt.Println("|    _____                _           _     _              |")
-	fmt.Println("|   |  __ \\              | |         | |   (_)             |")
-	fmt.Println("|   | |__) |___ _ __   __| | ___ _ __| |__  ___   _____    |")
-	fmt.Println("|   |  _  // _ \\ '_ \\ / _` |/ _ \\ '__| '_ \\| \\ \\ / / _ \\   |")
-	fmt.Println("|   | | \\ \\  __/ | | | (_| |  __/ |  | | | | |\\ V /  __/   |")
-	fmt.Println("|   |_|  \\_\\___|_| |_|\\__,_|\\___|_|  |_| |_|_| \\_/ \\___|   |")
-	fmt.Println("|                  COMMAND LINE INTERFACE                  |")
-	fmt.Println("------------------------------------------------------------")
-	fmt.Println("")
-	fmt.Println("Interact with the Renderhive network from the command line:")
+	logger.Manager.Println("------------------------------------------------------------")
+	logger.Manager.Println("|    _____                _           _     _              |")
+	logger.Manager.Println("|   |  __ \\              | |         | |   (_)             |")
+	logger.Manager.Println("|   | |__) |___ _ __   __| | ___ _ __| |__  ___   _____    |")
+	logger.Manager.Println("|   |  _  // _ \\ '_ \\ / _` |/ _ \\ '__| '_ \\| \\ \\ / / _ \\   |")
+	logger.Manager.Println("|   | | \\ \\  __/ | | | (_| |  __/ |  | | | | |\\ V /  __/   |")
+	logger.Manager.Println("|   |_|  \\_\\___|_| |_|\\__,_|\\___|_|  |_| |_|_| \\_/ \\___|   |")
+	logger.Manager.Println("|                  COMMAND LINE INTERFACE                  |")
+	logger.Manager.Println("------------------------------------------------------------")
+	logger.Manager.Println("")
+	logger.Manager.Println("Interact with the Renderhive network from the command line:")
 
 	// start a new interactive CLI session
 	for !clim.Quit {
@@ -202,7 +277,9 @@ func (clim *PackageManager) StartInteractive() {
 
 		// process the command
 		clim.Commands.Main.SetArgs(args)
-		clim.Commands.Main.Execute()
+		if err := clim.Commands.Main.Execute(); err != nil {
+			clim.PrintError(err)
+		}
 
 		// reset arguments and flags for the next command
 		// empty args again, so that they don't interfere with the next loop
@@ -225,6 +302,7 @@ func (clim *PackageManager) ResetFlags() {
 		subcmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
 			// fmt.Println(flag.Name, flag.DefValue)
 			flag.Value.Set(flag.DefValue)
+			flag.Changed = false
 			if val, ok := flag.Value.(pflag.SliceValue); ok {
 				_ = val.Replace(nil)
 			}
@@ -233,6 +311,7 @@ func (clim *PackageManager) ResetFlags() {
 		subcmd.Flags().VisitAll(func(flag *pflag.Flag) {
 			// fmt.Println(flag.Name, flag.DefValue)
 			flag.Value.Set(flag.DefValue)
+			flag.Changed = false
 			if val, ok := flag.Value.(pflag.SliceValue); ok {
 				_ = val.Replace(nil)
 			}
@@ -242,6 +321,7 @@ func (clim *PackageManager) ResetFlags() {
 			subsubcmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
 				// fmt.Println(flag.Name, flag.DefValue)
 				flag.Value.Set(flag.DefValue)
+				flag.Changed = false
 				if val, ok := flag.Value.(pflag.SliceValue); ok {
 					_ = val.Replace(nil)
 				}
@@ -250,6 +330,7 @@ func (clim *PackageManager) ResetFlags() {
 			subsubcmd.Flags().VisitAll(func(flag *pflag.Flag) {
 				// fmt.Println(flag.Name, flag.DefValue)
 				flag.Value.Set(flag.DefValue)
+				flag.Changed = false
 				if val, ok := flag.Value.(pflag.SliceValue); ok {
 					_ = val.Replace(nil)
 				}
@@ -259,6 +340,7 @@ func (clim *PackageManager) ResetFlags() {
 				subsubsubcmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
 					// fmt.Println(flag.Name, flag.DefValue)
 					flag.Value.Set(flag.DefValue)
+					flag.Changed = false
 					if val, ok := flag.Value.(pflag.SliceValue); ok {
 						_ = val.Replace(nil)
 					}
@@ -266,6 +348,7 @@ func (clim *PackageManager) ResetFlags() {
 
 				subsubsubcmd.Flags().VisitAll(func(flag *pflag.Flag) {
 					flag.Value.Set(flag.DefValue)
+					flag.Changed = false
 					if val, ok := flag.Value.(pflag.SliceValue); ok {
 						_ = val.Replace(nil)
 					}
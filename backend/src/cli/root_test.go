@@ -0,0 +1,83 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package cli
+
+import (
+
+	// standard
+	"bytes"
+	"strings"
+	"testing"
+
+	// external
+	"github.com/spf13/cobra"
+
+	// internal
+	"renderhive/logger"
+)
+
+func TestMissingRequiredFlagExitsNonzero(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+	var output, errors bytes.Buffer
+	logger.Manager.Output, logger.Manager.ErrorOutput = &output, &errors
+	defer func() { logger.Manager.Output, logger.Manager.ErrorOutput = nil, nil }()
+
+	// a command with a required flag
+	clim := &PackageManager{}
+	clim.CreateMainCommand()
+	called := false
+	command := &cobra.Command{
+		Use: "add",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			called = true
+			return nil
+		},
+	}
+	command.Flags().String("file", "", "")
+	command.MarkFlagRequired("file")
+	clim.Commands.Main.AddCommand(command)
+
+	// the command fails without the flag
+	err := clim.ExecuteCommand([]string{"add"})
+	if err == nil || called {
+		t.Fatalf("got error %v (called: %v), want a failed command", err, called)
+	}
+	if code := ExitCode(err); code == 0 {
+		t.Errorf("got exit code %v, want a nonzero exit code", code)
+	}
+
+	// the error is printed to stderr only
+	if !strings.Contains(errors.String(), "Error:") || !strings.Contains(errors.String(), "file") {
+		t.Errorf("got errors %q, want the missing flag", errors.String())
+	}
+	if output.Len() != 0 {
+		t.Errorf("got output %q, want no output", output.String())
+	}
+
+	// the command succeeds with the flag
+	if err := clim.ExecuteCommand([]string{"add", "--file", "scene.blend"}); err != nil || !called {
+		t.Fatalf("got error %v (called: %v), want a successful command", err, called)
+	}
+	if code := ExitCode(nil); code != 0 {
+		t.Errorf("got exit code %v, want 0", code)
+	}
+}
@@ -20,18 +20,35 @@
 
 package globals
 
+import (
+
+	// standard
+	"strconv"
+	"strings"
+)
+
 // BLENDER VERSION ARCHIVE
 // #############################################################################
 
 // DID of the w3up space storing the Blender binaries
 const RENDERHIVE_BLENDER_ARCHIVE_DID string = "did:key:z6MkrL1hN8qRwNsynXSvTL14gqQ6ZYg6wwB1zfr1yKr7wbKL"
 
+// CID of the manifest of the supported Blender versions, which is pinned by the
+// Renderhive project (empty = only the built-in versions are supported)
+// NOTE: The manifest adds new Blender versions without an app update (see
+// node/blender_manifest.go). The project signs it with the private key of the
+// public key below (hex encoded Hedera public key), which is required whenever
+// a manifest is used. No manifest was published yet, so both are empty.
+const RENDERHIVE_BLENDER_MANIFEST_CID string = ""
+const RENDERHIVE_BLENDER_MANIFEST_PUBLIC_KEY string = ""
+
 // specifies the structure of the Blender archive file
 type BlenderArchiveFile struct {
 	CID      string
 	SHA      string
 	Commit   string
 	Filename string
+	Size     int64 // size of the archive file in bytes (0 = not known)
 }
 type BlenderArchiveVersion struct {
 	Linux   BlenderArchiveFile
@@ -53,3 +70,89 @@ var RENDERHIVE_BLENDER_ARCHIVE_FILES = map[string]BlenderArchiveVersion{
 		Macos:   BlenderArchiveFile{},
 	},
 }
+
+// BLENDER CAPABILITIES
+// #############################################################################
+
+// Render engine of a range of Blender versions
+type BlenderEngineCapability struct {
+	Engine      string   // engine name of the service app
+	MinVersion  string   // first Blender version with this engine
+	MaxVersion  string   // first Blender version without this engine (empty = all later versions)
+	FeatureSets []string // feature sets of the engine (empty = the engine has no feature sets)
+}
+
+// define the render engines of the Blender versions
+// NOTE: Blender 4.2 replaced EEVEE by EEVEE-Next, which was renamed back to
+// EEVEE in Blender 5.0.
+var RENDERHIVE_BLENDER_ENGINE_CAPABILITIES = []BlenderEngineCapability{
+	{Engine: "EEVEE", MinVersion: "2.80", MaxVersion: "4.2"},
+	{Engine: "EEVEE_NEXT", MinVersion: "4.2", MaxVersion: "5.0"},
+	{Engine: "EEVEE", MinVersion: "5.0"},
+	{Engine: "CYCLES", MinVersion: "2.80", FeatureSets: []string{"SUPPORTED", "EXPERIMENTAL"}},
+}
+
+// the feature set of an engine, if none was selected
+const BLENDER_DEFAULT_FEATURE_SET = "SUPPORTED"
+
+// Get the render engines of a Blender version and their feature sets
+func GetBlenderCapabilities(version string) []BlenderEngineCapability {
+	var result []BlenderEngineCapability
+
+	for _, capability := range RENDERHIVE_BLENDER_ENGINE_CAPABILITIES {
+		if CompareBlenderVersions(version, capability.MinVersion) < 0 {
+			continue
+		}
+		if capability.MaxVersion != "" && CompareBlenderVersions(version, capability.MaxVersion) >= 0 {
+			continue
+		}
+		result = append(result, capability)
+	}
+
+	return result
+
+}
+
+// Get the engine name of a Blender version for an engine name of another version
+// NOTE: The name is upper case. EEVEE is called EEVEE_NEXT in the versions with
+// EEVEE-Next and vice versa.
+func NormalizeBlenderEngine(version string, engine string) string {
+
+	engine = strings.ToUpper(engine)
+	if engine != "EEVEE" && engine != "EEVEE_NEXT" {
+		return engine
+	}
+	for _, capability := range GetBlenderCapabilities(version) {
+		if capability.Engine == "EEVEE" || capability.Engine == "EEVEE_NEXT" {
+			return capability.Engine
+		}
+	}
+
+	return engine
+
+}
+
+// Compare two Blender versions (-1, if a is older than b; 0, if both are equal; 1 otherwise)
+// NOTE: Missing or invalid parts of a version count as 0 (e.g., "4.2" equals "4.2.0").
+func CompareBlenderVersions(a string, b string) int {
+
+	partsA, partsB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var numberA, numberB int
+		if i < len(partsA) {
+			numberA, _ = strconv.Atoi(partsA[i])
+		}
+		if i < len(partsB) {
+			numberB, _ = strconv.Atoi(partsB[i])
+		}
+		if numberA < numberB {
+			return -1
+		}
+		if numberA > numberB {
+			return 1
+		}
+	}
+
+	return 0
+
+}
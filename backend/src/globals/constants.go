@@ -25,6 +25,7 @@ import (
 	// standard
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	// external
@@ -42,6 +43,9 @@ const COMPILER_RENDERHIVE_LOGGER_LEVEL = zerolog.DebugLevel
 // Mirror node URL
 const HEDERA_TESTNET_MIRROR_NODE_URL = "https://testnet.mirrornode.hedera.com:443"
 
+// Memo prefix of the HCS topics of the render nodes (followed by the node's account ID)
+const HEDERA_NODE_TOPIC_MEMO_PREFIX = "renderhive-node::"
+
 // RENDERHIVE CONSTANTS
 // #############################################################################
 // Account ID of the Renderhive smart contract
@@ -59,6 +63,247 @@ const RENDERHIVE_TESTNET_RENDER_JOB_QUEUE = "0.0.2659518"
 // Hive cycle synchronization interval
 const RENDERHIVE_CONFIG_HIVE_CYCLE_SYNCHRONIZATION_INTERVAL = 1 * time.Hour
 
+// Gas limit of the read-only queries of the Renderhive smart contract
+const RENDERHIVE_CONFIG_CONTRACT_QUERY_GAS = 100000
+
+// Gas limit of the transactions of the Renderhive smart contract
+const RENDERHIVE_CONFIG_CONTRACT_TRANSACTION_GAS = 300000
+
+// Render job deadlines
+// NOTE: The deadline of a claimed render job is the estimated render time
+// multiplied with the safety factor, but never less than the minimum
+const RENDERHIVE_CONFIG_RENDER_JOB_DEADLINE_SAFETY_FACTOR = 2.0
+const RENDERHIVE_CONFIG_RENDER_JOB_DEADLINE_MINIMUM = 10 * time.Minute
+
+// Interval in which the render worker looks for the next render job
+const RENDERHIVE_CONFIG_RENDER_WORKER_INTERVAL = 5 * time.Second
+
+// Maximum time to wait for the version and build info of a Blender binary
+const RENDERHIVE_CONFIG_BLENDER_PROBE_TIMEOUT = 30 * time.Second
+
+// Maximum time to wait for the manifest of the supported Blender versions on start
+const RENDERHIVE_CONFIG_BLENDER_MANIFEST_TIMEOUT = 30 * time.Second
+
+// Maximum time to wait for the size of a Blender archive, which is not listed
+const RENDERHIVE_CONFIG_BLENDER_ARCHIVE_SIZE_TIMEOUT = 10 * time.Second
+
+// Benchmark scene rendered by the quick benchmark of a new Blender version
+const RENDERHIVE_CONFIG_BENCHMARK_QUICK_SCENE = "monster"
+
+// Maximum time to wait for the output of a terminated Blender benchmark tool
+const RENDERHIVE_CONFIG_BENCHMARK_WAIT_DELAY = 5 * time.Second
+
+// Maximum length of the benchmark tool output in the error of an unexpected output
+const RENDERHIVE_CONFIG_BENCHMARK_OUTPUT_SNIPPET = 200
+
+// Default maximum width and height of the frame previews (in pixels)
+const RENDERHIVE_CONFIG_PREVIEW_SIZE = 320
+
+// Default JPEG quality of the frame previews
+const RENDERHIVE_CONFIG_PREVIEW_QUALITY = 75
+
+// Maximum number of pixels of a frame, which is decoded for a preview
+const RENDERHIVE_CONFIG_PREVIEW_MAX_PIXELS = 16384 * 16384
+
+// Estimated render time per frame, until this node observed its own render times
+const RENDERHIVE_CONFIG_RENDER_JOB_FRAME_DURATION = 5 * time.Minute
+
+// Weight of the latest observed render time in the average render time per frame
+const RENDERHIVE_CONFIG_RENDER_JOB_FRAME_DURATION_SMOOTHING = 0.3
+
+// Priority added to the render jobs of the own render requests of this node
+const RENDERHIVE_CONFIG_OWN_REQUEST_PRIORITY = 1000
+
+// Number of timed out render attempts after which a render job is flagged
+const RENDERHIVE_CONFIG_RENDER_JOB_MAXIMUM_ATTEMPTS = 3
+
+// Default origins of the webpages that may call the JSON-RPC server (the Renderhive frontend)
+var RENDERHIVE_CONFIG_JSONRPC_ALLOWED_ORIGINS = []string{"https://localhost:5173", "https://127.0.0.1:5173"}
+
+// Default rate limits of the JSON-RPC server (calls per window and client)
+const RENDERHIVE_CONFIG_JSONRPC_RATE_LIMIT_WINDOW = 1 * time.Minute
+const RENDERHIVE_CONFIG_JSONRPC_RATE_LIMIT_GLOBAL = 300
+const RENDERHIVE_CONFIG_JSONRPC_RATE_LIMIT_DEFAULT = 120
+const RENDERHIVE_CONFIG_JSONRPC_RATE_LIMIT_COSTLY = 10
+
+// Number of tracked rate limit windows after which expired windows are removed
+const RENDERHIVE_CONFIG_JSONRPC_RATE_LIMIT_MAX_CLIENTS = 1000
+
+// Time after which a JSON-RPC call is answered with a timeout error
+const RENDERHIVE_CONFIG_JSONRPC_REQUEST_TIMEOUT = 60 * time.Second
+
+// Number of read-only JSON-RPC queries that may run in parallel
+const RENDERHIVE_CONFIG_JSONRPC_MAX_PARALLEL_QUERIES = 16
+
+// Default bind address of the health-check endpoint
+const RENDERHIVE_CONFIG_HEALTH_ADDRESS = "127.0.0.1:5175"
+
+// Default bind address of the metrics endpoint
+const RENDERHIVE_CONFIG_METRICS_ADDRESS = "127.0.0.1:5176"
+
+// Default fee caps of the transactions and queries of this node (in HBAR)
+const RENDERHIVE_CONFIG_HEDERA_MAX_TRANSACTION_FEE = 5.0
+const RENDERHIVE_CONFIG_HEDERA_MAX_QUERY_PAYMENT = 1.0
+
+// Initial and maximum backoff before a dropped topic subscription is resubscribed
+const RENDERHIVE_CONFIG_TOPIC_RESUBSCRIBE_BACKOFF = 1 * time.Second
+const RENDERHIVE_CONFIG_TOPIC_RESUBSCRIBE_MAX_BACKOFF = 1 * time.Minute
+
+// Maximum number of Hedera network nodes pinged by the 'doctor' command
+const RENDERHIVE_CONFIG_DOCTOR_HEDERA_PING_NODES = 3
+
+// Minimum operator account balance (in HBAR) before the health-check reports a warning
+const RENDERHIVE_CONFIG_HEALTH_MINIMUM_BALANCE = 1.0
+
+// Time the health-check reuses the status of the Hedera client (incl. the operator balance)
+const RENDERHIVE_CONFIG_HEALTH_HEDERA_INTERVAL = 1 * time.Minute
+
+// Maximum time the health-check waits for the status of the Hedera client
+const RENDERHIVE_CONFIG_HEALTH_HEDERA_TIMEOUT = 5 * time.Second
+
+// Maximum time the key rotation of an account waits for the account update transaction
+const RENDERHIVE_CONFIG_HEDERA_KEY_ROTATION_TIMEOUT = 2 * time.Minute
+
+// Maximum time the health-check waits for the lock of the render data
+const RENDERHIVE_CONFIG_HEALTH_LOCK_TIMEOUT = 2 * time.Second
+
+// Maximum time to wait for a DHT provider of deployed render offers and requests
+const RENDERHIVE_CONFIG_DEPLOY_PROVIDER_TIMEOUT = 2 * time.Minute
+
+// Time to collect further DHT providers after the first one was found
+const RENDERHIVE_CONFIG_DEPLOY_PROVIDER_GRACE_PERIOD = 10 * time.Second
+
+// Maximum time for inspecting the render settings of a Blender file
+const RENDERHIVE_CONFIG_BLENDER_INSPECTION_TIMEOUT = 2 * time.Minute
+
+// Number of peers the IPFS node waits for on start (at most the low watermark)
+const RENDERHIVE_CONFIG_IPFS_BOOTSTRAP_PEERS = 4
+
+// Default number of files added in parallel by the bulk import of the IPFS node
+const RENDERHIVE_CONFIG_IPFS_IMPORT_CONCURRENCY = 4
+
+// Maximum number of attempts to add a file/directory to the IPFS node and the
+// delay before the first retry (doubled with each further retry)
+const RENDERHIVE_CONFIG_IPFS_ADD_ATTEMPTS = 3
+const RENDERHIVE_CONFIG_IPFS_ADD_RETRY_DELAY = 2 * time.Second
+
+// Lifetime, cache time (TTL), and republish interval of the IPNS records of this node
+// NOTE: The records must be republished well before their lifetime ends.
+const RENDERHIVE_CONFIG_IPNS_RECORD_LIFETIME = 48 * time.Hour
+const RENDERHIVE_CONFIG_IPNS_RECORD_TTL = 5 * time.Minute
+const RENDERHIVE_CONFIG_IPNS_REPUBLISH_INTERVAL = 12 * time.Hour
+
+// Maximum time to publish or resolve an IPNS record
+const RENDERHIVE_CONFIG_IPNS_TIMEOUT = 2 * time.Minute
+
+// External services to query the public IP addresses of this node (tried in order)
+// and the maximum time to wait for each of them
+var RENDERHIVE_CONFIG_PUBLIC_IPV4_SERVICES = []string{"https://api.ipify.org", "https://ipv4.icanhazip.com", "https://v4.ident.me"}
+var RENDERHIVE_CONFIG_PUBLIC_IPV6_SERVICES = []string{"https://api6.ipify.org", "https://ipv6.icanhazip.com", "https://v6.ident.me"}
+
+const RENDERHIVE_CONFIG_PUBLIC_IP_TIMEOUT = 10 * time.Second
+
+// Circuit relays used by render nodes, which are not publicly reachable
+// NOTE: As long as the project runs no public relays, the list is empty and
+// AutoRelay uses the relays it finds in the DHT.
+var RENDERHIVE_CONFIG_IPFS_RELAYS = []string{}
+
+// Default limits of the render jobs claimed by this node
+// NOTE: The memory limit is a fraction of the system memory (if no maximum is configured).
+const RENDERHIVE_CONFIG_RENDER_LIMIT_MEMORY_FRACTION = 0.8
+const RENDERHIVE_CONFIG_RENDER_LIMIT_DURATION = 24 * time.Hour
+
+// Default restart policy of crashed Blender processes
+// NOTE: Crashes due to a lack of memory are restarted with smaller tiles. The
+// delay before a restart doubles with each restart up to the maximum delay.
+const RENDERHIVE_CONFIG_RENDER_MAX_RESTARTS = 0 // no restarts
+const RENDERHIVE_CONFIG_RENDER_RESTART_BACKOFF = 10 * time.Second
+const RENDERHIVE_CONFIG_RENDER_RESTART_MAX_BACKOFF = 5 * time.Minute
+const RENDERHIVE_CONFIG_RENDER_MIN_TILE_SIZE = 64
+const RENDERHIVE_CONFIG_RENDER_DEFAULT_TILE_SIZE = 2048 // tile size of Blender, if the file does not declare one
+
+// Parameters of the resource estimation of render jobs
+// NOTE: The memory is given in MB (like the peak memory of the benchmark results).
+const RENDERHIVE_CONFIG_RENDER_ESTIMATE_BASE_MEMORY = 512.0            // memory used by Blender itself
+const RENDERHIVE_CONFIG_RENDER_ESTIMATE_SCENE_MEMORY_FACTOR = 4.0      // memory per MB of the Blender file
+const RENDERHIVE_CONFIG_RENDER_ESTIMATE_PIXEL_MEMORY = 64.0            // bytes of render buffers per pixel
+const RENDERHIVE_CONFIG_RENDER_ESTIMATE_REFERENCE_PIXELS = 1920 * 1080 // resolution of the benchmark scenes
+
+// Default weights of the render offer ranking (price, throughput, and reliability)
+const RENDERHIVE_CONFIG_RANKING_WEIGHT_PRICE = 0.5
+const RENDERHIVE_CONFIG_RANKING_WEIGHT_THROUGHPUT = 0.3
+const RENDERHIVE_CONFIG_RANKING_WEIGHT_RELIABILITY = 0.2
+
+// Partial score of the render offer ranking, if the throughput or reliability is not known
+const RENDERHIVE_CONFIG_RANKING_MISSING_SCORE = 0.25
+
+// Half-life of the observed events of the node reputation
+const RENDERHIVE_CONFIG_REPUTATION_HALF_LIFE = 30 * 24 * time.Hour
+
+// Success rate of nodes without history and its weight (in pseudo events)
+const RENDERHIVE_CONFIG_REPUTATION_NEUTRAL_SUCCESS_RATE = 0.5
+const RENDERHIVE_CONFIG_REPUTATION_PRIOR_WEIGHT = 2.0
+
+// Default time closed render documents are kept active and interval of their sweep
+const RENDERHIVE_CONFIG_REPOSITORY_RETENTION = 30 * 24 * time.Hour
+const RENDERHIVE_CONFIG_REPOSITORY_SWEEP_INTERVAL = 1 * time.Hour
+
+// Default interval of the re-announcements of the active render offer and its minimum
+const RENDERHIVE_CONFIG_OFFER_ANNOUNCE_INTERVAL = 30 * time.Minute
+const RENDERHIVE_CONFIG_OFFER_ANNOUNCE_MINIMUM_INTERVAL = 1 * time.Minute
+
+// Age after which a render offer of the network is stale, if it was not re-announced
+const RENDERHIVE_CONFIG_NETWORK_OFFER_MAX_AGE = 2 * RENDERHIVE_CONFIG_OFFER_ANNOUNCE_INTERVAL
+
+// Interval of the checks of the render deadlines of the claimed render jobs
+const RENDERHIVE_CONFIG_RENDER_DEADLINE_CHECK_INTERVAL = 10 * time.Second
+
+// Interval of the status checks of the transactions, which were returned for signing
+const RENDERHIVE_CONFIG_TRANSACTION_POLL_INTERVAL = 10 * time.Second
+
+// Time the decommissioning waits for the removal of the node from the smart contract
+const RENDERHIVE_CONFIG_DECOMMISSION_REMOVAL_TIMEOUT = 10 * time.Minute
+
+// Interval of the balance checks of the operator account (only while events are subscribed)
+const RENDERHIVE_CONFIG_BALANCE_POLL_INTERVAL = 1 * time.Minute
+
+// Time a queried account balance is reused by the balance checks of payable contract calls
+const RENDERHIVE_CONFIG_BALANCE_CACHE_DURATION = 30 * time.Second
+
+// Number of events buffered for each subscriber of the node events
+const RENDERHIVE_CONFIG_EVENT_BUFFER = 64
+
+// Number of job queue messages buffered for processing
+const RENDERHIVE_CONFIG_JOB_QUEUE_BUFFER = 1024
+
+// Default number of workers for the background tasks of the job queue messages (e.g., pinning)
+const RENDERHIVE_CONFIG_JOB_QUEUE_WORKERS = 4
+
+// Default time limit of the background tasks of a job queue message
+const RENDERHIVE_CONFIG_JOB_QUEUE_MESSAGE_TIMEOUT = 2 * time.Minute
+
+// Minimum time between two writes of the last processed job queue message
+const RENDERHIVE_CONFIG_JOB_QUEUE_STATE_INTERVAL = 10 * time.Second
+
+// Time the app waits for its background operations on shutdown
+const RENDERHIVE_CONFIG_SHUTDOWN_TIMEOUT = 10 * time.Second
+
+// Schema version of the render offer and render request documents written by this node
+const RENDERHIVE_DOCUMENT_SCHEMA_VERSION = 1
+
+// Minimum length of the passphrase of an exported node archive
+const RENDERHIVE_CONFIG_NODE_ARCHIVE_MINIMUM_PASSPHRASE = 8
+
+// Environment variables of the operator account of non-interactive deployments (e.g., containers)
+// NOTE: Each secret can also be read from a file (e.g., a mounted secret), whose
+// path is given in the variable with the suffix RENDERHIVE_ENV_FILE_SUFFIX.
+const RENDERHIVE_ENV_ACCOUNT_ID = "RENDERHIVE_ACCOUNT_ID"
+const RENDERHIVE_ENV_PRIVATE_KEY = "RENDERHIVE_PRIVATE_KEY"
+const RENDERHIVE_ENV_KEYSTORE = "RENDERHIVE_KEYSTORE"
+const RENDERHIVE_ENV_PASSPHRASE = "RENDERHIVE_PASSPHRASE"
+const RENDERHIVE_ENV_PUBLIC_KEY = "RENDERHIVE_PUBLIC_KEY"
+const RENDERHIVE_ENV_FILE_SUFFIX = "_FILE"
+
 // path to application data
 const RENDERHIVE_APP_DIRECTORY = "renderhive/"
 const RENDERHIVE_APP_DIRECTORY_DATA = "data/"
@@ -68,18 +313,77 @@ const RENDERHIVE_APP_DIRECTORY_TEMP = "/tmp/"
 // path to local IPFS repository
 const RENDERHIVE_APP_DIRECTORY_IPFS_REPO = "ipfs/repo/"
 
+// MFS workspace of the render requests on the local IPFS node
+const RENDERHIVE_IPFS_MFS_WORKSPACE_REQUESTS = "/renderhive/requests/"
+
 // local path to Blender related directories
 const RENDERHIVE_APP_DIRECTORY_BLENDER_BINARIES = "/usr/local/bin/blender/"
 const RENDERHIVE_APP_DIRECTORY_BLENDER_BENCHMARKS = "data/blender/blender_benchmarks/"
+const RENDERHIVE_APP_DIRECTORY_BLENDER_BENCHMARK_CACHE = "data/blender/benchmark_cache/"
+const RENDERHIVE_APP_DIRECTORY_BLENDER_BENCHMARK_LAUNCHER = "data/blender/benchmark_launcher/"
+const RENDERHIVE_APP_DIRECTORY_BLENDER_SCRATCH = "data/blender/scratch/"
+const RENDERHIVE_APP_DIRECTORY_BLENDER_MANIFESTS = "data/blender/manifests/"
+const RENDERHIVE_APP_DIRECTORY_BLENDER_PROBES = "data/blender/probes/"
 
 // local paths to the render request and render offer documents (both own and from the hive)
 const RENDERHIVE_APP_DIRECTORY_LOCAL_REQUESTS = "data/render_requests/local/"
 const RENDERHIVE_APP_DIRECTORY_NETWORK_REQUESTS = "data/render_requests/network/"
+const RENDERHIVE_APP_DIRECTORY_PACKED_REQUESTS = "data/render_requests/packed/"
 const RENDERHIVE_APP_DIRECTORY_LOCAL_OFFERS = "data/render_offers/local/"
 const RENDERHIVE_APP_DIRECTORY_NETWORK_OFFERS = "data/render_offers/network/"
 
+// local paths to the archived render request and render offer documents
+const RENDERHIVE_APP_DIRECTORY_ARCHIVED_REQUESTS = "data/render_requests/archive/"
+const RENDERHIVE_APP_DIRECTORY_ARCHIVED_OFFERS = "data/render_offers/archive/"
+
+// local path to the evidence documents of raised disputes
+const RENDERHIVE_APP_DIRECTORY_LOCAL_DISPUTES = "data/disputes/local/"
+
+// file name of the result document in the directory of a render result
+const RENDERHIVE_RESULT_DOCUMENT_FILENAME = "result.json"
+
+// directory of the frame previews in a render result directory
+const RENDERHIVE_RESULT_PREVIEW_DIRECTORY = "previews"
+
+// local path to the rendered frames of the render jobs of this node
+const RENDERHIVE_APP_DIRECTORY_RENDER_OUTPUT = "data/render_output/"
+
+// local path to the sample scene and data of the self test
+const RENDERHIVE_APP_DIRECTORY_SELFTEST = "data/selftest/"
+
+// local path to the trusted python setup scripts downloaded from IPFS
+const RENDERHIVE_APP_DIRECTORY_SETUP_SCRIPTS = "data/scripts/"
+
+// local path to the transaction history of this node
+const RENDERHIVE_APP_DIRECTORY_TRANSACTION_HISTORY = "data/transactions/"
+
+// local path to the database of the SQLite render repository
+const RENDERHIVE_APP_DIRECTORY_DATABASE = "data/database/"
+
+// path to the reputation data of the render nodes
+const RENDERHIVE_APP_DIRECTORY_REPUTATION = "data/reputation/"
+
+// local path to the work proof documents of the rendered jobs
+const RENDERHIVE_APP_DIRECTORY_WORK_PROOFS = "data/workproofs/"
+
+// local path to the audit log of the fund-moving operations
+const RENDERHIVE_APP_DIRECTORY_AUDIT = "data/audit/"
+
+// path to the subscription state of the HCS topics
+const RENDERHIVE_APP_DIRECTORY_TOPICS = "data/topics/"
+
+// local path to the IPNS records published by this node
+const RENDERHIVE_APP_DIRECTORY_IPNS = "data/ipns/"
+
 // BLENDER CONSTANTS
 // #############################################################################
+// CIDs of the vetted internal python scripts executed by Blender
+const BLENDER_SCRIPT_INSPECT_RENDER_SETTINGS_CID = "Qmdbo7MwxDTyzyEuMUai7rDjx1ter7Cr9XS89HtNMJr15N"
+const BLENDER_SCRIPT_SCAN_DEPENDENCIES_CID = "QmeyXpbHnCPbTt6A3uNFUxnXc74oggYEWoReh431hNQxkV"
+const BLENDER_SCRIPT_PACK_EXTERNAL_DATA_CID = "QmTaj51LQzomcJaDMnVasEsWNytU4gdNxbWrCkNkJhGuPL"
+const BLENDER_SCRIPT_CREATE_SAMPLE_SCENE_CID = "QmfLECob4xrasg81wuGdiU3Qi4MA85y4FZcDn3QDSkJYCr"
+const BLENDER_SCRIPT_VALIDATE_BLEND_FILE_CID = "QmWLzq8DC2mTuxuwn3BRqKUM9MNcAcnx6C9VESq7Jap6fR"
+
 // Supported render engines
 const (
 
@@ -92,6 +396,9 @@ const (
 
 	// all engine options
 	BLENDER_RENDER_ENGINE_OPTIONS
+
+	// engines added later (appended, so that the values above do not change)
+	BLENDER_RENDER_ENGINE_EEVEE_NEXT
 )
 
 func GetBlenderEngineString(enum []uint8) []string {
@@ -101,11 +408,13 @@ func GetBlenderEngineString(enum []uint8) []string {
 		switch e {
 		case BLENDER_RENDER_ENGINE_EEVEE:
 			result = append(result, "EEVEE")
+		case BLENDER_RENDER_ENGINE_EEVEE_NEXT:
+			result = append(result, "EEVEE_NEXT")
 		case BLENDER_RENDER_ENGINE_CYCLES:
 			result = append(result, "CYCLES")
 
 		case BLENDER_RENDER_ENGINE_OPTIONS:
-			return []string{"EEVEE", "CYCLES"}
+			return []string{"EEVEE", "EEVEE_NEXT", "CYCLES"}
 
 		default:
 			fmt.Println(fmt.Errorf("Engine '%v' not in enumeration.", e))
@@ -119,9 +428,11 @@ func GetBlenderEngineEnum(engines []string) ([]uint8, error) {
 	var result []uint8
 
 	for _, e := range engines {
-		switch e {
+		switch strings.ToUpper(e) {
 		case "EEVEE":
 			result = append(result, BLENDER_RENDER_ENGINE_EEVEE)
+		case "EEVEE_NEXT":
+			result = append(result, BLENDER_RENDER_ENGINE_EEVEE_NEXT)
 		case "CYCLES":
 			result = append(result, BLENDER_RENDER_ENGINE_CYCLES)
 		default:
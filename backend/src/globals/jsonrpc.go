@@ -200,6 +200,23 @@ type AddNodeReply struct {
 	TransactionBytes string
 }
 
+// Method: createNodeTopic
+// #############################################################################
+
+// Arguments and reply
+type CreateNodeTopicArgs struct {
+	ContractID string // the ID of the smart contract
+	Register   bool   // true, if the node shall be registered with the new topic (addNode)
+	NodeStake  string // the amount of HBAR to deposit as node stake (if registered)
+
+	Gas uint64 // the gas limit for the transaction
+}
+type CreateNodeTopicReply struct {
+	Message          string
+	TopicID          string
+	TransactionBytes string // the addNode transaction (if registered)
+}
+
 // Method: removeNode
 // #############################################################################
 
@@ -299,6 +316,22 @@ type AddRenderJobReply struct {
 	TransactionBytes string
 }
 
+// Method: topUpRenderJob
+// #############################################################################
+
+// Arguments and reply
+type TopUpRenderJobArgs struct {
+	ContractID string // the ID of the smart contract
+	JobCID     string // the CID of the render job document
+	Amount     string // the amount of HBAR to add to the funding of the render job
+
+	Gas uint64 // the gas limit for the transaction
+}
+type TopUpRenderJobReply struct {
+	Message          string
+	TransactionBytes string
+}
+
 // Method: claimRenderJob
 // #############################################################################
 
@@ -310,7 +343,7 @@ type ClaimRenderJobArgs struct {
 	NodeCount     uint8  // the number of nodes to claim the job
 	NodeShare     uint64 // the share of work to be rendered by this node (in parts per 10,000 of the total work, i.e. 1% = 100 parts per 10,000)
 	ConsensusRoot string // the root of the consensus merkle tree for the hive cycle
-	JobRoot       string // the root of the job's merkle tree for the hive cycle
+	JobRoot       string // the root of the job's merkle tree (optional: computed from the work proof of the collected render result, must match it if given)
 
 	Gas uint64 // the gas limit for the transaction
 }
@@ -319,6 +352,65 @@ type ClaimRenderJobReply struct {
 	TransactionBytes string
 }
 
+// Method: raiseDispute
+// #############################################################################
+
+// Arguments and reply
+type RaiseDisputeResult struct {
+	OperatorAccountID string         // the account ID of the operator who submitted the result
+	ResultCID         string         // the CID of the render result
+	FrameHashes       map[int]string // the hashes of the rendered frames (by frame number)
+}
+type RaiseDisputeArgs struct {
+	ContractID string               // the ID of the smart contract
+	JobCID     string               // the CID of the render job document
+	Results    []RaiseDisputeResult // the disagreeing render results
+	Reason     string               // the reason for the dispute
+
+	Gas uint64 // the gas limit for the transaction
+}
+type RaiseDisputeReply struct {
+	Message          string
+	EvidenceCID      string
+	TransactionBytes string
+}
+
+// RENDERHIVE SMART CONTRACT – DASHBOARD
+// #############################################################################
+
+// Method: getNodeDashboard
+// #############################################################################
+
+// Arguments and reply
+type GetNodeDashboardArgs struct {
+	ContractID        string // the ID of the smart contract
+	OperatorAccountID string // the account ID of the operator
+	NodeAccountID     string // the account ID of the node (optional)
+
+	Gas uint64 // the gas limit for each read
+}
+type GetNodeDashboardReply struct {
+	Message string
+
+	// registration status
+	IsOperator      bool
+	IsOperatorError string
+	IsNode          bool
+	IsNodeError     string
+
+	// funds (in tinybar)
+	OperatorBalance      *big.Int
+	OperatorBalanceError string
+	ReservedFunds        *big.Int
+	ReservedFundsError   string
+	NodeStake            *big.Int
+	NodeStakeError       string
+
+	// hive cycle
+	HiveCycle      *big.Int
+	HiveCycleError string
+}
+
 // RENDERHIVE OPERATOR SERVICE
 // #############################################################################
 
@@ -436,6 +528,37 @@ type IsSessionValidReply struct {
 	Valid bool
 }
 
+// Method: GetTransactionHistory
+// #############################################################################
+
+// A transaction of the transaction history
+type TransactionHistoryRecord struct {
+	TransactionID      string
+	Type               string
+	Summary            string
+	Executed           bool
+	Status             string
+	Fee                int64 // in tinybar
+	FeeKnown           bool
+	ConsensusTimestamp string
+	CreatedTimestamp   int64 // unix time
+	UpdatedTimestamp   int64 // unix time
+}
+
+// Arguments and reply
+type GetTransactionHistoryArgs struct {
+	Type    string
+	Status  string
+	Search  string
+	Since   int64 // unix time
+	Limit   int
+	Refresh bool // query status and fees of pending transactions from the mirror node
+}
+
+type GetTransactionHistoryReply struct {
+	Transactions []TransactionHistoryRecord
+}
+
 // RENDERHIVE NODE SERVICE – RENDER OFFERS
 // #############################################################################
 
@@ -445,15 +568,19 @@ type IsSessionValidReply struct {
 // Arguments and reply
 type CreateRenderOfferArgs struct {
 	BlenderVersions []struct {
-		Version string
-		Engines []string
-		Devices []string
-		Threads uint8
+		Version     string
+		Engines     []string // render engines (empty = all engines of the version)
+		FeatureSets []string // feature sets of the render engines (empty = default feature set)
+		Devices     []string
+		Threads     uint8
+		Benchmark   bool // render a quick benchmark after adding the version
 	}
-	Price float64
+	Price   float64
+	IPNSKey string // IPNS key pointed at the render offer document (empty = none, "self" = key of the node)
 }
 type CreateRenderOfferReply struct {
-	Message string
+	Message  string
+	IPNSName string // IPNS name of the render offer (if published)
 }
 
 // Method: SubmitRenderOffer
@@ -462,6 +589,14 @@ type CreateRenderOfferReply struct {
 // Arguments and reply
 type SubmitRenderOfferArgs struct {
 	RenderOfferCID string
+	Benchmarks     []RenderOfferBenchmark `json:",omitempty"` // benchmark scores of the offered Blender versions
+}
+
+// Benchmark score of a Blender version and device of a render offer
+type RenderOfferBenchmark struct {
+	Version          string
+	Device           string
+	SamplesPerMinute float64 // sum of the samples per minute of all benchmark scenes
 }
 type SubmitRenderOfferReply struct {
 	Message          string
@@ -480,6 +615,89 @@ type PauseRenderOfferReply struct {
 	TransactionBytes string
 }
 
+// Method: ListRenderOffers
+// #############################################################################
+
+// Filter and page of a render offer or render request listing
+type RenderListArgs struct {
+	State   string // created, submitted, paused, or cancelled
+	Version string // Blender version
+	Owner   string // owner account ID
+	From    int64  // created at or after (unix time)
+	To      int64  // created before (unix time)
+	Offset  int
+	Limit   int // 0 = no limit
+}
+
+// A render offer of the listing
+type RenderOfferListItem struct {
+	DocumentCID      string
+	State            string
+	BlenderVersions  []string
+	Price            float64
+	Owner            string
+	CreatedTimestamp int64 // unix time
+}
+
+// Arguments and reply
+type ListRenderOffersArgs struct {
+	RenderListArgs
+}
+type ListRenderOffersReply struct {
+	Total  int // total number of matching render offers
+	Offers []RenderOfferListItem
+}
+
+// Method: GetOperatorOffers
+// #############################################################################
+
+// A render offer of an operator in the render hive
+type OperatorOfferItem struct {
+	DocumentCID        string
+	BlenderVersions    []string
+	Price              float64
+	CreatedTimestamp   int64 // unix time
+	SubmittedTimestamp int64 // unix time of the last announcement
+}
+
+// Arguments and reply
+type GetOperatorOffersArgs struct {
+	AccountID string // account ID of the operator
+}
+type GetOperatorOffersReply struct {
+	AccountID string
+	Offers    []OperatorOfferItem // active render offers (empty, if there are none)
+}
+
+// Method: GetNetworkCapacity
+// #############################################################################
+
+// Arguments and reply
+type GetNetworkCapacityArgs struct{}
+type GetNetworkCapacityReply struct {
+	Timestamp    int64   // unix time of the snapshot
+	ActiveNodes  int     // operators with at least one active render offer
+	ActiveOffers int     // active render offers
+	StaleOffers  int     // render offers, which were not re-announced in time
+	RatedNodes   int     // active nodes with a known render power
+	RenderPower  float64 // sum of the render power of the rated nodes (in samples per minute)
+	OpenRequests int     // render requests with queued or claimed render jobs
+	QueuedJobs   int     // render jobs waiting in the render hive queue
+	ClaimedJobs  int     // render jobs claimed for rendering
+}
+
+// Method: CancelBenchmark
+// #############################################################################
+
+// Arguments and reply
+type CancelBenchmarkArgs struct {
+	Version string // Blender version of the benchmarks to cancel (empty = all)
+}
+type CancelBenchmarkReply struct {
+	Canceled int // number of canceled benchmarks
+	Message  string
+}
+
 // RENDERHIVE NODE SERVICE – RENDER REQUESTS
 // #############################################################################
 
@@ -498,10 +716,24 @@ type CreateRenderRequestArgs struct {
 		Engine  string
 		Device  string
 	}
-	Price float64
+	Price    float64
+	Pack     bool  // pack all external data into a copy of the .blend file
+	Priority int   // priority of the render request (higher values are rendered first)
+	Deadline int64 // datetime by which all frames must be rendered (unix time, 0 = none)
+
+	// number of frames per subtask, which are rendered independently by different nodes (0 = single job)
+	FramesPerTask int
+
+	// number of region rows and columns each frame is split into, which are rendered by different nodes (0 = full frame)
+	RegionRows    int
+	RegionColumns int
+
+	// CID of a python script executed by Blender before rendering (empty = none)
+	SetupScriptCID string
 }
 type CreateRenderRequestReply struct {
-	Message string
+	Message  string
+	Warnings []string
 }
 
 // Method: SubmitRenderRequest
@@ -511,6 +743,20 @@ type CreateRenderRequestReply struct {
 type SubmitRenderRequestArgs struct {
 	RenderRequestCID string
 	BlenderFileCID   string
+	BlenderVersion   string // Blender version of the render request (empty = not known)
+	Priority         int    // priority of the render request
+	Deadline         int64  // deadline of the render request (unix time, 0 = none)
+	FrameStart       int    // first frame of the render request
+	FrameEnd         int    // last frame of the render request
+	FrameStep        int    // number of frames between two rendered frames
+	FramesPerTask    int    // number of frames per subtask (0 = rendered as a single job)
+	RegionRows       int    `json:",omitempty"` // number of region rows of each frame (0 = full frame)
+	RegionColumns    int    `json:",omitempty"` // number of region columns of each frame (0 = full frame)
+	SetupScriptCID   string `json:",omitempty"` // CID of the python setup script (empty = none)
+	ResolutionX      int    // x resolution of the render result (0 = not known)
+	ResolutionY      int    // y resolution of the render result (0 = not known)
+	Samples          int    // number of samples per pixel (0 = not known)
+	BlenderFileSize  int64  // size of the Blender file in bytes (0 = not known)
 }
 type SubmitRenderRequestReply struct {
 	Message          string
@@ -528,3 +774,239 @@ type CancelRenderRequestReply struct {
 	Message          string
 	TransactionBytes string
 }
+
+// Method: ListRenderRequests
+// #############################################################################
+
+// A render request of the listing
+type RenderRequestListItem struct {
+	DocumentCID      string
+	DirectoryCID     string
+	State            string
+	Version          string
+	BlenderFile      string
+	Price            float64
+	Owner            string
+	CreatedTimestamp int64 // unix time
+}
+
+// Arguments and reply
+type ListRenderRequestsArgs struct {
+	RenderListArgs
+}
+type ListRenderRequestsReply struct {
+	Total    int // total number of matching render requests
+	Requests []RenderRequestListItem
+}
+
+// Method: RankRenderOffers
+// #############################################################################
+
+// A render offer scored for a render request
+type RankedRenderOfferItem struct {
+	DocumentCID      string
+	Owner            string
+	Price            float64
+	Score            float64 // total score (0 ... 1)
+	PriceScore       float64
+	ThroughputScore  float64 // missing score, if no benchmark result is known
+	ReliabilityScore float64 // missing score, if no history of the node is known
+}
+
+// Arguments and reply
+type RankRenderOffersArgs struct {
+	RenderRequestCID string
+	OfferCIDs        []string // render offers to rank (default: all known render offers)
+
+	// weights of the partial scores (default: the configured weights)
+	PriceWeight       *float64
+	ThroughputWeight  *float64
+	ReliabilityWeight *float64
+}
+type RankRenderOffersReply struct {
+	Offers []RankedRenderOfferItem // matching render offers (best offer first)
+}
+
+// Method: GetNodeReputation
+// #############################################################################
+
+// Reputation of a render node
+type NodeReputationItem struct {
+	AccountID         string
+	Completions       float64 // decayed number of submitted render results
+	Releases          float64 // decayed number of released render jobs
+	Disputes          float64 // decayed number of lost disputes
+	CompletionRate    float64 // -1, if unknown
+	DisputeRate       float64 // -1, if unknown
+	AverageTurnaround float64 // in seconds (-1, if unknown)
+	SuccessRate       float64 // used for the offer ranking (neutral for nodes without history)
+	UpdatedTimestamp  int64   // unix time
+}
+
+// Arguments and reply
+type GetNodeReputationArgs struct {
+	AccountIDs []string // nodes to query (default: all known nodes)
+}
+type GetNodeReputationReply struct {
+	Nodes []NodeReputationItem
+}
+
+// Method: VerifyRenderResult
+// #############################################################################
+
+// Verification of a single frame of a render result
+type VerifiedFrameItem struct {
+	Frame    int
+	File     string
+	Declared string // CID declared in the result document
+	Actual   string // CID of the downloaded frame file
+	Passed   bool
+	Reason   string // reason, if the check failed
+}
+
+// Arguments and reply
+type VerifyRenderResultArgs struct {
+	RenderRequestCID string
+	ResultCID        string
+}
+type VerifyRenderResultReply struct {
+	Passed             bool // true, if all checks passed
+	RequestMatches     bool
+	BlenderFileMatches bool
+	Frames             []VerifiedFrameItem
+	MissingFrames      []int // requested frames, which are not part of the result
+}
+
+// Method: AggregateRenderResults
+// #############################################################################
+
+// Arguments and reply
+type AggregateRenderResultsArgs struct {
+	RenderRequestCID string
+}
+type AggregateRenderResultsReply struct {
+	ResultCID string // CID of the aggregated render result directory
+	Frames    int    // number of frames of the aggregated render result
+	Subtasks  int    // number of subtasks of the render request
+	Completed int    // number of completed subtasks
+}
+
+// Method: ReleaseRenderJob
+// #############################################################################
+
+// Arguments and reply
+type ReleaseRenderJobArgs struct {
+	RenderRequestCID string
+	Subtask          int // subtask of the render request (0 = not split)
+	Attempts         int
+	Reason           string
+}
+
+// Method: AnnounceRenderJobClaim
+// #############################################################################
+
+// Arguments and reply
+type AnnounceRenderJobClaimArgs struct {
+	RenderRequestCID string
+	Subtask          int   // subtask of the render request (0 = not split)
+	Deadline         int64 // datetime after which the claiming node abandons the job (unix time)
+}
+
+// Method: SubmitRenderResult
+// #############################################################################
+
+// Arguments and reply
+type SubmitRenderResultArgs struct {
+	RenderRequestCID        string
+	Subtask                 int // subtask of the render request (0 = not split)
+	ResultCID               string
+	SettlementTransactionID string `json:",omitempty"` // transaction ID of the settlement on the smart contract (see claimRenderJob)
+}
+
+// Method: ResolveDispute
+// #############################################################################
+
+// Arguments and reply
+type ResolveDisputeArgs struct {
+	RenderRequestCID  string
+	EvidenceCID       string
+	AcceptedResultCID string
+	Reason            string
+}
+
+// RENDERHIVE NODE SERVICE – BLENDER VERSIONS
+// #############################################################################
+
+// Method: GetSupportedBlenderVersions
+// #############################################################################
+
+// Blender archive of a supported Blender version for a platform
+type SupportedBlenderPlatformItem struct {
+	Platform  string // 'linux', 'windows', or 'macos'
+	Available bool   // true, if the Renderhive archive has a Blender archive for the platform
+	CID       string
+	SHA       string
+	Commit    string
+	Filename  string
+	Size      int64 // size of the archive file in bytes (0 = not known)
+}
+
+// Supported Blender version
+type SupportedBlenderVersionItem struct {
+	Version   string
+	Platforms []SupportedBlenderPlatformItem
+}
+
+// Arguments and reply
+type GetSupportedBlenderVersionsArgs struct {
+	Version string // version to validate (default: list all supported versions)
+}
+type GetSupportedBlenderVersionsReply struct {
+	Versions []SupportedBlenderVersionItem // supported versions (oldest version first)
+}
+
+// RENDERHIVE IPFS SERVICE – PINS
+// #############################################################################
+
+// Method: PinObject
+// #############################################################################
+
+// Arguments and reply
+type PinObjectArgs struct {
+	CID    string
+	Direct bool // pin only the root block (default: recursive)
+}
+type PinObjectReply struct {
+	CID    string
+	Pinned bool // pinning status after the operation
+}
+
+// Method: UnpinObject
+// #############################################################################
+
+// Arguments and reply
+type UnpinObjectArgs struct {
+	CID    string
+	Direct bool // remove a direct pin (default: recursive)
+}
+type UnpinObjectReply struct {
+	CID    string
+	Pinned bool // pinning status after the operation (true, if still pinned indirectly)
+}
+
+// Method: ListPins
+// #############################################################################
+
+// Pinned object
+type PinItem struct {
+	CID  string
+	Type string // 'recursive', 'direct', or 'indirect'
+}
+
+// Arguments and reply
+type ListPinsArgs struct {
+	Type string // 'all', 'recursive', 'direct', or 'indirect' (default: all)
+}
+type ListPinsReply struct {
+	Pins []PinItem
+}
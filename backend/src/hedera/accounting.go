@@ -0,0 +1,387 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+/*
+
+This file contains the cost accounting of the render jobs of this node.
+
+For each render job, the transaction history links the transactions of the job
+(claim, release, and result messages) and records the render time and the
+payout. The margin of a job is its payout minus its costs:
+
+    margin = payout - transaction fees - render hours * electricity cost per hour
+
+The electricity cost per render hour (in HBAR) is a proxy for the running costs
+of the node and can be set in the optional 'accounting.json' file of the
+configuration directory:
+
+    {"electricity_cost_per_hour": 0.5}
+
+Transaction fees are only known, after the transaction history was refreshed
+from the mirror node. Payouts are only known, after the settlement of the job
+was recorded. The report lists how many jobs are missing either of them.
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// Accounting of a render job of this node
+type JobAccount struct {
+	RenderRequestCID   string        `json:"render_request_cid"`
+	Subtask            int           `json:"subtask"`
+	Transactions       []string      `json:"transactions"`        // IDs of the transactions of the job
+	RenderTime         time.Duration `json:"render_time"`         // time between claim and result
+	Payout             int64         `json:"payout"`              // payout of the job in tinybar
+	PayoutKnown        bool          `json:"payout_known"`        // the payout was recorded from the settlement
+	CreatedTimestamp   time.Time     `json:"created_timestamp"`   // the datetime of the first transaction of the job
+	CompletedTimestamp time.Time     `json:"completed_timestamp"` // the datetime the render result was submitted
+}
+
+// Costs and margin of a render job (in tinybar)
+type JobBalance struct {
+	JobAccount
+	Fees        int64 // charged fees of the transactions of the job
+	FeesKnown   bool  // the fees of all transactions are known
+	Electricity int64 // electricity costs of the render time
+	Margin      int64 // payout minus fees and electricity costs
+}
+
+// Summary of the render jobs of a time span (in tinybar)
+type AccountingReport struct {
+	From        time.Time
+	To          time.Time
+	Jobs        int           // number of render jobs
+	Completed   int           // number of completed render jobs
+	RenderTime  time.Duration // total render time
+	Payout      int64         // total payout
+	Fees        int64         // total transaction fees
+	Electricity int64         // total electricity costs
+	Margin      int64         // total margin
+	Unsettled   int           // number of completed jobs without a known payout
+	UnknownFees int           // number of jobs with transactions of unknown fee
+}
+
+// Accounting settings of this node
+type AccountingSettings struct {
+	ElectricityCostPerHour float64 `json:"electricity_cost_per_hour"` // electricity cost of a render hour in HBAR
+}
+
+// JOB ACCOUNTING
+// #############################################################################
+// Get the time of a job account in the report (completion or first transaction)
+func (job *JobAccount) Timestamp() time.Time {
+
+	if !job.CompletedTimestamp.IsZero() {
+		return job.CompletedTimestamp
+	}
+
+	return job.CreatedTimestamp
+
+}
+
+// Link a transaction to the accounting of a render job
+func (history *TransactionHistory) AddJobTransaction(renderRequestCID string, subtask int, transactionID string) {
+
+	// transaction was not recorded
+	if transactionID == "" {
+		return
+	}
+
+	// lock the history
+	history.Mutex.Lock()
+	defer history.Mutex.Unlock()
+
+	job := history._getJob(renderRequestCID, subtask)
+	job.Transactions = append(job.Transactions, transactionID)
+	history._saveJobs()
+
+}
+
+// Get the IDs of the transactions linked to a render job
+func (history *TransactionHistory) JobTransactions(renderRequestCID string, subtask int) []string {
+
+	// lock the history
+	history.Mutex.Lock()
+	defer history.Mutex.Unlock()
+
+	for _, job := range history.Jobs {
+		if job.RenderRequestCID == renderRequestCID && job.Subtask == subtask {
+			return append([]string{}, job.Transactions...)
+		}
+	}
+
+	return []string{}
+
+}
+
+// Record the render time of a completed render job
+// NOTE: A job is completed only once. Later calls (e.g., from the settlement)
+// keep the recorded render time.
+func (history *TransactionHistory) CompleteJob(renderRequestCID string, subtask int, renderTime time.Duration) {
+
+	// lock the history
+	history.Mutex.Lock()
+	defer history.Mutex.Unlock()
+
+	job := history._getJob(renderRequestCID, subtask)
+	if !job.CompletedTimestamp.IsZero() {
+		return
+	}
+	job.RenderTime = renderTime
+	job.CompletedTimestamp = time.Now()
+	history._saveJobs()
+
+}
+
+// Record the payout of a render job from its settlement
+func (history *TransactionHistory) SetJobPayout(renderRequestCID string, subtask int, payout hederasdk.Hbar) {
+
+	// lock the history
+	history.Mutex.Lock()
+	defer history.Mutex.Unlock()
+
+	job := history._getJob(renderRequestCID, subtask)
+	job.Payout = payout.AsTinybar()
+	job.PayoutKnown = true
+	history._saveJobs()
+
+	// record the settlement in the audit log
+	Manager.AuditPayout(renderRequestCID, subtask, payout)
+
+}
+
+// Get the payout of a settlement from its transaction record
+// NOTE: The payout is credited to the account, which settled the render job.
+// The transaction fee paid by this account is added back to its transfers.
+func SettlementPayout(record hederasdk.TransactionRecord) hederasdk.Hbar {
+
+	payer := record.TransactionID.AccountID
+	if payer == nil {
+		return hederasdk.ZeroHbar
+	}
+
+	payout := int64(0)
+	for _, transfer := range record.Transfers {
+		if transfer.AccountID.String() == payer.String() {
+			payout += transfer.Amount.AsTinybar()
+		}
+	}
+	payout += record.TransactionFee.AsTinybar()
+
+	return hederasdk.HbarFromTinybar(payout)
+
+}
+
+// Get the costs and margins of the render jobs of a time span (oldest first)
+// NOTE: A zero time does not limit the time span.
+func (history *TransactionHistory) JobBalances(from time.Time, to time.Time, settings AccountingSettings) []JobBalance {
+	var balances []JobBalance
+
+	// lock the history
+	history.Mutex.Lock()
+	defer history.Mutex.Unlock()
+
+	for _, job := range history.Jobs {
+		if (!from.IsZero() && job.Timestamp().Before(from)) || (!to.IsZero() && !job.Timestamp().Before(to)) {
+			continue
+		}
+
+		// collect the transactions of the job
+		records := []TransactionRecord{}
+		for _, transactionID := range job.Transactions {
+			if record := history._get(transactionID); record != nil {
+				records = append(records, *record)
+			} else {
+				records = append(records, TransactionRecord{TransactionID: transactionID})
+			}
+		}
+
+		balances = append(balances, BalanceJob(*job, records, settings.ElectricityCostPerHour))
+	}
+
+	return balances
+
+}
+
+// Calculate the costs and the margin of a render job
+// NOTE: The electricity cost is given in HBAR per render hour.
+func BalanceJob(job JobAccount, records []TransactionRecord, electricityCostPerHour float64) JobBalance {
+
+	balance := JobBalance{JobAccount: job, FeesKnown: true}
+	for _, record := range records {
+		balance.Fees += record.Fee
+		balance.FeesKnown = balance.FeesKnown && record.FeeKnown
+	}
+	balance.Electricity = hederasdk.HbarFrom(job.RenderTime.Hours()*electricityCostPerHour, hederasdk.HbarUnits.Hbar).AsTinybar()
+	balance.Margin = job.Payout - balance.Fees - balance.Electricity
+
+	return balance
+
+}
+
+// Summarize the costs and margins of render jobs
+func SummarizeJobs(balances []JobBalance, from time.Time, to time.Time) AccountingReport {
+
+	report := AccountingReport{From: from, To: to}
+	for _, balance := range balances {
+		report.Jobs++
+		report.RenderTime += balance.RenderTime
+		report.Payout += balance.Payout
+		report.Fees += balance.Fees
+		report.Electricity += balance.Electricity
+		report.Margin += balance.Margin
+		if !balance.CompletedTimestamp.IsZero() {
+			report.Completed++
+			if !balance.PayoutKnown {
+				report.Unsettled++
+			}
+		}
+		if !balance.FeesKnown {
+			report.UnknownFees++
+		}
+	}
+
+	return report
+
+}
+
+// Get the path of the job accounting file
+func (history *TransactionHistory) JobsPath() string {
+	return filepath.Join(filepath.Dir(history.Path()), "jobs.json")
+}
+
+// Read the accounting settings from the configuration file
+func (settings *AccountingSettings) Read() error {
+	var err error
+
+	// Open the configuration file
+	file, err := os.Open(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "accounting.json"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Read the file content
+	fileData, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	// get the file content into a structure
+	err = json.Unmarshal(fileData, settings)
+	if err != nil {
+		return err
+	}
+
+	return settings.Validate()
+
+}
+
+// Check the accounting settings for invalid values
+func (settings *AccountingSettings) Validate() error {
+
+	if settings.ElectricityCostPerHour < 0 {
+		return errors.New("The electricity cost per hour must not be negative.")
+	}
+
+	return nil
+
+}
+
+// helper function to load the job accounting from the local file
+// NOTE: The caller must hold the mutex.
+func (history *TransactionHistory) _loadJobs() error {
+
+	// read the file (a missing file is an empty accounting)
+	data, err := os.ReadFile(history.JobsPath())
+	if os.IsNotExist(err) {
+		history.Jobs = []*JobAccount{}
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, &history.Jobs)
+
+}
+
+// helper function to get the account of a render job (created, if it does not exist)
+// NOTE: The caller must hold the mutex.
+func (history *TransactionHistory) _getJob(renderRequestCID string, subtask int) *JobAccount {
+
+	for _, job := range history.Jobs {
+		if job.RenderRequestCID == renderRequestCID && job.Subtask == subtask {
+			return job
+		}
+	}
+
+	job := &JobAccount{RenderRequestCID: renderRequestCID, Subtask: subtask, Transactions: []string{}, CreatedTimestamp: time.Now()}
+	history.Jobs = append(history.Jobs, job)
+
+	return job
+
+}
+
+// helper function to write the job accounting to the local file
+// NOTE: The caller must hold the mutex.
+func (history *TransactionHistory) _saveJobs() error {
+	var err error
+
+	// create the directory, if it does not exist
+	err = os.MkdirAll(filepath.Dir(history.JobsPath()), 0700)
+	if err != nil {
+		return err
+	}
+
+	// write the accounting to a temporary file and replace the old file
+	data, err := json.MarshalIndent(history.Jobs, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(history.JobsPath()+".tmp", data, 0600)
+	if err == nil {
+		err = os.Rename(history.JobsPath()+".tmp", history.JobsPath())
+	}
+	if err != nil {
+		logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Could not write the job accounting: %v", err))
+	}
+
+	return err
+
+}
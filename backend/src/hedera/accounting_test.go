@@ -0,0 +1,55 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+import (
+
+	// standard
+	"testing"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+)
+
+func TestSettlementPayout(t *testing.T) {
+	payer, _ := hederasdk.AccountIDFromString("0.0.1001")
+	other, _ := hederasdk.AccountIDFromString("0.0.1002")
+	record := hederasdk.TransactionRecord{
+		TransactionID:  hederasdk.TransactionIDGenerate(payer),
+		TransactionFee: hederasdk.HbarFromTinybar(5),
+		Transfers: []hederasdk.Transfer{
+			{AccountID: payer, Amount: hederasdk.HbarFromTinybar(-5)},
+			{AccountID: payer, Amount: hederasdk.HbarFromTinybar(100)},
+			{AccountID: other, Amount: hederasdk.HbarFromTinybar(-100)},
+		},
+	}
+
+	// the fee of the settling account is not part of the payout
+	if payout := SettlementPayout(record); payout.AsTinybar() != 100 {
+		t.Fatalf("got payout %v, want 100 tinybar", payout.AsTinybar())
+	}
+
+	// a settlement without transfers pays nothing
+	record.Transfers = record.Transfers[:1]
+	if payout := SettlementPayout(record); payout.AsTinybar() != 0 {
+		t.Errorf("got payout %v, want nothing", payout.AsTinybar())
+	}
+}
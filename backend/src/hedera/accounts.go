@@ -27,6 +27,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 
 	// external
 	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
@@ -34,6 +35,7 @@ import (
 	// internal
 	// . "renderhive/globals"
 	"renderhive/logger"
+	"renderhive/metrics"
 	"renderhive/utility"
 )
 
@@ -48,7 +50,10 @@ type HederaAccount struct {
 	PublicKey  hederasdk.PublicKey
 
 	// account information
-	Info hederasdk.AccountInfo
+	// NOTE: The information is updated by the background checks of the node,
+	// so it should be read with GetInfo() or GetBalance().
+	Info      hederasdk.AccountInfo
+	infoMutex sync.RWMutex
 }
 
 // ACCOUNT MANAGEMENT
@@ -65,7 +70,6 @@ func (h *HederaAccount) New(InitialBalance float64) (*hederasdk.TransactionRecei
 	if err != nil {
 		return nil, err
 	}
-	logger.Manager.Package["hedera"].Debug().Msg(fmt.Sprintf("[#] Private key: %v", h.PrivateKey))
 
 	// get the public key
 	h.PublicKey = h.PrivateKey.PublicKey()
@@ -75,9 +79,10 @@ func (h *HederaAccount) New(InitialBalance float64) (*hederasdk.TransactionRecei
 	newAccountTransaction, err := hederasdk.NewAccountCreateTransaction().
 		SetKey(h.PublicKey).
 		SetInitialBalance(hederasdk.HbarFrom(InitialBalance, hederasdk.HbarUnits.Tinybar)).
+		SetMaxTransactionFee(Manager.Fees.TransactionFee()).
 		Execute(Manager.NetworkClient)
 	if err != nil {
-		return nil, err
+		return nil, _feeCapError(err)
 	}
 
 	// Request the receipt of the account creation transaction
@@ -93,6 +98,53 @@ func (h *HederaAccount) New(InitialBalance float64) (*hederasdk.TransactionRecei
 	return &transactionReceipt, nil
 }
 
+// Generate a new key pair of the given type ("ed25519" or "ecdsa")
+func (h *HederaAccount) GenerateKey(keyType string) error {
+	var err error
+
+	// generate the private key
+	switch strings.ToLower(keyType) {
+	case "ed25519":
+		h.PrivateKey, err = hederasdk.PrivateKeyGenerateEd25519()
+	case "ecdsa":
+		h.PrivateKey, err = hederasdk.PrivateKeyGenerateEcdsa()
+	default:
+		return errors.New(fmt.Sprintf("Unknown key type '%v'.", keyType))
+	}
+	if err != nil {
+		return err
+	}
+
+	// derive the public key
+	h.PublicKey = h.PrivateKey.PublicKey()
+
+	// log information
+	logger.Manager.Package["hedera"].Debug().Msg(fmt.Sprintf("Generated a new %v key pair:", strings.ToUpper(keyType)))
+	logger.Manager.Package["hedera"].Debug().Msg(fmt.Sprintf(" [#] Public key: %v", h.PublicKey))
+
+	return nil
+}
+
+// Import an existing private key given in string format
+func (h *HederaAccount) ImportKey(privatekey string) error {
+	var err error
+
+	// parse the private key
+	h.PrivateKey, err = hederasdk.PrivateKeyFromString(privatekey)
+	if err != nil {
+		return errors.New("Could not parse the private key.")
+	}
+
+	// derive the public key
+	h.PublicKey = h.PrivateKey.PublicKey()
+
+	// log information
+	logger.Manager.Package["hedera"].Debug().Msg("Imported an existing private key:")
+	logger.Manager.Package["hedera"].Debug().Msg(fmt.Sprintf(" [#] Public key: %v", h.PublicKey))
+
+	return nil
+}
+
 // Load the private key from a keystore file
 func (h *HederaAccount) FromFile(filepath string, passphrase string, publickey string) error {
 	var err error
@@ -105,7 +157,7 @@ func (h *HederaAccount) FromFile(filepath string, passphrase string, publickey s
 	defer file.Close()
 
 	// load and decrypt the private key
-	h.PrivateKey, err = hederasdk.PrivateKeyReadKeystore(file, passphrase)
+	h.PrivateKey, err = ReadKeystore(file, passphrase)
 	if err != nil {
 		return err
 	}
@@ -122,61 +174,123 @@ func (h *HederaAccount) FromFile(filepath string, passphrase string, publickey s
 }
 
 // Write the private key to a keystore file
+// NOTE: The keystore is encrypted before any file is touched, so that a failed
+// encryption neither leaves an empty keystore nor moves the old one away.
 func (h *HederaAccount) ToFile(filepath string, passphrase string) error {
 	var err error
 
+	// Encrypt the private key
+	keystore, err := EncryptKeystore(h.PrivateKey, passphrase)
+	if err != nil {
+		logger.Manager.Errorln("WriteKeystore Error:", err)
+		return err
+	}
+
+	// Write the keystore to a temporary file first
+	tmpPath := filepath + ".tmp"
+	err = os.WriteFile(tmpPath, keystore, 0600)
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
 	// Rename the original file to create a backup, if already on exists
 	if isFile, _ := utility.IsFile(filepath); isFile {
-		err = os.Rename(filepath, strings.ReplaceAll(filepath, ".key", ".bak"))
+		err = os.Rename(filepath, _backupPath(filepath))
 		if err != nil {
+			os.Remove(tmpPath)
 			return err // Handle the error appropriately.
 		}
 	}
 
-	// Create the keystore file
-	file, err := os.Create(filepath)
+	// Move the keystore into place
+	err = os.Rename(tmpPath, filepath)
 	if err != nil {
+		os.Remove(tmpPath)
 		return err
 	}
-	defer file.Close()
 
-	// Write the keystore data into the file
-	err = h.PrivateKey.WriteKeystore(file, passphrase)
-	if err != nil {
-		fmt.Println("WriteKeystore Error:", err)
-		return err
+	return nil
+}
+
+// helper function to find a backup path for a keystore file, which does not
+// overwrite an older backup
+func _backupPath(filepath string) string {
+
+	// the first backup replaces the file extension
+	backupPath := strings.TrimSuffix(filepath, ".key") + ".bak"
+	if isFile, _ := utility.IsFile(backupPath); !isFile {
+		return backupPath
 	}
 
-	return nil
+	// further backups are numbered
+	for i := 1; ; i++ {
+		numberedPath := fmt.Sprintf("%v.%v", backupPath, i)
+		if isFile, _ := utility.IsFile(numberedPath); !isFile {
+			return numberedPath
+		}
+	}
 }
 
 // Update the public key of a Hedera account
-func (h *HederaAccount) UpdateKey(newKey *hederasdk.PrivateKey) (string, error) {
+// NOTE: The transaction is signed with the current key of the account and the new key
+func (h *HederaAccount) UpdateKey(newKey *hederasdk.PrivateKey, options ...TransactionOption) (*hederasdk.TransactionReceipt, []byte, error) {
 	var err error
+	var transaction interface{}
 
-	// Updating the account with the new key
-	newAccountUpdateTransaction, err := hederasdk.NewAccountUpdateTransaction().
+	// get the settings for the transaction
+	settings, err := MakeTransactionSettings(options...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// update the account with the new key
+	transaction = hederasdk.NewAccountUpdateTransaction().
 		SetAccountID(h.AccountID).
-		// The new key
-		SetKey(newKey.PublicKey()).
-		FreezeWith(Manager.NetworkClient)
+		SetKey(newKey.PublicKey())
+
+	// freeze the transaction for signing
+	transaction, err = _TransactionFreeze(transaction, options...)
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
-	println(newKey.PublicKey().String())
-	println(newKey.String())
 
-	// Have to sign with both keys, the initial key first
-	newAccountUpdateTransaction.Sign(Manager.Operator.PrivateKey)
-	newAccountUpdateTransaction.Sign(*newKey)
+	// have to sign with both keys, the current key first
+	transaction, err = hederasdk.TransactionSign(transaction, h.PrivateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	transaction, err = hederasdk.TransactionSign(transaction, *newKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// add the transaction to the transaction history
+	transactionID := Manager.History.Record(transaction, TRANSACTION_TYPE_ACCOUNT_UPDATE, fmt.Sprintf("key of account %v", h.AccountID), settings.Execute)
+
+	// if the transaction should be directly executed
+	if settings.Execute {
+
+		// submit the transaction to the Hedera network and get its receipt
+		transactionReceipt, err := _TransactionExecute(settings.Context, transaction, transactionID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// log the receipt status of the transaction
+		logger.Manager.Package["hedera"].Trace().Msg(fmt.Sprintf(" [#] Receipt: %s (Status: %s)", transactionReceipt.TransactionID.String(), transactionReceipt.Status))
 
-	// Sign with client operator private key and submit the transaction to the Hedera network
-	_, err = newAccountUpdateTransaction.Execute(Manager.NetworkClient)
+		return transactionReceipt, nil, nil
+
+	}
+
+	// get the transaction bytes
+	transactionBytes, err := hederasdk.TransactionToBytes(transaction)
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
 
-	return "", nil
+	return nil, transactionBytes, err
 
 }
 
@@ -189,7 +303,8 @@ func (h *HederaAccount) QueryInfo(m *PackageManager) (string, error) {
 
 	// Create the account info query
 	newAccountInfoQuery := hederasdk.NewAccountInfoQuery().
-		SetAccountID(h.AccountID)
+		SetAccountID(h.AccountID).
+		SetMaxQueryPayment(Manager.Fees.QueryPayment())
 
 	// get cost of this query
 	cost, err := newAccountInfoQuery.GetCost(Manager.NetworkClient)
@@ -198,9 +313,18 @@ func (h *HederaAccount) QueryInfo(m *PackageManager) (string, error) {
 	}
 
 	// sign with client operator private key and submit the query to a Hedera network
-	h.Info, err = newAccountInfoQuery.Execute(Manager.NetworkClient)
+	info, err := newAccountInfoQuery.Execute(Manager.NetworkClient)
 	if err != nil {
-		return "", err
+		return "", _feeCapError(err)
+	}
+	h.infoMutex.Lock()
+	h.Info = info
+	h.infoMutex.Unlock()
+	Manager.SetAccountBalance(h.AccountID, info.Balance)
+
+	// update the balance metric, if this is the operator account
+	if h.AccountID.String() == Manager.Operator.AccountID.String() {
+		metrics.Manager.SetOperatorBalance(info.Balance.As(hederasdk.HbarUnits.Hbar))
 	}
 
 	return cost.String(), nil
@@ -227,7 +351,35 @@ func (h *HederaAccount) QueryBalance(m *PackageManager) (string, error) {
 	}
 
 	// update the internal balance
+	h.infoMutex.Lock()
 	h.Info.Balance = accountBalance.Hbars
+	h.infoMutex.Unlock()
+	Manager.SetAccountBalance(h.AccountID, accountBalance.Hbars)
+
+	// update the balance metric, if this is the operator account
+	if h.AccountID.String() == Manager.Operator.AccountID.String() {
+		metrics.Manager.SetOperatorBalance(accountBalance.Hbars.As(hederasdk.HbarUnits.Hbar))
+	}
 
 	return cost.String(), nil
 }
+
+// Get the last queried information on this account
+func (h *HederaAccount) GetInfo() hederasdk.AccountInfo {
+
+	h.infoMutex.RLock()
+	defer h.infoMutex.RUnlock()
+
+	return h.Info
+
+}
+
+// Get the last queried balance of this account
+func (h *HederaAccount) GetBalance() hederasdk.Hbar {
+
+	h.infoMutex.RLock()
+	defer h.infoMutex.RUnlock()
+
+	return h.Info.Balance
+
+}
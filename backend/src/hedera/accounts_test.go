@@ -0,0 +1,129 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+import (
+
+	// standard
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	"renderhive/logger"
+)
+
+func TestBackupPathKeepsOlderBackups(t *testing.T) {
+	dir := t.TempDir()
+	keystorePath := filepath.Join(dir, "0.0.1001.key")
+
+	// the first backup replaces the extension
+	if got := _backupPath(keystorePath); got != filepath.Join(dir, "0.0.1001.bak") {
+		t.Fatalf("unexpected first backup path: %v", got)
+	}
+
+	// an existing backup is never reused
+	for i := 0; i < 3; i++ {
+		path := _backupPath(keystorePath)
+		if err := os.WriteFile(path, []byte(fmt.Sprint(i)), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := _backupPath(keystorePath); got != filepath.Join(dir, "0.0.1001.bak.3") {
+		t.Fatalf("unexpected numbered backup path: %v", got)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "0.0.1001.bak"))
+	if err != nil || string(data) != "0" {
+		t.Fatalf("the first backup was overwritten: %q, %v", data, err)
+	}
+}
+
+func TestKeyUpdateFailed(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"precheck", hederasdk.ErrHederaPreCheckStatus{Status: hederasdk.StatusInvalidSignature}, true},
+		{"receipt failure", hederasdk.ErrHederaReceiptStatus{Status: hederasdk.StatusInvalidSignature}, true},
+		{"wrapped receipt failure", fmt.Errorf("update: %w", hederasdk.ErrHederaReceiptStatus{Status: hederasdk.StatusInsufficientPayerBalance}), true},
+		{"timeout", errors.New("context deadline exceeded"), false},
+		{"network", errors.New("max attempts exceeded"), false},
+	}
+	for _, test := range tests {
+		if got := _keyUpdateFailed(test.err); got != test.want {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestUpdateKeyIsRecordedAndBounded(t *testing.T) {
+	defaultClient, defaultFees := Manager.NetworkClient, Manager.Fees
+	t.Cleanup(func() { Manager.NetworkClient, Manager.Fees = defaultClient, defaultFees })
+
+	key, err := hederasdk.PrivateKeyGenerateEd25519()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := hederasdk.PrivateKeyGenerateEd25519()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeAccount, _ := hederasdk.AccountIDFromString("0.0.3")
+	operator, _ := hederasdk.AccountIDFromString("0.0.1001")
+	Manager.NetworkClient = hederasdk.ClientForNetwork(map[string]hederasdk.AccountID{"127.0.0.1:50211": nodeAccount})
+	Manager.NetworkClient.SetOperator(operator, key)
+	Manager.Fees = DefaultFeeLimits()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+	if err := Manager.History.Load(); err != nil {
+		t.Fatal(err)
+	}
+	account := &HederaAccount{AccountID: operator, PrivateKey: key, PublicKey: key.PublicKey()}
+
+	// the transaction bytes for signing are recorded in the transaction history
+	receipt, transactionBytes, err := account.UpdateKey(&newKey, TransactionOptions.SetExecute(false, operator))
+	if err != nil || receipt != nil || len(transactionBytes) == 0 {
+		t.Fatalf("got receipt %v and %v bytes (%v), want the transaction bytes only", receipt, len(transactionBytes), err)
+	}
+	records := Manager.History.List(TransactionFilter{Type: TRANSACTION_TYPE_ACCOUNT_UPDATE})
+	if len(records) != 1 || records[0].Executed || records[0].Summary != "key of account 0.0.1001" {
+		t.Fatalf("got the records %+v, want one record of the key update", records)
+	}
+
+	// the execution gives up, when the context is done (but the transaction is still recorded)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	receipt, _, err = account.UpdateKey(&newKey, TransactionOptions.SetContext(ctx))
+	if receipt != nil || !errors.Is(err, context.Canceled) {
+		t.Fatalf("got receipt %v (%v), want to give up with the context", receipt, err)
+	}
+	records = Manager.History.List(TransactionFilter{Type: TRANSACTION_TYPE_ACCOUNT_UPDATE})
+	if len(records) != 2 || !records[0].Executed || records[0].Status != TRANSACTION_STATUS_PENDING {
+		t.Errorf("got the records %+v, want a pending record of the executed key update", records)
+	}
+}
@@ -0,0 +1,664 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+/*
+
+This file contains the audit log of the fund-moving operations of this node.
+
+Every deposit, withdrawal, and stake change executed on the network and every
+payout of a settlement is appended to the audit log, which is separate from the
+general log. The smart contract service only prepares the transactions, which
+are signed and executed by the wallet. Therefore, a prepared transaction waits
+in a pending file (outside of the hash chain), until the transaction history
+learns its outcome from the mirror node. Only then, it is appended to the audit
+log with its Hedera status. Transactions that could not be prepared, or that
+were never executed, do not move funds and are not audited.
+
+The audit log is append-only: each entry is a JSON line, which contains the hash
+of the previous entry, and its own hash over all of its fields:
+
+    hash = sha256(JSON of the entry without its hash)
+
+The hash and sequence number of the last entry are kept in a separate head
+file. Therefore, a modified, removed, or reordered entry breaks the chain, and
+removed entries at the end do not match the head anymore. 'hedera audit verify'
+checks the chain.
+
+A local rewrite of the whole log and its head would still be a valid chain.
+Therefore, the head is anchored with an HMAC, whose key is derived from the
+private key of the operator account:
+
+    mac = hmac-sha256(sha256("renderhive audit log" || private key), sequence:hash)
+
+Without the (encrypted) private key, the head cannot be forged. The head is
+only checked against the key, when the operator account is loaded.
+
+*/
+
+import (
+
+	// standard
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+	"github.com/spf13/cobra"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+	"renderhive/utility"
+)
+
+// fund-moving operations of the audit log
+const (
+	AUDIT_OPERATION_DEPOSIT          = "deposit"          // deposit of operator funds
+	AUDIT_OPERATION_WITHDRAWAL       = "withdrawal"       // withdrawal of operator funds
+	AUDIT_OPERATION_STAKE_DEPOSIT    = "stake_deposit"    // deposit of a node stake
+	AUDIT_OPERATION_STAKE_WITHDRAWAL = "stake_withdrawal" // withdrawal of a node stake
+	AUDIT_OPERATION_PAYOUT           = "payout"           // payout of a render job from its settlement
+	AUDIT_OPERATION_JOB_FUNDING      = "job_funding"      // funding of a new render job of this node
+	AUDIT_OPERATION_JOB_TOP_UP       = "job_top_up"       // additional funding of an existing render job of this node
+)
+
+// outcomes of the audit log (other outcomes are the Hedera status codes)
+const (
+	AUDIT_OUTCOME_SETTLED = "SETTLED" // the payout was recorded from the settlement
+)
+
+// hash of the (not existing) entry before the first entry
+var auditGenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// An entry of the audit log
+type AuditEntry struct {
+	Sequence      int       `json:"sequence"`                 // number of the entry (starting at 1)
+	Timestamp     time.Time `json:"timestamp"`                // the datetime the entry was appended
+	Operation     string    `json:"operation"`                // AUDIT_OPERATION_*
+	Amount        int64     `json:"amount"`                   // amount in tinybar (0, if not known, e.g., a complete stake withdrawal)
+	Account       string    `json:"account"`                  // account ID of the operator or node
+	TransactionID string    `json:"transaction_id,omitempty"` // ID of the Hedera transaction (if any)
+	Outcome       string    `json:"outcome"`                  // AUDIT_OUTCOME_* or the Hedera status code
+	Details       string    `json:"details,omitempty"`        // e.g., the error or the render job
+	PreviousHash  string    `json:"previous_hash"`            // hash of the previous entry
+	Hash          string    `json:"hash"`                     // hash of this entry
+}
+
+// Head of the audit log (last entry)
+type auditHead struct {
+	Sequence int    `json:"sequence"`
+	Hash     string `json:"hash"`
+	MAC      string `json:"mac,omitempty"` // HMAC of the head with the key of the operator account
+}
+
+// Audit log of this node
+type AuditLog struct {
+	Mutex    sync.Mutex
+	head     auditHead
+	pending  map[string]AuditEntry // prepared transactions without outcome (by transaction ID)
+	loaded   bool
+	key      []byte // HMAC key of the head (nil, if the operator account is not loaded)
+	Location string // directory of the audit log (default: the app data directory)
+}
+
+// AUDIT LOG
+// #############################################################################
+// Get the path of the audit log file
+func (audit *AuditLog) Path() string {
+
+	if audit.Location != "" {
+		return filepath.Join(audit.Location, "audit.log")
+	}
+
+	return filepath.Join(utility.GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_AUDIT, "audit.log")
+
+}
+
+// Set the key of the head from the private key of the operator account
+func (audit *AuditLog) SetKey(privateKey hederasdk.PrivateKey) {
+
+	// lock the audit log
+	audit.Mutex.Lock()
+	defer audit.Mutex.Unlock()
+
+	sum := sha256.Sum256(append([]byte("renderhive audit log"), privateKey.Bytes()...))
+	audit.key = sum[:]
+
+}
+
+// Check if the head of the audit log is anchored with the key of the operator account
+func (audit *AuditLog) Anchored() bool {
+
+	// lock the audit log
+	audit.Mutex.Lock()
+	defer audit.Mutex.Unlock()
+
+	return audit.key != nil
+
+}
+
+// Load the head of the audit log and the prepared transactions without outcome
+func (audit *AuditLog) Load() error {
+
+	// lock the audit log
+	audit.Mutex.Lock()
+	defer audit.Mutex.Unlock()
+
+	return audit._load()
+
+}
+
+// Append an entry to the audit log
+// NOTE: The sequence number, timestamp, and hashes are set by the audit log.
+func (audit *AuditLog) Append(entry AuditEntry) (AuditEntry, error) {
+
+	// lock the audit log
+	audit.Mutex.Lock()
+	defer audit.Mutex.Unlock()
+
+	if !audit.loaded {
+		err := audit._load()
+		if err != nil {
+			return entry, err
+		}
+	}
+
+	return audit._append(entry)
+
+}
+
+// helper function to append an entry to the audit log
+// NOTE: The caller must hold the mutex.
+func (audit *AuditLog) _append(entry AuditEntry) (AuditEntry, error) {
+
+	// chain the entry to the previous entry
+	entry.Sequence = audit.head.Sequence + 1
+	entry.Timestamp = time.Now().UTC()
+	entry.PreviousHash = audit.head.Hash
+	hash, err := entry.ComputeHash()
+	if err != nil {
+		return entry, err
+	}
+	entry.Hash = hash
+
+	// append the entry
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return entry, err
+	}
+	err = os.MkdirAll(filepath.Dir(audit.Path()), 0700)
+	if err != nil {
+		return entry, err
+	}
+	file, err := os.OpenFile(audit.Path(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return entry, err
+	}
+	_, err = file.Write(append(data, '\n'))
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return entry, fmt.Errorf("Could not append to the audit log: %v", err)
+	}
+
+	// update the head
+	audit.head = auditHead{Sequence: entry.Sequence, Hash: entry.Hash}
+	err = audit._saveHead()
+	if err != nil {
+		return entry, err
+	}
+
+	return entry, nil
+
+}
+
+// Keep a prepared transaction until its outcome is known
+// NOTE: The transaction is not part of the hash chain until it was executed.
+func (audit *AuditLog) Prepare(entry AuditEntry) error {
+
+	// lock the audit log
+	audit.Mutex.Lock()
+	defer audit.Mutex.Unlock()
+
+	if !audit.loaded {
+		err := audit._load()
+		if err != nil {
+			return err
+		}
+	}
+
+	entry.Timestamp = time.Now().UTC()
+	audit.pending[entry.TransactionID] = entry
+
+	return audit._savePending()
+
+}
+
+// Append the outcome of a prepared transaction to the audit log
+// NOTE: Returns false, if the transaction was not prepared or was already audited.
+func (audit *AuditLog) Complete(transactionID string, outcome string) (AuditEntry, bool, error) {
+
+	// lock the audit log
+	audit.Mutex.Lock()
+	defer audit.Mutex.Unlock()
+
+	if !audit.loaded {
+		err := audit._load()
+		if err != nil {
+			return AuditEntry{}, false, err
+		}
+	}
+
+	prepared, ok := audit.pending[transactionID]
+	if !ok {
+		return AuditEntry{}, false, nil
+	}
+	prepared.Outcome = outcome
+	entry, err := audit._append(prepared)
+	if err != nil {
+		return entry, true, err
+	}
+	delete(audit.pending, transactionID)
+
+	return entry, true, audit._savePending()
+
+}
+
+// Check the hash chain of the audit log
+// NOTE: Returns the number of valid entries and an error at the first broken entry.
+func (audit *AuditLog) Verify() (int, error) {
+
+	// lock the audit log
+	audit.Mutex.Lock()
+	defer audit.Mutex.Unlock()
+
+	entries, err := audit._read(true)
+	if err != nil {
+		return len(entries), err
+	}
+
+	// the last entry must be the head
+	head, err := audit._readHead()
+	if err != nil {
+		return len(entries), err
+	}
+	last := auditHead{Hash: auditGenesisHash}
+	if len(entries) > 0 {
+		last = auditHead{Sequence: entries[len(entries)-1].Sequence, Hash: entries[len(entries)-1].Hash}
+	}
+	if last.Sequence != head.Sequence || last.Hash != head.Hash {
+		return len(entries), fmt.Errorf("The audit log ends with entry %v, but the head is entry %v. Entries were removed or appended without the audit log.", last.Sequence, head.Sequence)
+	}
+
+	// the head must be anchored with the key of the operator account
+	if audit.key != nil && head.Sequence > 0 && !hmac.Equal([]byte(head.MAC), []byte(audit._mac(head))) {
+		return 0, fmt.Errorf("The head of the audit log was not written with the key of the operator account. The audit log was rewritten or written with another account.")
+	}
+
+	return len(entries), nil
+
+}
+
+// Compute the hash of the entry (without its hash field)
+func (entry AuditEntry) ComputeHash() (string, error) {
+
+	entry.Hash = ""
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+
+}
+
+// helper function to load the audit log
+// NOTE: The caller must hold the mutex.
+func (audit *AuditLog) _load() error {
+
+	head, err := audit._readHead()
+	if err != nil {
+		return err
+	}
+	audit.head = head
+
+	// get the prepared transactions without outcome
+	audit.pending = make(map[string]AuditEntry)
+	data, err := os.ReadFile(audit._pendingPath())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &audit.pending)
+		if err != nil {
+			return fmt.Errorf("Invalid pending transactions of the audit log: %v", err)
+		}
+	}
+	audit.loaded = true
+
+	return nil
+
+}
+
+// helper function to read the entries of the audit log (optionally checking the chain)
+// NOTE: The caller must hold the mutex.
+func (audit *AuditLog) _read(verify bool) ([]AuditEntry, error) {
+	var entries []AuditEntry
+
+	file, err := os.Open(audit.Path())
+	if os.IsNotExist(err) {
+		return entries, nil
+	} else if err != nil {
+		return entries, err
+	}
+	defer file.Close()
+
+	previous := auditGenesisHash
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var entry AuditEntry
+		err = json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			return entries, fmt.Errorf("Line %v of the audit log is not a valid entry: %v", line, err)
+		}
+
+		// check the chain
+		if verify {
+			if entry.Sequence != len(entries)+1 {
+				return entries, fmt.Errorf("Entry %v of the audit log has the sequence number %v.", len(entries)+1, entry.Sequence)
+			}
+			if entry.PreviousHash != previous {
+				return entries, fmt.Errorf("Entry %v of the audit log does not link to the previous entry.", entry.Sequence)
+			}
+			hash, err := entry.ComputeHash()
+			if err != nil {
+				return entries, err
+			}
+			if hash != entry.Hash {
+				return entries, fmt.Errorf("Entry %v of the audit log was modified.", entry.Sequence)
+			}
+			previous = entry.Hash
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+
+}
+
+// helper function to get the path of the head file
+func (audit *AuditLog) _headPath() string {
+	return audit.Path() + ".head"
+}
+
+// helper function to read the head of the audit log (genesis, if the log is empty)
+func (audit *AuditLog) _readHead() (auditHead, error) {
+
+	head := auditHead{Hash: auditGenesisHash}
+	data, err := os.ReadFile(audit._headPath())
+	if os.IsNotExist(err) {
+		return head, nil
+	} else if err != nil {
+		return head, err
+	}
+	err = json.Unmarshal(data, &head)
+	if err != nil {
+		return head, fmt.Errorf("Invalid head of the audit log: %v", err)
+	}
+
+	return head, nil
+
+}
+
+// helper function to get the path of the prepared transactions without outcome
+func (audit *AuditLog) _pendingPath() string {
+	return audit.Path() + ".pending"
+}
+
+// helper function to write the prepared transactions without outcome
+// NOTE: The caller must hold the mutex.
+func (audit *AuditLog) _savePending() error {
+
+	data, err := json.Marshal(audit.pending)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(audit._pendingPath()), 0700)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(audit._pendingPath()+".tmp", data, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(audit._pendingPath()+".tmp", audit._pendingPath())
+
+}
+
+// helper function to compute the HMAC of a head with the key of the operator account
+// NOTE: The caller must hold the mutex.
+func (audit *AuditLog) _mac(head auditHead) string {
+
+	mac := hmac.New(sha256.New, audit.key)
+	fmt.Fprintf(mac, "%v:%v", head.Sequence, head.Hash)
+
+	return hex.EncodeToString(mac.Sum(nil))
+
+}
+
+// helper function to write the head of the audit log
+// NOTE: The caller must hold the mutex.
+func (audit *AuditLog) _saveHead() error {
+
+	audit.head.MAC = ""
+	if audit.key != nil {
+		audit.head.MAC = audit._mac(audit.head)
+	}
+	data, err := json.Marshal(audit.head)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(audit._headPath()+".tmp", data, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(audit._headPath()+".tmp", audit._headPath())
+
+}
+
+// AUDITED OPERATIONS
+// #############################################################################
+// Record a fund-moving transaction, which was prepared for signing
+// NOTE: The amount is given in HBAR (e.g., "10" or "10 ℏ"; empty, if not known).
+// The transaction is appended to the audit log, when its outcome is known.
+func (hm *PackageManager) AuditTransaction(operation string, amount string, account string, transactionBytes []byte, err error) {
+	hm._auditTransaction(operation, amount, account, "", transactionBytes, err)
+}
+
+// Record the funding transaction of a render job (see AuditTransaction)
+func (hm *PackageManager) AuditJobFunding(operation string, renderRequestCID string, amount string, account string, transactionBytes []byte, err error) {
+	hm._auditTransaction(operation, amount, account, fmt.Sprintf("render request %v", renderRequestCID), transactionBytes, err)
+}
+
+// helper function to record a transaction of a fund-moving operation
+func (hm *PackageManager) _auditTransaction(operation string, amount string, account string, details string, transactionBytes []byte, err error) {
+
+	// a transaction, which could not be prepared, does not move funds
+	if err != nil {
+		return
+	}
+
+	entry := AuditEntry{
+		Operation: operation,
+		Account:   account,
+		Details:   details,
+	}
+	if amount != "" {
+		if hbar, parseErr := hederasdk.HbarFromString(amount); parseErr == nil {
+			entry.Amount = hbar.AsTinybar()
+		} else {
+			entry.Details = fmt.Sprintf("invalid amount '%v'", amount)
+		}
+	}
+	entry.TransactionID, err = TransactionIDFromBytes(transactionBytes)
+	if err != nil {
+		logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Could not audit the %v of account '%v': %v", operation, account, err))
+		return
+	}
+
+	err = hm.Audit.Prepare(entry)
+	if err != nil {
+		logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Could not keep the %v of account '%v' for the audit log: %v", operation, account, err))
+	}
+
+}
+
+// Record the payout of a render job from its settlement
+func (hm *PackageManager) AuditPayout(renderRequestCID string, subtask int, payout hederasdk.Hbar) {
+
+	hm._audit(AuditEntry{
+		Operation: AUDIT_OPERATION_PAYOUT,
+		Amount:    payout.AsTinybar(),
+		Account:   hm.Operator.AccountID.String(),
+		Outcome:   AUDIT_OUTCOME_SETTLED,
+		Details:   fmt.Sprintf("render request %v (subtask: %v)", renderRequestCID, subtask),
+	})
+
+}
+
+// helper function to record the outcome of a prepared transaction
+func (hm *PackageManager) _auditOutcome(transactionID string, status string) {
+
+	if status == TRANSACTION_STATUS_PENDING {
+		return
+	}
+
+	entry, ok, err := hm.Audit.Complete(transactionID, status)
+	if err != nil {
+		logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Could not record the %v of account '%v' in the audit log: %v", entry.Operation, entry.Account, err))
+		return
+	}
+	if !ok {
+		return
+	}
+
+	// log event
+	logger.Manager.Package["hedera"].Debug().Msg(fmt.Sprintf("Recorded the %v of account '%v' in the audit log (entry: %v, outcome: %v).", entry.Operation, entry.Account, entry.Sequence, entry.Outcome))
+
+}
+
+// helper function to append an entry to the audit log of this node
+func (hm *PackageManager) _audit(entry AuditEntry) {
+
+	entry, err := hm.Audit.Append(entry)
+	if err != nil {
+		logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Could not record the %v of account '%v' in the audit log: %v", entry.Operation, entry.Account, err))
+		return
+	}
+
+	// log event
+	logger.Manager.Package["hedera"].Debug().Msg(fmt.Sprintf("Recorded the %v of account '%v' in the audit log (entry: %v, outcome: %v).", entry.Operation, entry.Account, entry.Sequence, entry.Outcome))
+
+}
+
+// COMMAND LINE INTERFACE - AUDIT LOG
+// #############################################################################
+// Create the CLI command of the audit log
+func (hm *PackageManager) CreateCommandAudit() *cobra.Command {
+
+	// create an 'audit' command
+	command := &cobra.Command{
+		Use:   "audit",
+		Short: "Manage the audit log of the fund-moving operations",
+		Long:  "This command is for the audit log, which records the deposits, withdrawals, stake changes, and payouts of this node in a hash chain.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			return nil
+
+		},
+	}
+
+	// add the subcommands
+	command.AddCommand(hm.CreateCommandAudit_Verify())
+
+	return command
+
+}
+
+// Create the CLI command to check the hash chain of the audit log
+func (hm *PackageManager) CreateCommandAudit_Verify() *cobra.Command {
+
+	// create a 'verify' command
+	command := &cobra.Command{
+		Use:   "verify",
+		Short: "Check the integrity of the audit log",
+		Long:  "This command checks the hash chain of the audit log and reports the first entry, which was modified, removed, or reordered.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			entries, err := hm.Audit.Verify()
+			if err != nil {
+
+				logger.Manager.Println("")
+				logger.Manager.Printf("The first %v entries of the audit log are intact.\n", entries)
+				return fmt.Errorf("The audit log was tampered with: %w", err)
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Printf("The audit log is intact (%v entries).\n", entries)
+			logger.Manager.Resultf(" [#] Path: %v\n", hm.Audit.Path())
+			if !hm.Audit.Anchored() {
+				logger.Manager.Println("The head was not checked against the operator key, because no operator account is loaded.")
+			}
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	return command
+
+}
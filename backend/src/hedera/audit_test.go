@@ -0,0 +1,175 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+import (
+
+	// standard
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	"renderhive/logger"
+)
+
+// helper function to create an audit log with three entries in a temporary directory
+func _testAuditLog(t *testing.T, key hederasdk.PrivateKey) *AuditLog {
+	t.Helper()
+	logger.Manager.Init()
+
+	audit := &AuditLog{Location: t.TempDir()}
+	audit.SetKey(key)
+	for _, operation := range []string{AUDIT_OPERATION_DEPOSIT, AUDIT_OPERATION_STAKE_DEPOSIT, AUDIT_OPERATION_PAYOUT} {
+		_, err := audit.Append(AuditEntry{Operation: operation, Amount: 100, Account: "0.0.1001", Outcome: "SUCCESS"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return audit
+}
+
+func TestAuditLogAppendAndVerify(t *testing.T) {
+	key, _ := hederasdk.PrivateKeyGenerateEd25519()
+	audit := _testAuditLog(t, key)
+
+	entries, err := audit.Verify()
+	if err != nil || entries != 3 {
+		t.Fatalf("got %v entries (%v), want 3 intact entries", entries, err)
+	}
+
+	// a reloaded audit log continues the chain
+	reloaded := &AuditLog{Location: audit.Location}
+	reloaded.SetKey(key)
+	entry, err := reloaded.Append(AuditEntry{Operation: AUDIT_OPERATION_WITHDRAWAL, Account: "0.0.1001", Outcome: "SUCCESS"})
+	if err != nil || entry.Sequence != 4 {
+		t.Fatalf("got entry %v (%v), want entry 4", entry.Sequence, err)
+	}
+	if entries, err = reloaded.Verify(); err != nil || entries != 4 {
+		t.Errorf("got %v entries (%v), want 4 intact entries", entries, err)
+	}
+}
+
+func TestAuditLogDetectsTampering(t *testing.T) {
+	key, _ := hederasdk.PrivateKeyGenerateEd25519()
+
+	// a modified amount breaks the hash of the entry
+	audit := _testAuditLog(t, key)
+	data, _ := os.ReadFile(audit.Path())
+	os.WriteFile(audit.Path(), []byte(strings.Replace(string(data), `"amount":100`, `"amount":1`, 1)), 0600)
+	if entries, err := audit.Verify(); err == nil || entries != 0 {
+		t.Errorf("got %v intact entries (%v), want a modified first entry", entries, err)
+	}
+
+	// a removed last entry does not match the head
+	audit = _testAuditLog(t, key)
+	data, _ = os.ReadFile(audit.Path())
+	lines := strings.SplitAfter(string(data), "\n")
+	os.WriteFile(audit.Path(), []byte(strings.Join(lines[:2], "")), 0600)
+	if _, err := audit.Verify(); err == nil {
+		t.Error("a removed entry must be detected")
+	}
+}
+
+func TestAuditLogDetectsRewrite(t *testing.T) {
+	key, _ := hederasdk.PrivateKeyGenerateEd25519()
+	audit := _testAuditLog(t, key)
+
+	// a complete rewrite without the operator key is a valid chain
+	rewritten := &AuditLog{Location: audit.Location}
+	os.Remove(audit.Path())
+	os.Remove(audit._headPath())
+	_, err := rewritten.Append(AuditEntry{Operation: AUDIT_OPERATION_DEPOSIT, Amount: 1, Account: "0.0.1001", Outcome: "SUCCESS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = rewritten.Verify(); err != nil {
+		t.Fatalf("the chain of the rewritten audit log must be valid: %v", err)
+	}
+
+	// but its head is not anchored with the operator key
+	if _, err = audit.Verify(); err == nil {
+		t.Error("the rewritten audit log must be detected with the operator key")
+	}
+
+	// nor with the key of another account
+	other, _ := hederasdk.PrivateKeyGenerateEd25519()
+	audit = _testAuditLog(t, key)
+	audit.SetKey(other)
+	if _, err = audit.Verify(); err == nil {
+		t.Error("the head must not be valid with the key of another account")
+	}
+}
+
+func TestAuditLogRecordsOnlyExecutedTransactions(t *testing.T) {
+	logger.Manager.Init()
+	audit := &AuditLog{Location: t.TempDir()}
+
+	// a prepared transaction is not part of the audit log
+	err := audit.Prepare(AuditEntry{Operation: AUDIT_OPERATION_DEPOSIT, Amount: 100, Account: "0.0.1001", TransactionID: "0.0.1001@1700000000.000000000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := audit.Verify(); err != nil || entries != 0 {
+		t.Fatalf("got %v entries (%v), want no entry before the outcome is known", entries, err)
+	}
+
+	// the outcome is appended, after the transaction was executed (also after a restart)
+	reloaded := &AuditLog{Location: audit.Location}
+	entry, ok, err := reloaded.Complete("0.0.1001@1700000000.000000000", "SUCCESS")
+	if err != nil || !ok {
+		t.Fatalf("got %v (%v), want the prepared transaction to be audited", ok, err)
+	}
+	if entry.Sequence != 1 || entry.Outcome != "SUCCESS" || entry.Amount != 100 || entry.Operation != AUDIT_OPERATION_DEPOSIT {
+		t.Errorf("got the entry %+v", entry)
+	}
+
+	// the transaction is audited only once, and unknown transactions not at all
+	if _, ok, _ := reloaded.Complete("0.0.1001@1700000000.000000000", "SUCCESS"); ok {
+		t.Error("the transaction must only be audited once")
+	}
+	if _, ok, _ := reloaded.Complete("0.0.1001@1700000001.000000000", "SUCCESS"); ok {
+		t.Error("a transaction, which was not prepared, must not be audited")
+	}
+	if entries, err := reloaded.Verify(); err != nil || entries != 1 {
+		t.Errorf("got %v entries (%v), want 1 intact entry", entries, err)
+	}
+}
+
+func TestAuditTransactionSkipsUnpreparedTransactions(t *testing.T) {
+	logger.Manager.Init()
+	hm := &PackageManager{}
+	hm.Audit.Location = t.TempDir()
+
+	// a transaction, which could not be prepared, does not move funds
+	hm.AuditTransaction(AUDIT_OPERATION_WITHDRAWAL, "10", "0.0.1001", nil, errors.New("insufficient balance"))
+	if _, err := os.Stat(hm.Audit._pendingPath()); !os.IsNotExist(err) {
+		t.Errorf("got %v, want no prepared transaction", err)
+	}
+	if entries, err := hm.Audit.Verify(); err != nil || entries != 0 {
+		t.Errorf("got %v entries (%v), want no entry", entries, err)
+	}
+}
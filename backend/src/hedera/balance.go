@@ -0,0 +1,164 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+/*
+
+This file contains the balance check of payable contract calls.
+
+A payable contract call (e.g., a deposit of operator funds, a node stake, or
+the funding of a render job) fails on-chain, if the paying account cannot
+afford it, and the fee of the failed transaction is charged anyway. Therefore,
+the balance of the paying account is checked, before the transaction is built:
+
+    required = payable amount + maximum transaction fee
+
+The gas of a contract call is paid from the transaction fee, so the fee cap of
+the node (see fees.go) is an upper bound of the gas and fee costs. The balance
+is taken from the mirror node and reused for RENDERHIVE_CONFIG_BALANCE_CACHE_DURATION.
+If the balance cannot be obtained, the call is not blocked.
+
+*/
+
+import (
+
+	// standard
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// Error of a payable call, which the paying account cannot afford
+type InsufficientBalanceError struct {
+	AccountID string         // account ID of the paying account
+	Balance   hederasdk.Hbar // balance of the paying account
+	Amount    hederasdk.Hbar // payable amount of the call
+	Fee       hederasdk.Hbar // maximum transaction fee (including the gas)
+}
+
+// Recently queried account balances
+type balanceCache struct {
+	mutex   sync.Mutex
+	entries map[string]cachedBalance
+}
+
+// Account balance and the datetime it was queried
+type cachedBalance struct {
+	balance   hederasdk.Hbar
+	timestamp time.Time
+}
+
+// BALANCE CHECK
+// #############################################################################
+// Get the missing amount of the paying account
+func (e *InsufficientBalanceError) Shortfall() hederasdk.Hbar {
+	return hederasdk.HbarFromTinybar(e.Amount.AsTinybar() + e.Fee.AsTinybar() - e.Balance.AsTinybar())
+}
+
+// Get the error message with the shortfall
+func (e *InsufficientBalanceError) Error() string {
+	return fmt.Sprintf("Insufficient balance: The account %v has %v, but the call needs up to %v (%v plus a maximum fee of %v). Add at least %v to the account.", e.AccountID, e.Balance, hederasdk.HbarFromTinybar(e.Amount.AsTinybar()+e.Fee.AsTinybar()), e.Amount, e.Fee, e.Shortfall())
+}
+
+// Check if the balance covers the payable amount and the maximum transaction fee
+func CheckBalance(accountID string, balance hederasdk.Hbar, amount hederasdk.Hbar, fee hederasdk.Hbar) error {
+
+	if balance.AsTinybar() < amount.AsTinybar()+fee.AsTinybar() {
+		return &InsufficientBalanceError{AccountID: accountID, Balance: balance, Amount: amount, Fee: fee}
+	}
+
+	return nil
+
+}
+
+// Check if the paying account can afford a payable contract call
+// NOTE: Returns nil, if the balance of the account is not known.
+func (hm *PackageManager) CheckPayableBalance(accountID hederasdk.AccountID, amount hederasdk.Hbar) error {
+
+	if accountID.Account == 0 {
+		return nil
+	}
+
+	balance, err := hm.AccountBalance(accountID)
+	if err != nil {
+		logger.Manager.Package["hedera"].Warn().Msg(fmt.Sprintf("Could not check the balance of account %v before the contract call: %v", accountID, err))
+		return nil
+	}
+
+	err = CheckBalance(accountID.String(), balance, amount, hm.Fees.TransactionFee())
+	if err != nil {
+		logger.Manager.Package["hedera"].Warn().Msg(err.Error())
+	}
+
+	return err
+
+}
+
+// Get the balance of an account (from the mirror node or the recent queries)
+func (hm *PackageManager) AccountBalance(accountID hederasdk.AccountID) (hederasdk.Hbar, error) {
+
+	// reuse a recent balance
+	hm.balances.mutex.Lock()
+	cached, ok := hm.balances.entries[accountID.String()]
+	hm.balances.mutex.Unlock()
+	if ok && time.Since(cached.timestamp) < RENDERHIVE_CONFIG_BALANCE_CACHE_DURATION {
+		return cached.balance, nil
+	}
+
+	// query the mirror node
+	if hm.MirrorNode.URL == "" {
+		return hederasdk.Hbar{}, errors.New("No mirror node is configured.")
+	}
+	accounts, err := hm.MirrorNode.GetAccountInfo(accountID.String(), 1, "")
+	if err != nil {
+		return hederasdk.Hbar{}, err
+	}
+	if accounts == nil || len(*accounts) == 0 || (*accounts)[0].Account != accountID.String() {
+		return hederasdk.Hbar{}, fmt.Errorf("The mirror node does not know the account %v.", accountID)
+	}
+	balance := hederasdk.HbarFromTinybar((*accounts)[0].Balance.Balance)
+
+	hm.SetAccountBalance(accountID, balance)
+
+	return balance, nil
+
+}
+
+// Store the known balance of an account for the balance checks
+func (hm *PackageManager) SetAccountBalance(accountID hederasdk.AccountID, balance hederasdk.Hbar) {
+
+	hm.balances.mutex.Lock()
+	defer hm.balances.mutex.Unlock()
+
+	if hm.balances.entries == nil {
+		hm.balances.entries = make(map[string]cachedBalance)
+	}
+	hm.balances.entries[accountID.String()] = cachedBalance{balance: balance, timestamp: time.Now()}
+
+}
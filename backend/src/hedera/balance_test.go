@@ -0,0 +1,92 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+import (
+
+	// standard
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	"renderhive/logger"
+)
+
+func TestCheckBalance(t *testing.T) {
+	amount, fee := hederasdk.NewHbar(10), hederasdk.NewHbar(2)
+
+	// the balance covers the amount and the fee
+	for _, balance := range []hederasdk.Hbar{hederasdk.NewHbar(100), hederasdk.NewHbar(12)} {
+		if err := CheckBalance("0.0.1001", balance, amount, fee); err != nil {
+			t.Errorf("balance %v: expected a sufficient balance, got %v", balance, err)
+		}
+	}
+
+	// the fee is required on top of the amount
+	err := CheckBalance("0.0.1001", hederasdk.NewHbar(11), amount, fee)
+	var insufficient *InsufficientBalanceError
+	if !errors.As(err, &insufficient) {
+		t.Fatalf("expected an insufficient balance error, got %v", err)
+	}
+	if insufficient.Shortfall().AsTinybar() != hederasdk.NewHbar(1).AsTinybar() {
+		t.Errorf("expected a shortfall of 1 HBAR, got %v", insufficient.Shortfall())
+	}
+}
+
+func TestCheckPayableBalance(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+
+	// the mirror node knows the balance of the account (5 HBAR)
+	queries := 0
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries++
+		fmt.Fprint(w, `{"accounts":[{"account":"0.0.1001","balance":{"balance":500000000}}]}`)
+	}))
+	defer mirror.Close()
+	hm := &PackageManager{MirrorNode: MirrorNode{URL: mirror.URL}, Fees: FeeLimits{MaxTransactionFee: 1}}
+	accountID := hederasdk.AccountID{Account: 1001}
+
+	if err := hm.CheckPayableBalance(accountID, hederasdk.NewHbar(4)); err != nil {
+		t.Errorf("expected a sufficient balance, got %v", err)
+	}
+	var insufficient *InsufficientBalanceError
+	if err := hm.CheckPayableBalance(accountID, hederasdk.NewHbar(5)); !errors.As(err, &insufficient) {
+		t.Errorf("expected an insufficient balance, got %v", err)
+	}
+
+	// the balance is reused from the first query
+	if queries != 1 {
+		t.Errorf("expected 1 mirror node query, got %v", queries)
+	}
+
+	// an unknown balance does not block the call
+	hm.MirrorNode.URL = ""
+	if err := hm.CheckPayableBalance(hederasdk.AccountID{Account: 1002}, hederasdk.NewHbar(1000)); err != nil {
+		t.Errorf("expected an unknown balance not to block the call, got %v", err)
+	}
+}
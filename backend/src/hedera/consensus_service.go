@@ -442,6 +442,9 @@ func (topic *HederaTopic) SubmitMessage(message string, memo string, submitKey i
 		return nil, nil, err
 	}
 
+	// add the transaction to the transaction history
+	transactionID := Manager.History.Record(transaction, TRANSACTION_TYPE_TOPIC_MESSAGE, fmt.Sprintf("'%v' on topic %v (%v bytes)", memo, topic.ID, len(message)), settings.Execute)
+
 	// if the transaction should be directly executed
 	if settings.Execute {
 
@@ -450,13 +453,15 @@ func (topic *HederaTopic) SubmitMessage(message string, memo string, submitKey i
 		//       key was set as submit key
 		transactionResponse, err = hederasdk.TransactionExecute(transaction, Manager.NetworkClient)
 		if err != nil {
-			return nil, nil, err
+			Manager.History.Update(transactionID, nil, err)
+			return nil, nil, _feeCapError(err)
 		}
 
 		// get the transaction receipt
 		transactionReceipt, err := transactionResponse.GetReceipt(Manager.NetworkClient)
+		Manager.History.Update(transactionID, &transactionReceipt, err)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, _feeCapError(err)
 		}
 
 		// log the receipt status of the transaction
@@ -528,7 +533,7 @@ func (topic *HederaTopic) QueryInfo(m *PackageManager) (string, error) {
 	// create the topic info query
 	newTopicInfoQuery := hederasdk.NewTopicInfoQuery().
 		SetTopicID(topic.ID).
-		SetMaxQueryPayment(hederasdk.NewHbar(1))
+		SetMaxQueryPayment(Manager.Fees.QueryPayment())
 
 	// get cost of this query
 	cost, err := newTopicInfoQuery.GetCost(Manager.NetworkClient)
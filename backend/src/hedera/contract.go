@@ -0,0 +1,175 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+/*
+
+This file contains the connectivity check of the Renderhive smart contract.
+
+Operators can confirm that the configured smart contract is reachable before
+doing anything costly: The current hive cycle is requested with a local
+(read-only) contract call, which does not submit a transaction.
+
+*/
+
+import (
+
+	// standard
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+	"github.com/spf13/cobra"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// Information about the Renderhive smart contract
+type ContractStatus struct {
+	ContractID string   // ID of the smart contract
+	Network    string   // name of the Hedera network
+	HiveCycle  *big.Int // current hive cycle of the smart contract
+}
+
+// SMART CONTRACT CONNECTIVITY
+// #############################################################################
+// Get the name of the Hedera network of this node
+func (hm *PackageManager) NetworkName() string {
+
+	switch hm.NetworkType {
+	case NETWORK_TYPE_TESTNET:
+		return "testnet"
+	case NETWORK_TYPE_PREVIEWNET:
+		return "previewnet"
+	case NETWORK_TYPE_MAINNET:
+		return "mainnet"
+	}
+
+	return "unknown"
+
+}
+
+// Check if the smart contract responds and get its current hive cycle
+func (hm *PackageManager) CheckContract(contractID string, gas uint64) (*ContractStatus, error) {
+	var err error
+
+	status := &ContractStatus{
+		ContractID: strings.TrimSpace(contractID),
+		Network:    hm.NetworkName(),
+	}
+
+	// check the contract ID
+	if status.ContractID == "" {
+		return status, errors.New("No smart contract ID is configured.")
+	}
+	id, err := hederasdk.ContractIDFromString(status.ContractID)
+	if err != nil {
+		return status, errors.New(fmt.Sprintf("'%v' is not a valid smart contract ID: %v", status.ContractID, err))
+	}
+	if hm.NetworkClient == nil {
+		return status, errors.New("No Hedera network client found.")
+	}
+
+	// log debug event
+	logger.Manager.Package["hedera"].Debug().Msg(fmt.Sprintf("Querying the current hive cycle of contract '%v' ...", status.ContractID))
+
+	// request the current hive cycle
+	contract := HederaSmartContract{ID: id}
+	status.HiveCycle, err = contract.GetCurrentHiveCycle(gas)
+	if err != nil {
+		return status, errors.New(fmt.Sprintf("Contract '%v' could not be reached on the %v: %v", status.ContractID, status.Network, err))
+	}
+
+	return status, nil
+
+}
+
+// COMMAND LINE INTERFACE - SMART CONTRACT
+// #############################################################################
+// Create the CLI command to interact with the Renderhive smart contract
+func (hm *PackageManager) CreateCommandContract() *cobra.Command {
+
+	// create a 'contract' command for the node
+	command := &cobra.Command{
+		Use:   "contract",
+		Short: "Interact with the Renderhive smart contract",
+		Long:  "This command and its sub-commands enable the interaction with the Renderhive smart contract.",
+		Run: func(cmd *cobra.Command, args []string) {
+
+			return
+
+		},
+	}
+
+	// add the subcommands
+	command.AddCommand(hm.CreateCommandContract_Info())
+
+	return command
+
+}
+
+// Create the CLI command to check the connectivity of the smart contract
+func (hm *PackageManager) CreateCommandContract_Info() *cobra.Command {
+
+	// flags for the 'contract info' command
+	var contractID string
+	var gas uint64
+
+	// create a 'contract info' command for the node
+	command := &cobra.Command{
+		Use:   "info",
+		Short: "Check if the Renderhive smart contract responds",
+		Long:  "This command requests the current hive cycle from the configured Renderhive smart contract with a read-only query and prints it together with the contract ID and the network. The command fails, if the contract cannot be reached.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// query the smart contract
+			status, err := hm.CheckContract(contractID, gas)
+			if err != nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("The smart contract is not available: %w", err)
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Println("Renderhive smart contract:")
+			logger.Manager.Resultf(" [#] Contract ID: %v\n", status.ContractID)
+			logger.Manager.Resultf(" [#] Network: %v\n", status.Network)
+			logger.Manager.Resultf(" [#] Current hive cycle: %v\n", status.HiveCycle)
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().StringVarP(&contractID, "contract", "c", RENDERHIVE_TESTNET_SMART_CONTRACT, "The ID of the smart contract (default: configured contract)")
+	command.Flags().Uint64VarP(&gas, "gas", "g", RENDERHIVE_CONFIG_CONTRACT_QUERY_GAS, "The gas limit of the query")
+
+	return command
+
+}
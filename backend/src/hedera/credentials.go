@@ -0,0 +1,216 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+/*
+
+This file contains the loading of the operator account from the environment.
+
+Non-interactive deployments (e.g., containers) cannot enter the passphrase of
+the keystore in the frontend. Instead, the account can be injected with the
+following environment variables:
+
+  RENDERHIVE_ACCOUNT_ID    account ID of the operator (e.g., "0.0.1234")
+  RENDERHIVE_PRIVATE_KEY   private key of the operator (or)
+  RENDERHIVE_KEYSTORE      path of an encrypted keystore file (default: the
+                           keystore of the account in the configuration directory)
+  RENDERHIVE_PASSPHRASE    passphrase of the keystore file
+  RENDERHIVE_PUBLIC_KEY    public key, which the keystore must match (default:
+                           the public key of the node configuration)
+
+The private key and the passphrase can also be read from files (e.g., mounted
+secrets), whose paths are given in RENDERHIVE_PRIVATE_KEY_FILE and
+RENDERHIVE_PASSPHRASE_FILE. The secrets are never logged: the credentials only
+print the account ID and the source of the key. After they were read, the
+secrets are removed from the environment of the process, so that child
+processes (e.g., Blender) do not inherit them.
+
+*/
+
+import (
+
+	// standard
+	"fmt"
+	"os"
+	"strings"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// Operator account credentials of a non-interactive deployment
+type AccountCredentials struct {
+	AccountID  string // account ID of the operator
+	PrivateKey string // private key of the operator (empty, if a keystore is used)
+	Keystore   string // path of the keystore file (empty, if a private key is given)
+	Passphrase string // passphrase of the keystore file
+	PublicKey  string // public key, which the keystore must match
+}
+
+// ACCOUNT CREDENTIALS
+// #############################################################################
+// Read the account credentials from the environment variables
+// NOTE: Returns nil, if no account ID is set. The lookup function is usually
+// os.LookupEnv.
+func ReadAccountCredentials(lookup func(key string) (string, bool)) (*AccountCredentials, error) {
+	var err error
+
+	accountID, _ := lookup(RENDERHIVE_ENV_ACCOUNT_ID)
+	if strings.TrimSpace(accountID) == "" {
+		return nil, nil
+	}
+
+	credentials := &AccountCredentials{AccountID: strings.TrimSpace(accountID)}
+	credentials.PrivateKey, err = _readSecret(lookup, RENDERHIVE_ENV_PRIVATE_KEY)
+	if err != nil {
+		return nil, err
+	}
+	credentials.PrivateKey = strings.TrimSpace(credentials.PrivateKey)
+	credentials.Passphrase, err = _readSecret(lookup, RENDERHIVE_ENV_PASSPHRASE)
+	if err != nil {
+		return nil, err
+	}
+	keystore, _ := lookup(RENDERHIVE_ENV_KEYSTORE)
+	credentials.Keystore = strings.TrimSpace(keystore)
+	publicKey, _ := lookup(RENDERHIVE_ENV_PUBLIC_KEY)
+	credentials.PublicKey = strings.TrimSpace(publicKey)
+
+	return credentials, nil
+
+}
+
+// Remove the secrets of the account credentials from the environment
+// NOTE: The unset function is usually os.Unsetenv.
+func ClearAccountSecrets(unset func(key string) error) {
+
+	for _, name := range []string{RENDERHIVE_ENV_PRIVATE_KEY, RENDERHIVE_ENV_PASSPHRASE} {
+		if err := unset(name); err != nil {
+			logger.Manager.Package["hedera"].Warn().Msg(fmt.Sprintf("Could not remove %v from the environment: %v", name, err))
+		}
+	}
+
+}
+
+// Check the account credentials for missing or invalid values
+func (credentials *AccountCredentials) Validate() error {
+
+	if _, err := hederasdk.AccountIDFromString(credentials.AccountID); err != nil {
+		return fmt.Errorf("Invalid account ID '%v' in %v: %v", credentials.AccountID, RENDERHIVE_ENV_ACCOUNT_ID, err)
+	}
+
+	// the private key is given directly
+	if credentials.PrivateKey != "" {
+		if credentials.Keystore != "" {
+			return fmt.Errorf("Set either %v or %v, not both.", RENDERHIVE_ENV_PRIVATE_KEY, RENDERHIVE_ENV_KEYSTORE)
+		}
+		// NOTE: The parse error is not returned, since it could contain parts of the key.
+		if _, err := hederasdk.PrivateKeyFromString(credentials.PrivateKey); err != nil {
+			return fmt.Errorf("Invalid private key in %v.", RENDERHIVE_ENV_PRIVATE_KEY)
+		}
+		return nil
+	}
+
+	// the private key is read from a keystore
+	if credentials.Passphrase == "" {
+		return fmt.Errorf("The keystore of account %v needs a passphrase (%v or %v%v).", credentials.AccountID, RENDERHIVE_ENV_PASSPHRASE, RENDERHIVE_ENV_PASSPHRASE, RENDERHIVE_ENV_FILE_SUFFIX)
+	}
+	if credentials.PublicKey == "" {
+		return fmt.Errorf("The keystore of account %v needs the public key of the account (%v).", credentials.AccountID, RENDERHIVE_ENV_PUBLIC_KEY)
+	}
+	if _, err := hederasdk.PublicKeyFromString(credentials.PublicKey); err != nil {
+		return fmt.Errorf("Invalid public key '%v' in %v: %v", credentials.PublicKey, RENDERHIVE_ENV_PUBLIC_KEY, err)
+	}
+
+	return nil
+
+}
+
+// Describe the credentials without their secrets (e.g., for the log)
+func (credentials AccountCredentials) String() string {
+
+	if credentials.PrivateKey != "" {
+		return fmt.Sprintf("account %v (private key: [redacted])", credentials.AccountID)
+	}
+
+	keystore := credentials.Keystore
+	if keystore == "" {
+		keystore = "default"
+	}
+
+	return fmt.Sprintf("account %v (keystore: %v, passphrase: [redacted])", credentials.AccountID, keystore)
+
+}
+
+// Describe the credentials without their secrets (also for the %#v format)
+func (credentials AccountCredentials) GoString() string {
+	return credentials.String()
+}
+
+// Load the operator account from the account credentials
+func (hm *PackageManager) LoadAccountFromCredentials(credentials *AccountCredentials) error {
+
+	err := credentials.Validate()
+	if err != nil {
+		return err
+	}
+
+	// log info
+	logger.Manager.Package["hedera"].Info().Msg(fmt.Sprintf("Loading the operator %v from the environment ...", credentials))
+
+	if credentials.PrivateKey != "" {
+		return hm.SetAccount(credentials.AccountID, credentials.PrivateKey)
+	}
+
+	keystore := credentials.Keystore
+	if keystore == "" {
+		keystore = hm.KeystorePath(credentials.AccountID)
+	}
+
+	return hm.LoadAccountFromKeystore(credentials.AccountID, keystore, credentials.Passphrase, credentials.PublicKey)
+
+}
+
+// helper function to read a secret from an environment variable or the file
+// given in the environment variable with the file suffix
+func _readSecret(lookup func(key string) (string, bool), name string) (string, error) {
+
+	value, hasValue := lookup(name)
+	path, hasFile := lookup(name + RENDERHIVE_ENV_FILE_SUFFIX)
+	if hasValue && value != "" && hasFile && path != "" {
+		return "", fmt.Errorf("Set either %v or %v%v, not both.", name, name, RENDERHIVE_ENV_FILE_SUFFIX)
+	}
+	if !hasFile || path == "" {
+		return value, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("Could not read the secret file of %v%v: %v", name, RENDERHIVE_ENV_FILE_SUFFIX, err)
+	}
+
+	// NOTE: Only the line break at the end of the file is removed.
+	return strings.TrimRight(string(data), "\r\n"), nil
+
+}
@@ -0,0 +1,127 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+import (
+
+	// standard
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+)
+
+// helper function to create a lookup function from a map of variables
+func _lookupFrom(env map[string]string) func(key string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestReadAccountCredentials(t *testing.T) {
+	key, err := hederasdk.PrivateKeyGenerateEd25519()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// no account ID means no credentials
+	credentials, err := ReadAccountCredentials(_lookupFrom(map[string]string{}))
+	if err != nil || credentials != nil {
+		t.Fatalf("expected no credentials, got %v, %v", credentials, err)
+	}
+
+	// the private key is read from the environment
+	credentials, err = ReadAccountCredentials(_lookupFrom(map[string]string{
+		RENDERHIVE_ENV_ACCOUNT_ID:  " 0.0.1001 ",
+		RENDERHIVE_ENV_PRIVATE_KEY: key.String() + "\n",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credentials.AccountID != "0.0.1001" || credentials.PrivateKey != key.String() {
+		t.Fatalf("unexpected credentials: %#v", credentials)
+	}
+	if err = credentials.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(credentials.String(), key.String()) || strings.Contains(credentials.GoString(), key.String()) {
+		t.Fatal("the private key must not be printed")
+	}
+
+	// the passphrase is read from a secret file
+	path := filepath.Join(t.TempDir(), "passphrase")
+	if err = os.WriteFile(path, []byte("secret phrase\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	credentials, err = ReadAccountCredentials(_lookupFrom(map[string]string{
+		RENDERHIVE_ENV_ACCOUNT_ID:                              "0.0.1001",
+		RENDERHIVE_ENV_PASSPHRASE + RENDERHIVE_ENV_FILE_SUFFIX: path,
+		RENDERHIVE_ENV_PUBLIC_KEY:                              key.PublicKey().String(),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credentials.Passphrase != "secret phrase" {
+		t.Fatalf("unexpected passphrase: %q", credentials.Passphrase)
+	}
+	if err = credentials.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a secret must not be given twice
+	_, err = ReadAccountCredentials(_lookupFrom(map[string]string{
+		RENDERHIVE_ENV_ACCOUNT_ID:                              "0.0.1001",
+		RENDERHIVE_ENV_PASSPHRASE:                              "secret phrase",
+		RENDERHIVE_ENV_PASSPHRASE + RENDERHIVE_ENV_FILE_SUFFIX: path,
+	}))
+	if err == nil {
+		t.Fatal("expected an error for a passphrase given twice")
+	}
+}
+
+func TestClearAccountSecrets(t *testing.T) {
+	env := map[string]string{
+		RENDERHIVE_ENV_ACCOUNT_ID:  "0.0.1001",
+		RENDERHIVE_ENV_PRIVATE_KEY: "302e...",
+		RENDERHIVE_ENV_PASSPHRASE:  "secret phrase",
+	}
+	ClearAccountSecrets(func(key string) error {
+		delete(env, key)
+		return nil
+	})
+
+	if _, ok := env[RENDERHIVE_ENV_PRIVATE_KEY]; ok {
+		t.Error("the private key was not removed")
+	}
+	if _, ok := env[RENDERHIVE_ENV_PASSPHRASE]; ok {
+		t.Error("the passphrase was not removed")
+	}
+	if _, ok := env[RENDERHIVE_ENV_ACCOUNT_ID]; !ok {
+		t.Error("the account ID is not a secret and must be kept")
+	}
+}
@@ -0,0 +1,181 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+/*
+
+This file contains the fee caps of the transactions and queries of this node.
+
+Each transaction is built with a maximum transaction fee and each paid query
+with a maximum query payment, so a mispriced network or a large contract call
+cannot cost more than the operator intends. The caps are also the defaults of
+the network client and apply to transactions that are frozen for the signature
+by the operator's wallet. They can be set (in HBAR) in the optional 'fees.json'
+file of the configuration directory:
+
+    {"max_transaction_fee": 5, "max_query_payment": 1}
+
+If a transaction or query would exceed its cap, it fails with an error that
+names the cap, so the operator can raise it instead of silently overpaying.
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+)
+
+// Fee caps of the transactions and queries (in HBAR)
+type FeeLimits struct {
+	MaxTransactionFee float64 `json:"max_transaction_fee"` // maximum fee of a transaction
+	MaxQueryPayment   float64 `json:"max_query_payment"`   // maximum payment of a query
+}
+
+// FEE CAPS
+// #############################################################################
+// Get the default fee caps
+func DefaultFeeLimits() FeeLimits {
+	return FeeLimits{
+		MaxTransactionFee: RENDERHIVE_CONFIG_HEDERA_MAX_TRANSACTION_FEE,
+		MaxQueryPayment:   RENDERHIVE_CONFIG_HEDERA_MAX_QUERY_PAYMENT,
+	}
+}
+
+// Read the fee caps from the configuration file
+func (limits *FeeLimits) Read() error {
+	var err error
+
+	// Open the configuration file
+	file, err := os.Open(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "fees.json"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Read the file content
+	fileData, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	// get the file content into a structure
+	err = json.Unmarshal(fileData, limits)
+	if err != nil {
+		return err
+	}
+
+	return limits.Validate()
+
+}
+
+// Check the fee caps for invalid values
+func (limits *FeeLimits) Validate() error {
+
+	if limits.MaxTransactionFee <= 0 || limits.MaxQueryPayment <= 0 {
+		return errors.New("The fee caps must be greater than zero.")
+	}
+
+	return nil
+
+}
+
+// Get the maximum fee of a transaction
+func (limits FeeLimits) TransactionFee() hederasdk.Hbar {
+	return hederasdk.HbarFrom(limits.MaxTransactionFee, hederasdk.HbarUnits.Hbar)
+}
+
+// Get the maximum payment of a query
+func (limits FeeLimits) QueryPayment() hederasdk.Hbar {
+	return hederasdk.HbarFrom(limits.MaxQueryPayment, hederasdk.HbarUnits.Hbar)
+}
+
+// Load the fee caps of this node and apply them to the network client
+// NOTE: Falls back to the default caps, if the configuration file does not
+// exist or is invalid.
+func (hm *PackageManager) LoadFeeLimits() error {
+	var err error
+
+	hm.Fees = DefaultFeeLimits()
+	limits := DefaultFeeLimits()
+	err = limits.Read()
+	if err != nil && !os.IsNotExist(err) {
+		return errors.New(fmt.Sprintf("Could not read the fee caps (using the defaults): %v", err))
+	}
+	if err == nil {
+		hm.Fees = limits
+	}
+
+	if hm.NetworkClient != nil {
+		err = hm.NetworkClient.SetDefaultMaxTransactionFee(hm.Fees.TransactionFee())
+		if err != nil {
+			return err
+		}
+		err = hm.NetworkClient.SetDefaultMaxQueryPayment(hm.Fees.QueryPayment())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+// helper function to set the maximum transaction fee of a transaction
+func _TransactionSetFeeLimit(transaction interface{}) (interface{}, error) {
+
+	return hederasdk.TransactionSetMaxTransactionFee(transaction, Manager.Fees.TransactionFee())
+
+}
+
+// helper function to explain an error of a transaction or query that exceeds its fee cap
+// NOTE: Other errors are returned unchanged.
+func _feeCapError(err error) error {
+
+	var queryErr hederasdk.ErrMaxQueryPaymentExceeded
+	if errors.As(err, &queryErr) {
+		return fmt.Errorf("The query costs %v, which exceeds the maximum query payment of %v. Raise 'max_query_payment' in 'fees.json' to allow it. (Error: %w)", queryErr.QueryCost, queryErr.MaxQueryPayment, err)
+	}
+
+	var precheckErr hederasdk.ErrHederaPreCheckStatus
+	if errors.As(err, &precheckErr) && precheckErr.Status == hederasdk.StatusInsufficientTxFee {
+		return fmt.Errorf("The transaction fee exceeds the maximum transaction fee of %v. Raise 'max_transaction_fee' in 'fees.json' to allow it. (Error: %w)", Manager.Fees.TransactionFee(), err)
+	}
+
+	var receiptErr hederasdk.ErrHederaReceiptStatus
+	if errors.As(err, &receiptErr) && receiptErr.Status == hederasdk.StatusInsufficientTxFee {
+		return fmt.Errorf("The transaction fee exceeds the maximum transaction fee of %v. Raise 'max_transaction_fee' in 'fees.json' to allow it. (Error: %w)", Manager.Fees.TransactionFee(), err)
+	}
+
+	return err
+
+}
@@ -0,0 +1,136 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+import (
+
+	// standard
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+)
+
+// helper function to write the fee caps of this node
+func _writeFeeLimits(t *testing.T, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(RENDERHIVE_APP_DIRECTORY_CONFIG, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "fees.json"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadFeeLimits(t *testing.T) {
+	_chdirTemp(t)
+	hm := &PackageManager{NetworkClient: hederasdk.ClientForNetwork(map[string]hederasdk.AccountID{})}
+
+	// without a configuration file, the default caps are used
+	if err := hm.LoadFeeLimits(); err != nil {
+		t.Fatal(err)
+	}
+	if hm.Fees != DefaultFeeLimits() {
+		t.Errorf("got fee caps %+v, want the defaults", hm.Fees)
+	}
+
+	// the configured caps are applied to the network client
+	_writeFeeLimits(t, `{"max_transaction_fee": 3, "max_query_payment": 0.5}`)
+	if err := hm.LoadFeeLimits(); err != nil {
+		t.Fatal(err)
+	}
+	if hm.NetworkClient.GetDefaultMaxTransactionFee().AsTinybar() != hederasdk.NewHbar(3).AsTinybar() {
+		t.Errorf("got a maximum transaction fee of %v, want 3 ℏ", hm.NetworkClient.GetDefaultMaxTransactionFee())
+	}
+	if hm.NetworkClient.GetDefaultMaxQueryPayment().AsTinybar() != hederasdk.HbarFrom(0.5, hederasdk.HbarUnits.Hbar).AsTinybar() {
+		t.Errorf("got a maximum query payment of %v, want 0.5 ℏ", hm.NetworkClient.GetDefaultMaxQueryPayment())
+	}
+
+	// invalid caps are refused and the defaults are used
+	for _, content := range []string{`{"max_transaction_fee": 0, "max_query_payment": 1}`, `{"max_transaction_fee": 1, "max_query_payment": -1}`, `not json`} {
+		_writeFeeLimits(t, content)
+		if err := hm.LoadFeeLimits(); err == nil {
+			t.Errorf("%v: expected an error for invalid fee caps", content)
+		}
+		if hm.Fees != DefaultFeeLimits() {
+			t.Errorf("%v: got fee caps %+v, want the defaults", content, hm.Fees)
+		}
+	}
+}
+
+func TestTransactionSetFeeLimit(t *testing.T) {
+	defaultFees := Manager.Fees
+	t.Cleanup(func() { Manager.Fees = defaultFees })
+	Manager.Fees = FeeLimits{MaxTransactionFee: 2, MaxQueryPayment: 1}
+
+	// the cap is applied to a constructed transaction
+	transaction, err := _TransactionSetFeeLimit(hederasdk.NewTopicMessageSubmitTransaction())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fee, err := hederasdk.TransactionGetMaxTransactionFee(transaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee.AsTinybar() != hederasdk.NewHbar(2).AsTinybar() {
+		t.Errorf("got a maximum transaction fee of %v, want 2 ℏ", fee)
+	}
+}
+
+func TestFeeCapErrorRejectsTransactionsOverTheCap(t *testing.T) {
+	defaultFees := Manager.Fees
+	t.Cleanup(func() { Manager.Fees = defaultFees })
+	Manager.Fees = FeeLimits{MaxTransactionFee: 2, MaxQueryPayment: 1}
+
+	// a transaction with a fee over the cap is rejected by the network
+	for _, rejected := range []error{
+		hederasdk.ErrHederaPreCheckStatus{Status: hederasdk.StatusInsufficientTxFee},
+		hederasdk.ErrHederaReceiptStatus{Status: hederasdk.StatusInsufficientTxFee},
+	} {
+		err := _feeCapError(rejected)
+		if !strings.Contains(err.Error(), rejected.Error()) || !strings.Contains(err.Error(), "max_transaction_fee") {
+			t.Errorf("got %v, want the exceeded transaction fee cap", err)
+		}
+	}
+
+	// a query with a cost over the cap is rejected before it is paid
+	rejected := hederasdk.ErrMaxQueryPaymentExceeded{QueryCost: hederasdk.NewHbar(3), MaxQueryPayment: hederasdk.NewHbar(1)}
+	err := _feeCapError(rejected)
+	var queryErr hederasdk.ErrMaxQueryPaymentExceeded
+	if !errors.As(err, &queryErr) || !strings.Contains(err.Error(), "max_query_payment") {
+		t.Errorf("got %v, want the exceeded query payment cap", err)
+	}
+
+	// other errors are returned unchanged
+	for _, other := range []error{hederasdk.ErrHederaPreCheckStatus{Status: hederasdk.StatusInvalidSignature}, errors.New("timeout")} {
+		if err := _feeCapError(other); err != other {
+			t.Errorf("got %v, want the unchanged error %v", err, other)
+		}
+	}
+}
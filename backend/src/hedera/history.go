@@ -0,0 +1,481 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+/*
+
+The transaction history keeps a local record of all transactions this node
+prepared or submitted to the Hedera network (smart contract calls and topic
+messages). Most transactions are returned unsigned to the frontend, which
+signs and submits them. Their status and the charged fee are therefore
+obtained from the mirror node, when the history is refreshed.
+
+*/
+
+import (
+
+	// standard
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+	"renderhive/utility"
+)
+
+// transaction types of the history
+const (
+	TRANSACTION_TYPE_CONTRACT_CALL  = "ContractCall"
+	TRANSACTION_TYPE_TOPIC_MESSAGE  = "TopicMessage"
+	TRANSACTION_TYPE_TOPIC_CREATE   = "TopicCreate"
+	TRANSACTION_TYPE_ACCOUNT_UPDATE = "AccountUpdate"
+	TRANSACTION_TYPE_OTHER          = "Other"
+)
+
+// transaction states of the history (other states are the Hedera status codes)
+const (
+	TRANSACTION_STATUS_PENDING = "PENDING" // not yet found on the network
+	TRANSACTION_STATUS_EXPIRED = "EXPIRED" // never reached consensus within its valid duration
+)
+
+// time after which a pending transaction can not reach consensus anymore
+const TRANSACTION_HISTORY_EXPIRY = 3 * time.Minute
+
+// maximum number of transactions kept in the transaction history (the oldest are dropped)
+const TRANSACTION_HISTORY_LIMIT = 10000
+
+// A transaction in the transaction history
+type TransactionRecord struct {
+	TransactionID      string    `json:"transaction_id"`
+	Type               string    `json:"type"`
+	Summary            string    `json:"summary"`
+	Executed           bool      `json:"executed"`            // submitted by this node (true) or returned for signing (false)
+	Status             string    `json:"status"`              // PENDING, EXPIRED, or the Hedera status code
+	Fee                int64     `json:"fee"`                 // charged transaction fee in tinybar
+	FeeKnown           bool      `json:"fee_known"`           // the fee was obtained from the mirror node
+	ConsensusTimestamp string    `json:"consensus_timestamp"` // consensus timestamp reported by the mirror node
+	CreatedTimestamp   time.Time `json:"created_timestamp"`
+	UpdatedTimestamp   time.Time `json:"updated_timestamp"`
+}
+
+// Filter to query the transaction history
+type TransactionFilter struct {
+	Type   string    // only transactions of this type
+	Status string    // only transactions with this status
+	Search string    // only transactions whose summary contains this text
+	Since  time.Time // only transactions created after this time
+	Limit  int       // maximum number of transactions (newest first)
+}
+
+// Local transaction history of this node
+// NOTE: The history is an append-only log with one record per line. A changed
+// record is appended again and the last line of a transaction wins. The log is
+// compacted on load and when it has grown to twice the limit of the history.
+type TransactionHistory struct {
+	Mutex   sync.Mutex
+	Records []*TransactionRecord
+	Jobs    []*JobAccount // accounting of the render jobs of this node
+
+	lines int // number of lines in the log file
+}
+
+// TRANSACTION HISTORY
+// #############################################################################
+// Get the path of the transaction history file
+func (history *TransactionHistory) Path() string {
+	return filepath.Join(utility.GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_TRANSACTION_HISTORY, "history.log")
+}
+
+// Load the transaction history from the local file
+func (history *TransactionHistory) Load() error {
+	var err error
+
+	// lock the history
+	history.Mutex.Lock()
+	defer history.Mutex.Unlock()
+
+	// load the accounting of the render jobs
+	err = history._loadJobs()
+	if err != nil {
+		return err
+	}
+
+	// read the history of older versions, which was written as one document
+	history.Records = []*TransactionRecord{}
+	legacyPath := filepath.Join(filepath.Dir(history.Path()), "history.json")
+	data, err := os.ReadFile(legacyPath)
+	if err == nil {
+		err = json.Unmarshal(data, &history.Records)
+		if err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	// read the log (a missing file is an empty history)
+	file, err := os.Open(history.Path())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	} else if err == nil {
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+
+			// a partially written last line is skipped
+			record := &TransactionRecord{}
+			err = json.Unmarshal(scanner.Bytes(), record)
+			if err != nil {
+				logger.Manager.Package["hedera"].Warn().Msg(fmt.Sprintf("Line %v of the transaction history is not a valid record: %v", line, err))
+				continue
+			}
+
+			// the last line of a transaction replaces its previous lines
+			if existing := history._get(record.TransactionID); existing != nil {
+				*existing = *record
+			} else {
+				history.Records = append(history.Records, record)
+			}
+		}
+		err = scanner.Err()
+		if err != nil {
+			return err
+		}
+	}
+
+	// drop the oldest transactions and rewrite the log without the outdated lines
+	history._trim()
+	err = history._compact()
+	if err != nil {
+		return err
+	}
+	os.Remove(legacyPath)
+
+	logger.Manager.Package["hedera"].Debug().Msg(fmt.Sprintf(" [#] Loaded %v transactions from the transaction history.", len(history.Records)))
+
+	return err
+
+}
+
+// Add a prepared transaction to the transaction history
+func (history *TransactionHistory) Record(transaction interface{}, transactionType string, summary string, executed bool) string {
+
+	// get the transaction ID of the frozen transaction
+	transactionID, err := hederasdk.TransactionGetTransactionID(transaction)
+	if err != nil {
+		logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Could not record transaction '%v': %v", summary, err))
+		return ""
+	}
+
+	return history._record(&TransactionRecord{
+		TransactionID:    transactionID.String(),
+		Type:             transactionType,
+		Summary:          summary,
+		Executed:         executed,
+		Status:           TRANSACTION_STATUS_PENDING,
+		CreatedTimestamp: time.Now(),
+		UpdatedTimestamp: time.Now(),
+	})
+
+}
+
+// Update the status of a transaction in the transaction history
+func (history *TransactionHistory) Update(transactionID string, receipt *hederasdk.TransactionReceipt, err error) {
+
+	// transaction was not recorded
+	if transactionID == "" {
+		return
+	}
+
+	// lock the history
+	history.Mutex.Lock()
+	defer history.Mutex.Unlock()
+
+	// find the record
+	record := history._get(transactionID)
+	if record == nil {
+		return
+	}
+
+	// update the status from the receipt or the error
+	if receipt != nil && receipt.Status != hederasdk.StatusUnknown {
+		record.Status = receipt.Status.String()
+	} else if err != nil {
+		record.Status = fmt.Sprintf("FAILED: %v", err)
+	}
+	record.UpdatedTimestamp = time.Now()
+	history._append(record)
+
+}
+
+// Update pending transactions and unknown fees from the mirror node
+// NOTE: The mirror node is queried without holding the mutex, so a slow mirror
+// node does not block recording transactions.
+func (history *TransactionHistory) Refresh(mirrorNode *MirrorNode) error {
+	var err error
+
+	// get the incomplete records
+	history.Mutex.Lock()
+	var pending []TransactionRecord
+	for _, record := range history.Records {
+		if !record.FeeKnown && record.Status != TRANSACTION_STATUS_EXPIRED {
+			pending = append(pending, *record)
+		}
+	}
+	history.Mutex.Unlock()
+
+	updated := []TransactionRecord{}
+	for _, record := range pending {
+
+		// query the transaction from the mirror node
+		info, err := mirrorNode.GetTransactionInfo(record.TransactionID)
+		if err != nil {
+
+			// pending transactions expire after their valid duration
+			if record.Status == TRANSACTION_STATUS_PENDING && time.Since(record.CreatedTimestamp) > TRANSACTION_HISTORY_EXPIRY {
+				record.Status = TRANSACTION_STATUS_EXPIRED
+				updated = append(updated, record)
+			}
+			continue
+
+		}
+
+		// update the record
+		record.Status = info.Result
+		record.Fee = int64(info.ChargedTxFee)
+		record.FeeKnown = true
+		record.ConsensusTimestamp = info.ConsensusTimestamp
+		updated = append(updated, record)
+
+	}
+
+	// apply the updates to the records, which were not dropped in the meantime
+	history.Mutex.Lock()
+	for _, update := range updated {
+		record := history._get(update.TransactionID)
+		if record == nil {
+			continue
+		}
+		record.Status = update.Status
+		record.Fee = update.Fee
+		record.FeeKnown = update.FeeKnown
+		record.ConsensusTimestamp = update.ConsensusTimestamp
+		record.UpdatedTimestamp = time.Now()
+		if appendErr := history._append(record); appendErr != nil {
+			err = appendErr
+		}
+	}
+	history.Mutex.Unlock()
+
+	// audit the outcomes
+	for _, update := range updated {
+		Manager._auditOutcome(update.TransactionID, update.Status)
+	}
+
+	// log event
+	logger.Manager.Package["hedera"].Debug().Msg(fmt.Sprintf("Refreshed %v transactions of the transaction history.", len(updated)))
+
+	return err
+
+}
+
+// Get the status of a transaction in the transaction history
+func (history *TransactionHistory) Status(transactionID string) (string, bool) {
+
+	// lock the history
+	history.Mutex.Lock()
+	defer history.Mutex.Unlock()
+
+	record := history._get(transactionID)
+	if record == nil {
+		return "", false
+	}
+
+	return record.Status, true
+
+}
+
+// List the transactions of the history matching the filter (newest first)
+func (history *TransactionHistory) List(filter TransactionFilter) []TransactionRecord {
+	var records []TransactionRecord
+
+	// lock the history
+	history.Mutex.Lock()
+	defer history.Mutex.Unlock()
+
+	for i := len(history.Records) - 1; i >= 0; i-- {
+		record := history.Records[i]
+
+		// apply the filter
+		if filter.Type != "" && !strings.EqualFold(record.Type, filter.Type) {
+			continue
+		}
+		if filter.Status != "" && !strings.EqualFold(record.Status, filter.Status) {
+			continue
+		}
+		if filter.Search != "" && !strings.Contains(strings.ToLower(record.Summary), strings.ToLower(filter.Search)) {
+			continue
+		}
+		if !filter.Since.IsZero() && record.CreatedTimestamp.Before(filter.Since) {
+			continue
+		}
+
+		records = append(records, *record)
+		if filter.Limit > 0 && len(records) >= filter.Limit {
+			break
+		}
+	}
+
+	return records
+
+}
+
+// helper function to add a record to the transaction history
+func (history *TransactionHistory) _record(record *TransactionRecord) string {
+
+	// lock the history
+	history.Mutex.Lock()
+	defer history.Mutex.Unlock()
+
+	// add the record and drop the oldest records
+	history.Records = append(history.Records, record)
+	history._trim()
+	history._append(record)
+
+	return record.TransactionID
+
+}
+
+// Get a record by transaction ID
+// NOTE: The caller must hold the mutex.
+func (history *TransactionHistory) _get(transactionID string) *TransactionRecord {
+
+	for _, record := range history.Records {
+		if record.TransactionID == transactionID {
+			return record
+		}
+	}
+
+	return nil
+
+}
+
+// helper function to drop the oldest records above the limit of the history
+// NOTE: The caller must hold the mutex.
+func (history *TransactionHistory) _trim() {
+
+	if len(history.Records) > TRANSACTION_HISTORY_LIMIT {
+		history.Records = append([]*TransactionRecord{}, history.Records[len(history.Records)-TRANSACTION_HISTORY_LIMIT:]...)
+	}
+
+}
+
+// helper function to append a record to the log file
+// NOTE: The caller must hold the mutex.
+func (history *TransactionHistory) _append(record *TransactionRecord) error {
+	var err error
+
+	// compact the log, once most of its lines are outdated
+	if history.lines >= 2*TRANSACTION_HISTORY_LIMIT {
+		return history._compact()
+	}
+
+	// create the directory, if it does not exist
+	err = os.MkdirAll(filepath.Dir(history.Path()), 0700)
+	if err != nil {
+		return err
+	}
+
+	// append the record
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(history.Path(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Could not write the transaction history: %v", err))
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Could not write the transaction history: %v", err))
+		return err
+	}
+	history.lines++
+
+	return err
+
+}
+
+// helper function to rewrite the log file with one line per record
+// NOTE: The caller must hold the mutex.
+func (history *TransactionHistory) _compact() error {
+	var err error
+
+	// create the directory, if it does not exist
+	err = os.MkdirAll(filepath.Dir(history.Path()), 0700)
+	if err != nil {
+		return err
+	}
+
+	// write the records to a temporary file and replace the old file
+	var buffer bytes.Buffer
+	for _, record := range history.Records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		buffer.Write(append(data, '\n'))
+	}
+	err = os.WriteFile(history.Path()+".tmp", buffer.Bytes(), 0600)
+	if err != nil {
+		logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Could not write the transaction history: %v", err))
+		return err
+	}
+	err = os.Rename(history.Path()+".tmp", history.Path())
+	if err != nil {
+		logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Could not write the transaction history: %v", err))
+		return err
+	}
+	history.lines = len(history.Records)
+
+	return err
+
+}
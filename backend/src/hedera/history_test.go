@@ -0,0 +1,184 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+import (
+
+	// standard
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	// internal
+	"renderhive/logger"
+)
+
+func _testHistory(t *testing.T) *TransactionHistory {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+
+	history := &TransactionHistory{}
+	if err := history.Load(); err != nil {
+		t.Fatal(err)
+	}
+	return history
+}
+
+func _historyLines(t *testing.T, history *TransactionHistory) int {
+	data, err := os.ReadFile(history.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "\n")
+}
+
+func TestTransactionHistoryAppendsRecords(t *testing.T) {
+	history := _testHistory(t)
+
+	// each record and each update appends one line
+	history._record(&TransactionRecord{TransactionID: "0.0.1001@1700000000.000000001", Status: TRANSACTION_STATUS_PENDING})
+	history._record(&TransactionRecord{TransactionID: "0.0.1001@1700000000.000000002", Status: TRANSACTION_STATUS_PENDING})
+	history.Update("0.0.1001@1700000000.000000001", nil, errors.New("timeout"))
+	if lines := _historyLines(t, history); lines != 3 {
+		t.Fatalf("got %v lines, want 3", lines)
+	}
+
+	// the last line of a transaction wins and the log is compacted on load
+	loaded := &TransactionHistory{}
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Records) != 2 {
+		t.Fatalf("got %v records, want 2", len(loaded.Records))
+	}
+	if status, _ := loaded.Status("0.0.1001@1700000000.000000001"); status != "FAILED: timeout" {
+		t.Errorf("got status %q, want the updated status", status)
+	}
+	if lines := _historyLines(t, loaded); lines != 2 {
+		t.Errorf("got %v lines after loading, want 2", lines)
+	}
+}
+
+func TestTransactionHistorySkipsPartialLine(t *testing.T) {
+	history := _testHistory(t)
+	history._record(&TransactionRecord{TransactionID: "0.0.1001@1700000000.000000001"})
+
+	// a write interrupted by a crash leaves a partial last line
+	file, err := os.OpenFile(history.Path(), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"transaction_id":"0.0.10`)
+	file.Close()
+
+	loaded := &TransactionHistory{}
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Records) != 1 {
+		t.Errorf("got %v records, want 1", len(loaded.Records))
+	}
+}
+
+func TestTransactionHistoryMigratesLegacyFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+
+	history := &TransactionHistory{}
+	legacyPath := filepath.Join(filepath.Dir(history.Path()), "history.json")
+	os.MkdirAll(filepath.Dir(legacyPath), 0700)
+	os.WriteFile(legacyPath, []byte(`[{"transaction_id":"0.0.1001@1700000000.000000001","status":"SUCCESS"}]`), 0600)
+
+	if err := history.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if status, ok := history.Status("0.0.1001@1700000000.000000001"); !ok || status != "SUCCESS" {
+		t.Fatalf("got status %q, want the status of the legacy file", status)
+	}
+	if _, err := os.Stat(legacyPath); !os.IsNotExist(err) {
+		t.Errorf("the legacy file was not removed: %v", err)
+	}
+	if lines := _historyLines(t, history); lines != 1 {
+		t.Errorf("got %v lines, want 1", lines)
+	}
+}
+
+func TestTransactionHistoryIsBounded(t *testing.T) {
+	history := _testHistory(t)
+
+	for i := 0; i < TRANSACTION_HISTORY_LIMIT; i++ {
+		history.Records = append(history.Records, &TransactionRecord{TransactionID: fmt.Sprintf("0.0.1001@1700000000.%09d", i)})
+	}
+	history._record(&TransactionRecord{TransactionID: "0.0.1001@1800000000.000000000"})
+
+	// the oldest transaction is dropped
+	if len(history.Records) != TRANSACTION_HISTORY_LIMIT {
+		t.Fatalf("got %v records, want %v", len(history.Records), TRANSACTION_HISTORY_LIMIT)
+	}
+	if _, ok := history.Status("0.0.1001@1700000000.000000000"); ok {
+		t.Error("the oldest transaction was not dropped")
+	}
+
+	// a log with twice the limit of lines is compacted
+	history.lines = 2 * TRANSACTION_HISTORY_LIMIT
+	history._record(&TransactionRecord{TransactionID: "0.0.1001@1800000000.000000001"})
+	if lines := _historyLines(t, history); lines != TRANSACTION_HISTORY_LIMIT {
+		t.Errorf("got %v lines after compacting, want %v", lines, TRANSACTION_HISTORY_LIMIT)
+	}
+}
+
+func TestTransactionHistoryRefreshReleasesLock(t *testing.T) {
+	history := _testHistory(t)
+	history._record(&TransactionRecord{TransactionID: "0.0.1001@1700000000.000000001", Status: TRANSACTION_STATUS_PENDING, CreatedTimestamp: time.Now()})
+
+	// the history can be used while the mirror node is queried
+	locked := false
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if history.Mutex.TryLock() {
+			history.Mutex.Unlock()
+		} else {
+			locked = true
+		}
+		fmt.Fprint(w, `{"transactions":[{"result":"SUCCESS","charged_tx_fee":1234,"consensus_timestamp":"1700000001.000000000"}]}`)
+	}))
+	defer mirror.Close()
+
+	if err := history.Refresh(&MirrorNode{URL: mirror.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if locked {
+		t.Error("the mutex was held while querying the mirror node")
+	}
+
+	records := history.List(TransactionFilter{})
+	if len(records) != 1 || records[0].Status != "SUCCESS" || records[0].Fee != 1234 || !records[0].FeeKnown {
+		t.Fatalf("unexpected records: %+v", records)
+	}
+	if lines := _historyLines(t, history); lines != 2 {
+		t.Errorf("got %v lines, want 2", lines)
+	}
+}
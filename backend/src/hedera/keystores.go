@@ -0,0 +1,563 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+/*
+
+This file contains the inspection of the keystore files in the configuration
+directory.
+
+The keystore of an account is named after its account ID without the dots
+(e.g., 0.0.1234 -> 001234.key). Thus, the account IDs of shard 0 and realm 0
+can be derived from the file names without decrypting the keystores. A keystore
+is only decrypted to show its public key, which is compared with the key of the
+account on the mirror node. The private key is never displayed and discarded
+right after the public key was derived.
+
+The keystore format of the Hedera SDK only supports ED25519 keys. ECDSA keys are
+encrypted in the same format (PBKDF2, AES-128-CTR, HMAC-SHA384), but the
+keystore has an additional "keytype" field, so that the decrypted bytes are not
+mistaken for an ED25519 key. ED25519 keystores are still read and written by the
+Hedera SDK and stay compatible with other wallets.
+
+Passphrases and private keys are never passed as command line arguments, since
+they would end up in the shell history and the process list. The commands read
+the passphrase from the RENDERHIVE_PASSPHRASE (or RENDERHIVE_PASSPHRASE_FILE)
+environment variable and the private key to import from the
+RENDERHIVE_PRIVATE_KEY (or RENDERHIVE_PRIVATE_KEY_FILE) environment variable,
+or prompt for them.
+
+*/
+
+import (
+
+	// standard
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/term"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// Key types of the keystore format
+const (
+	KEYSTORE_KEY_TYPE_ED25519 = "ed25519"
+	KEYSTORE_KEY_TYPE_ECDSA   = "ecdsa"
+)
+
+// Parameters of the keystore encryption (identical to the Hedera SDK)
+const (
+	_keystoreVersion     = 1
+	_keystoreCipher      = "aes-128-ctr"
+	_keystoreKDF         = "pbkdf2"
+	_keystorePRF         = "hmac-sha256"
+	_keystoreKeyLength   = 32
+	_keystoreIterations  = 262144
+	_keystoreSaltLength  = 32
+	_keystoreNonceLength = 16
+)
+
+// Encrypted keystore with the key type of the private key
+type keystoreData struct {
+	Version uint8  `json:"version"`
+	KeyType string `json:"keytype,omitempty"`
+	Crypto  struct {
+		CipherText   string `json:"ciphertext"`
+		CipherParams struct {
+			IV string `json:"iv"`
+		} `json:"cipherparams"`
+		Cipher    string `json:"cipher"`
+		KDF       string `json:"kdf"`
+		KDFParams struct {
+			DKLength int    `json:"dklength"`
+			Salt     string `json:"salt"`
+			Count    int    `json:"c"`
+			PRF      string `json:"prf"`
+		} `json:"kdfparams"`
+		Mac string `json:"mac"`
+	} `json:"crypto"`
+}
+
+// Keystore file in the configuration directory
+type KeystoreFile struct {
+	Path              string    // path of the keystore file
+	AccountID         string    // account ID derived from the file name (empty, if not derivable)
+	ModifiedTimestamp time.Time // the datetime the keystore file was last modified
+}
+
+// Public information of a decrypted keystore
+type KeystoreInfo struct {
+	KeystoreFile
+	PublicKey hederasdk.PublicKey // public key of the private key in the keystore
+
+	// Account information of the mirror node
+	AccountFound bool           // the mirror node knows the account
+	Balance      hederasdk.Hbar // balance of the account
+	KeyMatches   bool           // the public key is the key of the account
+}
+
+// KEYSTORE FILES
+// #############################################################################
+// List the keystore files in the configuration directory
+func (hm *PackageManager) ListKeystores() ([]KeystoreFile, error) {
+
+	paths, err := filepath.Glob(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "*.key"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	keystores := []KeystoreFile{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		keystore := KeystoreFile{Path: path, ModifiedTimestamp: info.ModTime()}
+		keystore.AccountID, _ = AccountIDFromKeystorePath(path)
+		keystores = append(keystores, keystore)
+	}
+
+	return keystores, nil
+
+}
+
+// Derive the account ID from the file name of a keystore
+// NOTE: Only account IDs of shard 0 and realm 0 can be derived, since the dots
+// of the account ID are removed from the file name.
+func AccountIDFromKeystorePath(path string) (string, bool) {
+
+	name := strings.TrimSuffix(filepath.Base(path), ".key")
+	if !strings.HasPrefix(name, "00") || len(name) < 3 {
+		return "", false
+	}
+
+	accountID, err := hederasdk.AccountIDFromString("0.0." + name[2:])
+	if err != nil {
+		return "", false
+	}
+
+	return accountID.String(), true
+
+}
+
+// Decrypt the keystore of an account and get its public information
+func (hm *PackageManager) InspectKeystore(accountID string, passphrase string) (*KeystoreInfo, error) {
+	var err error
+
+	// check the account ID
+	account, err := hederasdk.AccountIDFromString(accountID)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid account ID '%v': %v", accountID, err))
+	}
+
+	// get the keystore file
+	path := hm.KeystorePath(account.String())
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Could not find a keystore for account %v: %v", account.String(), err))
+	}
+	info := &KeystoreInfo{KeystoreFile: KeystoreFile{Path: path, AccountID: account.String(), ModifiedTimestamp: stat.ModTime()}}
+
+	// decrypt the keystore and keep only the public key
+	info.PublicKey, err = _readKeystorePublicKey(path, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	// query the account from the mirror node
+	if hm.MirrorNode.URL != "" {
+		accounts, err := hm.MirrorNode.GetAccountInfo(account.String(), 1, "")
+		if err != nil {
+			return info, errors.New(fmt.Sprintf("Could not query the account from the mirror node: %v", err))
+		}
+		if accounts != nil && len(*accounts) > 0 {
+			info.AccountFound = true
+			info.Balance = hederasdk.HbarFromTinybar((*accounts)[0].Balance.Balance)
+			info.KeyMatches = strings.EqualFold((*accounts)[0].Key.Key, info.PublicKey.StringRaw())
+		}
+	}
+
+	return info, nil
+
+}
+
+// helper function to read the public key of the private key in a keystore file
+func _readKeystorePublicKey(path string, passphrase string) (hederasdk.PublicKey, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return hederasdk.PublicKey{}, err
+	}
+	defer file.Close()
+
+	privateKey, err := ReadKeystore(file, passphrase)
+	if err != nil {
+		return hederasdk.PublicKey{}, errors.New(fmt.Sprintf("Could not decrypt the keystore '%v' (wrong passphrase?): %v", path, err))
+	}
+
+	return privateKey.PublicKey(), nil
+
+}
+
+// KEYSTORE FORMAT
+// #############################################################################
+// Get the key type of a private key
+func KeystoreKeyType(privateKey hederasdk.PrivateKey) string {
+
+	// NOTE: The compressed public key of an ECDSA (secp256k1) key has 33 bytes.
+	if len(privateKey.PublicKey().BytesRaw()) == 33 {
+		return KEYSTORE_KEY_TYPE_ECDSA
+	}
+
+	return KEYSTORE_KEY_TYPE_ED25519
+
+}
+
+// Encrypt a private key with the passphrase into a keystore
+func EncryptKeystore(privateKey hederasdk.PrivateKey, passphrase string) ([]byte, error) {
+
+	// ED25519 keys use the keystore format of the Hedera SDK
+	if KeystoreKeyType(privateKey) == KEYSTORE_KEY_TYPE_ED25519 {
+		return privateKey.Keystore(passphrase)
+	}
+
+	// derive the encryption key from the passphrase
+	salt := make([]byte, _keystoreSaltLength)
+	iv := make([]byte, _keystoreNonceLength)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+	key := pbkdf2.Key([]byte(passphrase), salt, _keystoreIterations, _keystoreKeyLength, sha256.New)
+
+	// encrypt the raw private key
+	block, err := aes.NewCipher(key[:16])
+	if err != nil {
+		return nil, err
+	}
+	plainText := privateKey.BytesRaw()
+	cipherText := make([]byte, len(plainText))
+	cipher.NewCTR(block, iv).XORKeyStream(cipherText, plainText)
+
+	// authenticate the cipher text
+	mac := hmac.New(sha512.New384, key[16:])
+	mac.Write(cipherText)
+
+	keystore := keystoreData{Version: _keystoreVersion, KeyType: KEYSTORE_KEY_TYPE_ECDSA}
+	keystore.Crypto.CipherText = hex.EncodeToString(cipherText)
+	keystore.Crypto.CipherParams.IV = hex.EncodeToString(iv)
+	keystore.Crypto.Cipher = _keystoreCipher
+	keystore.Crypto.KDF = _keystoreKDF
+	keystore.Crypto.KDFParams.DKLength = _keystoreKeyLength
+	keystore.Crypto.KDFParams.Salt = hex.EncodeToString(salt)
+	keystore.Crypto.KDFParams.Count = _keystoreIterations
+	keystore.Crypto.KDFParams.PRF = _keystorePRF
+	keystore.Crypto.Mac = hex.EncodeToString(mac.Sum(nil))
+
+	return json.Marshal(keystore)
+
+}
+
+// Decrypt a keystore with the passphrase
+func DecryptKeystore(data []byte, passphrase string) (hederasdk.PrivateKey, error) {
+
+	var keystore keystoreData
+	err := json.Unmarshal(data, &keystore)
+	if err != nil {
+		return hederasdk.PrivateKey{}, err
+	}
+
+	// keystores without key type were written by the Hedera SDK
+	switch keystore.KeyType {
+	case "", KEYSTORE_KEY_TYPE_ED25519:
+		return hederasdk.PrivateKeyFromKeystore(data, passphrase)
+	case KEYSTORE_KEY_TYPE_ECDSA:
+	default:
+		return hederasdk.PrivateKey{}, errors.New(fmt.Sprintf("Unsupported key type '%v' of the keystore.", keystore.KeyType))
+	}
+
+	// check the encryption parameters
+	if keystore.Version != _keystoreVersion || keystore.Crypto.Cipher != _keystoreCipher || keystore.Crypto.KDF != _keystoreKDF || keystore.Crypto.KDFParams.PRF != _keystorePRF || keystore.Crypto.KDFParams.DKLength != _keystoreKeyLength {
+		return hederasdk.PrivateKey{}, errors.New("Unsupported encryption parameters of the keystore.")
+	}
+	salt, err := hex.DecodeString(keystore.Crypto.KDFParams.Salt)
+	if err != nil {
+		return hederasdk.PrivateKey{}, err
+	}
+	iv, err := hex.DecodeString(keystore.Crypto.CipherParams.IV)
+	if err != nil || len(iv) != aes.BlockSize {
+		return hederasdk.PrivateKey{}, errors.New("Invalid initialization vector of the keystore.")
+	}
+	cipherText, err := hex.DecodeString(keystore.Crypto.CipherText)
+	if err != nil {
+		return hederasdk.PrivateKey{}, err
+	}
+	expectedMac, err := hex.DecodeString(keystore.Crypto.Mac)
+	if err != nil {
+		return hederasdk.PrivateKey{}, err
+	}
+
+	// verify the passphrase before decrypting
+	key := pbkdf2.Key([]byte(passphrase), salt, keystore.Crypto.KDFParams.Count, _keystoreKeyLength, sha256.New)
+	mac := hmac.New(sha512.New384, key[16:])
+	mac.Write(cipherText)
+	if subtle.ConstantTimeCompare(mac.Sum(nil), expectedMac) == 0 {
+		return hederasdk.PrivateKey{}, errors.New("hmac mismatch; passphrase is incorrect")
+	}
+
+	// decrypt the raw private key
+	block, err := aes.NewCipher(key[:16])
+	if err != nil {
+		return hederasdk.PrivateKey{}, err
+	}
+	plainText := make([]byte, len(cipherText))
+	cipher.NewCTR(block, iv).XORKeyStream(plainText, cipherText)
+
+	return hederasdk.PrivateKeyFromBytesECDSA(plainText)
+
+}
+
+// Read and decrypt a keystore with the passphrase
+func ReadKeystore(source io.Reader, passphrase string) (hederasdk.PrivateKey, error) {
+
+	data, err := io.ReadAll(source)
+	if err != nil {
+		return hederasdk.PrivateKey{}, err
+	}
+
+	return DecryptKeystore(data, passphrase)
+
+}
+
+// COMMAND LINE INTERFACE - KEYSTORES
+// #############################################################################
+// Read a passphrase from the environment or prompt for it
+// NOTE: The environment is checked first, so that scripts do not block.
+func ReadPassphrase(cmd *cobra.Command, prompt string) (string, error) {
+
+	passphrase, err := _readSecret(os.LookupEnv, RENDERHIVE_ENV_PASSPHRASE)
+	if err != nil || passphrase != "" {
+		return passphrase, err
+	}
+
+	return PromptPassphrase(cmd, prompt)
+
+}
+
+// Read a private key from the environment or prompt for it
+// NOTE: Like passphrases, private keys are never passed as arguments.
+func ReadPrivateKey(cmd *cobra.Command, prompt string) (string, error) {
+
+	privateKey, err := _readSecret(os.LookupEnv, RENDERHIVE_ENV_PRIVATE_KEY)
+	if err != nil || privateKey != "" {
+		return strings.TrimSpace(privateKey), err
+	}
+
+	privateKey, err = PromptPassphrase(cmd, prompt)
+	if err != nil {
+		return "", errors.New("Could not read the private key.")
+	}
+
+	return strings.TrimSpace(privateKey), nil
+
+}
+
+// Prompt for a passphrase on the input of the command
+// NOTE: The passphrase is not echoed, if the input is a terminal. Otherwise,
+// the prompts of a command share a reader, so that piped input is not lost
+// between them.
+func PromptPassphrase(cmd *cobra.Command, prompt string) (string, error) {
+
+	logger.Manager.Printf("%v: ", prompt)
+
+	// read the passphrase from the terminal without echo
+	input := cmd.InOrStdin()
+	if file, ok := input.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+		passphrase, err := term.ReadPassword(int(file.Fd()))
+		logger.Manager.Println("")
+		if err != nil {
+			return "", errors.New("Could not read the passphrase.")
+		}
+		return string(passphrase), nil
+	}
+
+	line, err := _promptReader(input).ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", errors.New("Could not read the passphrase.")
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+
+}
+
+// Check if the passphrase is set in the environment
+func PassphraseInEnvironment() bool {
+	passphrase, err := _readSecret(os.LookupEnv, RENDERHIVE_ENV_PASSPHRASE)
+	return err == nil && passphrase != ""
+}
+
+// buffered reader of the prompts (shared by the prompts on the same input)
+var promptInput = struct {
+	sync.Mutex
+	input  io.Reader
+	reader *bufio.Reader
+}{}
+
+// helper function to get the buffered reader of the prompts on the input
+func _promptReader(input io.Reader) *bufio.Reader {
+
+	promptInput.Lock()
+	defer promptInput.Unlock()
+
+	if promptInput.reader == nil || promptInput.input != input {
+		promptInput.input = input
+		promptInput.reader = bufio.NewReader(input)
+	}
+
+	return promptInput.reader
+
+}
+
+// Create the CLI command to list the keystore files in the configuration directory
+func (hm *PackageManager) CreateCommandAccount_List() *cobra.Command {
+
+	// create a 'account list' command for the node
+	command := &cobra.Command{
+		Use:   "list",
+		Short: "List the keystore files in the config directory",
+		Long:  "This command lists the keystore files in the config directory and the account IDs derived from their file names. The keystores are not decrypted.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			keystores, err := hm.ListKeystores()
+			if err != nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not list the keystores: %w", err)
+
+			}
+
+			logger.Manager.Println("")
+			if len(keystores) == 0 {
+				logger.Manager.Println("There are no keystore files in the config directory.")
+				logger.Manager.Println("")
+				return nil
+			}
+
+			logger.Manager.Println("Keystore files in the config directory:")
+			for _, keystore := range keystores {
+				accountID := keystore.AccountID
+				if accountID == "" {
+					accountID = "unknown"
+				}
+				if keystore.AccountID != "" && keystore.AccountID == hm.Operator.AccountID.String() {
+					accountID += " (operator)"
+				}
+				logger.Manager.Resultf(" [#] %v: %v (modified: %v)\n", filepath.Base(keystore.Path), accountID, keystore.ModifiedTimestamp.Format(time.RFC3339))
+			}
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	return command
+
+}
+
+// Create the CLI command to decrypt a keystore and print the public account information
+func (hm *PackageManager) CreateCommandAccount_Info() *cobra.Command {
+
+	// create a 'account info' command for the node
+	command := &cobra.Command{
+		Use:   "info <account-id>",
+		Short: "Print the public key and balance of a keystore's account",
+		Long:  "This command decrypts the keystore of the given account with the passphrase and prints its public key. The balance and the key of the account are queried from the mirror node. The private key is never printed. The passphrase is read from the RENDERHIVE_PASSPHRASE environment variable or prompted for.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// read the passphrase
+			passphrase, err := ReadPassphrase(cmd, "Passphrase of the keystore")
+			if err != nil {
+				return err
+			}
+
+			info, err := hm.InspectKeystore(args[0], passphrase)
+			if info == nil {
+
+				logger.Manager.Println("")
+				return err
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Println("Keystore information:")
+			logger.Manager.Resultf(" [#] Keystore: %v\n", info.Path)
+			logger.Manager.Resultf(" [#] Account ID: %v\n", info.AccountID)
+			logger.Manager.Resultf(" [#] Public key: %v\n", info.PublicKey)
+			if err != nil {
+				logger.Manager.Println("")
+				return err
+			}
+			if info.AccountFound {
+				logger.Manager.Resultf(" [#] Balance: %v\n", info.Balance)
+				logger.Manager.Resultf(" [#] Key matches the account: %v\n", info.KeyMatches)
+			} else {
+				logger.Manager.Resultf(" [#] Balance: unknown (account not found on the mirror node)\n")
+			}
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	return command
+
+}
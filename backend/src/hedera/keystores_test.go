@@ -0,0 +1,305 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+import (
+
+	// standard
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+	"github.com/spf13/cobra"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+func TestKeystoreRoundTrip(t *testing.T) {
+	logger.Manager.Init()
+	for _, keyType := range []string{KEYSTORE_KEY_TYPE_ED25519, KEYSTORE_KEY_TYPE_ECDSA} {
+		var account HederaAccount
+		if err := account.GenerateKey(keyType); err != nil {
+			t.Fatal(err)
+		}
+		if got := KeystoreKeyType(account.PrivateKey); got != keyType {
+			t.Fatalf("%v: unexpected key type %v", keyType, got)
+		}
+
+		// the keystore is written without leftovers and only readable by the owner
+		dir := t.TempDir()
+		path := filepath.Join(dir, "001001.key")
+		if err := account.ToFile(path, "passphrase"); err != nil {
+			t.Fatalf("%v: %v", keyType, err)
+		}
+		stat, err := os.Stat(path)
+		if err != nil || stat.Size() == 0 || stat.Mode().Perm() != 0600 {
+			t.Fatalf("%v: unexpected keystore file: %v, %v", keyType, stat, err)
+		}
+		if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+			t.Fatalf("%v: the temporary keystore was not removed", keyType)
+		}
+
+		// the private key is restored with the correct type
+		var restored HederaAccount
+		if err := restored.FromFile(path, "passphrase", account.PublicKey.String()); err != nil {
+			t.Fatalf("%v: %v", keyType, err)
+		}
+		if restored.PrivateKey.String() != account.PrivateKey.String() {
+			t.Fatalf("%v: the restored key differs", keyType)
+		}
+
+		// a wrong passphrase is rejected
+		if err := restored.FromFile(path, "wrong", account.PublicKey.String()); err == nil {
+			t.Fatalf("%v: expected an error for a wrong passphrase", keyType)
+		}
+	}
+}
+
+func TestDecryptKeystoreRejectsUnknownKeyType(t *testing.T) {
+	key, err := hederasdk.PrivateKeyGenerateEcdsa()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := EncryptKeystore(key, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = bytes.Replace(data, []byte(`"keytype":"ecdsa"`), []byte(`"keytype":"rsa"`), 1)
+	if _, err := DecryptKeystore(data, "passphrase"); err == nil {
+		t.Fatal("expected an error for an unknown key type")
+	}
+}
+
+func TestPromptPassphrase(t *testing.T) {
+	logger.Manager.Init()
+	cmd := &cobra.Command{}
+	cmd.SetIn(strings.NewReader("my secret phrase\r\nnext line\n"))
+
+	passphrase, err := PromptPassphrase(cmd, "Passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if passphrase != "my secret phrase" {
+		t.Fatalf("unexpected passphrase: %q", passphrase)
+	}
+
+	// an empty input is an error
+	cmd.SetIn(strings.NewReader(""))
+	if _, err := PromptPassphrase(cmd, "Passphrase"); err == nil {
+		t.Fatal("expected an error for an empty input")
+	}
+}
+
+func TestPromptPassphraseSharesThePipedInput(t *testing.T) {
+	logger.Manager.Init()
+	cmd := &cobra.Command{}
+	cmd.SetIn(strings.NewReader("old passphrase\nnew passphrase\n"))
+
+	// the second prompt gets the second line (e.g., of 'account rotate')
+	for _, want := range []string{"old passphrase", "new passphrase"} {
+		passphrase, err := PromptPassphrase(cmd, "Passphrase")
+		if err != nil || passphrase != want {
+			t.Fatalf("got %q (%v), want %q", passphrase, err, want)
+		}
+	}
+}
+
+func TestPassphraseInEnvironment(t *testing.T) {
+	t.Setenv(RENDERHIVE_ENV_PASSPHRASE, "")
+	t.Setenv(RENDERHIVE_ENV_PASSPHRASE+RENDERHIVE_ENV_FILE_SUFFIX, "")
+	if PassphraseInEnvironment() {
+		t.Fatal("expected no passphrase in the environment")
+	}
+
+	// the passphrase is read from the variable or its file
+	t.Setenv(RENDERHIVE_ENV_PASSPHRASE, "passphrase")
+	if !PassphraseInEnvironment() {
+		t.Fatal("expected the passphrase of the variable")
+	}
+	t.Setenv(RENDERHIVE_ENV_PASSPHRASE, "")
+	path := filepath.Join(t.TempDir(), "passphrase")
+	os.WriteFile(path, []byte("passphrase\n"), 0600)
+	t.Setenv(RENDERHIVE_ENV_PASSPHRASE+RENDERHIVE_ENV_FILE_SUFFIX, path)
+	if !PassphraseInEnvironment() {
+		t.Fatal("expected the passphrase of the file")
+	}
+}
+
+func TestReadPrivateKey(t *testing.T) {
+	logger.Manager.Init()
+	cmd := &cobra.Command{}
+
+	// the environment is read first
+	t.Setenv(RENDERHIVE_ENV_PRIVATE_KEY, " 302e020100300506032b657004220420abc\n")
+	cmd.SetIn(strings.NewReader("prompted key\n"))
+	privateKey, err := ReadPrivateKey(cmd, "Private key")
+	if err != nil || privateKey != "302e020100300506032b657004220420abc" {
+		t.Fatalf("got %q (%v), want the key of the environment", privateKey, err)
+	}
+
+	// otherwise, the private key is prompted for
+	t.Setenv(RENDERHIVE_ENV_PRIVATE_KEY, "")
+	privateKey, err = ReadPrivateKey(cmd, "Private key")
+	if err != nil || privateKey != "prompted key" {
+		t.Fatalf("got %q (%v), want the prompted key", privateKey, err)
+	}
+}
+
+func TestAccountImportRejectsArguments(t *testing.T) {
+	logger.Manager.Init()
+	var hm PackageManager
+
+	// the private key is never accepted as an argument
+	command := hm.CreateCommandAccount_Import()
+	if err := command.Args(command, []string{"302e020100300506032b657004220420abc"}); err == nil {
+		t.Fatal("expected an error for a private key argument")
+	}
+	if err := command.Args(command, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// helper function to run a test in a temporary working directory, which holds
+// the configuration directory
+func _chdirTemp(t *testing.T) string {
+	dir := t.TempDir()
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+
+	return dir
+}
+
+func TestAccountIDFromKeystorePath(t *testing.T) {
+	tests := map[string]string{
+		"config/001234.key": "0.0.1234",
+		"001.key":           "0.0.1",
+		"1234.key":          "",
+		"00.key":            "",
+		"00abc.key":         "",
+	}
+	for path, want := range tests {
+		got, ok := AccountIDFromKeystorePath(path)
+		if got != want || ok != (want != "") {
+			t.Errorf("%v: got %q (%v), want %q", path, got, ok, want)
+		}
+	}
+}
+
+func TestListAndInspectKeystores(t *testing.T) {
+	logger.Manager.Init()
+	_chdirTemp(t)
+
+	// create a keystore of the account
+	var account HederaAccount
+	if err := account.GenerateKey(KEYSTORE_KEY_TYPE_ED25519); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll("config", 0700); err != nil {
+		t.Fatal(err)
+	}
+	hm := &PackageManager{}
+	if err := account.ToFile(hm.KeystorePath("0.0.1001"), "passphrase"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the keystore is listed without decrypting it
+	keystores, err := hm.ListKeystores()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keystores) != 1 || keystores[0].AccountID != "0.0.1001" {
+		t.Fatalf("unexpected keystores: %+v", keystores)
+	}
+
+	// the mirror node knows the account with the key of the keystore
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"accounts":[{"account":"0.0.1001","balance":{"balance":500000000},"key":{"_type":"ED25519","key":"%v"}}]}`, account.PublicKey.StringRaw())
+	}))
+	defer mirror.Close()
+	hm.MirrorNode.URL = mirror.URL
+
+	info, err := hm.InspectKeystore("0.0.1001", "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.PublicKey.String() != account.PublicKey.String() || !info.AccountFound || !info.KeyMatches {
+		t.Fatalf("unexpected keystore information: %+v", info)
+	}
+	if info.Balance.AsTinybar() != 500000000 {
+		t.Fatalf("unexpected balance: %v", info.Balance)
+	}
+
+	// a wrong passphrase does not reveal anything
+	if info, err := hm.InspectKeystore("0.0.1001", "wrong"); err == nil || info != nil {
+		t.Fatalf("expected an error for a wrong passphrase, got %+v", info)
+	}
+}
+
+func TestAccountInfoCommandPromptsForPassphrase(t *testing.T) {
+	logger.Manager.Init()
+	_chdirTemp(t)
+	os.Unsetenv(RENDERHIVE_ENV_PASSPHRASE)
+	os.Unsetenv(RENDERHIVE_ENV_PASSPHRASE + RENDERHIVE_ENV_FILE_SUFFIX)
+
+	var account HederaAccount
+	if err := account.GenerateKey(KEYSTORE_KEY_TYPE_ED25519); err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll("config", 0700)
+	hm := &PackageManager{}
+	if err := account.ToFile(hm.KeystorePath("0.0.1001"), "passphrase"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the command has no passphrase flag
+	command := hm.CreateCommandAccount_Info()
+	if command.Flags().Lookup("passphrase") != nil {
+		t.Fatal("the passphrase must not be a command line flag")
+	}
+
+	// the passphrase is read from the input
+	command.SetIn(strings.NewReader("passphrase\n"))
+	command.SetArgs([]string{"0.0.1001"})
+	command.SilenceUsage = true
+	command.SilenceErrors = true
+	if err := command.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	command.SetIn(strings.NewReader("wrong\n"))
+	if err := command.Execute(); err == nil {
+		t.Fatal("expected an error for a wrong passphrase")
+	}
+}
@@ -0,0 +1,98 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+import (
+
+	// standard
+	"strings"
+	"testing"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	"renderhive/logger"
+)
+
+func TestNodeTopicMemo(t *testing.T) {
+	if memo := NodeTopicMemo("0.0.1001"); memo != "renderhive-node::0.0.1001" {
+		t.Errorf("got memo %q, want 'renderhive-node::0.0.1001'", memo)
+	}
+
+	// the account ID can be parsed from the memo
+	memo := NodeTopicMemo("0.0.4515311")
+	accountID, err := hederasdk.AccountIDFromString(strings.TrimPrefix(memo, "renderhive-node::"))
+	if err != nil || accountID.String() != "0.0.4515311" {
+		t.Errorf("got account %v (%v) from memo %q", accountID, err, memo)
+	}
+
+	// the memo of the largest account IDs fits into a topic memo (100 bytes)
+	if memo := NodeTopicMemo("18446744073709551615.18446744073709551615.18446744073709551615"); len(memo) > 100 {
+		t.Errorf("got a memo of %v bytes, want at most 100", len(memo))
+	}
+}
+
+func TestCreateNodeTopicForWalletSignature(t *testing.T) {
+	defaultClient, defaultFees := Manager.NetworkClient, Manager.Fees
+	t.Cleanup(func() { Manager.NetworkClient, Manager.Fees = defaultClient, defaultFees })
+
+	key, err := hederasdk.PrivateKeyGenerateEd25519()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeAccount, _ := hederasdk.AccountIDFromString("0.0.3")
+	operator, _ := hederasdk.AccountIDFromString("0.0.1001")
+	Manager.NetworkClient = hederasdk.ClientForNetwork(map[string]hederasdk.AccountID{"127.0.0.1:50211": nodeAccount})
+	Manager.Fees = DefaultFeeLimits()
+	hm := &PackageManager{}
+	hm.Operator.AccountID, hm.Operator.PrivateKey, hm.Operator.PublicKey = operator, key, key.PublicKey()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+	if err := hm.History.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the transaction bytes create a topic with the memo and keys of the node
+	topic, transactionBytes, err := hm.CreateNodeTopic(TransactionOptions.SetExecute(false, operator))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if topic != nil || len(transactionBytes) == 0 {
+		t.Fatalf("got topic %v and %v bytes, want the transaction bytes only", topic, len(transactionBytes))
+	}
+	transaction, err := hederasdk.TransactionFromBytes(transactionBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	create, ok := transaction.(hederasdk.TopicCreateTransaction)
+	if !ok {
+		t.Fatalf("got a %T, want a topic create transaction", transaction)
+	}
+	if create.GetTopicMemo() != "renderhive-node::0.0.1001" {
+		t.Errorf("got memo %q, want 'renderhive-node::0.0.1001'", create.GetTopicMemo())
+	}
+	adminKey, _ := create.GetAdminKey()
+	submitKey, _ := create.GetSubmitKey()
+	if adminKey.String() != key.PublicKey().String() || submitKey.String() != key.PublicKey().String() {
+		t.Errorf("got admin key %v and submit key %v, want the operator key", adminKey, submitKey)
+	}
+}
@@ -30,8 +30,10 @@ services. This also includes a crypto wallet with very basic functionality.
 import (
 
 	// standard
+	"context"
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -43,6 +45,7 @@ import (
 	// internal
 	. "renderhive/globals"
 	"renderhive/logger"
+	"renderhive/utility"
 )
 
 // define the network types
@@ -65,6 +68,21 @@ type PackageManager struct {
 	// Mirror Node
 	MirrorNode MirrorNode
 
+	// Fee caps of the transactions and queries
+	Fees FeeLimits
+
+	// Supervised topic subscriptions of this node
+	Subscriptions []*TopicSubscription
+
+	// Transaction history of this node
+	History TransactionHistory
+
+	// Audit log of the fund-moving operations of this node
+	Audit AuditLog
+
+	// Recently queried account balances (see balance.go)
+	balances balanceCache
+
 	// Command line interface
 	Command      *cobra.Command
 	CommandFlags struct {
@@ -113,17 +131,41 @@ func (hm *PackageManager) Init(NetworkType int) error {
 	// set network type
 	hm.NetworkType = NetworkType
 
+	// apply the fee caps
+	err = hm.LoadFeeLimits()
+	if err != nil {
+		logger.Manager.Package["hedera"].Warn().Msg(err.Error())
+	}
+	logger.Manager.Package["hedera"].Debug().Msg(fmt.Sprintf(" [#] Fee caps: %v per transaction, %v per query", hm.Fees.TransactionFee(), hm.Fees.QueryPayment()))
+
 	// get the mirror node URL
 	hm.MirrorNode.URL = HEDERA_TESTNET_MIRROR_NODE_URL
 
 	// log info
 	logger.Manager.Main.Info().Msg(fmt.Sprintf(" [#] Mirror node: %v", hm.MirrorNode.URL))
 
+	// load the transaction history
+	err = hm.History.Load()
+	if err != nil {
+		return err
+	}
+
+	// load the audit log
+	err = hm.Audit.Load()
+	if err != nil {
+		return err
+	}
+
 	return err
 }
 
 // Load the account from the local data
 func (hm *PackageManager) LoadAccount(account_id string, passphrase string, publickey string) error {
+	return hm.LoadAccountFromKeystore(account_id, hm.KeystorePath(account_id), passphrase, publickey)
+}
+
+// Load the account from the given keystore file
+func (hm *PackageManager) LoadAccountFromKeystore(account_id string, keystore string, passphrase string, publickey string) error {
 	var err error
 
 	// read the node account ID into the node manager
@@ -139,7 +181,7 @@ func (hm *PackageManager) LoadAccount(account_id string, passphrase string, publ
 	//		 This needs to be improved from a security standpoint!!!
 
 	// read the private key from the keystore file and decrypt it
-	err = hm.Operator.FromFile(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, strings.ReplaceAll(account_id, ".", "")+".key"), passphrase, publickey)
+	err = hm.Operator.FromFile(keystore, passphrase, publickey)
 	if err != nil {
 		return err
 	}
@@ -150,6 +192,9 @@ func (hm *PackageManager) LoadAccount(account_id string, passphrase string, publ
 	// set this account as the operator
 	hm.NetworkClient.SetOperator(hm.Operator.AccountID, hm.Operator.PrivateKey)
 
+	// anchor the head of the audit log with the key of the operator
+	hm.Audit.SetKey(hm.Operator.PrivateKey)
+
 	// // query the account balance from the Hedera network
 	// queryCost, err := hm.Operator.QueryBalance(hm)
 	// logger.Manager.Package["hedera"].Info().Msg(fmt.Sprintf(" [#] Account Balance: %v", hm.Operator.Info.Balance))
@@ -157,7 +202,7 @@ func (hm *PackageManager) LoadAccount(account_id string, passphrase string, publ
 
 	// query the complete account information from the Hedera network
 	queryCost, err := hm.Operator.QueryInfo(hm)
-	logger.Manager.Package["hedera"].Info().Msg(fmt.Sprintf(" [#] Account Balance: %v", hm.Operator.Info.Balance))
+	logger.Manager.Package["hedera"].Info().Msg(fmt.Sprintf(" [#] Account Balance: %v", hm.Operator.GetBalance()))
 	logger.Manager.Package["hedera"].Debug().Msg(fmt.Sprintf(" [#] Costs (QueryInfo): %v", queryCost))
 
 	return err
@@ -189,6 +234,9 @@ func (hm *PackageManager) SetAccount(account_id string, privatekey string) error
 	// set this account as the operator
 	hm.NetworkClient.SetOperator(hm.Operator.AccountID, hm.Operator.PrivateKey)
 
+	// anchor the head of the audit log with the key of the operator
+	hm.Audit.SetKey(hm.Operator.PrivateKey)
+
 	// // query the account balance from the Hedera network
 	// queryCost, err := hm.Operator.QueryBalance(hm)
 	// logger.Manager.Package["hedera"].Info().Msg(fmt.Sprintf(" [#] Account Balance: %v", hm.Operator.Info.Balance))
@@ -196,12 +244,17 @@ func (hm *PackageManager) SetAccount(account_id string, privatekey string) error
 
 	// query the complete account information from the Hedera network
 	queryCost, err := hm.Operator.QueryInfo(hm)
-	logger.Manager.Package["hedera"].Info().Msg(fmt.Sprintf(" [#] Account Balance: %v", hm.Operator.Info.Balance))
+	logger.Manager.Package["hedera"].Info().Msg(fmt.Sprintf(" [#] Account Balance: %v", hm.Operator.GetBalance()))
 	logger.Manager.Package["hedera"].Debug().Msg(fmt.Sprintf(" [#] Costs (QueryInfo): %v", queryCost))
 
 	return err
 }
 
+// Get the path of the keystore file for the given account ID
+func (hm *PackageManager) KeystorePath(account_id string) string {
+	return filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, strings.ReplaceAll(account_id, ".", "")+".key")
+}
+
 // Deinitialize the Hedera manager
 func (hm *PackageManager) DeInit() error {
 	var err error
@@ -209,6 +262,12 @@ func (hm *PackageManager) DeInit() error {
 	// log event
 	logger.Manager.Package["hedera"].Debug().Msg("Deinitializing the Hedera manager ...")
 
+	// close the topic subscriptions
+	for _, subscription := range hm.Subscriptions {
+		subscription.Close()
+	}
+	hm.Subscriptions = nil
+
 	return err
 
 }
@@ -227,6 +286,9 @@ type TransactionSettings struct {
 	Schedule              bool
 	ScheduleExpiration    time.Time
 	ScheduleWaitForExpiry bool
+
+	// NOTE: has been implemented for the smart contract calls only
+	Context context.Context
 }
 
 // create a settings object with the given options
@@ -237,6 +299,7 @@ func MakeTransactionSettings(options ...TransactionOption) (*TransactionSettings
 		Execute:            true,
 		Schedule:           false,
 		ScheduleExpiration: time.Unix(0, 0),
+		Context:            context.Background(),
 	}
 
 	// apply each option to the settings object
@@ -275,6 +338,90 @@ func (TransactionOpts) SetSchedule(use bool, experiationTime time.Time, wait boo
 	}
 }
 
+// SetContext specifies the context, which limits how long the caller waits for the network
+func (TransactionOpts) SetContext(ctx context.Context) TransactionOption {
+	return func(settings *TransactionSettings) error {
+		settings.Context = ctx
+		return nil
+	}
+}
+
+// Run a blocking network call and report, if the context was done before it
+// returned
+// NOTE: The Hedera SDK does not support contexts, so the call always runs to its
+// end, while the caller keeps its locks. If the context is done in the meantime,
+// the results of the call are discarded.
+func Await(ctx context.Context, call func()) error {
+
+	// the context is already done
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	// wait for the call
+	call()
+
+	return ctx.Err()
+
+}
+
+// Get the record of a transaction (or give up, when the context is done)
+func GetRecord(ctx context.Context, response *hederasdk.TransactionResponse) (hederasdk.TransactionRecord, error) {
+	var record hederasdk.TransactionRecord
+	var err error
+
+	awaitErr := Await(ctx, func() {
+		record, err = response.GetRecord(Manager.NetworkClient)
+	})
+	if awaitErr != nil {
+		return hederasdk.TransactionRecord{}, fmt.Errorf("Gave up waiting for the record of transaction %v: %w", response.TransactionID, awaitErr)
+	}
+
+	return record, err
+
+}
+
+// helper function to execute a frozen transaction and get its receipt (or give
+// up, when the context is done)
+// NOTE: If the context is done first, the transaction may still be executed. Its
+// result is recorded in the transaction history, when it finished.
+func _TransactionExecute(ctx context.Context, transaction interface{}, transactionID string) (*hederasdk.TransactionReceipt, error) {
+	var transactionReceipt hederasdk.TransactionReceipt
+	var executeErr, receiptErr error
+
+	err := Await(ctx, func() {
+
+		// get the transaction response
+		transactionResponse, err := hederasdk.TransactionExecute(transaction, Manager.NetworkClient)
+		if err != nil {
+			Manager.History.Update(transactionID, nil, err)
+			executeErr = _feeCapError(err)
+			return
+		}
+
+		// get the transaction receipt
+		transactionReceipt, err = transactionResponse.GetReceipt(Manager.NetworkClient)
+		Manager.History.Update(transactionID, &transactionReceipt, err)
+		if err != nil {
+			receiptErr = _feeCapError(err)
+		}
+
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Gave up waiting for transaction %v (it may still be executed): %w", transactionID, err)
+	}
+	if executeErr != nil {
+		return nil, executeErr
+	}
+	if receiptErr != nil {
+		return nil, receiptErr
+	}
+
+	return &transactionReceipt, nil
+
+}
+
 // helper function to freeze a transaction for signature by an external wallet
 func _TransactionFreeze(_transaction interface{}, options ...TransactionOption) (interface{}, error) {
 	var err error
@@ -286,6 +433,12 @@ func _TransactionFreeze(_transaction interface{}, options ...TransactionOption)
 		return nil, err
 	}
 
+	// cap the transaction fee
+	_transaction, err = _TransactionSetFeeLimit(_transaction)
+	if err != nil {
+		return nil, err
+	}
+
 	// if the transaction shall NOT be directly executed
 	if settings.Execute == false {
 
@@ -831,6 +984,22 @@ func _TransactionSchedule(_transaction interface{}, options ...TransactionOption
 
 }
 
+// Get the transaction ID of frozen transaction bytes
+func TransactionIDFromBytes(transactionBytes []byte) (string, error) {
+
+	transaction, err := hederasdk.TransactionFromBytes(transactionBytes)
+	if err != nil {
+		return "", err
+	}
+	transactionID, err := hederasdk.TransactionGetTransactionID(transaction)
+	if err != nil {
+		return "", err
+	}
+
+	return transactionID.String(), nil
+
+}
+
 // helper function that takes any transaction bytes, signs it with the current operator, and sends it to the Hedera network
 func _ExecuteWithClient(transactionBytes []byte) (*hederasdk.TransactionReceipt, error) {
 	var err error
@@ -884,20 +1053,98 @@ func (hm *PackageManager) TopicInfoFromString(topicID string) (*HederaTopic, err
 }
 
 // Subscribe to the topic
+// NOTE: The subscription is supervised and resubscribed from the last received
+// message, if its stream ends.
 func (hm *PackageManager) TopicSubscribe(topic *HederaTopic, startTime time.Time, onNext func(message hederasdk.TopicMessage)) error {
 	var err error
 
 	logger.Manager.Package["hedera"].Debug().Msg(fmt.Sprintf("Subscribe to topic with ID %v.", topic.ID))
 
 	// subscribe to the topic
-	err = topic.Subscribe(startTime, onNext)
+	subscription, err := topic.SubscribeSupervised(startTime, onNext)
 	if err != nil {
 		return err
 	}
+	hm.Subscriptions = append(hm.Subscriptions, subscription)
 
 	return err
 }
 
+// Get the memo of the HCS topic of a render node
+func NodeTopicMemo(nodeAccountID string) string {
+	return HEDERA_NODE_TOPIC_MEMO_PREFIX + nodeAccountID
+}
+
+// Create the HCS topic of this render node
+// NOTE: The node account is the admin and the only submitter of the topic.
+// With 'TransactionOptions.SetExecute(false, ...)', the transaction bytes are
+// returned for the signature by an external wallet and the topic is nil.
+func (hm *PackageManager) CreateNodeTopic(options ...TransactionOption) (*HederaTopic, []byte, error) {
+	var err error
+	var transaction interface{}
+
+	// get the settings for the transaction
+	settings, err := MakeTransactionSettings(options...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// create the topic with the memo convention of the render nodes
+	memo := NodeTopicMemo(hm.Operator.AccountID.String())
+	transaction = hederasdk.NewTopicCreateTransaction().
+		SetTopicMemo(memo).
+		SetAdminKey(hm.Operator.PublicKey).
+		SetSubmitKey(hm.Operator.PublicKey)
+
+	// freeze the transaction for signing
+	transaction, err = _TransactionFreeze(transaction, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// add the transaction to the transaction history
+	transactionID := hm.History.Record(transaction, TRANSACTION_TYPE_TOPIC_CREATE, fmt.Sprintf("topic '%v'", memo), settings.Execute)
+
+	// if the transaction should be directly executed
+	if settings.Execute {
+
+		// sign with the node account (the client operator) and submit the transaction
+		transactionResponse, err := hederasdk.TransactionExecute(transaction, hm.NetworkClient)
+		if err != nil {
+			hm.History.Update(transactionID, nil, err)
+			return nil, nil, _feeCapError(err)
+		}
+
+		// get the topic ID from the transaction receipt
+		transactionReceipt, err := transactionResponse.GetReceipt(hm.NetworkClient)
+		hm.History.Update(transactionID, &transactionReceipt, err)
+		if err != nil {
+			return nil, nil, _feeCapError(err)
+		}
+		if transactionReceipt.TopicID == nil {
+			return nil, nil, errors.New("The transaction receipt contains no topic ID.")
+		}
+
+		// log event
+		logger.Manager.Package["hedera"].Info().Msg(fmt.Sprintf("Created the node topic %v ('%v').", transactionReceipt.TopicID, memo))
+
+		topic := &HederaTopic{ID: *transactionReceipt.TopicID}
+		topic.Info.TopicMemo = memo
+
+		return topic, nil, nil
+
+	}
+
+	// get the transaction bytes
+	transactionBytes, err := hederasdk.TransactionToBytes(transaction)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return nil, transactionBytes, nil
+
+}
+
 // HEDERA MANAGER COMMAND LINE INTERFACE
 // #############################################################################
 // Create the command for the command line interface
@@ -908,13 +1155,620 @@ func (hm *PackageManager) CreateCommand() *cobra.Command {
 		Use:   "hedera",
 		Short: "Commands for the interaction with the Hedera services",
 		Long:  "This command and its sub-commands enable the interaction with the Hedera services required by the Renderhive network",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
-			return
+			return nil
 
 		},
 	}
 
+	// add the subcommands
+	hm.Command.AddCommand(hm.CreateCommandAccount())
+	hm.Command.AddCommand(hm.CreateCommandHistory())
+	hm.Command.AddCommand(hm.CreateCommandReport())
+	hm.Command.AddCommand(hm.CreateCommandContract())
+	hm.Command.AddCommand(hm.CreateCommandAudit())
+
 	return hm.Command
 
 }
+
+// Create the CLI command to list the transaction history of this node
+func (hm *PackageManager) CreateCommandHistory() *cobra.Command {
+
+	// flags for the 'history' command
+	var filter TransactionFilter
+	var since time.Duration
+	var refresh bool
+
+	// create a 'history' command for the node
+	command := &cobra.Command{
+		Use:   "history",
+		Short: "List the transaction history of this node",
+		Long:  "This command lists the smart contract calls and topic messages this node prepared or submitted to the Hedera network together with their transaction IDs, status, and charged fees.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			// update the pending transactions from the mirror node
+			if refresh {
+				err = hm.History.Refresh(&hm.MirrorNode)
+				if err != nil {
+
+					logger.Manager.Println("")
+					return fmt.Errorf("Could not refresh the transaction history: %w", err)
+
+				}
+			}
+
+			// only list the transactions of the given time span
+			if since > 0 {
+				filter.Since = time.Now().Add(-since)
+			}
+
+			// list the transactions
+			records := hm.History.List(filter)
+			logger.Manager.Println("")
+			logger.Manager.Printf("Found %v transactions in the transaction history:\n", len(records))
+			for _, record := range records {
+				fee := "unknown"
+				if record.FeeKnown {
+					fee = hederasdk.HbarFromTinybar(record.Fee).String()
+				}
+				logger.Manager.Resultf(" [#] %v | %v | %v | %v | Fee: %v\n", record.CreatedTimestamp.Format(time.RFC3339), record.TransactionID, record.Status, record.Type, fee)
+				logger.Manager.Resultf("     %v\n", record.Summary)
+			}
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().StringVarP(&filter.Type, "type", "t", "", "Only list transactions of this type ('ContractCall' or 'TopicMessage')")
+	command.Flags().StringVarP(&filter.Status, "status", "s", "", "Only list transactions with this status (e.g., 'PENDING', 'SUCCESS')")
+	command.Flags().StringVarP(&filter.Search, "search", "q", "", "Only list transactions whose summary contains this text")
+	command.Flags().DurationVarP(&since, "since", "d", 0, "Only list transactions of the given time span (e.g., '24h')")
+	command.Flags().IntVarP(&filter.Limit, "limit", "l", 0, "The maximum number of transactions to list (default: all)")
+	command.Flags().BoolVarP(&refresh, "refresh", "r", false, "Query the status and fees of pending transactions from the mirror node")
+
+	return command
+
+}
+
+// Create the CLI command to report the costs and margins of the render jobs of this node
+func (hm *PackageManager) CreateCommandReport() *cobra.Command {
+
+	// flags for the 'report' command
+	var from, to string
+	var electricity float64
+	var jobs bool
+	var refresh bool
+
+	// create a 'report' command for the node
+	command := &cobra.Command{
+		Use:   "report",
+		Short: "Report the costs and margins of the render jobs of this node",
+		Long:  "This command summarizes the payouts, transaction fees, electricity costs, and margins of the render jobs of this node in the given date range. The electricity cost per render hour is read from the 'accounting.json' configuration file, unless it is passed with '--electricity'.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			var fromTime, toTime time.Time
+
+			// get the date range (the end date is included)
+			if from != "" {
+				fromTime, err = time.ParseInLocation(time.DateOnly, from, time.Local)
+			}
+			if err == nil && to != "" {
+				toTime, err = time.ParseInLocation(time.DateOnly, to, time.Local)
+				toTime = toTime.AddDate(0, 0, 1)
+			}
+			if err != nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Invalid date (expected YYYY-MM-DD): %w", err)
+
+			}
+
+			// get the electricity cost per render hour
+			settings := AccountingSettings{}
+			if cmd.Flags().Changed("electricity") {
+				settings.ElectricityCostPerHour = electricity
+				err = settings.Validate()
+			} else {
+				err = settings.Read()
+				if os.IsNotExist(err) {
+					err = nil
+				}
+			}
+			if err != nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Invalid accounting settings: %w", err)
+
+			}
+
+			// update the fees of the transactions from the mirror node
+			if refresh {
+				err = hm.History.Refresh(&hm.MirrorNode)
+				if err != nil {
+
+					logger.Manager.Println("")
+					return fmt.Errorf("Could not refresh the transaction history: %w", err)
+
+				}
+			}
+
+			// summarize the render jobs
+			balances := hm.History.JobBalances(fromTime, toTime, settings)
+			report := SummarizeJobs(balances, fromTime, toTime)
+
+			logger.Manager.Println("")
+			logger.Manager.Println("Render job accounting:")
+			if jobs {
+				for _, balance := range balances {
+					fees := hederasdk.HbarFromTinybar(balance.Fees).String()
+					if !balance.FeesKnown {
+						fees += " (incomplete)"
+					}
+					payout := "unknown"
+					if balance.PayoutKnown {
+						payout = hederasdk.HbarFromTinybar(balance.Payout).String()
+					}
+					logger.Manager.Resultf(" [#] %v | %v (subtask %v) | Render time: %v | Payout: %v | Fees: %v | Electricity: %v | Margin: %v\n", balance.Timestamp().Format(time.DateOnly), balance.RenderRequestCID, balance.Subtask, balance.RenderTime.Round(time.Second), payout, fees, hederasdk.HbarFromTinybar(balance.Electricity), hederasdk.HbarFromTinybar(balance.Margin))
+				}
+			}
+			logger.Manager.Resultf(" [#] Render jobs: %v (completed: %v)\n", report.Jobs, report.Completed)
+			logger.Manager.Resultf(" [#] Render time: %v\n", report.RenderTime.Round(time.Second))
+			logger.Manager.Resultf(" [#] Payout: %v\n", hederasdk.HbarFromTinybar(report.Payout))
+			logger.Manager.Resultf(" [#] Transaction fees: %v\n", hederasdk.HbarFromTinybar(report.Fees))
+			logger.Manager.Resultf(" [#] Electricity (%v ℏ per hour): %v\n", settings.ElectricityCostPerHour, hederasdk.HbarFromTinybar(report.Electricity))
+			logger.Manager.Resultf(" [#] Margin: %v\n", hederasdk.HbarFromTinybar(report.Margin))
+			if report.Unsettled > 0 {
+				logger.Manager.Printf("%v completed jobs have no recorded payout yet.\n", report.Unsettled)
+			}
+			if report.UnknownFees > 0 {
+				logger.Manager.Printf("%v jobs have transactions of unknown fee. Use '--refresh' to query them from the mirror node.\n", report.UnknownFees)
+			}
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().StringVarP(&from, "from", "f", "", "Only report the render jobs from this date on (YYYY-MM-DD)")
+	command.Flags().StringVarP(&to, "to", "t", "", "Only report the render jobs up to this date (YYYY-MM-DD)")
+	command.Flags().Float64VarP(&electricity, "electricity", "e", 0, "The electricity cost per render hour in HBAR (overrides 'accounting.json')")
+	command.Flags().BoolVarP(&jobs, "jobs", "j", false, "List the costs and margins of each render job")
+	command.Flags().BoolVarP(&refresh, "refresh", "r", false, "Query the fees of pending transactions from the mirror node")
+
+	return command
+
+}
+
+// Create the CLI command to manage the Hedera account of this node
+func (hm *PackageManager) CreateCommandAccount() *cobra.Command {
+
+	// create a 'account' command for the node
+	command := &cobra.Command{
+		Use:   "account",
+		Short: "Manage the Hedera account and keystore of this node",
+		Long:  "This command and its sub-commands enable the creation and import of the encrypted keystore file, which holds the private key of the Hedera account of this node.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			return nil
+
+		},
+	}
+
+	// add the subcommands
+	command.AddCommand(hm.CreateCommandAccount_Create())
+	command.AddCommand(hm.CreateCommandAccount_Import())
+	command.AddCommand(hm.CreateCommandAccount_Rotate())
+	command.AddCommand(hm.CreateCommandAccount_List())
+	command.AddCommand(hm.CreateCommandAccount_Info())
+
+	return command
+
+}
+
+// Create the CLI command to generate a new key pair and write it to an encrypted keystore
+func (hm *PackageManager) CreateCommandAccount_Create() *cobra.Command {
+
+	// flags for the 'account create' command
+	var accountID string
+	var keyType string
+
+	// create a 'account create' command for the node
+	command := &cobra.Command{
+		Use:   "create",
+		Short: "Generate a new key pair and store it in an encrypted keystore",
+		Long:  "This command generates a new key pair, encrypts the private key with a passphrase, and writes the keystore file to the config directory. If no account ID is given, the alias account ID of the public key is used. The passphrase is read from the RENDERHIVE_PASSPHRASE environment variable or prompted for.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			// read the passphrase
+			passphrase, err := ReadPassphrase(cmd, "Passphrase for the new keystore")
+			if err != nil {
+				return err
+			}
+
+			// generate the key pair
+			account, keystorePath, err := hm._createKeystore(accountID, keyType, "", passphrase)
+			if err != nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not create the keystore: %w", err)
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Println("Created a new encrypted keystore:")
+			logger.Manager.Resultf(" [#] Keystore: %v\n", keystorePath)
+			logger.Manager.Resultf(" [#] Account ID: %v\n", account.AccountID)
+			logger.Manager.Resultf(" [#] Public key: %v\n", account.PublicKey)
+			logger.Manager.Println("")
+			if accountID == "" {
+				logger.Manager.Println("To create the Hedera account for this key, transfer HBAR to the alias account ID above or create")
+				logger.Manager.Println("an account with the public key in your wallet. Afterwards, rename the keystore file to the")
+				logger.Manager.Println("account ID assigned by the network (e.g., 0.0.1234 -> 001234.key).")
+				logger.Manager.Println("")
+			}
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().StringVarP(&accountID, "account", "a", "", "The Hedera account ID of an existing account for this key (optional)")
+	command.Flags().StringVarP(&keyType, "type", "t", "ed25519", "The type of the key pair ('ed25519' or 'ecdsa')")
+
+	return command
+
+}
+
+// Create the CLI command to import an existing private key into an encrypted keystore
+func (hm *PackageManager) CreateCommandAccount_Import() *cobra.Command {
+
+	// flags for the 'account import' command
+	var accountID string
+
+	// create a 'account import' command for the node
+	command := &cobra.Command{
+		Use:   "import",
+		Short: "Import an existing private key into an encrypted keystore",
+		Long:  "This command encrypts an existing private key with a passphrase and writes the keystore file for the given account to the config directory. The private key is read from the RENDERHIVE_PRIVATE_KEY (or RENDERHIVE_PRIVATE_KEY_FILE) environment variable or prompted for. The passphrase is read from the RENDERHIVE_PASSPHRASE environment variable or prompted for.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			// read the private key
+			privateKey, err := ReadPrivateKey(cmd, "Private key to import")
+			if err != nil {
+				return err
+			}
+
+			// read the passphrase
+			passphrase, err := ReadPassphrase(cmd, "Passphrase for the new keystore")
+			if err != nil {
+				return err
+			}
+
+			// import the private key
+			account, keystorePath, err := hm._createKeystore(accountID, "", privateKey, passphrase)
+			if err != nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not import the private key: %w", err)
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Println("Imported the private key into an encrypted keystore:")
+			logger.Manager.Resultf(" [#] Keystore: %v\n", keystorePath)
+			logger.Manager.Resultf(" [#] Account ID: %v\n", account.AccountID)
+			logger.Manager.Resultf(" [#] Public key: %v\n", account.PublicKey)
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().StringVarP(&accountID, "account", "a", "", "The Hedera account ID the private key belongs to")
+
+	return command
+
+}
+
+// Create the CLI command to rotate the key of a Hedera account
+func (hm *PackageManager) CreateCommandAccount_Rotate() *cobra.Command {
+
+	// flags for the 'account rotate' command
+	var accountID string
+	var dryRun bool
+
+	// create a 'account rotate' command for the node
+	command := &cobra.Command{
+		Use:   "rotate",
+		Short: "Rotate the key of a Hedera account",
+		Long:  "This command generates a new key pair, updates the key of the Hedera account on the network, and re-encrypts the keystore file with the new key. The old keystore is kept as backup. The current passphrase is read from the RENDERHIVE_PASSPHRASE environment variable or prompted for. The new keystore is encrypted with the passphrase of the environment or with a new passphrase, which is prompted for.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			// use the operator account, if no account ID was given
+			if accountID == "" {
+				accountID = hm.Operator.AccountID.String()
+			}
+
+			// read the passphrases
+			passphrase, err := ReadPassphrase(cmd, "Passphrase of the current keystore")
+			if err != nil {
+				return err
+			}
+			// NOTE: With a passphrase in the environment, the keystore keeps
+			// its passphrase, so that scripts do not block.
+			newPassphrase := passphrase
+			if !dryRun && !PassphraseInEnvironment() {
+				newPassphrase, err = PromptPassphrase(cmd, "Passphrase for the new keystore (empty: keep the current one)")
+				if err != nil {
+					return err
+				}
+				if newPassphrase == "" {
+					newPassphrase = passphrase
+				}
+			}
+
+			// rotate the key
+			account, err := hm.RotateKey(accountID, passphrase, newPassphrase, dryRun)
+			if err != nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not rotate the account key: %w", err)
+
+			}
+
+			logger.Manager.Println("")
+			if dryRun {
+				logger.Manager.Println("Dry run: Account key rotation was prepared, but not submitted.")
+			} else {
+				logger.Manager.Println("Rotated the account key:")
+			}
+			logger.Manager.Resultf(" [#] Account ID: %v\n", account.AccountID)
+			logger.Manager.Resultf(" [#] New public key: %v\n", account.PublicKey)
+			logger.Manager.Resultf(" [#] Keystore: %v\n", hm.KeystorePath(account.AccountID.String()))
+			logger.Manager.Println("")
+			if !dryRun {
+				logger.Manager.Println("NOTE: The public key in the node configuration must be updated before the next sign in.")
+				logger.Manager.Println("")
+			}
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().StringVarP(&accountID, "account", "a", "", "The Hedera account ID (default: the operator account of this node)")
+	command.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "Prepare the key rotation without submitting the transaction or writing the keystore")
+
+	return command
+
+}
+
+// Rotate the key of a Hedera account and re-encrypt the keystore with the new key
+// NOTE: The old keystore is only replaced after the network confirmed the new key
+func (hm *PackageManager) RotateKey(account_id string, passphrase string, newPassphrase string, dryRun bool) (*HederaAccount, error) {
+	var err error
+	var account HederaAccount
+	var newAccount HederaAccount
+
+	// a passphrase is mandatory
+	if passphrase == "" || newPassphrase == "" {
+		return nil, errors.New("A passphrase is required to decrypt and encrypt the keystore.")
+	}
+
+	// get the account ID
+	account.AccountID, err = hederasdk.AccountIDFromString(account_id)
+	if err != nil {
+		return nil, err
+	}
+
+	// read the current private key from the keystore file and decrypt it
+	keystorePath := hm.KeystorePath(account.AccountID.String())
+	file, err := os.Open(keystorePath)
+	if err != nil {
+		return nil, err
+	}
+	account.PrivateKey, err = ReadKeystore(file, passphrase)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+	account.PublicKey = account.PrivateKey.PublicKey()
+
+	// generate the new key pair
+	newAccount.AccountID = account.AccountID
+	err = newAccount.GenerateKey("ed25519")
+	if err != nil {
+		return nil, err
+	}
+
+	// log information
+	logger.Manager.Package["hedera"].Info().Msg(fmt.Sprintf("Rotating the key of account '%v' ...", account.AccountID))
+	logger.Manager.Package["hedera"].Info().Msg(fmt.Sprintf(" [#] Old public key: %v", account.PublicKey))
+	logger.Manager.Package["hedera"].Info().Msg(fmt.Sprintf(" [#] New public key: %v", newAccount.PublicKey))
+
+	// if this is only a dry run, prepare the transaction without submitting it
+	// NOTE: A prepared transaction does not need an operator, so the network
+	//       client is left untouched
+	if dryRun {
+		_, _, err = account.UpdateKey(&newAccount.PrivateKey, TransactionOptions.SetExecute(false, account.AccountID))
+		if err != nil {
+			return nil, err
+		}
+
+		return &newAccount, nil
+	}
+
+	// the network client requires an operator to pay for the transaction
+	if hm.NetworkClient.GetOperatorAccountID().String() == "0.0.0" {
+		hm.NetworkClient.SetOperator(account.AccountID, account.PrivateKey)
+	}
+
+	// write the new key to a temporary keystore first, so that it is not lost
+	// if the transaction succeeds but the keystore can not be written
+	pendingPath := strings.TrimSuffix(keystorePath, ".key") + ".pending.key"
+	err = newAccount.ToFile(pendingPath, newPassphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	// update the key of the account on the network
+	// NOTE: The pending keystore is only removed, if the network proved that
+	//       the transaction failed. Otherwise the new key might already be
+	//       active and would be lost.
+	ctx, cancel := context.WithTimeout(context.Background(), RENDERHIVE_CONFIG_HEDERA_KEY_ROTATION_TIMEOUT)
+	defer cancel()
+	receipt, _, err := account.UpdateKey(&newAccount.PrivateKey, TransactionOptions.SetContext(ctx))
+	if err != nil {
+		if _keyUpdateFailed(err) {
+			os.Remove(pendingPath)
+			return nil, err
+		}
+
+		// ask the mirror node, if the new key is active nevertheless
+		if !hm._hasAccountKey(account.AccountID, newAccount.PublicKey) {
+			return nil, errors.New(fmt.Sprintf("The outcome of the key rotation is unknown. The new key is kept in '%v': %v", pendingPath, err))
+		}
+
+	} else if receipt.Status != hederasdk.StatusSuccess {
+		os.Remove(pendingPath)
+		return nil, errors.New(fmt.Sprintf("Account update transaction failed with status '%v'.", receipt.Status))
+	}
+
+	// replace the keystore (the old keystore is kept as backup)
+	err = newAccount.ToFile(keystorePath, newPassphrase)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Account key was rotated, but the keystore could not be written. The new key is stored in '%v': %v", pendingPath, err))
+	}
+	os.Remove(pendingPath)
+
+	// if this is the operator account, update the operator
+	if hm.Operator.AccountID.String() == account.AccountID.String() || hm.NetworkClient.GetOperatorAccountID().String() == account.AccountID.String() {
+		hm.Operator.PrivateKey = newAccount.PrivateKey
+		hm.Operator.PublicKey = newAccount.PublicKey
+		hm.NetworkClient.SetOperator(account.AccountID, newAccount.PrivateKey)
+	}
+
+	// log information
+	if receipt != nil {
+		logger.Manager.Package["hedera"].Info().Msg(fmt.Sprintf(" [#] Key rotated with transaction: %v", receipt.TransactionID))
+	} else {
+		logger.Manager.Package["hedera"].Info().Msg(" [#] Key rotation was confirmed by the mirror node")
+	}
+
+	return &newAccount, nil
+
+}
+
+// helper function to check if an error of a submitted transaction proves that
+// the transaction was not applied by the network
+func _keyUpdateFailed(err error) bool {
+
+	// the transaction was rejected before it reached consensus
+	var precheckErr hederasdk.ErrHederaPreCheckStatus
+	if errors.As(err, &precheckErr) {
+		return true
+	}
+
+	// the transaction reached consensus, but failed
+	var receiptErr hederasdk.ErrHederaReceiptStatus
+	if errors.As(err, &receiptErr) {
+		return receiptErr.Status != hederasdk.StatusSuccess
+	}
+
+	return false
+}
+
+// helper function to check with the mirror node if an account uses the given key
+func (hm *PackageManager) _hasAccountKey(accountID hederasdk.AccountID, publicKey hederasdk.PublicKey) bool {
+
+	// query the account information
+	accounts, err := hm.MirrorNode.GetAccountInfo(accountID.String(), 1, "")
+	if err != nil || accounts == nil || len(*accounts) == 0 {
+		return false
+	}
+
+	return strings.EqualFold((*accounts)[0].Key.Key, publicKey.StringRaw())
+}
+
+// helper function to generate or import a private key and write it to an encrypted keystore
+func (hm *PackageManager) _createKeystore(accountID string, keyType string, privatekey string, passphrase string) (*HederaAccount, string, error) {
+	var err error
+	var account HederaAccount
+
+	// a passphrase is mandatory
+	if passphrase == "" {
+		return nil, "", errors.New("A passphrase is required to encrypt the keystore.")
+	}
+
+	// generate a new key or import the given one
+	if privatekey == "" {
+
+		err = account.GenerateKey(keyType)
+		if err != nil {
+			return nil, "", err
+		}
+
+	} else {
+
+		// an account ID is mandatory for imported keys
+		if accountID == "" {
+			return nil, "", errors.New("An account ID is required to import a private key.")
+		}
+
+		err = account.ImportKey(privatekey)
+		if err != nil {
+			return nil, "", err
+		}
+
+	}
+
+	// get the account ID
+	if accountID != "" {
+		account.AccountID, err = hederasdk.AccountIDFromString(accountID)
+		if err != nil {
+			return nil, "", err
+		}
+	} else {
+		account.AccountID = *account.PublicKey.ToAccountID(0, 0)
+	}
+
+	// do not overwrite an existing keystore
+	keystorePath := hm.KeystorePath(account.AccountID.String())
+	if isFile, _ := utility.IsFile(keystorePath); isFile {
+		return nil, "", errors.New(fmt.Sprintf("Keystore file '%v' already exists.", keystorePath))
+	}
+
+	// make sure the config directory exists
+	err = os.MkdirAll(filepath.Dir(keystorePath), 0700)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// encrypt the private key and write the keystore file
+	err = account.ToFile(keystorePath, passphrase)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// log information
+	logger.Manager.Package["hedera"].Info().Msg(fmt.Sprintf("Keystore for account '%v' written to '%v'.", account.AccountID, keystorePath))
+
+	return &account, keystorePath, nil
+
+}
@@ -0,0 +1,60 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+import (
+	// standard
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAwaitWaitsForTheCall(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// the call runs to its end, even if the context is done before
+	finished := false
+	err := Await(ctx, func() {
+		time.Sleep(50 * time.Millisecond)
+		finished = true
+	})
+	if !finished {
+		t.Fatal("Await returned before the call finished")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want a timeout error", err)
+	}
+
+	// a call is not started with a done context
+	started := false
+	err = Await(ctx, func() { started = true })
+	if started || err == nil {
+		t.Fatalf("call started with a done context (error: %v)", err)
+	}
+
+	// a call in time has no error
+	err = Await(context.Background(), func() {})
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+/*
+
+This file contains the check of the accounts, which sign the smart contract
+calls of the operator.
+
+The Renderhive smart contract identifies operators and nodes by the solidity
+address of their account ID (the "long-zero" address 0x0000...<account>), which
+the service app passes as function parameter, and compares it with 'msg.sender'.
+For accounts with an ED25519 key, 'msg.sender' is this long-zero address. For
+accounts with an ECDSA (secp256k1) key and an EVM address alias, 'msg.sender' is
+the EVM address derived from the key instead. The contract would then not
+recognize the operator as the sender of its own calls. Therefore, contract
+operations require an ED25519 account or an ECDSA account without EVM address
+alias.
+
+*/
+
+import (
+
+	// standard
+	"errors"
+	"fmt"
+	"strings"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+)
+
+// Key types reported by the mirror node
+const (
+	KEY_TYPE_ED25519         = "ED25519"
+	KEY_TYPE_ECDSA_SECP256K1 = "ECDSA_SECP256K1"
+)
+
+// Result of the check of an account, which signs smart contract calls
+type ContractSignerCheck struct {
+	AccountID  string // account ID of the signer
+	KeyType    string // key type of the account (e.g., "ED25519" or "ECDSA_SECP256K1")
+	Address    string // long-zero address, which identifies the account in the contract
+	EvmAddress string // EVM address of the account ('msg.sender' of its contract calls)
+	Compatible bool   // the contract recognizes the account as 'msg.sender'
+	Reason     string // explanation, if the account is not compatible
+}
+
+// CONTRACT SIGNERS
+// #############################################################################
+// Check if the contract recognizes an account with the given key type and EVM address as 'msg.sender'
+// NOTE: An empty EVM address is treated as the long-zero address.
+func CheckContractSigner(accountID hederasdk.AccountID, keyType string, evmAddress string) ContractSignerCheck {
+
+	check := ContractSignerCheck{
+		AccountID:  accountID.String(),
+		KeyType:    keyType,
+		Address:    "0x" + accountID.ToSolidityAddress(),
+		EvmAddress: evmAddress,
+		Compatible: true,
+	}
+	if check.EvmAddress == "" {
+		check.EvmAddress = check.Address
+	}
+
+	// an ECDSA account with an EVM address alias calls the contract with its EVM address
+	if strings.EqualFold(keyType, KEY_TYPE_ECDSA_SECP256K1) && !strings.EqualFold(check.EvmAddress, check.Address) {
+		check.Compatible = false
+		check.Reason = fmt.Sprintf("Account %v uses an ECDSA key with the EVM address %v, which the smart contract sees as 'msg.sender' instead of the account address %v. Please use an account with an ED25519 key for contract operations.", check.AccountID, check.EvmAddress, check.Address)
+	}
+
+	return check
+
+}
+
+// Query the key type and EVM address of an account from the mirror node and check it
+func (hm *PackageManager) QueryContractSigner(accountID hederasdk.AccountID) (ContractSignerCheck, error) {
+
+	accounts, err := hm.MirrorNode.GetAccountInfo(accountID.String(), 1, "")
+	if err != nil {
+		return ContractSignerCheck{}, err
+	}
+	if accounts == nil || len(*accounts) == 0 {
+		return ContractSignerCheck{}, errors.New(fmt.Sprintf("Account %v was not found on the mirror node.", accountID.String()))
+	}
+	account := (*accounts)[0]
+
+	return CheckContractSigner(accountID, account.Key.Type, account.EvmAddress), nil
+
+}
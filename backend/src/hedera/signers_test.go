@@ -0,0 +1,99 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+import (
+
+	// standard
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	"renderhive/logger"
+)
+
+func TestCheckContractSigner(t *testing.T) {
+	accountID := hederasdk.AccountID{Account: 1001}
+	longZero := "0x" + accountID.ToSolidityAddress()
+	alias := "0x8c2e5a4f6f30a0b9e6b3f5b0d2c1e0f9a8b7c6d5"
+
+	for _, test := range []struct {
+		keyType    string
+		evmAddress string
+		compatible bool
+	}{
+		{KEY_TYPE_ED25519, "", true},
+		{KEY_TYPE_ED25519, longZero, true},
+		{KEY_TYPE_ECDSA_SECP256K1, "", true},
+		{KEY_TYPE_ECDSA_SECP256K1, "0x" + strings.ToUpper(longZero[2:]), true},
+		{KEY_TYPE_ECDSA_SECP256K1, alias, false},
+		{strings.ToLower(KEY_TYPE_ECDSA_SECP256K1), alias, false},
+	} {
+		check := CheckContractSigner(accountID, test.keyType, test.evmAddress)
+		if check.Compatible != test.compatible {
+			t.Errorf("key type %v with EVM address %q: expected compatible=%v, got %+v", test.keyType, test.evmAddress, test.compatible, check)
+		}
+		if !check.Compatible && check.Reason == "" {
+			t.Errorf("key type %v with EVM address %q: expected a reason for the incompatible signer", test.keyType, test.evmAddress)
+		}
+		if check.Address != longZero {
+			t.Errorf("expected the long-zero address %v, got %v", longZero, check.Address)
+		}
+	}
+}
+
+func TestQueryContractSignerFlagsEvmAlias(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"accounts":[{"account":"0.0.1001","evm_address":"0x8c2e5a4f6f30a0b9e6b3f5b0d2c1e0f9a8b7c6d5","key":{"_type":"ECDSA_SECP256K1","key":"02"}}]}`)
+	}))
+	defer mirror.Close()
+	hm := &PackageManager{MirrorNode: MirrorNode{URL: mirror.URL}}
+
+	check, err := hm.QueryContractSigner(hederasdk.AccountID{Account: 1001})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Compatible || check.KeyType != KEY_TYPE_ECDSA_SECP256K1 || check.EvmAddress != "0x8c2e5a4f6f30a0b9e6b3f5b0d2c1e0f9a8b7c6d5" {
+		t.Errorf("expected the ECDSA account with an EVM address alias to be flagged, got %+v", check)
+	}
+}
+
+func TestQueryContractSignerUnknownAccount(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"accounts":[]}`)
+	}))
+	defer mirror.Close()
+	hm := &PackageManager{MirrorNode: MirrorNode{URL: mirror.URL}}
+
+	if _, err := hm.QueryContractSigner(hederasdk.AccountID{Account: 1001}); err == nil {
+		t.Error("expected an error for an account unknown to the mirror node")
+	}
+}
@@ -23,18 +23,20 @@ package hedera
 import (
 
 	// standard
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
 	"os"
 
-	//"errors"
-
 	// external
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
 
 	// internal
 	"renderhive/logger"
+	"renderhive/metrics"
 )
 
 // Hedera Smart Contract data
@@ -164,15 +166,23 @@ func (contract *HederaSmartContract) _New(bytecode []byte, adminKey interface{},
 	if settings.Execute {
 
 		// sign with client operator private key and submit the query to a Hedera network
-		transactionResponse, err := newContractCreateFlowTransaction.Execute(Manager.NetworkClient)
+		var transactionResponse hederasdk.TransactionResponse
+		var transactionReceipt hederasdk.TransactionReceipt
+		var executeErr, receiptErr error
+		err = Await(settings.Context, func() {
+			transactionResponse, executeErr = newContractCreateFlowTransaction.Execute(Manager.NetworkClient)
+			if executeErr == nil {
+				transactionReceipt, receiptErr = transactionResponse.GetReceipt(Manager.NetworkClient)
+			}
+		})
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, nil, fmt.Errorf("Gave up waiting for the contract deployment (it may still be executed): %w", err)
 		}
-
-		// get the transaction receipt
-		transactionReceipt, err := transactionResponse.GetReceipt(Manager.NetworkClient)
-		if err != nil {
-			return &transactionResponse, nil, nil, err
+		if executeErr != nil {
+			return nil, nil, nil, executeErr
+		}
+		if receiptErr != nil {
+			return &transactionResponse, nil, nil, receiptErr
 		}
 
 		// log the receipt status of the transaction
@@ -328,7 +338,6 @@ func (contract *HederaSmartContract) Delete(adminKey interface{}, options ...Tra
 func (contract *HederaSmartContract) CallFunction(name string, parameters *hederasdk.ContractFunctionParameters, gas uint64, options ...TransactionOption) (*hederasdk.TransactionResponse, *hederasdk.TransactionReceipt, []byte, error) {
 	var err error
 	var transaction interface{}
-	var transactionResponse hederasdk.TransactionResponse
 
 	// get the settings for the transaction
 	settings, err := MakeTransactionSettings(options...)
@@ -348,22 +357,14 @@ func (contract *HederaSmartContract) CallFunction(name string, parameters *heder
 		return nil, nil, nil, err
 	}
 
+	// add the transaction to the transaction history
+	transactionID := Manager.History.Record(transaction, TRANSACTION_TYPE_CONTRACT_CALL, fmt.Sprintf("%v() on contract %v (gas: %v)", name, contract.ID, gas), settings.Execute)
+
 	// if the transaction should be directly executed
 	if settings.Execute {
 
-		// get the transaction response
-		transactionResponse, err = hederasdk.TransactionExecute(transaction, Manager.NetworkClient)
-		if err != nil {
-			return &transactionResponse, nil, nil, err
-		}
-
-		// get the transaction receipt
-		transactionReceipt, err := transactionResponse.GetReceipt(Manager.NetworkClient)
-		if err != nil {
-			return &transactionResponse, &transactionReceipt, nil, err
-		}
-
-		return &transactionResponse, &transactionReceipt, nil, err
+		// execute the transaction and get its receipt
+		return _executeContractCall(settings.Context, transaction, transactionID, name)
 
 	}
 
@@ -381,7 +382,6 @@ func (contract *HederaSmartContract) CallFunction(name string, parameters *heder
 func (contract *HederaSmartContract) CallPayableFunction(name string, amount string, parameters *hederasdk.ContractFunctionParameters, gas uint64, options ...TransactionOption) (*hederasdk.TransactionResponse, *hederasdk.TransactionReceipt, []byte, error) {
 	var err error
 	var transaction interface{}
-	var transactionResponse hederasdk.TransactionResponse
 
 	// get the settings for the transaction
 	settings, err := MakeTransactionSettings(options...)
@@ -395,6 +395,16 @@ func (contract *HederaSmartContract) CallPayableFunction(name string, amount str
 		return nil, nil, nil, err
 	}
 
+	// check if the paying account can afford the call
+	payer := Manager.Operator.AccountID
+	if !settings.Execute {
+		payer = settings.ExecuteAccountID
+	}
+	err = Manager.CheckPayableBalance(payer, _amount)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	// create the cmart contract call
 	transaction = hederasdk.NewContractExecuteTransaction().
 		SetContractID(contract.ID).
@@ -408,22 +418,14 @@ func (contract *HederaSmartContract) CallPayableFunction(name string, amount str
 		return nil, nil, nil, err
 	}
 
+	// add the transaction to the transaction history
+	transactionID := Manager.History.Record(transaction, TRANSACTION_TYPE_CONTRACT_CALL, fmt.Sprintf("%v() on contract %v (amount: %v, gas: %v)", name, contract.ID, _amount, gas), settings.Execute)
+
 	// if the transaction should be directly executed
 	if settings.Execute {
 
-		// get the transaction response
-		transactionResponse, err = hederasdk.TransactionExecute(transaction, Manager.NetworkClient)
-		if err != nil {
-			return &transactionResponse, nil, nil, err
-		}
-
-		// get the transaction receipt
-		transactionReceipt, err := transactionResponse.GetReceipt(Manager.NetworkClient)
-		if err != nil {
-			return &transactionResponse, &transactionReceipt, nil, err
-		}
-
-		return &transactionResponse, &transactionReceipt, nil, err
+		// execute the transaction and get its receipt
+		return _executeContractCall(settings.Context, transaction, transactionID, name)
 
 	}
 
@@ -438,25 +440,176 @@ func (contract *HederaSmartContract) CallPayableFunction(name string, amount str
 }
 
 // Call a smart contract function local (i.e., on a single node)
-func (contract *HederaSmartContract) CallFunctionLocal(name string, parameters *hederasdk.ContractFunctionParameters, gas uint64) (*hederasdk.ContractFunctionResult, error) {
+// NOTE: Only the context option is used.
+func (contract *HederaSmartContract) CallFunctionLocal(name string, parameters *hederasdk.ContractFunctionParameters, gas uint64, options ...TransactionOption) (*hederasdk.ContractFunctionResult, error) {
 	var err error
 
+	// get the settings for the query
+	settings, err := MakeTransactionSettings(options...)
+	if err != nil {
+		return nil, err
+	}
+
 	// create the local smart contract call
 	newContractCallQueryTransaction := hederasdk.NewContractCallQuery().
 		SetContractID(contract.ID).
 		SetGas(gas).
-		SetFunction(name, parameters)
+		SetFunction(name, parameters).
+		SetMaxQueryPayment(Manager.Fees.QueryPayment())
 
 	// get the function result
-	functionResult, err := newContractCallQueryTransaction.Execute(Manager.NetworkClient)
+	var functionResult hederasdk.ContractFunctionResult
+	var queryErr error
+	err = Await(settings.Context, func() {
+		functionResult, queryErr = newContractCallQueryTransaction.Execute(Manager.NetworkClient)
+	})
 	if err != nil {
-		return nil, err
+		metrics.Manager.ObserveContractCall(name, false)
+		return nil, fmt.Errorf("Gave up waiting for the result of %v(): %w", name, err)
 	}
+	err = queryErr
+	if err != nil {
+		metrics.Manager.ObserveContractCall(name, false)
+		return nil, _feeCapError(err)
+	}
+	metrics.Manager.ObserveContractCall(name, true)
 
 	return &functionResult, err
 
 }
 
+// helper function to execute a smart contract call and get its receipt
+// NOTE: If the context is done first, the transaction may still be executed. Its
+// result is recorded in the transaction history, when it finished.
+func _executeContractCall(ctx context.Context, transaction interface{}, transactionID string, name string) (*hederasdk.TransactionResponse, *hederasdk.TransactionReceipt, []byte, error) {
+	var transactionResponse hederasdk.TransactionResponse
+	var transactionReceipt hederasdk.TransactionReceipt
+	var executeErr, receiptErr error
+
+	err := Await(ctx, func() {
+
+		// get the transaction response
+		transactionResponse, executeErr = hederasdk.TransactionExecute(transaction, Manager.NetworkClient)
+		if executeErr != nil {
+			executeErr = _feeCapError(executeErr)
+			metrics.Manager.ObserveContractCall(name, false)
+			Manager.History.Update(transactionID, nil, executeErr)
+			return
+		}
+
+		// get the transaction receipt
+		transactionReceipt, receiptErr = transactionResponse.GetReceipt(Manager.NetworkClient)
+		Manager.History.Update(transactionID, &transactionReceipt, receiptErr)
+		if receiptErr != nil {
+			receiptErr = _feeCapError(receiptErr)
+			metrics.Manager.ObserveContractCall(name, false)
+			return
+		}
+		metrics.Manager.ObserveContractCall(name, true)
+
+	})
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("Gave up waiting for the call of %v() (the transaction may still be executed): %w", name, err)
+	}
+	if executeErr != nil {
+		return &transactionResponse, nil, nil, executeErr
+	}
+	if receiptErr != nil {
+		return &transactionResponse, &transactionReceipt, nil, receiptErr
+	}
+
+	return &transactionResponse, &transactionReceipt, nil, nil
+
+}
+
+// Get the current hive cycle from the smart contract with a local (read-only) call
+func (contract *HederaSmartContract) GetCurrentHiveCycle(gas uint64) (*big.Int, error) {
+
+	// call the function
+	functionResult, err := contract.CallFunctionLocal("getCurrentHiveCycle", nil, gas)
+	if err != nil {
+		return nil, err
+	}
+
+	// the function returns a single uint256
+	hiveCycle, err := DecodeContractInteger(functionResult, "getCurrentHiveCycle", 0)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Contract '%v' returned no hive cycle.", contract.ID.String()))
+	}
+
+	return hiveCycle, nil
+
+}
+
+// SMART CONTRACT RESULT DECODING
+// #############################################################################
+// ABI types of the integer return values of the Renderhive smart contract functions
+// NOTE: Signed and unsigned integers have the same 32 bytes encoding, but a
+// negative int256 is encoded in two's complement. Each function must be listed
+// with the type of its return value in the contract ABI. All getters of the
+// current contract return uint256 (balances, stakes, and timestamps cannot be
+// negative). DecodeInt256 decodes the values of int256 getters, once the ABI
+// has any.
+var contractIntegerTypes = map[string]string{
+	"getCurrentHiveCycle":      "uint256",
+	"getOperatorBalance":       "uint256",
+	"getReservedOperatorFunds": "uint256",
+	"getOperatorLastActivity":  "uint256",
+	"getNodeStake":             "uint256",
+}
+
+// Decode an unsigned 256-bit integer (uint256) of a contract function result
+func DecodeUint256(data []byte) (*big.Int, error) {
+
+	if len(data) != 32 {
+		return nil, errors.New(fmt.Sprintf("Invalid uint256 encoding of %v bytes.", len(data)))
+	}
+
+	return new(big.Int).SetBytes(data), nil
+
+}
+
+// Decode a signed 256-bit integer (int256) in two's complement of a contract function result
+func DecodeInt256(data []byte) (*big.Int, error) {
+
+	value, err := DecodeUint256(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// subtract 2^256, if the sign bit is set
+	if data[0]&0x80 != 0 {
+		value.Sub(value, new(big.Int).Lsh(big.NewInt(1), 256))
+	}
+
+	return value, nil
+
+}
+
+// Decode the integer return value at the given index of a contract function
+// result with the ABI type of the function
+func DecodeContractInteger(result *hederasdk.ContractFunctionResult, function string, index uint64) (*big.Int, error) {
+
+	abiType, ok := contractIntegerTypes[function]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("The return type of contract function '%v' is not known.", function))
+	}
+	if result == nil || uint64(len(result.ContractCallResult)) < (index+1)*32 {
+		return nil, errors.New(fmt.Sprintf("Contract function '%v' returned no value at index %v.", function, index))
+	}
+	data := result.ContractCallResult[index*32 : (index+1)*32]
+
+	switch abiType {
+	case "int256":
+		return DecodeInt256(data)
+	case "uint256":
+		return DecodeUint256(data)
+	}
+
+	return nil, errors.New(fmt.Sprintf("The return type '%v' of contract function '%v' is not supported.", abiType, function))
+
+}
+
 // Get the events emitted by the contract after a function call
 // TODO:
 // Might be good, if the wallet address would be an indexed event parameter
@@ -502,7 +655,7 @@ func (contract *HederaSmartContract) QueryInfo(m *PackageManager) (string, error
 	// create the topic info query
 	newContractInfoQuery := hederasdk.NewContractInfoQuery().
 		SetContractID(contract.ID).
-		SetMaxQueryPayment(hederasdk.NewHbar(1))
+		SetMaxQueryPayment(Manager.Fees.QueryPayment())
 
 	// get cost of this query
 	cost, err := newContractInfoQuery.GetCost(Manager.NetworkClient)
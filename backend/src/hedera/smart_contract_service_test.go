@@ -0,0 +1,123 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+import (
+
+	// standard
+	"bytes"
+	"math/big"
+	"testing"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+)
+
+// helper function to encode a 32 bytes word with the given first and last bytes
+func _testWord(first byte, fill byte, last byte) []byte {
+	word := bytes.Repeat([]byte{fill}, 32)
+	word[0], word[31] = first, last
+	return word
+}
+
+func TestDecodeInt256TwosComplement(t *testing.T) {
+	maxInt256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(1))
+	minInt256 := new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 255))
+	tests := []struct {
+		name string
+		data []byte
+		want *big.Int
+	}{
+		{"zero", _testWord(0x00, 0x00, 0x00), big.NewInt(0)},
+		{"one", _testWord(0x00, 0x00, 0x01), big.NewInt(1)},
+		{"minus one", _testWord(0xff, 0xff, 0xff), big.NewInt(-1)},
+		{"minus two", _testWord(0xff, 0xff, 0xfe), big.NewInt(-2)},
+		{"minus 256", _testWord(0xff, 0xff, 0x00), big.NewInt(-256)},
+		{"maximum", _testWord(0x7f, 0xff, 0xff), maxInt256},
+		{"minimum", _testWord(0x80, 0x00, 0x00), minInt256},
+	}
+	for _, test := range tests {
+		value, err := DecodeInt256(test.data)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		if value.Cmp(test.want) != 0 {
+			t.Errorf("%v: got %v, want %v", test.name, value, test.want)
+		}
+	}
+}
+
+func TestDecodeUint256IgnoresTheSignBit(t *testing.T) {
+	maxUint256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+	// the encoding of -1 is the largest unsigned value
+	value, err := DecodeUint256(_testWord(0xff, 0xff, 0xff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.Cmp(maxUint256) != 0 {
+		t.Errorf("got %v, want %v", value, maxUint256)
+	}
+
+	// only 32 bytes words are valid
+	for _, data := range [][]byte{nil, make([]byte, 31), make([]byte, 33)} {
+		if _, err := DecodeUint256(data); err == nil {
+			t.Errorf("expected an error for %v bytes", len(data))
+		}
+		if _, err := DecodeInt256(data); err == nil {
+			t.Errorf("expected an error for %v bytes", len(data))
+		}
+	}
+}
+
+func TestDecodeContractIntegerUsesTheABIType(t *testing.T) {
+	contractIntegerTypes["testSignedGetter"] = "int256"
+	contractIntegerTypes["testUnknownGetter"] = "bytes32"
+	defer delete(contractIntegerTypes, "testSignedGetter")
+	defer delete(contractIntegerTypes, "testUnknownGetter")
+
+	// the second return value encodes -5
+	result := &hederasdk.ContractFunctionResult{ContractCallResult: append(_testWord(0x00, 0x00, 0x07), _testWord(0xff, 0xff, 0xfb)...)}
+
+	if value, err := DecodeContractInteger(result, "testSignedGetter", 1); err != nil || value.Int64() != -5 {
+		t.Errorf("signed getter: got %v, %v, want -5", value, err)
+	}
+	if value, err := DecodeContractInteger(result, "getOperatorBalance", 1); err != nil || value.Sign() <= 0 {
+		t.Errorf("unsigned getter: got %v, %v, want a positive value", value, err)
+	}
+	if value, err := DecodeContractInteger(result, "getCurrentHiveCycle", 0); err != nil || value.Int64() != 7 {
+		t.Errorf("first value: got %v, %v, want 7", value, err)
+	}
+
+	// unknown functions, unsupported types, and missing values are errors
+	if _, err := DecodeContractInteger(result, "unknownGetter", 0); err == nil {
+		t.Error("expected an error for an unknown function")
+	}
+	if _, err := DecodeContractInteger(result, "testUnknownGetter", 0); err == nil {
+		t.Error("expected an error for an unsupported type")
+	}
+	if _, err := DecodeContractInteger(result, "getNodeStake", 2); err == nil {
+		t.Error("expected an error for a missing value")
+	}
+	if _, err := DecodeContractInteger(nil, "getNodeStake", 0); err == nil {
+		t.Error("expected an error for a missing result")
+	}
+}
@@ -0,0 +1,269 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+/*
+
+This file contains the supervision of the topic subscriptions.
+
+A topic subscription is a gRPC stream from a mirror node. The Hedera SDK
+retries some stream errors itself, but if it gives up (or the mirror node
+closes the stream), the subscription ends without further notice. Therefore,
+each subscription is supervised: When its stream ends, the topic is subscribed
+again with an exponential backoff.
+
+The resubscription starts right after the consensus timestamp of the last
+received message. Messages with a sequence number that was already received
+are dropped, so no message is missed or processed twice across reconnects.
+
+*/
+
+import (
+
+	// standard
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+	"google.golang.org/grpc/status"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// function to open the stream of a topic subscription
+// NOTE: onDrop is called, when the stream ends. The returned function closes
+// the stream.
+type topicStream func(startTime time.Time, onNext func(message hederasdk.TopicMessage), onDrop func(err error)) (func(), error)
+
+// Supervised subscription of a topic
+type TopicSubscription struct {
+	Topic *HederaTopic // subscribed topic
+
+	// message handling
+	onNext       func(message hederasdk.TopicMessage)
+	open         topicStream
+	lastSequence uint64    // sequence number of the last received message
+	lastTime     time.Time // consensus timestamp of the last received message
+	startTime    time.Time // start time of the subscription
+
+	// stream state
+	mutex       sync.Mutex
+	unsubscribe func()        // closes the current stream
+	dropped     chan error    // receives the end of the current stream
+	closed      chan struct{} // closed, when the subscription is closed
+	reconnects  int           // number of resubscriptions
+}
+
+// TOPIC SUBSCRIPTION SUPERVISION
+// #############################################################################
+// Subscribe to a topic and resubscribe, whenever the stream of the subscription ends
+func (topic *HederaTopic) SubscribeSupervised(startTime time.Time, onNext func(message hederasdk.TopicMessage)) (*TopicSubscription, error) {
+
+	return _subscribeSupervised(topic, startTime, onNext, topic._openStream)
+
+}
+
+// Get the consensus timestamp of the last received message (zero, if none was received)
+func (subscription *TopicSubscription) LastConsensusTimestamp() time.Time {
+
+	subscription.mutex.Lock()
+	defer subscription.mutex.Unlock()
+
+	return subscription.lastTime
+
+}
+
+// Get the number of resubscriptions after the stream ended
+func (subscription *TopicSubscription) Reconnects() int {
+
+	subscription.mutex.Lock()
+	defer subscription.mutex.Unlock()
+
+	return subscription.reconnects
+
+}
+
+// Close the subscription (without resubscription)
+func (subscription *TopicSubscription) Close() {
+
+	subscription.mutex.Lock()
+	defer subscription.mutex.Unlock()
+
+	select {
+	case <-subscription.closed:
+		return
+	default:
+	}
+	close(subscription.closed)
+	if subscription.unsubscribe != nil {
+		subscription.unsubscribe()
+		subscription.unsubscribe = nil
+	}
+
+}
+
+// helper function to start a supervised subscription with the given stream function
+func _subscribeSupervised(topic *HederaTopic, startTime time.Time, onNext func(message hederasdk.TopicMessage), open topicStream) (*TopicSubscription, error) {
+
+	subscription := &TopicSubscription{
+		Topic:     topic,
+		onNext:    onNext,
+		open:      open,
+		startTime: startTime,
+		dropped:   make(chan error, 1),
+		closed:    make(chan struct{}),
+	}
+
+	// open the first stream
+	err := subscription._connect()
+	if err != nil {
+		return nil, err
+	}
+
+	// supervise the stream
+	go subscription._supervise()
+
+	return subscription, nil
+
+}
+
+// helper function to open a new stream from the last received message on
+func (subscription *TopicSubscription) _connect() error {
+
+	subscription.mutex.Lock()
+	defer subscription.mutex.Unlock()
+
+	// start right after the last received message
+	startTime := subscription.startTime
+	if !subscription.lastTime.IsZero() {
+		startTime = subscription.lastTime.Add(time.Nanosecond)
+	}
+
+	// each stream reports its own end only once
+	var once sync.Once
+	onDrop := func(err error) {
+		once.Do(func() {
+			select {
+			case subscription.dropped <- err:
+			default:
+			}
+		})
+	}
+
+	unsubscribe, err := subscription.open(startTime, subscription._receive, onDrop)
+	if err != nil {
+		return err
+	}
+	subscription.unsubscribe = unsubscribe
+
+	return nil
+
+}
+
+// helper function to pass a received message on (without duplicates)
+func (subscription *TopicSubscription) _receive(message hederasdk.TopicMessage) {
+
+	subscription.mutex.Lock()
+	if message.SequenceNumber != 0 && message.SequenceNumber <= subscription.lastSequence {
+		subscription.mutex.Unlock()
+		return
+	}
+	subscription.lastSequence = message.SequenceNumber
+	if message.ConsensusTimestamp.After(subscription.lastTime) {
+		subscription.lastTime = message.ConsensusTimestamp
+	}
+	subscription.mutex.Unlock()
+
+	subscription.onNext(message)
+
+}
+
+// helper function to resubscribe with an exponential backoff, whenever the stream ends
+func (subscription *TopicSubscription) _supervise() {
+
+	for {
+		// wait for the end of the stream
+		var err error
+		select {
+		case err = <-subscription.dropped:
+		case <-subscription.closed:
+			return
+		}
+
+		// log event
+		logger.Manager.Package["hedera"].Warn().Msg(fmt.Sprintf("Subscription of topic %v ended: %v", subscription.Topic.ID, err))
+
+		// resubscribe with an exponential backoff
+		backoff := RENDERHIVE_CONFIG_TOPIC_RESUBSCRIBE_BACKOFF
+		for {
+			select {
+			case <-time.After(backoff):
+			case <-subscription.closed:
+				return
+			}
+
+			err = subscription._connect()
+			if err == nil {
+				break
+			}
+			backoff = 2 * backoff
+			if backoff > RENDERHIVE_CONFIG_TOPIC_RESUBSCRIBE_MAX_BACKOFF {
+				backoff = RENDERHIVE_CONFIG_TOPIC_RESUBSCRIBE_MAX_BACKOFF
+			}
+			logger.Manager.Package["hedera"].Warn().Msg(fmt.Sprintf("Could not resubscribe to topic %v (retry in %v): %v", subscription.Topic.ID, backoff, err))
+		}
+
+		// log event
+		subscription.mutex.Lock()
+		subscription.reconnects++
+		reconnects, lastTime := subscription.reconnects, subscription.lastTime
+		subscription.mutex.Unlock()
+		logger.Manager.Package["hedera"].Info().Msg(fmt.Sprintf("Resubscribed to topic %v after the last message at %v (reconnect #%v).", subscription.Topic.ID, lastTime, reconnects))
+	}
+
+}
+
+// helper function to open the stream of the topic from a mirror node
+func (topic *HederaTopic) _openStream(startTime time.Time, onNext func(message hederasdk.TopicMessage), onDrop func(err error)) (func(), error) {
+
+	query := hederasdk.NewTopicMessageQuery().
+		SetTopicID(topic.ID).
+		SetStartTime(startTime).
+		SetErrorHandler(func(stat status.Status) {
+			onDrop(stat.Err())
+		}).
+		SetCompletionHandler(func() {
+			onDrop(errors.New("The mirror node closed the stream."))
+		})
+
+	handle, err := query.Subscribe(Manager.NetworkClient, onNext)
+	if err != nil {
+		return nil, err
+	}
+
+	return handle.Unsubscribe, nil
+
+}
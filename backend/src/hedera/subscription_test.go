@@ -0,0 +1,128 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package hedera
+
+import (
+
+	// standard
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	"renderhive/logger"
+)
+
+func TestSubscriptionResubscribesDroppedStream(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+
+	start := time.Unix(1700000000, 0)
+	message := func(sequence uint64) hederasdk.TopicMessage {
+		return hederasdk.TopicMessage{SequenceNumber: sequence, ConsensusTimestamp: start.Add(time.Duration(sequence) * time.Second)}
+	}
+
+	// the first stream delivers two messages and drops, the second one
+	// repeats the last message before it delivers a new one
+	var mutex sync.Mutex
+	var startTimes []time.Time
+	var closed int
+	open := func(startTime time.Time, onNext func(message hederasdk.TopicMessage), onDrop func(err error)) (func(), error) {
+		mutex.Lock()
+		startTimes = append(startTimes, startTime)
+		streams := len(startTimes)
+		mutex.Unlock()
+		if streams == 1 {
+			go func() {
+				onNext(message(1))
+				onNext(message(2))
+				onDrop(errors.New("stream reset"))
+			}()
+		} else {
+			go func() {
+				onNext(message(2))
+				onNext(message(3))
+			}()
+		}
+		return func() { mutex.Lock(); closed++; mutex.Unlock() }, nil
+	}
+
+	received := make(chan uint64, 10)
+	subscription, err := _subscribeSupervised(&HederaTopic{}, start, func(message hederasdk.TopicMessage) {
+		received <- message.SequenceNumber
+	}, open)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each message is received exactly once across the reconnect
+	for _, expected := range []uint64{1, 2, 3} {
+		select {
+		case sequence := <-received:
+			if sequence != expected {
+				t.Fatalf("expected message %v, got %v", expected, sequence)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("message %v was not received", expected)
+		}
+	}
+
+	// the reconnect is counted, after the new stream was opened
+	deadline := time.Now().Add(5 * time.Second)
+	for subscription.Reconnects() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	subscription.Close()
+
+	if subscription.Reconnects() != 1 {
+		t.Errorf("expected 1 reconnect, got %v", subscription.Reconnects())
+	}
+	if !subscription.LastConsensusTimestamp().Equal(message(3).ConsensusTimestamp) {
+		t.Errorf("unexpected last consensus timestamp: %v", subscription.LastConsensusTimestamp())
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(startTimes) != 2 || !startTimes[0].Equal(start) || !startTimes[1].Equal(message(2).ConsensusTimestamp.Add(time.Nanosecond)) {
+		t.Errorf("expected the resubscription right after the last message, got %v", startTimes)
+	}
+	if closed != 1 {
+		t.Errorf("expected the current stream to be closed once, got %v", closed)
+	}
+	select {
+	case sequence := <-received:
+		t.Errorf("unexpected duplicate message %v", sequence)
+	default:
+	}
+}
+
+func TestSubscriptionFailsWithoutStream(t *testing.T) {
+	open := func(startTime time.Time, onNext func(message hederasdk.TopicMessage), onDrop func(err error)) (func(), error) {
+		return nil, errors.New("mirror node unavailable")
+	}
+
+	if _, err := _subscribeSupervised(&HederaTopic{}, time.Now(), func(message hederasdk.TopicMessage) {}, open); err == nil {
+		t.Error("expected an error, if the first stream cannot be opened")
+	}
+}
@@ -0,0 +1,176 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+/*
+
+This file contains the bandwidth statistics of the local IPFS node.
+
+The libp2p host of the node counts the bytes it receives from and sends to
+other peers, i.e. also the traffic of serving Blender files and render results
+to other nodes. The totals are counted since the start of the node, the rates
+are moving averages in bytes per second. The statistics are exported on the
+metrics endpoint and shown by the 'ipfs bw' command.
+
+The counters are only available, if the bandwidth metrics are not disabled in
+the configuration of the IPFS repo ('Swarm.DisableBandwidthMetrics'). The
+option is enabled again, when the local node starts.
+
+*/
+
+import (
+
+	// standard
+	"errors"
+	"fmt"
+	"sort"
+
+	// external
+	"github.com/libp2p/go-libp2p/core/metrics"
+	"github.com/spf13/cobra"
+
+	// internal
+	"renderhive/logger"
+)
+
+// Bandwidth of the local IPFS node (in bytes and bytes per second)
+type Bandwidth struct {
+	TotalIn  int64   // bytes received since the start of the node
+	TotalOut int64   // bytes sent since the start of the node
+	RateIn   float64 // current receive rate
+	RateOut  float64 // current send rate
+}
+
+// Bandwidth statistics of the local IPFS node
+type BandwidthStats struct {
+	Bandwidth                      // bandwidth of all protocols
+	Protocols map[string]Bandwidth // bandwidth by libp2p protocol ID
+}
+
+// BANDWIDTH STATISTICS
+// #############################################################################
+// Get the bandwidth statistics of the local IPFS node
+func (ipfsm *PackageManager) BandwidthStats() (BandwidthStats, error) {
+
+	// check if the node is running
+	if ipfsm.IpfsNode == nil {
+		return BandwidthStats{}, errors.New("Could not find the local IPFS node.")
+	}
+
+	// the reporter is not created, if the bandwidth metrics are disabled
+	if ipfsm.IpfsNode.Reporter == nil {
+		return BandwidthStats{}, errors.New("The bandwidth metrics are disabled in the IPFS node configuration (Swarm.DisableBandwidthMetrics). Restart the node to enable them.")
+	}
+
+	stats := BandwidthStats{
+		Bandwidth: _bandwidth(ipfsm.IpfsNode.Reporter.GetBandwidthTotals()),
+		Protocols: map[string]Bandwidth{},
+	}
+	for protocol, protocolStats := range ipfsm.IpfsNode.Reporter.GetBandwidthByProtocol() {
+		stats.Protocols[string(protocol)] = _bandwidth(protocolStats)
+	}
+
+	return stats, nil
+
+}
+
+// helper function to convert the libp2p bandwidth statistics
+func _bandwidth(stats metrics.Stats) Bandwidth {
+
+	return Bandwidth{TotalIn: stats.TotalIn, TotalOut: stats.TotalOut, RateIn: stats.RateIn, RateOut: stats.RateOut}
+
+}
+
+// helper function to format a number of bytes for the command line
+func _formatBytes(bytes float64) string {
+
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	unit := 0
+	for bytes >= 1024 && unit < len(units)-1 {
+		bytes /= 1024
+		unit++
+	}
+
+	return fmt.Sprintf("%.1f %v", bytes, units[unit])
+
+}
+
+// COMMAND LINE INTERFACE - BANDWIDTH
+// #############################################################################
+// Create the CLI command to print the bandwidth statistics of the local IPFS node
+func (ipfsm *PackageManager) CreateCommandBandwidth() *cobra.Command {
+
+	// flags for the 'bw' command
+	var protocols bool
+
+	// create a 'bw' command for the node
+	command := &cobra.Command{
+		Use:   "bw",
+		Short: "Print the bandwidth statistics of the IPFS node",
+		Long:  "This command prints the bytes received and sent by the local IPFS node since its start and the current bandwidth. With '--protocols', the statistics are also listed for each libp2p protocol.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			stats, err := ipfsm.BandwidthStats()
+			if err != nil {
+
+				logger.Manager.Println("")
+				return err
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Println("Bandwidth of the local IPFS node:")
+			logger.Manager.Resultf(" [#] Total in: %v\n", _formatBytes(float64(stats.TotalIn)))
+			logger.Manager.Resultf(" [#] Total out: %v\n", _formatBytes(float64(stats.TotalOut)))
+			logger.Manager.Resultf(" [#] Rate in: %v/s\n", _formatBytes(stats.RateIn))
+			logger.Manager.Resultf(" [#] Rate out: %v/s\n", _formatBytes(stats.RateOut))
+
+			// list the protocols with the most traffic first
+			if protocols {
+				names := make([]string, 0, len(stats.Protocols))
+				for name := range stats.Protocols {
+					names = append(names, name)
+				}
+				sort.Slice(names, func(i, j int) bool {
+					a, b := stats.Protocols[names[i]], stats.Protocols[names[j]]
+					return a.TotalIn+a.TotalOut > b.TotalIn+b.TotalOut
+				})
+
+				logger.Manager.Println("")
+				logger.Manager.Println("Bandwidth by protocol:")
+				for _, name := range names {
+					bandwidth := stats.Protocols[name]
+					logger.Manager.Resultf(" [#] %v: %v in, %v out (%v/s in, %v/s out)\n", name, _formatBytes(float64(bandwidth.TotalIn)), _formatBytes(float64(bandwidth.TotalOut)), _formatBytes(bandwidth.RateIn), _formatBytes(bandwidth.RateOut))
+				}
+			}
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().BoolVarP(&protocols, "protocols", "p", false, "List the bandwidth of each libp2p protocol")
+
+	return command
+
+}
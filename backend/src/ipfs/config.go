@@ -0,0 +1,418 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+/*
+
+The local IPFS node can be tuned with the optional 'ipfs.json' file of the
+configuration directory. It enables the experimental features of kubo and sets
+the limits of the libp2p connection and resource managers. The options are
+applied to the IPFS repo configuration each time the local node is started.
+Options that are not set keep the kubo defaults.
+
+The datastore backend is the exception: It is only applied, when the repo is
+created, since the blocks of an existing repo cannot be read by another backend.
+Changing it later requires a fresh repo (or a migration of the repo with
+'ipfs-ds-convert'), otherwise the node keeps the backend of the repo and warns.
+For render workloads, the backends have the following trade-offs:
+
+  - "flatfs" (default): stores each block as a file. It is the most reliable
+    backend, reclaims space right after a garbage collection, and needs little
+    memory. Adding large Blender files is slower, since each 256 KiB block is
+    a separate file (many small files and inodes).
+  - "badgerds": stores the blocks in a log-structured key-value store. Adding
+    and reading many gigabytes (e.g., large Blender files and rendered frames)
+    is faster, but it uses up to several gigabytes of memory and reclaims the
+    space of removed blocks only slowly. The badger backend of kubo is still
+    experimental.
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	// external
+	"github.com/ipfs/kubo/config"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// datastore backends of the IPFS repo (names of the kubo profiles)
+const (
+	IPFS_DATASTORE_FLATFS = "flatfs"
+	IPFS_DATASTORE_BADGER = "badgerds"
+)
+
+// IPFS NODE CONFIGURATION
+// #############################################################################
+// Configuration options of the local IPFS node
+type IpfsNodeConfig struct {
+
+	// Experimental features
+	// https://github.com/ipfs/kubo/blob/master/docs/experimental-features.md
+	FilestoreEnabled     bool `json:"filestore_enabled"`      // reference files instead of copying them into the datastore
+	UrlstoreEnabled      bool `json:"urlstore_enabled"`       // reference web content instead of copying it into the datastore
+	Libp2pStreamMounting bool `json:"libp2p_stream_mounting"` // forward libp2p streams to local services ('ipfs p2p')
+	P2pHttpProxy         bool `json:"p2p_http_proxy"`         // proxy HTTP requests to peers via the gateway
+
+	// Datastore backend of the repo ("flatfs" or "badgerds"; only applied when the repo is created)
+	Datastore string `json:"datastore"`
+
+	// Public addresses announced to other peers (queried, if empty)
+	ExternalIPv4 string `json:"external_ipv4"` // public IPv4 address (e.g., of the NAT router with a port forwarding)
+	ExternalIPv6 string `json:"external_ipv6"` // public IPv6 address
+
+	// Circuit relays for nodes behind a NAT or firewall
+	AutoRelayDisabled bool     `json:"autorelay_disabled"` // do not reserve relay slots, if the node is not publicly reachable
+	Relays            []string `json:"relays"`             // multiaddrs (incl. peer ID) of the relays to use (default: Renderhive relays or relays found in the DHT)
+
+	// libp2p connection manager
+	ConnMgrLowWater    int64  `json:"connmgr_low_water"`    // number of connections the connection manager trims down to
+	ConnMgrHighWater   int64  `json:"connmgr_high_water"`   // number of connections that triggers the trimming
+	ConnMgrGracePeriod string `json:"connmgr_grace_period"` // duration new connections are protected from trimming (e.g., "20s")
+
+	// libp2p resource manager
+	ResourceMgrMaxMemory          string `json:"resourcemgr_max_memory"`           // maximum memory used by libp2p (e.g., "4GB")
+	ResourceMgrMaxFileDescriptors int64  `json:"resourcemgr_max_file_descriptors"` // maximum file descriptors used by libp2p
+
+	// Bandwidth limits of the add and get operations in bytes per second (0 = unlimited)
+	BandwidthMaxUpload               int64 `json:"bandwidth_max_upload"`                 // shared by all add operations
+	BandwidthMaxDownload             int64 `json:"bandwidth_max_download"`               // shared by all get operations
+	BandwidthMaxUploadPerOperation   int64 `json:"bandwidth_max_upload_per_operation"`   // for each add operation
+	BandwidthMaxDownloadPerOperation int64 `json:"bandwidth_max_download_per_operation"` // for each get operation
+}
+
+// Read the IPFS node configuration from the configuration file
+func (ipfsm *PackageManager) ReadNodeConfig() error {
+	var err error
+	var nodeConfig IpfsNodeConfig
+
+	// Open the configuration file
+	file, err := os.Open(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "ipfs.json"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Read the file content
+	fileData, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	// get the file content into a structure
+	err = json.Unmarshal(fileData, &nodeConfig)
+	if err != nil {
+		return err
+	}
+
+	// check the configuration
+	err = nodeConfig.Validate()
+	if err != nil {
+		return err
+	}
+
+	ipfsm.NodeConfig = nodeConfig
+	ipfsm.UploadLimiter = newBandwidthLimiter(nodeConfig.BandwidthMaxUpload)
+	ipfsm.DownloadLimiter = newBandwidthLimiter(nodeConfig.BandwidthMaxDownload)
+
+	return err
+
+}
+
+// Validate the combination of IPFS node configuration options
+func (nodeConfig *IpfsNodeConfig) Validate() error {
+
+	// connection manager watermarks
+	if nodeConfig.ConnMgrLowWater < 0 || nodeConfig.ConnMgrHighWater < 0 {
+		return errors.New("The connection manager watermarks must not be negative.")
+	}
+	if nodeConfig.ConnMgrLowWater > 0 && nodeConfig.ConnMgrHighWater > 0 && nodeConfig.ConnMgrLowWater > nodeConfig.ConnMgrHighWater {
+		return errors.New(fmt.Sprintf("The connection manager low watermark (%v) must not exceed the high watermark (%v).", nodeConfig.ConnMgrLowWater, nodeConfig.ConnMgrHighWater))
+	}
+	if nodeConfig.ConnMgrGracePeriod != "" {
+		_, err := time.ParseDuration(nodeConfig.ConnMgrGracePeriod)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Invalid connection manager grace period '%v'.", nodeConfig.ConnMgrGracePeriod))
+		}
+	}
+
+	// resource manager limits
+	if nodeConfig.ResourceMgrMaxFileDescriptors < 0 {
+		return errors.New("The maximum number of file descriptors must not be negative.")
+	}
+
+	// datastore backend
+	if nodeConfig.Datastore != "" && !IsSupportedDatastore(nodeConfig.Datastore) {
+		return errors.New(fmt.Sprintf("Invalid datastore backend '%v' (supported: %v, %v).", nodeConfig.Datastore, IPFS_DATASTORE_FLATFS, IPFS_DATASTORE_BADGER))
+	}
+
+	// external addresses
+	if nodeConfig.ExternalIPv4 != "" {
+		ip := net.ParseIP(nodeConfig.ExternalIPv4)
+		if ip == nil || ip.To4() == nil {
+			return errors.New(fmt.Sprintf("Invalid external IPv4 address '%v'.", nodeConfig.ExternalIPv4))
+		}
+	}
+	if nodeConfig.ExternalIPv6 != "" {
+		ip := net.ParseIP(nodeConfig.ExternalIPv6)
+		if ip == nil || ip.To4() != nil {
+			return errors.New(fmt.Sprintf("Invalid external IPv6 address '%v'.", nodeConfig.ExternalIPv6))
+		}
+	}
+
+	// relays
+	for _, relay := range nodeConfig.Relays {
+		_, err := peer.AddrInfoFromString(relay)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Invalid relay address '%v': %v", relay, err))
+		}
+	}
+
+	// bandwidth limits
+	if nodeConfig.BandwidthMaxUpload < 0 || nodeConfig.BandwidthMaxDownload < 0 || nodeConfig.BandwidthMaxUploadPerOperation < 0 || nodeConfig.BandwidthMaxDownloadPerOperation < 0 {
+		return errors.New("The bandwidth limits must not be negative.")
+	}
+
+	// the p2p HTTP proxy relies on libp2p stream mounting
+	if nodeConfig.P2pHttpProxy && !nodeConfig.Libp2pStreamMounting {
+		return errors.New("The p2p HTTP proxy requires libp2p stream mounting to be enabled.")
+	}
+
+	return nil
+
+}
+
+// Get the datastore backend of the IPFS node configuration (the default, if none is set)
+func (nodeConfig *IpfsNodeConfig) DatastoreBackend() string {
+
+	if nodeConfig.Datastore == "" {
+		return IPFS_DATASTORE_FLATFS
+	}
+
+	return nodeConfig.Datastore
+
+}
+
+// Create the configuration of a new repo with the configured datastore backend
+func (nodeConfig *IpfsNodeConfig) InitRepoConfig() (*config.Config, error) {
+
+	// Create a config with default options and a 2048 bit key
+	cfg, err := config.Init(io.Discard, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	// the profiles of kubo set the datastore spec of the backend
+	profile, ok := config.Profiles[nodeConfig.DatastoreBackend()]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Invalid datastore backend '%v'.", nodeConfig.DatastoreBackend()))
+	}
+	err = profile.Transform(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+
+}
+
+// Check if the repo configuration uses the configured datastore backend and
+// warn, if it does not (the backend of an existing repo cannot be changed)
+func (nodeConfig *IpfsNodeConfig) CheckDatastore(cfg *config.Config) {
+
+	backend := RepoDatastoreBackend(cfg.Datastore.Spec)
+	if nodeConfig.Datastore == "" || backend == nodeConfig.Datastore {
+		return
+	}
+	if backend == "" {
+		backend = "unknown"
+	}
+
+	logger.Manager.Package["ipfs"].Warn().Msg(fmt.Sprintf(" [#] The IPFS repo uses the '%v' datastore, but '%v' is configured. The datastore can only be changed with a fresh repo or a migration (e.g., with 'ipfs-ds-convert'). Keeping the '%v' datastore.", backend, nodeConfig.Datastore, backend))
+
+}
+
+// Check if a datastore backend is supported
+func IsSupportedDatastore(backend string) bool {
+	return backend == IPFS_DATASTORE_FLATFS || backend == IPFS_DATASTORE_BADGER
+}
+
+// Get the datastore backend of a datastore spec of a repo configuration
+// NOTE: Returns an empty string, if the spec contains none of the supported backends.
+func RepoDatastoreBackend(spec map[string]interface{}) string {
+
+	var backend string
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			if kind, ok := value["type"].(string); ok && IsSupportedDatastore(kind) && backend == "" {
+				backend = kind
+			}
+			for _, child := range value {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range value {
+				walk(child)
+			}
+		}
+	}
+	walk(spec)
+
+	return backend
+
+}
+
+// Apply the IPFS node configuration to the repo configuration
+func (nodeConfig *IpfsNodeConfig) Apply(cfg *config.Config) {
+
+	// Experimental features
+	// NOTE: The features are not covered by the stability guarantees of kubo.
+	cfg.Experimental.FilestoreEnabled = nodeConfig.FilestoreEnabled
+	if nodeConfig.FilestoreEnabled {
+		logger.Manager.Package["ipfs"].Warn().Msg(" [#] Experimental filestore enabled: Referenced files must not be moved or modified, otherwise their blocks become unavailable.")
+	}
+	cfg.Experimental.UrlstoreEnabled = nodeConfig.UrlstoreEnabled
+	if nodeConfig.UrlstoreEnabled {
+		logger.Manager.Package["ipfs"].Warn().Msg(" [#] Experimental urlstore enabled: Referenced web content becomes unavailable if it changes or goes offline.")
+	}
+	cfg.Experimental.Libp2pStreamMounting = nodeConfig.Libp2pStreamMounting
+	if nodeConfig.Libp2pStreamMounting {
+		logger.Manager.Package["ipfs"].Warn().Msg(" [#] Experimental libp2p stream mounting enabled: Forwarded local services are reachable by remote peers.")
+	}
+	cfg.Experimental.P2pHttpProxy = nodeConfig.P2pHttpProxy
+	if nodeConfig.P2pHttpProxy {
+		logger.Manager.Package["ipfs"].Warn().Msg(" [#] Experimental p2p HTTP proxy enabled: The gateway proxies HTTP requests to remote peers.")
+	}
+
+	// libp2p connection manager
+	if nodeConfig.ConnMgrLowWater > 0 {
+		cfg.Swarm.ConnMgr.LowWater = config.NewOptionalInteger(nodeConfig.ConnMgrLowWater)
+	}
+	if nodeConfig.ConnMgrHighWater > 0 {
+		cfg.Swarm.ConnMgr.HighWater = config.NewOptionalInteger(nodeConfig.ConnMgrHighWater)
+	}
+	if nodeConfig.ConnMgrGracePeriod != "" {
+		gracePeriod, _ := time.ParseDuration(nodeConfig.ConnMgrGracePeriod)
+		cfg.Swarm.ConnMgr.GracePeriod = config.NewOptionalDuration(gracePeriod)
+	}
+
+	// libp2p resource manager
+	if nodeConfig.ResourceMgrMaxMemory != "" {
+		cfg.Swarm.ResourceMgr.MaxMemory = config.NewOptionalString(nodeConfig.ResourceMgrMaxMemory)
+	}
+	if nodeConfig.ResourceMgrMaxFileDescriptors > 0 {
+		cfg.Swarm.ResourceMgr.MaxFileDescriptors = config.NewOptionalInteger(nodeConfig.ResourceMgrMaxFileDescriptors)
+	}
+
+	// circuit relays
+	nodeConfig._applyRelays(cfg)
+
+	// warn, if the resulting watermarks do not fit to each other
+	lowWater := cfg.Swarm.ConnMgr.LowWater.WithDefault(config.DefaultConnMgrLowWater)
+	highWater := cfg.Swarm.ConnMgr.HighWater.WithDefault(config.DefaultConnMgrHighWater)
+	if lowWater > highWater {
+		logger.Manager.Package["ipfs"].Warn().Msg(fmt.Sprintf(" [#] Connection manager low watermark (%v) exceeds the high watermark (%v).", lowWater, highWater))
+	}
+
+}
+
+// Get the public IPv4 address announced by the node
+// NOTE: The configured external address overrides the queried address.
+func (nodeConfig *IpfsNodeConfig) PublicIPv4() (string, error) {
+
+	if nodeConfig.ExternalIPv4 != "" {
+		return nodeConfig.ExternalIPv4, nil
+	}
+
+	return GetPublicIPv4()
+
+}
+
+// Get the public IPv6 address announced by the node
+// NOTE: The configured external address overrides the queried address.
+func (nodeConfig *IpfsNodeConfig) PublicIPv6() (string, error) {
+
+	if nodeConfig.ExternalIPv6 != "" {
+		return nodeConfig.ExternalIPv6, nil
+	}
+
+	return GetPublicIPv6()
+
+}
+
+// Get the multiaddresses with the public IP addresses announced by the node
+// NOTE: Without a public IP address, the node can still be reached via AutoNAT
+// and relays, so a failed query does not stop the node.
+func (nodeConfig *IpfsNodeConfig) AnnounceAddresses() []string {
+	addresses := []string{}
+
+	// get the public IPv4 and add it to the announced addresses
+	ipv4, err := nodeConfig.PublicIPv4()
+	if err != nil {
+		logger.Manager.Package["ipfs"].Warn().Msg(fmt.Sprintf(" [#] Could not query the public IPv4 address (not announced): %v", err))
+	}
+	if ipv4 != "" {
+
+		// add the multiaddr with the public IP to the configuration
+		addresses = append(addresses, fmt.Sprintf("/ip4/%v/tcp/4001", ipv4))
+		addresses = append(addresses, fmt.Sprintf("/ip4/%v/udp/4001/quic", ipv4))
+		addresses = append(addresses, fmt.Sprintf("/ip4/%v/udp/4001/quic-v1", ipv4))
+		addresses = append(addresses, fmt.Sprintf("/ip4/%v/udp/4001/quic-v1/webtransport", ipv4))
+
+		logger.Manager.Package["ipfs"].Info().Msg(fmt.Sprintf(" [#] Public IPv4 address: %v", ipv4))
+	}
+
+	// get the public IPv6 and add it to the announced addresses
+	ipv6, err := nodeConfig.PublicIPv6()
+	if err != nil {
+		logger.Manager.Package["ipfs"].Warn().Msg(fmt.Sprintf(" [#] Could not query the public IPv6 address (not announced): %v", err))
+	}
+	if ipv6 != "" && ipv6 != ipv4 {
+
+		// add the multiaddr with the public IP to the configuration
+		addresses = append(addresses, fmt.Sprintf("/ip6/%v/tcp/4001", ipv6))
+		addresses = append(addresses, fmt.Sprintf("/ip6/%v/udp/4001/quic", ipv6))
+		addresses = append(addresses, fmt.Sprintf("/ip6/%v/udp/4001/quic-v1", ipv6))
+		addresses = append(addresses, fmt.Sprintf("/ip6/%v/udp/4001/quic-v1/webtransport", ipv6))
+
+		logger.Manager.Package["ipfs"].Info().Msg(fmt.Sprintf(" [#] Public IPv6 address: %v", ipv6))
+
+	}
+
+	return addresses
+
+}
@@ -0,0 +1,152 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+import (
+
+	// standard
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	// external
+	serialize "github.com/ipfs/kubo/config/serialize"
+	"github.com/ipfs/kubo/plugin/loader"
+	"github.com/ipfs/kubo/repo/fsrepo"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+var testPluginsOnce sync.Once
+
+// helper function to inject the preloaded plugins (e.g., the datastores) once
+func _testInjectPlugins(t *testing.T) {
+	t.Helper()
+	var err error
+	testPluginsOnce.Do(func() {
+		var plugins *loader.PluginLoader
+		plugins, err = loader.NewPluginLoader("")
+		if err == nil {
+			err = plugins.Initialize()
+		}
+		if err == nil {
+			err = plugins.Inject()
+		}
+	})
+	if err != nil {
+		t.Fatalf("failed to load the IPFS plugins: %v", err)
+	}
+}
+
+func TestInitRepoConfigDatastore(t *testing.T) {
+	_testInjectPlugins(t)
+	for _, datastore := range []string{"", IPFS_DATASTORE_FLATFS, IPFS_DATASTORE_BADGER} {
+		nodeConfig := &IpfsNodeConfig{Datastore: datastore}
+		cfg, err := nodeConfig.InitRepoConfig()
+		if err != nil {
+			t.Fatalf("datastore %q: %v", datastore, err)
+		}
+		if backend := RepoDatastoreBackend(cfg.Datastore.Spec); backend != nodeConfig.DatastoreBackend() {
+			t.Errorf("datastore %q: expected the '%v' spec, got '%v'", datastore, nodeConfig.DatastoreBackend(), backend)
+		}
+
+		// the spec is written to the config of the new repo
+		repoPath := t.TempDir()
+		if err = fsrepo.Init(repoPath, cfg); err != nil {
+			t.Fatalf("datastore %q: %v", datastore, err)
+		}
+		written, err := serialize.Load(filepath.Join(repoPath, "config"))
+		if err != nil {
+			t.Fatalf("datastore %q: %v", datastore, err)
+		}
+		if backend := RepoDatastoreBackend(written.Datastore.Spec); backend != nodeConfig.DatastoreBackend() {
+			t.Errorf("datastore %q: expected the '%v' spec in the repo, got '%v'", datastore, nodeConfig.DatastoreBackend(), backend)
+		}
+	}
+}
+
+func TestInitRepoConfigInvalidDatastore(t *testing.T) {
+	nodeConfig := &IpfsNodeConfig{Datastore: "leveldb"}
+	if err := nodeConfig.Validate(); err == nil {
+		t.Error("expected an unsupported datastore to be rejected")
+	}
+	if _, err := nodeConfig.InitRepoConfig(); err == nil {
+		t.Error("expected no repo config for an unsupported datastore")
+	}
+}
+
+// helper function to replace the IP lookup services with services that fail
+func _testFailingIPServices(t *testing.T) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	defaultIPv4, defaultIPv6 := RENDERHIVE_CONFIG_PUBLIC_IPV4_SERVICES, RENDERHIVE_CONFIG_PUBLIC_IPV6_SERVICES
+	RENDERHIVE_CONFIG_PUBLIC_IPV4_SERVICES, RENDERHIVE_CONFIG_PUBLIC_IPV6_SERVICES = []string{server.URL}, []string{server.URL}
+	t.Cleanup(func() {
+		RENDERHIVE_CONFIG_PUBLIC_IPV4_SERVICES, RENDERHIVE_CONFIG_PUBLIC_IPV6_SERVICES = defaultIPv4, defaultIPv6
+	})
+}
+
+func TestAnnounceAddressesWithoutPublicIP(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	_testFailingIPServices(t)
+
+	// the node is started without announce addresses
+	nodeConfig := &IpfsNodeConfig{}
+	if addresses := nodeConfig.AnnounceAddresses(); len(addresses) != 0 {
+		t.Errorf("got announce addresses %v, want none", addresses)
+	}
+}
+
+func TestAnnounceAddressesOfTheExternalAddresses(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	_testFailingIPServices(t)
+
+	// the configured addresses are announced without a query
+	nodeConfig := &IpfsNodeConfig{ExternalIPv4: "203.0.113.7"}
+	addresses := nodeConfig.AnnounceAddresses()
+	if len(addresses) != 4 || addresses[0] != "/ip4/203.0.113.7/tcp/4001" {
+		t.Errorf("got announce addresses %v, want the IPv4 addresses", addresses)
+	}
+	nodeConfig = &IpfsNodeConfig{ExternalIPv4: "203.0.113.7", ExternalIPv6: "2001:db8::7"}
+	addresses = nodeConfig.AnnounceAddresses()
+	if len(addresses) != 8 || !strings.HasPrefix(addresses[4], "/ip6/2001:db8::7/") {
+		t.Errorf("got announce addresses %v, want the IPv4 and IPv6 addresses", addresses)
+	}
+
+	// the external addresses must be of their address family
+	for _, nodeConfig := range []*IpfsNodeConfig{{ExternalIPv4: "2001:db8::7"}, {ExternalIPv6: "203.0.113.7"}, {ExternalIPv4: "router"}} {
+		if err := nodeConfig.Validate(); err == nil {
+			t.Errorf("%+v: expected an invalid external address", nodeConfig)
+		}
+	}
+}
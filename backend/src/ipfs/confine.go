@@ -0,0 +1,144 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+/*
+
+The files of a render request are added to IPFS as a directory, whose entries
+are named by the relative paths of the files (e.g., 'textures/wood.png'). When
+another node downloads the directory, the entries are written below its output
+path. Since the entry names come from the network, a name like '../../x' could
+otherwise escape the output path. Therefore:
+
+  - the relative paths of the files must consist of plain path components
+    (no absolute paths, no empty components, no '.' or '..', no backslashes)
+  - each entry of a downloaded directory must be a single path component and
+    symbolic links are not written, since they could point anywhere
+
+A download with an unsafe entry fails and its partial output is removed.
+
+*/
+
+import (
+
+	// standard
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	// external
+	"github.com/ipfs/boxo/files"
+)
+
+// Error of a file name, which could escape the directory it is written to
+var ErrUnsafePath = errors.New("unsafe path")
+
+// PATH CONFINEMENT
+// #############################################################################
+// Check if the relative path of a file stays within its directory (e.g., 'textures/wood.png')
+func ValidateRelativePath(name string) error {
+
+	if name == "" {
+		return fmt.Errorf("%w: The file name is empty.", ErrUnsafePath)
+	}
+	if strings.HasPrefix(name, "/") || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return fmt.Errorf("%w: The file name '%v' is an absolute path.", ErrUnsafePath, name)
+	}
+	for _, component := range strings.Split(name, "/") {
+		err := ValidateEntryName(component)
+		if err != nil {
+			return fmt.Errorf("%w: The file name '%v' is not a plain relative path.", ErrUnsafePath, name)
+		}
+	}
+
+	return nil
+
+}
+
+// Check if the name of a directory entry is a single path component
+func ValidateEntryName(name string) error {
+
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\\x00") {
+		return fmt.Errorf("%w: Invalid directory entry '%v'.", ErrUnsafePath, name)
+	}
+
+	return nil
+
+}
+
+// CONFINED FILE NODES
+// #############################################################################
+// Directory node whose entries are checked, before they are written
+type confinedDirectory struct {
+	files.Directory
+}
+
+func (cd *confinedDirectory) Entries() files.DirIterator {
+	return &confinedDirIterator{DirIterator: cd.Directory.Entries()}
+}
+
+// Directory iterator, which stops at the first unsafe entry
+type confinedDirIterator struct {
+	files.DirIterator
+	err error
+}
+
+func (ci *confinedDirIterator) Next() bool {
+
+	if ci.err != nil || !ci.DirIterator.Next() {
+		return false
+	}
+	ci.err = ValidateEntryName(ci.DirIterator.Name())
+	if ci.err == nil {
+		if _, ok := ci.DirIterator.Node().(*files.Symlink); ok {
+			ci.err = fmt.Errorf("%w: The directory entry '%v' is a symbolic link.", ErrUnsafePath, ci.DirIterator.Name())
+		}
+	}
+
+	return ci.err == nil
+
+}
+
+func (ci *confinedDirIterator) Node() files.Node {
+	return confineNode(ci.DirIterator.Node())
+}
+
+func (ci *confinedDirIterator) Err() error {
+
+	if ci.err != nil {
+		return ci.err
+	}
+
+	return ci.DirIterator.Err()
+
+}
+
+// Wrap the file or directory node to check its entries, before they are written
+func confineNode(node files.Node) files.Node {
+
+	if directory, ok := node.(files.Directory); ok {
+		return &confinedDirectory{directory}
+	}
+
+	return node
+
+}
@@ -0,0 +1,101 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+import (
+
+	// standard
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	// external
+	"github.com/ipfs/boxo/files"
+)
+
+func TestValidateRelativePath(t *testing.T) {
+	for _, name := range []string{"scene.blend", "textures/wood.png", "a/b/c.exr"} {
+		if err := ValidateRelativePath(name); err != nil {
+			t.Errorf("expected '%v' to be valid: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "../x", "textures/../../x", "/abs", "a\\b", "./x", "a//b", "a/", "a\x00b"} {
+		if err := ValidateRelativePath(name); !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("expected '%v' to be unsafe, got %v", name, err)
+		}
+	}
+}
+
+func TestValidateEntryName(t *testing.T) {
+	if err := ValidateEntryName("wood.png"); err != nil {
+		t.Errorf("expected a plain entry name to be valid: %v", err)
+	}
+	for _, name := range []string{"", ".", "..", "../x", "a/b", "a\\b", "/abs"} {
+		if err := ValidateEntryName(name); !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("expected '%v' to be unsafe, got %v", name, err)
+		}
+	}
+}
+
+func TestConfineNodeRejectsUnsafeEntries(t *testing.T) {
+	for name, entry := range map[string]files.Node{
+		"..":      files.NewBytesFile([]byte("escaped")),
+		"../x":    files.NewBytesFile([]byte("escaped")),
+		"a\\b":    files.NewBytesFile([]byte("escaped")),
+		"link":    files.NewLinkFile("/etc/passwd", nil),
+		"nested":  files.NewMapDirectory(map[string]files.Node{"../../x": files.NewBytesFile([]byte("escaped"))}),
+		"symlink": files.NewMapDirectory(map[string]files.Node{"link": files.NewLinkFile("../../x", nil)}),
+	} {
+		directory := files.NewMapDirectory(map[string]files.Node{name: entry})
+		output := filepath.Join(t.TempDir(), "output")
+
+		err := files.WriteTo(confineNode(directory), output)
+		if !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("entry '%v': expected an unsafe path error, got %v", name, err)
+		}
+		if _, err := os.Lstat(filepath.Join(filepath.Dir(output), "x")); err == nil {
+			t.Errorf("entry '%v': a file was written outside of the output path", name)
+		}
+	}
+}
+
+func TestConfineNodeWritesSafeEntries(t *testing.T) {
+	directory := files.NewMapDirectory(map[string]files.Node{
+		"scene.blend": files.NewBytesFile([]byte("blend")),
+		"textures":    files.NewMapDirectory(map[string]files.Node{"wood.png": files.NewBytesFile([]byte("png"))}),
+	})
+	output := filepath.Join(t.TempDir(), "output")
+
+	if err := files.WriteTo(confineNode(directory), output); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(output, "textures", "wood.png"))
+	if err != nil || string(data) != "png" {
+		t.Errorf("expected the nested file to be written, got %q (%v)", data, err)
+	}
+
+	// a single file is not wrapped
+	file := files.NewBytesFile([]byte("blend"))
+	if confineNode(file) != files.Node(file) {
+		t.Error("expected a file node to be returned unchanged")
+	}
+}
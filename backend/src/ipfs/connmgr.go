@@ -0,0 +1,184 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+/*
+
+This file contains the connection limits of the local IPFS node.
+
+As a full DHT node, the local node connects to many peers, which consumes
+memory and file descriptors. The libp2p connection manager closes the oldest
+connections, when the number of connections exceeds the high watermark, until
+only the low watermark is left. New connections are protected for the grace
+period. The watermarks and the grace period are set with the 'connmgr_*'
+options of 'ipfs.json'.
+
+After the node started, the limits of the running connection manager are
+compared with the configured limits, so that a configuration, which did not
+reach the node, is noticed. The node also waits for no more peers on start than
+the low watermark allows.
+
+*/
+
+import (
+
+	// standard
+	"errors"
+	"fmt"
+	"time"
+
+	// external
+	"github.com/ipfs/kubo/config"
+	"github.com/libp2p/go-libp2p/p2p/net/connmgr"
+	"github.com/spf13/cobra"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// Connection limits and connections of the local IPFS node
+type ConnectionStatus struct {
+	Connections int           // number of open connections
+	Peers       int           // number of connected peers
+	LowWater    int           // number of connections the connection manager trims down to
+	HighWater   int           // number of connections that triggers the trimming
+	GracePeriod time.Duration // duration new connections are protected from trimming
+	LastTrim    time.Time     // the datetime of the last trimming (zero, if never)
+}
+
+// CONNECTION LIMITS
+// #############################################################################
+// Get the connection limits and the number of connections of the local IPFS node
+func (ipfsm *PackageManager) ConnectionStatus() (ConnectionStatus, error) {
+
+	// check if the node is running
+	if ipfsm.IpfsNode == nil || ipfsm.IpfsNode.PeerHost == nil {
+		return ConnectionStatus{}, errors.New("Could not find the local IPFS node.")
+	}
+
+	network := ipfsm.IpfsNode.PeerHost.Network()
+	status := ConnectionStatus{
+		Connections: len(network.Conns()),
+		Peers:       len(network.Peers()),
+	}
+
+	// get the limits of the running connection manager
+	manager, ok := ipfsm.IpfsNode.PeerHost.ConnManager().(interface{ GetInfo() connmgr.CMInfo })
+	if !ok {
+		return status, errors.New("The connection manager of the IPFS node is disabled (no connection limits).")
+	}
+	info := manager.GetInfo()
+	status.LowWater = info.LowWater
+	status.HighWater = info.HighWater
+	status.GracePeriod = info.GracePeriod
+	status.LastTrim = info.LastTrim
+
+	return status, nil
+
+}
+
+// Check if the running connection manager uses the configured limits
+func (ipfsm *PackageManager) CheckConnectionLimits() error {
+
+	status, err := ipfsm.ConnectionStatus()
+	if err != nil {
+		return err
+	}
+
+	// get the configured limits of the repo
+	cfg, err := ipfsm.IpfsRepo.Config()
+	if err != nil {
+		return err
+	}
+	lowWater := cfg.Swarm.ConnMgr.LowWater.WithDefault(config.DefaultConnMgrLowWater)
+	highWater := cfg.Swarm.ConnMgr.HighWater.WithDefault(config.DefaultConnMgrHighWater)
+	gracePeriod := cfg.Swarm.ConnMgr.GracePeriod.WithDefault(config.DefaultConnMgrGracePeriod)
+
+	if int64(status.LowWater) != lowWater || int64(status.HighWater) != highWater || status.GracePeriod != gracePeriod {
+		return errors.New(fmt.Sprintf("The connection manager uses the limits %v/%v/%v instead of the configured limits %v/%v/%v (low/high watermark, grace period).", status.LowWater, status.HighWater, status.GracePeriod, lowWater, highWater, gracePeriod))
+	}
+
+	return nil
+
+}
+
+// helper function to get the number of peers the node waits for on start
+// NOTE: The node never waits for more peers than the low watermark, since the
+// connection manager would trim the connections again.
+func (ipfsm *PackageManager) _bootstrapPeers() int {
+
+	peers := RENDERHIVE_CONFIG_IPFS_BOOTSTRAP_PEERS
+	if status, err := ipfsm.ConnectionStatus(); err == nil && status.LowWater > 0 && status.LowWater < peers {
+		peers = status.LowWater
+	}
+
+	return peers
+
+}
+
+// COMMAND LINE INTERFACE - CONNECTION LIMITS
+// #############################################################################
+// Create the CLI command to print the connections and limits of the local IPFS node
+func (ipfsm *PackageManager) CreateCommandSwarm_Limits() *cobra.Command {
+
+	// create a 'limits' command for the node
+	command := &cobra.Command{
+		Use:   "limits",
+		Short: "Print the connections and the connection limits",
+		Long:  "This command prints the number of open connections and connected peers of the local IPFS node and the watermarks and grace period of its connection manager.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			status, err := ipfsm.ConnectionStatus()
+			if err != nil && status.Connections == 0 && status.Peers == 0 {
+
+				logger.Manager.Println("")
+				return err
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Println("Connections of the local IPFS node:")
+			logger.Manager.Resultf(" [#] Connections: %v\n", status.Connections)
+			logger.Manager.Resultf(" [#] Peers: %v\n", status.Peers)
+			if err != nil {
+				logger.Manager.Resultf(" [#] Limits: %v\n", err)
+			} else {
+				logger.Manager.Resultf(" [#] Low watermark: %v\n", status.LowWater)
+				logger.Manager.Resultf(" [#] High watermark: %v\n", status.HighWater)
+				logger.Manager.Resultf(" [#] Grace period: %v\n", status.GracePeriod)
+				if !status.LastTrim.IsZero() {
+					logger.Manager.Resultf(" [#] Last trim: %v\n", status.LastTrim.Format(time.RFC3339))
+				}
+				if status.Connections > status.HighWater {
+					logger.Manager.Resultf(" [#] The connections exceed the high watermark and will be trimmed.\n")
+				}
+			}
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	return command
+
+}
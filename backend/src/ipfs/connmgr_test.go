@@ -0,0 +1,184 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+import (
+
+	// standard
+	"context"
+	"testing"
+	"time"
+
+	// external
+	"github.com/ipfs/kubo/config"
+	"github.com/ipfs/kubo/core"
+	"github.com/ipfs/kubo/core/node/libp2p"
+	"github.com/ipfs/kubo/repo/fsrepo"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// helper function to start an online IPFS node on the loopback interface with
+// the given node configuration
+func _testConnMgrNode(t *testing.T, nodeConfig *IpfsNodeConfig) *PackageManager {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+	_testInjectPlugins(t)
+
+	// create a repo with the applied configuration, which does not connect to
+	// other peers
+	cfg, err := nodeConfig.InitRepoConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeConfig.Apply(cfg)
+	cfg.Addresses.Swarm = []string{"/ip4/127.0.0.1/tcp/0"}
+	cfg.Bootstrap = []string{}
+	repoPath := t.TempDir()
+	if err = fsrepo.Init(repoPath, cfg); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := fsrepo.Open(repoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// start the node
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	node, err := core.NewNode(ctx, &core.BuildCfg{Online: true, Routing: libp2p.NilRouterOption, Repo: repo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { node.Close() })
+
+	return &PackageManager{IpfsContext: ctx, IpfsNode: node, IpfsRepo: repo, NodeConfig: *nodeConfig}
+}
+
+func TestConfiguredWatermarksReachTheNode(t *testing.T) {
+	ipfsm := _testConnMgrNode(t, &IpfsNodeConfig{ConnMgrLowWater: 2, ConnMgrHighWater: 10, ConnMgrGracePeriod: "45s"})
+
+	status, err := ipfsm.ConnectionStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.LowWater != 2 || status.HighWater != 10 || status.GracePeriod != 45*time.Second {
+		t.Errorf("got the limits %v/%v/%v, want 2/10/45s", status.LowWater, status.HighWater, status.GracePeriod)
+	}
+	if status.Connections != 0 || status.Peers != 0 {
+		t.Errorf("got %v connections to %v peers, want none", status.Connections, status.Peers)
+	}
+	if err := ipfsm.CheckConnectionLimits(); err != nil {
+		t.Errorf("expected the configured limits to be used: %v", err)
+	}
+
+	// the node waits for no more peers than the low watermark on start
+	if peers := ipfsm._bootstrapPeers(); peers != 2 {
+		t.Errorf("got %v bootstrap peers, want the low watermark of 2", peers)
+	}
+
+	// a configuration, which did not reach the node, is noticed
+	cfg, err := ipfsm.IpfsRepo.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Swarm.ConnMgr.HighWater = config.NewOptionalInteger(20)
+	if err := ipfsm.IpfsRepo.SetConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := ipfsm.CheckConnectionLimits(); err == nil {
+		t.Error("expected the changed high watermark to be noticed")
+	}
+}
+
+func TestDefaultWatermarksReachTheNode(t *testing.T) {
+	ipfsm := _testConnMgrNode(t, &IpfsNodeConfig{})
+
+	status, err := ipfsm.ConnectionStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(status.LowWater) != config.DefaultConnMgrLowWater || int64(status.HighWater) != config.DefaultConnMgrHighWater || status.GracePeriod != config.DefaultConnMgrGracePeriod {
+		t.Errorf("got the limits %v/%v/%v, want the defaults of kubo", status.LowWater, status.HighWater, status.GracePeriod)
+	}
+	if err := ipfsm.CheckConnectionLimits(); err != nil {
+		t.Errorf("expected the default limits to be used: %v", err)
+	}
+
+	// the default low watermark does not limit the bootstrap peers
+	if peers := ipfsm._bootstrapPeers(); peers != RENDERHIVE_CONFIG_IPFS_BOOTSTRAP_PEERS {
+		t.Errorf("got %v bootstrap peers, want %v", peers, RENDERHIVE_CONFIG_IPFS_BOOTSTRAP_PEERS)
+	}
+}
+
+func TestBootstrapPeersOfTheLowWatermark(t *testing.T) {
+	tests := []struct {
+		lowWater int64
+		peers    int
+	}{
+		{RENDERHIVE_CONFIG_IPFS_BOOTSTRAP_PEERS - 1, RENDERHIVE_CONFIG_IPFS_BOOTSTRAP_PEERS - 1},
+		{RENDERHIVE_CONFIG_IPFS_BOOTSTRAP_PEERS, RENDERHIVE_CONFIG_IPFS_BOOTSTRAP_PEERS},
+		{RENDERHIVE_CONFIG_IPFS_BOOTSTRAP_PEERS + 1, RENDERHIVE_CONFIG_IPFS_BOOTSTRAP_PEERS},
+	}
+	for _, test := range tests {
+		ipfsm := _testConnMgrNode(t, &IpfsNodeConfig{ConnMgrLowWater: test.lowWater, ConnMgrHighWater: 10})
+		if peers := ipfsm._bootstrapPeers(); peers != test.peers {
+			t.Errorf("low watermark %v: got %v bootstrap peers, want %v", test.lowWater, peers, test.peers)
+		}
+	}
+
+	// without a running node the default applies
+	ipfsm := &PackageManager{}
+	if peers := ipfsm._bootstrapPeers(); peers != RENDERHIVE_CONFIG_IPFS_BOOTSTRAP_PEERS {
+		t.Errorf("got %v bootstrap peers without a node, want %v", peers, RENDERHIVE_CONFIG_IPFS_BOOTSTRAP_PEERS)
+	}
+	if _, err := ipfsm.ConnectionStatus(); err == nil {
+		t.Error("expected no connection status without a node")
+	}
+}
+
+func TestValidateWatermarks(t *testing.T) {
+	tests := []struct {
+		name       string
+		nodeConfig IpfsNodeConfig
+		valid      bool
+	}{
+		{"defaults", IpfsNodeConfig{}, true},
+		{"low below high", IpfsNodeConfig{ConnMgrLowWater: 50, ConnMgrHighWater: 100}, true},
+		{"low equals high", IpfsNodeConfig{ConnMgrLowWater: 100, ConnMgrHighWater: 100}, true},
+		{"low above high", IpfsNodeConfig{ConnMgrLowWater: 101, ConnMgrHighWater: 100}, false},
+		{"only low", IpfsNodeConfig{ConnMgrLowWater: 50}, true},
+		{"negative low", IpfsNodeConfig{ConnMgrLowWater: -1}, false},
+		{"negative high", IpfsNodeConfig{ConnMgrHighWater: -1}, false},
+		{"grace period", IpfsNodeConfig{ConnMgrGracePeriod: "1m30s"}, true},
+		{"invalid grace period", IpfsNodeConfig{ConnMgrGracePeriod: "90"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.nodeConfig.Validate(); (err == nil) != test.valid {
+				t.Errorf("got error %v, want valid %v", err, test.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+/*
+
+This file contains the resumable retrieval of files and directories from IPFS.
+
+The object is written to a temporary path next to the output path ('.part')
+in chunks. The progress is recorded in a state file ('.part.json'), which also
+binds the temporary data to the CID. If the retrieval is interrupted, the next
+retrieval of the same CID continues each file at the end of its temporary
+data. Completed files of a directory are skipped.
+
+When all data is retrieved, the CID of the temporary data is calculated and
+compared to the requested CID, before the data is moved to the output path.
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	// external
+	"github.com/ipfs/boxo/files"
+	"github.com/ipfs/boxo/path"
+	gocid "github.com/ipfs/go-cid"
+	ioptions "github.com/ipfs/kubo/core/coreiface/options"
+
+	// internal
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// number of bytes retrieved before the progress is reported and recorded
+const downloadChunkSize = 1024 * 1024
+
+// Callback reporting the number of retrieved bytes and the total number of bytes
+type DownloadProgress func(received int64, total int64)
+
+// State of an interrupted retrieval
+type downloadState struct {
+	CID      string `json:"cid"`
+	Received int64  `json:"received"`
+	Total    int64  `json:"total"`
+}
+
+// RESUMABLE RETRIEVAL
+// #############################################################################
+// Get a file/directory from IPFS and write it to a local path (resuming an interrupted retrieval)
+func (ipfsm *PackageManager) GetObjectResumable(cid_string string, outputPath string, progress DownloadProgress) (string, error) {
+	var err error
+
+	// get a CID object from the string
+	cidObject, err := ParseCID(cid_string)
+	if err != nil {
+		return "", err
+	}
+
+	// the output path must not exist
+	if _, err := os.Lstat(outputPath); err == nil {
+		return "", errors.New(fmt.Sprintf("Path '%v' already exists.", outputPath))
+	}
+
+	// log info event
+	logger.Manager.Package["ipfs"].Debug().Msg(fmt.Sprintf("Downloading an object from IPFS (resumable): %v", cidObject.String()))
+
+	// get the root node of the file/directory
+	rootNode, err := ipfsm.IpfsAPI.Unixfs().Get(ipfsm.IpfsContext, path.FromCid(cidObject))
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Could not get file with CID: %s", err))
+	}
+	defer rootNode.Close()
+
+	// get the state of a previous retrieval
+	tempPath := outputPath + ".part"
+	statePath := tempPath + ".json"
+	state := downloadState{}
+	data, err := os.ReadFile(statePath)
+	if err == nil {
+		err = json.Unmarshal(data, &state)
+	}
+
+	// discard temporary data of another CID
+	if err != nil || state.CID != cidObject.String() {
+		err = os.RemoveAll(tempPath)
+		if err != nil {
+			return "", err
+		}
+		state = downloadState{CID: cidObject.String()}
+	} else {
+		logger.Manager.Package["ipfs"].Debug().Msg(fmt.Sprintf(" [#] Resuming the download at %v of %v bytes.", state.Received, state.Total))
+	}
+	state.Total, _ = rootNode.Size()
+	state.Received = 0
+
+	// retrieve the data into the temporary path
+	err = ipfsm._download(rootNode, tempPath, &state, statePath, ipfsm._downloadLimiters(), progress)
+	if errors.Is(err, ErrUnsafePath) {
+		os.RemoveAll(tempPath)
+		os.Remove(statePath)
+		return "", fmt.Errorf("Could not write out the fetched CID: %w", err)
+	} else if err != nil {
+		return "", err
+	}
+
+	// verify the retrieved data
+	retrievedCID, err := ipfsm._hashDownload(tempPath, cidObject)
+	if err != nil {
+		return "", err
+	}
+	if retrievedCID != cidObject.String() {
+		os.RemoveAll(tempPath)
+		os.Remove(statePath)
+		return "", errors.New(fmt.Sprintf("The retrieved data does not match the CID (retrieved: %v).", retrievedCID))
+	}
+
+	// move the data to the output path
+	err = os.Rename(tempPath, outputPath)
+	if err != nil {
+		return "", err
+	}
+	os.Remove(statePath)
+
+	// log info event
+	logger.Manager.Package["ipfs"].Debug().Msg(fmt.Sprintf(" [#] Finished the download of %v bytes.", state.Received))
+
+	return outputPath, nil
+
+}
+
+// Write the file/directory node to the temporary path (continuing existing data)
+func (ipfsm *PackageManager) _download(node files.Node, tempPath string, state *downloadState, statePath string, limiters []*bandwidthLimiter, progress DownloadProgress) error {
+	var err error
+
+	switch n := node.(type) {
+	case *files.Symlink:
+
+		// symbolic links are not written, since their target could point anywhere
+		return fmt.Errorf("%w: The entry '%v' is a symbolic link.", ErrUnsafePath, filepath.Base(tempPath))
+
+	case files.File:
+
+		// open the temporary file and continue at its end
+		file, err := os.OpenFile(tempPath, os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		offset, err := file.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+
+		// start again, if the temporary file is larger than the retrieved file
+		size, err := n.Size()
+		if err == nil && offset > size {
+			err = file.Truncate(0)
+			if err != nil {
+				return err
+			}
+			offset, _ = file.Seek(0, io.SeekStart)
+		}
+
+		// skip the retrieved data
+		_, err = n.Seek(offset, io.SeekStart)
+		if err != nil {
+			return err
+		}
+		state.Received += offset
+
+		// retrieve the remaining data in chunks
+		reader := &throttledReader{n, limiters}
+		for {
+			written, err := io.CopyN(file, reader, downloadChunkSize)
+			state.Received += written
+
+			// record the progress
+			if written > 0 {
+				file.Sync()
+				ipfsm._recordDownload(state, statePath)
+				if progress != nil {
+					progress(state.Received, state.Total)
+				}
+			}
+
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return errors.New(fmt.Sprintf("Download interrupted: %v", err))
+			}
+		}
+
+	case files.Directory:
+
+		err = os.MkdirAll(tempPath, 0755)
+		if err != nil {
+			return err
+		}
+
+		// retrieve each entry of the directory
+		entries := n.Entries()
+		for entries.Next() {
+			name := entries.Name()
+			err = ValidateEntryName(name)
+			if err != nil {
+				return err
+			}
+			err = ipfsm._download(entries.Node(), filepath.Join(tempPath, name), state, statePath, limiters, progress)
+			if err != nil {
+				return err
+			}
+		}
+		return entries.Err()
+
+	}
+
+	return errors.New(fmt.Sprintf("File type %T at '%v' is not supported.", node, tempPath))
+
+}
+
+// Write the progress of the retrieval to the state file
+func (ipfsm *PackageManager) _recordDownload(state *downloadState, statePath string) {
+
+	data, err := json.Marshal(state)
+	if err == nil {
+		err = os.WriteFile(statePath, data, 0644)
+	}
+	if err != nil {
+		logger.Manager.Package["ipfs"].Error().Msg(fmt.Sprintf(" [#] Could not record the download progress: %v", err))
+	}
+
+}
+
+// Check if a local file/directory matches a CID (e.g., a cached copy of an object)
+func (ipfsm *PackageManager) VerifyPath(localPath string, cid_string string) error {
+
+	// get a CID object from the string
+	cidObject, err := ParseCID(cid_string)
+	if err != nil {
+		return err
+	}
+	if ipfsm.IpfsAPI == nil {
+		return errors.New("The IPFS node is not running.")
+	}
+
+	// calculate the CID of the local data
+	localCID, err := ipfsm._hashDownload(localPath, cidObject)
+	if err != nil {
+		return err
+	}
+	if localCID != cidObject.String() {
+		return errors.New(fmt.Sprintf("The local data does not match the CID (local: %v).", localCID))
+	}
+
+	return nil
+
+}
+
+// Calculate the CID of the retrieved data with the CID version of the requested CID
+// NOTE: The CID only matches, if the object was added with the default chunker.
+func (ipfsm *PackageManager) _hashDownload(tempPath string, cidObject gocid.Cid) (string, error) {
+	var err error
+
+	// get the file information
+	stat, err := os.Stat(tempPath)
+	if err != nil {
+		return "", err
+	}
+
+	// load the file into memory
+	// NOTE: Hidden files are part of the object, so they are included.
+	file, err := files.NewSerialFile(tempPath, true, stat)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	// calculate the hash
+	options := []ioptions.UnixfsAddOption{ioptions.Unixfs.HashOnly(true), ioptions.Unixfs.CidVersion(int(cidObject.Version()))}
+	cid, err := ipfsm.IpfsAPI.Unixfs().Add(ipfsm.IpfsContext, file, options...)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Failed to calculate only hash: %v", err.Error()))
+	}
+
+	return cid.RootCid().String(), nil
+
+}
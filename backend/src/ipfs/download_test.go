@@ -0,0 +1,140 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+import (
+	// standard
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	// external
+	"github.com/ipfs/boxo/files"
+	gocid "github.com/ipfs/go-cid"
+	"github.com/ipfs/kubo/core"
+	"github.com/ipfs/kubo/core/coreapi"
+	ioptions "github.com/ipfs/kubo/core/coreiface/options"
+
+	// internal
+	"renderhive/logger"
+)
+
+// helper function to create a package manager with an offline in-memory IPFS node
+func _testOfflineManager(t *testing.T) *PackageManager {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	node, err := core.NewNode(ctx, &core.BuildCfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { node.Close() })
+	api, err := coreapi.NewCoreAPI(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &PackageManager{IpfsContext: ctx, IpfsNode: node, IpfsAPI: api}
+}
+
+func TestHashDownloadIncludesHiddenFiles(t *testing.T) {
+	ipfsm := _testOfflineManager(t)
+
+	// a downloaded directory with a hidden file
+	directory := t.TempDir()
+	if err := os.WriteFile(filepath.Join(directory, "scene.blend"), []byte("blend"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(directory, ".hidden"), []byte("hidden"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the CID of the object with the hidden file
+	stat, err := os.Stat(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := files.NewSerialFile(directory, true, stat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	added, err := ipfsm.IpfsAPI.Unixfs().Add(ipfsm.IpfsContext, file, ioptions.Unixfs.HashOnly(true), ioptions.Unixfs.CidVersion(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cid, err := gocid.Decode(added.RootCid().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the download matches the CID
+	local, err := ipfsm._hashDownload(directory, cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if local != cid.String() {
+		t.Fatalf("got CID %v, want %v", local, cid)
+	}
+}
+
+// helper function to add a directory with the given entries to the offline IPFS node
+func _testAddDirectory(t *testing.T, ipfsm *PackageManager, entries map[string]files.Node) string {
+	t.Helper()
+
+	added, err := ipfsm.IpfsAPI.Unixfs().Add(ipfsm.IpfsContext, files.NewMapDirectory(entries))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return added.RootCid().String()
+}
+
+func TestResumableDownloadRejectsUnsafeEntries(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+	ipfsm := _testOfflineManager(t)
+
+	for name, entries := range map[string]map[string]files.Node{
+		"symlink":        {"link": files.NewLinkFile("/etc/passwd", nil)},
+		"nested symlink": {"textures": files.NewMapDirectory(map[string]files.Node{"link": files.NewLinkFile("../../x", nil)})},
+		"traversal":      {"../x": files.NewBytesFile([]byte("escaped"))},
+		"nul":            {"a\x00b": files.NewBytesFile([]byte("escaped"))},
+	} {
+		cid := _testAddDirectory(t, ipfsm, entries)
+		output := filepath.Join(t.TempDir(), "output")
+
+		_, err := ipfsm.GetObjectResumable(cid, output, nil)
+		if !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("%v: expected an unsafe path error, got %v", name, err)
+		}
+
+		// nothing is written outside of the output path and the partial output is removed
+		for _, path := range []string{filepath.Join(filepath.Dir(output), "x"), output, output + ".part", output + ".part.json"} {
+			if _, err := os.Lstat(path); err == nil {
+				t.Errorf("%v: unexpected file '%v'", name, filepath.Base(path))
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+/*
+
+This file contains the functions to read and replace the peer identity of the
+local IPFS repository. They enable the migration of a node to a new computer,
+without changing its peer ID.
+
+*/
+
+import (
+
+	// standard
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	// external
+	"github.com/ipfs/kubo/config"
+	serialize "github.com/ipfs/kubo/config/serialize"
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	// internal
+	. "renderhive/globals"
+	. "renderhive/utility"
+)
+
+// IPFS PEER IDENTITY
+// #############################################################################
+// Get the path of the local IPFS repository
+func RepoPath() string {
+	return filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_IPFS_REPO)
+}
+
+// Read the peer identity from the configuration of the local IPFS repository
+// NOTE: Returns an error satisfying os.IsNotExist, if the repository was not
+// initialized yet.
+func ReadIdentity() (config.Identity, error) {
+
+	cfg, err := serialize.Load(filepath.Join(RepoPath(), "config"))
+	if errors.Is(err, serialize.ErrNotInitialized) {
+		return config.Identity{}, os.ErrNotExist
+	}
+	if err != nil {
+		return config.Identity{}, err
+	}
+
+	return cfg.Identity, nil
+
+}
+
+// Replace the peer identity in the configuration of the local IPFS repository
+// NOTE: The local IPFS node uses the new identity after its next start.
+func WriteIdentity(identity config.Identity) error {
+	var err error
+
+	// check the identity before replacing the current one
+	err = ValidateIdentity(identity)
+	if err != nil {
+		return err
+	}
+
+	// replace only the identity and keep all other options of the repository
+	path := filepath.Join(RepoPath(), "config")
+	var cfg map[string]interface{}
+	err = serialize.ReadConfigFile(path, &cfg)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Could not read the IPFS repository configuration (start the node once to create it): %v", err))
+	}
+	cfg["Identity"] = identity
+
+	return serialize.WriteConfigFile(path, cfg)
+
+}
+
+// Check if the private key of a peer identity belongs to its peer ID
+func ValidateIdentity(identity config.Identity) error {
+
+	key, err := identity.DecodePrivateKey("")
+	if err != nil {
+		return errors.New(fmt.Sprintf("Invalid private key of the IPFS peer identity: %v", err))
+	}
+	id, err := peer.IDFromPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if id.String() != identity.PeerID {
+		return errors.New(fmt.Sprintf("The private key of the IPFS peer identity does not belong to the peer ID '%v'.", identity.PeerID))
+	}
+
+	return nil
+
+}
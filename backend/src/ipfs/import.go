@@ -0,0 +1,260 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+/*
+
+This file contains the bulk import of local files into the IPFS repository of
+the node.
+
+Operators can pre-add a collection of files (e.g., an asset library of Blender
+files) to the local IPFS node, so render requests referencing these CIDs are
+rendered without another upload. Each regular file of the directory tree is
+added as a file of its own, so its CID does not depend on the directory it was
+imported from.
+
+The CID of each file is calculated first. Files whose content is already
+stored on the local node are skipped (and only pinned, if requested). The
+files are added by a number of parallel workers.
+
+*/
+
+import (
+
+	// standard
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	// external
+	"github.com/ipfs/boxo/path"
+	gocid "github.com/ipfs/go-cid"
+	"github.com/spf13/cobra"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// Result of a file of the bulk import
+type ImportResult struct {
+	Path    string // path of the file relative to the imported directory
+	CID     string // content identifier (CID) of the file
+	Skipped bool   // true, if the content was already stored on the local node
+	Pinned  bool   // true, if the file is pinned on the local node
+	Err     error  // error, if the file could not be imported
+}
+
+// Callback reporting the number of processed files, the total number of files, and the last result
+type ImportProgress func(done int, total int, result ImportResult)
+
+// BULK IMPORT
+// #############################################################################
+// Add all files of a local directory tree to the local IPFS node
+func (ipfsm *PackageManager) ImportDirectory(directory string, pin bool, concurrency int, progress ImportProgress) ([]ImportResult, error) {
+	var err error
+
+	if ipfsm.IpfsNode == nil {
+		return nil, errors.New("No IPFS node found")
+	}
+	if concurrency < 1 {
+		concurrency = RENDERHIVE_CONFIG_IPFS_IMPORT_CONCURRENCY
+	}
+
+	// collect the regular files of the directory tree
+	paths := []string{}
+	err = filepath.WalkDir(directory, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			paths = append(paths, filePath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Could not read directory '%v': %v", directory, err))
+	}
+
+	// log debug event
+	logger.Manager.Package["ipfs"].Debug().Msg(fmt.Sprintf("Importing %v files from '%v' (workers: %v) ...", len(paths), directory, concurrency))
+
+	// import the files with a number of parallel workers
+	jobs := make(chan string)
+	results := make(chan ImportResult)
+	var workers sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for filePath := range jobs {
+				result := ipfsm._importFile(filePath, pin)
+				result.Path, _ = filepath.Rel(directory, filePath)
+				results <- result
+			}
+		}()
+	}
+	go func() {
+		for _, filePath := range paths {
+			jobs <- filePath
+		}
+		close(jobs)
+		workers.Wait()
+		close(results)
+	}()
+
+	// collect the results and report the progress
+	imported := []ImportResult{}
+	for result := range results {
+		imported = append(imported, result)
+		if progress != nil {
+			progress(len(imported), len(paths), result)
+		}
+	}
+	sort.Slice(imported, func(i, j int) bool { return imported[i].Path < imported[j].Path })
+
+	return imported, nil
+
+}
+
+// helper function to import a single file
+func (ipfsm *PackageManager) _importFile(filePath string, pin bool) ImportResult {
+	var err error
+
+	result := ImportResult{}
+
+	// calculate the CID and check if the content is already stored
+	result.CID, err = ipfsm.GetHashFromPath(filePath)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	cidObject, err := gocid.Parse(result.CID)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Skipped, err = ipfsm.IpfsNode.Blockstore.Has(ipfsm.IpfsContext, cidObject)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	// add the file, if its content is not stored yet
+	if !result.Skipped {
+		result.CID, err = ipfsm.AddObjectFromPath(filePath, pin)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+	} else if pin {
+		// NOTE: The content is stored locally, so it is pinned without a DHT lookup.
+		err = ipfsm.IpfsAPI.Pin().Add(ipfsm.IpfsContext, path.FromCid(cidObject))
+		if err != nil {
+			result.Err = errors.New(fmt.Sprintf("Could not pin '%v': %v", result.CID, err))
+			return result
+		}
+	}
+
+	// check the pin status
+	_, result.Pinned, err = ipfsm.IpfsAPI.Pin().IsPinned(ipfsm.IpfsContext, path.FromCid(cidObject))
+	if err != nil {
+		result.Err = err
+	}
+
+	return result
+
+}
+
+// COMMAND LINE INTERFACE - BULK IMPORT
+// #############################################################################
+// Create the CLI command to import a local directory into the IPFS node
+func (ipfsm *PackageManager) CreateCommandImport() *cobra.Command {
+
+	// flags for the 'import' command
+	var pin bool
+	var concurrency int
+
+	// create an 'import' command for the node
+	command := &cobra.Command{
+		Use:   "import <dir>",
+		Short: "Add all files of a local directory to the IPFS node",
+		Long:  "This command recursively adds all files of a local directory to the local IPFS node (e.g., an asset library of Blender files). Each file is added with its own CID. Files that are already stored on the node are skipped.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// check if the path is pointing to a directory
+			if stat, err := os.Stat(args[0]); err != nil || !stat.IsDir() {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("'%v' is not a directory.", args[0])
+
+			}
+
+			// import the files and report each result
+			logger.Manager.Println("")
+			logger.Manager.Printf("Importing the files of '%v' into the IPFS node:\n", args[0])
+			failed, skipped := 0, 0
+			results, err := ipfsm.ImportDirectory(args[0], pin, concurrency, func(done int, total int, result ImportResult) {
+				if result.Err != nil {
+					failed += 1
+					logger.Manager.Errorln(fmt.Errorf(" [#] (%v/%v) %v: %v", done, total, result.Path, result.Err))
+					return
+				}
+
+				state := "added"
+				if result.Skipped {
+					skipped += 1
+					state = "already present"
+				}
+				if result.Pinned {
+					state += ", pinned"
+				}
+				logger.Manager.Resultf(" [#] (%v/%v) %v: %v (%v)\n", done, total, result.Path, result.CID, state)
+			})
+			if err != nil {
+
+				return fmt.Errorf("Could not import '%v': %w", args[0], err)
+
+			}
+
+			logger.Manager.Printf("Imported %v files (%v already present, %v failed).\n", len(results)-failed, skipped, failed)
+			logger.Manager.Println("")
+			if failed > 0 {
+				return fmt.Errorf("%v of %v files could not be imported.", failed, len(results))
+			}
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().BoolVarP(&pin, "pin", "p", true, "Pin the imported files locally to protect them from garbage collection")
+	command.Flags().IntVarP(&concurrency, "concurrency", "c", RENDERHIVE_CONFIG_IPFS_IMPORT_CONCURRENCY, "The number of files added in parallel")
+
+	return command
+
+}
@@ -0,0 +1,145 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+import (
+	// standard
+	"os"
+	"path/filepath"
+	"testing"
+
+	// external
+	"github.com/ipfs/boxo/files"
+)
+
+// helper function to create a small nested directory of files
+func _testImportDirectory(t *testing.T) (string, map[string][]byte) {
+	t.Helper()
+
+	directory := t.TempDir()
+	content := map[string][]byte{
+		"scene.blend":                  []byte("blend"),
+		"textures/wood.png":            []byte("wood"),
+		"textures/stone/granite.png":   []byte("granite"),
+		"textures/stone/duplicate.png": []byte("wood"),
+	}
+	for name, data := range content {
+		path := filepath.Join(directory, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return directory, content
+}
+
+func TestImportDirectoryAddsAndPinsTheFiles(t *testing.T) {
+	ipfsm := _testWorkspaceManager(t)
+	directory, content := _testImportDirectory(t)
+
+	progress := 0
+	results, err := ipfsm.ImportDirectory(directory, true, 2, func(done int, total int, result ImportResult) {
+		progress += 1
+		if done != progress || total != len(content) {
+			t.Errorf("got progress %v/%v, want %v/%v", done, total, progress, len(content))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(content) || progress != len(content) {
+		t.Fatalf("got %v results (%v reported), want %v", len(results), progress, len(content))
+	}
+
+	// each file is added with the CID of its own content and pinned
+	for _, result := range results {
+		data, ok := content[filepath.ToSlash(result.Path)]
+		if !ok {
+			t.Fatalf("unexpected file %v", result.Path)
+		}
+		cid, err := ipfsm.GetHashFromObject(files.NewBytesFile(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Err != nil || result.CID != cid || !result.Pinned {
+			t.Errorf("%v: got %+v, want the pinned CID %v", result.Path, result, cid)
+		}
+	}
+
+	// a repeated import skips the stored content
+	results, err = ipfsm.ImportDirectory(directory, true, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		if result.Err != nil || !result.Skipped || !result.Pinned {
+			t.Errorf("%v: got %+v, want a skipped and pinned file", result.Path, result)
+		}
+	}
+}
+
+func TestImportDirectoryWithoutPinning(t *testing.T) {
+	ipfsm := _testWorkspaceManager(t)
+	directory, content := _testImportDirectory(t)
+
+	results, err := ipfsm.ImportDirectory(directory, false, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(content) {
+		t.Fatalf("got %v results, want %v", len(results), len(content))
+	}
+	for _, result := range results {
+		if result.Err != nil || result.Pinned {
+			t.Errorf("%v: got %+v, want an unpinned file", result.Path, result)
+		}
+	}
+}
+
+func TestImportDirectoryRejectsInvalidInput(t *testing.T) {
+	ipfsm := _testWorkspaceManager(t)
+
+	// a directory that does not exist
+	if _, err := ipfsm.ImportDirectory(filepath.Join(t.TempDir(), "missing"), true, 1, nil); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+
+	// the command only imports directories
+	file := filepath.Join(t.TempDir(), "scene.blend")
+	if err := os.WriteFile(file, []byte("blend"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{file}, {filepath.Join(t.TempDir(), "missing")}, {}} {
+		command := ipfsm.CreateCommandImport()
+		command.SetArgs(args)
+		if err := command.Execute(); err == nil {
+			t.Errorf("%v: expected the command to fail", args)
+		}
+	}
+
+	// without an IPFS node
+	if _, err := (&PackageManager{}).ImportDirectory(t.TempDir(), true, 1, nil); err == nil {
+		t.Error("expected an error without an IPFS node")
+	}
+}
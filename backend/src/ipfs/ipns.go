@@ -0,0 +1,446 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+/*
+
+This file contains the IPNS records published by the local IPFS node.
+
+Render documents get a new CID each time they change. An IPNS name is derived
+from a key of the node and can always point to the latest CID of a document
+(e.g., the current render offer document of the node). The node's own key is
+named 'self'; further named keys are generated on their first use.
+
+IPNS records are signed with a lifetime and a cache time (TTL):
+
+  - The lifetime is the time a record stays valid. Other nodes drop expired
+    records, so the name becomes unresolvable, if the record is not
+    republished in time. Therefore, a background republisher publishes all
+    records of this node again after the republish interval, which is well
+    below the lifetime.
+  - The TTL is the time resolvers may cache the resolved CID. A short TTL
+    lets other nodes see updates quickly, but causes more lookups.
+
+The records are saved in the app data ('data/ipns/records.json'), so that the
+republisher continues after a restart. The republisher runs in the background,
+since publishing a record can take up to RENDERHIVE_CONFIG_IPNS_TIMEOUT.
+
+*/
+
+import (
+
+	// standard
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	// external
+	"github.com/ipfs/boxo/path"
+	ioptions "github.com/ipfs/kubo/core/coreiface/options"
+	"github.com/spf13/cobra"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// name of the file of the IPNS records in the IPNS directory
+const ipnsRecordsFile = "records.json"
+
+// IPNS record published by this node
+type IPNSRecord struct {
+	Key       string    // name of the key the record is signed with
+	Name      string    // IPNS name derived from the key
+	CID       string    // CID the record points to
+	Published time.Time // datetime the record was last published
+}
+
+// IPNS RECORDS
+// #############################################################################
+// Publish an IPNS record of the local node, which points to the given CID
+// NOTE: The key is the name of a key in the keystore of the node ('self', if
+// empty). A missing key is generated. Returns the IPNS name.
+func (ipfsm *PackageManager) PublishIPNS(cid string, key string) (string, error) {
+	var err error
+
+	if ipfsm.IpfsAPI == nil {
+		return "", errors.New("No IPFS node found")
+	}
+
+	// get a CID object from the string
+	cidObject, err := ParseCID(cid)
+	if err != nil {
+		return "", err
+	}
+
+	// use the key of the node or a named key
+	if key == "" {
+		key = "self"
+	}
+	err = ipfsm._ipnsKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	// log debug event
+	logger.Manager.Package["ipfs"].Debug().Msg(fmt.Sprintf("Publishing IPNS record of key '%v': %v", key, cid))
+
+	// publish the record
+	ctx, cancel := context.WithTimeout(ipfsm.IpfsContext, RENDERHIVE_CONFIG_IPNS_TIMEOUT)
+	defer cancel()
+	name, err := ipfsm.IpfsAPI.Name().Publish(ctx, path.FromCid(cidObject),
+		ioptions.Name.Key(key),
+		ioptions.Name.ValidTime(RENDERHIVE_CONFIG_IPNS_RECORD_LIFETIME),
+		ioptions.Name.TTL(RENDERHIVE_CONFIG_IPNS_RECORD_TTL),
+		ioptions.Name.AllowOffline(true),
+	)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Could not publish IPNS record of key '%v': %v", key, err))
+	}
+
+	// remember the record for the republisher
+	ipfsm.ipnsMutex.Lock()
+	defer ipfsm.ipnsMutex.Unlock()
+	if ipfsm.IPNSRecords == nil {
+		ipfsm.IPNSRecords = make(map[string]*IPNSRecord)
+	}
+	ipfsm.IPNSRecords[key] = &IPNSRecord{
+		Key:       key,
+		Name:      name.String(),
+		CID:       cidObject.String(),
+		Published: time.Now(),
+	}
+	err = ipfsm._saveIPNSRecords()
+	if err != nil {
+		logger.Manager.Package["ipfs"].Error().Msg(fmt.Sprintf("Could not save the IPNS records: %v", err))
+	}
+
+	return name.String(), nil
+
+}
+
+// Resolve an IPNS name to the CID it points to
+func (ipfsm *PackageManager) ResolveIPNS(name string) (string, error) {
+	var err error
+
+	if ipfsm.IpfsAPI == nil {
+		return "", errors.New("No IPFS node found")
+	}
+
+	// resolve the name
+	ctx, cancel := context.WithTimeout(ipfsm.IpfsContext, RENDERHIVE_CONFIG_IPNS_TIMEOUT)
+	defer cancel()
+	resolved, err := ipfsm.IpfsAPI.Name().Resolve(ctx, name)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Could not resolve IPNS name '%v': %v", name, err))
+	}
+	immutable, err := path.NewImmutablePath(resolved)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("IPNS name '%v' does not point to a CID: %v", name, resolved.String()))
+	}
+
+	return immutable.RootCid().String(), nil
+
+}
+
+// Get the IPNS records published by this node (sorted by key)
+func (ipfsm *PackageManager) GetIPNSRecords() []IPNSRecord {
+
+	ipfsm.ipnsMutex.Lock()
+	defer ipfsm.ipnsMutex.Unlock()
+
+	records := []IPNSRecord{}
+	for _, record := range ipfsm.IPNSRecords {
+		records = append(records, *record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Key < records[j].Key
+	})
+
+	return records
+
+}
+
+// Get the IPNS record, which points to the given CID
+func (ipfsm *PackageManager) GetIPNSRecordByCID(cid string) (IPNSRecord, bool) {
+
+	for _, record := range ipfsm.GetIPNSRecords() {
+		if record.CID == cid {
+			return record, true
+		}
+	}
+
+	return IPNSRecord{}, false
+
+}
+
+// Read the IPNS records published by this node from the app data
+func (ipfsm *PackageManager) LoadIPNSRecords() error {
+
+	data, err := os.ReadFile(filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_IPNS, ipnsRecordsFile))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	records := map[string]*IPNSRecord{}
+	err = json.Unmarshal(data, &records)
+	if err != nil {
+		return err
+	}
+
+	ipfsm.ipnsMutex.Lock()
+	defer ipfsm.ipnsMutex.Unlock()
+	ipfsm.IPNSRecords = records
+
+	return nil
+
+}
+
+// Republish the IPNS records of this node in the background before they expire
+// NOTE: This is called regularly by the background loop of the app, but only
+// republishes once per republish interval and never twice at the same time.
+func (ipfsm *PackageManager) CheckIPNSRepublish() error {
+
+	// republish at most once per interval
+	ipfsm.ipnsMutex.Lock()
+	if ipfsm.republishing || time.Since(ipfsm.lastRepublish) < RENDERHIVE_CONFIG_IPNS_REPUBLISH_INTERVAL {
+		ipfsm.ipnsMutex.Unlock()
+		return nil
+	}
+	ipfsm.republishing = true
+	ipfsm.lastRepublish = time.Now()
+	ipfsm.ipnsMutex.Unlock()
+
+	go func() {
+		err := ipfsm.RepublishIPNS()
+		if err != nil {
+			logger.Manager.Package["ipfs"].Error().Msg(fmt.Sprintf("Error in IPNS republish: %v", err))
+		}
+
+		ipfsm.ipnsMutex.Lock()
+		ipfsm.republishing = false
+		ipfsm.ipnsMutex.Unlock()
+	}()
+
+	return nil
+
+}
+
+// Republish all IPNS records of this node
+func (ipfsm *PackageManager) RepublishIPNS() error {
+	var errs []error
+
+	for _, record := range ipfsm.GetIPNSRecords() {
+		_, err := ipfsm.PublishIPNS(record.CID, record.Key)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+
+}
+
+// Stop republishing the IPNS records of this node (e.g., when it is decommissioned)
+// NOTE: The records expire after their lifetime. Returns the removed records,
+// so that they can be published again.
+func (ipfsm *PackageManager) StopIPNSRepublish() []IPNSRecord {
+
+	records := ipfsm.GetIPNSRecords()
+
+	ipfsm.ipnsMutex.Lock()
+	defer ipfsm.ipnsMutex.Unlock()
+	ipfsm.IPNSRecords = nil
+	err := ipfsm._saveIPNSRecords()
+	if err != nil {
+		logger.Manager.Package["ipfs"].Error().Msg(fmt.Sprintf("Could not save the IPNS records: %v", err))
+	}
+
+	return records
+
+}
+
+// helper function to save the IPNS records of this node to the app data
+// NOTE: The caller must hold the IPNS mutex.
+func (ipfsm *PackageManager) _saveIPNSRecords() error {
+
+	directory := filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_IPNS)
+	err := os.MkdirAll(directory, 0700)
+	if err != nil {
+		return err
+	}
+	records := ipfsm.IPNSRecords
+	if records == nil {
+		records = map[string]*IPNSRecord{}
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// replace the file atomically, so that a crash does not lose the records
+	path := filepath.Join(directory, ipnsRecordsFile)
+	err = os.WriteFile(path+".tmp", data, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+
+}
+
+// helper function to make sure a key of the given name exists in the keystore
+func (ipfsm *PackageManager) _ipnsKey(key string) error {
+
+	if key == "self" {
+		return nil
+	}
+
+	keys, err := ipfsm.IpfsAPI.Key().List(ipfsm.IpfsContext)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Could not list the keys of the IPFS node: %v", err))
+	}
+	for _, k := range keys {
+		if k.Name() == key {
+			return nil
+		}
+	}
+
+	// generate the missing key
+	_, err = ipfsm.IpfsAPI.Key().Generate(ipfsm.IpfsContext, key)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Could not generate IPNS key '%v': %v", key, err))
+	}
+
+	// log debug event
+	logger.Manager.Package["ipfs"].Debug().Msg(fmt.Sprintf("Generated IPNS key '%v'", key))
+
+	return nil
+
+}
+
+// COMMAND LINE INTERFACE - IPNS
+// #############################################################################
+// Create the CLI command to publish and resolve IPNS names
+func (ipfsm *PackageManager) CreateCommandName() *cobra.Command {
+
+	// create a 'name' command for the node
+	command := &cobra.Command{
+		Use:   "name",
+		Short: "Publish and resolve IPNS names",
+		Long:  "This command and its sub-commands publish IPNS records of the local IPFS node and resolve IPNS names to CIDs.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			return nil
+
+		},
+	}
+
+	// add the subcommands
+	command.AddCommand(ipfsm.CreateCommandName_Publish())
+	command.AddCommand(ipfsm.CreateCommandName_Resolve())
+
+	return command
+
+}
+
+// Create the CLI command to publish an IPNS record
+func (ipfsm *PackageManager) CreateCommandName_Publish() *cobra.Command {
+
+	// flags for the 'name publish' command
+	var key string
+
+	// create a 'name publish' command for the node
+	command := &cobra.Command{
+		Use:   "publish <cid>",
+		Short: "Publish an IPNS record pointing to a CID",
+		Long:  "This command publishes an IPNS record of the local IPFS node, which points to the given CID. The record is republished regularly while the node is running.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// publish the record
+			name, err := ipfsm.PublishIPNS(args[0], key)
+			if err != nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not publish IPNS record: %w", err)
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Println("Published IPNS record:")
+			logger.Manager.Resultf(" [#] Name: /ipns/%v\n", name)
+			logger.Manager.Resultf(" [#] CID: %v\n", args[0])
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().StringVarP(&key, "key", "k", "self", "The name of the key the record is signed with (generated, if missing)")
+
+	return command
+
+}
+
+// Create the CLI command to resolve an IPNS name
+func (ipfsm *PackageManager) CreateCommandName_Resolve() *cobra.Command {
+
+	// create a 'name resolve' command for the node
+	command := &cobra.Command{
+		Use:   "resolve <name>",
+		Short: "Resolve an IPNS name to a CID",
+		Long:  "This command resolves an IPNS name to the CID its record points to.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// resolve the name
+			cid, err := ipfsm.ResolveIPNS(args[0])
+			if err != nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not resolve IPNS name: %w", err)
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Println("Resolved IPNS name:")
+			logger.Manager.Resultf(" [#] Name: %v\n", args[0])
+			logger.Manager.Resultf(" [#] CID: %v\n", cid)
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	return command
+
+}
@@ -0,0 +1,96 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+import (
+	// standard
+	"testing"
+	"time"
+
+	// internal
+	"renderhive/logger"
+)
+
+func TestIPNSRecordsPersisted(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// the records of a previous session
+	previous := &PackageManager{IPNSRecords: map[string]*IPNSRecord{
+		"offer": {Key: "offer", Name: "k51offer", CID: "bafyoffer", Published: time.Now()},
+	}}
+	if err := previous._saveIPNSRecords(); err != nil {
+		t.Fatal(err)
+	}
+
+	// are known again after a restart
+	ipfsm := &PackageManager{}
+	if err := ipfsm.LoadIPNSRecords(); err != nil {
+		t.Fatal(err)
+	}
+	record, ok := ipfsm.GetIPNSRecordByCID("bafyoffer")
+	if !ok || record.Key != "offer" || record.Name != "k51offer" {
+		t.Fatalf("got record %+v (%v) after the restart", record, ok)
+	}
+
+	// stopped records are not republished after a restart
+	ipfsm.StopIPNSRepublish()
+	restarted := &PackageManager{}
+	if err := restarted.LoadIPNSRecords(); err != nil {
+		t.Fatal(err)
+	}
+	if records := restarted.GetIPNSRecords(); len(records) != 0 {
+		t.Errorf("got %v records after they were stopped", len(records))
+	}
+}
+
+func TestCheckIPNSRepublishInBackground(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// without an IPFS node, republishing fails in the background
+	ipfsm := &PackageManager{IPNSRecords: map[string]*IPNSRecord{
+		"offer": {Key: "offer", Name: "k51offer", CID: "bafyoffer"},
+	}}
+	if err := ipfsm.CheckIPNSRepublish(); err != nil {
+		t.Fatalf("got %v from the background republish", err)
+	}
+
+	// the next check does not republish again within the interval
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ipfsm.ipnsMutex.Lock()
+		republishing := ipfsm.republishing
+		ipfsm.ipnsMutex.Unlock()
+		if !republishing {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the background republish did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	last := ipfsm.lastRepublish
+	ipfsm.CheckIPNSRepublish()
+	if ipfsm.republishing || !ipfsm.lastRepublish.Equal(last) {
+		t.Errorf("the records were republished again within the interval")
+	}
+}
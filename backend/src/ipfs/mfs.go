@@ -0,0 +1,299 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+/*
+
+This file contains the helpers for the mutable file system (MFS) of the local
+IPFS node.
+
+The MFS is used as a workspace to assemble larger directories (e.g., render
+requests with nested asset folders) step by step. Files and directories are
+written to, copied to, and removed from MFS paths like on a local file system.
+Flushing a workspace writes its directory tree to the local IPFS node and
+returns the CID of the directory.
+
+All MFS paths are absolute (e.g., '/renderhive/requests/1/textures/wood.png').
+The parent directories of written and copied files are created as required.
+
+*/
+
+import (
+
+	// standard
+	"errors"
+	"fmt"
+	gopath "path"
+	"strings"
+
+	// external
+	"github.com/ipfs/boxo/files"
+	"github.com/ipfs/boxo/mfs"
+	"github.com/ipfs/boxo/path"
+	ipld "github.com/ipfs/go-ipld-format"
+	ioptions "github.com/ipfs/kubo/core/coreiface/options"
+
+	// internal
+	"renderhive/logger"
+)
+
+// Information about a file or directory in the MFS
+type FilesStatInfo struct {
+	CID            string // content identifier (CID) of the file/directory
+	Type           string // "file" or "directory"
+	Size           int64  // size of the file data in bytes (0 for directories)
+	CumulativeSize uint64 // size of the file/directory including all blocks
+}
+
+// MUTABLE FILE SYSTEM
+// #############################################################################
+// Create a directory in the MFS (including its parent directories, if 'parents' is set)
+func (ipfsm *PackageManager) FilesMkdir(mfsPath string, parents bool) error {
+	var err error
+
+	root, mfsPath, err := ipfsm._filesRoot(mfsPath)
+	if err != nil {
+		return err
+	}
+
+	err = mfs.Mkdir(root, mfsPath, mfs.MkdirOpts{Mkparents: parents})
+	if err != nil {
+		return errors.New(fmt.Sprintf("Could not create MFS directory '%v': %v", mfsPath, err))
+	}
+
+	return nil
+
+}
+
+// Write a file or directory to the MFS (an existing file is replaced)
+func (ipfsm *PackageManager) FilesWrite(mfsPath string, object files.Node) error {
+	var err error
+
+	root, mfsPath, err := ipfsm._filesRoot(mfsPath)
+	if err != nil {
+		return err
+	}
+
+	// log debug event
+	logger.Manager.Package["ipfs"].Debug().Msg(fmt.Sprintf("Writing to the MFS: %v", mfsPath))
+
+	// add the data to the local node without pinning it (the MFS references it)
+	added, err := ipfsm.IpfsAPI.Unixfs().Add(ipfsm.IpfsContext, throttleNode(object, ipfsm._uploadLimiters()), ioptions.Unixfs.Pin(false))
+	if err != nil {
+		return errors.New(fmt.Sprintf("Could not add data for MFS path '%v': %v", mfsPath, err))
+	}
+	node, err := ipfsm.IpfsAPI.Dag().Get(ipfsm.IpfsContext, added.RootCid())
+	if err != nil {
+		return err
+	}
+
+	return ipfsm._filesPut(root, mfsPath, node)
+
+}
+
+// Copy a file or directory to the MFS
+// NOTE: The source is either an MFS path or an IPFS path (e.g., '/ipfs/<cid>').
+func (ipfsm *PackageManager) FilesCp(source string, mfsPath string) error {
+	var err error
+	var node ipld.Node
+
+	root, mfsPath, err := ipfsm._filesRoot(mfsPath)
+	if err != nil {
+		return err
+	}
+
+	// get the source node
+	if strings.HasPrefix(source, "/ipfs/") {
+		sourcePath, err := path.NewPath(source)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Not a valid IPFS path: %v", source))
+		}
+		node, err = ipfsm.IpfsAPI.ResolveNode(ipfsm.IpfsContext, sourcePath)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Could not resolve '%v': %v", source, err))
+		}
+	} else {
+		fsNode, err := mfs.Lookup(root, gopath.Clean(source))
+		if err != nil {
+			return errors.New(fmt.Sprintf("Could not find MFS path '%v': %v", source, err))
+		}
+		node, err = fsNode.GetNode()
+		if err != nil {
+			return err
+		}
+	}
+
+	return ipfsm._filesPut(root, mfsPath, node)
+
+}
+
+// Get the information about a file or directory in the MFS
+func (ipfsm *PackageManager) FilesStat(mfsPath string) (*FilesStatInfo, error) {
+	var err error
+
+	root, mfsPath, err := ipfsm._filesRoot(mfsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	fsNode, err := mfs.Lookup(root, mfsPath)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Could not find MFS path '%v': %v", mfsPath, err))
+	}
+	node, err := fsNode.GetNode()
+	if err != nil {
+		return nil, err
+	}
+	cumulativeSize, err := node.Size()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &FilesStatInfo{
+		CID:            node.Cid().String(),
+		Type:           "directory",
+		CumulativeSize: cumulativeSize,
+	}
+	if file, ok := fsNode.(*mfs.File); ok {
+		info.Type = "file"
+		info.Size, err = file.Size()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return info, nil
+
+}
+
+// Remove a file or directory from the MFS
+func (ipfsm *PackageManager) FilesRm(mfsPath string) error {
+	var err error
+
+	root, mfsPath, err := ipfsm._filesRoot(mfsPath)
+	if err != nil {
+		return err
+	}
+	if mfsPath == "/" {
+		return errors.New("The MFS root cannot be removed.")
+	}
+
+	parent, err := ipfsm._filesDirectory(root, gopath.Dir(mfsPath))
+	if err != nil {
+		return err
+	}
+	err = parent.Unlink(gopath.Base(mfsPath))
+	if err != nil {
+		return errors.New(fmt.Sprintf("Could not remove MFS path '%v': %v", mfsPath, err))
+	}
+
+	return parent.Flush()
+
+}
+
+// Write the changes of an MFS path to the local IPFS node and get its CID
+func (ipfsm *PackageManager) FilesFlush(mfsPath string) (string, error) {
+	var err error
+
+	root, mfsPath, err := ipfsm._filesRoot(mfsPath)
+	if err != nil {
+		return "", err
+	}
+
+	node, err := mfs.FlushPath(ipfsm.IpfsContext, root, mfsPath)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Could not flush MFS path '%v': %v", mfsPath, err))
+	}
+
+	// log debug event
+	logger.Manager.Package["ipfs"].Debug().Msg(fmt.Sprintf("Flushed the MFS path '%v': %v", mfsPath, node.Cid().String()))
+
+	return node.Cid().String(), nil
+
+}
+
+// helper function to get the MFS root of the local node and the clean MFS path
+func (ipfsm *PackageManager) _filesRoot(mfsPath string) (*mfs.Root, string, error) {
+
+	if ipfsm.IpfsNode == nil || ipfsm.IpfsNode.FilesRoot == nil {
+		return nil, "", errors.New("No IPFS node found")
+	}
+	if !strings.HasPrefix(mfsPath, "/") {
+		return nil, "", errors.New(fmt.Sprintf("MFS path '%v' is not absolute.", mfsPath))
+	}
+
+	return ipfsm.IpfsNode.FilesRoot, gopath.Clean(mfsPath), nil
+
+}
+
+// helper function to get an MFS directory (and create it, if it does not exist)
+func (ipfsm *PackageManager) _filesDirectory(root *mfs.Root, mfsPath string) (*mfs.Directory, error) {
+
+	fsNode, err := mfs.Lookup(root, mfsPath)
+	if err != nil {
+		err = mfs.Mkdir(root, mfsPath, mfs.MkdirOpts{Mkparents: true})
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Could not create MFS directory '%v': %v", mfsPath, err))
+		}
+		fsNode, err = mfs.Lookup(root, mfsPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	directory, ok := fsNode.(*mfs.Directory)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("MFS path '%v' is not a directory.", mfsPath))
+	}
+
+	return directory, nil
+
+}
+
+// helper function to put a node at an MFS path (replacing an existing entry)
+func (ipfsm *PackageManager) _filesPut(root *mfs.Root, mfsPath string, node ipld.Node) error {
+
+	if mfsPath == "/" {
+		return errors.New("The MFS root cannot be replaced.")
+	}
+
+	parent, err := ipfsm._filesDirectory(root, gopath.Dir(mfsPath))
+	if err != nil {
+		return err
+	}
+
+	// replace an existing entry
+	name := gopath.Base(mfsPath)
+	if _, err := parent.Child(name); err == nil {
+		err = parent.Unlink(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = parent.AddChild(name, node)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Could not write MFS path '%v': %v", mfsPath, err))
+	}
+
+	return nil
+
+}
@@ -0,0 +1,176 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+import (
+	// standard
+	"testing"
+
+	// external
+	"github.com/ipfs/boxo/files"
+
+	// internal
+	"renderhive/logger"
+)
+
+// helper function to create a package manager with an MFS of an offline IPFS node
+func _testWorkspaceManager(t *testing.T) *PackageManager {
+	t.Helper()
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	return _testOfflineManager(t)
+}
+
+// helper function to create the files of a render request with nested asset folders
+func _testWorkspaceFiles() map[string]files.Node {
+	return map[string]files.Node{
+		"scene.blend": files.NewBytesFile([]byte("blend")),
+		"textures": files.NewMapDirectory(map[string]files.Node{
+			"wood.png": files.NewBytesFile([]byte("wood")),
+			"stone": files.NewMapDirectory(map[string]files.Node{
+				"granite.png": files.NewBytesFile([]byte("granite")),
+			}),
+		}),
+	}
+}
+
+func TestFilesWorkspaceBuildsNestedDirectories(t *testing.T) {
+	ipfsm := _testWorkspaceManager(t)
+
+	// assemble the workspace file by file
+	if err := ipfsm.FilesMkdir("/renderhive/requests/1/textures", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := ipfsm.FilesWrite("/renderhive/requests/1/scene.blend", files.NewBytesFile([]byte("blend"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := ipfsm.FilesWrite("/renderhive/requests/1/textures/wood.png", files.NewBytesFile([]byte("wood"))); err != nil {
+		t.Fatal(err)
+	}
+
+	// the parent directories of a written file are created
+	if err := ipfsm.FilesWrite("/renderhive/requests/1/textures/stone/granite.png", files.NewBytesFile([]byte("stone"))); err != nil {
+		t.Fatal(err)
+	}
+
+	// an existing file is replaced
+	if err := ipfsm.FilesWrite("/renderhive/requests/1/textures/stone/granite.png", files.NewBytesFile([]byte("granite"))); err != nil {
+		t.Fatal(err)
+	}
+	stat, err := ipfsm.FilesStat("/renderhive/requests/1/textures/stone/granite.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Type != "file" || stat.Size != int64(len("granite")) {
+		t.Errorf("unexpected file information: %+v", stat)
+	}
+
+	// the flushed workspace has the CID of the same directory added at once
+	cid, err := ipfsm.FilesFlush("/renderhive/requests/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	added, err := ipfsm.IpfsAPI.Unixfs().Add(ipfsm.IpfsContext, files.NewMapDirectory(_testWorkspaceFiles()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cid != added.RootCid().String() {
+		t.Errorf("got workspace CID %v, want %v", cid, added.RootCid())
+	}
+	stat, err = ipfsm.FilesStat("/renderhive/requests/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Type != "directory" || stat.CID != cid {
+		t.Errorf("unexpected directory information: %+v", stat)
+	}
+}
+
+func TestFilesCopyAndRemove(t *testing.T) {
+	ipfsm := _testWorkspaceManager(t)
+
+	if err := ipfsm.FilesWrite("/workspace/textures", files.NewMapDirectory(_testWorkspaceFiles())); err != nil {
+		t.Fatal(err)
+	}
+	source, err := ipfsm.FilesStat("/workspace/textures/textures/stone")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// copy from an MFS path and from an IPFS path
+	if err := ipfsm.FilesCp("/workspace/textures/textures/stone", "/copies/mfs/stone"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ipfsm.FilesCp("/ipfs/"+source.CID, "/copies/ipfs/stone"); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/copies/mfs/stone", "/copies/ipfs/stone"} {
+		stat, err := ipfsm.FilesStat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.CID != source.CID {
+			t.Errorf("%v: got CID %v, want %v", path, stat.CID, source.CID)
+		}
+	}
+
+	// a removed path cannot be found anymore
+	if err := ipfsm.FilesRm("/copies/mfs/stone"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ipfsm.FilesStat("/copies/mfs/stone"); err == nil {
+		t.Error("expected the removed path not to be found")
+	}
+	if err := ipfsm.FilesCp("/copies/mfs/stone", "/copies/again"); err == nil {
+		t.Error("expected an error for a missing source")
+	}
+}
+
+func TestFilesRejectsInvalidPaths(t *testing.T) {
+	ipfsm := _testWorkspaceManager(t)
+
+	if err := ipfsm.FilesMkdir("relative/path", true); err == nil {
+		t.Error("expected an error for a relative MFS path")
+	}
+	if err := ipfsm.FilesWrite("/", files.NewBytesFile([]byte("root"))); err == nil {
+		t.Error("expected an error for replacing the MFS root")
+	}
+	if err := ipfsm.FilesRm("/"); err == nil {
+		t.Error("expected an error for removing the MFS root")
+	}
+	if err := ipfsm.FilesMkdir("/a/b", false); err == nil {
+		t.Error("expected an error for a missing parent directory")
+	}
+
+	// a file is not a directory
+	if err := ipfsm.FilesWrite("/file", files.NewBytesFile([]byte("file"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := ipfsm.FilesWrite("/file/nested", files.NewBytesFile([]byte("nested"))); err == nil {
+		t.Error("expected an error for writing into a file")
+	}
+
+	// without an IPFS node
+	if _, err := (&PackageManager{}).FilesStat("/"); err == nil {
+		t.Error("expected an error without an IPFS node")
+	}
+}
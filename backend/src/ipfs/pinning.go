@@ -0,0 +1,243 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+/*
+
+Remote pinning services implementing the IPFS Pinning Service API can be used
+to keep render offer documents, render request files, and render results
+available while the local node is offline. The service is configured in the
+optional 'pinning.json' file of the configuration directory.
+
+*/
+
+import (
+
+	// standard
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+	"renderhive/metrics"
+	. "renderhive/utility"
+)
+
+// REMOTE PINNING SERVICE
+// #############################################################################
+// Configuration of a remote pinning service (IPFS Pinning Service API)
+type RemotePinningService struct {
+	Name        string `json:"name"`
+	Endpoint    string `json:"endpoint"`
+	AccessToken string `json:"access_token"`
+	AutoPin     bool   `json:"auto_pin"`
+}
+
+// Status of a pin request on the remote pinning service
+type RemotePinStatus struct {
+	RequestID string    `json:"requestid"`
+	Status    string    `json:"status"`
+	Created   time.Time `json:"created"`
+	Pin       struct {
+		CID  string `json:"cid"`
+		Name string `json:"name,omitempty"`
+	} `json:"pin"`
+	Delegates []string `json:"delegates"`
+}
+
+// Read the remote pinning service configuration from the configuration file
+func (ipfsm *PackageManager) ReadRemotePinningConfig() error {
+	var err error
+	var service RemotePinningService
+
+	// Open the configuration file
+	file, err := os.Open(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "pinning.json"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Read the file content
+	fileData, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	// get the file content into a structure
+	err = json.Unmarshal(fileData, &service)
+	if err != nil {
+		return err
+	}
+
+	// check the configuration
+	if service.Endpoint == "" {
+		return errors.New("Remote pinning service endpoint is missing.")
+	}
+	if service.AccessToken == "" {
+		return errors.New("Remote pinning service access token is missing.")
+	}
+	service.Endpoint = strings.TrimSuffix(service.Endpoint, "/")
+
+	ipfsm.RemotePinning = &service
+
+	// log event (NOTE: never log the access token)
+	logger.Manager.Package["ipfs"].Info().Msg(fmt.Sprintf(" [#] Remote pinning service: %v (%v)", service.Name, service.Endpoint))
+
+	return err
+
+}
+
+// Pin an object on the remote pinning service
+func (ipfsm *PackageManager) PinObjectRemote(cid string, name string) (*RemotePinStatus, error) {
+
+	status, err := ipfsm._pinObjectRemote(cid, name)
+	metrics.Manager.ObservePin(err == nil)
+
+	return status, err
+
+}
+
+func (ipfsm *PackageManager) _pinObjectRemote(cid string, name string) (*RemotePinStatus, error) {
+	var err error
+	var status RemotePinStatus
+
+	// check if a remote pinning service was configured
+	if ipfsm.RemotePinning == nil {
+		return nil, errors.New("No remote pinning service configured.")
+	}
+
+	// check the CID
+	cidObject, err := ParseCID(cid)
+	if err != nil {
+		return nil, err
+	}
+	cid = cidObject.String()
+
+	// prepare the request body
+	body, err := json.Marshal(map[string]string{"cid": cid, "name": name})
+	if err != nil {
+		return nil, err
+	}
+
+	// send the pin request to the service
+	err = ipfsm._remotePinningRequest(http.MethodPost, "/pins", bytes.NewReader(body), &status)
+	if err != nil {
+		return nil, err
+	}
+
+	// log event
+	logger.Manager.Package["ipfs"].Debug().Msg(fmt.Sprintf("Requested remote pin of '%v' (request: %v, status: %v)", cid, status.RequestID, status.Status))
+
+	return &status, err
+
+}
+
+// Get the status of a pin request from the remote pinning service
+func (ipfsm *PackageManager) GetRemotePinStatus(requestid string) (*RemotePinStatus, error) {
+	var err error
+	var status RemotePinStatus
+
+	// check if a remote pinning service was configured
+	if ipfsm.RemotePinning == nil {
+		return nil, errors.New("No remote pinning service configured.")
+	}
+
+	// query the pin status from the service
+	err = ipfsm._remotePinningRequest(http.MethodGet, "/pins/"+requestid, nil, &status)
+	if err != nil {
+		return nil, err
+	}
+
+	return &status, err
+
+}
+
+// Pin an object on the remote pinning service, if automatic pinning is enabled
+func (ipfsm *PackageManager) AutoPinObjectRemote(cid string, name string) {
+
+	// skip, if no service is configured or automatic pinning is disabled
+	if ipfsm.RemotePinning == nil || !ipfsm.RemotePinning.AutoPin {
+		return
+	}
+
+	// remote pinning is optional and must not interrupt the caller
+	status, err := ipfsm.PinObjectRemote(cid, name)
+	if err != nil {
+		logger.Manager.Package["ipfs"].Error().Msg(fmt.Sprintf("Could not pin '%v' on remote pinning service: %v", cid, err))
+		return
+	}
+
+	logger.Manager.Package["ipfs"].Info().Msg(fmt.Sprintf("Pinned '%v' on remote pinning service (status: %v).", cid, status.Status))
+
+}
+
+// Send an authorized request to the remote pinning service
+func (ipfsm *PackageManager) _remotePinningRequest(method string, path string, body io.Reader, result interface{}) error {
+	var err error
+
+	// create the request
+	request, err := http.NewRequest(method, ipfsm.RemotePinning.Endpoint+path, body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+ipfsm.RemotePinning.AccessToken)
+	request.Header.Set("Content-Type", "application/json")
+
+	// send the request
+	client := &http.Client{Timeout: 30 * time.Second}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	// read the response
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	// check the response status
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		var failure struct {
+			Error struct {
+				Reason  string `json:"reason"`
+				Details string `json:"details"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &failure) == nil && failure.Error.Reason != "" {
+			return errors.New(fmt.Sprintf("Remote pinning service returned '%v': %v", failure.Error.Reason, failure.Error.Details))
+		}
+		return errors.New(fmt.Sprintf("Remote pinning service returned status '%v'.", response.Status))
+	}
+
+	return json.Unmarshal(data, result)
+
+}
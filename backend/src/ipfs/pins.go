@@ -0,0 +1,241 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+/*
+
+This file contains the manual management of the pins of the local IPFS node.
+
+Operators can pin content to keep it available on their node and unpin content
+to free disk space. A pin is either recursive (the object with all its
+children) or direct (only the root block). Objects that are part of a
+recursively pinned directory are pinned indirectly and cannot be unpinned on
+their own.
+
+*/
+
+import (
+
+	// standard
+	"errors"
+	"fmt"
+	"sort"
+
+	// external
+	ioptions "github.com/ipfs/kubo/core/coreiface/options"
+	"github.com/spf13/cobra"
+
+	// internal
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// Object pinned on the local IPFS node
+type PinInfo struct {
+	CID  string // CID of the pinned object
+	Type string // type of the pin ('recursive', 'direct', or 'indirect')
+}
+
+// PINS
+// #############################################################################
+// List the objects pinned on the local IPFS node (sorted by CID)
+// NOTE: The pin type is 'all', 'recursive', 'direct', or 'indirect'.
+func (ipfsm *PackageManager) ListPins(pinType string) ([]PinInfo, error) {
+
+	if ipfsm.IpfsAPI == nil {
+		return nil, errors.New("No IPFS node found")
+	}
+
+	// list all pins by default
+	if pinType == "" {
+		pinType = "all"
+	}
+	typeOption, err := ioptions.Pin.Ls.Type(pinType)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid pin type '%v' (expected 'all', 'recursive', 'direct', or 'indirect').", pinType))
+	}
+
+	pins, err := ipfsm.IpfsAPI.Pin().Ls(ipfsm.IpfsContext, typeOption)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Could not list the pins: %v", err))
+	}
+
+	list := []PinInfo{}
+	for pin := range pins {
+		if pin.Err() != nil {
+			return nil, errors.New(fmt.Sprintf("Could not list the pins: %v", pin.Err()))
+		}
+		list = append(list, PinInfo{CID: pin.Path().RootCid().String(), Type: pin.Type()})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CID < list[j].CID
+	})
+
+	return list, nil
+
+}
+
+// COMMAND LINE INTERFACE - PINS
+// #############################################################################
+// Create the CLI command to pin an object on the local IPFS node
+func (ipfsm *PackageManager) CreateCommandPin_Add() *cobra.Command {
+
+	// flags for the 'pin add' command
+	var recursive bool
+
+	// create a 'pin add' command for the node
+	command := &cobra.Command{
+		Use:   "add <cid>",
+		Short: "Pin an object on the local IPFS node",
+		Long:  "This command pins an object on the local IPFS node, so it stays available and is not removed by the garbage collection. By default, the object is pinned with all its children; with '--recursive=false' only its root block is pinned.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// check the CID
+			cid, err := ParseCID(args[0])
+			if err != nil {
+
+				logger.Manager.Println("")
+				return err
+
+			}
+
+			// pin the object
+			pinned, err := ipfsm.PinObjectWithMode(cid.String(), recursive)
+			if err != nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not pin object with CID '%v': %w", cid.String(), err)
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Println("Pinned object on local IPFS node:")
+			logger.Manager.Resultf(" [#] CID: %v\n", cid.String())
+			logger.Manager.Resultf(" [#] Recursive: %v\n", recursive)
+			logger.Manager.Resultf(" [#] Pinned: %v\n", pinned)
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().BoolVar(&recursive, "recursive", true, "Pin the object with all its children")
+
+	return command
+
+}
+
+// Create the CLI command to unpin an object on the local IPFS node
+func (ipfsm *PackageManager) CreateCommandPin_Rm() *cobra.Command {
+
+	// flags for the 'pin rm' command
+	var recursive bool
+
+	// create a 'pin rm' command for the node
+	command := &cobra.Command{
+		Use:   "rm <cid>",
+		Short: "Unpin an object on the local IPFS node",
+		Long:  "This command removes the pin of an object on the local IPFS node, so its blocks can be removed by the garbage collection. Use '--recursive=false' for directly pinned objects. An object that is part of another pinned object remains pinned indirectly.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// check the CID
+			cid, err := ParseCID(args[0])
+			if err != nil {
+
+				logger.Manager.Println("")
+				return err
+
+			}
+
+			// unpin the object
+			pinned, err := ipfsm.UnPinObjectWithMode(cid.String(), recursive)
+			if err != nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not unpin object with CID '%v': %w", cid.String(), err)
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Println("Unpinned object on local IPFS node:")
+			logger.Manager.Resultf(" [#] CID: %v\n", cid.String())
+			logger.Manager.Resultf(" [#] Pinned: %v\n", pinned)
+			if pinned {
+				logger.Manager.Println(" [#] The object is still pinned indirectly as part of another pinned object.")
+			}
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().BoolVar(&recursive, "recursive", true, "Remove a recursive pin (otherwise a direct pin)")
+
+	return command
+
+}
+
+// Create the CLI command to list the objects pinned on the local IPFS node
+func (ipfsm *PackageManager) CreateCommandPin_Ls() *cobra.Command {
+
+	// flags for the 'pin ls' command
+	var pinType string
+
+	// create a 'pin ls' command for the node
+	command := &cobra.Command{
+		Use:   "ls",
+		Short: "List the objects pinned on the local IPFS node",
+		Long:  "This command lists the objects pinned on the local IPFS node together with their pin type.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// get the pins
+			pins, err := ipfsm.ListPins(pinType)
+			if err != nil {
+
+				logger.Manager.Println("")
+				return err
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Println(fmt.Sprintf("Objects pinned on local IPFS node (%v):", len(pins)))
+			for _, pin := range pins {
+				logger.Manager.Resultf(" [#] %v (%v)\n", pin.CID, pin.Type)
+			}
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().StringVarP(&pinType, "type", "t", "recursive", "The type of the listed pins ('all', 'recursive', 'direct', or 'indirect')")
+
+	return command
+
+}
@@ -0,0 +1,193 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+/*
+
+This file contains the circuit relay support of the local IPFS node.
+
+Many render nodes run behind a NAT or firewall and cannot be dialed directly,
+even if they announce their public addresses. AutoNAT asks other peers to dial
+the node back and determines its reachability:
+
+  - Public: The node is dialable and announces its own addresses.
+  - Private: The node is not dialable. AutoRelay reserves a slot on one or
+    more circuit relays and announces the relay addresses ('/p2p-circuit')
+    instead. Peers connect via the relay and then try to upgrade to a direct
+    connection with hole punching.
+
+The relays are taken from the 'relays' option of 'ipfs.json', the Renderhive
+relays, or (if neither is set) the relays AutoRelay finds in the DHT.
+
+*/
+
+import (
+
+	// standard
+	"fmt"
+	"strings"
+
+	// external
+	"github.com/ipfs/kubo/config"
+	"github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/spf13/cobra"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// CIRCUIT RELAYS
+// #############################################################################
+// helper function to apply the relay options to the repo configuration
+func (nodeConfig *IpfsNodeConfig) _applyRelays(cfg *config.Config) {
+
+	if nodeConfig.AutoRelayDisabled {
+		cfg.Swarm.RelayClient.Enabled = config.False
+		cfg.Swarm.RelayClient.StaticRelays = nil
+		return
+	}
+
+	// use the configured relays or the Renderhive relays
+	// NOTE: Without static relays, AutoRelay finds relays in the DHT.
+	relays := nodeConfig.Relays
+	if len(relays) == 0 {
+		relays = RENDERHIVE_CONFIG_IPFS_RELAYS
+	}
+	cfg.Swarm.RelayClient.Enabled = config.True
+	cfg.Swarm.RelayClient.StaticRelays = relays
+	cfg.Swarm.EnableHolePunching = config.True
+
+}
+
+// Get the reachability of the node observed by AutoNAT
+func (ipfsm *PackageManager) Reachability() network.Reachability {
+
+	return network.Reachability(ipfsm.reachability.Load())
+
+}
+
+// Get the relay addresses the node announces (empty, if it is publicly reachable)
+func (ipfsm *PackageManager) GetRelayAddresses() []string {
+
+	addresses := []string{}
+	if ipfsm.IpfsNode == nil || ipfsm.IpfsNode.PeerHost == nil {
+		return addresses
+	}
+
+	for _, address := range ipfsm.IpfsNode.PeerHost.Addrs() {
+		if strings.Contains(address.String(), "/p2p-circuit") {
+			addresses = append(addresses, address.String())
+		}
+	}
+
+	return addresses
+
+}
+
+// helper function to track the reachability of the node until it is closed
+func (ipfsm *PackageManager) _watchReachability() error {
+
+	if ipfsm.IpfsNode == nil || ipfsm.IpfsNode.PeerHost == nil {
+		return fmt.Errorf("The node is not online.")
+	}
+
+	subscription, err := ipfsm.IpfsNode.PeerHost.EventBus().Subscribe(new(event.EvtLocalReachabilityChanged))
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer subscription.Close()
+
+		for {
+			select {
+			case e, ok := <-subscription.Out():
+				if !ok {
+					return
+				}
+				reachability := e.(event.EvtLocalReachabilityChanged).Reachability
+				ipfsm.reachability.Store(int32(reachability))
+
+				// log event
+				logger.Manager.Package["ipfs"].Info().Msg(fmt.Sprintf(" [#] Reachability of the node changed: %v", reachability))
+				if reachability == network.ReachabilityPrivate && ipfsm.NodeConfig.AutoRelayDisabled {
+					logger.Manager.Package["ipfs"].Warn().Msg(" [#] The node is not publicly reachable and AutoRelay is disabled: Other peers cannot retrieve its content.")
+				}
+
+			case <-ipfsm.IpfsContext.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+
+}
+
+// COMMAND LINE INTERFACE - STATUS
+// #############################################################################
+// Create the CLI command to print the network status of the local IPFS node
+func (ipfsm *PackageManager) CreateCommandStatus() *cobra.Command {
+
+	// create a 'status' command for the node
+	command := &cobra.Command{
+		Use:   "status",
+		Short: "Print the network status of the IPFS node",
+		Long:  "This command prints the peer ID, the number of connected peers, the reachability observed by AutoNAT (public, private, or unknown), and the relay addresses of the local IPFS node.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// check if the node is running
+			if ipfsm.IpfsNode == nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not find the local IPFS node.")
+
+			}
+
+			peers, err := ipfsm.GetConnectedPeers()
+			if err != nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not get the connected peers: %w", err)
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Println("Status of the local IPFS node:")
+			logger.Manager.Resultf(" [#] PeerID: %v\n", ipfsm.IpfsNode.Identity.String())
+			logger.Manager.Resultf(" [#] Connected peers: %v\n", len(peers))
+			logger.Manager.Resultf(" [#] Reachability: %v\n", ipfsm.Reachability())
+			logger.Manager.Resultf(" [#] AutoRelay: %v\n", !ipfsm.NodeConfig.AutoRelayDisabled)
+			for _, address := range ipfsm.GetRelayAddresses() {
+				logger.Manager.Resultf(" [#] Relay address: %v\n", address)
+			}
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	return command
+
+}
@@ -0,0 +1,188 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+/*
+
+This file contains the retry of failed adds to the local IPFS node.
+
+A transient error of the datastore (e.g., a busy or briefly unavailable disk)
+should not fail a whole deployment. Therefore, a failed add is retried a few
+times with an increasing delay. Permanent errors (e.g., a missing file or a
+full disk) are returned immediately.
+
+An add only succeeds, if the root block of the added content can be read
+again from the local blockstore and matches its CID. Otherwise, the node would
+announce content it does not store.
+
+*/
+
+import (
+
+	// standard
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"syscall"
+	"time"
+
+	// external
+	"github.com/ipfs/boxo/files"
+	gocid "github.com/ipfs/go-cid"
+	ioptions "github.com/ipfs/kubo/core/coreiface/options"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// ADD WITH RETRY
+// #############################################################################
+// helper function to add a file/directory to the local IPFS node and retry transient failures
+// NOTE: A node cannot be read twice, so 'open' is called for each attempt.
+func (ipfsm *PackageManager) _addWithRetry(open func() (files.Node, error), pin bool) (string, error) {
+	var lastErr error
+
+	delay := RENDERHIVE_CONFIG_IPFS_ADD_RETRY_DELAY
+	for attempt := 1; attempt <= RENDERHIVE_CONFIG_IPFS_ADD_ATTEMPTS; attempt++ {
+
+		// wait before the next attempt
+		if attempt > 1 {
+			logger.Manager.Package["ipfs"].Warn().Msg(fmt.Sprintf("Adding to the IPFS node failed (attempt %v of %v): %v", attempt-1, RENDERHIVE_CONFIG_IPFS_ADD_ATTEMPTS, lastErr))
+			select {
+			case <-time.After(delay):
+			case <-ipfsm.IpfsContext.Done():
+				return "", lastErr
+			}
+			delay *= 2
+		}
+
+		// get the node to add
+		node, err := open()
+		if err != nil {
+			// the node cannot be read again for a retry
+			if lastErr != nil {
+				return "", lastErr
+			}
+			return "", err
+		}
+
+		cid, err := ipfsm._addVerified(node, pin)
+		if err == nil {
+			return cid, nil
+		}
+		if !_isTransientAddError(err) {
+			return "", err
+		}
+		lastErr = err
+
+	}
+
+	return "", lastErr
+
+}
+
+// helper function to add a file/directory to the local IPFS node and check its root block
+func (ipfsm *PackageManager) _addVerified(node files.Node, pin bool) (string, error) {
+
+	added, err := ipfsm.IpfsAPI.Unixfs().Add(ipfsm.IpfsContext, throttleNode(node, ipfsm._uploadLimiters()), ioptions.Unixfs.Pin(pin))
+	if err != nil {
+		return "", err
+	}
+
+	// read the root block again from the local blockstore
+	cid := added.RootCid()
+	err = ipfsm._checkStored(cid)
+	if err != nil {
+		return "", err
+	}
+
+	return cid.String(), nil
+
+}
+
+// helper function to check if the block of a CID is stored on the local node
+func (ipfsm *PackageManager) _checkStored(cid gocid.Cid) error {
+
+	if ipfsm.IpfsNode == nil || ipfsm.IpfsNode.Blockstore == nil {
+		return errors.New("No IPFS node found")
+	}
+
+	block, err := ipfsm.IpfsNode.Blockstore.Get(ipfsm.IpfsContext, cid)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Root block '%v' is not stored after the add: %v", cid.String(), err))
+	}
+	sum, err := cid.Prefix().Sum(block.RawData())
+	if err != nil || !sum.Equals(cid) {
+		return errors.New(fmt.Sprintf("Root block '%v' does not match its CID after the add.", cid.String()))
+	}
+
+	return nil
+
+}
+
+// helper function to rewind a file/directory node, so it can be added again
+func _rewindNode(node files.Node) error {
+
+	switch n := node.(type) {
+	case files.File:
+		_, err := n.Seek(0, io.SeekStart)
+		return err
+	case files.Directory:
+		entries := n.Entries()
+		for entries.Next() {
+			err := _rewindNode(entries.Node())
+			if err != nil {
+				return err
+			}
+		}
+		return entries.Err()
+	}
+
+	return nil
+
+}
+
+// helper function to check if an add failed due to a transient error
+// NOTE: Bad paths, a full disk, and cancelled adds are permanent errors.
+func _isTransientAddError(err error) bool {
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, fs.ErrPermission), errors.Is(err, fs.ErrInvalid):
+		return false
+	case errors.Is(err, syscall.ENOSPC):
+		return false
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	}
+
+	// the datastore does not wrap all errors of the file system
+	message := strings.ToLower(err.Error())
+	if strings.Contains(message, "no space left on device") || strings.Contains(message, "no such file or directory") {
+		return false
+	}
+
+	return true
+
+}
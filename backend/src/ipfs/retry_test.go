@@ -0,0 +1,150 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+import (
+	// standard
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	// external
+	"github.com/ipfs/boxo/files"
+	gocid "github.com/ipfs/go-cid"
+)
+
+// reader failing with an error
+type failingReader struct {
+	err error
+}
+
+func (fr *failingReader) Read(p []byte) (int, error) {
+	return 0, fr.err
+}
+
+// helper function to get a node to add, which fails with the errors of the first attempts
+func _testFailingAdds(errs ...error) (func() (files.Node, error), *int) {
+	attempts := 0
+	open := func() (files.Node, error) {
+		attempts += 1
+		if attempts <= len(errs) {
+			return files.NewReaderFile(&failingReader{errs[attempts-1]}), nil
+		}
+		return files.NewBytesFile([]byte("blend")), nil
+	}
+
+	return open, &attempts
+}
+
+func TestAddWithRetryRetriesTransientErrors(t *testing.T) {
+	ipfsm := _testWorkspaceManager(t)
+
+	open, attempts := _testFailingAdds(errors.New("resource temporarily unavailable"))
+	cid, err := ipfsm._addWithRetry(open, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *attempts != 2 {
+		t.Errorf("got %v attempts, want 2", *attempts)
+	}
+	want, err := ipfsm.GetHashFromObject(files.NewBytesFile([]byte("blend")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cid != want {
+		t.Errorf("got CID %v, want %v", cid, want)
+	}
+}
+
+func TestAddWithRetryReturnsPermanentErrors(t *testing.T) {
+	ipfsm := _testWorkspaceManager(t)
+
+	for _, permanent := range []error{syscall.ENOSPC, fs.ErrNotExist, context.Canceled} {
+		open, attempts := _testFailingAdds(permanent)
+		if _, err := ipfsm._addWithRetry(open, false); err == nil {
+			t.Errorf("%v: expected the add to fail", permanent)
+		}
+		if *attempts != 1 {
+			t.Errorf("%v: got %v attempts, want 1", permanent, *attempts)
+		}
+	}
+
+	// a node that cannot be opened is not retried
+	attempts := 0
+	_, err := ipfsm._addWithRetry(func() (files.Node, error) {
+		attempts += 1
+		return nil, os.ErrNotExist
+	}, false)
+	if !errors.Is(err, os.ErrNotExist) || attempts != 1 {
+		t.Errorf("got %v after %v attempts, want the error of the first attempt", err, attempts)
+	}
+}
+
+func TestAddWithRetryStopsWithTheNode(t *testing.T) {
+	ipfsm := _testWorkspaceManager(t)
+	ctx, cancel := context.WithCancel(ipfsm.IpfsContext)
+	ipfsm.IpfsContext = ctx
+
+	// the node is stopped while waiting for the next attempt
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	open, attempts := _testFailingAdds(errors.New("resource temporarily unavailable"), errors.New("resource temporarily unavailable"))
+	if _, err := ipfsm._addWithRetry(open, false); err == nil {
+		t.Error("expected the add to fail")
+	}
+	if *attempts != 1 || time.Since(start) > time.Second {
+		t.Errorf("got %v attempts in %v, want 1 attempt", *attempts, time.Since(start))
+	}
+}
+
+func TestIsTransientAddError(t *testing.T) {
+	for err, transient := range map[error]bool{
+		errors.New("resource temporarily unavailable"):            true,
+		errors.New("datastore closed"):                            true,
+		fmt.Errorf("write: %w", syscall.ENOSPC):                   false,
+		errors.New("write /data/blocks: no space left on device"): false,
+		fmt.Errorf("open: %w", fs.ErrNotExist):                    false,
+		errors.New("open scene.blend: no such file or directory"): false,
+		fs.ErrPermission:         false,
+		context.DeadlineExceeded: false,
+	} {
+		if _isTransientAddError(err) != transient {
+			t.Errorf("%v: got transient %v, want %v", err, !transient, transient)
+		}
+	}
+}
+
+func TestCheckStoredRejectsMissingBlocks(t *testing.T) {
+	ipfsm := _testWorkspaceManager(t)
+
+	cid, err := gocid.Decode("bafkreieehljcbl5lnncnyryvfwd5hm7xsukz45kdhynlgkj7yibbs2v37y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ipfsm._checkStored(cid); err == nil {
+		t.Error("expected an error for a block that is not stored")
+	}
+}
@@ -48,6 +48,8 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	// external
@@ -55,7 +57,6 @@ import (
 	"github.com/ipfs/boxo/path"
 	gocid "github.com/ipfs/go-cid"
 	"github.com/ipfs/kubo/commands"
-	"github.com/ipfs/kubo/config"
 	"github.com/ipfs/kubo/core"
 	"github.com/ipfs/kubo/core/coreapi"
 	"github.com/ipfs/kubo/core/corehttp"
@@ -73,6 +74,7 @@ import (
 	// internal
 	. "renderhive/globals"
 	"renderhive/logger"
+	"renderhive/metrics"
 	. "renderhive/utility"
 )
 
@@ -87,10 +89,27 @@ type PackageManager struct {
 	IpfsNode          *core.IpfsNode
 	IpfsAPI           icore.CoreAPI
 	IpfsPlugins       *loader.PluginLoader
+	NodeConfig        IpfsNodeConfig
+
+	// Bandwidth limits shared by all add and get operations
+	UploadLimiter   *bandwidthLimiter
+	DownloadLimiter *bandwidthLimiter
 
 	// w3up service
 	W3Agent w3cliAgent
 
+	// Remote pinning service
+	RemotePinning *RemotePinningService
+
+	// Reachability of the node observed by AutoNAT
+	reachability atomic.Int32
+
+	// IPNS records published by this node (by key name)
+	IPNSRecords   map[string]*IPNSRecord
+	ipnsMutex     sync.Mutex
+	lastRepublish time.Time
+	republishing  bool // true, while the records are republished in the background
+
 	// Command line interface
 	Command      *cobra.Command
 	CommandFlags struct {
@@ -116,6 +135,19 @@ func (ipfsm *PackageManager) Init() error {
 		logger.Manager.Package["ipfs"].Error().Msg(err.Error())
 	}
 
+	// Read the IPNS records published before the restart
+	err = ipfsm.LoadIPNSRecords()
+	if err != nil {
+		logger.Manager.Package["ipfs"].Error().Msg(fmt.Sprintf("Could not load the IPNS records: %v", err))
+	}
+
+	// Read the (optional) remote pinning service configuration
+	err = ipfsm.ReadRemotePinningConfig()
+	if err != nil && !os.IsNotExist(err) {
+		logger.Manager.Package["ipfs"].Error().Msg(err.Error())
+		logger.Manager.Package["ipfs"].Error().Msg("Continue without remote pinning service.")
+	}
+
 	// Initialize w3 CLI command
 	ipfsm.W3Agent.Path = "w3"
 
@@ -186,7 +218,7 @@ func (ipfsm *PackageManager) StartLocalNode() (*core.IpfsNode, error) {
 	// IPFS Repository
 	// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 	// create a local repository path, if it does not exist
-	ipfsm.IpfsRepoPath = filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_IPFS_REPO)
+	ipfsm.IpfsRepoPath = RepoPath()
 	if _, err := os.Stat(ipfsm.IpfsRepoPath); os.IsNotExist(err) {
 
 		err = os.MkdirAll(ipfsm.IpfsRepoPath, 0700)
@@ -196,6 +228,14 @@ func (ipfsm *PackageManager) StartLocalNode() (*core.IpfsNode, error) {
 
 	}
 
+	// IPFS Node Configuration
+	// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
+	// Read the (optional) configuration of the local IPFS node
+	err = ipfsm.ReadNodeConfig()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.New(fmt.Sprintf("Invalid IPFS node configuration: %v", err.Error()))
+	}
+
 	// IPFS Plugins
 	// +++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++++
 	// Load any external plugins if available on externalPluginsPath
@@ -219,22 +259,12 @@ func (ipfsm *PackageManager) StartLocalNode() (*core.IpfsNode, error) {
 	ipfsm.IpfsRepo, err = fsrepo.Open(ipfsm.IpfsRepoPath)
 	if err != nil {
 
-		// Create a config with default options and a 2048 bit key
-		cfg, err := config.Init(io.Discard, 2048)
+		// Create a config with default options, a 2048 bit key, and the configured datastore
+		cfg, err := ipfsm.NodeConfig.InitRepoConfig()
 		if err != nil {
 			return nil, errors.New(fmt.Sprintf("Could not init IPFS repo configuration: %v", err.Error()))
 		}
 
-		// Enable experimental features
-		// https://github.com/ipfs/kubo/blob/master/docs/experimental-features.md#ipfs-filestore
-		cfg.Experimental.FilestoreEnabled = false
-		// https://github.com/ipfs/kubo/blob/master/docs/experimental-features.md#ipfs-urlstore
-		cfg.Experimental.UrlstoreEnabled = false
-		// https://github.com/ipfs/kubo/blob/master/docs/experimental-features.md#ipfs-p2p
-		cfg.Experimental.Libp2pStreamMounting = false
-		// https://github.com/ipfs/kubo/blob/master/docs/experimental-features.md#p2p-http-proxy
-		cfg.Experimental.P2pHttpProxy = false
-
 		// Create the repo with the defined configuration
 		err = fsrepo.Init(ipfsm.IpfsRepoPath, cfg)
 		if err != nil {
@@ -248,7 +278,7 @@ func (ipfsm *PackageManager) StartLocalNode() (*core.IpfsNode, error) {
 		}
 
 		// log debug event
-		logger.Manager.Package["ipfs"].Info().Msg(fmt.Sprintf(" [#] Created a new local IPFS repo in '%v'", ipfsm.IpfsRepoPath))
+		logger.Manager.Package["ipfs"].Info().Msg(fmt.Sprintf(" [#] Created a new local IPFS repo in '%v' (datastore: %v)", ipfsm.IpfsRepoPath, ipfsm.NodeConfig.DatastoreBackend()))
 
 	}
 
@@ -263,41 +293,18 @@ func (ipfsm *PackageManager) StartLocalNode() (*core.IpfsNode, error) {
 		return nil, err
 	}
 
-	// empty the append announce addresses
-	cfg.Addresses.AppendAnnounce = []string{}
+	// apply the experimental features and resource limits
+	ipfsm.NodeConfig.CheckDatastore(cfg)
+	ipfsm.NodeConfig.Apply(cfg)
 
-	// get the public IPv4 and add it to the announced addresses
-	ipv4, err := GetPublicIPv4()
-	if err != nil {
-		return nil, err
+	// enable the bandwidth metrics (required for the bandwidth statistics)
+	if cfg.Swarm.DisableBandwidthMetrics {
+		logger.Manager.Package["ipfs"].Info().Msg(" [#] Enabled the bandwidth metrics of the IPFS node.")
+		cfg.Swarm.DisableBandwidthMetrics = false
 	}
-	if ipv4 != "" {
 
-		// add the multiaddr with the public IP to the configuration
-		cfg.Addresses.AppendAnnounce = append(cfg.Addresses.AppendAnnounce, fmt.Sprintf("/ip4/%v/tcp/4001", ipv4))
-		cfg.Addresses.AppendAnnounce = append(cfg.Addresses.AppendAnnounce, fmt.Sprintf("/ip4/%v/udp/4001/quic", ipv4))
-		cfg.Addresses.AppendAnnounce = append(cfg.Addresses.AppendAnnounce, fmt.Sprintf("/ip4/%v/udp/4001/quic-v1", ipv4))
-		cfg.Addresses.AppendAnnounce = append(cfg.Addresses.AppendAnnounce, fmt.Sprintf("/ip4/%v/udp/4001/quic-v1/webtransport", ipv4))
-
-		logger.Manager.Package["ipfs"].Info().Msg(fmt.Sprintf(" [#] Queried IPv4 address: %v", ipv4))
-	}
-
-	// get the public IPv6 and add it to the announced addresses
-	ipv6, err := GetPublicIPv6()
-	if err != nil {
-		return nil, err
-	}
-	if ipv6 != "" && ipv6 != ipv4 {
-
-		// add the multiaddr with the public IP to the configuration
-		cfg.Addresses.AppendAnnounce = append(cfg.Addresses.AppendAnnounce, fmt.Sprintf("/ip6/%v/tcp/4001", ipv6))
-		cfg.Addresses.AppendAnnounce = append(cfg.Addresses.AppendAnnounce, fmt.Sprintf("/ip6/%v/udp/4001/quic", ipv6))
-		cfg.Addresses.AppendAnnounce = append(cfg.Addresses.AppendAnnounce, fmt.Sprintf("/ip6/%v/udp/4001/quic-v1", ipv6))
-		cfg.Addresses.AppendAnnounce = append(cfg.Addresses.AppendAnnounce, fmt.Sprintf("/ip6/%v/udp/4001/quic-v1/webtransport", ipv6))
-
-		logger.Manager.Package["ipfs"].Info().Msg(fmt.Sprintf(" [#] Queried IPv6 address: %v", ipv6))
-
-	}
+	// announce the public IP addresses of this node
+	cfg.Addresses.AppendAnnounce = ipfsm.NodeConfig.AnnounceAddresses()
 
 	// Save the updated configuration back to the repo
 	if err := ipfsm.IpfsRepo.SetConfig(cfg); err != nil {
@@ -326,6 +333,27 @@ func (ipfsm *PackageManager) StartLocalNode() (*core.IpfsNode, error) {
 		return nil, err
 	}
 
+	// export the bandwidth statistics on the metrics endpoint
+	metrics.Manager.SetIpfsBandwidthSource(func() (int64, int64, float64, float64) {
+		stats, err := ipfsm.BandwidthStats()
+		if err != nil {
+			return 0, 0, 0, 0
+		}
+		return stats.TotalIn, stats.TotalOut, stats.RateIn, stats.RateOut
+	})
+
+	// check if the configured connection limits reached the node
+	err = ipfsm.CheckConnectionLimits()
+	if err != nil {
+		logger.Manager.Package["ipfs"].Warn().Msg(fmt.Sprintf(" [#] %v", err))
+	}
+
+	// track the reachability of the node
+	err = ipfsm._watchReachability()
+	if err != nil {
+		logger.Manager.Package["ipfs"].Warn().Msg(fmt.Sprintf(" [#] Could not track the reachability of the node: %v", err))
+	}
+
 	// log debug event
 	logger.Manager.Package["ipfs"].Info().Msg(fmt.Sprintf(" [#] Initialized local node in '%v'", ipfsm.IpfsRepoPath))
 	logger.Manager.Package["ipfs"].Info().Msg(fmt.Sprintf(" [#] PeerID: %v", ipfsm.IpfsNode.Identity.String()))
@@ -336,6 +364,7 @@ func (ipfsm *PackageManager) StartLocalNode() (*core.IpfsNode, error) {
 		// wait until the node is connected to a minimum amount of peers or the timeout
 		// passed
 		start := time.Now()
+		minPeers := ipfsm._bootstrapPeers()
 		for {
 
 			// get peer connections
@@ -347,7 +376,7 @@ func (ipfsm *PackageManager) StartLocalNode() (*core.IpfsNode, error) {
 			// check if the node is connected to a minimum amount of peers
 			if len(peers) == 0 && time.Now().Sub(start) > 10*time.Second {
 				return nil, errors.New(fmt.Sprintf(" [#] Failed to bootstrap (no peers found)"))
-			} else if len(peers) >= 4 {
+			} else if len(peers) >= minPeers {
 				logger.Manager.Package["ipfs"].Info().Msg(fmt.Sprintf(" [#] IPFS node is now connected to %v peers", len(peers)))
 
 				// Test pinning and writing file to disk
@@ -482,11 +511,19 @@ func (ipfsm *PackageManager) GetHashFromObject(object files.Node) (string, error
 func (ipfsm *PackageManager) AddObject(object files.Node, pin bool) (string, error) {
 	var err error
 
-	cid, err := ipfsm.IpfsAPI.Unixfs().Add(ipfsm.IpfsContext, object, ioptions.Unixfs.Pin(pin))
+	// rewind the node for each retry
+	attempts := 0
+	cid, err := ipfsm._addWithRetry(func() (files.Node, error) {
+		attempts++
+		if attempts > 1 {
+			return object, _rewindNode(object)
+		}
+		return object, nil
+	}, pin)
 	if err != nil {
 		return "", errors.New(fmt.Sprintf("Failed to put file/directory on the IPFS node: %v", err.Error()))
 	}
-	return cid.RootCid().String(), nil
+	return cid, nil
 
 }
 
@@ -500,33 +537,30 @@ func (ipfsm *PackageManager) AddObjectFromPath(path string, pin bool) (string, e
 		return "", err
 	}
 
-	file, err := files.NewSerialFile(path, false, stat)
-	if err != nil {
-		return "", err
-	}
-
-	cid, err := ipfsm.IpfsAPI.Unixfs().Add(ipfsm.IpfsContext, file, ioptions.Unixfs.Pin(pin))
+	// open the local file again for each attempt
+	cid, err := ipfsm._addWithRetry(func() (files.Node, error) {
+		return files.NewSerialFile(path, false, stat)
+	}, pin)
 	if err != nil {
 		return "", errors.New(fmt.Sprintf("Failed to put file on the IPFS node: %v", err.Error()))
 	}
 
-	return cid.RootCid().String(), nil
+	return cid, nil
 
 }
 
 // Add a file from a byte array of the file data to the local IPFS node
 func (ipfsm *PackageManager) AddObjectFromBytes(data []byte, pin bool) (string, error) {
 
-	// Create a File from the byte array
-	file := files.NewBytesFile(data)
-
-	// Add the File to IPFS
-	cid, err := ipfsm.IpfsAPI.Unixfs().Add(ipfsm.IpfsContext, file, ioptions.Unixfs.Pin(pin))
+	// Add a File created from the byte array to IPFS
+	cid, err := ipfsm._addWithRetry(func() (files.Node, error) {
+		return files.NewBytesFile(data), nil
+	}, pin)
 	if err != nil {
 		return "", fmt.Errorf("Failed to put data on the IPFS node: %v", err)
 	}
 
-	return cid.RootCid().String(), nil
+	return cid, nil
 }
 
 // Create and add a directory on the local IPFS node based on a map of files
@@ -536,24 +570,39 @@ func (ipfsm *PackageManager) AddDirectoryFromFiles(fileMap map[string]files.Node
 	// create a local directory mapping from the file list
 	dirObject := files.NewMapDirectory(fileMap)
 
-	// add the directory to IPFS
-	cid, err := ipfsm.IpfsAPI.Unixfs().Add(ipfsm.IpfsContext, dirObject, ioptions.Unixfs.Pin(pin))
+	// add the directory to IPFS (and rewind its files for each retry)
+	attempts := 0
+	cid, err := ipfsm._addWithRetry(func() (files.Node, error) {
+		attempts++
+		if attempts > 1 {
+			return dirObject, _rewindNode(dirObject)
+		}
+		return dirObject, nil
+	}, pin)
 	if err != nil {
 		return "", errors.New(fmt.Sprintf("Failed to put directory on the IPFS node: %v", err.Error()))
 	}
 
 	// return the CID of the directory
-	return cid.RootCid().String(), nil
+	return cid, nil
 }
 
 // Get a file/directory from IPFS and write it to a local path
 func (ipfsm *PackageManager) GetObject(cid_string string, outputPath string) (string, error) {
+
+	return ipfsm.GetObjectContext(ipfsm.IpfsContext, cid_string, outputPath)
+
+}
+
+// Get a file/directory from IPFS and write it to a local path, and give up,
+// when the context is canceled
+func (ipfsm *PackageManager) GetObjectContext(ctx context.Context, cid_string string, outputPath string) (string, error) {
 	var err error
 
 	// get a CID object from the string
-	cidObject, err := gocid.Parse(cid_string)
+	cidObject, err := ParseCID(cid_string)
 	if err != nil {
-		return "", errors.New(fmt.Sprintf("Not a valid CID string: %s", cid_string))
+		return "", err
 	}
 
 	// get a path object from the CID object
@@ -563,7 +612,7 @@ func (ipfsm *PackageManager) GetObject(cid_string string, outputPath string) (st
 	logger.Manager.Package["ipfs"].Debug().Msg(fmt.Sprintf("Downloading a new object from IPFS: %v", cidPath.String()))
 
 	// try to retrieve the file/directory
-	rootNode, err := ipfsm.IpfsAPI.Unixfs().Get(ipfsm.IpfsContext, cidPath)
+	rootNode, err := ipfsm.IpfsAPI.Unixfs().Get(ctx, cidPath)
 	if err != nil {
 		return "", errors.New(fmt.Sprintf("Could not get file with CID: %s", err))
 	}
@@ -571,7 +620,15 @@ func (ipfsm *PackageManager) GetObject(cid_string string, outputPath string) (st
 	// log info event
 	logger.Manager.Package["ipfs"].Debug().Msg(fmt.Sprintf(" [#] Finished and obtained rootNode: %v", rootNode))
 
-	err = files.WriteTo(rootNode, outputPath)
+	// do not write entries, which could escape the output path
+	if _, ok := rootNode.(*files.Symlink); ok {
+		return "", fmt.Errorf("Could not write out the fetched CID: %w: The object is a symbolic link.", ErrUnsafePath)
+	}
+	err = files.WriteTo(throttleNode(confineNode(rootNode), ipfsm._downloadLimiters()), outputPath)
+	if errors.Is(err, ErrUnsafePath) {
+		os.RemoveAll(outputPath)
+		return "", fmt.Errorf("Could not write out the fetched CID: %w", err)
+	}
 	if err != nil {
 		return "", errors.New(fmt.Sprintf("Could not write out the fetched CID: %s", err))
 	}
@@ -580,26 +637,85 @@ func (ipfsm *PackageManager) GetObject(cid_string string, outputPath string) (st
 
 }
 
+// Get the size of a file/directory on IPFS without downloading its content
+// NOTE: Only the root node is fetched. Gives up, when the context is canceled.
+func (ipfsm *PackageManager) GetObjectSize(ctx context.Context, cid_string string) (int64, error) {
+
+	if ipfsm.IpfsAPI == nil {
+		return 0, errors.New("The IPFS node is not running.")
+	}
+
+	// get a CID object from the string
+	cidObject, err := ParseCID(cid_string)
+	if err != nil {
+		return 0, err
+	}
+
+	// get the root node of the file/directory
+	rootNode, err := ipfsm.IpfsAPI.Unixfs().Get(ctx, path.FromCid(cidObject))
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Could not get file with CID: %s", err))
+	}
+	defer rootNode.Close()
+
+	return rootNode.Size()
+
+}
+
 // Pin a file based on the CID on the local IPFS node
 func (ipfsm *PackageManager) PinObject(cid_string string) (bool, error) {
-	var err error
 
-	// only if a CID was passed
-	if cid_string == "" {
-		return false, errors.New(fmt.Sprintf("Could not pin IPFS object '%v': Not a valid CID.", cid_string))
-	}
+	return ipfsm.PinObjectWithMode(cid_string, true)
+
+}
+
+// Pin a file based on the CID on the local IPFS node, either with all its
+// children (recursive) or only its root block (direct)
+func (ipfsm *PackageManager) PinObjectWithMode(cid_string string, recursive bool) (bool, error) {
+
+	// pin the object and record the result
+	pinned, err := ipfsm._pinObject(ipfsm.IpfsContext, cid_string, recursive)
+	metrics.Manager.ObservePin(err == nil)
+
+	return pinned, err
+
+}
+
+// Pin a file based on the CID on the local IPFS node (with all its children)
+// and give up, when the context is canceled
+func (ipfsm *PackageManager) PinObjectContext(ctx context.Context, cid_string string) (bool, error) {
+
+	return ipfsm.PinObjectWithModeContext(ctx, cid_string, true)
+
+}
+
+// Pin a file based on the CID on the local IPFS node, either recursive or
+// direct, and give up, when the context is canceled
+func (ipfsm *PackageManager) PinObjectWithModeContext(ctx context.Context, cid_string string, recursive bool) (bool, error) {
+
+	// pin the object and record the result
+	pinned, err := ipfsm._pinObject(ctx, cid_string, recursive)
+	metrics.Manager.ObservePin(err == nil)
+
+	return pinned, err
+
+}
+
+// helper function to pin a file based on the CID on the local IPFS node
+func (ipfsm *PackageManager) _pinObject(ctx context.Context, cid_string string, recursive bool) (bool, error) {
+	var err error
 
 	// get a CID object from the string
-	cidObject, err := gocid.Parse(cid_string)
+	cidObject, err := ParseCID(cid_string)
 	if err != nil {
-		return false, errors.New(fmt.Sprintf("Not a valid CID string: %s", cid_string))
+		return false, err
 	}
 
 	// get a path object from the CID object
 	ipfsPath := path.FromCid(cidObject)
 
 	// test, if file is already pinned
-	_, pinned, err := ipfsm.IpfsAPI.Pin().IsPinned(ipfsm.IpfsContext, ipfsPath)
+	_, pinned, err := ipfsm.IpfsAPI.Pin().IsPinned(ctx, ipfsPath)
 	if err != nil {
 		logger.Manager.Package["ipfs"].Trace().Msg(fmt.Sprintf("Could not pin IPFS object '%v': %v", cid_string, err.Error()))
 		return false, errors.New(fmt.Sprintf("Could not pin '%v': %s", ipfsPath, err))
@@ -609,21 +725,21 @@ func (ipfsm *PackageManager) PinObject(cid_string string) (bool, error) {
 	if !pinned {
 
 		// Check if object is advertised in the DHT (i.e., if at least one provider exists)
-		_, err := ipfsm.IpfsAPI.Dht().FindProviders(context.Background(), ipfsPath, ioptions.Dht.NumProviders(1))
+		_, err := ipfsm.IpfsAPI.Dht().FindProviders(ctx, ipfsPath, ioptions.Dht.NumProviders(1))
 		if err != nil {
 			logger.Manager.Package["ipfs"].Trace().Msg(fmt.Sprintf("Could not pin IPFS object '%v': %v", cid_string, err.Error()))
 			return false, errors.New(fmt.Sprintf("The file '%v' is not advertised in the DHT yet.", cid_string))
 		}
 
 		// pin the file
-		err = ipfsm.IpfsAPI.Pin().Add(ipfsm.IpfsContext, ipfsPath)
+		err = ipfsm.IpfsAPI.Pin().Add(ctx, ipfsPath, ioptions.Pin.Recursive(recursive))
 		if err != nil {
 			logger.Manager.Package["ipfs"].Trace().Msg(fmt.Sprintf("Could not pin IPFS object '%v': %v", ipfsPath, err.Error()))
 			return false, errors.New(fmt.Sprintf("Could not pin '%v': %s", ipfsPath, err))
 		}
 
 		// test, if file is now pinned
-		_, pinned, err = ipfsm.IpfsAPI.Pin().IsPinned(ipfsm.IpfsContext, ipfsPath)
+		_, pinned, err = ipfsm.IpfsAPI.Pin().IsPinned(ctx, ipfsPath)
 		if err != nil {
 			logger.Manager.Package["ipfs"].Trace().Msg(fmt.Sprintf("Could not pin IPFS object '%v': %v", ipfsPath, err.Error()))
 			return false, errors.New(fmt.Sprintf("Could not pin '%v': %s", ipfsPath, err))
@@ -641,21 +757,122 @@ func (ipfsm *PackageManager) PinObject(cid_string string) (bool, error) {
 
 }
 
+// Result of a probe of the DHT providers of an object
+type ProviderProbe struct {
+	Providers int   // number of providers found (without this node)
+	Err       error // error of the probe (e.g., if no provider was found)
+}
+
+// Announce an object to the DHT and wait until it has at least one provider
+// NOTE: Returns the number of providers found until the timeout expired. This
+// node is not counted, since it always provides its own objects.
+func (ipfsm *PackageManager) ProbeProviders(cid_string string, timeout time.Duration) (int, error) {
+	var err error
+	var count int
+
+	// get a CID object from the string
+	cidObject, err := ParseCID(cid_string)
+	if err != nil {
+		return 0, err
+	}
+
+	// get a path object from the CID object
+	ipfsPath := path.FromCid(cidObject)
+
+	// announce the object to the DHT
+	ctx, cancel := context.WithTimeout(ipfsm.IpfsContext, timeout)
+	defer cancel()
+	err = ipfsm.IpfsAPI.Dht().Provide(ctx, ipfsPath)
+	if err != nil {
+		logger.Manager.Package["ipfs"].Trace().Msg(fmt.Sprintf("Could not announce IPFS object '%v': %v", cid_string, err.Error()))
+	}
+
+	// search for the providers of the object
+	providers, err := ipfsm.IpfsAPI.Dht().FindProviders(ctx, ipfsPath, ioptions.Dht.NumProviders(20))
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Could not find providers of '%v': %v", cid_string, err))
+	}
+
+	// count the providers of the network
+	count = _countProviders(providers, ipfsm.IpfsNode.Identity, RENDERHIVE_CONFIG_DEPLOY_PROVIDER_GRACE_PERIOD)
+
+	// log event
+	logger.Manager.Package["ipfs"].Debug().Msg(fmt.Sprintf("Found %v provider(s) of IPFS object '%v'.", count, cid_string))
+
+	// if no provider was found
+	if count == 0 {
+		return 0, errors.New(fmt.Sprintf("The object '%v' has no provider in the DHT yet.", cid_string))
+	}
+
+	return count, nil
+
+}
+
+// Probe the DHT providers of an object in the background (see ProbeProviders)
+// NOTE: The channel receives exactly one result.
+func (ipfsm *PackageManager) ProbeProvidersAsync(cid_string string, timeout time.Duration) <-chan ProviderProbe {
+
+	result := make(chan ProviderProbe, 1)
+	go func() {
+		count, err := ipfsm.ProbeProviders(cid_string, timeout)
+		result <- ProviderProbe{Providers: count, Err: err}
+	}()
+
+	return result
+
+}
+
+// helper function to count the providers other than this node until the
+// channel is closed or the grace period after the first provider expired
+func _countProviders(providers <-chan peer.AddrInfo, self peer.ID, gracePeriod time.Duration) int {
+	var count int
+
+	var grace <-chan time.Time
+	for {
+		select {
+		case provider, ok := <-providers:
+			if !ok {
+				return count
+			}
+			if provider.ID == self {
+				continue
+			}
+			count += 1
+			if count == 1 {
+				grace = time.After(gracePeriod)
+			}
+		case <-grace:
+			return count
+		}
+	}
+
+}
+
 // Unpin a file based on the CID on the local IPFS node
 func (ipfsm *PackageManager) UnPinObject(cid_string string) (bool, error) {
+
+	return ipfsm.UnPinObjectWithMode(cid_string, true)
+
+}
+
+// Unpin a file based on the CID on the local IPFS node, which was pinned
+// recursively or directly
+// NOTE: Returns true, if the file is still pinned afterwards (e.g., indirectly
+// as part of another pinned directory).
+func (ipfsm *PackageManager) UnPinObjectWithMode(cid_string string, recursive bool) (bool, error) {
 	var err error
 
 	// get a CID object from the string
-	cidObject, err := gocid.Parse(cid_string)
+	cidObject, err := ParseCID(cid_string)
 	if err != nil {
-		return false, errors.New(fmt.Sprintf("Not a valid CID string: %s", cid_string))
+		return false, err
 	}
 
 	// get a path object from the CID object
 	ipfsPath := path.FromCid(cidObject)
 
 	// unpin the file
-	err = ipfsm.IpfsAPI.Pin().Rm(ipfsm.IpfsContext, ipfsPath)
+	err = ipfsm.IpfsAPI.Pin().Rm(ipfsm.IpfsContext, ipfsPath, ioptions.Pin.RmRecursive(recursive))
 	if err != nil {
 		return false, errors.New(fmt.Sprintf("Could not unpin '%v': %s", ipfsPath, err))
 	}
@@ -722,6 +939,14 @@ func (pr *progressReader) Read(p []byte) (n int, err error) {
 	return n, err
 }
 func (ipfsm *PackageManager) DownloadFromGateway(cid string, outputPath string, progress chan<- float64) error {
+
+	// the subdomain of the gateway requires a CIDv1
+	cidObject, err := ParseCID(cid)
+	if err != nil {
+		return err
+	}
+	cid = gocid.NewCidV1(cidObject.Type(), cidObject.Hash()).String()
+
 	resp, err := http.Get("https://" + cid + ".ipfs.w3s.link")
 	if err != nil {
 		return err
@@ -757,19 +982,23 @@ func (ipfsm *PackageManager) CreateCommand() *cobra.Command {
 		Use:   "ipfs",
 		Short: "Commands for the interaction with the IPFS and Filecoin services",
 		Long:  "This command and its sub-commands enable the interaction with the IPFS and Filecoin services required by the Renderhive network",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
-			return
+			return nil
 
 		},
 	}
 
 	// add the subcommands (IPFS)
 	ipfsm.Command.AddCommand(ipfsm.CreateCommandInfo())
+	ipfsm.Command.AddCommand(ipfsm.CreateCommandStatus())
+	ipfsm.Command.AddCommand(ipfsm.CreateCommandBandwidth())
 	ipfsm.Command.AddCommand(ipfsm.CreateCommandSwarm())
 	ipfsm.Command.AddCommand(ipfsm.CreateCommandAdd())
+	ipfsm.Command.AddCommand(ipfsm.CreateCommandImport())
 	ipfsm.Command.AddCommand(ipfsm.CreateCommandGet())
 	ipfsm.Command.AddCommand(ipfsm.CreateCommandPin())
+	ipfsm.Command.AddCommand(ipfsm.CreateCommandName())
 
 	// add the subcommands (Filecoin / w3up service)
 	ipfsm.Command.AddCommand(ipfsm.CreateCommandW3())
@@ -789,7 +1018,7 @@ func (ipfsm *PackageManager) CreateCommandInfo() *cobra.Command {
 		Use:   "info",
 		Short: "Print information about the IPFS repo",
 		Long:  "This command prints the configuration of the IPFS repo.",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
 			// check if the repo is initialized
 			if ipfsm.IpfsRepo != nil {
@@ -797,33 +1026,30 @@ func (ipfsm *PackageManager) CreateCommandInfo() *cobra.Command {
 				cfg, err := ipfsm.IpfsRepo.Config()
 				if err != nil {
 
-					fmt.Println("")
-					fmt.Println(fmt.Errorf("Could retrieve configuration of the repo."))
-					fmt.Println("")
+					logger.Manager.Println("")
+					return fmt.Errorf("Could not retrieve the configuration of the repo: %w", err)
 
 				}
 
 				// convert to JSON string
 				jsonString, err := json.MarshalIndent(cfg, "", "\t")
 				if err != nil {
-					fmt.Println(err)
-					return
+					return err
 				}
 
 				// print the configuration
-				fmt.Println("")
-				fmt.Println(string(jsonString))
-				fmt.Println("")
+				logger.Manager.Println("")
+				logger.Manager.Resultln(string(jsonString))
+				logger.Manager.Println("")
 
 			} else {
 
-				fmt.Println("")
-				fmt.Println(fmt.Errorf("Could not find repo."))
-				fmt.Println("")
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not find repo.")
 
 			}
 
-			return
+			return nil
 
 		},
 	}
@@ -843,18 +1069,17 @@ func (ipfsm *PackageManager) CreateCommandSwarm() *cobra.Command {
 		Use:   "swarm",
 		Short: "Manage connections to the p2p network",
 		Long:  "A tool to manipulate the network swarm. The swarm is the component that opens, listens for, and maintains connections to other ipfs peers in the internet.",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
 			// check if the repo is initialized
 			if ipfsm.IpfsRepo == nil {
 
-				fmt.Println("")
-				fmt.Println(fmt.Errorf("Could not find repo."))
-				fmt.Println("")
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not find repo.")
 
 			}
 
-			return
+			return nil
 
 		},
 	}
@@ -863,6 +1088,7 @@ func (ipfsm *PackageManager) CreateCommandSwarm() *cobra.Command {
 	command.AddCommand(ipfsm.CreateCommandSwarm_Connect())
 	command.AddCommand(ipfsm.CreateCommandSwarm_Disconnect())
 	command.AddCommand(ipfsm.CreateCommandSwarm_Peers())
+	command.AddCommand(ipfsm.CreateCommandSwarm_Limits())
 
 	return command
 
@@ -880,7 +1106,7 @@ func (ipfsm *PackageManager) CreateCommandSwarm_Connect() *cobra.Command {
 		Short: "Open connection to a given address",
 		Long:  "This command opens a new direct connection to a peer address, where the address is given in multiaddr format.",
 		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
 			// check if the repo is initialized
 			if ipfsm.IpfsRepo != nil {
@@ -889,29 +1115,25 @@ func (ipfsm *PackageManager) CreateCommandSwarm_Connect() *cobra.Command {
 				addrInfo, err := ipfsm.SwarmConnect(args[0])
 				if err != nil {
 
-					fmt.Println("")
-					fmt.Println(fmt.Errorf("Could not connect to the peer: %v", err.Error()))
-					fmt.Println("")
-
-					return
+					logger.Manager.Println("")
+					return fmt.Errorf("Could not connect to the peer: %w", err)
 
 				}
 
-				fmt.Println("")
-				fmt.Println(fmt.Sprintf("Connected to peer:"))
-				fmt.Println(fmt.Sprintf(" [#] ID: %v", addrInfo.ID))
-				fmt.Println(fmt.Sprintf(" [#] Multiaddr: %v", addrInfo.Addrs))
-				fmt.Println("")
+				logger.Manager.Println("")
+				logger.Manager.Println(fmt.Sprintf("Connected to peer:"))
+				logger.Manager.Resultln(fmt.Sprintf(" [#] ID: %v", addrInfo.ID))
+				logger.Manager.Resultln(fmt.Sprintf(" [#] Multiaddr: %v", addrInfo.Addrs))
+				logger.Manager.Println("")
 
 			} else {
 
-				fmt.Println("")
-				fmt.Println(fmt.Errorf("Could not find repo."))
-				fmt.Println("")
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not find repo.")
 
 			}
 
-			return
+			return nil
 
 		},
 	}
@@ -932,7 +1154,7 @@ func (ipfsm *PackageManager) CreateCommandSwarm_Disconnect() *cobra.Command {
 		Short: "Close connection from a given address",
 		Long:  "This command closes a direct connection to a peer address, where the address is given in multiaddr format.",
 		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
 			// check if the repo is initialized
 			if ipfsm.IpfsRepo != nil {
@@ -941,27 +1163,23 @@ func (ipfsm *PackageManager) CreateCommandSwarm_Disconnect() *cobra.Command {
 				err := ipfsm.SwarmDisconnect(args[0])
 				if err != nil {
 
-					fmt.Println("")
-					fmt.Println(fmt.Errorf("Could not disconnect from the peer: %v", err.Error()))
-					fmt.Println("")
-
-					return
+					logger.Manager.Println("")
+					return fmt.Errorf("Could not disconnect from the peer: %w", err)
 
 				}
 
-				fmt.Println("")
-				fmt.Println(fmt.Sprintf("Disconnected from peer: %v", args[0]))
-				fmt.Println("")
+				logger.Manager.Println("")
+				logger.Manager.Println(fmt.Sprintf("Disconnected from peer: %v", args[0]))
+				logger.Manager.Println("")
 
 			} else {
 
-				fmt.Println("")
-				fmt.Println(fmt.Errorf("Could not find repo."))
-				fmt.Println("")
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not find repo.")
 
 			}
 
-			return
+			return nil
 
 		},
 	}
@@ -981,7 +1199,7 @@ func (ipfsm *PackageManager) CreateCommandSwarm_Peers() *cobra.Command {
 		Use:   "peers",
 		Short: "List peers with open connections",
 		Long:  "This command lists the set of peers this IPFS node is connected to.",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
 			// check if the repo is initialized
 			if ipfsm.IpfsRepo != nil {
@@ -990,31 +1208,27 @@ func (ipfsm *PackageManager) CreateCommandSwarm_Peers() *cobra.Command {
 				peers, err := ipfsm.GetConnectedPeers()
 				if ipfsm.IpfsNode == nil {
 
-					fmt.Println("")
-					fmt.Println(fmt.Errorf(err.Error()))
-					fmt.Println("")
-
-					return
+					logger.Manager.Println("")
+					return err
 
 				}
-				fmt.Println("")
+				logger.Manager.Println("")
 				for i, peer := range peers {
-					fmt.Println(fmt.Sprintf("Peer %v:", i))
-					fmt.Println(fmt.Sprintf(" [#] ID: %v", peer.ID()))
-					fmt.Println(fmt.Sprintf(" [#] Connected via address: %v", peer.Address()))
-					fmt.Println(fmt.Sprintf(" [#] Connection direction: %v", peer.Direction()))
+					logger.Manager.Println(fmt.Sprintf("Peer %v:", i))
+					logger.Manager.Resultln(fmt.Sprintf(" [#] ID: %v", peer.ID()))
+					logger.Manager.Resultln(fmt.Sprintf(" [#] Connected via address: %v", peer.Address()))
+					logger.Manager.Resultln(fmt.Sprintf(" [#] Connection direction: %v", peer.Direction()))
 				}
-				fmt.Println("")
+				logger.Manager.Println("")
 
 			} else {
 
-				fmt.Println("")
-				fmt.Println(fmt.Errorf("Could not find repo."))
-				fmt.Println("")
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not find repo.")
 
 			}
 
-			return
+			return nil
 
 		},
 	}
@@ -1035,27 +1249,24 @@ func (ipfsm *PackageManager) CreateCommandAdd() *cobra.Command {
 		Short: "Add a local file/directory to the IPFS node",
 		Long:  "This command adds a local file/directory to the local IPFS node.",
 		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
 			// add the file/directory from IPFS and write it to the given path
 			cid, err := ipfsm.AddObjectFromPath(args[0], pin)
 			if err != nil {
 
-				fmt.Println("")
-				fmt.Println(fmt.Errorf("Could not add '%v' to IPFS node: %v", args[0], err.Error()))
-				fmt.Println("")
-
-				return
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not add '%v' to IPFS node: %w", args[0], err)
 
 			}
 
-			fmt.Println("")
-			fmt.Println("Added file/directory to IPFS:")
-			fmt.Printf(" [#] Path: %v\n", args[0])
-			fmt.Printf(" [#] CID: %v\n", cid)
-			fmt.Println("")
+			logger.Manager.Println("")
+			logger.Manager.Println("Added file/directory to IPFS:")
+			logger.Manager.Resultf(" [#] Path: %v\n", args[0])
+			logger.Manager.Resultf(" [#] CID: %v\n", cid)
+			logger.Manager.Println("")
 
-			return
+			return nil
 
 		},
 	}
@@ -1072,6 +1283,7 @@ func (ipfsm *PackageManager) CreateCommandGet() *cobra.Command {
 
 	// flags for the 'get' command
 	var path string
+	var resume bool
 
 	// create a 'get' command for the node
 	command := &cobra.Command{
@@ -1079,43 +1291,50 @@ func (ipfsm *PackageManager) CreateCommandGet() *cobra.Command {
 		Short: "Retrieve a file/directory from IPFS",
 		Long:  "This command makes a GET request on the IPFS network to retrieve a file or directory.",
 		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
 			// get the file/directory from IPFS and write it to the given path
-			cid, err := gocid.Parse(args[0])
+			cid, err := ParseCID(args[0])
 			if err != nil {
 
-				fmt.Println("")
-				fmt.Println(fmt.Errorf("'%v' is not a valid CID.", args[0]))
-				fmt.Println("")
+				logger.Manager.Println("")
+				return err
 
 			} else {
 
 				// retrieve the file
-				newpath, err := ipfsm.GetObject(cid.String(), path)
+				var newpath string
+				if resume {
+					newpath, err = ipfsm.GetObjectResumable(cid.String(), path, func(received int64, total int64) {
+						if total > 0 {
+							logger.Manager.Printf("\r [#] Downloaded: %.1f %%", float64(received)/float64(total)*100)
+						}
+					})
+					logger.Manager.Println("")
+				} else {
+					newpath, err = ipfsm.GetObject(cid.String(), path)
+				}
 				if err != nil {
 
-					fmt.Println("")
-					fmt.Println(fmt.Errorf("Could not get file/directory with CID '%v': %v", cid.String(), err.Error()))
-					fmt.Println("")
-
-					return
+					logger.Manager.Println("")
+					return fmt.Errorf("Could not get file/directory with CID '%v': %w", cid.String(), err)
 				}
 
-				fmt.Println("")
-				fmt.Println("Retrieved file/directory from IPFS:")
-				fmt.Printf(" [#] Path: %v\n", newpath)
-				fmt.Println("")
+				logger.Manager.Println("")
+				logger.Manager.Println("Retrieved file/directory from IPFS:")
+				logger.Manager.Resultf(" [#] Path: %v\n", newpath)
+				logger.Manager.Println("")
 
 			}
 
-			return
+			return nil
 
 		},
 	}
 
 	// add command flags
 	command.Flags().StringVarP(&path, "path", "p", "", "Store the file/directory in the given folder")
+	command.Flags().BoolVarP(&resume, "resume", "r", false, "Retrieve the file/directory in chunks and resume an interrupted retrieval")
 
 	return command
 
@@ -1125,23 +1344,49 @@ func (ipfsm *PackageManager) CreateCommandGet() *cobra.Command {
 func (ipfsm *PackageManager) CreateCommandPin() *cobra.Command {
 
 	// flags for the 'pin' command
-	// var path string
+	var remote bool
+	var status string
 
 	// create a 'pin' command for the node
 	command := &cobra.Command{
 		Use:   "pin <ipfs-path>",
 		Short: "Pin (and unpin) objects to local IPFS node storage.",
-		Long:  "Stores an IPFS object(s) from a given path locally to disk.",
-		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
+		Long:  "Stores an IPFS object(s) from a given path locally to disk. The sub-commands 'add', 'rm', and 'ls' pin, unpin, and list the objects pinned on the local IPFS node.",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if status != "" {
+				return nil
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// query the status of a remote pin request
+			if status != "" {
+
+				pinStatus, err := ipfsm.GetRemotePinStatus(status)
+				if err != nil {
+
+					logger.Manager.Println("")
+					return fmt.Errorf("Could not get status of remote pin request '%v': %w", status, err)
+				}
+
+				logger.Manager.Println("")
+				logger.Manager.Println("Remote pin request:")
+				logger.Manager.Resultf(" [#] Request ID: %v\n", pinStatus.RequestID)
+				logger.Manager.Resultf(" [#] CID: %v\n", pinStatus.Pin.CID)
+				logger.Manager.Resultf(" [#] Status: %v\n", pinStatus.Status)
+				logger.Manager.Resultf(" [#] Created: %v\n", pinStatus.Created)
+				logger.Manager.Println("")
+
+				return nil
+			}
 
 			// pin the object on the local IPFS node
-			cid, err := gocid.Parse(args[0])
+			cid, err := ParseCID(args[0])
 			if err != nil {
 
-				fmt.Println("")
-				fmt.Println(fmt.Errorf("'%v' is not a valid CID.", args[0]))
-				fmt.Println("")
+				logger.Manager.Println("")
+				return err
 
 			} else {
 
@@ -1149,27 +1394,47 @@ func (ipfsm *PackageManager) CreateCommandPin() *cobra.Command {
 				_, err := ipfsm.PinObject(cid.String())
 				if err != nil {
 
-					fmt.Println("")
-					fmt.Println(fmt.Errorf("Could not pin file/directory with CID '%v': %v", cid.String(), err.Error()))
-					fmt.Println("")
-
-					return
+					logger.Manager.Println("")
+					return fmt.Errorf("Could not pin file/directory with CID '%v': %w", cid.String(), err)
 				}
 
-				fmt.Println("")
-				fmt.Println("Pinned file/directory on local IPFS node:")
-				fmt.Printf(" [#] CID: %v\n", cid.String())
-				fmt.Println("")
+				logger.Manager.Println("")
+				logger.Manager.Println("Pinned file/directory on local IPFS node:")
+				logger.Manager.Resultf(" [#] CID: %v\n", cid.String())
+				logger.Manager.Println("")
+
+				// pin the object on the remote pinning service
+				if remote {
+
+					pinStatus, err := ipfsm.PinObjectRemote(cid.String(), "")
+					if err != nil {
+
+						return fmt.Errorf("Could not pin file/directory on remote pinning service: %w", err)
+					}
+
+					logger.Manager.Println("Pinned file/directory on remote pinning service:")
+					logger.Manager.Resultf(" [#] Service: %v\n", ipfsm.RemotePinning.Name)
+					logger.Manager.Resultf(" [#] Request ID: %v\n", pinStatus.RequestID)
+					logger.Manager.Resultf(" [#] Status: %v\n", pinStatus.Status)
+					logger.Manager.Println("")
+
+				}
 
 			}
 
-			return
+			return nil
 
 		},
 	}
 
 	// add command flags
-	// command.Flags().StringVarP(&var, "", "", "", "DESCRIPTION")
+	command.Flags().BoolVarP(&remote, "remote", "r", false, "Also pin the object on the configured remote pinning service")
+	command.Flags().StringVarP(&status, "status", "s", "", "Query the status of a remote pin request by its request ID")
+
+	// add the subcommands
+	command.AddCommand(ipfsm.CreateCommandPin_Add())
+	command.AddCommand(ipfsm.CreateCommandPin_Rm())
+	command.AddCommand(ipfsm.CreateCommandPin_Ls())
 
 	return command
 
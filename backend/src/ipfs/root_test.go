@@ -0,0 +1,66 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+import (
+	// standard
+	"testing"
+	"time"
+
+	// external
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+func TestCountProvidersExcludesThisNode(t *testing.T) {
+	self := peer.ID("self")
+
+	// this node is not counted as a provider
+	providers := make(chan peer.AddrInfo, 3)
+	providers <- peer.AddrInfo{ID: self}
+	providers <- peer.AddrInfo{ID: peer.ID("other")}
+	providers <- peer.AddrInfo{ID: peer.ID("another")}
+	close(providers)
+	if count := _countProviders(providers, self, time.Minute); count != 2 {
+		t.Errorf("got %v providers, want 2", count)
+	}
+
+	// only this node provides the object
+	providers = make(chan peer.AddrInfo, 1)
+	providers <- peer.AddrInfo{ID: self}
+	close(providers)
+	if count := _countProviders(providers, self, time.Minute); count != 0 {
+		t.Errorf("got %v providers, want none besides this node", count)
+	}
+
+	// the counting stops after the grace period after the first provider
+	providers = make(chan peer.AddrInfo, 1)
+	providers <- peer.AddrInfo{ID: peer.ID("other")}
+	done := make(chan int)
+	go func() { done <- _countProviders(providers, self, 10*time.Millisecond) }()
+	select {
+	case count := <-done:
+		if count != 1 {
+			t.Errorf("got %v providers, want 1", count)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the counting did not stop after the grace period")
+	}
+}
@@ -0,0 +1,200 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+/*
+
+The bandwidth of the add and get operations of the local IPFS node can be
+limited with the optional 'ipfs.json' file of the configuration directory.
+Each limit is given in bytes per second:
+
+  - bandwidth_max_upload / bandwidth_max_download: shared by all concurrent
+    add / get operations of the node
+  - bandwidth_max_upload_per_operation / bandwidth_max_download_per_operation:
+    applied to each single add / get operation
+
+The file streams are wrapped by a rate-limited reader, which waits for the
+slowest of the applicable limits before it passes the read data on.
+
+*/
+
+import (
+
+	// standard
+	"io"
+	"sync"
+	"time"
+
+	// external
+	"github.com/ipfs/boxo/files"
+)
+
+// maximum number of bytes read at once from a throttled file stream
+const bandwidthChunkSize = 32 * 1024
+
+// Bandwidth limit shared by all streams using it
+type bandwidthLimiter struct {
+	Mutex sync.Mutex
+	Rate  int64     // bytes per second (0 = unlimited)
+	next  time.Time // time at which all reserved bytes are transferred
+}
+
+// BANDWIDTH LIMITS
+// #############################################################################
+// Create a new bandwidth limit (nil, if the rate is unlimited)
+func newBandwidthLimiter(rate int64) *bandwidthLimiter {
+
+	if rate <= 0 {
+		return nil
+	}
+
+	return &bandwidthLimiter{Rate: rate}
+
+}
+
+// Reserve the transfer of n bytes and get the time at which the transfer is allowed to complete
+func (limiter *bandwidthLimiter) reserve(n int) time.Time {
+
+	limiter.Mutex.Lock()
+	defer limiter.Mutex.Unlock()
+
+	// unused bandwidth is not saved up
+	now := time.Now()
+	if limiter.next.Before(now) {
+		limiter.next = now
+	}
+	limiter.next = limiter.next.Add(time.Duration(float64(n) / float64(limiter.Rate) * float64(time.Second)))
+
+	return limiter.next
+
+}
+
+// Wait until the transfer of n bytes complies with all limits
+func waitForBandwidth(limiters []*bandwidthLimiter, n int) {
+
+	deadline := time.Time{}
+	for _, limiter := range limiters {
+		if t := limiter.reserve(n); t.After(deadline) {
+			deadline = t
+		}
+	}
+	time.Sleep(time.Until(deadline))
+
+}
+
+// Get the limits for a new upload (add) operation
+func (ipfsm *PackageManager) _uploadLimiters() []*bandwidthLimiter {
+	return ipfsm._limiters(ipfsm.UploadLimiter, ipfsm.NodeConfig.BandwidthMaxUploadPerOperation)
+}
+
+// Get the limits for a new download (get) operation
+func (ipfsm *PackageManager) _downloadLimiters() []*bandwidthLimiter {
+	return ipfsm._limiters(ipfsm.DownloadLimiter, ipfsm.NodeConfig.BandwidthMaxDownloadPerOperation)
+}
+
+// Combine the global limit and a new limit for a single operation
+func (ipfsm *PackageManager) _limiters(global *bandwidthLimiter, perOperation int64) []*bandwidthLimiter {
+	var limiters []*bandwidthLimiter
+
+	if global != nil {
+		limiters = append(limiters, global)
+	}
+	if limiter := newBandwidthLimiter(perOperation); limiter != nil {
+		limiters = append(limiters, limiter)
+	}
+
+	return limiters
+
+}
+
+// RATE-LIMITED FILE STREAMS
+// #############################################################################
+// Reader that limits the bandwidth of the underlying reader
+type throttledReader struct {
+	reader   io.Reader
+	limiters []*bandwidthLimiter
+}
+
+// Read from the underlying reader in chunks and wait for the bandwidth limits
+func (tr *throttledReader) Read(p []byte) (int, error) {
+
+	if len(p) > bandwidthChunkSize {
+		p = p[:bandwidthChunkSize]
+	}
+	n, err := tr.reader.Read(p)
+	if n > 0 {
+		waitForBandwidth(tr.limiters, n)
+	}
+
+	return n, err
+
+}
+
+// File node whose content is read with limited bandwidth
+type throttledFile struct {
+	files.File
+	reader *throttledReader
+}
+
+func (tf *throttledFile) Read(p []byte) (int, error) {
+	return tf.reader.Read(p)
+}
+
+// Directory node whose files are read with limited bandwidth
+type throttledDirectory struct {
+	files.Directory
+	limiters []*bandwidthLimiter
+}
+
+func (td *throttledDirectory) Entries() files.DirIterator {
+	return &throttledDirIterator{td.Directory.Entries(), td.limiters}
+}
+
+// Directory iterator returning nodes that are read with limited bandwidth
+type throttledDirIterator struct {
+	files.DirIterator
+	limiters []*bandwidthLimiter
+}
+
+func (ti *throttledDirIterator) Node() files.Node {
+	return throttleNode(ti.DirIterator.Node(), ti.limiters)
+}
+
+// Wrap the file or directory node to read its content with limited bandwidth
+func throttleNode(node files.Node, limiters []*bandwidthLimiter) files.Node {
+
+	// nothing to limit
+	if len(limiters) == 0 {
+		return node
+	}
+
+	switch n := node.(type) {
+	case *files.Symlink:
+		return node
+	case files.File:
+		return &throttledFile{n, &throttledReader{n, limiters}}
+	case files.Directory:
+		return &throttledDirectory{n, limiters}
+	}
+
+	return node
+
+}
@@ -0,0 +1,147 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package ipfs
+
+import (
+
+	// standard
+	"bytes"
+	"io"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	// external
+	"github.com/ipfs/boxo/files"
+)
+
+// helper function to create random test data
+func _testData(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(data)
+	return data
+}
+
+func TestThrottledReaderLimitsTheThroughput(t *testing.T) {
+	data := _testData(256 * 1024)
+
+	// 256 KiB at 1 MiB/s take at least 250 ms
+	start := time.Now()
+	reader := &throttledReader{bytes.NewReader(data), []*bandwidthLimiter{newBandwidthLimiter(1024 * 1024)}}
+	read, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("read %v bytes in %v, want at least 200ms", len(read), elapsed)
+	}
+	if !bytes.Equal(read, data) {
+		t.Error("the throttled data was corrupted")
+	}
+}
+
+func TestThrottledReaderWithoutLimits(t *testing.T) {
+	if newBandwidthLimiter(0) != nil || newBandwidthLimiter(-1) != nil {
+		t.Error("expected no limiter for an unlimited rate")
+	}
+
+	// a node without limits is not wrapped
+	file := files.NewBytesFile([]byte("data"))
+	if throttleNode(file, nil) != files.Node(file) {
+		t.Error("expected an unlimited node not to be wrapped")
+	}
+}
+
+func TestGlobalLimiterCapsConcurrentStreams(t *testing.T) {
+	global := newBandwidthLimiter(1024 * 1024)
+	data := _testData(64 * 1024)
+
+	// 4 streams of 64 KiB share 1 MiB/s and take at least 250 ms
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			read, err := io.ReadAll(&throttledReader{bytes.NewReader(data), []*bandwidthLimiter{global}})
+			if err != nil || !bytes.Equal(read, data) {
+				t.Errorf("the throttled data was corrupted (%v)", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("read 4 streams in %v, want at least 200ms", elapsed)
+	}
+}
+
+func TestPerOperationLimits(t *testing.T) {
+	ipfsm := &PackageManager{UploadLimiter: newBandwidthLimiter(1000)}
+	ipfsm.NodeConfig.BandwidthMaxUploadPerOperation = 500
+
+	// each operation gets the global limit and its own limit
+	first, second := ipfsm._uploadLimiters(), ipfsm._uploadLimiters()
+	if len(first) != 2 || first[0] != ipfsm.UploadLimiter || first[1].Rate != 500 {
+		t.Fatalf("unexpected limits: %+v", first)
+	}
+	if first[1] == second[1] {
+		t.Error("expected the operations not to share their own limit")
+	}
+
+	// without any configured limits, the downloads are unlimited
+	if limiters := ipfsm._downloadLimiters(); len(limiters) != 0 {
+		t.Errorf("got %v download limits, want none", len(limiters))
+	}
+}
+
+func TestThrottleNodeWrapsTheDirectoryEntries(t *testing.T) {
+	data := _testData(1024)
+	directory := files.NewMapDirectory(map[string]files.Node{
+		"scene.blend": files.NewBytesFile(data),
+		"textures":    files.NewMapDirectory(map[string]files.Node{"wood.png": files.NewBytesFile(data)}),
+	})
+
+	node := throttleNode(directory, []*bandwidthLimiter{newBandwidthLimiter(1024 * 1024)})
+	count := 0
+	var walk func(node files.Node)
+	walk = func(node files.Node) {
+		switch n := node.(type) {
+		case *throttledFile:
+			read, err := io.ReadAll(n)
+			if err != nil || !bytes.Equal(read, data) {
+				t.Errorf("the throttled file was corrupted (%v)", err)
+			}
+			count++
+		case *throttledDirectory:
+			entries := n.Entries()
+			for entries.Next() {
+				walk(entries.Node())
+			}
+		default:
+			t.Errorf("unexpected unthrottled node %T", node)
+		}
+	}
+	walk(node)
+	if count != 2 {
+		t.Errorf("read %v files, want 2", count)
+	}
+}
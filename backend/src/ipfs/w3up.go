@@ -161,7 +161,7 @@ func (w3cli *w3cliAgent) ProcessOutput(name string, output io.ReadCloser) error
 				// Unmarshal the JSON data into the UCAN struct
 				var Upload w3cliUpload
 				if err := json.Unmarshal([]byte(line), &Upload); err != nil {
-					fmt.Println("Error:", err)
+					logger.Manager.Errorln("Error:", err)
 					return err
 				}
 				currentSpace.Uploads = append(currentSpace.Uploads, Upload)
@@ -327,7 +327,7 @@ func (w3cli *w3cliAgent) ProcessOutput(name string, output io.ReadCloser) error
 					// Unmarshal the JSON data into the UCAN struct
 					var UCAN w3cliUCAN
 					if err := json.Unmarshal([]byte(line), &UCAN); err != nil {
-						fmt.Println("Error:", err)
+						logger.Manager.Errorln("Error:", err)
 						return err
 					}
 					w3cli.Delegations = append(w3cli.Delegations, UCAN)
@@ -357,7 +357,7 @@ func (w3cli *w3cliAgent) ProcessOutput(name string, output io.ReadCloser) error
 					// Unmarshal the JSON data into the UCAN struct
 					var UCAN w3cliUCAN
 					if err := json.Unmarshal([]byte(line), &UCAN); err != nil {
-						fmt.Println("Error:", err)
+						logger.Manager.Errorln("Error:", err)
 						return err
 					}
 					w3cli.Proofs = append(w3cli.Proofs, UCAN)
@@ -862,17 +862,17 @@ func (ipfsm *PackageManager) CreateCommandW3() *cobra.Command {
 		Use:   "w3",
 		Short: "Interact with the Filecoin-based w3up service",
 		Long:  "This command the base command to interact with the Filecoin layer of the renderhive.",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
 			// if the w3 agent is NOT initialized
 			if ipfsm.W3Agent.DIDkey == "" {
 
-				fmt.Println("")
-				fmt.Println(fmt.Errorf("Could not find a w3 agent for this node."))
-				fmt.Println("")
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not find a w3 agent for this node.")
+
 			}
 
-			return
+			return nil
 
 		},
 	}
@@ -893,62 +893,58 @@ func (ipfsm *PackageManager) CreateCommandW3_Info() *cobra.Command {
 		Use:   "info",
 		Short: "Print information about the w3up service agent",
 		Long:  "This command prints the available information of the w3up service agent.",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
 			// if the w3 agent is initialized
 			if ipfsm.W3Agent.DIDkey != "" {
 
 				// Print info
-				fmt.Println("")
-				fmt.Println(fmt.Sprintf("The w3up service agent information:"))
+				logger.Manager.Println("")
+				logger.Manager.Println(fmt.Sprintf("The w3up service agent information:"))
 
 				// Authorize this agent
 				_, err = ipfsm.W3Agent.Whoami()
 				if err != nil {
-					fmt.Println(fmt.Errorf(err.Error()))
-					return
+					return err
 				}
 
 				// Print info
-				fmt.Println(fmt.Sprintf(" [#] DID: %v", ipfsm.W3Agent.DIDkey))
+				logger.Manager.Resultln(fmt.Sprintf(" [#] DID: %v", ipfsm.W3Agent.DIDkey))
 
 				// Get list of spaces this agent has access to
 				err = ipfsm.W3Agent.SpaceList()
 				if err != nil {
-					fmt.Println(fmt.Errorf(err.Error()))
-					return
+					return err
 				}
 
 				// Print info
-				fmt.Println(fmt.Sprintf(" [#] Number of available spaces: %v", len(ipfsm.W3Agent.Spaces)))
+				logger.Manager.Resultln(fmt.Sprintf(" [#] Number of available spaces: %v", len(ipfsm.W3Agent.Spaces)))
 
 				// if there is at least one space
 				if len(ipfsm.W3Agent.Spaces) > 0 {
 
 					// Print info
-					fmt.Println(fmt.Sprintf(" [#] Current space: '%v' (%v)", ipfsm.W3Agent.Spaces[ipfsm.W3Agent.ActiveSpace].Name, ipfsm.W3Agent.ActiveSpace))
+					logger.Manager.Resultln(fmt.Sprintf(" [#] Current space: '%v' (%v)", ipfsm.W3Agent.Spaces[ipfsm.W3Agent.ActiveSpace].Name, ipfsm.W3Agent.ActiveSpace))
 
 					// Get list of uploads in the active space
 					err = ipfsm.W3Agent.UploadList()
 					if err != nil {
-						fmt.Println(fmt.Errorf(err.Error()))
-						return
+						return err
 					}
 
 					// Print info
-					fmt.Println(fmt.Sprintf(" [#] Number of available files in current space: %v", len(ipfsm.W3Agent.Spaces[ipfsm.W3Agent.ActiveSpace].Uploads)))
+					logger.Manager.Resultln(fmt.Sprintf(" [#] Number of available files in current space: %v", len(ipfsm.W3Agent.Spaces[ipfsm.W3Agent.ActiveSpace].Uploads)))
 
 				}
 
 			} else {
 
-				fmt.Println("")
-				fmt.Println(fmt.Errorf("Could not find a w3 agent for this node."))
-				fmt.Println("")
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not find a w3 agent for this node.")
 
 			}
 
-			return
+			return nil
 
 		},
 	}
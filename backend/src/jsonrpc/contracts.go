@@ -29,10 +29,12 @@ package jsonrpc
 import (
 
 	// standard
+	"context"
 	"encoding/hex"
 	"fmt"
 	"math/big"
 	"net/http"
+	"strings"
 
 	// external
 
@@ -60,36 +62,37 @@ type ContractService struct{}
 
 // Method
 func (ops *ContractService) Deploy(r *http.Request, args *DeployArgs, reply *DeployReply) error {
-	var err error
-	var transactionBytes []byte
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the locked mutex (or give up, when the request times out)
+	return _callLocked(r.Context(), true, func() error {
+		var err error
+		var transactionBytes []byte
 
-	// TODO: Implement further checks and security measures
+		// TODO: Implement further checks and security measures
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Starting to deploy smart contract (Gas: %v)", args.Gas))
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Starting to deploy smart contract (Gas: %v)", args.Gas))
 
-	// prepare a new contract object
-	contract := hedera.HederaSmartContract{}
+		// prepare a new contract object
+		contract := hedera.HederaSmartContract{}
 
-	// deploy the new contract
-	response, receipt, transactionBytes, err := contract.NewFromBin(args.ContractFilepath, nil, args.Gas)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// deploy the new contract
+		response, receipt, transactionBytes, err := contract.NewFromBin(args.ContractFilepath, nil, args.Gas, hedera.TransactionOptions.SetContext(r.Context()))
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] New Contract ID: %v", receipt.ContractID.String()))
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] New Contract ID: %v", receipt.ContractID.String()))
 
-	// set a reply message
-	reply.Message = "New smart contract was deployed as " + receipt.ContractID.String() + " with transaction: " + response.TransactionID.String() + "!"
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+		// set a reply message
+		reply.Message = "New smart contract was deployed as " + receipt.ContractID.String() + " with transaction: " + response.TransactionID.String() + "!"
+		reply.TransactionBytes = hex.EncodeToString(transactionBytes)
 
-	// create reply for the RPC client
-	return nil
+		// create reply for the RPC client
+		return nil
+
+	})
 
 }
 
@@ -99,53 +102,43 @@ func (ops *ContractService) Deploy(r *http.Request, args *DeployArgs, reply *Dep
 
 // Method
 func (ops *ContractService) GetCurrentHiveCycle(r *http.Request, args *GetCurrentHiveCycleArgs, reply *GetCurrentHiveCycleReply) error {
-	var err error
-	var transactionBytes []byte
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the mutex locked for a read-only query (or give up,
+	// when the request times out)
+	return _callLocked(r.Context(), false, func() error {
+		var err error
 
-	// TODO: Implement further checks and security measures
+		// TODO: Implement further checks and security measures
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
-
-	// prepare the contract object
-	contractID, err := hederasdk.ContractIDFromString(args.ContractID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-	contract := hedera.HederaSmartContract{ID: contractID}
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
 
-	// call the function
-	response, _, transactionBytes, err := contract.CallFunction("getCurrentHiveCycle", nil, args.Gas)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// prepare the contract object
+		contractID, err := hederasdk.ContractIDFromString(args.ContractID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		contract := hedera.HederaSmartContract{ID: contractID}
 
-	// get the result of the function call
-	record, err := response.GetRecord(hedera.Manager.NetworkClient)
-	if err != nil {
-		return fmt.Errorf("Error getting contract response record: %v", err)
-	}
-	functionResult, err := record.GetContractExecuteResult()
-	if err != nil {
-		return fmt.Errorf("Error getting contract execute result: %v", err)
-	}
+		// call the function
+		functionResult, err := contract.CallFunctionLocal("getCurrentHiveCycle", nil, args.Gas, hedera.TransactionOptions.SetContext(r.Context()))
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
 
-	// set a reply value
-	reply.Value = new(big.Int).SetBytes(functionResult.GetInt256(0))
+		// set a reply value
+		reply.Value, err = hedera.DecodeContractInteger(functionResult, "getCurrentHiveCycle", 0)
+		if err != nil {
+			return fmt.Errorf("Error decoding contract execute result: %v", err)
+		}
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Contract function called with transaction: %v", response.TransactionID.String()))
+		// set a reply message
+		reply.Message = "getCurrentHiveCycle function was called with a local (read-only) query.\n\n" + fmt.Sprintf("Result: %v", reply.Value)
 
-	// set a reply message
-	reply.Message = "getCurrentHiveCycle function was called with transaction: " + response.TransactionID.String() + "\n\n" + fmt.Sprintf("Result: %v", reply.Value)
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+		// create reply for the RPC client
+		return nil
 
-	// create reply for the RPC client
-	return nil
+	})
 
 }
 
@@ -158,45 +151,45 @@ func (ops *ContractService) GetCurrentHiveCycle(r *http.Request, args *GetCurren
 
 // Method
 func (ops *ContractService) RegisterOperator(r *http.Request, args *RegisterOperatorArgs, reply *RegisterOperatorReply) error {
-	var err error
-	var transactionBytes []byte
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the mutex locked for the preparation of the
+	// transaction bytes (or give up, when the request times out)
+	return _callLocked(r.Context(), false, func() error {
+		var err error
+		var transactionBytes []byte
 
-	// TODO: Implement further checks and security measures
+		// TODO: Implement further checks and security measures
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
 
-	// prepare the contract object
-	contractID, err := hederasdk.ContractIDFromString(args.ContractID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-	contract := hedera.HederaSmartContract{ID: contractID}
+		// prepare the contract object
+		contractID, err := hederasdk.ContractIDFromString(args.ContractID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		contract := hedera.HederaSmartContract{ID: contractID}
 
-	// prepare the parameters for the function call
-	params := hederasdk.NewContractFunctionParameters().AddString(args.OperatorTopicID)
-	fmt.Println("Params:", contract.ID.String())
-	fmt.Println("Params:", params)
+		// prepare the parameters for the function call
+		params := hederasdk.NewContractFunctionParameters().AddString(args.OperatorTopicID)
 
-	// call the function
-	_, _, transactionBytes, err = contract.CallFunction("registerOperator", params, args.Gas, hedera.TransactionOptions.SetExecute(false, node.Manager.User.UserAccount.AccountID))
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// call the function
+		_, _, transactionBytes, err = contract.CallFunction("registerOperator", params, args.Gas, hedera.TransactionOptions.SetExecute(false, node.Manager.User.UserAccount.AccountID))
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
 
-	// set a reply message
-	reply.Message = "" //"registerOperator function was called with transaction: " + response.TransactionID.String()
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+		// set a reply message
+		reply.Message = "" //"registerOperator function was called with transaction: " + response.TransactionID.String()
+		reply.TransactionBytes = hex.EncodeToString(transactionBytes)
 
-	// create reply for the RPC client
-	return nil
+		// create reply for the RPC client
+		return nil
+
+	})
 
 }
 
@@ -206,40 +199,42 @@ func (ops *ContractService) RegisterOperator(r *http.Request, args *RegisterOper
 
 // Method
 func (ops *ContractService) UnregisterOperator(r *http.Request, args *UnregisterOperatorArgs, reply *UnregisterOperatorReply) error {
-	var err error
-	var transactionBytes []byte
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the mutex locked for the preparation of the
+	// transaction bytes (or give up, when the request times out)
+	return _callLocked(r.Context(), false, func() error {
+		var err error
+		var transactionBytes []byte
 
-	// TODO: Implement further checks and security measures
+		// TODO: Implement further checks and security measures
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
 
-	// prepare the contract object
-	contractID, err := hederasdk.ContractIDFromString(args.ContractID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-	contract := hedera.HederaSmartContract{ID: contractID}
+		// prepare the contract object
+		contractID, err := hederasdk.ContractIDFromString(args.ContractID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		contract := hedera.HederaSmartContract{ID: contractID}
 
-	// call the function
-	_, _, transactionBytes, err = contract.CallFunction("unregisterOperator", nil, args.Gas, hedera.TransactionOptions.SetExecute(false, node.Manager.User.UserAccount.AccountID))
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// call the function
+		_, _, transactionBytes, err = contract.CallFunction("unregisterOperator", nil, args.Gas, hedera.TransactionOptions.SetExecute(false, node.Manager.User.UserAccount.AccountID))
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
 
-	// set a reply message
-	reply.Message = "" //"unregisterOperator function was called with transaction: " + response.TransactionID.String()
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+		// set a reply message
+		reply.Message = "" //"unregisterOperator function was called with transaction: " + response.TransactionID.String()
+		reply.TransactionBytes = hex.EncodeToString(transactionBytes)
 
-	// create reply for the RPC client
-	return nil
+		// create reply for the RPC client
+		return nil
+
+	})
 
 }
 
@@ -249,61 +244,61 @@ func (ops *ContractService) UnregisterOperator(r *http.Request, args *Unregister
 
 // Method
 func (ops *ContractService) DepositOperatorFunds(r *http.Request, args *DepositOperatorFundsArgs, reply *DepositOperatorFundsReply) error {
-	var err error
-	var transactionBytes []byte
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the mutex locked for the preparation of the
+	// transaction bytes (or give up, when the request times out)
+	return _callLocked(r.Context(), false, func() error {
+		var err error
+		var transactionBytes []byte
 
-	// TODO: Implement further checks and security measures
+		// TODO: Implement further checks and security measures
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
 
-	// prepare the contract object
-	contractID, err := hederasdk.ContractIDFromString(args.ContractID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-	contract := hedera.HederaSmartContract{ID: contractID}
+		// prepare the contract object
+		contractID, err := hederasdk.ContractIDFromString(args.ContractID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		contract := hedera.HederaSmartContract{ID: contractID}
 
-	// call the payable function
-	response, receipt, transactionBytes, err := contract.CallPayableFunction("depositOperatorFunds", args.Amount, nil, args.Gas, hedera.TransactionOptions.SetExecute(false, node.Manager.User.UserAccount.AccountID))
-	fmt.Println("Response:", response)
-	fmt.Println("Receipt:", receipt)
-	if err != nil {
+		// call the payable function
+		_, _, transactionBytes, err = contract.CallPayableFunction("depositOperatorFunds", args.Amount, nil, args.Gas, hedera.TransactionOptions.SetExecute(false, node.Manager.User.UserAccount.AccountID))
+		hedera.Manager.AuditTransaction(hedera.AUDIT_OPERATION_DEPOSIT, args.Amount, node.Manager.User.UserAccount.AccountID.String(), transactionBytes, err)
+		if err != nil {
 
-		// // if a result is returned
-		// if response != nil {
-		// 	// get the error message, if any
-		// 	record, err := response.GetRecordQuery().Execute(hedera.Manager.NetworkClient)
-		// 	if err != nil {
-		// 		return fmt.Errorf("Error getting contract response record: %v", err)
-		// 	}
-
-		// 	functionResult, err := record.GetContractExecuteResult()
-		// 	if err != nil {
-		// 		return fmt.Errorf("Error getting contract execute result: %v", err)
-		// 	}
-
-		// 	fmt.Println("Error (%v): %v", err, functionResult.ErrorMessage)
-		// 	return fmt.Errorf("Error (%v): %v", err, functionResult.ErrorMessage)
-		// }
+			// // if a result is returned
+			// if response != nil {
+			// 	// get the error message, if any
+			// 	record, err := response.GetRecordQuery().Execute(hedera.Manager.NetworkClient)
+			// 	if err != nil {
+			// 		return fmt.Errorf("Error getting contract response record: %v", err)
+			// 	}
 
-		// fmt.Println("Error (%v): %v", err, "No details available")
-		return fmt.Errorf("Error (%v): %v", err, "No details available")
-	}
+			// 	functionResult, err := record.GetContractExecuteResult()
+			// 	if err != nil {
+			// 		return fmt.Errorf("Error getting contract execute result: %v", err)
+			// 	}
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
+			// 	fmt.Println("Error (%v): %v", err, functionResult.ErrorMessage)
+			// 	return fmt.Errorf("Error (%v): %v", err, functionResult.ErrorMessage)
+			// }
 
-	// set a reply message
-	reply.Message = "" //"DepositOperatorFunds function was called with transaction: " + response.TransactionID.String()
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+			return fmt.Errorf("Error (%v): %v", err, "No details available")
+		}
 
-	// create reply for the RPC client
-	return nil
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
+
+		// set a reply message
+		reply.Message = "" //"DepositOperatorFunds function was called with transaction: " + response.TransactionID.String()
+		reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+
+		// create reply for the RPC client
+		return nil
+
+	})
 
 }
 
@@ -313,69 +308,67 @@ func (ops *ContractService) DepositOperatorFunds(r *http.Request, args *DepositO
 
 // Method
 func (ops *ContractService) WithdrawOperatorFunds(r *http.Request, args *WithdrawOperatorFundsArgs, reply *WithdrawOperatorFundsReply) error {
-	var err error
-	var transactionBytes []byte
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the mutex locked for the preparation of the
+	// transaction bytes (or give up, when the request times out)
+	return _callLocked(r.Context(), false, func() error {
+		var err error
+		var transactionBytes []byte
 
-	// TODO: Implement further checks and security measures
+		// TODO: Implement further checks and security measures
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
 
-	// prepare the contract object
-	contractID, err := hederasdk.ContractIDFromString(args.ContractID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-	contract := hedera.HederaSmartContract{ID: contractID}
+		// prepare the contract object
+		contractID, err := hederasdk.ContractIDFromString(args.ContractID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		contract := hedera.HederaSmartContract{ID: contractID}
 
-	// convert the string amount of HBAR to a Hbar object
-	amount, err := hederasdk.HbarFromString(args.Amount)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// convert the string amount of HBAR to a Hbar object
+		amount, err := hederasdk.HbarFromString(args.Amount)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
 
-	// convert HBAR to TINYBAR as big.Int
-	amountBigInt := new(big.Int).SetInt64(amount.AsTinybar())
+		// convert HBAR to TINYBAR as big.Int
+		amountBigInt := new(big.Int).SetInt64(amount.AsTinybar())
 
-	// prepare the parameters for the function call
-	params := hederasdk.NewContractFunctionParameters().AddUint256BigInt(amountBigInt)
-	fmt.Println("Params:", contract.ID.String())
-	fmt.Println("Params:", params)
-
-	// call the payable function
-	_, _, transactionBytes, err = contract.CallFunction("withdrawOperatorFunds", params, args.Gas, hedera.TransactionOptions.SetExecute(false, node.Manager.User.UserAccount.AccountID))
-	// fmt.Println("Response:", response)
-	// fmt.Println("Receipt:", receipt)
-	// fmt.Println("Error:", err)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// prepare the parameters for the function call
+		params := hederasdk.NewContractFunctionParameters().AddUint256BigInt(amountBigInt)
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
+		// call the payable function
+		_, _, transactionBytes, err = contract.CallFunction("withdrawOperatorFunds", params, args.Gas, hedera.TransactionOptions.SetExecute(false, node.Manager.User.UserAccount.AccountID))
+		hedera.Manager.AuditTransaction(hedera.AUDIT_OPERATION_WITHDRAWAL, args.Amount, node.Manager.User.UserAccount.AccountID.String(), transactionBytes, err)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
 
-	// get the result of the function call
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
 
-	// record, err := response.GetRecord(hedera.Manager.NetworkClient)
-	// if err != nil {
-	// 	return fmt.Errorf("Error getting contract response record: %v", err)
-	// }
+		// get the result of the function call
 
-	// functionResult, err := record.GetContractExecuteResult()
-	// if err != nil {
-	// 	return fmt.Errorf("Error getting contract execute result: %v", err)
-	// }
+		// record, err := response.GetRecord(hedera.Manager.NetworkClient)
+		// if err != nil {
+		// 	return fmt.Errorf("Error getting contract response record: %v", err)
+		// }
 
-	// set a reply message
-	reply.Message = "" //"WithdrawOperatorFunds function was called with transaction: " + response.TransactionID.String()
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+		// functionResult, err := record.GetContractExecuteResult()
+		// if err != nil {
+		// 	return fmt.Errorf("Error getting contract execute result: %v", err)
+		// }
 
-	// create reply for the RPC client
-	return nil
+		// set a reply message
+		reply.Message = "" //"WithdrawOperatorFunds function was called with transaction: " + response.TransactionID.String()
+		reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+
+		// create reply for the RPC client
+		return nil
+
+	})
 
 }
 
@@ -385,74 +378,61 @@ func (ops *ContractService) WithdrawOperatorFunds(r *http.Request, args *Withdra
 
 // Method
 func (ops *ContractService) GetOperatorBalance(r *http.Request, args *GetOperatorBalanceArgs, reply *GetOperatorBalanceReply) error {
-	var err error
-	var transactionBytes []byte
-
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
-
-	// TODO: Implement further checks and security measures
-
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
-
-	// prepare the contract object
-	contractID, err := hederasdk.ContractIDFromString(args.ContractID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-	contract := hedera.HederaSmartContract{ID: contractID}
-
-	// prepare the passed AccountID
-	accountID, err := hederasdk.AccountIDFromString(args.AccountID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-
-	// prepare the parameters for the function call
-	params, err := hederasdk.NewContractFunctionParameters().AddAddress(accountID.ToSolidityAddress())
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-
-	// call the function
-	response, _, transactionBytes, err := contract.CallFunction("getOperatorBalance", params, args.Gas)
-	// fmt.Println("Response:", response)
-	// fmt.Println("Receipt:", receipt)
-	// fmt.Println("Error:", err)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Contract function called with transaction: %v", response.TransactionID.String()))
-
-	// get the result of the function call
-	record, err := response.GetRecord(hedera.Manager.NetworkClient)
-	if err != nil {
-		return fmt.Errorf("Error getting contract response record: %v", err)
-	}
-	functionResult, err := record.GetContractExecuteResult()
-	if err != nil {
-		return fmt.Errorf("Error getting contract execute result: %v", err)
-	}
-
-	// set a reply value
-	reply.Value = new(big.Int).SetBytes(functionResult.GetInt256(0))
-
-	// convert tℏ to ℏ
-	amount, err := hederasdk.HbarFromString(reply.Value.String() + " tℏ")
-	if err != nil {
-		return fmt.Errorf("Error getting contract response record: %v", err)
-	}
-
-	// set a reply message
-	reply.Message = "getOperatorBalance function was called with transaction: " + response.TransactionID.String() + "\n\n" + fmt.Sprintf("Result: %v", amount)
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
 
-	// create reply for the RPC client
-	return nil
+	// call the method with the mutex locked for a read-only query (or give up,
+	// when the request times out)
+	return _callLocked(r.Context(), false, func() error {
+		var err error
+
+		// TODO: Implement further checks and security measures
+
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
+
+		// prepare the contract object
+		contractID, err := hederasdk.ContractIDFromString(args.ContractID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		contract := hedera.HederaSmartContract{ID: contractID}
+
+		// prepare the passed AccountID
+		accountID, err := hederasdk.AccountIDFromString(args.AccountID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+
+		// prepare the parameters for the function call
+		params, err := hederasdk.NewContractFunctionParameters().AddAddress(accountID.ToSolidityAddress())
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+
+		// call the function
+		functionResult, err := contract.CallFunctionLocal("getOperatorBalance", params, args.Gas, hedera.TransactionOptions.SetContext(r.Context()))
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+
+		// set a reply value
+		reply.Value, err = hedera.DecodeContractInteger(functionResult, "getOperatorBalance", 0)
+		if err != nil {
+			return fmt.Errorf("Error decoding contract execute result: %v", err)
+		}
+
+		// convert tℏ to ℏ
+		amount, err := hederasdk.HbarFromString(reply.Value.String() + " tℏ")
+		if err != nil {
+			return fmt.Errorf("Error getting contract response record: %v", err)
+		}
+
+		// set a reply message
+		reply.Message = "getOperatorBalance function was called with a local (read-only) query.\n\n" + fmt.Sprintf("Result: %v", amount)
+
+		// create reply for the RPC client
+		return nil
+
+	})
 
 }
 
@@ -462,75 +442,62 @@ func (ops *ContractService) GetOperatorBalance(r *http.Request, args *GetOperato
 
 // Method
 func (ops *ContractService) GetReservedOperatorFunds(r *http.Request, args *GetReservedOperatorFundsArgs, reply *GetReservedOperatorFundsReply) error {
-	var err error
-	var transactionBytes []byte
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the mutex locked for a read-only query (or give up,
+	// when the request times out)
+	return _callLocked(r.Context(), false, func() error {
+		var err error
 
-	// TODO: Implement further checks and security measures
+		// TODO: Implement further checks and security measures
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
-
-	// prepare the contract object
-	contractID, err := hederasdk.ContractIDFromString(args.ContractID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-	contract := hedera.HederaSmartContract{ID: contractID}
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
 
-	// prepare the passed AccountID
-	accountID, err := hederasdk.AccountIDFromString(args.AccountID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// prepare the contract object
+		contractID, err := hederasdk.ContractIDFromString(args.ContractID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		contract := hedera.HederaSmartContract{ID: contractID}
 
-	// prepare the parameters for the function call
-	params, err := hederasdk.NewContractFunctionParameters().AddAddress(accountID.ToSolidityAddress())
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// prepare the passed AccountID
+		accountID, err := hederasdk.AccountIDFromString(args.AccountID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
 
-	// call the function
-	response, _, transactionBytes, err := contract.CallFunction("getReservedOperatorFunds", params, args.Gas)
-	// fmt.Println("Response:", response)
-	// fmt.Println("Receipt:", receipt)
-	// fmt.Println("Error:", err)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// prepare the parameters for the function call
+		params, err := hederasdk.NewContractFunctionParameters().AddAddress(accountID.ToSolidityAddress())
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Contract function called with transaction: %v", response.TransactionID.String()))
+		// call the function
+		functionResult, err := contract.CallFunctionLocal("getReservedOperatorFunds", params, args.Gas, hedera.TransactionOptions.SetContext(r.Context()))
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
 
-	// get the result of the function call
-	record, err := response.GetRecord(hedera.Manager.NetworkClient)
-	if err != nil {
-		return fmt.Errorf("Error getting contract response record: %v", err)
-	}
-	functionResult, err := record.GetContractExecuteResult()
-	if err != nil {
-		return fmt.Errorf("Error getting contract execute result: %v", err)
-	}
+		// set a reply value
+		reply.Value, err = hedera.DecodeContractInteger(functionResult, "getReservedOperatorFunds", 0)
+		if err != nil {
+			return fmt.Errorf("Error decoding contract execute result: %v", err)
+		}
 
-	// set a reply value
-	reply.Value = new(big.Int).SetBytes(functionResult.GetInt256(0))
+		// convert tℏ to ℏ
+		amount, err := hederasdk.HbarFromString(reply.Value.String() + " tℏ")
 
-	// convert tℏ to ℏ
-	amount, err := hederasdk.HbarFromString(reply.Value.String() + " tℏ")
+		if err != nil {
+			return fmt.Errorf("Error getting contract response record: %v", err)
+		}
 
-	if err != nil {
-		return fmt.Errorf("Error getting contract response record: %v", err)
-	}
+		// set a reply messages
+		reply.Message = "getReservedOperatorFunds function was called with a local (read-only) query.\n\n" + fmt.Sprintf("Result: %v", amount)
 
-	// set a reply messages
-	reply.Message = "getReservedOperatorFunds function was called with transaction: " + response.TransactionID.String() + "\n\n" + fmt.Sprintf("Result: %v", amount)
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+		// create reply for the RPC client
+		return nil
 
-	// create reply for the RPC client
-	return nil
+	})
 
 }
 
@@ -540,68 +507,50 @@ func (ops *ContractService) GetReservedOperatorFunds(r *http.Request, args *GetR
 
 // Method
 func (ops *ContractService) IsOperator(r *http.Request, args *IsOperatorArgs, reply *IsOperatorReply) error {
-	var err error
-	var transactionBytes []byte
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the mutex locked for a read-only query (or give up,
+	// when the request times out)
+	return _callLocked(r.Context(), false, func() error {
+		var err error
 
-	// TODO: Implement further checks and security measures
+		// TODO: Implement further checks and security measures
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
-
-	// prepare the contract object
-	contractID, err := hederasdk.ContractIDFromString(args.ContractID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-	contract := hedera.HederaSmartContract{ID: contractID}
-
-	// prepare the passed AccountID
-	accountID, err := hederasdk.AccountIDFromString(args.AccountID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-
-	// prepare the parameters for the function call
-	params, err := hederasdk.NewContractFunctionParameters().AddAddress(accountID.ToSolidityAddress())
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
 
-	// call the function
-	response, _, transactionBytes, err := contract.CallFunction("isOperator", params, args.Gas)
-	// fmt.Println("Response:", response)
-	// fmt.Println("Receipt:", receipt)
-	// fmt.Println("Error:", err)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// prepare the contract object
+		contractID, err := hederasdk.ContractIDFromString(args.ContractID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		contract := hedera.HederaSmartContract{ID: contractID}
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Contract function called with transaction: %v", response.TransactionID.String()))
+		// prepare the passed AccountID
+		accountID, err := hederasdk.AccountIDFromString(args.AccountID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
 
-	// get the result of the function call
+		// prepare the parameters for the function call
+		params, err := hederasdk.NewContractFunctionParameters().AddAddress(accountID.ToSolidityAddress())
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
 
-	record, err := response.GetRecord(hedera.Manager.NetworkClient)
-	if err != nil {
-		return fmt.Errorf("Error getting contract response record: %v", err)
-	}
+		// call the function
+		functionResult, err := contract.CallFunctionLocal("isOperator", params, args.Gas, hedera.TransactionOptions.SetContext(r.Context()))
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
 
-	functionResult, err := record.GetContractExecuteResult()
-	if err != nil {
-		return fmt.Errorf("Error getting contract execute result: %v", err)
-	}
+		// set a reply message
+		reply.Value = functionResult.GetBool(0)
+		reply.Message = "IsOperator function was called with a local (read-only) query.\n\n" + fmt.Sprintf("Result: %v", reply.Value)
 
-	// set a reply message
-	reply.Value = functionResult.GetBool(0)
-	reply.Message = "IsOperator function was called with transaction: " + response.TransactionID.String() + "\n\n" + fmt.Sprintf("Result: %v", reply.Value)
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+		// create reply for the RPC client
+		return nil
 
-	// create reply for the RPC client
-	return nil
+	})
 
 }
 
@@ -611,70 +560,55 @@ func (ops *ContractService) IsOperator(r *http.Request, args *IsOperatorArgs, re
 
 // Method
 func (ops *ContractService) GetOperatorLastActivity(r *http.Request, args *GetOperatorLastActivityArgs, reply *GetOperatorLastActivityReply) error {
-	var err error
-	var transactionBytes []byte
-
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
-
-	// TODO: Implement further checks and security measures
-
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
 
-	// prepare the contract object
-	contractID, err := hederasdk.ContractIDFromString(args.ContractID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-	contract := hedera.HederaSmartContract{ID: contractID}
+	// call the method with the mutex locked for a read-only query (or give up,
+	// when the request times out)
+	return _callLocked(r.Context(), false, func() error {
+		var err error
 
-	// prepare the passed AccountID
-	accountID, err := hederasdk.AccountIDFromString(args.AccountID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// TODO: Implement further checks and security measures
 
-	// prepare the parameters for the function call
-	params, err := hederasdk.NewContractFunctionParameters().AddAddress(accountID.ToSolidityAddress())
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
 
-	// call the function
-	response, _, transactionBytes, err := contract.CallFunction("getOperatorLastActivity", params, args.Gas)
-	// fmt.Println("Response:", response)
-	// fmt.Println("Receipt:", receipt)
-	// fmt.Println("Error:", err)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// prepare the contract object
+		contractID, err := hederasdk.ContractIDFromString(args.ContractID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		contract := hedera.HederaSmartContract{ID: contractID}
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Contract function called with transaction: %v", response.TransactionID.String()))
+		// prepare the passed AccountID
+		accountID, err := hederasdk.AccountIDFromString(args.AccountID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
 
-	// get the result of the function call
+		// prepare the parameters for the function call
+		params, err := hederasdk.NewContractFunctionParameters().AddAddress(accountID.ToSolidityAddress())
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
 
-	record, err := response.GetRecord(hedera.Manager.NetworkClient)
-	if err != nil {
-		return fmt.Errorf("Error getting contract response record: %v", err)
-	}
+		// call the function
+		functionResult, err := contract.CallFunctionLocal("getOperatorLastActivity", params, args.Gas, hedera.TransactionOptions.SetContext(r.Context()))
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
 
-	functionResult, err := record.GetContractExecuteResult()
-	if err != nil {
-		return fmt.Errorf("Error getting contract execute result: %v", err)
-	}
+		// set a reply value
+		reply.Value, err = hedera.DecodeContractInteger(functionResult, "getOperatorLastActivity", 0)
+		if err != nil {
+			return fmt.Errorf("Error decoding contract execute result: %v", err)
+		}
 
-	// set a reply value
-	reply.Value = new(big.Int).SetBytes(functionResult.GetInt256(0))
+		// set a reply message
+		reply.Message = "GetOperatorLastActivity function was called with a local (read-only) query.\n\n" + fmt.Sprintf("Result: %v", reply.Value)
 
-	// set a reply message
-	reply.Message = "GetOperatorLastActivity function was called with transaction: " + response.TransactionID.String() + "\n\n" + fmt.Sprintf("Result: %v", reply.Value)
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+		// create reply for the RPC client
+		return nil
 
-	// create reply for the RPC client
-	return nil
+	})
 
 }
 
@@ -687,71 +621,143 @@ func (ops *ContractService) GetOperatorLastActivity(r *http.Request, args *GetOp
 
 // Method
 func (ops *ContractService) AddNode(r *http.Request, args *AddNodeArgs, reply *AddNodeReply) error {
-	var err error
-	var transactionBytes []byte
-
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
-
-	// TODO: Implement further checks and security measures
-
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
-
-	// prepare the contract object
-	contractID, err := hederasdk.ContractIDFromString(args.ContractID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-	contract := hedera.HederaSmartContract{ID: contractID}
-
-	// prepare the passed NodeAccountID
-	accountID, err := hederasdk.AccountIDFromString(args.NodeAccountID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
 
-	// prepare the parameters for the function call
-	params, err := hederasdk.NewContractFunctionParameters().AddAddress(accountID.ToSolidityAddress())
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+	// call the method with the mutex locked for the preparation of the
+	// transaction bytes (or give up, when the request times out)
+	return _callLocked(r.Context(), false, func() error {
+		var err error
+		var transactionBytes []byte
+
+		// TODO: Implement further checks and security measures
+
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
+
+		// prepare the contract object
+		contractID, err := hederasdk.ContractIDFromString(args.ContractID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		contract := hedera.HederaSmartContract{ID: contractID}
+
+		// prepare the passed NodeAccountID
+		accountID, err := hederasdk.AccountIDFromString(args.NodeAccountID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+
+		// prepare the parameters for the function call
+		params, err := hederasdk.NewContractFunctionParameters().AddAddress(accountID.ToSolidityAddress())
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+
+		// add the topic ID to the parameters
+		params = params.AddString(args.TopicID)
+
+		// call the function
+		_, _, transactionBytes, err = contract.CallPayableFunction("addNode", args.NodeStake, params, args.Gas, hedera.TransactionOptions.SetExecute(false, node.Manager.User.UserAccount.AccountID))
+		hedera.Manager.AuditTransaction(hedera.AUDIT_OPERATION_STAKE_DEPOSIT, args.NodeStake, accountID.String(), transactionBytes, err)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
+
+		// // get the event log
+		// events, err := contract.GetEventLog(response, "AddedNode")
+		// if err != nil {
+		// 	return fmt.Errorf("Error: %v", err)
+		// }
 
-	// add the topic ID to the parameters
-	params = params.AddString(args.TopicID)
+		// // convert event values to usable types
+		// fmt.Println("Events:", events)
+		// callingAddress, _ := hederasdk.AccountIDFromSolidityAddress(events[0][0].(common.Address).Hex()[2:])
+		// nodeAddress, _ := hederasdk.AccountIDFromSolidityAddress(events[0][1].(common.Address).Hex()[2:])
+		// nodeTopic := events[0][2].(string)
+		// RegistrationTime := time.Unix(events[0][3].(*big.Int).Int64(), 0)
 
-	// call the function
-	_, _, transactionBytes, err = contract.CallPayableFunction("addNode", args.NodeStake, params, args.Gas, hedera.TransactionOptions.SetExecute(false, node.Manager.User.UserAccount.AccountID))
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// // log info
+		// logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Contract Event Log: 'Added Node: %v, %v, %v, %v'", callingAddress.String(), nodeAddress.String(), nodeTopic, RegistrationTime.String()))
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
+		// set a reply message
+		reply.Message = "" //"addNode function was called with transaction: " + response.TransactionID.String()
+		reply.TransactionBytes = hex.EncodeToString(transactionBytes)
 
-	// // get the event log
-	// events, err := contract.GetEventLog(response, "AddedNode")
-	// if err != nil {
-	// 	return fmt.Errorf("Error: %v", err)
-	// }
+		// create reply for the RPC client
+		return nil
 
-	// // convert event values to usable types
-	// fmt.Println("Events:", events)
-	// callingAddress, _ := hederasdk.AccountIDFromSolidityAddress(events[0][0].(common.Address).Hex()[2:])
-	// nodeAddress, _ := hederasdk.AccountIDFromSolidityAddress(events[0][1].(common.Address).Hex()[2:])
-	// nodeTopic := events[0][2].(string)
-	// RegistrationTime := time.Unix(events[0][3].(*big.Int).Int64(), 0)
+	})
 
-	// // log info
-	// logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Contract Event Log: 'Added Node: %v, %v, %v, %v'", callingAddress.String(), nodeAddress.String(), nodeTopic, RegistrationTime.String()))
+}
 
-	// set a reply message
-	reply.Message = "" //"addNode function was called with transaction: " + response.TransactionID.String()
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+// Method: createNodeTopic
+// 			- create the HCS topic of this node and optionally register the node
+// 			  with the new topic in the Renderhive Smart Contract
+// #############################################################################
 
-	// create reply for the RPC client
-	return nil
+// Method
+// NOTE: The topic is created and paid by the node account, which is its admin
+// and submitter. The addNode transaction is returned for the execution with
+// the operator wallet.
+func (ops *ContractService) CreateNodeTopic(r *http.Request, args *CreateNodeTopicArgs, reply *CreateNodeTopicReply) error {
+
+	// call the method with the locked mutex (or give up, when the request times out)
+	return _callLocked(r.Context(), true, func() error {
+		var err error
+		var transactionBytes []byte
+
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Creating the HCS topic of node %v", hedera.Manager.Operator.AccountID.String()))
+
+		// create the topic and store it in the node configuration
+		topic, _, err := hedera.Manager.CreateNodeTopic()
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		err = node.Manager.SetNodeTopic(topic.ID.String())
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		reply.TopicID = topic.ID.String()
+		reply.Message = fmt.Sprintf("Created the node topic %v ('%v').", reply.TopicID, topic.Info.TopicMemo)
+
+		// register the node with the new topic
+		if args.Register {
+
+			// prepare the contract object
+			contractID, err := hederasdk.ContractIDFromString(args.ContractID)
+			if err != nil {
+				return fmt.Errorf("Error: %v", err)
+			}
+			contract := hedera.HederaSmartContract{ID: contractID}
+
+			// prepare the parameters for the function call
+			params, err := hederasdk.NewContractFunctionParameters().AddAddress(hedera.Manager.Operator.AccountID.ToSolidityAddress())
+			if err != nil {
+				return fmt.Errorf("Error: %v", err)
+			}
+			params = params.AddString(reply.TopicID)
+
+			// call the function
+			_, _, transactionBytes, err = contract.CallPayableFunction("addNode", args.NodeStake, params, args.Gas, hedera.TransactionOptions.SetExecute(false, node.Manager.User.UserAccount.AccountID))
+			hedera.Manager.AuditTransaction(hedera.AUDIT_OPERATION_STAKE_DEPOSIT, args.NodeStake, hedera.Manager.Operator.AccountID.String(), transactionBytes, err)
+			if err != nil {
+				return fmt.Errorf("Error: %v", err)
+			}
+
+			// log info
+			logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
+
+			reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+
+		}
+
+		// create reply for the RPC client
+		return nil
+
+	})
 
 }
 
@@ -761,260 +767,392 @@ func (ops *ContractService) AddNode(r *http.Request, args *AddNodeArgs, reply *A
 
 // Method
 func (ops *ContractService) RemoveNode(r *http.Request, args *RemoveNodeArgs, reply *RemoveNodeReply) error {
-	var err error
-	var transactionBytes []byte
-
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
-
-	// TODO: Implement further checks and security measures
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
-
-	// prepare the contract object
-	contractID, err := hederasdk.ContractIDFromString(args.ContractID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-	contract := hedera.HederaSmartContract{ID: contractID}
+	// call the method with the mutex locked for the preparation of the
+	// transaction bytes (or give up, when the request times out)
+	return _callLocked(r.Context(), false, func() error {
+		var err error
+		var transactionBytes []byte
+
+		// TODO: Implement further checks and security measures
+
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
+
+		// prepare the contract object
+		contractID, err := hederasdk.ContractIDFromString(args.ContractID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		contract := hedera.HederaSmartContract{ID: contractID}
+
+		// prepare the passed NodeAccountID
+		accountID, err := hederasdk.AccountIDFromString(args.NodeAccountID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+
+		// prepare the parameters for the function call
+		params, err := hederasdk.NewContractFunctionParameters().AddAddress(accountID.ToSolidityAddress())
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+
+		// call the function
+		_, _, transactionBytes, err = contract.CallFunction("removeNode", params, args.Gas, hedera.TransactionOptions.SetExecute(false, node.Manager.User.UserAccount.AccountID))
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
+
+		// // get the event log
+		// events, err := contract.GetEventLog(response, "RemovedNode")
+		// if err != nil {
+		// 	return fmt.Errorf("Error: %v", err)
+		// }
 
-	// prepare the passed NodeAccountID
-	accountID, err := hederasdk.AccountIDFromString(args.NodeAccountID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// // convert event values to usable types
+		// fmt.Println("Events:", events)
+		// callingAddress, _ := hederasdk.AccountIDFromSolidityAddress(events[0][0].(common.Address).Hex()[2:])
+		// nodeAddress, _ := hederasdk.AccountIDFromSolidityAddress(events[0][1].(common.Address).Hex()[2:])
+		// nodeTopic := events[0][2].(string)
+		// DeletionTime := time.Unix(events[0][3].(*big.Int).Int64(), 0)
 
-	// prepare the parameters for the function call
-	params, err := hederasdk.NewContractFunctionParameters().AddAddress(accountID.ToSolidityAddress())
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// // log info
+		// logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Contract Event Log: 'Removed Node: %v, %v, %v, %v'", callingAddress.String(), nodeAddress.String(), nodeTopic, DeletionTime.String()))
 
-	// call the function
-	_, _, transactionBytes, err = contract.CallFunction("removeNode", params, args.Gas, hedera.TransactionOptions.SetExecute(false, node.Manager.User.UserAccount.AccountID))
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// set a reply message
+		reply.Message = "" //"removeNode function was called with transaction: " + response.TransactionID.String()
+		reply.TransactionBytes = hex.EncodeToString(transactionBytes)
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
+		// create reply for the RPC client
+		return nil
 
-	// // get the event log
-	// events, err := contract.GetEventLog(response, "RemovedNode")
-	// if err != nil {
-	// 	return fmt.Errorf("Error: %v", err)
-	// }
+	})
 
-	// // convert event values to usable types
-	// fmt.Println("Events:", events)
-	// callingAddress, _ := hederasdk.AccountIDFromSolidityAddress(events[0][0].(common.Address).Hex()[2:])
-	// nodeAddress, _ := hederasdk.AccountIDFromSolidityAddress(events[0][1].(common.Address).Hex()[2:])
-	// nodeTopic := events[0][2].(string)
-	// DeletionTime := time.Unix(events[0][3].(*big.Int).Int64(), 0)
+}
 
-	// // log info
-	// logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Contract Event Log: 'Removed Node: %v, %v, %v, %v'", callingAddress.String(), nodeAddress.String(), nodeTopic, DeletionTime.String()))
+// Method: isNode
+// 			- check if the given node is registered in the Renderhive Smart Contract
+// #############################################################################
 
-	// set a reply message
-	reply.Message = "" //"removeNode function was called with transaction: " + response.TransactionID.String()
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+// Method
+func (ops *ContractService) IsNode(r *http.Request, args *IsNodeArgs, reply *IsNodeReply) error {
 
-	// create reply for the RPC client
-	return nil
+	// call the method with the mutex locked for a read-only query (or give up,
+	// when the request times out)
+	return _callLocked(r.Context(), false, func() error {
+		var err error
+
+		// TODO: Implement further checks and security measures
+
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
+
+		// prepare the contract object
+		contractID, err := hederasdk.ContractIDFromString(args.ContractID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		contract := hedera.HederaSmartContract{ID: contractID}
+
+		// prepare the passed AccountIDs
+		operatorAccountID, err := hederasdk.AccountIDFromString(args.OperatorAccountID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		nodeAccountID, err := hederasdk.AccountIDFromString(args.NodeAccountID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+
+		// prepare the parameters for the function call
+		params := hederasdk.NewContractFunctionParameters()
+
+		// add operator account ID to the parameters
+		params, err = params.AddAddress(operatorAccountID.ToSolidityAddress())
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+
+		// add node account ID to the parameters
+		params, err = params.AddAddress(nodeAccountID.ToSolidityAddress())
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+
+		// call the function
+		functionResult, err := contract.CallFunctionLocal("isNode", params, args.Gas, hedera.TransactionOptions.SetContext(r.Context()))
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+
+		// set a reply message
+		reply.Value = functionResult.GetBool(0)
+		reply.Message = "IsNode function was called with a local (read-only) query.\n\n" + fmt.Sprintf("Result: %v", reply.Value)
+
+		// create reply for the RPC client
+		return nil
+
+	})
 
 }
 
-// Method: isNode
-// 			- check if the given node is registered in the Renderhive Smart Contract
+// Method: depositNodeStake
+// 			- deposit HBAR for an operator in the Renderhive Smart Contract
 // #############################################################################
 
 // Method
-func (ops *ContractService) IsNode(r *http.Request, args *IsNodeArgs, reply *IsNodeReply) error {
-	var err error
-	var transactionBytes []byte
+func (ops *ContractService) DepositNodeStake(r *http.Request, args *DepositNodeStakeArgs, reply *DepositNodeStakeReply) error {
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the mutex locked for the preparation of the
+	// transaction bytes (or give up, when the request times out)
+	return _callLocked(r.Context(), false, func() error {
+		var err error
+		var transactionBytes []byte
 
-	// TODO: Implement further checks and security measures
+		// TODO: Implement further checks and security measures
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
 
-	// prepare the contract object
-	contractID, err := hederasdk.ContractIDFromString(args.ContractID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-	contract := hedera.HederaSmartContract{ID: contractID}
+		// prepare the contract object
+		contractID, err := hederasdk.ContractIDFromString(args.ContractID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		contract := hedera.HederaSmartContract{ID: contractID}
 
-	// prepare the passed AccountIDs
-	operatorAccountID, err := hederasdk.AccountIDFromString(args.OperatorAccountID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-	nodeAccountID, err := hederasdk.AccountIDFromString(args.NodeAccountID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// prepare the passed NodeAccountID
+		nodeAccountID, err := hederasdk.AccountIDFromString(args.NodeAccountID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
 
-	// prepare the parameters for the function call
-	params := hederasdk.NewContractFunctionParameters()
+		// prepare the parameters for the function call
+		params := hederasdk.NewContractFunctionParameters()
 
-	// add operator account ID to the parameters
-	params, err = params.AddAddress(operatorAccountID.ToSolidityAddress())
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// add node account ID to the parameters
+		params, err = params.AddAddress(nodeAccountID.ToSolidityAddress())
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
 
-	// add node account ID to the parameters
-	params, err = params.AddAddress(nodeAccountID.ToSolidityAddress())
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// call the payable function
+		_, _, transactionBytes, err = contract.CallPayableFunction("depositNodeStake", args.NodeStake, params, args.Gas, hedera.TransactionOptions.SetExecute(false, node.Manager.User.UserAccount.AccountID))
+		hedera.Manager.AuditTransaction(hedera.AUDIT_OPERATION_STAKE_DEPOSIT, args.NodeStake, nodeAccountID.String(), transactionBytes, err)
+		if err != nil {
 
-	// call the function
-	response, _, transactionBytes, err := contract.CallFunction("isNode", params, args.Gas)
-	// fmt.Println("Response:", response)
-	// fmt.Println("Receipt:", receipt)
-	// fmt.Println("Error:", err)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+			return fmt.Errorf("Error (%v): %v", err, "No details available")
+		}
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Contract function called with transaction: %v", response.TransactionID.String()))
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
 
-	// get the result of the function call
-	record, err := response.GetRecord(hedera.Manager.NetworkClient)
-	if err != nil {
-		return fmt.Errorf("Error getting contract response record: %v", err)
-	}
+		// set a reply message
+		reply.Message = "" //"depositNodeStake function was called with transaction: " + response.TransactionID.String()
+		reply.TransactionBytes = hex.EncodeToString(transactionBytes)
 
-	functionResult, err := record.GetContractExecuteResult()
-	if err != nil {
-		return fmt.Errorf("Error getting contract execute result: %v", err)
-	}
+		// create reply for the RPC client
+		return nil
 
-	// set a reply message
-	reply.Value = functionResult.GetBool(0)
-	reply.Message = "IsNode function was called with transaction: " + response.TransactionID.String() + "\n\n" + fmt.Sprintf("Result: %v", reply.Value)
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+	})
 
-	// create reply for the RPC client
-	return nil
+}
+
+// Method: withdrawNodeStake
+// 			- withdraw the complete node stake from the Renderhive Smart Contract
+// #############################################################################
+
+// Method
+func (ops *ContractService) WithdrawNodeStake(r *http.Request, args *WithdrawNodeStakeArgs, reply *WithdrawNodeStakeReply) error {
+
+	// call the method with the mutex locked for the preparation of the
+	// transaction bytes (or give up, when the request times out)
+	return _callLocked(r.Context(), false, func() error {
+		var err error
+		var transactionBytes []byte
+
+		// TODO: Implement further checks and security measures
+
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
+
+		// prepare the contract object
+		contractID, err := hederasdk.ContractIDFromString(args.ContractID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		contract := hedera.HederaSmartContract{ID: contractID}
+
+		// prepare the passed NodeAccountID
+		nodeAccountID, err := hederasdk.AccountIDFromString(args.NodeAccountID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+
+		// prepare the parameters for the function call
+		params := hederasdk.NewContractFunctionParameters()
+
+		// add node account ID to the parameters
+		params, err = params.AddAddress(nodeAccountID.ToSolidityAddress())
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		// call the payable function
+		_, _, transactionBytes, err = contract.CallFunction("withdrawNodeStake", params, args.Gas, hedera.TransactionOptions.SetExecute(false, node.Manager.User.UserAccount.AccountID))
+		hedera.Manager.AuditTransaction(hedera.AUDIT_OPERATION_STAKE_WITHDRAWAL, "", nodeAccountID.String(), transactionBytes, err)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
+
+		// set a reply message
+		reply.Message = "" //"withdrawNodeStake function was called with transaction: " + response.TransactionID.String()
+		reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+
+		// create reply for the RPC client
+		return nil
+
+	})
 
 }
 
-// Method: depositNodeStake
-// 			- deposit HBAR for an operator in the Renderhive Smart Contract
+// Method: getNodeStake
+// 			- get the node stake of the Renderhive Smart Contract
 // #############################################################################
 
 // Method
-func (ops *ContractService) DepositNodeStake(r *http.Request, args *DepositNodeStakeArgs, reply *DepositNodeStakeReply) error {
-	var err error
-	var transactionBytes []byte
+func (ops *ContractService) GetNodeStake(r *http.Request, args *GetNodeStakeArgs, reply *GetNodeStakeReply) error {
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the mutex locked for a read-only query (or give up,
+	// when the request times out)
+	return _callLocked(r.Context(), false, func() error {
+		var err error
+
+		// TODO: Implement further checks and security measures
+
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
+
+		// prepare the contract object
+		contractID, err := hederasdk.ContractIDFromString(args.ContractID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		contract := hedera.HederaSmartContract{ID: contractID}
+
+		// prepare the passed NodeAccountID
+		nodeAccountID, err := hederasdk.AccountIDFromString(args.NodeAccountID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+
+		// prepare the parameters for the function call
+		params := hederasdk.NewContractFunctionParameters()
+
+		// add node account ID to the parameters
+		params, err = params.AddAddress(nodeAccountID.ToSolidityAddress())
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		// call the payable function
+		functionResult, err := contract.CallFunctionLocal("getNodeStake", params, args.Gas, hedera.TransactionOptions.SetContext(r.Context()))
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+
+		// set a reply value
+		reply.Value, err = hedera.DecodeContractInteger(functionResult, "getNodeStake", 0)
+		if err != nil {
+			return fmt.Errorf("Error decoding contract execute result: %v", err)
+		}
+
+		// convert tℏ to ℏ
+		amount, err := hederasdk.HbarFromString(reply.Value.String() + " tℏ")
+		if err != nil {
+			return fmt.Errorf("Error getting contract response record: %v", err)
+		}
+
+		// set a reply message
+		reply.Message = "getNodeStake function was called with a local (read-only) query.\n\n" + fmt.Sprintf("Result: %v", amount)
+
+		// create reply for the RPC client
+		return nil
+
+	})
 
-	// TODO: Implement further checks and security measures
+}
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
+// RENDERHIVE SMART CONTRACT – RENDER JOB MANAGEMENT
+// #############################################################################
 
-	// prepare the contract object
-	contractID, err := hederasdk.ContractIDFromString(args.ContractID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-	contract := hedera.HederaSmartContract{ID: contractID}
+// Method: addRenderJob
+// 			- add a new render job to the Renderhive Smart Contract
+// #############################################################################
 
-	// prepare the passed NodeAccountID
-	nodeAccountID, err := hederasdk.AccountIDFromString(args.NodeAccountID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+// Method
+func (ops *ContractService) AddRenderJob(r *http.Request, args *AddRenderJobArgs, reply *AddRenderJobReply) error {
 
-	// prepare the parameters for the function call
-	params := hederasdk.NewContractFunctionParameters()
+	// call the method with the mutex locked for the preparation of the
+	// transaction bytes (or give up, when the request times out)
+	return _callLocked(r.Context(), false, func() error {
+		var err error
+		var transactionBytes []byte
 
-	// add node account ID to the parameters
-	params, err = params.AddAddress(nodeAccountID.ToSolidityAddress())
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// TODO: Implement further checks and security measures
 
-	// call the payable function
-	_, _, transactionBytes, err = contract.CallPayableFunction("depositNodeStake", args.NodeStake, params, args.Gas, hedera.TransactionOptions.SetExecute(false, node.Manager.User.UserAccount.AccountID))
-	// fmt.Println("Response:", response)
-	// fmt.Println("Receipt:", receipt)
-	if err != nil {
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
 
-		// fmt.Println("Error (%v): %v", err, "No details available")
-		return fmt.Errorf("Error (%v): %v", err, "No details available")
-	}
+		// prepare the contract object
+		contractID, err := hederasdk.ContractIDFromString(args.ContractID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		contract := hedera.HederaSmartContract{ID: contractID}
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
+		// prepare the parameters for the function call
+		params := hederasdk.NewContractFunctionParameters().AddString(args.JobCID)
+		params = params.AddUint256BigInt(new(big.Int).SetUint64(args.Work))
 
-	// set a reply message
-	reply.Message = "" //"depositNodeStake function was called with transaction: " + response.TransactionID.String()
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+		// call the function
+		_, _, transactionBytes, err = contract.CallPayableFunction("addRenderJob", args.Funding, params, args.Gas, hedera.TransactionOptions.SetExecute(false, node.Manager.User.UserAccount.AccountID))
+		hedera.Manager.AuditJobFunding(hedera.AUDIT_OPERATION_JOB_FUNDING, args.JobCID, args.Funding, node.Manager.User.UserAccount.AccountID.String(), transactionBytes, err)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
 
-	// create reply for the RPC client
-	return nil
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
+
+		// set a reply message
+		reply.Message = "" //"addRenderJob function was called with transaction: " + response.TransactionID.String()
+		reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+
+		// create reply for the RPC client
+		return nil
+
+	})
 
 }
 
-// Method: withdrawNodeStake
-// 			- withdraw the complete node stake from the Renderhive Smart Contract
+// Method: topUpRenderJob
+// 			- add funding to an existing render job in the Renderhive Smart Contract
 // #############################################################################
 
 // Method
-func (ops *ContractService) WithdrawNodeStake(r *http.Request, args *WithdrawNodeStakeArgs, reply *WithdrawNodeStakeReply) error {
-	var err error
-	var transactionBytes []byte
-
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
-
-	// TODO: Implement further checks and security measures
+func (ops *ContractService) TopUpRenderJob(r *http.Request, args *TopUpRenderJobArgs, reply *TopUpRenderJobReply) error {
 
 	// log info
 	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
 
-	// prepare the contract object
-	contractID, err := hederasdk.ContractIDFromString(args.ContractID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-	contract := hedera.HederaSmartContract{ID: contractID}
-
-	// prepare the passed NodeAccountID
-	nodeAccountID, err := hederasdk.AccountIDFromString(args.NodeAccountID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-
-	// prepare the parameters for the function call
-	params := hederasdk.NewContractFunctionParameters()
-
-	// add node account ID to the parameters
-	params, err = params.AddAddress(nodeAccountID.ToSolidityAddress())
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-	// call the payable function
-	_, _, transactionBytes, err = contract.CallFunction("withdrawNodeStake", params, args.Gas, hedera.TransactionOptions.SetExecute(false, node.Manager.User.UserAccount.AccountID))
-	// fmt.Println("Response:", response)
-	// fmt.Println("Receipt:", receipt)
-	// fmt.Println("Error:", err)
+	// check the render job and prepare the transaction (under the lock of the render data)
+	transactionBytes, err := node.Manager.TopUpRenderJob(r.Context(), args.ContractID, args.JobCID, args.Amount, args.Gas)
 	if err != nil {
 		return fmt.Errorf("Error: %v", err)
 	}
@@ -1023,7 +1161,7 @@ func (ops *ContractService) WithdrawNodeStake(r *http.Request, args *WithdrawNod
 	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
 
 	// set a reply message
-	reply.Message = "" //"withdrawNodeStake function was called with transaction: " + response.TransactionID.String()
+	reply.Message = ""
 	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
 
 	// create reply for the RPC client
@@ -1031,222 +1169,298 @@ func (ops *ContractService) WithdrawNodeStake(r *http.Request, args *WithdrawNod
 
 }
 
-// Method: getNodeStake
-// 			- get the node stake of the Renderhive Smart Contract
+// Method: claimRenderJob
+// 			- claim a render job in the Renderhive Smart Contract
 // #############################################################################
 
 // Method
-func (ops *ContractService) GetNodeStake(r *http.Request, args *GetNodeStakeArgs, reply *GetNodeStakeReply) error {
-	var err error
-	var transactionBytes []byte
-
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
-
-	// TODO: Implement further checks and security measures
-
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
-
-	// prepare the contract object
-	contractID, err := hederasdk.ContractIDFromString(args.ContractID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-	contract := hedera.HederaSmartContract{ID: contractID}
-
-	// prepare the passed NodeAccountID
-	nodeAccountID, err := hederasdk.AccountIDFromString(args.NodeAccountID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-
-	// prepare the parameters for the function call
-	params := hederasdk.NewContractFunctionParameters()
-
-	// add node account ID to the parameters
-	params, err = params.AddAddress(nodeAccountID.ToSolidityAddress())
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-	// call the payable function
-	response, _, transactionBytes, err := contract.CallFunction("getNodeStake", params, args.Gas)
-	// fmt.Println("Response:", response)
-	// fmt.Println("Receipt:", receipt)
-	// fmt.Println("Error:", err)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+func (ops *ContractService) ClaimRenderJob(r *http.Request, args *ClaimRenderJobArgs, reply *ClaimRenderJobReply) error {
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Contract function called with transaction: %v", response.TransactionID.String()))
+	// call the method with the locked mutex (or give up, when the request times out)
+	return _callLocked(r.Context(), true, func() error {
+		var err error
+		var transactionBytes []byte
+
+		// TODO: Implement further checks and security measures
+
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
+
+		// prepare the contract object
+		contractID, err := hederasdk.ContractIDFromString(args.ContractID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		contract := hedera.HederaSmartContract{ID: contractID}
+
+		// the job root is computed from the work proof of the collected render result
+		var jobRootHex string
+		err = _withRenderLock(r.Context(), func() error {
+			jobRootHex, err = node.Manager.RenderJobRoot(args.JobCID)
+			return err
+		})
+		if err != nil {
+			return rpcError(err)
+		}
+		if args.JobRoot != "" && !strings.EqualFold(args.JobRoot, jobRootHex) {
+			return fmt.Errorf("Error: The job root '%v' does not match the work proof of the render job (expected: '%v').", args.JobRoot, jobRootHex)
+		}
+
+		// convert consensus root string from hex encoded string (0x15645...) to [32]bytes
+		var consensusRoot [32]byte
+		var jobRoot [32]byte
+
+		_consensusRoot, err := hex.DecodeString(strings.TrimPrefix(args.ConsensusRoot, "0x"))
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		_jobRoot, err := hex.DecodeString(strings.TrimPrefix(jobRootHex, "0x"))
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		if len(_consensusRoot) != 32 || len(_jobRoot) != 32 {
+			return fmt.Errorf("Error: %v", "_consensusRoot and _jobRoot must be 32 bytes long")
+		} else {
+			// convert to [32]byte
+			copy(consensusRoot[:], _consensusRoot)
+			copy(jobRoot[:], _jobRoot)
+
+		}
+
+		// prepare the parameters for the function call
+		params := hederasdk.NewContractFunctionParameters().AddString(args.JobCID)
+		params = params.AddUint256BigInt(new(big.Int).SetUint64(args.HiveCycle))
+		params = params.AddUint8(args.NodeCount)
+		params = params.AddUint128BigInt(new(big.Int).SetUint64(args.NodeShare))
+		params = params.AddBytes32(consensusRoot)
+		params = params.AddBytes32(jobRoot)
+
+		// call the function
+		response, _, transactionBytes, err := contract.CallFunction("claimRenderJob", params, args.Gas, hedera.TransactionOptions.SetContext(r.Context()))
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Contract function called with transaction: %v", response.TransactionID.String()))
+
+		// get the payout from the record of the settlement
+		var payout *hederasdk.Hbar
+		record, err := hedera.GetRecord(r.Context(), response)
+		if err != nil {
+			logger.Manager.Package["jsonrpc"].Warn().Msg(fmt.Sprintf("Could not get the payout of the settlement %v: %v", response.TransactionID.String(), err))
+		} else {
+			amount := hedera.SettlementPayout(record)
+			payout = &amount
+		}
+
+		// the render result names the settlement, so that the completion counts
+		// NOTE: The settlement is recorded even after a timeout, since the
+		// transaction was already executed.
+		Manager.RenderMutex.Lock()
+		node.Manager.RecordSettlement(args.JobCID, response.TransactionID.String(), payout)
+		Manager.RenderMutex.Unlock()
+
+		// // get the event log
+		// events, err := contract.GetEventLog(response, "AddedNode")
+		// if err != nil {
+		// 	return fmt.Errorf("Error: %v", err)
+		// }
 
-	// get the result of the function call
-	record, err := response.GetRecord(hedera.Manager.NetworkClient)
-	if err != nil {
-		return fmt.Errorf("Error getting contract response record: %v", err)
-	}
-	functionResult, err := record.GetContractExecuteResult()
-	if err != nil {
-		return fmt.Errorf("Error getting contract execute result: %v", err)
-	}
+		// // convert event values to usable types
+		// fmt.Println("Events:", events)
+		// callingAddress, _ := hederasdk.AccountIDFromSolidityAddress(events[0][0].(common.Address).Hex()[2:])
+		// nodeAddress, _ := hederasdk.AccountIDFromSolidityAddress(events[0][1].(common.Address).Hex()[2:])
+		// nodeTopic := events[0][2].(string)
+		// RegistrationTime := time.Unix(events[0][3].(*big.Int).Int64(), 0)
 
-	// set a reply value
-	reply.Value = new(big.Int).SetBytes(functionResult.GetInt256(0))
+		// // log info
+		// logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Contract Event Log: 'Added Node: %v, %v, %v, %v'", callingAddress.String(), nodeAddress.String(), nodeTopic, RegistrationTime.String()))
 
-	// convert tℏ to ℏ
-	amount, err := hederasdk.HbarFromString(reply.Value.String() + " tℏ")
-	if err != nil {
-		return fmt.Errorf("Error getting contract response record: %v", err)
-	}
+		// set a reply message
+		reply.Message = "claimRenderJob function was called with transaction: " + response.TransactionID.String()
+		reply.TransactionBytes = hex.EncodeToString(transactionBytes)
 
-	// set a reply message
-	reply.Message = "getNodeStake function was called with transaction: " + response.TransactionID.String() + "\n\n" + fmt.Sprintf("Result: %v", amount)
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+		// create reply for the RPC client
+		return nil
 
-	// create reply for the RPC client
-	return nil
+	})
 
 }
 
-// RENDERHIVE SMART CONTRACT – RENDER JOB MANAGEMENT
-// #############################################################################
-
-// Method: addRenderJob
-// 			- add a new render job to the Renderhive Smart Contract
+// Method: raiseDispute
+// 			- raise a dispute for a render job with disagreeing render results
 // #############################################################################
 
 // Method
-func (ops *ContractService) AddRenderJob(r *http.Request, args *AddRenderJobArgs, reply *AddRenderJobReply) error {
-	var err error
-	var transactionBytes []byte
+func (ops *ContractService) RaiseDispute(r *http.Request, args *RaiseDisputeArgs, reply *RaiseDisputeReply) error {
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the locked render data (or give up, when the request times out)
+	return _callRenderLocked(r.Context(), true, func() error {
+		var err error
 
-	// TODO: Implement further checks and security measures
-
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
 
-	// prepare the contract object
-	contractID, err := hederasdk.ContractIDFromString(args.ContractID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
-	contract := hedera.HederaSmartContract{ID: contractID}
+		// convert the render results
+		results := []node.RenderResult{}
+		for _, result := range args.Results {
+			results = append(results, node.RenderResult{
+				OperatorAccountID: result.OperatorAccountID,
+				ResultCID:         result.ResultCID,
+				FrameHashes:       result.FrameHashes,
+			})
+		}
 
-	// prepare the parameters for the function call
-	params := hederasdk.NewContractFunctionParameters().AddString(args.JobCID)
-	params = params.AddUint256BigInt(new(big.Int).SetUint64(args.Work))
+		// package the evidence and call the function
+		dispute, transactionBytes, err := node.Manager.RaiseDispute(args.ContractID, args.JobCID, results, args.Reason, args.Gas)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
 
-	// call the function
-	_, _, transactionBytes, err = contract.CallPayableFunction("addRenderJob", args.Funding, params, args.Gas, hedera.TransactionOptions.SetExecute(false, node.Manager.User.UserAccount.AccountID))
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
-	}
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Dispute evidence: %v", dispute.EvidenceCID))
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
+		// set a reply message
+		reply.Message = ""
+		reply.EvidenceCID = dispute.EvidenceCID
+		reply.TransactionBytes = hex.EncodeToString(transactionBytes)
 
-	// set a reply message
-	reply.Message = "" //"addRenderJob function was called with transaction: " + response.TransactionID.String()
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+		// create reply for the RPC client
+		return nil
 
-	// create reply for the RPC client
-	return nil
+	})
 
 }
 
-// Method: claimRenderJob
-// 			- claim a render job in the Renderhive Smart Contract
+// RENDERHIVE SMART CONTRACT – DASHBOARD
+// #############################################################################
+
+// Method: getNodeDashboard
+// 			- get the registration status and the funds of an operator and its node
+// 			  together with the current hive cycle in a single call
 // #############################################################################
 
 // Method
-func (ops *ContractService) ClaimRenderJob(r *http.Request, args *ClaimRenderJobArgs, reply *ClaimRenderJobReply) error {
-	var err error
-	var transactionBytes []byte
+// NOTE: All values are read with local (read-only) contract calls. Each read
+// is best-effort: If a read fails, its error is returned in the corresponding
+// error field and the other values are still returned.
+func (ops *ContractService) GetNodeDashboard(r *http.Request, args *GetNodeDashboardArgs, reply *GetNodeDashboardReply) error {
+
+	// call the method with the mutex locked for a read-only query (or give up,
+	// when the request times out)
+	return _callLocked(r.Context(), false, func() error {
+		var err error
+
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Reading the node dashboard from the smart contract (Gas: %v)", args.Gas))
+
+		// prepare the contract object
+		contractID, err := hederasdk.ContractIDFromString(args.ContractID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		contract := hedera.HederaSmartContract{ID: contractID}
+
+		// prepare the passed AccountIDs
+		operatorAccountID, err := hederasdk.AccountIDFromString(args.OperatorAccountID)
+		if err != nil {
+			return fmt.Errorf("Error: %v", err)
+		}
+		var nodeAccountID *hederasdk.AccountID
+		if args.NodeAccountID != "" {
+			accountID, err := hederasdk.AccountIDFromString(args.NodeAccountID)
+			if err != nil {
+				return fmt.Errorf("Error: %v", err)
+			}
+			nodeAccountID = &accountID
+		}
+
+		// operator registration and funds
+		reply.IsOperator, reply.IsOperatorError = _dashboardBool(r.Context(), &contract, "isOperator", args.Gas, operatorAccountID)
+		reply.OperatorBalance, reply.OperatorBalanceError = _dashboardInt(r.Context(), &contract, "getOperatorBalance", args.Gas, operatorAccountID)
+		reply.ReservedFunds, reply.ReservedFundsError = _dashboardInt(r.Context(), &contract, "getReservedOperatorFunds", args.Gas, operatorAccountID)
+
+		// node registration and stake
+		if nodeAccountID != nil {
+			reply.IsNode, reply.IsNodeError = _dashboardBool(r.Context(), &contract, "isNode", args.Gas, operatorAccountID, *nodeAccountID)
+			reply.NodeStake, reply.NodeStakeError = _dashboardInt(r.Context(), &contract, "getNodeStake", args.Gas, *nodeAccountID)
+		} else {
+			reply.IsNodeError = "No node account ID was given."
+			reply.NodeStakeError = reply.IsNodeError
+		}
+
+		// current hive cycle
+		reply.HiveCycle, err = contract.GetCurrentHiveCycle(args.Gas)
+		if err != nil {
+			reply.HiveCycleError = err.Error()
+		}
+
+		// set a reply message
+		reply.Message = fmt.Sprintf("Node dashboard of operator %v was read from contract %v.", args.OperatorAccountID, args.ContractID)
+
+		// create reply for the RPC client
+		return nil
+
+	})
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+}
 
-	// TODO: Implement further checks and security measures
+// INTERNAL HELPER FUNCTIONS
+// #############################################################################
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Calling a smart contract function (Gas: %v)", args.Gas))
+// helper function to call a read-only contract function with account addresses
+// as parameters
+func _dashboardCall(ctx context.Context, contract *hedera.HederaSmartContract, name string, gas uint64, accounts ...hederasdk.AccountID) (*hederasdk.ContractFunctionResult, error) {
+	var err error
 
-	// prepare the contract object
-	contractID, err := hederasdk.ContractIDFromString(args.ContractID)
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
+	// prepare the parameters for the function call
+	params := hederasdk.NewContractFunctionParameters()
+	for _, account := range accounts {
+		params, err = params.AddAddress(account.ToSolidityAddress())
+		if err != nil {
+			return nil, err
+		}
 	}
-	contract := hedera.HederaSmartContract{ID: contractID}
-
-	// convert consensus root string from hex encoded string (0x15645...) to [32]bytes
-	var consensusRoot [32]byte
-	var jobRoot [32]byte
 
-	_consensusRoot, err := hex.DecodeString(args.ConsensusRoot[2:])
+	// call the function
+	functionResult, err := contract.CallFunctionLocal(name, params, gas, hedera.TransactionOptions.SetContext(ctx))
 	if err != nil {
-		return fmt.Errorf("Error: %v", err)
+		return nil, err
 	}
-	_jobRoot, err := hex.DecodeString(args.JobRoot[2:])
-	if err != nil {
-		return fmt.Errorf("Error: %v", err)
+	if len(functionResult.ContractCallResult) < 32 {
+		return nil, fmt.Errorf("Contract function '%v' returned no result.", name)
 	}
-	if len(_consensusRoot) != 32 || len(_jobRoot) != 32 {
-		return fmt.Errorf("Error: %v", "_consensusRoot and _jobRoot must be 32 bytes long")
-	} else {
-		// convert to [32]byte
-		copy(consensusRoot[:], _consensusRoot)
-		copy(jobRoot[:], _jobRoot)
 
-	}
+	return functionResult, nil
 
-	// prepare the parameters for the function call
-	params := hederasdk.NewContractFunctionParameters().AddString(args.JobCID)
-	params = params.AddUint256BigInt(new(big.Int).SetUint64(args.HiveCycle))
-	params = params.AddUint8(args.NodeCount)
-	params = params.AddUint128BigInt(new(big.Int).SetUint64(args.NodeShare))
-	params = params.AddBytes32(consensusRoot)
-	params = params.AddBytes32(jobRoot)
+}
 
-	// call the function
-	response, _, transactionBytes, err := contract.CallFunction("claimRenderJob", params, args.Gas)
+// helper function to read a boolean of the dashboard (with its error message)
+func _dashboardBool(ctx context.Context, contract *hedera.HederaSmartContract, name string, gas uint64, accounts ...hederasdk.AccountID) (bool, string) {
+
+	functionResult, err := _dashboardCall(ctx, contract, name, gas, accounts...)
 	if err != nil {
-		return fmt.Errorf("Error: %v", err)
+		return false, err.Error()
 	}
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Contract function called with transaction: %v", response.TransactionID.String()))
-
-	// // get the event log
-	// events, err := contract.GetEventLog(response, "AddedNode")
-	// if err != nil {
-	// 	return fmt.Errorf("Error: %v", err)
-	// }
+	return functionResult.GetBool(0), ""
 
-	// // convert event values to usable types
-	// fmt.Println("Events:", events)
-	// callingAddress, _ := hederasdk.AccountIDFromSolidityAddress(events[0][0].(common.Address).Hex()[2:])
-	// nodeAddress, _ := hederasdk.AccountIDFromSolidityAddress(events[0][1].(common.Address).Hex()[2:])
-	// nodeTopic := events[0][2].(string)
-	// RegistrationTime := time.Unix(events[0][3].(*big.Int).Int64(), 0)
+}
 
-	// // log info
-	// logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Contract Event Log: 'Added Node: %v, %v, %v, %v'", callingAddress.String(), nodeAddress.String(), nodeTopic, RegistrationTime.String()))
+// helper function to read an integer of the dashboard (with its error message)
+func _dashboardInt(ctx context.Context, contract *hedera.HederaSmartContract, name string, gas uint64, accounts ...hederasdk.AccountID) (*big.Int, string) {
 
-	// set a reply message
-	reply.Message = "claimRenderJob function was called with transaction: " + response.TransactionID.String()
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+	functionResult, err := _dashboardCall(ctx, contract, name, gas, accounts...)
+	if err != nil {
+		return nil, err.Error()
+	}
+	value, err := hedera.DecodeContractInteger(functionResult, name, 0)
+	if err != nil {
+		return nil, err.Error()
+	}
 
-	// create reply for the RPC client
-	return nil
+	return value, ""
 
 }
-
-// INTERNAL HELPER FUNCTIONS
-// #############################################################################
@@ -0,0 +1,198 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package jsonrpc
+
+/*
+
+This file contains the origin validation (CORS) of the JSON-RPC server.
+
+Browsers send the origin of the webpage with each cross-origin request. Since
+the JSON-RPC server performs sensitive operations, requests from origins that
+are not allowed are rejected, before any method is called. Requests without an
+origin (e.g., from the command line) are not sent by a webpage and are passed
+on to the authentication.
+
+The Renderhive frontend is allowed by default. Other origins can be allowed in
+the optional 'cors.json' file of the configuration directory:
+
+    {"allowed_origins": ["https://localhost:5173", "https://my-frontend:8443"]}
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// HTTP methods and headers the frontend may use
+const corsAllowedMethods = "POST, GET, OPTIONS"
+const corsAllowedHeaders = "Content-Type, Content-Length, withCredentials"
+
+// CORS settings of the JSON-RPC server
+type CorsSettings struct {
+	AllowedOrigins []string `json:"allowed_origins"` // origins of the webpages that may call the server
+}
+
+// CORS SETTINGS
+// #############################################################################
+// Get the default CORS settings
+func DefaultCorsSettings() CorsSettings {
+	return CorsSettings{AllowedOrigins: append([]string{}, RENDERHIVE_CONFIG_JSONRPC_ALLOWED_ORIGINS...)}
+}
+
+// Read the CORS settings from the configuration file
+func (settings *CorsSettings) Read() error {
+	var err error
+
+	// Open the configuration file
+	file, err := os.Open(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "cors.json"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Read the file content
+	fileData, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	// get the file content into a structure
+	err = json.Unmarshal(fileData, settings)
+	if err != nil {
+		return err
+	}
+
+	return settings.Validate()
+
+}
+
+// Check the CORS settings for invalid origins
+// NOTE: An origin consists of the scheme, the host, and the (optional) port.
+func (settings *CorsSettings) Validate() error {
+
+	for _, origin := range settings.AllowedOrigins {
+		parsed, err := url.Parse(origin)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" || (parsed.Path != "" && parsed.Path != "/") || parsed.RawQuery != "" {
+			return errors.New(fmt.Sprintf("Invalid origin '%v' (expected e.g. 'https://localhost:5173').", origin))
+		}
+	}
+
+	return nil
+
+}
+
+// Check if an origin may call the JSON-RPC server
+func (settings *CorsSettings) IsAllowed(origin string) bool {
+
+	for _, allowed := range settings.AllowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// Load the CORS settings of the JSON-RPC server
+// NOTE: Falls back to the default settings, if the configuration file does not
+// exist or is invalid.
+func (jsonrpcm *PackageManager) LoadCorsSettings() error {
+	var err error
+
+	settings := DefaultCorsSettings()
+	err = settings.Read()
+	if err != nil {
+		settings = DefaultCorsSettings()
+	}
+	jsonrpcm.Cors = settings
+	if err != nil && !os.IsNotExist(err) {
+		return errors.New(fmt.Sprintf("Could not read the CORS settings (using the defaults): %v", err))
+	}
+
+	return nil
+
+}
+
+// CORS middleware handler for the router
+func (jsonrpcm *PackageManager) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		// the response depends on the origin of the request
+		w.Header().Add("Vary", "Origin")
+
+		// requests without origin were not sent by a webpage
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// reject requests of other webpages
+		if !jsonrpcm.Cors.IsAllowed(origin) {
+
+			// log event
+			logger.Manager.Package["jsonrpc"].Warn().Msg(fmt.Sprintf("Rejected a %v request from the origin '%v'.", r.Method, origin))
+
+			http.Error(w, fmt.Sprintf("Origin '%v' is not allowed", origin), http.StatusForbidden)
+			return
+
+		}
+
+		// Set CORS headers
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Methods", corsAllowedMethods)
+		w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+
+		// Handling CORS preflight request
+		if r.Method == http.MethodOptions {
+			// log event
+			logger.Manager.Package["jsonrpc"].Debug().Msg("Handling the OPTIONS Request")
+
+			// reject preflights for methods the server does not provide
+			if method := r.Header.Get("Access-Control-Request-Method"); method != "" && !strings.Contains(", "+corsAllowedMethods+", ", ", "+strings.ToUpper(method)+", ") {
+				http.Error(w, fmt.Sprintf("Method '%v' is not allowed", method), http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Access-Control-Max-Age", "600")
+
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,125 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package jsonrpc
+
+import (
+
+	// standard
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// helper function to send a request through the CORS middleware
+func _testCorsRequest(jsonrpcm *PackageManager, method string, origin string, requestMethod string) (*httptest.ResponseRecorder, bool) {
+	called := false
+	handler := jsonrpcm.corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := httptest.NewRequest(method, "/jsonrpc", nil)
+	if origin != "" {
+		request.Header.Set("Origin", origin)
+	}
+	if requestMethod != "" {
+		request.Header.Set("Access-Control-Request-Method", requestMethod)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	return recorder, called
+}
+
+func TestCorsAllowsTheListedOrigins(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+	jsonrpcm := &PackageManager{Cors: CorsSettings{AllowedOrigins: []string{"https://localhost:5173", "https://my-frontend:8443/"}}}
+
+	for _, origin := range []string{"https://localhost:5173", "https://my-frontend:8443", "HTTPS://LOCALHOST:5173"} {
+		recorder, called := _testCorsRequest(jsonrpcm, http.MethodPost, origin, "")
+		if !called || recorder.Code != http.StatusOK {
+			t.Errorf("%v: got status %v (called: %v), want the request to pass", origin, recorder.Code, called)
+		}
+		if recorder.Header().Get("Access-Control-Allow-Origin") != origin || recorder.Header().Get("Access-Control-Allow-Credentials") != "true" {
+			t.Errorf("%v: unexpected CORS headers %v", origin, recorder.Header())
+		}
+	}
+
+	// requests without origin are not sent by a webpage
+	recorder, called := _testCorsRequest(jsonrpcm, http.MethodPost, "", "")
+	if !called || recorder.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("got status %v (called: %v), want the request to pass without CORS headers", recorder.Code, called)
+	}
+}
+
+func TestCorsRejectsOtherOrigins(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+	jsonrpcm := &PackageManager{Cors: CorsSettings{AllowedOrigins: []string{"https://localhost:5173"}}}
+
+	for _, origin := range []string{"https://evil.example", "http://localhost:5173", "https://localhost:5174", "https://localhost:5173.evil.example", "null"} {
+		for _, method := range []string{http.MethodPost, http.MethodOptions} {
+			recorder, called := _testCorsRequest(jsonrpcm, method, origin, http.MethodPost)
+			if called || recorder.Code != http.StatusForbidden || recorder.Header().Get("Access-Control-Allow-Origin") != "" {
+				t.Errorf("%v %v: got status %v (called: %v), want the request to be rejected", method, origin, recorder.Code, called)
+			}
+		}
+	}
+}
+
+func TestCorsPreflight(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+	jsonrpcm := &PackageManager{Cors: DefaultCorsSettings()}
+	origin := RENDERHIVE_CONFIG_JSONRPC_ALLOWED_ORIGINS[0]
+
+	// the preflight of the frontend's requests is answered without calling a method
+	recorder, called := _testCorsRequest(jsonrpcm, http.MethodOptions, origin, http.MethodPost)
+	if called || recorder.Code != http.StatusNoContent {
+		t.Fatalf("got status %v (called: %v), want an answered preflight", recorder.Code, called)
+	}
+	if recorder.Header().Get("Access-Control-Allow-Methods") != corsAllowedMethods || recorder.Header().Get("Access-Control-Allow-Headers") != corsAllowedHeaders || recorder.Header().Get("Access-Control-Max-Age") == "" {
+		t.Errorf("unexpected preflight headers %v", recorder.Header())
+	}
+
+	// a preflight for a method the server does not provide
+	recorder, called = _testCorsRequest(jsonrpcm, http.MethodOptions, origin, http.MethodDelete)
+	if called || recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("got status %v (called: %v), want the method to be rejected", recorder.Code, called)
+	}
+}
+
+func TestCorsSettingsValidate(t *testing.T) {
+	if settings := DefaultCorsSettings(); settings.Validate() != nil {
+		t.Error("expected the default CORS settings to be valid")
+	}
+	for _, origin := range []string{"localhost:5173", "https://", "https://localhost:5173/path", "https://localhost:5173?x=1"} {
+		settings := CorsSettings{AllowedOrigins: []string{origin}}
+		if settings.Validate() == nil {
+			t.Errorf("%v: expected an invalid origin", origin)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package jsonrpc
+
+import (
+	// standard
+	"errors"
+	"fmt"
+	"testing"
+
+	// external
+	"github.com/gorilla/rpc/v2/json2"
+
+	// internal
+	"renderhive/node"
+)
+
+func TestRpcErrorMapsTheRenderErrors(t *testing.T) {
+	tests := []struct {
+		kind error
+		code json2.ErrorCode
+	}{
+		{node.ErrOfferNotFound, RPC_ERROR_NOT_FOUND},
+		{node.ErrRequestNotFound, RPC_ERROR_NOT_FOUND},
+		{node.ErrDocumentNotFound, RPC_ERROR_NOT_FOUND},
+		{node.ErrAlreadySubmitted, RPC_ERROR_CONFLICT},
+		{node.ErrAlreadyExists, RPC_ERROR_CONFLICT},
+		{node.ErrDocumentMismatch, RPC_ERROR_DOCUMENT_MISMATCH},
+		{node.ErrUnsupportedVersion, RPC_ERROR_UNSUPPORTED_VERSION},
+		{node.ErrInvalidArgument, json2.E_BAD_PARAMS},
+		{node.ErrNetworkUnavailable, RPC_ERROR_NETWORK_UNAVAILABLE},
+		{node.ErrTransactionFailed, RPC_ERROR_TRANSACTION_FAILED},
+		{node.ErrBenchmarkUnavailable, RPC_ERROR_BENCHMARK_UNAVAILABLE},
+		{node.ErrBenchmarkCanceled, RPC_ERROR_BENCHMARK_CANCELED},
+		{node.ErrJobInfeasible, RPC_ERROR_JOB_INFEASIBLE},
+		{node.ErrBlenderCrashed, RPC_ERROR_BLENDER_CRASHED},
+		{node.ErrUnsupportedSchema, RPC_ERROR_UNSUPPORTED_SCHEMA},
+		{node.ErrUntrustedScript, RPC_ERROR_UNTRUSTED_SCRIPT},
+		{node.ErrRequesterOnly, RPC_ERROR_REQUESTER_ONLY},
+		{node.ErrStorageFailed, RPC_ERROR_STORAGE_FAILED},
+	}
+	for _, test := range tests {
+		// the kind is found through the render error and further wrapping
+		err := fmt.Errorf("Could not handle the call: %w", &node.RenderError{Kind: test.kind, Message: "failed"})
+		rpcErr, ok := rpcError(err).(*json2.Error)
+		if !ok {
+			t.Fatalf("rpcError(%v) is no JSON-RPC error", test.kind)
+		}
+		if rpcErr.Code != test.code {
+			t.Errorf("rpcError(%v) = %v, want %v", test.kind, rpcErr.Code, test.code)
+		}
+		if rpcErr.Message != err.Error() {
+			t.Errorf("rpcError(%v) has the message %q, want %q", test.kind, rpcErr.Message, err.Error())
+		}
+	}
+
+	// untyped errors are server errors
+	if rpcErr := rpcError(errors.New("unknown")).(*json2.Error); rpcErr.Code != json2.E_SERVER {
+		t.Errorf("got %v for an untyped error, want %v", rpcErr.Code, json2.E_SERVER)
+	}
+}
@@ -0,0 +1,163 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package jsonrpc
+
+/*
+
+ The WebSocket endpoint, which pushes the events of the node to the frontend, so
+ that it does not need to poll the JSON-RPC server for status changes. The
+ endpoint is served by the JSON-RPC server on the route '/events'. Therefore, it
+ uses the same TLS certificate and origin validation and requires the session
+ cookie of a signed in operator. The connection is closed, when the session
+ token expires.
+
+ The client selects the event types with the 'types' query parameter (e.g.,
+ 'wss://localhost:5174/events?types=job.claimed,render.progress'). Without the
+ parameter, all events are sent. Each event is sent as a JSON text message (see
+ node/events.go for the event schema).
+
+*/
+
+import (
+
+	// standard
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	// external
+	"github.com/gorilla/websocket"
+
+	// internal
+	"renderhive/logger"
+	"renderhive/node"
+)
+
+// route of the event stream
+const eventsRoute = "/events"
+
+// time limits of the WebSocket connections
+const (
+	eventsWriteTimeout = 10 * time.Second // time to send a message to the client
+	eventsPingInterval = 30 * time.Second // interval of the keep-alive pings
+	eventsPongTimeout  = 60 * time.Second // time the client has to answer a ping
+)
+
+// EVENT STREAM
+// #############################################################################
+// Stream the node events of the requested types to a WebSocket client
+func (jsonrpcm *PackageManager) handleEvents(w http.ResponseWriter, r *http.Request) {
+
+	// get the requested event types
+	types := []string{}
+	if query := r.URL.Query().Get("types"); query != "" {
+		for _, eventType := range strings.Split(query, ",") {
+			if eventType = strings.TrimSpace(eventType); eventType != "" {
+				types = append(types, eventType)
+			}
+		}
+	}
+
+	// subscribe before the upgrade, so that unknown types are rejected with an HTTP error
+	subscription, err := node.Manager.Events.Subscribe(types)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer node.Manager.Events.Unsubscribe(subscription)
+
+	// upgrade the connection
+	// NOTE: The origin was already checked by the CORS middleware.
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return origin == "" || jsonrpcm.Cors.IsAllowed(origin)
+		},
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.Manager.Package["jsonrpc"].Warn().Msg(fmt.Sprintf("Could not open the event stream: %v", err))
+		return
+	}
+	defer conn.Close()
+
+	// log event
+	logger.Manager.Package["jsonrpc"].Debug().Msg(fmt.Sprintf("Opened an event stream for '%v' (types: %v).", r.RemoteAddr, strings.Join(types, ", ")))
+
+	// read the control messages of the client until it closes the connection
+	closed := make(chan struct{})
+	conn.SetReadDeadline(time.Now().Add(eventsPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(eventsPongTimeout))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// close the stream, when the session expires
+	expiry := time.NewTimer(time.Until(jsonrpcm.SessionToken.ExpiresAt))
+	defer expiry.Stop()
+	ping := time.NewTicker(eventsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+
+		// send the events
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+			err = conn.WriteJSON(event)
+			if err != nil {
+				return
+			}
+
+		// keep the connection alive
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(eventsWriteTimeout))
+			if err != nil {
+				return
+			}
+
+		// the session expired
+		case <-expiry.C:
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "session expired"), time.Now().Add(eventsWriteTimeout))
+			return
+
+		// the client closed the connection
+		case <-closed:
+
+			// log event
+			logger.Manager.Package["jsonrpc"].Debug().Msg(fmt.Sprintf("Closed the event stream of '%v'.", r.RemoteAddr))
+			return
+
+		}
+	}
+
+}
@@ -0,0 +1,312 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package jsonrpc
+
+/*
+
+ A lightweight health-check endpoint, which enables the monitoring of the service app
+ (e.g., by systemd or by the healthcheck of a Docker container). The endpoint is served
+ on a separate plain HTTP server, so that it does not require the TLS certificates or
+ a session token of the JSON-RPC server.
+
+*/
+
+import (
+
+	// standard
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/hedera"
+	"renderhive/ipfs"
+	"renderhive/logger"
+	"renderhive/node"
+)
+
+// HEALTH CHECK STRUCTURES
+// #############################################################################
+
+// status of the IPFS node
+type HealthIPFS struct {
+	Online bool `json:"online"`
+	Peers  int  `json:"peers"`
+}
+
+// status of the Hedera client
+type HealthHedera struct {
+	Reachable      bool   `json:"reachable"`
+	AccountID      string `json:"accountID,omitempty"`
+	Balance        string `json:"balance,omitempty"`
+	BalanceWarning bool   `json:"balanceWarning"`
+	Error          string `json:"error,omitempty"`
+}
+
+// status of the render node
+type HealthRenderer struct {
+	ActiveRenders int    `json:"activeRenders"`
+	QueuedRenders int    `json:"queuedRenders"`
+	QueueDuration string `json:"queueDuration"` // estimated render time of the queued renders
+	Error         string `json:"error,omitempty"`
+}
+
+// health-check response
+type HealthReport struct {
+	Healthy  bool           `json:"healthy"`
+	Uptime   string         `json:"uptime"`
+	IPFS     HealthIPFS     `json:"ipfs"`
+	Hedera   HealthHedera   `json:"hedera"`
+	Renderer HealthRenderer `json:"renderer"`
+}
+
+// HEALTH CHECK SERVER
+// #############################################################################
+
+// Create the health-check server on the given bind address
+// NOTE: The server is created before it is started in a goroutine, so that
+// StopHealthServer does not race with its creation.
+func (jsonrpcm *PackageManager) CreateHealthServer(address string) {
+
+	// create a small separate router for the health-check
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", jsonrpcm.healthHandler)
+
+	// prepare the server
+	jsonrpcm.healthMutex.Lock()
+	jsonrpcm.HealthServer = &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	jsonrpcm.healthMutex.Unlock()
+
+}
+
+// Start the health-check server created by CreateHealthServer
+func (jsonrpcm *PackageManager) StartHealthServer() error {
+
+	jsonrpcm.healthMutex.Lock()
+	server := jsonrpcm.HealthServer
+	jsonrpcm.healthMutex.Unlock()
+	if server == nil {
+		return fmt.Errorf("The health-check server was not created.")
+	}
+
+	// log event
+	logger.Manager.Package["jsonrpc"].Debug().Msg(fmt.Sprintf("Health-check server starting on '%v' ...", server.Addr))
+
+	// start the server
+	return server.ListenAndServe()
+
+}
+
+// Stop the health-check server
+func (jsonrpcm *PackageManager) StopHealthServer() {
+
+	// if the server was not started
+	jsonrpcm.healthMutex.Lock()
+	server := jsonrpcm.HealthServer
+	jsonrpcm.healthMutex.Unlock()
+	if server == nil {
+		return
+	}
+
+	// shutdown the server gracefully
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Manager.Package["jsonrpc"].Error().Msg(fmt.Sprintf("Health-check server shutdown failed: %v", err))
+	}
+
+}
+
+// Get the health status of all subsystems
+func (jsonrpcm *PackageManager) GetHealthReport() HealthReport {
+	var report HealthReport
+
+	// uptime of the service app
+	report.Uptime = time.Since(jsonrpcm.StartTime).Round(time.Second).String()
+
+	// IPFS
+	if ipfs.Manager.IpfsNode != nil {
+		report.IPFS.Online = ipfs.Manager.IpfsNode.IsOnline
+		if peers, err := ipfs.Manager.GetConnectedPeers(); err == nil {
+			report.IPFS.Peers = len(peers)
+		}
+	}
+
+	// Hedera
+	report.Hedera = jsonrpcm._getHealthHedera()
+
+	// render node (read under the lock of the render data, which is only
+	// awaited shortly, so that a long-running method does not stall the check)
+	ctx, cancel := context.WithTimeout(context.Background(), RENDERHIVE_CONFIG_HEALTH_LOCK_TIMEOUT)
+	defer cancel()
+	if err := jsonrpcm.RenderMutex.RLockContext(ctx); err != nil {
+		report.Renderer.Error = err.Error()
+	} else {
+		if node.Manager.Renderer.Busy {
+			report.Renderer.ActiveRenders = 1
+		}
+		report.Renderer.QueuedRenders = len(node.Manager.Renderer.NodeQueue)
+		report.Renderer.QueueDuration = node.Manager.QueueDuration().Round(time.Second).String()
+		jsonrpcm.RenderMutex.RUnlock()
+	}
+
+	// IPFS and Hedera are the critical subsystems
+	report.Healthy = report.IPFS.Online && report.Hedera.Reachable
+
+	return report
+
+}
+
+// HTTP handler for the health-check endpoint
+func (jsonrpcm *PackageManager) healthHandler(w http.ResponseWriter, r *http.Request) {
+
+	// get the health report
+	report := jsonrpcm.GetHealthReport()
+
+	// respond with 503, if a critical subsystem is down
+	w.Header().Set("Content-Type", "application/json")
+	if report.Healthy {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+
+}
+
+// helper function to get the status of the Hedera client, which is only
+// queried again after RENDERHIVE_CONFIG_HEALTH_HEDERA_INTERVAL
+// NOTE: Concurrent health-checks wait for the running query instead of
+// starting their own. A query, which does not finish within the timeout, is
+// reported as unreachable, while it keeps running in the background.
+func (jsonrpcm *PackageManager) _getHealthHedera() HealthHedera {
+
+	jsonrpcm.healthHedera.Lock()
+
+	// use the cached status
+	if !jsonrpcm.healthHedera.Timestamp.IsZero() && time.Since(jsonrpcm.healthHedera.Timestamp) < RENDERHIVE_CONFIG_HEALTH_HEDERA_INTERVAL {
+		status := jsonrpcm.healthHedera.Status
+		jsonrpcm.healthHedera.Unlock()
+		return status
+	}
+
+	// query the network, if no other health-check does it already
+	timeout := jsonrpcm.healthHedera.timeout
+	if timeout == 0 {
+		timeout = RENDERHIVE_CONFIG_HEALTH_HEDERA_TIMEOUT
+	}
+	if jsonrpcm.healthHedera.running == nil {
+		check := jsonrpcm.checkHedera
+		if check == nil {
+			check = _checkHedera
+		}
+		running := make(chan struct{})
+		jsonrpcm.healthHedera.running = running
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			status := check(ctx)
+
+			jsonrpcm.healthHedera.Lock()
+			jsonrpcm.healthHedera.Status = status
+			jsonrpcm.healthHedera.Timestamp = time.Now()
+			jsonrpcm.healthHedera.running = nil
+			jsonrpcm.healthHedera.Unlock()
+			close(running)
+		}()
+	}
+	running := jsonrpcm.healthHedera.running
+	jsonrpcm.healthHedera.Unlock()
+
+	// wait for the query without holding the lock
+	select {
+	case <-running:
+	case <-time.After(timeout):
+		return HealthHedera{Error: fmt.Sprintf("The Hedera network did not respond within %v.", timeout)}
+	}
+
+	jsonrpcm.healthHedera.Lock()
+	defer jsonrpcm.healthHedera.Unlock()
+
+	return jsonrpcm.healthHedera.Status
+
+}
+
+// helper function to query the status of the Hedera client from the network
+// NOTE: The status of a query, which did not finish before the context was
+// done, is reported as unreachable.
+func _checkHedera(ctx context.Context) HealthHedera {
+	var status HealthHedera
+
+	if hedera.Manager.NetworkClient == nil {
+		return status
+	}
+
+	// if an operator account was loaded, query its balance (free of charge),
+	// otherwise ping any of the network nodes
+	if hedera.Manager.NetworkClient.GetOperatorAccountID().String() != "0.0.0" {
+		var balance hederasdk.AccountBalance
+		var err error
+		awaitErr := hedera.Await(ctx, func() {
+			balance, err = hederasdk.NewAccountBalanceQuery().
+				SetAccountID(hedera.Manager.NetworkClient.GetOperatorAccountID()).
+				Execute(hedera.Manager.NetworkClient)
+		})
+		if awaitErr != nil {
+			status.Error = fmt.Sprintf("The Hedera network did not respond: %v", awaitErr)
+		} else if err != nil {
+			status.Error = err.Error()
+		} else {
+			status.Reachable = true
+			status.AccountID = hedera.Manager.NetworkClient.GetOperatorAccountID().String()
+			status.Balance = balance.Hbars.String()
+			status.BalanceWarning = balance.Hbars.As(hederasdk.HbarUnits.Hbar) < RENDERHIVE_CONFIG_HEALTH_MINIMUM_BALANCE
+		}
+	} else {
+		for _, nodeAccountID := range hedera.Manager.NetworkClient.GetNetwork() {
+			var err error
+			awaitErr := hedera.Await(ctx, func() {
+				err = hedera.Manager.NetworkClient.Ping(nodeAccountID)
+			})
+			if awaitErr != nil {
+				status.Error = fmt.Sprintf("The Hedera network did not respond: %v", awaitErr)
+			} else if err != nil {
+				status.Error = err.Error()
+			} else {
+				status.Reachable = true
+			}
+			break
+		}
+	}
+
+	return status
+
+}
@@ -0,0 +1,151 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package jsonrpc
+
+import (
+
+	// standard
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	// internal
+	"renderhive/logger"
+	"renderhive/node"
+)
+
+func TestHealthReportCachesHederaStatus(t *testing.T) {
+	var calls int32
+	jsonrpcm := &PackageManager{RenderMutex: &node.RenderMutex{}}
+	jsonrpcm.checkHedera = func(ctx context.Context) HealthHedera {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return HealthHedera{Reachable: true, Balance: "5 ℏ"}
+	}
+
+	// concurrent checks share a single query of the network
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report := jsonrpcm.GetHealthReport()
+			if !report.Hedera.Reachable || report.Hedera.Balance != "5 ℏ" {
+				t.Errorf("unexpected Hedera status: %+v", report.Hedera)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Fatalf("got %v queries, want 1", calls)
+	}
+
+	// an outdated status is queried again
+	jsonrpcm.healthHedera.Timestamp = time.Now().Add(-2 * time.Minute)
+	jsonrpcm.GetHealthReport()
+	if calls != 2 {
+		t.Errorf("got %v queries, want 2", calls)
+	}
+}
+
+func TestHealthReportDoesNotWaitForTheLock(t *testing.T) {
+	jsonrpcm := &PackageManager{RenderMutex: &node.RenderMutex{}}
+	jsonrpcm.checkHedera = func(ctx context.Context) HealthHedera { return HealthHedera{Reachable: true} }
+
+	// a long-running method holds the lock of the render data
+	jsonrpcm.RenderMutex.Lock()
+	defer jsonrpcm.RenderMutex.Unlock()
+
+	start := time.Now()
+	report := jsonrpcm.GetHealthReport()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("the health-check took %v", elapsed)
+	}
+	if report.Renderer.Error == "" {
+		t.Error("expected an error for the renderer status")
+	}
+}
+
+func TestHealthReportDoesNotWaitForAHangingHederaQuery(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+
+	// the query of the Hedera network hangs until the test ends
+	var calls int32
+	release := make(chan struct{})
+	defer close(release)
+	jsonrpcm := &PackageManager{RenderMutex: &node.RenderMutex{}}
+	jsonrpcm.healthHedera.timeout = 50 * time.Millisecond
+	jsonrpcm.checkHedera = func(ctx context.Context) HealthHedera {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return HealthHedera{Reachable: true}
+	}
+
+	// the check reports the network as unreachable after the timeout
+	start := time.Now()
+	report := jsonrpcm.GetHealthReport()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("the health-check took %v", elapsed)
+	}
+	if report.Hedera.Reachable || report.Hedera.Error == "" || report.Healthy {
+		t.Errorf("expected an unreachable Hedera network, got %+v", report.Hedera)
+	}
+
+	// further checks do not start another query, while the query is running
+	jsonrpcm.GetHealthReport()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("got %v queries, want 1", n)
+	}
+
+	// the health-check server can be stopped during the query
+	jsonrpcm.CreateHealthServer("127.0.0.1:0")
+	stopped := make(chan struct{})
+	go func() {
+		jsonrpcm.StopHealthServer()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the health-check server could not be stopped during the query")
+	}
+}
+
+func TestHealthServerIsCreatedBeforeStart(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+	jsonrpcm := &PackageManager{}
+
+	// the server can be stopped before its goroutine started it
+	jsonrpcm.CreateHealthServer("127.0.0.1:0")
+	if jsonrpcm.HealthServer == nil {
+		t.Fatal("the health-check server was not created")
+	}
+	jsonrpcm.StopHealthServer()
+
+	// a stopped server does not start anymore
+	if err := jsonrpcm.StartHealthServer(); err == nil {
+		t.Error("expected the stopped server not to start")
+	}
+}
@@ -0,0 +1,139 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package jsonrpc
+
+/*
+
+  The IPFS service enables the management of the pins of the local IPFS node via the JSON-RPC.
+
+*/
+
+import (
+
+	// standard
+	"fmt"
+	"net/http"
+
+	// external
+	"github.com/gorilla/rpc/v2/json2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/ipfs"
+	"renderhive/utility"
+)
+
+// SERVICE INITIALIZATION
+// #############################################################################
+
+// export the IpfsService for net/rpc
+type IpfsService struct{}
+
+// RENDERHIVE IPFS SERVICE – PINS
+// #############################################################################
+
+// Method: PinObject
+// 			- pin an object on the local IPFS node
+// #############################################################################
+
+// Method
+// NOTE: The mutex is not locked, since the object may have to be fetched from the network.
+func (is *IpfsService) PinObject(r *http.Request, args *PinObjectArgs, reply *PinObjectReply) error {
+
+	// check the CID
+	cid, err := utility.ParseCID(args.CID)
+	if err != nil {
+		return &json2.Error{Code: json2.E_BAD_PARAMS, Message: err.Error()}
+	}
+
+	// pin the object (or give up, when the request times out)
+	pinned, err := ipfs.Manager.PinObjectWithModeContext(r.Context(), cid.String(), !args.Direct)
+	if err != nil {
+		return &json2.Error{Code: json2.E_SERVER, Message: fmt.Sprintf("Could not pin object: %v", err)}
+	}
+
+	// create reply for the RPC client
+	reply.CID = cid.String()
+	reply.Pinned = pinned
+
+	return nil
+
+}
+
+// Method: UnpinObject
+// 			- unpin an object on the local IPFS node
+// #############################################################################
+
+// Method
+func (is *IpfsService) UnpinObject(r *http.Request, args *UnpinObjectArgs, reply *UnpinObjectReply) error {
+
+	// call the method with the locked mutex (or give up, when the request times out)
+	return _callLocked(r.Context(), true, func() error {
+
+		// check the CID
+		cid, err := utility.ParseCID(args.CID)
+		if err != nil {
+			return &json2.Error{Code: json2.E_BAD_PARAMS, Message: err.Error()}
+		}
+
+		// unpin the object
+		pinned, err := ipfs.Manager.UnPinObjectWithMode(cid.String(), !args.Direct)
+		if err != nil {
+			return &json2.Error{Code: json2.E_SERVER, Message: fmt.Sprintf("Could not unpin object: %v", err)}
+		}
+
+		// create reply for the RPC client
+		reply.CID = cid.String()
+		reply.Pinned = pinned
+
+		return nil
+
+	})
+
+}
+
+// Method: ListPins
+// 			- list the objects pinned on the local IPFS node
+// #############################################################################
+
+// Method
+func (is *IpfsService) ListPins(r *http.Request, args *ListPinsArgs, reply *ListPinsReply) error {
+
+	// call the method with the locked mutex (or give up, when the request times out)
+	return _callLocked(r.Context(), true, func() error {
+
+		// get the pins
+		pins, err := ipfs.Manager.ListPins(args.Type)
+		if err != nil {
+			return &json2.Error{Code: json2.E_BAD_PARAMS, Message: err.Error()}
+		}
+
+		// create reply for the RPC client
+		reply.Pins = []PinItem{}
+		for _, pin := range pins {
+			reply.Pins = append(reply.Pins, PinItem{CID: pin.CID, Type: pin.Type})
+		}
+
+		return nil
+
+	})
+
+}
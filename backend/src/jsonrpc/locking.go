@@ -0,0 +1,128 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package jsonrpc
+
+/*
+
+This file contains the lock of the JSON-RPC methods.
+
+The lock protects the session and the data of the operator, which the methods
+read and change (e.g., the user account, the node data, and the sign-in state),
+and the sequence of the transactions the node submits for its operator (e.g.,
+the deploy of a smart contract or the creation of the node topic). It does NOT
+protect the render data (e.g., the render offers, requests, and jobs), which is
+locked by the lock of the render data (see node/locking.go), nor the Hedera
+client, the transaction history, or the metrics, which synchronize themselves.
+
+Methods that only query the smart contract (e.g., the balance of an operator)
+or only prepare the transaction bytes for the signature of the user's wallet
+(e.g., a deposit) neither change the state of the node nor submit a transaction.
+They take a shared (read) lock and run in parallel. Methods that submit a
+transaction for the operator or change the session take the exclusive lock,
+which waits for the running queries and blocks new ones. Waiting methods are
+served in the order they arrived, so a stream of queries cannot starve a
+state-changing method.
+
+Methods that also read or change the render data take the lock of the render
+data AFTER the lock of the JSON-RPC methods, never the other way around.
+
+*/
+import (
+
+	// standard
+	"context"
+	"fmt"
+	"sync"
+
+	// external
+	"golang.org/x/sync/semaphore"
+
+	// internal
+	. "renderhive/globals"
+)
+
+// Read-write lock of the JSON-RPC methods, which can be locked with a context
+// NOTE: The zero value is an unlocked lock.
+type RequestMutex struct {
+	once      sync.Once
+	semaphore *semaphore.Weighted
+}
+
+// Lock of a JSON-RPC method (i.e., the lock of the JSON-RPC methods or the lock
+// of the render data)
+type methodMutex interface {
+	LockContext(ctx context.Context) error
+	Unlock()
+	RLockContext(ctx context.Context) error
+	RUnlock()
+}
+
+// REQUEST LOCK
+// #############################################################################
+// Lock the mutex exclusively
+func (m *RequestMutex) Lock() {
+	m._semaphore().Acquire(context.Background(), RENDERHIVE_CONFIG_JSONRPC_MAX_PARALLEL_QUERIES)
+}
+
+// Lock the mutex exclusively or give up, when the context is done
+func (m *RequestMutex) LockContext(ctx context.Context) error {
+
+	err := m._semaphore().Acquire(ctx, RENDERHIVE_CONFIG_JSONRPC_MAX_PARALLEL_QUERIES)
+	if err != nil {
+		return fmt.Errorf("The request timed out, while waiting for another request: %w", err)
+	}
+
+	return nil
+
+}
+
+// Unlock the exclusively locked mutex
+func (m *RequestMutex) Unlock() {
+	m._semaphore().Release(RENDERHIVE_CONFIG_JSONRPC_MAX_PARALLEL_QUERIES)
+}
+
+// Lock the mutex for a read-only query or give up, when the context is done
+func (m *RequestMutex) RLockContext(ctx context.Context) error {
+
+	err := m._semaphore().Acquire(ctx, 1)
+	if err != nil {
+		return fmt.Errorf("The request timed out, while waiting for another request: %w", err)
+	}
+
+	return nil
+
+}
+
+// Unlock the mutex after a read-only query
+func (m *RequestMutex) RUnlock() {
+	m._semaphore().Release(1)
+}
+
+// helper function to get the semaphore of the mutex
+func (m *RequestMutex) _semaphore() *semaphore.Weighted {
+
+	m.once.Do(func() {
+		m.semaphore = semaphore.NewWeighted(RENDERHIVE_CONFIG_JSONRPC_MAX_PARALLEL_QUERIES)
+	})
+
+	return m.semaphore
+
+}
@@ -0,0 +1,126 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package jsonrpc
+
+import (
+	// standard
+	"context"
+	"testing"
+	"time"
+
+	// internal
+	"renderhive/node"
+)
+
+func TestContractCallsDoNotWaitForTheRenderData(t *testing.T) {
+	renderMutex := Manager.RenderMutex
+	Manager.RenderMutex = &node.RenderMutex{}
+	defer func() { Manager.RenderMutex = renderMutex }()
+
+	// the render worker holds the lock of the render data
+	Manager.RenderMutex.Lock()
+	defer Manager.RenderMutex.Unlock()
+
+	// the preparation of transaction bytes and exclusive calls still run
+	for _, exclusive := range []bool{false, true} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err := _callLocked(ctx, exclusive, func() error { return nil })
+		cancel()
+		if err != nil {
+			t.Fatalf("call (exclusive: %v) waited for the render data: %v", exclusive, err)
+		}
+	}
+
+	// methods of the node service wait for the render data
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	called := false
+	err := _callRenderLocked(ctx, false, func() error {
+		called = true
+		return nil
+	})
+	if err == nil || called {
+		t.Fatalf("got %v (called: %v), want a timeout error", err, called)
+	}
+}
+
+func TestPreparedTransactionsRunInParallel(t *testing.T) {
+
+	// two calls, which only prepare transaction bytes, hold the lock together
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- _callLocked(context.Background(), false, func() error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := _callLocked(ctx, false, func() error { return nil }); err != nil {
+		t.Fatalf("the second call waited for the first one: %v", err)
+	}
+
+	// an exclusive call waits for the running calls
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := _callLocked(ctx, true, func() error { return nil }); err == nil {
+		t.Fatalf("the exclusive call did not wait for the running call")
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Benchmark of parallel balance queries with the shared lock and with the
+// exclusive lock (each query waits 1 ms for the network)
+func BenchmarkRequestMutexParallelQueries(b *testing.B) {
+	query := func() { time.Sleep(time.Millisecond) }
+
+	b.Run("shared", func(b *testing.B) {
+		var mutex RequestMutex
+		b.SetParallelism(16)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				mutex.RLockContext(context.Background())
+				query()
+				mutex.RUnlock()
+			}
+		})
+	})
+
+	b.Run("exclusive", func(b *testing.B) {
+		var mutex RequestMutex
+		b.SetParallelism(16)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				mutex.Lock()
+				query()
+				mutex.Unlock()
+			}
+		})
+	})
+}
@@ -31,13 +31,17 @@ import (
 	// standard
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	// external
 	//  hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+	"github.com/gorilla/rpc/v2/json2"
 
 	// internal
 	. "renderhive/globals"
@@ -62,52 +66,63 @@ type NodeService struct{}
 
 // Method
 func (ops *NodeService) CreateRenderOffer(r *http.Request, args *CreateRenderOfferArgs, reply *CreateRenderOfferReply) error {
-	var err error
-	var offerCID string
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the locked render data (or give up, when the request times out)
+	return _callRenderLocked(r.Context(), true, func() error {
+		var err error
+		var offerCID string
 
-	// TODO: Implement further checks and security measures
+		// TODO: Implement further checks and security measures
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Creating a new render offer"))
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Creating a new render offer"))
 
-	// create the render offer
-	offer, err := node.Manager.NewRenderOffer(args.Price)
-	if err != nil {
-		return fmt.Errorf("Could not create new render offer: %v", err)
-	}
+		// create the render offer
+		offer, err := node.Manager.NewRenderOffer(args.Price)
+		if err != nil {
+			return rpcError(fmt.Errorf("Could not create new render offer: %w", err))
+		}
+
+		// iterate over the Blender versions and add them to the offer
+		for _, blender := range args.BlenderVersions {
+
+			// add the blender version to the offer
+			err = offer.AddBlenderVersion(blender.Version, &blender.Engines, &blender.FeatureSets, &blender.Devices, blender.Threads)
+			if err != nil {
+				return rpcError(fmt.Errorf("Could not add blender version to render offer: %w", err))
+			}
 
-	// iterate over the Blender versions and add them to the offer
-	for _, blender := range args.BlenderVersions {
+			// render a quick benchmark for an initial render score
+			if blender.Benchmark {
+				err = offer.QuickBenchmark(node.Manager.Context(), blender.Version)
+				if err != nil {
+					logger.Manager.Package["jsonrpc"].Warn().Msg(fmt.Sprintf(" [#] Could not render the quick benchmark: %v", err))
+				}
+			}
+
+			// log info
+			logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Blender version '%v' added to render offer", blender.Version))
 
-		// add the blender version to the offer
-		err = offer.AddBlenderVersion(blender.Version, &blender.Engines, &blender.Devices, blender.Threads)
+		}
+
+		// deploy the render offer to the local IPFS
+		offer.IPNSKey = args.IPNSKey
+		offerCID, err = offer.Deploy()
 		if err != nil {
-			return fmt.Errorf("Could not add blender version to render offer: %v", err)
+			return rpcError(fmt.Errorf("Could not deploy the render offer: %w", err))
 		}
+		reply.IPNSName = offer.IPNSName
 
 		// log info
-		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Blender version '%v' added to render offer", blender.Version))
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Render offer document uploaded with CID: %v", offer.DocumentCID))
 
-	}
+		// set a reply message
+		reply.Message = "New render offer was created locally: http://localhost:5001/ipfs/" + offerCID + "!"
 
-	// deploy the render offer to the local IPFS
-	offerCID, err = offer.Deploy()
-	if err != nil {
-		return fmt.Errorf("Could not deploy the render offer: %v", err)
-	}
+		// create reply for the RPC client
+		return nil
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Render offer document uploaded with CID: %v", offer.DocumentCID))
-
-	// set a reply message
-	reply.Message = "New render offer was created locally: http://localhost:5001/ipfs/" + offerCID + "!"
-
-	// create reply for the RPC client
-	return nil
+	})
 
 }
 
@@ -117,40 +132,41 @@ func (ops *NodeService) CreateRenderOffer(r *http.Request, args *CreateRenderOff
 
 // Method
 func (ops *NodeService) SubmitRenderOffer(r *http.Request, args *SubmitRenderOfferArgs, reply *SubmitRenderOfferReply) error {
-	var err error
-	var transactionBytes []byte
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the locked render data (or give up, when the request times out)
+	return _callRenderLocked(r.Context(), true, func() error {
+		var err error
+		var transactionBytes []byte
 
-	// TODO: Implement further checks and security measures
+		// TODO: Implement further checks and security measures
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Submitting a render offer to the renderhive network"))
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Submitting a render offer to the renderhive network"))
 
-	// deploy the render offer to the local IPFS
-	offer, err := node.Manager.GetRenderOffer(args.RenderOfferCID)
-	if err != nil {
-		return fmt.Errorf("Failed to submit render offer: %v", err)
-	}
+		// deploy the render offer to the local IPFS
+		offer, err := node.Manager.GetRenderOffer(args.RenderOfferCID)
+		if err != nil {
+			return rpcError(fmt.Errorf("Failed to submit render offer: %w", err))
+		}
 
-	// submit the render offer to the network
-	_, transactionBytes, err = offer.Submit()
-	if err != nil {
-		return fmt.Errorf("Failed to submit render offer: %v", err)
-	}
+		// submit the render offer to the network
+		_, transactionBytes, err = offer.Submit()
+		if err != nil {
+			return rpcError(fmt.Errorf("Failed to submit render offer: %w", err))
+		}
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
 
-	// set a reply message
-	// networkName, _ := hedera.Manager.NetworkClient.GetLedgerID().ToNetworkName()
-	reply.Message = "" //"Render offer was successfully submitted: http://hashscan.io/" + networkName.String() + "/transaction/" + receipt.TransactionID.String() + "!"
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+		// set a reply message
+		// networkName, _ := hedera.Manager.NetworkClient.GetLedgerID().ToNetworkName()
+		reply.Message = "" //"Render offer was successfully submitted: http://hashscan.io/" + networkName.String() + "/transaction/" + receipt.TransactionID.String() + "!"
+		reply.TransactionBytes = hex.EncodeToString(transactionBytes)
 
-	// create reply for the RPC client
-	return nil
+		// create reply for the RPC client
+		return nil
+
+	})
 
 }
 
@@ -160,39 +176,163 @@ func (ops *NodeService) SubmitRenderOffer(r *http.Request, args *SubmitRenderOff
 
 // Method
 func (ops *NodeService) PauseRenderOffer(r *http.Request, args *PauseRenderOfferArgs, reply *PauseRenderOfferReply) error {
-	var err error
-	var transactionBytes []byte
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the locked render data (or give up, when the request times out)
+	return _callRenderLocked(r.Context(), true, func() error {
+		var err error
+		var transactionBytes []byte
+
+		// TODO: Implement further checks and security measures
+
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Submitting a render offer to the renderhive network"))
+
+		// deploy the render offer to the local IPFS
+		offer, err := node.Manager.GetRenderOffer(args.RenderOfferCID)
+		if err != nil {
+			return rpcError(fmt.Errorf("Failed to submit render offer: %w", err))
+		}
+
+		// pause the render offer
+		_, transactionBytes, err = offer.Pause()
+		if err != nil {
+			return rpcError(fmt.Errorf("Failed to submit render offer: %w", err))
+		}
+
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
 
-	// TODO: Implement further checks and security measures
+		// set a reply message
+		// networkName, _ := hedera.Manager.NetworkClient.GetLedgerID().ToNetworkName()
+		reply.Message = "" //"Render offer was successfully submitted: http://hashscan.io/" + networkName.String() + "/transaction/" + receipt.TransactionID.String() + "!"
+		reply.TransactionBytes = hex.EncodeToString(transactionBytes)
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Submitting a render offer to the renderhive network"))
+		// create reply for the RPC client
+		return nil
 
-	// deploy the render offer to the local IPFS
-	offer, err := node.Manager.GetRenderOffer(args.RenderOfferCID)
+	})
+
+}
+
+// Method: ListRenderOffers
+// 			- list the render offers of the local node page by page
+// #############################################################################
+
+// Method
+func (ops *NodeService) ListRenderOffers(r *http.Request, args *ListRenderOffersArgs, reply *ListRenderOffersReply) error {
+
+	// call the method with the locked render data (or give up, when the request times out)
+	return _callRenderLocked(r.Context(), true, func() error {
+
+		// get the page of render offers
+		offers, total, err := node.Manager.ListRenderOffers(renderListFilter(&args.RenderListArgs))
+		if err != nil {
+			return rpcError(fmt.Errorf("Failed to list render offers: %w", err))
+		}
+
+		// create reply for the RPC client
+		reply.Total = total
+		reply.Offers = []RenderOfferListItem{}
+		for _, offer := range offers {
+			item := RenderOfferListItem{
+				DocumentCID:      offer.DocumentCID,
+				State:            offer.RepositoryState(),
+				BlenderVersions:  []string{},
+				Price:            offer.Price,
+				CreatedTimestamp: offer.CreatedTimestamp.Unix(),
+			}
+			for _, blender := range offer.BlenderVersions {
+				item.BlenderVersions = append(item.BlenderVersions, blender.Version)
+			}
+			if offer.Owner != nil {
+				item.Owner = offer.Owner.String()
+			}
+			reply.Offers = append(reply.Offers, item)
+		}
+
+		return nil
+
+	})
+
+}
+
+// Method: GetOperatorOffers
+// 			- get the active render offers of an operator in the render hive
+// #############################################################################
+
+// Method
+// NOTE: The mutex is not locked, since the download of the render offer documents may take a while.
+func (ops *NodeService) GetOperatorOffers(r *http.Request, args *GetOperatorOffersArgs, reply *GetOperatorOffersReply) error {
+
+	// get the render offers of the operator
+	offers, err := node.Manager.GetOperatorOffers(args.AccountID)
 	if err != nil {
-		return fmt.Errorf("Failed to submit render offer: %v", err)
+		return rpcError(fmt.Errorf("Failed to get the render offers of the operator: %w", err))
 	}
 
-	// pause the render offer
-	_, transactionBytes, err = offer.Pause()
-	if err != nil {
-		return fmt.Errorf("Failed to submit render offer: %v", err)
+	// create reply for the RPC client
+	reply.AccountID = args.AccountID
+	reply.Offers = []OperatorOfferItem{}
+	for _, offer := range offers {
+		item := OperatorOfferItem{
+			DocumentCID:        offer.DocumentCID,
+			BlenderVersions:    []string{},
+			Price:              offer.Price,
+			CreatedTimestamp:   offer.CreatedTimestamp.Unix(),
+			SubmittedTimestamp: offer.SubmittedTimestamp.Unix(),
+		}
+		for _, blender := range offer.BlenderVersions {
+			item.BlenderVersions = append(item.BlenderVersions, blender.Version)
+		}
+		reply.Offers = append(reply.Offers, item)
 	}
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
+	return nil
+
+}
+
+// Method: GetNetworkCapacity
+// 			- estimate the render capacity of the render hive
+// #############################################################################
+
+// Method
+func (ops *NodeService) GetNetworkCapacity(r *http.Request, args *GetNetworkCapacityArgs, reply *GetNetworkCapacityReply) error {
 
-	// set a reply message
-	// networkName, _ := hedera.Manager.NetworkClient.GetLedgerID().ToNetworkName()
-	reply.Message = "" //"Render offer was successfully submitted: http://hashscan.io/" + networkName.String() + "/transaction/" + receipt.TransactionID.String() + "!"
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+	// estimate the network capacity
+	capacity := node.Manager.NetworkCapacity()
 
 	// create reply for the RPC client
+	reply.Timestamp = capacity.Timestamp.Unix()
+	reply.ActiveNodes = capacity.ActiveNodes
+	reply.ActiveOffers = capacity.ActiveOffers
+	reply.StaleOffers = capacity.StaleOffers
+	reply.RatedNodes = capacity.RatedNodes
+	reply.RenderPower = capacity.RenderPower
+	reply.OpenRequests = capacity.OpenRequests
+	reply.QueuedJobs = capacity.QueuedJobs
+	reply.ClaimedJobs = capacity.ClaimedJobs
+
+	return nil
+
+}
+
+// Method: CancelBenchmark
+// 			- cancel the running Blender benchmarks of this node
+// #############################################################################
+
+// Method
+// NOTE: The mutex is not locked, since CreateRenderOffer holds the mutex while
+// it runs the quick benchmark, which would then never be canceled.
+func (ops *NodeService) CancelBenchmark(r *http.Request, args *CancelBenchmarkArgs, reply *CancelBenchmarkReply) error {
+
+	// cancel the benchmarks
+	reply.Canceled = node.CancelBenchmarks(args.Version)
+	if reply.Canceled == 0 {
+		reply.Message = "No running benchmark found."
+	} else {
+		reply.Message = fmt.Sprintf("Canceled %v running benchmark(s).", reply.Canceled)
+	}
+
 	return nil
 
 }
@@ -206,80 +346,125 @@ func (ops *NodeService) PauseRenderOffer(r *http.Request, args *PauseRenderOffer
 
 // Method
 func (ops *NodeService) CreateRenderRequest(r *http.Request, args *CreateRenderRequestArgs, reply *CreateRenderRequestReply) error {
-	var err error
-	var requestCID string
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the locked render data (or give up, when the request times out)
+	return _callRenderLocked(r.Context(), true, func() error {
+		var err error
+		var requestCID string
+		var blenderFileData []byte
 
-	// TODO: Implement further checks and security measures
+		// TODO: Implement further checks and security measures
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Creating a new render request"))
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Creating a new render request"))
 
-	// create the render request
-	request, err := node.Manager.NewRenderRequest(args.Blender.Version, args.Price)
-	if err != nil {
-		return fmt.Errorf("Could not create new render request: %v", err)
-	}
+		// create the render request
+		request, err := node.Manager.NewRenderRequest(args.Blender.Version, args.Price)
+		if err != nil {
+			return rpcError(fmt.Errorf("Could not create new render request: %w", err))
+		}
 
-	// Iterate over the file data and add each file to the request
-	for _, file := range args.Files {
+		// set the priority and the deadline of the render request
+		deadline := time.Time{}
+		if args.Deadline > 0 {
+			deadline = time.Unix(args.Deadline, 0)
+		}
+		err = request.SetSchedule(args.Priority, deadline)
+		if err != nil {
+			return rpcError(fmt.Errorf("Could not create new render request: %w", err))
+		}
 
-		// Decode the Base64 file data
-		fileData, err := base64.StdEncoding.DecodeString(file.FileData)
+		// split the frame range into subtasks
+		err = request.SetFramesPerTask(args.FramesPerTask)
 		if err != nil {
-			return fmt.Errorf("Error decoding file data: %v", err)
+			return rpcError(fmt.Errorf("Could not create new render request: %w", err))
 		}
 
-		// add the file to the request
-		err = request.AddFileFromBytes(file.FileName, fileData)
+		// split each frame into regions
+		err = request.SetRegionGrid(args.RegionRows, args.RegionColumns)
 		if err != nil {
-			return fmt.Errorf("Could not add file to request: %v", err)
+			return rpcError(fmt.Errorf("Could not create new render request: %w", err))
 		}
 
-		// if the filename contains the .blend file suffix
-		if strings.ToLower(filepath.Ext(file.FileName)) == ".blend" {
+		// set the python setup script
+		err = request.SetSetupScript(args.SetupScriptCID)
+		if err != nil {
+			return rpcError(fmt.Errorf("Could not create new render request: %w", err))
+		}
 
-			// check if there was no blender file added yet
-			if request.BlenderFile.CID != "" {
-				return fmt.Errorf("Only one .blend file is allowed per render request")
+		// Iterate over the file data and add each file to the request
+		for _, file := range args.Files {
+
+			// Decode the Base64 file data
+			fileData, err := base64.StdEncoding.DecodeString(file.FileData)
+			if err != nil {
+				return rpcError(fmt.Errorf("Error decoding file data: %w", err))
 			}
 
-			// get the CID of the .blend file
-			request.BlenderFile.CID, err = ipfs.Manager.GetHashFromObject(request.Files[file.FileName])
+			// add the file to the request
+			err = request.AddFileFromBytes(file.FileName, fileData)
 			if err != nil {
-				return fmt.Errorf("Could not get CID of .blend file: %v", err)
+				return rpcError(fmt.Errorf("Could not add file to request: %w", err))
+			}
+
+			// if the filename contains the .blend file suffix
+			if strings.ToLower(filepath.Ext(file.FileName)) == ".blend" {
+
+				// check if there was no blender file added yet
+				if request.BlenderFile.CID != "" {
+					return fmt.Errorf("Only one .blend file is allowed per render request")
+				}
+
+				// get the CID of the .blend file
+				request.BlenderFile.CID, err = ipfs.Manager.GetHashFromObject(request.Files[file.FileName])
+				if err != nil {
+					return rpcError(fmt.Errorf("Could not get CID of .blend file: %w", err))
+				}
+				blenderFileData = fileData
+
 			}
 
+			// log info
+			logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] File '%v' (%v bytes) added to directory", file.FileName, len(fileData)))
+
 		}
 
-		// log info
-		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] File '%v' (%v bytes) added to directory", file.FileName, len(fileData)))
+		// check if there was a blender file added
+		if request.BlenderFile.CID == "" {
+			return fmt.Errorf("No .blend file was added to the render request")
+		}
 
-	}
+		// pack the external data into a copy of the .blend file
+		if args.Pack {
+			err = node.Manager.PackRenderRequest(request)
+			if err != nil {
+				return rpcError(fmt.Errorf("Could not pack .blend file: %w", err))
+			}
+		}
 
-	// check if there was a blender file added
-	if request.BlenderFile.CID == "" {
-		return fmt.Errorf("No .blend file was added to the render request")
-	}
+		// inspect the render settings of the .blend file
+		reply.Warnings, err = inspectBlenderFile(request, blenderFileData, &node.RenderSettings{Engine: args.Blender.Engine, Device: args.Blender.Device})
+		if err != nil {
+			logger.Manager.Package["jsonrpc"].Warn().Msg(fmt.Sprintf(" [#] Could not inspect the .blend file: %v", err))
+		}
 
-	// deploy the render request to the local IPFS
-	requestCID, err = request.Deploy()
-	if err != nil {
-		return fmt.Errorf("Could not deploy render request: %v", err)
-	}
+		// deploy the render request to the local IPFS
+		requestCID, err = request.Deploy()
+		if err != nil {
+			return rpcError(fmt.Errorf("Could not deploy render request: %w", err))
+		}
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Render request document uploaded with CID: %v", request.DocumentCID))
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Directory uploaded with CID: %v", request.DirectoryCID))
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Render request document uploaded with CID: %v", request.DocumentCID))
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Directory uploaded with CID: %v", request.DirectoryCID))
 
-	// set a reply message
-	reply.Message = "New render request was created locally: http://localhost:5001/ipfs/" + requestCID + "!"
+		// set a reply message
+		reply.Message = "New render request was created locally: http://localhost:5001/ipfs/" + requestCID + "!"
 
-	// create reply for the RPC client
-	return nil
+		// create reply for the RPC client
+		return nil
+
+	})
 
 }
 
@@ -289,40 +474,41 @@ func (ops *NodeService) CreateRenderRequest(r *http.Request, args *CreateRenderR
 
 // Method
 func (ops *NodeService) SubmitRenderRequest(r *http.Request, args *SubmitRenderRequestArgs, reply *SubmitRenderRequestReply) error {
-	var err error
-	var transactionBytes []byte
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the locked render data (or give up, when the request times out)
+	return _callRenderLocked(r.Context(), true, func() error {
+		var err error
+		var transactionBytes []byte
 
-	// TODO: Implement further checks and security measures
+		// TODO: Implement further checks and security measures
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Submitting a render request to the renderhive network"))
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Submitting a render request to the renderhive network"))
 
-	// deploy the render request to the local IPFS
-	request, err := node.Manager.GetRenderRequest(args.RenderRequestCID)
-	if err != nil {
-		return fmt.Errorf("Failed to submit render request: %v", err)
-	}
+		// deploy the render request to the local IPFS
+		request, err := node.Manager.GetRenderRequest(args.RenderRequestCID)
+		if err != nil {
+			return rpcError(fmt.Errorf("Failed to submit render request: %w", err))
+		}
 
-	// submit the render request to the network
-	_, transactionBytes, err = request.Submit()
-	if err != nil {
-		return fmt.Errorf("Failed to submit render request: %v", err)
-	}
+		// submit the render request to the network
+		_, transactionBytes, err = request.Submit()
+		if err != nil {
+			return rpcError(fmt.Errorf("Failed to submit render request: %w", err))
+		}
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
 
-	// set a reply message
-	// networkName, _ := hedera.Manager.NetworkClient.GetLedgerID().ToNetworkName()
-	reply.Message = "" //"Render request was successfully submitted: http://hashscan.io/" + networkName.String() + "/transaction/" + receipt.TransactionID.String() + "!"
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+		// set a reply message
+		// networkName, _ := hedera.Manager.NetworkClient.GetLedgerID().ToNetworkName()
+		reply.Message = "" //"Render request was successfully submitted: http://hashscan.io/" + networkName.String() + "/transaction/" + receipt.TransactionID.String() + "!"
+		reply.TransactionBytes = hex.EncodeToString(transactionBytes)
 
-	// create reply for the RPC client
-	return nil
+		// create reply for the RPC client
+		return nil
+
+	})
 
 }
 
@@ -332,39 +518,428 @@ func (ops *NodeService) SubmitRenderRequest(r *http.Request, args *SubmitRenderR
 
 // Method
 func (ops *NodeService) CancelRenderRequest(r *http.Request, args *CancelRenderRequestArgs, reply *CancelRenderRequestReply) error {
-	var err error
-	var transactionBytes []byte
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the locked render data (or give up, when the request times out)
+	return _callRenderLocked(r.Context(), true, func() error {
+		var err error
+		var transactionBytes []byte
 
-	// TODO: Implement further checks and security measures
+		// TODO: Implement further checks and security measures
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Submitting a render request to the renderhive network"))
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf("Submitting a render request to the renderhive network"))
 
-	// deploy the render request to the local IPFS
-	request, err := node.Manager.GetRenderRequest(args.RenderRequestCID)
+		// deploy the render request to the local IPFS
+		request, err := node.Manager.GetRenderRequest(args.RenderRequestCID)
+		if err != nil {
+			return rpcError(fmt.Errorf("Failed to submit render request: %w", err))
+		}
+
+		// submit the render request to the network
+		_, transactionBytes, err = request.Cancel()
+		if err != nil {
+			return rpcError(fmt.Errorf("Failed to submit render request: %w", err))
+		}
+
+		// log info
+		logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
+
+		// set a reply message
+		// networkName, _ := hedera.Manager.NetworkClient.GetLedgerID().ToNetworkName()
+		reply.Message = "" //"Render request was successfully submitted: http://hashscan.io/" + networkName.String() + "/transaction/" + receipt.TransactionID.String() + "!"
+		reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+
+		// create reply for the RPC client
+		return nil
+
+	})
+
+}
+
+// Method: ListRenderRequests
+// 			- list the render requests of the local node page by page
+// #############################################################################
+
+// Method
+func (ops *NodeService) ListRenderRequests(r *http.Request, args *ListRenderRequestsArgs, reply *ListRenderRequestsReply) error {
+
+	// call the method with the locked render data (or give up, when the request times out)
+	return _callRenderLocked(r.Context(), true, func() error {
+
+		// get the page of render requests
+		requests, total, err := node.Manager.ListRenderRequests(renderListFilter(&args.RenderListArgs))
+		if err != nil {
+			return rpcError(fmt.Errorf("Failed to list render requests: %w", err))
+		}
+
+		// create reply for the RPC client
+		reply.Total = total
+		reply.Requests = []RenderRequestListItem{}
+		for _, request := range requests {
+			item := RenderRequestListItem{
+				DocumentCID:      request.DocumentCID,
+				DirectoryCID:     request.DirectoryCID,
+				State:            request.RepositoryState(),
+				Version:          request.Version,
+				BlenderFile:      filepath.Base(request.BlenderFile.Path),
+				Price:            request.Price,
+				CreatedTimestamp: request.CreatedTimestamp.Unix(),
+			}
+			if request.Owner != nil {
+				item.Owner = request.Owner.String()
+			}
+			reply.Requests = append(reply.Requests, item)
+		}
+
+		return nil
+
+	})
+
+}
+
+// Method: RankRenderOffers
+// 			- rank the render offers matching a render request
+// #############################################################################
+
+// Method
+func (ops *NodeService) RankRenderOffers(r *http.Request, args *RankRenderOffersArgs, reply *RankRenderOffersReply) error {
+
+	// call the method with the locked render data (or give up, when the request times out)
+	return _callRenderLocked(r.Context(), true, func() error {
+		var offers []*node.RenderOffer
+
+		// get the render request
+		request, err := node.Manager.GetRenderRequest(args.RenderRequestCID)
+		if err != nil {
+			return rpcError(fmt.Errorf("Failed to rank render offers: %w", err))
+		}
+
+		// get the render offers (the offers of this node and the active offers
+		// announced on the network by default)
+		if len(args.OfferCIDs) == 0 {
+			offers, _, _ = node.Manager.ListRenderOffers(node.RenderListFilter{})
+			known := map[string]bool{}
+			for _, offer := range offers {
+				known[offer.DocumentCID] = true
+			}
+			for _, offer := range node.Manager.GetNetworkOffers() {
+				if !known[offer.DocumentCID] {
+					known[offer.DocumentCID] = true
+					offers = append(offers, offer)
+				}
+			}
+		}
+		for _, cid := range args.OfferCIDs {
+			offer, err := node.Manager.GetRenderOffer(cid)
+			if err != nil {
+				offer, err = node.Manager.GetNetworkOffer(cid)
+			}
+			if err != nil {
+				return rpcError(fmt.Errorf("Failed to rank render offers: %w", err))
+			}
+			offers = append(offers, offer)
+		}
+
+		// override the configured weights
+		weights := node.Manager.GetRankingWeights()
+		if args.PriceWeight != nil {
+			weights.Price = *args.PriceWeight
+		}
+		if args.ThroughputWeight != nil {
+			weights.Throughput = *args.ThroughputWeight
+		}
+		if args.ReliabilityWeight != nil {
+			weights.Reliability = *args.ReliabilityWeight
+		}
+		err = weights.Validate()
+		if err != nil {
+			return rpcError(fmt.Errorf("Failed to rank render offers: %w", err))
+		}
+
+		// create reply for the RPC client
+		reply.Offers = []RankedRenderOfferItem{}
+		for _, entry := range node.RankOffersWithWeights(request, offers, weights, node.Manager.OperatorSuccessRate) {
+			item := RankedRenderOfferItem{
+				DocumentCID:      entry.Offer.DocumentCID,
+				Price:            entry.Offer.Price,
+				Score:            entry.Score,
+				PriceScore:       entry.PriceScore,
+				ThroughputScore:  entry.ThroughputScore,
+				ReliabilityScore: entry.ReliabilityScore,
+			}
+			if entry.Offer.Owner != nil {
+				item.Owner = entry.Offer.Owner.String()
+			}
+			reply.Offers = append(reply.Offers, item)
+		}
+
+		return nil
+
+	})
+
+}
+
+// Method: GetNodeReputation
+// 			- get the reputation of the render nodes observed by this node
+// #############################################################################
+
+// Method
+func (ops *NodeService) GetNodeReputation(r *http.Request, args *GetNodeReputationArgs, reply *GetNodeReputationReply) error {
+	var reputations []node.NodeReputation
+
+	// get the reputation of the requested nodes (all known nodes by default)
+	if len(args.AccountIDs) == 0 {
+		reputations = node.Manager.Reputation.List()
+	}
+	for _, accountID := range args.AccountIDs {
+		reputation, _ := node.Manager.Reputation.Get(accountID)
+		reputations = append(reputations, reputation)
+	}
+
+	// create reply for the RPC client
+	reply.Nodes = []NodeReputationItem{}
+	for _, reputation := range reputations {
+		item := NodeReputationItem{
+			AccountID:         reputation.AccountID,
+			Completions:       reputation.Completions,
+			Releases:          reputation.Releases,
+			Disputes:          reputation.Disputes,
+			CompletionRate:    -1,
+			DisputeRate:       -1,
+			AverageTurnaround: -1,
+			SuccessRate:       reputation.SuccessRate(),
+		}
+		if rate, ok := reputation.CompletionRate(); ok {
+			item.CompletionRate = rate
+		}
+		if rate, ok := reputation.DisputeRate(); ok {
+			item.DisputeRate = rate
+		}
+		if turnaround, ok := reputation.AverageTurnaround(); ok {
+			item.AverageTurnaround = turnaround.Seconds()
+		}
+		if !reputation.UpdatedTimestamp.IsZero() {
+			item.UpdatedTimestamp = reputation.UpdatedTimestamp.Unix()
+		}
+		reply.Nodes = append(reply.Nodes, item)
+	}
+
+	return nil
+
+}
+
+// Method: VerifyRenderResult
+// 			- download a render result and verify it against its render request
+// #############################################################################
+
+// Method
+// NOTE: The mutex is not locked, since the download of the render result may take a while.
+func (ops *NodeService) VerifyRenderResult(r *http.Request, args *VerifyRenderResultArgs, reply *VerifyRenderResultReply) error {
+
+	// verify the render result
+	verification, err := node.Manager.VerifyRenderResult(args.RenderRequestCID, args.ResultCID)
 	if err != nil {
-		return fmt.Errorf("Failed to submit render request: %v", err)
+		return rpcError(fmt.Errorf("Failed to verify the render result: %w", err))
+	}
+
+	// create reply for the RPC client
+	reply.Passed = verification.Passed
+	reply.RequestMatches = verification.RequestMatches
+	reply.BlenderFileMatches = verification.BlenderFileMatches
+	reply.MissingFrames = verification.MissingFrames
+	reply.Frames = []VerifiedFrameItem{}
+	for _, frame := range verification.Frames {
+		reply.Frames = append(reply.Frames, VerifiedFrameItem{
+			Frame:    frame.Frame,
+			File:     frame.File,
+			Declared: frame.Declared,
+			Actual:   frame.Actual,
+			Passed:   frame.Passed,
+			Reason:   frame.Reason,
+		})
 	}
 
-	// submit the render request to the network
-	_, transactionBytes, err = request.Cancel()
+	return nil
+
+}
+
+// Method: AggregateRenderResults
+// 			- aggregate the subtask results of a render request
+// #############################################################################
+
+// Method
+// NOTE: The mutex is not locked, since the download of the render results may take a while.
+func (ops *NodeService) AggregateRenderResults(r *http.Request, args *AggregateRenderResultsArgs, reply *AggregateRenderResultsReply) error {
+
+	// get the progress of the subtasks
+	status := node.Manager.GetSubtaskStatus(args.RenderRequestCID)
+	reply.Subtasks = status.Total
+	reply.Completed = status.Completed
+
+	// aggregate the render results
+	resultCID, document, err := node.Manager.AggregateRenderResults(args.RenderRequestCID)
 	if err != nil {
-		return fmt.Errorf("Failed to submit render request: %v", err)
+		return rpcError(fmt.Errorf("Failed to aggregate the render results: %w", err))
 	}
 
-	// log info
-	logger.Manager.Package["jsonrpc"].Info().Msg(fmt.Sprintf(" [#] Sending transaction bytes to frontend for execution with operator wallet"))
+	// create reply for the RPC client
+	reply.ResultCID = resultCID
+	reply.Frames = len(document.Frames)
+
+	return nil
 
-	// set a reply message
-	// networkName, _ := hedera.Manager.NetworkClient.GetLedgerID().ToNetworkName()
-	reply.Message = "" //"Render request was successfully submitted: http://hashscan.io/" + networkName.String() + "/transaction/" + receipt.TransactionID.String() + "!"
-	reply.TransactionBytes = hex.EncodeToString(transactionBytes)
+}
+
+// Method: GetSupportedBlenderVersions
+// 			- list the Blender versions supported by the Renderhive network
+// #############################################################################
+
+// Method
+func (ops *NodeService) GetSupportedBlenderVersions(r *http.Request, args *GetSupportedBlenderVersionsArgs, reply *GetSupportedBlenderVersionsReply) error {
+
+	// get the supported versions (or only the requested version)
+	versions := node.SupportedBlenderVersions()
+	if args.Version != "" {
+		supported, err := node.GetSupportedBlenderVersion(args.Version)
+		if err != nil {
+			return rpcError(err)
+		}
+		versions = []node.SupportedBlenderVersion{supported}
+	}
 
 	// create reply for the RPC client
+	reply.Versions = []SupportedBlenderVersionItem{}
+	for _, supported := range versions {
+		item := SupportedBlenderVersionItem{Version: supported.Version}
+		for _, platform := range supported.Platforms {
+			item.Platforms = append(item.Platforms, SupportedBlenderPlatformItem{
+				Platform:  platform.Platform,
+				Available: platform.Available,
+				CID:       platform.Archive.CID,
+				SHA:       platform.Archive.SHA,
+				Commit:    platform.Archive.Commit,
+				Filename:  platform.Archive.Filename,
+				Size:      platform.Archive.Size,
+			})
+		}
+		reply.Versions = append(reply.Versions, item)
+	}
+
 	return nil
 
 }
+
+// INTERNAL HELPER FUNCTIONS
+// #############################################################################
+
+// JSON-RPC error codes of the render errors
+const (
+	RPC_ERROR_NOT_FOUND             json2.ErrorCode = -32004 // render offer, render request, or document not found
+	RPC_ERROR_CONFLICT              json2.ErrorCode = -32009 // already submitted or already exists
+	RPC_ERROR_UNSUPPORTED_VERSION   json2.ErrorCode = -32010 // Blender version not supported
+	RPC_ERROR_NETWORK_UNAVAILABLE   json2.ErrorCode = -32011 // IPFS or Hedera network not available
+	RPC_ERROR_TRANSACTION_FAILED    json2.ErrorCode = -32012 // Hedera transaction failed
+	RPC_ERROR_BENCHMARK_UNAVAILABLE json2.ErrorCode = -32013 // Blender benchmark tool not available
+	RPC_ERROR_DOCUMENT_MISMATCH     json2.ErrorCode = -32014 // written document does not match the render offer
+	RPC_ERROR_BENCHMARK_CANCELED    json2.ErrorCode = -32015 // Blender benchmark was canceled
+	RPC_ERROR_UNSUPPORTED_SCHEMA    json2.ErrorCode = -32016 // render document of an unknown schema version
+	RPC_ERROR_UNTRUSTED_SCRIPT      json2.ErrorCode = -32017 // python setup script not trusted by this node
+	RPC_ERROR_REQUESTER_ONLY        json2.ErrorCode = -32018 // node runs in requester-only mode
+	RPC_ERROR_JOB_INFEASIBLE        json2.ErrorCode = -32019 // render job cannot be rendered by this node
+	RPC_ERROR_BLENDER_CRASHED       json2.ErrorCode = -32020 // Blender process crashed
+	RPC_ERROR_STORAGE_FAILED        json2.ErrorCode = -32021 // render documents could not be read from or written to disk
+)
+
+// helper function to map the render errors to JSON-RPC errors
+func rpcError(err error) error {
+
+	code := json2.E_SERVER
+	switch {
+	case errors.Is(err, node.ErrOfferNotFound), errors.Is(err, node.ErrRequestNotFound), errors.Is(err, node.ErrDocumentNotFound):
+		code = RPC_ERROR_NOT_FOUND
+	case errors.Is(err, node.ErrAlreadySubmitted), errors.Is(err, node.ErrAlreadyExists):
+		code = RPC_ERROR_CONFLICT
+	case errors.Is(err, node.ErrUnsupportedVersion):
+		code = RPC_ERROR_UNSUPPORTED_VERSION
+	case errors.Is(err, node.ErrInvalidArgument):
+		code = json2.E_BAD_PARAMS
+	case errors.Is(err, node.ErrDocumentMismatch):
+		code = RPC_ERROR_DOCUMENT_MISMATCH
+	case errors.Is(err, node.ErrNetworkUnavailable):
+		code = RPC_ERROR_NETWORK_UNAVAILABLE
+	case errors.Is(err, node.ErrTransactionFailed):
+		code = RPC_ERROR_TRANSACTION_FAILED
+	case errors.Is(err, node.ErrBenchmarkUnavailable):
+		code = RPC_ERROR_BENCHMARK_UNAVAILABLE
+	case errors.Is(err, node.ErrBenchmarkCanceled):
+		code = RPC_ERROR_BENCHMARK_CANCELED
+	case errors.Is(err, node.ErrUnsupportedSchema):
+		code = RPC_ERROR_UNSUPPORTED_SCHEMA
+	case errors.Is(err, node.ErrUntrustedScript):
+		code = RPC_ERROR_UNTRUSTED_SCRIPT
+	case errors.Is(err, node.ErrRequesterOnly):
+		code = RPC_ERROR_REQUESTER_ONLY
+	case errors.Is(err, node.ErrJobInfeasible):
+		code = RPC_ERROR_JOB_INFEASIBLE
+	case errors.Is(err, node.ErrBlenderCrashed):
+		code = RPC_ERROR_BLENDER_CRASHED
+	case errors.Is(err, node.ErrStorageFailed):
+		code = RPC_ERROR_STORAGE_FAILED
+	}
+
+	return &json2.Error{Code: code, Message: err.Error()}
+
+}
+
+// helper function to convert the listing arguments into a listing filter
+func renderListFilter(args *RenderListArgs) node.RenderListFilter {
+
+	filter := node.RenderListFilter{
+		State:   args.State,
+		Version: args.Version,
+		Owner:   args.Owner,
+		Offset:  args.Offset,
+		Limit:   args.Limit,
+	}
+	if args.From > 0 {
+		filter.From = time.Unix(args.From, 0)
+	}
+	if args.To > 0 {
+		filter.To = time.Unix(args.To, 0)
+	}
+
+	return filter
+
+}
+
+// helper function to inspect the .blend file of a render request, which is only available in memory
+func inspectBlenderFile(request *node.RenderRequest, data []byte, declared *node.RenderSettings) ([]string, error) {
+
+	// inspect the local file directly (e.g., a packed copy)
+	if request.BlenderFile.Path != "" {
+		return node.Manager.InspectRenderRequest(request, declared)
+	}
+
+	// write the .blend file into a temporary file
+	file, err := os.CreateTemp(RENDERHIVE_APP_DIRECTORY_TEMP, "renderhive_request_*.blend")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	err = file.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// inspect the file
+	request.BlenderFile.Path = file.Name()
+	defer func() { request.BlenderFile.Path = "" }()
+
+	return node.Manager.InspectRenderRequest(request, declared)
+
+}
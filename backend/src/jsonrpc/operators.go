@@ -51,6 +51,7 @@ import (
 	. "renderhive/globals"
 	"renderhive/hedera"
 	"renderhive/logger"
+	"renderhive/metrics"
 	"renderhive/node"
 	"renderhive/utility"
 )
@@ -70,32 +71,33 @@ type OperatorService struct{}
 
 // Adds a known operator
 func (ops *OperatorService) SignUp(r *http.Request, args *SignUpArgs, reply *SignUpReply) error {
-	var err error
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
-
-	// TODO: Implement further checks and security measures
-
-	// handle the different signup steps
-	switch args.Step {
-	case "init":
-		err = ops.signUpInit(args, reply)
-	case "create":
-		err = ops.signUpCreate(args, reply)
-	case "contract":
-		err = ops.signUpContract(args, reply)
-	case "storage":
-		err = ops.signUpStorage(args, reply)
-	case "finalize":
-		err = ops.signUpFinalize(args, reply)
-	default:
-		err = fmt.Errorf("Invalid step: %v", args.Step)
-	}
+	// call the method with the locked mutex (or give up, when the request times out)
+	return _callLocked(r.Context(), true, func() error {
+		var err error
+
+		// TODO: Implement further checks and security measures
+
+		// handle the different signup steps
+		switch args.Step {
+		case "init":
+			err = ops.signUpInit(args, reply)
+		case "create":
+			err = ops.signUpCreate(args, reply)
+		case "contract":
+			err = ops.signUpContract(args, reply)
+		case "storage":
+			err = ops.signUpStorage(args, reply)
+		case "finalize":
+			err = ops.signUpFinalize(args, reply)
+		default:
+			err = fmt.Errorf("Invalid step: %v", args.Step)
+		}
 
-	// create reply for the RPC client
-	return err
+		// create reply for the RPC client
+		return err
+
+	})
 
 }
 
@@ -284,20 +286,21 @@ func (ops *OperatorService) signUpFinalize(args *SignUpArgs, reply *SignUpReply)
 // Signs in using a known operator account
 func (ops *OperatorService) GetSignInPayload(r *http.Request, args *GetSignInPayloadArgs, reply *GetSignInPayloadReply) error {
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the locked mutex (or give up, when the request times out)
+	return _callLocked(r.Context(), true, func() error {
 
-	// Get the hash value of the node configuration file
-	hash, err := node.Manager.HashNodeData()
-	if err != nil {
-		return err
-	}
+		// Get the hash value of the node configuration file
+		hash, err := node.Manager.HashNodeData()
+		if err != nil {
+			return err
+		}
 
-	// create reply for the RPC client
-	reply.Payload = hash
+		// create reply for the RPC client
+		reply.Payload = hash
 
-	return nil
+		return nil
+
+	})
 
 }
 
@@ -307,113 +310,63 @@ func (ops *OperatorService) GetSignInPayload(r *http.Request, args *GetSignInPay
 
 // Signs in using a known operator account
 func (ops *OperatorService) SignIn(r *http.Request, args *SignInArgs, reply *SignInReply) error {
-	var err error
-
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
 
-	// LOAD PRIVATE KEY FROM KEYSTORE
-	// *************************************************************************
+	// call the method with the locked mutex (or give up, when the request times out)
+	return _callLocked(r.Context(), true, func() error {
+		var err error
 
-	// log info
-	logger.Manager.Main.Info().Msg("Received sign-in request from frontend.")
-
-	// log info
-	logger.Manager.Main.Info().Msg(" [#] Decrypting node private key and signing in ...")
-
-	// LOAD ACCOUNT DETAILS AND DECRYPT THE NODE's PRIVATE KEY
-	err = hedera.Manager.LoadAccount(node.Manager.Node.HederaAccount.AccountID, args.Passphrase, node.Manager.Node.HederaAccount.PublicKey)
-	if err != nil {
-		return fmt.Errorf("Failed to load private key: %v", err)
-	}
+		// LOAD PRIVATE KEY FROM KEYSTORE
+		// *************************************************************************
 
-	// GENERATE A SESSION JWT
-	// *************************************************************************
-	// TODO: We reuse the node's Hedera keys to sign and verify the session token.
-	// 		 Would it be significantly more secure to create a new key pair here?
-
-	// use the node's keys to sign a JWT
-	Manager.SessionToken.PrivateKey = ed25519.NewKeyFromSeed(hedera.Manager.Operator.PrivateKey.BytesRaw())
-	Manager.SessionToken.PublicKey = ed25519.NewKeyFromSeed(hedera.Manager.Operator.PrivateKey.BytesRaw()).Public().(ed25519.PublicKey)
-
-	// generate the JWT and store it in the package manager
-	Manager.SessionToken.SignedString, err = Manager.generateJWT(Manager.SessionToken.PrivateKey)
-	if err != nil {
-		return err
-	}
+		// log info
+		logger.Manager.Main.Info().Msg("Received sign-in request from frontend.")
 
-	// update the status variable to notify the middleware that it should set a HttpOnly cookie
-	Manager.SessionToken.Update = true
+		// log info
+		logger.Manager.Main.Info().Msg(" [#] Decrypting node private key and signing in ...")
 
-	// set a name for the HttpOnly cookie
-	Manager.SessionCookie.Name = "renderhive-session"
-
-	// READ HCS TOPIC INFORMATION & SUBSCRIBE
-	// *************************************************************************
-	// hive cycle synchronization topic
-	if RENDERHIVE_TESTNET_TOPIC_HIVE_CYCLE_SYNCHRONIZATION != "" {
-		node.Manager.HiveCycleSynchronizationTopic, err = hedera.Manager.TopicInfoFromString(RENDERHIVE_TESTNET_TOPIC_HIVE_CYCLE_SYNCHRONIZATION)
-		if err != nil {
-			return err
-		}
-		err = hedera.Manager.TopicSubscribe(node.Manager.HiveCycleSynchronizationTopic, time.Unix(0, 0), node.Manager.HiveCycle.MessageCallback())
+		// LOAD ACCOUNT DETAILS AND DECRYPT THE NODE's PRIVATE KEY
+		err = hedera.Manager.LoadAccount(node.Manager.Node.HederaAccount.AccountID, args.Passphrase, node.Manager.Node.HederaAccount.PublicKey)
 		if err != nil {
-			return err
+			return fmt.Errorf("Failed to load private key: %v", err)
 		}
-	}
 
-	// hive cycle application topic
-	if RENDERHIVE_TESTNET_TOPIC_HIVE_CYCLE_APPLICATION != "" {
-		node.Manager.HiveCycleApplicationTopic, err = hedera.Manager.TopicInfoFromString(RENDERHIVE_TESTNET_TOPIC_HIVE_CYCLE_APPLICATION)
-		if err != nil {
-			return err
-		}
-		err = hedera.Manager.TopicSubscribe(node.Manager.HiveCycleApplicationTopic, time.Unix(0, 0), func(message hederasdk.TopicMessage) {
+		// GENERATE A SESSION JWT
+		// *************************************************************************
+		// TODO: We reuse the node's Hedera keys to sign and verify the session token.
+		// 		 Would it be significantly more secure to create a new key pair here?
 
-			logger.Manager.Package["hedera"].Info().Msg(fmt.Sprintf("Message received: %s", string(message.Contents)))
+		// use the node's keys to sign a JWT
+		Manager.SessionToken.PrivateKey = ed25519.NewKeyFromSeed(hedera.Manager.Operator.PrivateKey.BytesRaw())
+		Manager.SessionToken.PublicKey = ed25519.NewKeyFromSeed(hedera.Manager.Operator.PrivateKey.BytesRaw()).Public().(ed25519.PublicKey)
 
-		})
+		// generate the JWT and store it in the package manager
+		Manager.SessionToken.SignedString, err = Manager.generateJWT(Manager.SessionToken.PrivateKey)
 		if err != nil {
 			return err
 		}
-	}
 
-	// hive cycle validation topic
-	if RENDERHIVE_TESTNET_TOPIC_HIVE_CYCLE_VALIDATION != "" {
-		node.Manager.HiveCycleValidationTopic, err = hedera.Manager.TopicInfoFromString(RENDERHIVE_TESTNET_TOPIC_HIVE_CYCLE_VALIDATION)
-		if err != nil {
-			return err
-		}
-		err = hedera.Manager.TopicSubscribe(node.Manager.HiveCycleValidationTopic, time.Unix(0, 0), func(message hederasdk.TopicMessage) {
+		// update the status variable to notify the middleware that it should set a HttpOnly cookie
+		Manager.SessionToken.Update = true
 
-			logger.Manager.Package["hedera"].Info().Msg(fmt.Sprintf("Message received: %s", string(message.Contents)))
+		// set a name for the HttpOnly cookie
+		Manager.SessionCookie.Name = "renderhive-session"
 
-		})
+		// READ HCS TOPIC INFORMATION & SUBSCRIBE
+		// *************************************************************************
+		err = Manager.SubscribeTopics()
 		if err != nil {
 			return err
 		}
-	}
 
-	// render job queue
-	if RENDERHIVE_TESTNET_RENDER_JOB_QUEUE != "" {
-		node.Manager.JobQueueTopic, err = hedera.Manager.TopicInfoFromString(RENDERHIVE_TESTNET_RENDER_JOB_QUEUE)
-		if err != nil {
-			return err
-		}
-		err = hedera.Manager.TopicSubscribe(node.Manager.JobQueueTopic, time.Unix(0, 0), node.Manager.JobQueueMessageCallback())
-		if err != nil {
-			return err
-		}
-	}
+		// set the user session to active
+		Manager.SessionActive = true
 
-	// set the user session to active
-	Manager.SessionActive = true
+		// create reply for the RPC client
+		reply.Message = "Operator signed in!"
+		reply.SignedIn = Manager.SessionActive
+		return nil
 
-	// create reply for the RPC client
-	reply.Message = "Operator signed in!"
-	reply.SignedIn = Manager.SessionActive
-	return nil
+	})
 
 }
 
@@ -424,24 +377,25 @@ func (ops *OperatorService) SignIn(r *http.Request, args *SignInArgs, reply *Sig
 // Signs in using a known operator account
 func (ops *OperatorService) SignOut(r *http.Request, args *SignOutArgs, reply *SignOutReply) error {
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the locked mutex (or give up, when the request times out)
+	return _callLocked(r.Context(), true, func() error {
 
-	// log info
-	logger.Manager.Main.Info().Msg("Received sign-out request from frontend.")
+		// log info
+		logger.Manager.Main.Info().Msg("Received sign-out request from frontend.")
 
-	// set the cookie expiry time to now
-	Manager.SessionToken.ExpiresAt = time.Now()
-	Manager.SessionToken.Update = true
+		// set the cookie expiry time to now
+		Manager.SessionToken.ExpiresAt = time.Now()
+		Manager.SessionToken.Update = true
 
-	// set the user session to inactive
-	Manager.SessionActive = false
+		// set the user session to inactive
+		Manager.SessionActive = false
 
-	// create reply for the RPC client
-	reply.Message = "Operator signed out!"
-	reply.SignedIn = Manager.SessionActive
-	return nil
+		// create reply for the RPC client
+		reply.Message = "Operator signed out!"
+		reply.SignedIn = Manager.SessionActive
+		return nil
+
+	})
 
 }
 
@@ -452,20 +406,21 @@ func (ops *OperatorService) SignOut(r *http.Request, args *SignOutArgs, reply *S
 // Get info about the operator via the accountid
 func (ops *OperatorService) GetInfo(r *http.Request, args *GetInfoArgs, reply *GetInfoReply) error {
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the locked mutex (or give up, when the request times out)
+	return _callLocked(r.Context(), true, func() error {
 
-	// node operator details
-	reply.Username = node.Manager.User.Username
-	reply.UserEmail = node.Manager.User.Email
-	reply.UserAccount = node.Manager.User.UserAccount.AccountID.String()
+		// node operator details
+		reply.Username = node.Manager.User.Username
+		reply.UserEmail = node.Manager.User.Email
+		reply.UserAccount = node.Manager.User.UserAccount.AccountID.String()
 
-	// node details
-	reply.NodeName = node.Manager.Node.Name
-	reply.NodeAccount = node.Manager.Node.HederaAccount.AccountID
+		// node details
+		reply.NodeName = node.Manager.Node.Name
+		reply.NodeAccount = node.Manager.Node.HederaAccount.AccountID
+
+		return nil
+	})
 
-	return nil
 }
 
 // Method: GetContractInfo
@@ -475,21 +430,22 @@ func (ops *OperatorService) GetInfo(r *http.Request, args *GetInfoArgs, reply *G
 // Get info about the operator from the smart contract via the operator accountid
 func (ops *OperatorService) GetContractInfo(r *http.Request, args *GetContractInfoArgs, reply *GetContractInfoReply) error {
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the locked mutex (or give up, when the request times out)
+	return _callLocked(r.Context(), true, func() error {
 
-	// TODO: Query the mirror node to check if the operator account id is known
-	//		 to the smart contract
+		// TODO: Query the mirror node to check if the operator account id is known
+		//		 to the smart contract
 
-	// create reply with info abou the operator and the node for the client
-	reply.Username = node.Manager.User.Username
-	reply.UserEmail = node.Manager.User.Email
-	reply.UserAccount = node.Manager.User.UserAccount.AccountID.String()
-	reply.NodeAlias = node.Manager.Node.Name
-	reply.NodeAccount = node.Manager.Node.HederaAccount.AccountID
+		// create reply with info abou the operator and the node for the client
+		reply.Username = node.Manager.User.Username
+		reply.UserEmail = node.Manager.User.Email
+		reply.UserAccount = node.Manager.User.UserAccount.AccountID.String()
+		reply.NodeAlias = node.Manager.Node.Name
+		reply.NodeAccount = node.Manager.Node.HederaAccount.AccountID
+
+		return nil
+	})
 
-	return nil
 }
 
 // Method: IsSessionValid
@@ -504,19 +460,143 @@ func (ops *OperatorService) GetContractInfo(r *http.Request, args *GetContractIn
 //	contains a valid session token.
 func (ops *OperatorService) IsSessionValid(r *http.Request, args *IsSessionValidArgs, reply *IsSessionValidReply) error {
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the locked mutex (or give up, when the request times out)
+	return _callLocked(r.Context(), true, func() error {
 
-	// create reply with info abou the operator and the node for the client
-	reply.Valid = true
+		// create reply with info abou the operator and the node for the client
+		reply.Valid = true
+
+		return nil
+	})
+
+}
+
+// Method: GetTransactionHistory
+//			- list the transactions this node prepared or submitted
+// #############################################################################
+
+// Get the transaction history of this node
+func (ops *OperatorService) GetTransactionHistory(r *http.Request, args *GetTransactionHistoryArgs, reply *GetTransactionHistoryReply) error {
+
+	// call the method with the locked mutex (or give up, when the request times out)
+	return _callLocked(r.Context(), true, func() error {
+		var err error
+
+		// update the pending transactions from the mirror node
+		if args.Refresh {
+			err = hedera.Manager.History.Refresh(&hedera.Manager.MirrorNode)
+			if err != nil {
+				return err
+			}
+		}
+
+		// prepare the filter
+		filter := hedera.TransactionFilter{
+			Type:   args.Type,
+			Status: args.Status,
+			Search: args.Search,
+			Limit:  args.Limit,
+		}
+		if args.Since > 0 {
+			filter.Since = time.Unix(args.Since, 0)
+		}
+
+		// create reply with the matching transactions
+		reply.Transactions = []TransactionHistoryRecord{}
+		for _, record := range hedera.Manager.History.List(filter) {
+			reply.Transactions = append(reply.Transactions, TransactionHistoryRecord{
+				TransactionID:      record.TransactionID,
+				Type:               record.Type,
+				Summary:            record.Summary,
+				Executed:           record.Executed,
+				Status:             record.Status,
+				Fee:                record.Fee,
+				FeeKnown:           record.FeeKnown,
+				ConsensusTimestamp: record.ConsensusTimestamp,
+				CreatedTimestamp:   record.CreatedTimestamp.Unix(),
+				UpdatedTimestamp:   record.UpdatedTimestamp.Unix(),
+			})
+		}
+
+		return nil
+	})
 
-	return nil
 }
 
 // INTERNAL HELPER FUNCTIONS
 // #############################################################################
 
+// Subscribe to the HCS topics of the render hive with the signed in operator account
+// NOTE: This is called on sign-in and, for accounts loaded from the environment, at startup.
+func (jsonrpcm *PackageManager) SubscribeTopics() error {
+	var err error
+
+	// hive cycle synchronization topic
+	if RENDERHIVE_TESTNET_TOPIC_HIVE_CYCLE_SYNCHRONIZATION != "" {
+		node.Manager.HiveCycleSynchronizationTopic, err = hedera.Manager.TopicInfoFromString(RENDERHIVE_TESTNET_TOPIC_HIVE_CYCLE_SYNCHRONIZATION)
+		if err != nil {
+			return err
+		}
+		err = hedera.Manager.TopicSubscribe(node.Manager.HiveCycleSynchronizationTopic, time.Unix(0, 0), node.Manager.HiveCycle.MessageCallback())
+		if err != nil {
+			return err
+		}
+	}
+
+	// hive cycle application topic
+	if RENDERHIVE_TESTNET_TOPIC_HIVE_CYCLE_APPLICATION != "" {
+		node.Manager.HiveCycleApplicationTopic, err = hedera.Manager.TopicInfoFromString(RENDERHIVE_TESTNET_TOPIC_HIVE_CYCLE_APPLICATION)
+		if err != nil {
+			return err
+		}
+		err = hedera.Manager.TopicSubscribe(node.Manager.HiveCycleApplicationTopic, time.Unix(0, 0), func(message hederasdk.TopicMessage) {
+
+			metrics.Manager.ObserveTopicMessage("hive_cycle_application")
+			logger.Manager.Package["hedera"].Info().Msg(fmt.Sprintf("Message received: %s", string(message.Contents)))
+
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// hive cycle validation topic
+	if RENDERHIVE_TESTNET_TOPIC_HIVE_CYCLE_VALIDATION != "" {
+		node.Manager.HiveCycleValidationTopic, err = hedera.Manager.TopicInfoFromString(RENDERHIVE_TESTNET_TOPIC_HIVE_CYCLE_VALIDATION)
+		if err != nil {
+			return err
+		}
+		err = hedera.Manager.TopicSubscribe(node.Manager.HiveCycleValidationTopic, time.Unix(0, 0), func(message hederasdk.TopicMessage) {
+
+			metrics.Manager.ObserveTopicMessage("hive_cycle_validation")
+			logger.Manager.Package["hedera"].Info().Msg(fmt.Sprintf("Message received: %s", string(message.Contents)))
+
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// render job queue
+	if topicID := node.Manager.JobQueueTopicID(); topicID != "" {
+		node.Manager.JobQueueTopic, err = hedera.Manager.TopicInfoFromString(topicID)
+		if err != nil {
+			return err
+		}
+		start, err := node.Manager.JobQueueStartTime()
+		if err != nil {
+			return err
+		}
+		err = hedera.Manager.TopicSubscribe(node.Manager.JobQueueTopic, start, node.Manager.JobQueueMessageCallback())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
 // Read operator information known to this machine from a file
 func (jsonrpcm *PackageManager) FromFile(path string) error {
 
@@ -48,13 +48,14 @@ type Reply struct {
 // Just say hellow to the name provided by the client
 func (ps *PingService) SayHello(r *http.Request, args *Args, reply *Reply) error {
 
-	// lock the mutex
-	Manager.Mutex.Lock()
-	defer Manager.Mutex.Unlock()
+	// call the method with the locked mutex (or give up, when the request times out)
+	return _callLocked(r.Context(), true, func() error {
 
-	// return the string
-	reply.Message = "Hello, " + args.Who + "!"
-	return nil
+		// return the string
+		reply.Message = "Hello, " + args.Who + "!"
+		return nil
+
+	})
 
 }
 
@@ -0,0 +1,431 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package jsonrpc
+
+/*
+
+This file contains the rate limiting of the JSON-RPC server.
+
+Each client may call each method only a limited number of times per time
+window, and all methods together only a limited number of times per window
+(global limit). Costly methods (contract calls, topic messages, render
+submissions, and the sign-in) have a stricter limit than cheap reads. A client
+is the session of an authenticated operator or, before the sign-in, the remote
+address of the request.
+
+The limits can be set in the optional 'ratelimits.json' file of the
+configuration directory:
+
+    {
+      "global":  {"requests": 300, "window": "1m"},
+      "default": {"requests": 120, "window": "1m"},
+      "costly":  {"requests": 10, "window": "1m"},
+      "methods": {"ContractService.Deploy": {"requests": 2, "window": "10m"}}
+    }
+
+A call that exceeds a limit is rejected with a JSON-RPC server error and the
+HTTP status 429 (Too Many Requests).
+
+*/
+
+import (
+
+	// standard
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	// external
+	"github.com/gorilla/rpc/v2/json2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// methods with the stricter rate limit for costly calls
+var costlyMethods = map[string]bool{
+	"ContractService.Deploy":                true,
+	"ContractService.RegisterOperator":      true,
+	"ContractService.UnregisterOperator":    true,
+	"ContractService.DepositOperatorFunds":  true,
+	"ContractService.WithdrawOperatorFunds": true,
+	"ContractService.AddNode":               true,
+	"ContractService.CreateNodeTopic":       true,
+	"ContractService.RemoveNode":            true,
+	"ContractService.DepositNodeStake":      true,
+	"ContractService.WithdrawNodeStake":     true,
+	"ContractService.AddRenderJob":          true,
+	"ContractService.TopUpRenderJob":        true,
+	"ContractService.ClaimRenderJob":        true,
+	"ContractService.RaiseDispute":          true,
+	"NodeService.CreateRenderOffer":         true,
+	"NodeService.SubmitRenderOffer":         true,
+	"NodeService.PauseRenderOffer":          true,
+	"NodeService.CreateRenderRequest":       true,
+	"NodeService.SubmitRenderRequest":       true,
+	"NodeService.CancelRenderRequest":       true,
+	"NodeService.VerifyRenderResult":        true,
+	"NodeService.AggregateRenderResults":    true,
+	"IpfsService.PinObject":                 true,
+	"OperatorService.SignUp":                true,
+	"OperatorService.SignIn":                true,
+}
+
+// key of the client identity in the request context
+type rpcClientKey struct{}
+
+// Rate limit of JSON-RPC calls
+type RateLimit struct {
+	Requests int    `json:"requests"` // maximum number of calls per window
+	Window   string `json:"window"`   // duration of the window (e.g., '1m')
+}
+
+// Rate limits of the JSON-RPC server
+type RateLimits struct {
+	Global  RateLimit            `json:"global"`  // limit of all calls of a client
+	Default RateLimit            `json:"default"` // limit of each cheap method per client
+	Costly  RateLimit            `json:"costly"`  // limit of each costly method per client
+	Methods map[string]RateLimit `json:"methods"` // limits of single methods per client
+}
+
+// Rate limiter of the JSON-RPC server
+type RateLimiter struct {
+	Mutex   sync.Mutex
+	Limits  RateLimits
+	windows map[string]*rateWindow
+	now     func() time.Time
+}
+
+// calls of a client in the current window of a limit
+type rateWindow struct {
+	Start time.Time
+	Count int
+}
+
+// RATE LIMITS
+// #############################################################################
+// Get the default rate limits
+func DefaultRateLimits() RateLimits {
+	window := RENDERHIVE_CONFIG_JSONRPC_RATE_LIMIT_WINDOW.String()
+	return RateLimits{
+		Global:  RateLimit{Requests: RENDERHIVE_CONFIG_JSONRPC_RATE_LIMIT_GLOBAL, Window: window},
+		Default: RateLimit{Requests: RENDERHIVE_CONFIG_JSONRPC_RATE_LIMIT_DEFAULT, Window: window},
+		Costly:  RateLimit{Requests: RENDERHIVE_CONFIG_JSONRPC_RATE_LIMIT_COSTLY, Window: window},
+		Methods: map[string]RateLimit{},
+	}
+}
+
+// Read the rate limits from the configuration file
+// NOTE: Limits that are not set in the file keep their default.
+func (limits *RateLimits) Read() error {
+	var err error
+
+	// Open the configuration file
+	file, err := os.Open(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "ratelimits.json"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Read the file content
+	fileData, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	// get the file content into a structure
+	err = json.Unmarshal(fileData, limits)
+	if err != nil {
+		return err
+	}
+
+	return limits.Validate()
+
+}
+
+// Check the rate limits for invalid values
+func (limits *RateLimits) Validate() error {
+
+	all := map[string]RateLimit{"global": limits.Global, "default": limits.Default, "costly": limits.Costly}
+	for method, limit := range limits.Methods {
+		all[method] = limit
+	}
+	for name, limit := range all {
+		if _, err := limit.Duration(); err != nil || limit.Requests <= 0 {
+			return errors.New(fmt.Sprintf("Invalid rate limit '%v': the number of requests and the window must be greater than zero.", name))
+		}
+	}
+
+	return nil
+
+}
+
+// Get the duration of the window of a rate limit
+func (limit RateLimit) Duration() (time.Duration, error) {
+
+	window, err := time.ParseDuration(limit.Window)
+	if err != nil {
+		return 0, err
+	}
+	if window <= 0 {
+		return 0, errors.New("the window must be greater than zero")
+	}
+
+	return window, nil
+
+}
+
+// Get the rate limit of a method
+func (limits *RateLimits) Method(method string) RateLimit {
+
+	if limit, ok := limits.Methods[method]; ok {
+		return limit
+	}
+	if costlyMethods[method] {
+		return limits.Costly
+	}
+
+	return limits.Default
+
+}
+
+// RATE LIMITER
+// #############################################################################
+// Create a new rate limiter with the given rate limits
+func NewRateLimiter(limits RateLimits) *RateLimiter {
+
+	return &RateLimiter{
+		Limits:  limits,
+		windows: map[string]*rateWindow{},
+		now:     time.Now,
+	}
+
+}
+
+// Load the rate limiter of the JSON-RPC server
+// NOTE: Falls back to the default limits, if the configuration file does not
+// exist or is invalid.
+func (jsonrpcm *PackageManager) LoadRateLimiter() error {
+	var err error
+
+	limits := DefaultRateLimits()
+	err = limits.Read()
+	if err != nil {
+		limits = DefaultRateLimits()
+	}
+	jsonrpcm.RateLimiter = NewRateLimiter(limits)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.New(fmt.Sprintf("Could not read the rate limits (using the defaults): %v", err))
+	}
+
+	return nil
+
+}
+
+// Check if a client may call a method and count the call
+// NOTE: Returns the time until the client may call the method again, if the
+// call exceeds a rate limit.
+func (limiter *RateLimiter) Allow(client string, method string) (bool, time.Duration) {
+
+	// lock the limiter
+	limiter.Mutex.Lock()
+	defer limiter.Mutex.Unlock()
+
+	now := limiter.now()
+
+	// check the global and the method limit, before counting the call
+	global, globalKey := limiter.Limits.Global, client+"|*"
+	local, localKey := limiter.Limits.Method(method), client+"|"+method
+	if wait := limiter._wait(globalKey, global, now); wait > 0 {
+		return false, wait
+	}
+	if wait := limiter._wait(localKey, local, now); wait > 0 {
+		return false, wait
+	}
+	limiter.windows[globalKey].Count++
+	limiter.windows[localKey].Count++
+
+	// forget the expired windows of other clients
+	if len(limiter.windows) > RENDERHIVE_CONFIG_JSONRPC_RATE_LIMIT_MAX_CLIENTS {
+		limiter._prune(now)
+	}
+
+	return true, 0
+
+}
+
+// helper function to get the time until the window of a limit allows another call
+// NOTE: Starts a new window, if the current window has expired. The caller
+// must hold the mutex.
+func (limiter *RateLimiter) _wait(key string, limit RateLimit, now time.Time) time.Duration {
+
+	duration, err := limit.Duration()
+	if err != nil {
+		duration = RENDERHIVE_CONFIG_JSONRPC_RATE_LIMIT_WINDOW
+	}
+
+	window, ok := limiter.windows[key]
+	if !ok || !now.Before(window.Start.Add(duration)) {
+		window = &rateWindow{Start: now}
+		limiter.windows[key] = window
+	}
+	if window.Count >= limit.Requests {
+		return window.Start.Add(duration).Sub(now)
+	}
+
+	return 0
+
+}
+
+// helper function to remove the windows that have certainly expired
+// NOTE: The caller must hold the mutex.
+func (limiter *RateLimiter) _prune(now time.Time) {
+
+	// get the longest window of all limits
+	limits := []RateLimit{limiter.Limits.Global, limiter.Limits.Default, limiter.Limits.Costly}
+	for _, limit := range limiter.Limits.Methods {
+		limits = append(limits, limit)
+	}
+	longest := RENDERHIVE_CONFIG_JSONRPC_RATE_LIMIT_WINDOW
+	for _, limit := range limits {
+		if duration, err := limit.Duration(); err == nil && duration > longest {
+			longest = duration
+		}
+	}
+
+	for key, window := range limiter.windows {
+		if !now.Before(window.Start.Add(longest)) {
+			delete(limiter.windows, key)
+		}
+	}
+
+}
+
+// Rate limiting middleware handler for the router
+// NOTE: Runs after the authentication middleware, which identifies the client.
+func (jsonrpcm *PackageManager) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		// only limit the JSON-RPC calls
+		if r.Method != http.MethodPost || jsonrpcm.RateLimiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// get the method name and the ID of the request
+		method, id, err := _rpcMethodAndID(r)
+		if err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+
+		// check the rate limits of the client
+		client := _rpcClient(r)
+		allowed, wait := jsonrpcm.RateLimiter.Allow(client, method)
+		if !allowed {
+
+			// log event
+			logger.Manager.Package["jsonrpc"].Warn().Msg(fmt.Sprintf("Rate limit of '%v' exceeded by client '%v' (retry in %v).", method, client, wait.Round(time.Second)))
+
+			// return a JSON-RPC error
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Header().Set("Retry-After", fmt.Sprintf("%d", int(math.Ceil(wait.Seconds()))))
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"error": map[string]interface{}{
+					"code":    json2.E_SERVER,
+					"message": fmt.Sprintf("Rate limit of '%v' exceeded. Retry in %v.", method, wait.Round(time.Second)),
+				},
+				"id": id,
+			})
+			return
+
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// helper function to identify the client of a request (session or remote address)
+func _rpcClient(r *http.Request) string {
+
+	if client, ok := r.Context().Value(rpcClientKey{}).(string); ok {
+		return client
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "address:" + host
+
+}
+
+// helper function to add the session of an authenticated client to a request
+func _withRpcSession(r *http.Request, token string) *http.Request {
+
+	hash := sha256.Sum256([]byte(token))
+
+	return r.WithContext(context.WithValue(r.Context(), rpcClientKey{}, "session:"+hex.EncodeToString(hash[:8])))
+
+}
+
+// helper function to get the method name and the ID of a JSON-RPC request
+// NOTE: The request body is restored for the next handler.
+func _rpcMethodAndID(r *http.Request) (string, interface{}, error) {
+
+	bodyBytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", nil, err
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(strings.NewReader(string(bodyBytes)))
+
+	var requestBody struct {
+		Method string      `json:"method"`
+		ID     interface{} `json:"id"`
+	}
+	err = json.Unmarshal(bodyBytes, &requestBody)
+	if err != nil {
+		return "", nil, err
+	}
+	if requestBody.Method == "" {
+		return "", nil, errors.New("missing method")
+	}
+
+	return requestBody.Method, requestBody.ID, nil
+
+}
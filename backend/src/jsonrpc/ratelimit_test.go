@@ -0,0 +1,182 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package jsonrpc
+
+import (
+
+	// standard
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	// external
+	"github.com/gorilla/rpc/v2/json2"
+
+	// internal
+	"renderhive/logger"
+)
+
+// helper function to create a rate limiter with a clock controlled by the test
+func _testRateLimiter(limits RateLimits) (*RateLimiter, *time.Time) {
+	now := time.Unix(1700000000, 0)
+	limiter := NewRateLimiter(limits)
+	limiter.now = func() time.Time { return now }
+
+	return limiter, &now
+}
+
+func TestRateLimiterRejectsCallsOverTheLimit(t *testing.T) {
+	limiter, now := _testRateLimiter(RateLimits{
+		Global:  RateLimit{Requests: 100, Window: "1m"},
+		Default: RateLimit{Requests: 3, Window: "1m"},
+		Costly:  RateLimit{Requests: 1, Window: "1m"},
+	})
+
+	// the calls up to the limit are allowed
+	for i := 0; i < 3; i++ {
+		if allowed, _ := limiter.Allow("client", "NodeService.GetRenderOffers"); !allowed {
+			t.Fatalf("call %v: expected the call to be allowed", i+1)
+		}
+	}
+
+	// the call over the limit is rejected until the window ends
+	*now = now.Add(20 * time.Second)
+	allowed, wait := limiter.Allow("client", "NodeService.GetRenderOffers")
+	if allowed || wait != 40*time.Second {
+		t.Errorf("got allowed %v (retry in %v), want a rejection for 40s", allowed, wait)
+	}
+
+	// other clients and methods have their own limits
+	if allowed, _ := limiter.Allow("other", "NodeService.GetRenderOffers"); !allowed {
+		t.Error("expected the call of another client to be allowed")
+	}
+	if allowed, _ := limiter.Allow("client", "NodeService.GetRenderRequests"); !allowed {
+		t.Error("expected the call of another method to be allowed")
+	}
+
+	// costly methods have the stricter limit
+	if allowed, _ := limiter.Allow("client", "ContractService.Deploy"); !allowed {
+		t.Error("expected the first costly call to be allowed")
+	}
+	if allowed, _ := limiter.Allow("client", "ContractService.Deploy"); allowed {
+		t.Error("expected the second costly call to be rejected")
+	}
+
+	// the limits recover after the window
+	*now = now.Add(40 * time.Second)
+	if allowed, _ := limiter.Allow("client", "NodeService.GetRenderOffers"); !allowed {
+		t.Error("expected the call to be allowed after the window")
+	}
+	if allowed, _ := limiter.Allow("client", "ContractService.Deploy"); allowed {
+		t.Error("expected the costly call to be rejected within its window")
+	}
+	*now = now.Add(20 * time.Second)
+	if allowed, _ := limiter.Allow("client", "ContractService.Deploy"); !allowed {
+		t.Error("expected the costly call to be allowed after its window")
+	}
+}
+
+func TestRateLimiterGlobalAndMethodLimits(t *testing.T) {
+	limiter, now := _testRateLimiter(RateLimits{
+		Global:  RateLimit{Requests: 4, Window: "1m"},
+		Default: RateLimit{Requests: 10, Window: "1m"},
+		Costly:  RateLimit{Requests: 10, Window: "1m"},
+		Methods: map[string]RateLimit{"NodeService.GetRenderOffers": {Requests: 1, Window: "10m"}},
+	})
+
+	// a method with its own limit
+	limiter.Allow("client", "NodeService.GetRenderOffers")
+	if allowed, wait := limiter.Allow("client", "NodeService.GetRenderOffers"); allowed || wait != 10*time.Minute {
+		t.Errorf("got allowed %v (retry in %v), want a rejection for 10m", allowed, wait)
+	}
+
+	// all methods of a client share the global limit (rejected calls are not counted)
+	for _, method := range []string{"A.Read", "B.Read", "C.Read"} {
+		if allowed, _ := limiter.Allow("client", method); !allowed {
+			t.Errorf("%v: expected the call to be allowed", method)
+		}
+	}
+	if allowed, _ := limiter.Allow("client", "D.Read"); allowed {
+		t.Error("expected the call over the global limit to be rejected")
+	}
+	*now = now.Add(time.Minute)
+	if allowed, _ := limiter.Allow("client", "D.Read"); !allowed {
+		t.Error("expected the call to be allowed after the global window")
+	}
+}
+
+func TestRateLimitMiddlewareReturnsAJsonRpcError(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+
+	limiter, _ := _testRateLimiter(RateLimits{
+		Global:  RateLimit{Requests: 100, Window: "1m"},
+		Default: RateLimit{Requests: 1, Window: "1m"},
+		Costly:  RateLimit{Requests: 1, Window: "1m"},
+	})
+	jsonrpcm := &PackageManager{RateLimiter: limiter}
+	handler := jsonrpcm.rateLimitMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	call := func() *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/jsonrpc", strings.NewReader(`{"jsonrpc": "2.0", "method": "NodeService.GetRenderOffers", "id": 7}`))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	if recorder := call(); recorder.Code != http.StatusOK {
+		t.Fatalf("got status %v, want the first call to pass", recorder.Code)
+	}
+	recorder := call()
+	if recorder.Code != http.StatusTooManyRequests || recorder.Header().Get("Retry-After") != "60" {
+		t.Errorf("got status %v (retry after %q), want 429 and 60s", recorder.Code, recorder.Header().Get("Retry-After"))
+	}
+	var response struct {
+		Error struct {
+			Code    json2.ErrorCode `json:"code"`
+			Message string          `json:"message"`
+		} `json:"error"`
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Error.Code != json2.E_SERVER || response.ID != 7 || !strings.Contains(response.Error.Message, "Rate limit") {
+		t.Errorf("unexpected JSON-RPC error: %+v", response)
+	}
+}
+
+func TestRateLimitsValidate(t *testing.T) {
+	if limits := DefaultRateLimits(); limits.Validate() != nil {
+		t.Error("expected the default rate limits to be valid")
+	}
+	for _, limit := range []RateLimit{{Requests: 0, Window: "1m"}, {Requests: 1, Window: "0s"}, {Requests: 1, Window: "soon"}} {
+		limits := DefaultRateLimits()
+		limits.Methods["NodeService.GetRenderOffers"] = limit
+		if limits.Validate() == nil {
+			t.Errorf("%+v: expected an invalid rate limit", limit)
+		}
+	}
+}
@@ -59,6 +59,7 @@ import (
 
 	// internal
 	"renderhive/logger"
+	"renderhive/node"
 	// "renderhive/globals"
 	// "renderhive/hedera"
 )
@@ -68,19 +69,39 @@ type PackageManager struct {
 
 	// JSON RPC
 	// General
-	Mutex         sync.Mutex
+	Mutex         RequestMutex      // lock of the session and the operator's transactions (see locking.go)
+	RenderMutex   *node.RenderMutex // lock of the render data (see node/locking.go)
 	JsonRpcServer *rpc.Server
 	HttpServer    http.Server
 	Listener      net.Listener
 	Port          string
 	CertFile      string
 	KeyFile       string
+	StartTime     time.Time
+
+	// Health-check
+	HealthServer *http.Server
+	healthMutex  sync.Mutex // lock of the health-check server
+	healthHedera struct {
+		sync.Mutex // lock of the cached status (never held during a query)
+		Status     HealthHedera
+		Timestamp  time.Time
+		running    chan struct{} // closed, when the running query finished (nil = no query)
+		timeout    time.Duration // maximum wait for a query (0 = RENDERHIVE_CONFIG_HEALTH_HEDERA_TIMEOUT)
+	}
+	checkHedera func(ctx context.Context) HealthHedera // check of the Hedera client (nil = query the network)
+
+	// Origin validation, rate limiting, and request timeout
+	Cors        CorsSettings
+	RateLimiter *RateLimiter
+	Timeout     time.Duration // timeout of the JSON-RPC calls (0 = default timeout)
 
 	// Services
 	PingService     *PingService
 	ContractService *ContractService
 	OperatorService *OperatorService
 	NodeService     *NodeService
+	IpfsService     *IpfsService
 
 	// Session data
 	SessionActive bool
@@ -105,7 +126,7 @@ type PackageManager struct {
 // JSON-RPC MANAGER
 // #############################################################################
 // create the render manager variable
-var Manager = PackageManager{}
+var Manager = PackageManager{RenderMutex: &node.Manager.Renderer.Mutex}
 
 // Initialize everything required for the JSON-RPC management
 func (jsonrpcm *PackageManager) Init() error {
@@ -114,11 +135,15 @@ func (jsonrpcm *PackageManager) Init() error {
 	// log information
 	logger.Manager.Package["jsonrpc"].Info().Msg("Initializing the JSON-RPC manager ...")
 
+	// remember the start time for the uptime of the service app
+	jsonrpcm.StartTime = time.Now()
+
 	// Create all services
 	jsonrpcm.PingService = new(PingService)
 	jsonrpcm.ContractService = new(ContractService)
 	jsonrpcm.OperatorService = new(OperatorService)
 	jsonrpcm.NodeService = new(NodeService)
+	jsonrpcm.IpfsService = new(IpfsService)
 
 	return err
 
@@ -131,6 +156,9 @@ func (jsonrpcm *PackageManager) DeInit() error {
 	// log event
 	logger.Manager.Package["jsonrpc"].Debug().Msg("Deinitializing the JSON-RPC manager ...")
 
+	// stop the health-check server
+	jsonrpcm.StopHealthServer()
+
 	return err
 
 }
@@ -162,6 +190,20 @@ func (jsonrpcm *PackageManager) StartServer(port string, certFile string, keyFil
 	if err != nil {
 		return err
 	}
+	err = jsonrpcm.JsonRpcServer.RegisterService(jsonrpcm.IpfsService, "IpfsService")
+	if err != nil {
+		return err
+	}
+
+	// load the allowed origins and the rate limits
+	err = jsonrpcm.LoadCorsSettings()
+	if err != nil {
+		logger.Manager.Package["jsonrpc"].Warn().Msg(err.Error())
+	}
+	err = jsonrpcm.LoadRateLimiter()
+	if err != nil {
+		logger.Manager.Package["jsonrpc"].Warn().Msg(err.Error())
+	}
 
 	// Create a new router
 	router := mux.NewRouter()
@@ -169,6 +211,8 @@ func (jsonrpcm *PackageManager) StartServer(port string, certFile string, keyFil
 	// Apply middleware to the router
 	router.Use(jsonrpcm.corsMiddleware)
 	router.Use(jsonrpcm.authenticationMiddleware)
+	router.Use(jsonrpcm.rateLimitMiddleware)
+	router.Use(jsonrpcm.timeoutMiddleware)
 
 	// Handle OPTIONS requests on the JSON-RPC route
 	router.HandleFunc("/jsonrpc", func(w http.ResponseWriter, r *http.Request) {
@@ -204,8 +248,8 @@ func (jsonrpcm *PackageManager) StartServer(port string, certFile string, keyFil
 
 			// log event
 			logger.Manager.Package["jsonrpc"].Debug().Msg("Setting HttpOnly cookie ...")
-			logger.Manager.Package["jsonrpc"].Debug().Msg(fmt.Sprintf(" [#] Name: ", jsonrpcm.SessionCookie.Name))
-			logger.Manager.Package["jsonrpc"].Debug().Msg(fmt.Sprintf(" [#] String: ", jsonrpcm.SessionToken.SignedString))
+			logger.Manager.Package["jsonrpc"].Debug().Msg(fmt.Sprintf(" [#] Name: %v", jsonrpcm.SessionCookie.Name))
+			logger.Manager.Package["jsonrpc"].Debug().Msg(fmt.Sprintf(" [#] String: %v", jsonrpcm.SessionToken.SignedString))
 
 			// set the cookie, which will expire at the same time as the token
 			http.SetCookie(w, &http.Cookie{
@@ -232,6 +276,9 @@ func (jsonrpcm *PackageManager) StartServer(port string, certFile string, keyFil
 
 	}).Methods("POST")
 
+	// Push the node events to the WebSocket clients
+	router.HandleFunc(eventsRoute, jsonrpcm.handleEvents).Methods("GET")
+
 	// Setting up HTTPS Server configuration
 	tlsConfig := &tls.Config{
 		MinVersion: tls.VersionTLS12,
@@ -363,29 +410,6 @@ func (b *BufferedResponseWriter) WriteBufferedResponse() {
 // JSON-RPC MIDDLEWARE
 // #############################################################################
 
-// CORS middleware handler for the router
-func (jsonrpcm *PackageManager) corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "https://localhost:5173")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, withCredentials")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-
-		// Handling CORS preflight request
-		if r.Method == "OPTIONS" {
-			// log event
-			logger.Manager.Package["jsonrpc"].Debug().Msg("Handling the OPTIONS Request")
-
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
 // Authentication middleware handler for the router
 func (jsonrpcm *PackageManager) authenticationMiddleware(next http.Handler) http.Handler {
 
@@ -400,14 +424,17 @@ func (jsonrpcm *PackageManager) authenticationMiddleware(next http.Handler) http
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		// get the method name from the request
-		method, err := jsonrpcm.getRpcMethod(w, r)
-		if err != nil {
-			http.Error(w, "Invalid request", http.StatusBadRequest)
-			return
-		}
-		if _, isAllowed := whitelistMethods[method]; isAllowed {
-			next.ServeHTTP(w, r)
-			return
+		// NOTE: The event stream has no JSON-RPC method, but always requires a session.
+		if r.URL.Path != eventsRoute {
+			method, err := jsonrpcm.getRpcMethod(w, r)
+			if err != nil {
+				http.Error(w, "Invalid request", http.StatusBadRequest)
+				return
+			}
+			if _, isAllowed := whitelistMethods[method]; isAllowed {
+				next.ServeHTTP(w, r)
+				return
+			}
 		}
 
 		// VERIFY THE JWT
@@ -436,8 +463,8 @@ func (jsonrpcm *PackageManager) authenticationMiddleware(next http.Handler) http
 			return
 		}
 
-		// Call the next handler in the chain
-		next.ServeHTTP(w, r)
+		// Call the next handler in the chain (for the authenticated session)
+		next.ServeHTTP(w, _withRpcSession(r, tokenString))
 	})
 }
 
@@ -479,9 +506,9 @@ func (jsonrpcm *PackageManager) CreateCommand() *cobra.Command {
 		Use:   "jsonrpc",
 		Short: "Commands for the web frontend of the Renderhive Service App",
 		Long:  "This command and its sub-commands enable the management of the web frontend for the Renderhive Service App.",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
-			return
+			return nil
 
 		},
 	}
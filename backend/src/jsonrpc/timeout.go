@@ -0,0 +1,264 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package jsonrpc
+
+/*
+
+This file contains the request timeout of the JSON-RPC server.
+
+Each JSON-RPC call gets a context with a deadline, which is passed from the
+HTTP request to the methods and from there to the Hedera calls. A call that is
+not answered before the deadline gets a timeout error, so a slow network call
+cannot freeze the JSON-RPC server:
+
+  - Methods wait for the lock only until the deadline of their request (see
+    node/locking.go).
+  - Methods run with the locked mutex in the background (see _call()).
+    If a method does not return before the deadline, the call gets a timeout
+    error, but the method keeps the lock until it actually returned. Later
+    calls wait for the lock only until their own deadline, so they time out
+    instead of blocking the JSON-RPC server.
+  - The Hedera SDK does not support contexts, so a Hedera call always runs to
+    its end. Its results are discarded, if the deadline passed in the meantime
+    (see hedera.Await()).
+  - Methods that do not finish in time are answered by the timeout middleware.
+
+*/
+
+import (
+
+	// standard
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	// external
+	"github.com/gorilla/rpc/v2/json2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// Response of a JSON-RPC method, which is sent after the method finished
+type timeoutWriter struct {
+	mutex    sync.Mutex
+	header   http.Header
+	body     bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+// REQUEST TIMEOUT
+// #############################################################################
+// Request timeout middleware handler for the router
+// NOTE: Runs after the rate limiting middleware, so rejected calls do not start
+// a method.
+func (jsonrpcm *PackageManager) timeoutMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		// only limit the JSON-RPC calls
+		if r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// get the method name and the ID of the request
+		method, id, err := _rpcMethodAndID(r)
+		if err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+
+		// call the method with the deadline of the request
+		timeout := jsonrpcm._requestTimeout()
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		tw := &timeoutWriter{header: http.Header{}}
+		done := make(chan struct{})
+		panicked := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicked <- p
+				}
+			}()
+			next.ServeHTTP(tw, r.WithContext(ctx))
+			close(done)
+		}()
+
+		select {
+		case p := <-panicked:
+			panic(p)
+
+		// send the response of the method
+		case <-done:
+			tw.mutex.Lock()
+			defer tw.mutex.Unlock()
+			for key, values := range tw.header {
+				w.Header()[key] = values
+			}
+			if tw.status == 0 {
+				tw.status = http.StatusOK
+			}
+			w.WriteHeader(tw.status)
+			w.Write(tw.body.Bytes())
+
+		// send a timeout error
+		case <-ctx.Done():
+			tw.mutex.Lock()
+			defer tw.mutex.Unlock()
+			tw.timedOut = true
+
+			// log event
+			logger.Manager.Package["jsonrpc"].Warn().Msg(fmt.Sprintf("Call of '%v' timed out after %v.", method, timeout))
+
+			// return a JSON-RPC error
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"error": map[string]interface{}{
+					"code":    json2.E_SERVER,
+					"message": fmt.Sprintf("Call of '%v' timed out after %v.", method, timeout),
+				},
+				"id": id,
+			})
+
+		}
+
+	})
+}
+
+// helper function to call a method with the locked mutex of the JSON-RPC
+// methods (or give up, when the context is done)
+// NOTE: The method keeps the lock until it returned, even if the context is done
+// before. Its reply must therefore not be read after a timeout error.
+func _callLocked(ctx context.Context, exclusive bool, method func() error) error {
+	return _call(ctx, &Manager.Mutex, exclusive, method)
+}
+
+// helper function to call a method with the locked mutex of the render data
+// (or give up, when the context is done)
+func _callRenderLocked(ctx context.Context, exclusive bool, method func() error) error {
+	return _call(ctx, Manager.RenderMutex, exclusive, method)
+}
+
+// helper function to lock the render data within a method, which already holds
+// the lock of the JSON-RPC methods (or give up, when the context is done)
+func _withRenderLock(ctx context.Context, method func() error) error {
+
+	err := Manager.RenderMutex.LockContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer Manager.RenderMutex.Unlock()
+
+	return method()
+
+}
+
+// helper function to call a method with the given locked mutex (or give up,
+// when the context is done)
+func _call(ctx context.Context, mutex methodMutex, exclusive bool, method func() error) error {
+
+	// lock the mutex (or give up, when the request times out)
+	if exclusive {
+		err := mutex.LockContext(ctx)
+		if err != nil {
+			return err
+		}
+	} else {
+		err := mutex.RLockContext(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// call the method and unlock the mutex, when it returned
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if exclusive {
+				mutex.Unlock()
+			} else {
+				mutex.RUnlock()
+			}
+		}()
+		result <- method()
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("The request timed out, while the method was still running: %w", ctx.Err())
+	}
+
+}
+
+// helper function to get the timeout of the JSON-RPC calls
+func (jsonrpcm *PackageManager) _requestTimeout() time.Duration {
+
+	if jsonrpcm.Timeout > 0 {
+		return jsonrpcm.Timeout
+	}
+
+	return RENDERHIVE_CONFIG_JSONRPC_REQUEST_TIMEOUT
+
+}
+
+// Get the header of the response
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// Write to the body of the response (discarded after the timeout)
+func (tw *timeoutWriter) Write(data []byte) (int, error) {
+
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+
+	return tw.body.Write(data)
+
+}
+
+// Write the status code of the response
+func (tw *timeoutWriter) WriteHeader(status int) {
+
+	tw.mutex.Lock()
+	defer tw.mutex.Unlock()
+	if tw.timedOut || tw.status != 0 {
+		return
+	}
+	tw.status = status
+
+}
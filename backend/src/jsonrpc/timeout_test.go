@@ -0,0 +1,73 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package jsonrpc
+
+import (
+	// standard
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCallLockedTimesOutWithoutDeadlock(t *testing.T) {
+	// a slow method times out, but keeps the lock until it returned
+	release := make(chan struct{})
+	returned := make(chan struct{})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := _callLocked(ctx, true, func() error {
+		defer close(returned)
+		<-release
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("slow call: got %v, want a timeout error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("slow call returned after %v", elapsed)
+	}
+
+	// a later call times out, while it waits for the lock
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	called := false
+	err = _callLocked(ctx, false, func() error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || called {
+		t.Fatalf("waiting call: got %v (called: %v), want a timeout error", err, called)
+	}
+
+	// after the slow method returned, the next call runs
+	close(release)
+	<-returned
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err = _callLocked(ctx, true, func() error {
+		return errors.New("method error")
+	})
+	if err == nil || err.Error() != "method error" {
+		t.Fatalf("next call: got %v, want the error of the method", err)
+	}
+}
@@ -0,0 +1,164 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package logger
+
+/*
+
+This file contains the verbosity of the Renderhive Service App and the output
+helpers of the command line interface.
+
+The verbosity sets the level of all loggers and decides which CLI output is
+printed:
+
+  - quiet: only errors are logged and the CLI only prints results and errors
+  - normal: the default logger level and all CLI output
+  - debug: trace logging of all package loggers and all CLI output
+
+The CLI commands print their output with the helpers below instead of the fmt
+package: Println/Printf for decorative messages (headers, blank lines, hints),
+Resultln/Resultf for the results of a command, and Errorln for errors. Errors
+are written to stderr, so that scripts can separate them from the results.
+
+*/
+
+import (
+
+	// standard
+	"fmt"
+	"io"
+	"os"
+
+	// external
+	"github.com/rs/zerolog"
+
+	// internal
+	. "renderhive/globals"
+)
+
+// verbosity levels of the service app
+const (
+	VERBOSITY_QUIET  int = iota // only results and errors
+	VERBOSITY_NORMAL            // default output
+	VERBOSITY_DEBUG             // trace logging
+)
+
+// VERBOSITY
+// #############################################################################
+// Set the verbosity of the loggers and the CLI output
+func (logm *PackageManager) SetVerbosity(verbosity int) {
+
+	logm.Verbosity = verbosity
+
+	// get the logger level of the verbosity
+	level := COMPILER_RENDERHIVE_LOGGER_LEVEL
+	switch verbosity {
+	case VERBOSITY_QUIET:
+		level = zerolog.ErrorLevel
+	case VERBOSITY_DEBUG:
+		level = zerolog.TraceLevel
+	}
+
+	// update the main logger and all package loggers
+	zerolog.SetGlobalLevel(level)
+	if logm.Main != nil {
+		*logm.Main = logm.Main.Level(level)
+	}
+	for _, logger := range logm.Package {
+		*logger = logger.Level(level)
+	}
+
+}
+
+// Check if the CLI output is reduced to results and errors
+func (logm *PackageManager) IsQuiet() bool {
+	return logm.Verbosity == VERBOSITY_QUIET
+}
+
+// helper function to get the verbosity from the '--quiet' and '--debug' flags
+// NOTE: The flags are parsed by the CLI later, but the loggers are required earlier.
+func _verbosityFromArgs(args []string) int {
+
+	verbosity := VERBOSITY_NORMAL
+	for _, arg := range args {
+		switch arg {
+		case "-q", "--quiet":
+			verbosity = VERBOSITY_QUIET
+		case "--debug":
+			return VERBOSITY_DEBUG
+		}
+	}
+
+	return verbosity
+
+}
+
+// COMMAND LINE OUTPUT
+// #############################################################################
+// Print a decorative line (suppressed in quiet mode)
+func (logm *PackageManager) Println(a ...interface{}) {
+	if !logm.IsQuiet() {
+		fmt.Fprintln(logm._output(), a...)
+	}
+}
+
+// Print a formatted decorative message (suppressed in quiet mode)
+func (logm *PackageManager) Printf(format string, a ...interface{}) {
+	if !logm.IsQuiet() {
+		fmt.Fprintf(logm._output(), format, a...)
+	}
+}
+
+// Print a line of a command result
+func (logm *PackageManager) Resultln(a ...interface{}) {
+	fmt.Fprintln(logm._output(), a...)
+}
+
+// Print a formatted message of a command result
+func (logm *PackageManager) Resultf(format string, a ...interface{}) {
+	fmt.Fprintf(logm._output(), format, a...)
+}
+
+// Print an error message of a command (to stderr)
+func (logm *PackageManager) Errorln(a ...interface{}) {
+	fmt.Fprintln(logm._errorOutput(), a...)
+}
+
+// helper function to get the writer of the CLI output
+func (logm *PackageManager) _output() io.Writer {
+
+	if logm.Output == nil {
+		return os.Stdout
+	}
+
+	return logm.Output
+
+}
+
+// helper function to get the writer of the CLI errors
+func (logm *PackageManager) _errorOutput() io.Writer {
+
+	if logm.ErrorOutput == nil {
+		return os.Stderr
+	}
+
+	return logm.ErrorOutput
+
+}
@@ -0,0 +1,85 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package logger
+
+import (
+
+	// standard
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestQuietSuppressesDecorativeOutput(t *testing.T) {
+	var output, errors bytes.Buffer
+	logm := &PackageManager{Output: &output, ErrorOutput: &errors}
+
+	// the normal verbosity prints everything
+	logm.SetVerbosity(VERBOSITY_NORMAL)
+	logm.Println("header")
+	logm.Resultln("result")
+	if output.String() != "header\nresult\n" {
+		t.Fatalf("got output %q", output.String())
+	}
+
+	// the quiet verbosity only prints results and errors
+	output.Reset()
+	logm.SetVerbosity(VERBOSITY_QUIET)
+	logm.Println("header")
+	logm.Printf("hint %v\n", 1)
+	logm.Resultf("result %v\n", 2)
+	logm.Errorln("failed")
+	if output.String() != "result 2\n" {
+		t.Errorf("got output %q, want only the result", output.String())
+	}
+	if errors.String() != "failed\n" {
+		t.Errorf("got errors %q, want the error", errors.String())
+	}
+	logm.SetVerbosity(VERBOSITY_NORMAL)
+}
+
+func TestErrorsAreWrittenToTheErrorOutput(t *testing.T) {
+	var output, errors bytes.Buffer
+	logm := &PackageManager{Output: &output, ErrorOutput: &errors}
+
+	logm.Errorln("Error:", "failed")
+	if output.Len() != 0 {
+		t.Errorf("got output %q, want no output", output.String())
+	}
+	if errors.String() != "Error: failed\n" {
+		t.Errorf("got errors %q", errors.String())
+	}
+}
+
+func TestVerbosityFromArgs(t *testing.T) {
+	cases := map[string]int{
+		"":                VERBOSITY_NORMAL,
+		"-q":              VERBOSITY_QUIET,
+		"--quiet":         VERBOSITY_QUIET,
+		"--debug":         VERBOSITY_DEBUG,
+		"--quiet --debug": VERBOSITY_DEBUG,
+	}
+	for args, want := range cases {
+		if got := _verbosityFromArgs(strings.Fields(args)); got != want {
+			t.Errorf("%q: got verbosity %v, want %v", args, got, want)
+		}
+	}
+}
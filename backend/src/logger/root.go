@@ -32,6 +32,7 @@ import (
 
 	// standard
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -56,6 +57,11 @@ type PackageManager struct {
 	// Writers
 	FileWriter    *os.File
 	ConsoleWriter zerolog.ConsoleWriter
+	Output        io.Writer // Writer of the CLI output (default: stdout)
+	ErrorOutput   io.Writer // Writer of the CLI errors (default: stderr)
+
+	// Verbosity of the loggers and the CLI output (VERBOSITY_*)
+	Verbosity int
 
 	// Loggers
 	Main    *zerolog.Logger
@@ -124,6 +130,9 @@ func (logm *PackageManager) Init() error {
 	logm.AddPackageLogger("jsonrpc")
 	logm.AddPackageLogger("cli")
 
+	// set the verbosity requested on the command line
+	logm.SetVerbosity(_verbosityFromArgs(os.Args[1:]))
+
 	return err
 
 }
@@ -159,9 +168,9 @@ func (logm *PackageManager) CreateCommand() *cobra.Command {
 		Use:   "logger",
 		Short: "Commands for the interaction with the Renderhive Service App logger",
 		Long:  "This command and its sub-commands enable the interaction with the logger of the Renderhive Service App",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
-			return
+			return nil
 
 		},
 	}
@@ -26,15 +26,17 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
 	"sync"
+	"syscall"
 
 	// external
 	// hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
 
 	// internal
 	// . "renderhive/globals"
+	"renderhive/cli"
 	"renderhive/logger"
-	// "renderhive/cli"
 	// "renderhive/hedera"
 	// "renderhive/node"
 )
@@ -49,8 +51,6 @@ func init() {
 
 	// INITIALIZE SERVICE APP
 	// ***************************************************************************
-	// TODO: use the signal library to catch interrupts, so that the app still
-	//       shuts down decently?
 	ServiceApp = AppManager{}
 	ServiceApp.Quit = make(chan bool, 1)
 	ServiceApp.WG = sync.WaitGroup{}
@@ -75,11 +75,22 @@ func init() {
 func main() {
 
 	// prepare end of program
-	defer os.Exit(0)
+	exitCode := 0
+	defer func() { os.Exit(exitCode) }()
 
 	// deinitialize the service app at the end of the main function
 	defer ServiceApp.DeInit()
 
+	// deinitialize the service app on interrupts, so that it still shuts down decently
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		logger.Manager.Main.Info().Msg(fmt.Sprintf("Received signal '%v'.", sig))
+		ServiceApp.DeInit()
+		os.Exit(0)
+	}()
+
 	// placeholder
 	// fmt.Println(time.Now().Add(30 * time.Second))
 
@@ -91,11 +102,27 @@ func main() {
 		// start the command line interface
 		ServiceApp.CLIManager.StartInteractive()
 
+	} else if len(ServiceApp.CLIManager.Commands.Main.Flags().Args()) > 0 {
+
+		// execute the command passed on the command line and exit
+		// NOTE: A failed command exits with a nonzero status (e.g., for health scripts).
+		err := ServiceApp.CLIManager.ExecuteCommand(os.Args[1:])
+		exitCode = cli.ExitCode(err)
+		return
+
 	}
 
 	// BACKEND SERVER(S)
 	// ***************************************************************************
 
+	// subscribe to the topics, if the operator account was loaded from the environment
+	if ServiceApp.EnvironmentAccount {
+		err := ServiceApp.JsonRpcManager.SubscribeTopics()
+		if err != nil {
+			logger.Manager.Main.Error().Msg(fmt.Sprintf("Could not subscribe to the topics: %v", err))
+		}
+	}
+
 	// start local IPFS server in a goroutine (the function does this internally)
 	err := ServiceApp.IPFSManager.StartHTTPServer()
 	if err != nil {
@@ -129,6 +156,43 @@ func main() {
 		}
 	}()
 
+	// start the health-check server in a goroutine
+	if ServiceApp.CLIManager.Commands.MainFlags.HealthAddress != "" {
+		ServiceApp.JsonRpcManager.CreateHealthServer(ServiceApp.CLIManager.Commands.MainFlags.HealthAddress)
+		ServiceApp.WG.Add(1)
+		go func() {
+			defer ServiceApp.WG.Done()
+			err := ServiceApp.JsonRpcManager.StartHealthServer()
+			if err != nil {
+				if err == http.ErrServerClosed {
+					// log information
+					logger.Manager.Package["jsonrpc"].Error().Msg("Health-check server closed gracefully")
+				} else {
+					// log information
+					logger.Manager.Package["jsonrpc"].Error().Msg(fmt.Sprintf("Error starting health-check server: %v", err))
+				}
+			}
+		}()
+	}
+
+	// start the metrics server in a goroutine
+	if ServiceApp.CLIManager.Commands.MainFlags.Metrics {
+		ServiceApp.WG.Add(1)
+		go func() {
+			defer ServiceApp.WG.Done()
+			err := ServiceApp.MetricsManager.StartServer(ServiceApp.CLIManager.Commands.MainFlags.MetricsAddress)
+			if err != nil {
+				if err == http.ErrServerClosed {
+					// log information
+					logger.Manager.Main.Error().Msg("Metrics server closed gracefully")
+				} else {
+					// log information
+					logger.Manager.Main.Error().Msg(fmt.Sprintf("Error starting metrics server: %v", err))
+				}
+			}
+		}()
+	}
+
 	// wait for the wait group to finish
 	ServiceApp.WG.Wait()
 
@@ -0,0 +1,315 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package metrics
+
+/*
+
+The metrics package collects runtime metrics of the Renderhive Service App and
+exports them in the Prometheus format. The metric names are part of the public
+interface for node operators (dashboards, alerting rules) and must be kept stable:
+
+  renderhive_renders_completed_total           Renders finished successfully
+  renderhive_renders_failed_total              Renders finished with an error
+  renderhive_render_duration_seconds           Histogram of render durations
+  renderhive_ipfs_pins_total{result}           Pin operations ("success", "failure")
+  renderhive_topic_messages_processed_total{topic}
+                                               HCS topic messages processed by the callbacks
+  renderhive_contract_calls_total{function,result}
+                                               Smart contract function calls ("success", "failure")
+  renderhive_operator_balance_hbar             Last known balance of the operator account
+  renderhive_ipfs_bandwidth_bytes_total{direction}
+                                               Bytes received ("in") and sent ("out") by the IPFS node
+  renderhive_ipfs_bandwidth_rate_bytes_per_second{direction}
+                                               Current bandwidth of the IPFS node ("in", "out")
+
+*/
+
+import (
+
+	// standard
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	// external
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	// internal
+	"renderhive/logger"
+)
+
+// structure for the metrics manager
+type PackageManager struct {
+
+	// Registry of all metrics
+	// NOTE: A separate registry is used to avoid collisions with the metrics
+	//       registered by the IPFS libraries in the default registry
+	Registry *prometheus.Registry
+
+	// Metrics
+	RendersCompleted       prometheus.Counter
+	RendersFailed          prometheus.Counter
+	RenderDuration         prometheus.Histogram
+	IpfsPins               *prometheus.CounterVec
+	TopicMessagesProcessed *prometheus.CounterVec
+	ContractCalls          *prometheus.CounterVec
+	OperatorBalance        prometheus.Gauge
+
+	// Bandwidth counters of the local IPFS node (read on each scrape)
+	ipfsBandwidth BandwidthSource
+	ipfsMutex     sync.RWMutex
+
+	// HTTP server
+	Server *http.Server
+}
+
+// Source of the bandwidth counters of the local IPFS node (in bytes and bytes per second)
+type BandwidthSource func() (totalIn int64, totalOut int64, rateIn float64, rateOut float64)
+
+// METRICS MANAGER
+// #############################################################################
+// create the metrics manager variable
+var Manager = PackageManager{}
+
+// Initialize everything required for the metrics
+func (metricsm *PackageManager) Init() error {
+	var err error
+
+	// log information
+	logger.Manager.Main.Debug().Msg("Initializing the metrics manager ...")
+
+	// create the registry
+	metricsm.Registry = prometheus.NewRegistry()
+
+	// create the metrics
+	metricsm.RendersCompleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "renderhive_renders_completed_total",
+		Help: "Number of renders finished successfully.",
+	})
+	metricsm.RendersFailed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "renderhive_renders_failed_total",
+		Help: "Number of renders finished with an error.",
+	})
+	metricsm.RenderDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "renderhive_render_duration_seconds",
+		Help:    "Duration of the renders in seconds.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 16),
+	})
+	metricsm.IpfsPins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "renderhive_ipfs_pins_total",
+		Help: "Number of IPFS pin operations by result.",
+	}, []string{"result"})
+	metricsm.TopicMessagesProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "renderhive_topic_messages_processed_total",
+		Help: "Number of HCS topic messages processed by topic.",
+	}, []string{"topic"})
+	metricsm.ContractCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "renderhive_contract_calls_total",
+		Help: "Number of smart contract function calls by function and result.",
+	}, []string{"function", "result"})
+	metricsm.OperatorBalance = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "renderhive_operator_balance_hbar",
+		Help: "Last known balance of the operator account in HBAR.",
+	})
+
+	// register the metrics
+	metricsm.Registry.MustRegister(
+		metricsm.RendersCompleted,
+		metricsm.RendersFailed,
+		metricsm.RenderDuration,
+		metricsm.IpfsPins,
+		metricsm.TopicMessagesProcessed,
+		metricsm.ContractCalls,
+		metricsm.OperatorBalance,
+	)
+
+	// register the bandwidth metrics of the IPFS node for each direction
+	for _, direction := range []string{"in", "out"} {
+		direction := direction
+		metricsm.Registry.MustRegister(
+			prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Name:        "renderhive_ipfs_bandwidth_bytes_total",
+				Help:        "Number of bytes received and sent by the IPFS node.",
+				ConstLabels: prometheus.Labels{"direction": direction},
+			}, func() float64 {
+				total, _ := metricsm._ipfsBandwidth(direction)
+				return total
+			}),
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Name:        "renderhive_ipfs_bandwidth_rate_bytes_per_second",
+				Help:        "Current bandwidth of the IPFS node in bytes per second.",
+				ConstLabels: prometheus.Labels{"direction": direction},
+			}, func() float64 {
+				_, rate := metricsm._ipfsBandwidth(direction)
+				return rate
+			}),
+		)
+	}
+
+	return err
+
+}
+
+// Deinitialize the metrics manager
+func (metricsm *PackageManager) DeInit() error {
+	var err error
+
+	// log event
+	logger.Manager.Main.Debug().Msg("Deinitializing the metrics manager ...")
+
+	// stop the server
+	metricsm.StopServer()
+
+	return err
+
+}
+
+// Start the HTTP server, which exports the metrics on '/metrics'
+func (metricsm *PackageManager) StartServer(address string) error {
+	var err error
+
+	// create a small separate router for the metrics
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(metricsm.Registry, promhttp.HandlerOpts{}))
+
+	// prepare the server
+	metricsm.Server = &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	// log event
+	logger.Manager.Main.Debug().Msg(fmt.Sprintf("Metrics server starting on '%v' ...", address))
+
+	// start the server
+	err = metricsm.Server.ListenAndServe()
+	if err != nil {
+		return err
+	}
+
+	return err
+
+}
+
+// Stop the HTTP server
+func (metricsm *PackageManager) StopServer() {
+
+	// if the server was not started
+	if metricsm.Server == nil {
+		return
+	}
+
+	// shutdown the server
+	err := metricsm.Server.Close()
+	if err != nil {
+		logger.Manager.Main.Error().Msg(fmt.Sprintf("Metrics server shutdown failed: %v", err))
+	}
+
+}
+
+// INSTRUMENTATION
+// #############################################################################
+// NOTE: All functions are safe to call, if the metrics manager was not initialized
+
+// Record a finished render
+func (metricsm *PackageManager) ObserveRender(duration time.Duration, success bool) {
+	if metricsm.Registry == nil {
+		return
+	}
+
+	if success {
+		metricsm.RendersCompleted.Inc()
+	} else {
+		metricsm.RendersFailed.Inc()
+	}
+	metricsm.RenderDuration.Observe(duration.Seconds())
+}
+
+// Record a pin operation
+func (metricsm *PackageManager) ObservePin(success bool) {
+	if metricsm.Registry == nil {
+		return
+	}
+
+	metricsm.IpfsPins.WithLabelValues(_result(success)).Inc()
+}
+
+// Record a processed topic message
+func (metricsm *PackageManager) ObserveTopicMessage(topic string) {
+	if metricsm.Registry == nil {
+		return
+	}
+
+	metricsm.TopicMessagesProcessed.WithLabelValues(topic).Inc()
+}
+
+// Record a smart contract function call
+func (metricsm *PackageManager) ObserveContractCall(function string, success bool) {
+	if metricsm.Registry == nil {
+		return
+	}
+
+	metricsm.ContractCalls.WithLabelValues(function, _result(success)).Inc()
+}
+
+// Set the balance of the operator account
+func (metricsm *PackageManager) SetOperatorBalance(hbar float64) {
+	if metricsm.Registry == nil {
+		return
+	}
+
+	metricsm.OperatorBalance.Set(hbar)
+}
+
+// Set the source of the bandwidth counters of the local IPFS node (nil = none)
+func (metricsm *PackageManager) SetIpfsBandwidthSource(source BandwidthSource) {
+	metricsm.ipfsMutex.Lock()
+	defer metricsm.ipfsMutex.Unlock()
+
+	metricsm.ipfsBandwidth = source
+}
+
+// helper function to get the total bytes and the rate of a bandwidth direction
+func (metricsm *PackageManager) _ipfsBandwidth(direction string) (float64, float64) {
+	metricsm.ipfsMutex.RLock()
+	defer metricsm.ipfsMutex.RUnlock()
+
+	if metricsm.ipfsBandwidth == nil {
+		return 0, 0
+	}
+
+	totalIn, totalOut, rateIn, rateOut := metricsm.ipfsBandwidth()
+	if direction == "in" {
+		return float64(totalIn), rateIn
+	}
+	return float64(totalOut), rateOut
+}
+
+// helper function to get the result label
+func _result(success bool) string {
+	if success {
+		return "success"
+	}
+	return "failure"
+}
@@ -0,0 +1,328 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the announcements of the active render offers of this node.
+
+The submitted render offers are re-announced on the job queue topic in a fixed
+interval, so that other nodes can treat offers, which were not announced for a
+while, as outdated. Paused offers are not announced. When the node shuts down,
+it withdraws its active offers by submitting pause messages, so that the network
+quickly learns that the node is no longer available. The withdrawal is not stored
+in the offers, so the offers are announced again, when the node starts the next
+time.
+
+Both can be set in the optional 'offers.json' file of the configuration
+directory:
+
+    {"announce_interval": "30m", "keep_on_shutdown": false}
+
+An interval of "0" disables the periodic announcements. For quick restarts, the
+withdrawal can also be skipped with the '--keep-offers' flag of the app.
+
+The periodic announcements are submitted in the background, so that a slow
+consensus service does not block the background loop of the app. On shutdown,
+no further announcement is started and the withdrawal waits for a running
+announcement, so that the pause messages are the last messages of the node.
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// Announcement settings of the render offers of this node
+type RenderOfferAnnouncementSettings struct {
+	AnnounceInterval string `json:"announce_interval"` // interval of the re-announcements of the active offers (e.g., "30m"; "0" = never)
+	KeepOnShutdown   bool   `json:"keep_on_shutdown"`  // do not withdraw the active offer when the node shuts down
+}
+
+// ANNOUNCEMENT SETTINGS
+// #############################################################################
+// Get the default announcement settings of the render offers
+func DefaultRenderOfferAnnouncementSettings() RenderOfferAnnouncementSettings {
+	return RenderOfferAnnouncementSettings{
+		AnnounceInterval: RENDERHIVE_CONFIG_OFFER_ANNOUNCE_INTERVAL.String(),
+		KeepOnShutdown:   false,
+	}
+}
+
+// Read the announcement settings from the configuration file
+func (settings *RenderOfferAnnouncementSettings) Read() error {
+	var err error
+
+	// Open the configuration file
+	file, err := os.Open(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "offers.json"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Read the file content
+	fileData, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	// get the file content into a structure
+	err = json.Unmarshal(fileData, settings)
+	if err != nil {
+		return err
+	}
+
+	return settings.Validate()
+
+}
+
+// Check the announcement settings for invalid values
+func (settings *RenderOfferAnnouncementSettings) Validate() error {
+
+	_, err := settings.Interval()
+
+	return err
+
+}
+
+// Get the interval of the re-announcements (zero, if disabled)
+func (settings *RenderOfferAnnouncementSettings) Interval() (time.Duration, error) {
+
+	if settings.AnnounceInterval == "" {
+		return RENDERHIVE_CONFIG_OFFER_ANNOUNCE_INTERVAL, nil
+	}
+
+	interval, err := time.ParseDuration(settings.AnnounceInterval)
+	if err != nil {
+		return 0, newRenderError(ErrInvalidArgument, "Invalid announce interval '%v': %w", settings.AnnounceInterval, err)
+	}
+	if interval < 0 {
+		return 0, newRenderError(ErrInvalidArgument, "The announce interval must not be negative.")
+	}
+	if interval > 0 && interval < RENDERHIVE_CONFIG_OFFER_ANNOUNCE_MINIMUM_INTERVAL {
+		return 0, newRenderError(ErrInvalidArgument, "The announce interval must be at least %v.", RENDERHIVE_CONFIG_OFFER_ANNOUNCE_MINIMUM_INTERVAL)
+	}
+
+	return interval, nil
+
+}
+
+// Get the announcement settings of this node (the configured or the default settings)
+func (nm *PackageManager) GetRenderOfferAnnouncementSettings() RenderOfferAnnouncementSettings {
+
+	// read the configured settings
+	settings := DefaultRenderOfferAnnouncementSettings()
+	err := settings.Read()
+	if err != nil {
+		return DefaultRenderOfferAnnouncementSettings()
+	}
+
+	return settings
+
+}
+
+// OFFER ANNOUNCEMENTS
+// #############################################################################
+// Re-announce the active render offers of this node periodically (in the background)
+// NOTE: The offers are announced once after the start of the node, since they
+// may have been withdrawn on the last shutdown.
+func (nm *PackageManager) CheckRenderOfferAnnouncements() error {
+
+	nm.announceMutex.Lock()
+	defer nm.announceMutex.Unlock()
+
+	// check at most once per minute and not during a running announcement or the shutdown
+	if nm.announcing || nm.Context().Err() != nil || time.Since(nm.lastAnnouncementCheck) < RENDERHIVE_CONFIG_OFFER_ANNOUNCE_MINIMUM_INTERVAL {
+		return nil
+	}
+	nm.lastAnnouncementCheck = time.Now()
+
+	// only submitted and not paused offers are announced
+	offers := []*RenderOffer{}
+	for _, offer := range nm.GetActiveRenderOffers() {
+		if _isAnnounced(offer) {
+			offers = append(offers, offer)
+		}
+	}
+	if len(offers) == 0 || (nm.JobQueueTopic == nil && nm.submitOfferMessage == nil) {
+		return nil
+	}
+
+	settings := nm.GetRenderOfferAnnouncementSettings()
+	interval, err := settings.Interval()
+	if err != nil {
+		return err
+	}
+
+	// announce the offers on start and after each interval
+	if !nm.lastAnnouncement.IsZero() && (interval == 0 || time.Since(nm.lastAnnouncement) < interval) {
+		return nil
+	}
+	announced := !nm.lastAnnouncement.IsZero()
+
+	nm.announcing = true
+	nm.announceGroup.Add(1)
+	go func() {
+		defer nm.announceGroup.Done()
+
+		err := nm._announceRenderOffers(offers, announced)
+		if err != nil {
+			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Error in render offer announcement: %v", err))
+		}
+
+		nm.announceMutex.Lock()
+		nm.announcing = false
+		nm.announceMutex.Unlock()
+	}()
+
+	return nil
+
+}
+
+// Announce a render offer of this node on the job queue topic
+func (nm *PackageManager) AnnounceRenderOffer(offer *RenderOffer) error {
+
+	err := nm._submitRenderOfferMessage(offer, METHOD_NODE_SUBMIT_RENDER_OFFER, "renderhive-v0.1.0::submit-render-offer", &SubmitRenderOfferArgs{RenderOfferCID: offer.DocumentCID, Benchmarks: _offerBenchmarks(offer)})
+	if err != nil {
+		return err
+	}
+	nm.announceMutex.Lock()
+	nm.lastAnnouncement = time.Now()
+	nm.announceMutex.Unlock()
+
+	// log event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf("Announced render offer '%v'.", offer.DocumentCID))
+
+	return nil
+
+}
+
+// Withdraw the active render offers of this node from the network
+// NOTE: The offers are paused on the job queue topic, but stay active on this
+// node. This is called when the node shuts down (after the node manager was
+// canceled, so that no further announcement is started).
+func (nm *PackageManager) WithdrawRenderOffer() error {
+	var errs []error
+
+	// wait for a running announcement, which would otherwise follow the withdrawal
+	nm.announceGroup.Wait()
+
+	// only submitted and not paused offers are withdrawn
+	for _, offer := range nm.GetActiveRenderOffers() {
+		if !_isAnnounced(offer) {
+			continue
+		}
+
+		// log event
+		logger.Manager.Package["node"].Info().Msg(fmt.Sprintf("Withdrawing render offer '%v' from the network ...", offer.DocumentCID))
+
+		err := nm._submitRenderOfferMessage(offer, METHOD_NODE_PAUSE_RENDER_OFFER, "renderhive-v0.1.0::pause-render-offer", &PauseRenderOfferArgs{RenderOfferCID: offer.DocumentCID})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	nm.announceMutex.Lock()
+	nm.lastAnnouncement = time.Time{}
+	nm.announceMutex.Unlock()
+
+	return errors.Join(errs...)
+
+}
+
+// helper function to announce the render offers and publish the expired offers
+func (nm *PackageManager) _announceRenderOffers(offers []*RenderOffer, announced bool) error {
+	var errs []error
+
+	for _, offer := range offers {
+
+		// stop on shutdown, since the offers are withdrawn
+		if nm.Context().Err() != nil {
+			break
+		}
+		err := nm.AnnounceRenderOffer(offer)
+
+		// the other nodes treat the offer as outdated, if it was not re-announced in time
+		nm.announceMutex.Lock()
+		expire := err != nil && announced && !nm.expiredOffers[offer.DocumentCID]
+		if expire {
+			if nm.expiredOffers == nil {
+				nm.expiredOffers = make(map[string]bool)
+			}
+			nm.expiredOffers[offer.DocumentCID] = true
+		} else if err == nil {
+			delete(nm.expiredOffers, offer.DocumentCID)
+		}
+		nm.announceMutex.Unlock()
+		if expire {
+			nm.PublishEvent(EVENT_OFFER_EXPIRED, OfferEventData{
+				RenderOfferCID: offer.DocumentCID,
+				Reason:         err.Error(),
+			})
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+
+}
+
+// helper function to submit a render offer message with the node's account
+func (nm *PackageManager) _submitRenderOfferMessage(offer *RenderOffer, method int, memo string, args interface{}) error {
+
+	jsonMessage, err := nm.EncodeCommand([]string{}, SERVICE_NODE, method, args)
+	if err != nil {
+		return err
+	}
+	if nm.submitOfferMessage != nil {
+		return nm.submitOfferMessage(jsonMessage, memo)
+	}
+	if nm.JobQueueTopic == nil {
+		return newRenderError(ErrNetworkUnavailable, "Render offer '%v' could not be announced: Not subscribed to the job queue topic.", offer.DocumentCID)
+	}
+	_, _, err = nm.JobQueueTopic.SubmitMessage(jsonMessage, memo, nil)
+	if err != nil {
+		return newRenderError(ErrTransactionFailed, "Render offer '%v' could not be announced: %w.", offer.DocumentCID, err)
+	}
+
+	return nil
+
+}
+
+// helper function to check if a render offer was submitted and is not paused
+func _isAnnounced(offer *RenderOffer) bool {
+
+	return offer != nil && offer.DocumentCID != "" && !offer.SubmittedTimestamp.IsZero() && !offer.Paused
+
+}
@@ -0,0 +1,87 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+	// standard
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRenderOfferAnnouncementShutdown(t *testing.T) {
+	offer := &RenderOffer{DocumentCID: "offer-cpu", BlenderVersions: []RenderOfferBlenderVersions{{Devices: []string{"CPU"}}}}
+	nm := _testOfferManager(t, offer)
+	nm.ctx, nm.cancel = context.WithCancel(context.Background())
+
+	// the consensus service answers only after the release
+	var mutex sync.Mutex
+	var memos []string
+	started := make(chan struct{})
+	release := make(chan struct{})
+	nm.submitOfferMessage = func(message string, memo string) error {
+		if memo == "renderhive-v0.1.0::submit-render-offer" {
+			close(started)
+			<-release
+		}
+		mutex.Lock()
+		memos = append(memos, memo)
+		mutex.Unlock()
+		return nil
+	}
+
+	// the announcement does not block the background loop of the app
+	start := time.Now()
+	if err := nm.CheckRenderOfferAnnouncements(); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("the announcement blocked for %v", time.Since(start))
+	}
+
+	// the shutdown waits for the running announcement
+	<-started
+	nm.Cancel()
+	withdrawn := make(chan error)
+	go func() { withdrawn <- nm.WithdrawRenderOffer() }()
+	select {
+	case <-withdrawn:
+		t.Fatalf("the offer was withdrawn during the announcement")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	if err := <-withdrawn; err != nil {
+		t.Fatal(err)
+	}
+
+	// the withdrawal is the last message and no announcement starts after the shutdown
+	nm.lastAnnouncementCheck = time.Time{}
+	if err := nm.CheckRenderOfferAnnouncements(); err != nil {
+		t.Fatal(err)
+	}
+	nm.announceGroup.Wait()
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(memos) != 2 || memos[0] != "renderhive-v0.1.0::submit-render-offer" || memos[1] != "renderhive-v0.1.0::pause-render-offer" {
+		t.Errorf("got messages %v, want the announcement followed by the withdrawal", memos)
+	}
+}
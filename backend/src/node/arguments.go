@@ -0,0 +1,188 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the command line arguments of Blender for rendering.
+
+The arguments are created from the render settings and never taken from the
+user or a render request directly. Blender executes python code with several of
+its arguments (e.g., '--python-expr') and the python scripts embedded in a
+Blender file with '--enable-autoexec'. Therefore, the created arguments always
+disable the auto-execution and are checked against a denylist of these
+arguments. The vetted internal scripts and the trusted setup scripts are added
+after the check.
+
+*/
+
+import (
+
+	// standard
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Blender arguments, which execute python code
+var blenderArgumentDenylist = []string{
+	"-P", "--python",
+	"--python-text",
+	"--python-expr",
+	"--python-console",
+	"-y", "--enable-autoexec",
+}
+
+// file formats of Blender's '-F' argument
+var blenderFileFormatPattern = regexp.MustCompile(`^[A-Z0-9_]+$`)
+
+// BLENDER ARGUMENTS
+// #############################################################################
+// Create the Blender arguments to render the frames of a Blender file into the output path
+// NOTE: The output path may contain '#' characters for the frame number. The
+// tile size of the settings (if any) overrides the tile size of the Blender file
+// and the region of the settings (if any) restricts the rendering to this region.
+func BlenderRenderArguments(blend_file string, output string, settings RenderSettings) ([]string, error) {
+
+	// paths must not be mistaken for arguments
+	if blend_file == "" || strings.HasPrefix(blend_file, "-") {
+		return nil, newRenderError(ErrInvalidArgument, "Invalid path of the Blender file '%v'.", blend_file)
+	}
+	if output == "" || strings.HasPrefix(output, "-") {
+		return nil, newRenderError(ErrInvalidArgument, "Invalid output path '%v'.", output)
+	}
+
+	// check the frame range
+	step := settings.FrameStep
+	if step < 1 {
+		step = 1
+	}
+	if settings.FrameStart < 0 || settings.FrameEnd < settings.FrameStart {
+		return nil, newRenderError(ErrInvalidArgument, "Invalid frame range %v-%v.", settings.FrameStart, settings.FrameEnd)
+	}
+
+	// NOTE: The auto-execution must be disabled before the Blender file is loaded.
+	args := []string{"--disable-autoexec", blend_file, "-o", output}
+
+	// use the render engine and file format of the settings (if any)
+	if settings.Engine != "" {
+		engine, ok := _getEngineIdentifier(settings.Engine)
+		if !ok {
+			return nil, newRenderError(ErrInvalidArgument, "Invalid render engine '%v'.", settings.Engine)
+		}
+		args = append(args, "-E", engine)
+	}
+	if settings.FileFormat != "" {
+		if !blenderFileFormatPattern.MatchString(settings.FileFormat) {
+			return nil, newRenderError(ErrInvalidArgument, "Invalid file format '%v'.", settings.FileFormat)
+		}
+		args = append(args, "-F", settings.FileFormat)
+	}
+
+	// render the frame range
+	args = append(args,
+		"-s", strconv.Itoa(settings.FrameStart),
+		"-e", strconv.Itoa(settings.FrameEnd),
+		"-j", strconv.Itoa(step),
+		"-a",
+	)
+	err := CheckBlenderArguments(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// set the tile size before the frames are rendered
+	if settings.TileX > 0 || settings.TileY > 0 {
+		args = append(args[:len(args)-1], "--python-expr", _tileSizeExpression(settings.TileX, settings.TileY), "-a")
+	}
+
+	// set the render border of the region before the frames are rendered
+	if settings.Region != nil {
+		regionArgs, err := settings.Region.BlenderArgs()
+		if err != nil {
+			return nil, newRenderError(ErrInvalidArgument, "%w", err)
+		}
+		args = append(append(args[:len(args)-1], regionArgs...), "-a")
+	}
+
+	return args, nil
+
+}
+
+// helper function to create the Python expression, which sets the tile size of the scene
+// NOTE: Blender has no command line flag for the tile size. The expression is
+// generated internally from the numeric tile size only (no user input is passed
+// to Python). Blender 3.0 and newer use a single (square) tile size in Cycles.
+func _tileSizeExpression(tileX int, tileY int) string {
+
+	if tileX <= 0 {
+		tileX = tileY
+	}
+	if tileY <= 0 {
+		tileY = tileX
+	}
+	tileSize := tileX
+	if tileY < tileSize {
+		tileSize = tileY
+	}
+
+	return fmt.Sprintf(
+		"import bpy\n"+
+			"s = bpy.context.scene\n"+
+			"if hasattr(s.render, 'tile_x'): s.render.tile_x, s.render.tile_y = %d, %d\n"+
+			"if hasattr(s, 'cycles') and hasattr(s.cycles, 'tile_size'): s.cycles.use_auto_tile, s.cycles.tile_size = True, %d\n",
+		tileX, tileY, tileSize,
+	)
+
+}
+
+// Check that the Blender arguments do not execute python code
+func CheckBlenderArguments(args []string) error {
+
+	for _, arg := range args {
+		name, _, _ := strings.Cut(arg, "=")
+		for _, denied := range blenderArgumentDenylist {
+			if name == denied {
+				return newRenderError(ErrInvalidArgument, "The Blender argument '%v' is not allowed.", arg)
+			}
+		}
+	}
+
+	return nil
+
+}
+
+// helper function to convert the engine names of the service app to Blender's engine identifiers
+func _getEngineIdentifier(engine string) (string, bool) {
+
+	switch strings.ToUpper(engine) {
+	case "CYCLES":
+		return "CYCLES", true
+	case "EEVEE":
+		return "BLENDER_EEVEE", true
+	case "EEVEE_NEXT":
+		return "BLENDER_EEVEE_NEXT", true
+	}
+
+	return "", false
+
+}
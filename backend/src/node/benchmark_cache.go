@@ -0,0 +1,256 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the download cache of the Blender benchmark tool.
+
+The benchmark tool downloads a Blender version and a benchmark scene before
+each benchmark. The downloads are kept in a cache directory of the app data,
+which persists across restarts: The benchmark tool is started with its user
+cache directory (XDG_CACHE_HOME) pointing to this directory.
+
+The files each download adds to the cache directory are recorded with their
+SHA-256 checksums in the cache manifest ('manifest.json'). A later download of
+the same Blender version or scene is skipped, if all its files still exist and
+match their checksums. Otherwise, the download is repeated and the entry of the
+manifest is replaced. If a download fails or is canceled, the files it added or
+changed are removed, so the cache only contains complete downloads.
+
+*/
+
+import (
+
+	// standard
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// name of the cache manifest in the cache directory
+const benchmarkCacheManifest = "manifest.json"
+
+// Cached download of the Blender benchmark tool
+type BenchmarkCacheEntry struct {
+	Files      map[string]string `json:"files"`      // SHA-256 checksums by path (relative to the cache directory)
+	Downloaded time.Time         `json:"downloaded"` // datetime of the download
+}
+
+// BENCHMARK DOWNLOAD CACHE
+// #############################################################################
+// Get the cache directory of the Blender benchmark tool
+func (tool *BlenderBenchmarkTool) CachePath() string {
+
+	if tool.CacheDirectory != "" {
+		return tool.CacheDirectory
+	}
+
+	return filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_BLENDER_BENCHMARK_CACHE)
+
+}
+
+// Check if the files of a cached download still match their checksums
+func (entry *BenchmarkCacheEntry) Validate(directory string) error {
+
+	if len(entry.Files) == 0 {
+		return fmt.Errorf("The cache entry has no files.")
+	}
+
+	for file, checksum := range entry.Files {
+		actual, err := _sha256File(filepath.Join(directory, file))
+		if err != nil {
+			return err
+		}
+		if actual != checksum {
+			return fmt.Errorf("File '%v' does not match its checksum.", file)
+		}
+	}
+
+	return nil
+
+}
+
+// helper function to run a download of the benchmark tool, unless it is cached
+func (tool *BlenderBenchmarkTool) _download(ctx context.Context, path string, key string, args []string) error {
+	var err error
+
+	// create the cache directory
+	directory := tool.CachePath()
+	err = os.MkdirAll(directory, 0700)
+	if err != nil {
+		return err
+	}
+	manifest := _readBenchmarkCache(directory)
+
+	// use the cached download
+	if entry, ok := manifest[key]; ok && !tool.NoCache {
+		err = entry.Validate(directory)
+		if err == nil {
+			logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Using the cached download of '%v'", key))
+			return nil
+		}
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Cached download of '%v' is invalid: %v", key, err))
+	}
+
+	// download and record the new or changed files
+	before, err := _listCacheFiles(directory)
+	if err != nil {
+		return err
+	}
+	_, err = tool._execute(ctx, path, args)
+	after, listErr := _listCacheFiles(directory)
+	if err != nil {
+
+		// remove the files of an interrupted download, which are not in the manifest
+		if listErr == nil {
+			_removeCacheChanges(directory, before, after)
+		}
+
+		return err
+	}
+	if listErr != nil {
+		return listErr
+	}
+
+	entry := BenchmarkCacheEntry{Files: map[string]string{}, Downloaded: time.Now()}
+	for file, modified := range after {
+		if previous, ok := before[file]; ok && previous.Equal(modified) {
+			continue
+		}
+		entry.Files[file], err = _sha256File(filepath.Join(directory, file))
+		if err != nil {
+			return err
+		}
+	}
+
+	// NOTE: A download without new files cannot be validated later.
+	delete(manifest, key)
+	if len(entry.Files) > 0 {
+		manifest[key] = entry
+	}
+
+	return _writeBenchmarkCache(directory, manifest)
+
+}
+
+// helper function to remove the files that were added or changed in the cache directory
+func _removeCacheChanges(directory string, before map[string]time.Time, after map[string]time.Time) {
+
+	for file, modified := range after {
+		if previous, ok := before[file]; ok && previous.Equal(modified) {
+			continue
+		}
+		err := os.Remove(filepath.Join(directory, file))
+		if err != nil {
+			logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Could not remove the partial download '%v': %v", file, err))
+		}
+	}
+
+}
+
+// helper function to read the cache manifest (empty, if it does not exist)
+func _readBenchmarkCache(directory string) map[string]BenchmarkCacheEntry {
+
+	manifest := map[string]BenchmarkCacheEntry{}
+	data, err := os.ReadFile(filepath.Join(directory, benchmarkCacheManifest))
+	if err != nil {
+		return manifest
+	}
+	err = json.Unmarshal(data, &manifest)
+	if err != nil {
+		logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Could not read the benchmark cache manifest: %v", err))
+		return map[string]BenchmarkCacheEntry{}
+	}
+
+	return manifest
+
+}
+
+// helper function to write the cache manifest
+func _writeBenchmarkCache(directory string, manifest map[string]BenchmarkCacheEntry) error {
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(directory, benchmarkCacheManifest), data, 0600)
+
+}
+
+// helper function to list the files of the cache directory with their modification time
+func _listCacheFiles(directory string) (map[string]time.Time, error) {
+
+	files := map[string]time.Time{}
+	err := filepath.WalkDir(directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		file, err := filepath.Rel(directory, path)
+		if err != nil || file == benchmarkCacheManifest {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		files[file] = info.ModTime()
+		return nil
+	})
+
+	return files, err
+
+}
+
+// helper function to calculate the SHA-256 checksum of a file
+func _sha256File(path string) (string, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+
+}
@@ -0,0 +1,115 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the lookup of the Blender benchmark launcher (the command
+line interface of the official Blender benchmark tool).
+
+The launcher is looked up in the following order:
+
+  1. the launcher file passed with '--launcher' (if any)
+  2. the launcher directory of the app data
+  3. the 'benchmark' directory next to the Renderhive executable
+  4. the 'benchmark' directory of the working directory (e.g., with 'go run',
+     whose executable is built into a temporary directory)
+
+The launcher is not downloaded automatically, because the checksums of the
+official launcher archives are not published. It must be installed manually
+from Blender Open Data. If the launcher is not found, the error explains where
+to place it.
+
+*/
+
+import (
+
+	// standard
+	"os"
+	"path/filepath"
+	"runtime"
+
+	// internal
+	. "renderhive/globals"
+	. "renderhive/utility"
+)
+
+// BENCHMARK LAUNCHER
+// #############################################################################
+// Get the file name of the Blender benchmark launcher on this platform
+func BenchmarkLauncherName() string {
+
+	if runtime.GOOS == "windows" {
+		return "benchmark-launcher-cli.exe"
+	}
+
+	return "benchmark-launcher-cli"
+
+}
+
+// Get the path of the Blender benchmark launcher in the app data
+func BenchmarkLauncherPath() string {
+	return filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_BLENDER_BENCHMARK_LAUNCHER, BenchmarkLauncherName())
+}
+
+// Find the Blender benchmark launcher in the app data, next to the executable, or in the working directory
+// NOTE: The launcher is not downloaded.
+func FindBenchmarkLauncher() (string, bool) {
+
+	candidates := []string{BenchmarkLauncherPath()}
+	if executable, err := os.Executable(); err == nil {
+		candidates = append(candidates, filepath.Join(filepath.Dir(executable), "benchmark", BenchmarkLauncherName()))
+	}
+	if path, err := filepath.Abs(filepath.Join("benchmark", BenchmarkLauncherName())); err == nil {
+		candidates = append(candidates, path)
+	}
+	for _, path := range candidates {
+		if ok, _ := IsFile(path); ok {
+			return path, true
+		}
+	}
+
+	return "", false
+
+}
+
+// Find the Blender benchmark launcher
+// NOTE: Returns an ErrBenchmarkUnavailable error with instructions, if the
+// launcher is not installed.
+func (tool *BlenderBenchmarkTool) LauncherPath() (string, error) {
+
+	// use the launcher file of the operator
+	if tool.LauncherFile != "" {
+		path, err := filepath.Abs(tool.LauncherFile)
+		if ok, _ := IsFile(path); err != nil || !ok {
+			return "", newRenderError(ErrBenchmarkUnavailable, "The Blender benchmark launcher '%v' does not exist. Check the path passed with '--launcher'.", tool.LauncherFile)
+		}
+		return path, nil
+	}
+
+	// look for the launcher in the app data and next to the executable
+	if path, ok := FindBenchmarkLauncher(); ok {
+		return path, nil
+	}
+
+	return "", newRenderError(ErrBenchmarkUnavailable, "The Blender benchmark launcher was not found. Download the benchmark launcher CLI from https://opendata.blender.org and place it at '%v', or pass its path with '--launcher'.", BenchmarkLauncherPath())
+
+}
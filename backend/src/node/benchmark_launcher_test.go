@@ -0,0 +1,83 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+	// standard
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindBenchmarkLauncherInWorkingDirectory(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := _chdirTemp(t)
+
+	// no launcher was placed yet
+	if path, ok := FindBenchmarkLauncher(); ok {
+		t.Fatalf("found an unexpected launcher: %v", path)
+	}
+
+	// the launcher in the 'benchmark' directory of the working directory is found (e.g., with 'go run')
+	launcher := filepath.Join(dir, "benchmark", BenchmarkLauncherName())
+	os.MkdirAll(filepath.Dir(launcher), 0700)
+	os.WriteFile(launcher, []byte{}, 0700)
+	path, ok := FindBenchmarkLauncher()
+	if !ok {
+		t.Fatal("the launcher in the working directory was not found")
+	}
+	resolved, _ := filepath.EvalSymlinks(path)
+	expected, _ := filepath.EvalSymlinks(launcher)
+	if resolved != expected {
+		t.Errorf("got launcher %v, want %v", path, launcher)
+	}
+
+	// the launcher of the app data is preferred
+	os.MkdirAll(filepath.Dir(BenchmarkLauncherPath()), 0700)
+	os.WriteFile(BenchmarkLauncherPath(), []byte{}, 0700)
+	if path, _ = FindBenchmarkLauncher(); path != BenchmarkLauncherPath() {
+		t.Errorf("got launcher %v, want the launcher of the app data", path)
+	}
+}
+
+func TestMissingBenchmarkLauncherIsActionable(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	_chdirTemp(t)
+
+	// the error names the expected path and the download page
+	_, err := (&BlenderBenchmarkTool{}).LauncherPath()
+	if !errors.Is(err, ErrBenchmarkUnavailable) {
+		t.Fatalf("got %v, want an error of the kind %q", err, ErrBenchmarkUnavailable)
+	}
+	for _, hint := range []string{BenchmarkLauncherPath(), "https://opendata.blender.org", "--launcher"} {
+		if !strings.Contains(err.Error(), hint) {
+			t.Errorf("the error %q does not mention %q", err, hint)
+		}
+	}
+
+	// a missing launcher file of the operator is reported as well
+	_, err = (&BlenderBenchmarkTool{LauncherFile: "missing/launcher"}).LauncherPath()
+	if !errors.Is(err, ErrBenchmarkUnavailable) || !strings.Contains(err.Error(), "missing/launcher") {
+		t.Errorf("got %v, want an error naming the launcher file", err)
+	}
+}
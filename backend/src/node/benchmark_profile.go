@@ -0,0 +1,181 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the benchmark of all Blender versions of a render offer.
+
+The Blender versions are benchmarked one after another, since benchmarks
+running in parallel would compete for the same devices and distort each other's
+results. The result of each version is stored with its Blender benchmark tool as
+for a single benchmark. A failed benchmark does not stop the benchmarks of the
+other versions, but a canceled benchmark does.
+
+The benchmarks are combined into a capability profile of the render offer, which
+lists the render score (average samples per minute of the benchmark scenes) of
+each version and the range of the render scores over all versions.
+
+*/
+
+import (
+
+	// standard
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// Benchmark of a Blender version of a render offer
+type BlenderVersionBenchmark struct {
+	Version          string        // Blender version
+	SamplesPerMinute float64       // average samples per minute of the benchmark scenes (0, if the benchmark failed)
+	Duration         time.Duration // duration of the benchmark
+	Err              error         // nil, if the benchmark succeeded
+}
+
+// Combined capability profile of the Blender versions of a render offer
+type RenderOfferBenchmarkProfile struct {
+	Versions  []BlenderVersionBenchmark // benchmarks of all versions (oldest version first)
+	Succeeded []string                  // versions with a render score
+	Failed    []string                  // versions without a render score
+
+	// Render scores of the benchmarked versions (in samples per minute)
+	Fastest string  // version with the highest render score
+	Min     float64 // lowest render score
+	Max     float64 // highest render score
+	Mean    float64 // mean render score
+}
+
+// BENCHMARK PROFILE
+// #############################################################################
+// Benchmark all Blender versions of the render offer one after another
+// NOTE: The progress function is called after each benchmark (may be nil).
+func (ro *RenderOffer) BenchmarkAll(ctx context.Context, device string, scene string, progress func(index int, total int, benchmark BlenderVersionBenchmark)) RenderOfferBenchmarkProfile {
+
+	return ro._benchmarkAll(ctx, func(ctx context.Context, version string) error {
+
+		blender := ro.Blender[version]
+		if blender.BenchmarkTool == nil {
+			return newRenderError(ErrBenchmarkUnavailable, "Blender v%v has no benchmark tool.", version)
+		}
+
+		return blender.BenchmarkTool.Run(ctx, ro, version, device, scene)
+
+	}, progress)
+
+}
+
+// Check if the benchmarks of a profile were successful
+func (profile RenderOfferBenchmarkProfile) Passed() bool {
+
+	return len(profile.Failed) == 0 && len(profile.Succeeded) > 0
+
+}
+
+// helper function to benchmark all Blender versions with the given benchmark function
+func (ro *RenderOffer) _benchmarkAll(ctx context.Context, run func(ctx context.Context, version string) error, progress func(index int, total int, benchmark BlenderVersionBenchmark)) RenderOfferBenchmarkProfile {
+
+	// benchmark the oldest version first
+	versions := make([]string, 0, len(ro.Blender))
+	for version := range ro.Blender {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return CompareBlenderVersions(versions[i], versions[j]) < 0
+	})
+
+	benchmarks := []BlenderVersionBenchmark{}
+	for i, version := range versions {
+		benchmark := BlenderVersionBenchmark{Version: version}
+
+		// the remaining benchmarks are skipped, if the benchmarks were canceled
+		if ctx.Err() != nil {
+			benchmark.Err = newRenderError(ErrBenchmarkCanceled, "The benchmark of Blender v%v was canceled. (Error: %w)", version, ctx.Err())
+		} else {
+
+			// log event
+			logger.Manager.Package["node"].Info().Msg(fmt.Sprintf("Benchmarking Blender v%v (%v/%v) ...", version, i+1, len(versions)))
+
+			started := time.Now()
+			benchmark.Err = run(ctx, version)
+			benchmark.Duration = time.Since(started)
+
+		}
+
+		// get the render score from the stored result
+		if benchmark.Err == nil {
+			var ok bool
+			if tool := ro.Blender[version].BenchmarkTool; tool != nil {
+				benchmark.SamplesPerMinute, ok = _benchmarkThroughput(tool.GetResult())
+			}
+			if !ok {
+				benchmark.Err = newRenderError(ErrBenchmarkUnavailable, "The benchmark of Blender v%v returned no render score.", version)
+			}
+		}
+		if benchmark.Err != nil {
+			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Benchmark of Blender v%v failed: %v", version, benchmark.Err))
+		}
+
+		benchmarks = append(benchmarks, benchmark)
+		if progress != nil {
+			progress(i+1, len(versions), benchmark)
+		}
+	}
+
+	return NewRenderOfferBenchmarkProfile(benchmarks)
+
+}
+
+// Combine the benchmarks of the Blender versions into a capability profile
+func NewRenderOfferBenchmarkProfile(benchmarks []BlenderVersionBenchmark) RenderOfferBenchmarkProfile {
+
+	profile := RenderOfferBenchmarkProfile{Versions: benchmarks, Succeeded: []string{}, Failed: []string{}}
+	sum := 0.0
+	for _, benchmark := range benchmarks {
+		if benchmark.Err != nil {
+			profile.Failed = append(profile.Failed, benchmark.Version)
+			continue
+		}
+		profile.Succeeded = append(profile.Succeeded, benchmark.Version)
+
+		// update the range of the render scores
+		if len(profile.Succeeded) == 1 || benchmark.SamplesPerMinute < profile.Min {
+			profile.Min = benchmark.SamplesPerMinute
+		}
+		if len(profile.Succeeded) == 1 || benchmark.SamplesPerMinute > profile.Max {
+			profile.Max = benchmark.SamplesPerMinute
+			profile.Fastest = benchmark.Version
+		}
+		sum += benchmark.SamplesPerMinute
+	}
+	if len(profile.Succeeded) > 0 {
+		profile.Mean = sum / float64(len(profile.Succeeded))
+	}
+
+	return profile
+
+}
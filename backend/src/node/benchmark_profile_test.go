@@ -0,0 +1,117 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	// internal
+	"renderhive/logger"
+)
+
+// helper function to create a render offer with a benchmark tool per Blender version
+func _testBenchmarkOffer(versions ...string) *RenderOffer {
+	offer := &RenderOffer{Blender: map[string]BlenderAppData{}}
+	for _, version := range versions {
+		offer.Blender[version] = BlenderAppData{BenchmarkTool: &BlenderBenchmarkTool{}}
+	}
+	return offer
+}
+
+func TestBenchmarkAllContinuesPastFailures(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+	offer := _testBenchmarkOffer("4.1.0", "3.6.0", "4.0.0")
+
+	// the mocked benchmark tool stores a render score for all but one version
+	scores := map[string]float64{"3.6.0": 100, "4.1.0": 300}
+	var runs []string
+	run := func(ctx context.Context, version string) error {
+		runs = append(runs, version)
+		score, ok := scores[version]
+		if !ok {
+			return errors.New("benchmark crashed")
+		}
+		offer.Blender[version].BenchmarkTool.SetResult([]BlenderBenchmarkResult{_testBenchmarkResult("CPU", score)})
+		return nil
+	}
+	var progress []string
+	profile := offer._benchmarkAll(context.Background(), run, func(index int, total int, benchmark BlenderVersionBenchmark) {
+		progress = append(progress, benchmark.Version)
+		if total != 3 {
+			t.Errorf("expected 3 versions in total, got %v", total)
+		}
+	})
+
+	// the versions are benchmarked one after another (oldest version first)
+	if strings.Join(runs, ",") != "3.6.0,4.0.0,4.1.0" || strings.Join(progress, ",") != "3.6.0,4.0.0,4.1.0" {
+		t.Errorf("unexpected benchmark order: %v (progress: %v)", runs, progress)
+	}
+	if strings.Join(profile.Succeeded, ",") != "3.6.0,4.1.0" || strings.Join(profile.Failed, ",") != "4.0.0" {
+		t.Errorf("unexpected summary: succeeded %v, failed %v", profile.Succeeded, profile.Failed)
+	}
+	if profile.Fastest != "4.1.0" || profile.Min != 100 || profile.Max != 300 || profile.Mean != 200 {
+		t.Errorf("unexpected capability profile: %+v", profile)
+	}
+	if profile.Passed() {
+		t.Error("expected a profile with a failed version not to pass")
+	}
+}
+
+func TestBenchmarkAllWithoutRenderScore(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+	offer := _testBenchmarkOffer("4.1.0")
+
+	// a benchmark without a stored result has no render score
+	profile := offer._benchmarkAll(context.Background(), func(ctx context.Context, version string) error { return nil }, nil)
+	if len(profile.Failed) != 1 || !errors.Is(profile.Versions[0].Err, ErrBenchmarkUnavailable) {
+		t.Errorf("expected the benchmark without a render score to fail, got %+v", profile.Versions)
+	}
+}
+
+func TestBenchmarkAllStopsWhenCanceled(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+	offer := _testBenchmarkOffer("3.6.0", "4.0.0", "4.1.0")
+
+	// the first benchmark cancels the remaining ones
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runs := 0
+	profile := offer._benchmarkAll(ctx, func(ctx context.Context, version string) error {
+		runs++
+		cancel()
+		return ctx.Err()
+	}, nil)
+
+	if runs != 1 {
+		t.Errorf("expected 1 benchmark run, got %v", runs)
+	}
+	if len(profile.Failed) != 3 || !errors.Is(profile.Versions[2].Err, ErrBenchmarkCanceled) {
+		t.Errorf("expected the remaining benchmarks to be canceled, got %+v", profile.Versions)
+	}
+}
@@ -0,0 +1,474 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the manifest of the supported Blender versions.
+
+The Blender versions supported by the Renderhive network are built into the app
+(see RENDERHIVE_BLENDER_ARCHIVE_FILES). To support a new Blender version without
+an app update, the Renderhive project publishes a manifest of the supported
+versions on IPFS and pins it. On start, the node fetches the manifest from its
+CID in the background and merges it into the built-in versions:
+
+  - a version of the manifest, which is not built in, is added
+  - a platform of a built-in version, which has no built-in archive, is added
+  - a built-in archive is never replaced or removed by the manifest
+
+The manifest is a JSON document:
+
+    {
+      "SchemaVersion": 1,
+      "Versions": {"4.1.1": {"Linux": {"CID": "...", "SHA": "...", "Commit": "...", "Filename": "...", "Size": 0}}},
+      "Signature": "<hex>"
+    }
+
+The CID and the public key are the trusted values: The IPFS node checks each
+block of the download against the CID, and the manifest must carry a valid
+signature of the public key. A manifest is only used, if both are configured.
+The signature covers the JSON encoding of the 'SchemaVersion' and the
+'Versions' by the standard library of Go (without spaces and with sorted keys).
+
+The CID and the public key are RENDERHIVE_BLENDER_MANIFEST_CID and
+RENDERHIVE_BLENDER_MANIFEST_PUBLIC_KEY by default and can be changed in the
+optional 'blender_manifest.json' file of the configuration directory:
+
+    {"cid": "bafy...", "public_key": "302a...", "timeout": "30s"}
+
+NOTE: The Renderhive project did not publish a manifest yet, so that both
+default values are empty and the node only supports the built-in versions until
+a manifest is configured.
+
+A downloaded manifest is cached, so that the node also starts without network.
+The cached copy is checked against the CID and the signature on each start. If
+the manifest cannot be fetched or is invalid, the node only supports the
+built-in versions. Until the manifest is loaded, only the built-in versions are
+available.
+
+*/
+
+import (
+
+	// standard
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/ipfs"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// schema version of the manifest of the supported Blender versions
+const BLENDER_MANIFEST_SCHEMA_VERSION = 1
+
+// valid Blender versions of a manifest (e.g., "4.1.1")
+var blenderManifestVersion = regexp.MustCompile(`^[0-9]+(\.[0-9]+)*$`)
+
+// the Blender versions built into the app
+var builtinBlenderArchiveFiles = _copyBlenderArchiveFiles(RENDERHIVE_BLENDER_ARCHIVE_FILES)
+
+// the Blender versions supported by the render hive (built-in versions and the
+// versions of the loaded manifest) and the CID of the loaded manifest
+// NOTE: The manifest is loaded in the background, so that the map is only
+// replaced (never changed) under the lock.
+var blenderArchiveFiles = builtinBlenderArchiveFiles
+var blenderManifestCID string
+var blenderArchiveMutex sync.RWMutex
+
+// Manifest of the supported Blender versions
+type BlenderArchiveManifest struct {
+	SchemaVersion int                              // Schema version of the manifest
+	Versions      map[string]BlenderArchiveVersion // Blender archives by Blender version
+	Signature     string                           `json:",omitempty"` // Signature of the Renderhive project (hex encoded)
+}
+
+// Settings of the manifest of the supported Blender versions
+type BlenderManifestSettings struct {
+	CID       string `json:"cid"`        // CID of the manifest (empty = only the built-in versions)
+	PublicKey string `json:"public_key"` // public key of the manifest signature (required with a CID)
+	Timeout   string `json:"timeout"`    // maximum time to wait for the manifest (e.g., "30s")
+}
+
+// BLENDER MANIFEST SETTINGS
+// #############################################################################
+// Get the default settings of the manifest of the supported Blender versions
+func DefaultBlenderManifestSettings() BlenderManifestSettings {
+	return BlenderManifestSettings{
+		CID:       RENDERHIVE_BLENDER_MANIFEST_CID,
+		PublicKey: RENDERHIVE_BLENDER_MANIFEST_PUBLIC_KEY,
+		Timeout:   RENDERHIVE_CONFIG_BLENDER_MANIFEST_TIMEOUT.String(),
+	}
+}
+
+// Read the manifest settings from the configuration file
+func (settings *BlenderManifestSettings) Read() error {
+	var err error
+
+	// Open the configuration file
+	file, err := os.Open(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "blender_manifest.json"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Read the file content
+	fileData, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	// get the file content into a structure
+	err = json.Unmarshal(fileData, settings)
+	if err != nil {
+		return err
+	}
+
+	return settings.Validate()
+
+}
+
+// Check the manifest settings for invalid values
+func (settings *BlenderManifestSettings) Validate() error {
+
+	if settings.CID != "" {
+		if _, err := ParseCID(settings.CID); err != nil {
+			return newRenderError(ErrInvalidArgument, "Invalid CID of the Blender manifest '%v': %v", settings.CID, err)
+		}
+	}
+	if settings.PublicKey != "" {
+		if _, err := hederasdk.PublicKeyFromString(settings.PublicKey); err != nil {
+			return newRenderError(ErrInvalidArgument, "Invalid public key of the Blender manifest: %v", err)
+		}
+	} else if settings.CID != "" {
+		return newRenderError(ErrInvalidArgument, "The Blender manifest '%v' requires a public key to check its signature.", settings.CID)
+	}
+	if _, err := settings.TimeoutDuration(); err != nil {
+		return err
+	}
+
+	return nil
+
+}
+
+// Get the maximum time to wait for the manifest
+func (settings *BlenderManifestSettings) TimeoutDuration() (time.Duration, error) {
+
+	timeout, err := time.ParseDuration(settings.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, newRenderError(ErrInvalidArgument, "Invalid timeout of the Blender manifest '%v'.", settings.Timeout)
+	}
+
+	return timeout, nil
+
+}
+
+// Get the manifest settings of this node (the configured or the default settings)
+func (nm *PackageManager) GetBlenderManifestSettings() BlenderManifestSettings {
+
+	// read the configured settings
+	settings := DefaultBlenderManifestSettings()
+	err := settings.Read()
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Invalid Blender manifest settings (using the default settings): %v", err))
+		}
+		return DefaultBlenderManifestSettings()
+	}
+
+	return settings
+
+}
+
+// BLENDER MANIFEST
+// #############################################################################
+// Decode a manifest of the supported Blender versions and check its signature
+func DecodeBlenderArchiveManifest(data []byte, publicKey string) (*BlenderArchiveManifest, error) {
+
+	manifest := &BlenderArchiveManifest{}
+	err := json.Unmarshal(data, manifest)
+	if err != nil {
+		return nil, newRenderError(ErrInvalidArgument, "Blender manifest could not be decoded: %w", err)
+	}
+	if manifest.SchemaVersion < 1 || manifest.SchemaVersion > BLENDER_MANIFEST_SCHEMA_VERSION {
+		return nil, newRenderError(ErrUnsupportedSchema, "Blender manifest has schema version %v (supported: %v).", manifest.SchemaVersion, BLENDER_MANIFEST_SCHEMA_VERSION)
+	}
+	if publicKey == "" {
+		return nil, newRenderError(ErrInvalidArgument, "The signature of the Blender manifest cannot be checked without a public key.")
+	}
+	err = manifest.VerifySignature(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, manifest.Validate()
+
+}
+
+// Check the Blender versions of the manifest for invalid values
+// NOTE: The version, commit, and file name become parts of local paths.
+func (manifest *BlenderArchiveManifest) Validate() error {
+
+	for version, archive := range manifest.Versions {
+		if !blenderManifestVersion.MatchString(version) {
+			return newRenderError(ErrInvalidArgument, "Blender manifest contains the invalid version '%v'.", version)
+		}
+		for _, platform := range []BlenderArchiveFile{archive.Linux, archive.Windows, archive.Macos} {
+			if platform == (BlenderArchiveFile{}) {
+				continue
+			}
+			if _, err := ParseCID(platform.CID); err != nil {
+				return newRenderError(ErrInvalidArgument, "Blender manifest contains an invalid CID for version '%v': %v", version, err)
+			}
+			if platform.SHA == "" || platform.Commit == "" || platform.Filename == "" || platform.Size < 0 {
+				return newRenderError(ErrInvalidArgument, "Blender manifest contains an incomplete archive for version '%v'.", version)
+			}
+			if filepath.Base(platform.Filename) != platform.Filename || filepath.Base(platform.Commit) != platform.Commit || platform.Filename == ".." || platform.Commit == ".." {
+				return newRenderError(ErrInvalidArgument, "Blender manifest contains an invalid file name or commit for version '%v'.", version)
+			}
+		}
+	}
+
+	return nil
+
+}
+
+// Get the signed content of the manifest
+func (manifest *BlenderArchiveManifest) SigningPayload() ([]byte, error) {
+
+	return json.Marshal(struct {
+		SchemaVersion int
+		Versions      map[string]BlenderArchiveVersion
+	}{manifest.SchemaVersion, manifest.Versions})
+
+}
+
+// Sign the manifest with the private key of the Renderhive project
+func (manifest *BlenderArchiveManifest) Sign(privateKey hederasdk.PrivateKey) error {
+
+	payload, err := manifest.SigningPayload()
+	if err != nil {
+		return err
+	}
+	manifest.Signature = hex.EncodeToString(privateKey.Sign(payload))
+
+	return nil
+
+}
+
+// Check the signature of the manifest against the trusted public key
+func (manifest *BlenderArchiveManifest) VerifySignature(publicKey string) error {
+
+	key, err := hederasdk.PublicKeyFromString(publicKey)
+	if err != nil {
+		return newRenderError(ErrInvalidArgument, "Invalid public key of the Blender manifest: %v", err)
+	}
+	signature, err := hex.DecodeString(manifest.Signature)
+	if err != nil || len(signature) == 0 {
+		return newRenderError(ErrDocumentMismatch, "Blender manifest has no valid signature.")
+	}
+	payload, err := manifest.SigningPayload()
+	if err != nil {
+		return err
+	}
+	if !key.Verify(payload, signature) {
+		return newRenderError(ErrDocumentMismatch, "The signature of the Blender manifest does not match the trusted public key.")
+	}
+
+	return nil
+
+}
+
+// Merge the Blender versions of a manifest into the built-in Blender versions
+// (see the description of this file)
+func MergeBlenderArchiveFiles(builtin map[string]BlenderArchiveVersion, manifest map[string]BlenderArchiveVersion) map[string]BlenderArchiveVersion {
+
+	merged := _copyBlenderArchiveFiles(builtin)
+	for version, archive := range manifest {
+		current := merged[version]
+		if current.Linux == (BlenderArchiveFile{}) {
+			current.Linux = archive.Linux
+		}
+		if current.Windows == (BlenderArchiveFile{}) {
+			current.Windows = archive.Windows
+		}
+		if current.Macos == (BlenderArchiveFile{}) {
+			current.Macos = archive.Macos
+		}
+		merged[version] = current
+	}
+
+	return merged
+
+}
+
+// Get the Blender versions supported by the render hive
+// NOTE: The map must not be changed by the caller.
+func GetBlenderArchiveFiles() map[string]BlenderArchiveVersion {
+
+	blenderArchiveMutex.RLock()
+	defer blenderArchiveMutex.RUnlock()
+
+	return blenderArchiveFiles
+
+}
+
+// Get the Blender archives of a Blender version supported by the render hive
+func GetBlenderArchive(version string) (BlenderArchiveVersion, bool) {
+
+	archive, ok := GetBlenderArchiveFiles()[version]
+	return archive, ok
+
+}
+
+// Get the CID of the loaded Blender manifest (empty = only the built-in versions)
+func (nm *PackageManager) GetBlenderManifestCID() string {
+
+	blenderArchiveMutex.RLock()
+	defer blenderArchiveMutex.RUnlock()
+
+	return blenderManifestCID
+
+}
+
+// Fetch the manifest of the supported Blender versions and merge it into the
+// built-in Blender versions
+// NOTE: Until the manifest is loaded and if it cannot be loaded, only the
+// built-in versions are supported.
+func (nm *PackageManager) LoadBlenderArchiveManifest() error {
+
+	_setBlenderArchiveFiles(builtinBlenderArchiveFiles, "")
+
+	settings := nm.GetBlenderManifestSettings()
+	if settings.CID == "" {
+		return nil
+	}
+
+	manifest, err := nm._fetchBlenderArchiveManifest(settings)
+	if err != nil {
+		return fmt.Errorf("Could not load the Blender manifest '%v' (only the built-in Blender versions are supported): %w", settings.CID, err)
+	}
+	_setBlenderArchiveFiles(MergeBlenderArchiveFiles(builtinBlenderArchiveFiles, manifest.Versions), settings.CID)
+
+	// log event
+	logger.Manager.Package["node"].Info().Msg(fmt.Sprintf("Loaded the Blender manifest '%v' with %v Blender version(s).", settings.CID, len(manifest.Versions)))
+
+	return nil
+
+}
+
+// helper function to get the manifest from the local cache or IPFS
+func (nm *PackageManager) _fetchBlenderArchiveManifest(settings BlenderManifestSettings) (*BlenderArchiveManifest, error) {
+
+	// the IPFS node downloads the manifest and checks the cached copy
+	if ipfs.Manager.IpfsAPI == nil {
+		return nil, newRenderError(ErrNetworkUnavailable, "The IPFS node is not running.")
+	}
+
+	path := filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_BLENDER_MANIFESTS, settings.CID+".json")
+	data, err := os.ReadFile(path)
+	if err == nil {
+
+		// the cached copy must still match the CID
+		err = ipfs.Manager.VerifyPath(path, settings.CID)
+		if err != nil {
+			logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Discarding the cached Blender manifest '%v': %v", settings.CID, err))
+			os.Remove(path)
+		}
+
+	}
+	if err != nil {
+
+		// download the manifest
+		timeout, err := settings.TimeoutDuration()
+		if err != nil {
+			return nil, err
+		}
+		err = os.MkdirAll(filepath.Dir(path), 0700)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(nm.ctx, timeout)
+		defer cancel()
+		_, err = ipfs.Manager.GetObjectContext(ctx, settings.CID, path)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			os.Remove(path)
+			return nil, newRenderError(ErrNetworkUnavailable, "The Blender manifest could not be downloaded within %v.", timeout)
+		} else if err != nil {
+			os.Remove(path)
+			return nil, newRenderError(ErrNetworkUnavailable, "The Blender manifest could not be downloaded: %w", err)
+		}
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+	}
+
+	manifest, err := DecodeBlenderArchiveManifest(data, settings.PublicKey)
+	if err != nil {
+
+		// do not keep an invalid document in the cache
+		os.Remove(path)
+		return nil, err
+
+	}
+
+	return manifest, nil
+
+}
+
+// helper function to replace the Blender versions supported by the render hive
+func _setBlenderArchiveFiles(files map[string]BlenderArchiveVersion, manifestCID string) {
+
+	blenderArchiveMutex.Lock()
+	defer blenderArchiveMutex.Unlock()
+
+	blenderArchiveFiles = files
+	blenderManifestCID = manifestCID
+
+}
+
+// helper function to copy a map of Blender versions
+func _copyBlenderArchiveFiles(files map[string]BlenderArchiveVersion) map[string]BlenderArchiveVersion {
+
+	copied := make(map[string]BlenderArchiveVersion, len(files))
+	for version, archive := range files {
+		copied[version] = archive
+	}
+
+	return copied
+
+}
@@ -0,0 +1,164 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+	// standard
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// CID used for the archives and the manifest of the tests
+const testManifestCID = "bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"
+
+// helper function to create an archive of a Blender version
+func _testManifestArchive(commit string) BlenderArchiveFile {
+	return BlenderArchiveFile{CID: testManifestCID, SHA: "00", Commit: commit, Filename: "blender-" + commit + ".tar.xz"}
+}
+
+func TestMergeBlenderArchiveFiles(t *testing.T) {
+	builtin := map[string]BlenderArchiveVersion{
+		"4.1.1": {Linux: _testManifestArchive("builtin")},
+	}
+	manifest := map[string]BlenderArchiveVersion{
+		"4.1.1": {Linux: _testManifestArchive("manifest"), Windows: _testManifestArchive("windows")},
+		"4.2.0": {Linux: _testManifestArchive("new")},
+	}
+
+	merged := MergeBlenderArchiveFiles(builtin, manifest)
+
+	// a built-in archive is kept, a missing platform and a new version are added
+	if merged["4.1.1"].Linux.Commit != "builtin" {
+		t.Errorf("the manifest replaced the built-in archive with %q", merged["4.1.1"].Linux.Commit)
+	}
+	if merged["4.1.1"].Windows.Commit != "windows" {
+		t.Errorf("the missing platform of the built-in version was not added")
+	}
+	if merged["4.2.0"].Linux.Commit != "new" {
+		t.Errorf("the new version of the manifest was not added")
+	}
+
+	// the built-in versions are not changed
+	if len(builtin) != 1 || builtin["4.1.1"].Windows != (BlenderArchiveFile{}) {
+		t.Errorf("the built-in versions were changed: %v", builtin)
+	}
+
+	// without a manifest, only the built-in versions are supported
+	if merged := MergeBlenderArchiveFiles(builtin, nil); len(merged) != 1 || merged["4.1.1"] != builtin["4.1.1"] {
+		t.Errorf("got %v, want the built-in versions", merged)
+	}
+}
+
+func TestDecodeBlenderArchiveManifest(t *testing.T) {
+	key, _ := hederasdk.PrivateKeyGenerateEd25519()
+	otherKey, _ := hederasdk.PrivateKeyGenerateEd25519()
+	manifest := BlenderArchiveManifest{
+		SchemaVersion: BLENDER_MANIFEST_SCHEMA_VERSION,
+		Versions:      map[string]BlenderArchiveVersion{"4.2.0": {Linux: _testManifestArchive("new")}},
+	}
+	if err := manifest.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(manifest)
+
+	if decoded, err := DecodeBlenderArchiveManifest(data, key.PublicKey().String()); err != nil || decoded.Versions["4.2.0"].Linux.Commit != "new" {
+		t.Fatalf("got %v, %v for a signed manifest", decoded, err)
+	}
+
+	// a manifest is never used without a valid signature
+	if _, err := DecodeBlenderArchiveManifest(data, ""); err == nil {
+		t.Errorf("a manifest was decoded without a public key")
+	}
+	if _, err := DecodeBlenderArchiveManifest(data, otherKey.PublicKey().String()); err == nil {
+		t.Errorf("a manifest was decoded with the signature of another key")
+	}
+	unsigned := manifest
+	unsigned.Signature = ""
+	data, _ = json.Marshal(unsigned)
+	if _, err := DecodeBlenderArchiveManifest(data, key.PublicKey().String()); err == nil {
+		t.Errorf("an unsigned manifest was decoded")
+	}
+	tampered := manifest
+	tampered.Versions = map[string]BlenderArchiveVersion{"4.2.0": {Linux: _testManifestArchive("tampered")}}
+	data, _ = json.Marshal(tampered)
+	if _, err := DecodeBlenderArchiveManifest(data, key.PublicKey().String()); err == nil {
+		t.Errorf("a changed manifest was decoded")
+	}
+}
+
+func TestBlenderManifestSettingsRequirePublicKey(t *testing.T) {
+	key, _ := hederasdk.PrivateKeyGenerateEd25519()
+
+	settings := BlenderManifestSettings{CID: testManifestCID, Timeout: "30s"}
+	if err := settings.Validate(); err == nil {
+		t.Errorf("a manifest CID without a public key is valid")
+	}
+	settings.PublicKey = key.PublicKey().String()
+	if err := settings.Validate(); err != nil {
+		t.Errorf("got %v for a manifest CID with a public key", err)
+	}
+	settings = BlenderManifestSettings{Timeout: "30s"}
+	if err := settings.Validate(); err != nil {
+		t.Errorf("got %v without a manifest", err)
+	}
+}
+
+func TestLoadBlenderArchiveManifestFallback(t *testing.T) {
+	logger.Manager.Init()
+	dir := _chdirTemp(t)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Cleanup(func() { _setBlenderArchiveFiles(builtinBlenderArchiveFiles, "") })
+	nm := &PackageManager{}
+
+	// versions of a previously loaded manifest
+	_setBlenderArchiveFiles(map[string]BlenderArchiveVersion{"9.9.9": {}}, testManifestCID)
+
+	// the manifest cannot be fetched without the IPFS node
+	key, _ := hederasdk.PrivateKeyGenerateEd25519()
+	settings, _ := json.Marshal(BlenderManifestSettings{CID: testManifestCID, PublicKey: key.PublicKey().String(), Timeout: "1s"})
+	os.MkdirAll(filepath.Join(dir, RENDERHIVE_APP_DIRECTORY_CONFIG), 0700)
+	if err := os.WriteFile(filepath.Join(dir, RENDERHIVE_APP_DIRECTORY_CONFIG, "blender_manifest.json"), settings, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := nm.LoadBlenderArchiveManifest(); err == nil {
+		t.Fatalf("the manifest was loaded without the IPFS node")
+	}
+
+	// only the built-in versions are supported
+	if files := GetBlenderArchiveFiles(); len(files) != len(builtinBlenderArchiveFiles) {
+		t.Errorf("got %v Blender versions, want the %v built-in versions", len(files), len(builtinBlenderArchiveFiles))
+	}
+	if _, ok := GetBlenderArchive("9.9.9"); ok {
+		t.Errorf("a version of the previous manifest is still supported")
+	}
+	if cid := nm.GetBlenderManifestCID(); cid != "" {
+		t.Errorf("got manifest CID %q, want none", cid)
+	}
+}
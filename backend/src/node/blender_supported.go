@@ -0,0 +1,233 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the Blender versions supported by the Renderhive network.
+
+The supported versions are the versions of the Renderhive archive (see
+RENDERHIVE_BLENDER_ARCHIVE_FILES), which stores a Blender archive for each
+version and platform on IPFS. Only these versions can be installed on a node.
+The listing shows, for which platforms an archive is available, and the CIDs
+of the archives, so that operators know the valid values of the '--version'
+flags. The size of an archive, which is not listed in the Renderhive archive,
+is read from the root node of the archive on IPFS (if the IPFS node runs).
+
+*/
+
+import (
+
+	// standard
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	// external
+	"github.com/spf13/cobra"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/ipfs"
+	"renderhive/logger"
+)
+
+// Blender archive of a supported Blender version for a platform
+type SupportedBlenderPlatform struct {
+	Platform  string             // 'linux', 'windows', or 'macos'
+	Available bool               // true, if the Renderhive archive has a Blender archive for the platform
+	Archive   BlenderArchiveFile // the Blender archive of the platform
+}
+
+// Blender version supported by the Renderhive network
+type SupportedBlenderVersion struct {
+	Version   string
+	Platforms []SupportedBlenderPlatform // in the order: linux, windows, macos
+}
+
+// SUPPORTED BLENDER VERSIONS
+// #############################################################################
+// Get the Blender versions of the Renderhive archive (oldest version first)
+func SupportedBlenderVersions() []SupportedBlenderVersion {
+
+	files := GetBlenderArchiveFiles()
+	versions := make([]SupportedBlenderVersion, 0, len(files))
+	for version, archive := range files {
+		versions = append(versions, SupportedBlenderVersion{
+			Version: version,
+			Platforms: []SupportedBlenderPlatform{
+				_supportedPlatform("linux", archive.Linux),
+				_supportedPlatform("windows", archive.Windows),
+				_supportedPlatform("macos", archive.Macos),
+			},
+		})
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		if order := CompareBlenderVersions(versions[i].Version, versions[j].Version); order != 0 {
+			return order < 0
+		}
+		return versions[i].Version < versions[j].Version
+	})
+
+	return versions
+
+}
+
+// Get a Blender version of the Renderhive archive
+func GetSupportedBlenderVersion(version string) (SupportedBlenderVersion, error) {
+
+	err := ValidateBlenderVersion(version)
+	if err != nil {
+		return SupportedBlenderVersion{}, err
+	}
+	for _, supported := range SupportedBlenderVersions() {
+		if supported.Version == version {
+			return supported, nil
+		}
+	}
+
+	return SupportedBlenderVersion{}, newRenderError(ErrUnsupportedVersion, "Blender version '%v' is not supported by the Renderhive network.", version)
+
+}
+
+// Check if a Blender version is supported by the Renderhive network
+// NOTE: The error lists the supported versions.
+func ValidateBlenderVersion(version string) error {
+
+	if _, ok := GetBlenderArchive(version); ok {
+		return nil
+	}
+
+	var valid []string
+	for _, supported := range SupportedBlenderVersions() {
+		valid = append(valid, supported.Version)
+	}
+	if len(valid) == 0 {
+		return newRenderError(ErrUnsupportedVersion, "Blender version '%v' is not supported by the Renderhive network. There are no supported versions.", version)
+	}
+
+	return newRenderError(ErrUnsupportedVersion, "Blender version '%v' is not supported by the Renderhive network. Supported versions: %v", version, strings.Join(valid, ", "))
+
+}
+
+// helper function to get the availability of a Blender archive
+func _supportedPlatform(platform string, archive BlenderArchiveFile) SupportedBlenderPlatform {
+
+	return SupportedBlenderPlatform{
+		Platform:  platform,
+		Available: archive.CID != "" && archive.Filename != "",
+		Archive:   archive,
+	}
+
+}
+
+// helper function to get the size of a Blender archive (from IPFS, if it is not listed)
+func _archiveSizeOf(archive BlenderArchiveFile) int64 {
+
+	if archive.Size > 0 || archive.CID == "" {
+		return archive.Size
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), RENDERHIVE_CONFIG_BLENDER_ARCHIVE_SIZE_TIMEOUT)
+	defer cancel()
+	size, err := ipfs.Manager.GetObjectSize(ctx, archive.CID)
+	if err != nil {
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf("Could not get the size of the Blender archive '%v': %v", archive.Filename, err))
+		return 0
+	}
+
+	return size
+
+}
+
+// helper function to format the size of a Blender archive
+func _archiveSize(size int64) string {
+
+	if size <= 0 {
+		return "unknown"
+	}
+
+	return fmt.Sprintf("%.1f MiB", float64(size)/(1024*1024))
+
+}
+
+// COMMAND LINE INTERFACE - SUPPORTED BLENDER VERSIONS
+// #############################################################################
+// Create the CLI command to list the Blender versions supported by the Renderhive network
+func (nm *PackageManager) CreateCommandBlender_Supported() *cobra.Command {
+
+	// flags for the 'blender supported' command
+	var version string
+
+	// create a 'blender supported' command for the node
+	command := &cobra.Command{
+		Use:   "supported",
+		Short: "List the Blender versions supported by the Renderhive network",
+		Long:  "This command lists the Blender versions of the Renderhive archive with the platforms, sizes, and CIDs of their Blender archives. Only these versions can be installed on a node. With '--version', the command checks if the given version is supported.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			versions := SupportedBlenderVersions()
+			if len(version) != 0 {
+				supported, err := GetSupportedBlenderVersion(version)
+				if err != nil {
+
+					logger.Manager.Println("")
+					return err
+
+				}
+				versions = []SupportedBlenderVersion{supported}
+			}
+
+			logger.Manager.Println("")
+			if len(versions) == 0 {
+				logger.Manager.Println("There are no Blender versions supported by the Renderhive network.")
+				logger.Manager.Println("")
+				return nil
+			}
+
+			logger.Manager.Println("The following Blender versions are supported by the Renderhive network:")
+			if manifestCID := nm.GetBlenderManifestCID(); manifestCID != "" {
+				logger.Manager.Printf(" (built-in versions and Blender manifest '%v')\n", manifestCID)
+			}
+			for _, supported := range versions {
+				logger.Manager.Resultf(" [#] Version: %v\n", supported.Version)
+				for _, platform := range supported.Platforms {
+					if !platform.Available {
+						logger.Manager.Resultf("      - %v: not available\n", platform.Platform)
+						continue
+					}
+					logger.Manager.Resultf("      - %v: %v (Size: %v | Commit: %v | CID: %v)\n", platform.Platform, platform.Archive.Filename, _archiveSize(_archiveSizeOf(platform.Archive)), platform.Archive.Commit, platform.Archive.CID)
+				}
+			}
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().StringVarP(&version, "version", "v", "", "The Blender version to check (default: all supported versions)")
+
+	return command
+
+}
@@ -0,0 +1,83 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+	// standard
+	"regexp"
+	"strings"
+	"testing"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+func TestBlenderArchiveFiles(t *testing.T) {
+	sha := regexp.MustCompile(`^[0-9A-Fa-f]{64}$`)
+
+	supported := SupportedBlenderVersions()
+	if len(supported) != len(RENDERHIVE_BLENDER_ARCHIVE_FILES) {
+		t.Fatalf("got %v supported versions, want %v", len(supported), len(RENDERHIVE_BLENDER_ARCHIVE_FILES))
+	}
+	for _, version := range supported {
+		archives := RENDERHIVE_BLENDER_ARCHIVE_FILES[version.Version]
+		for i, archive := range []BlenderArchiveFile{archives.Linux, archives.Windows, archives.Macos} {
+			platform := version.Platforms[i]
+			if platform.Archive != archive || platform.Available != (archive.CID != "") {
+				t.Errorf("Blender v%v (%v): the listed archive does not match the Renderhive archive", version.Version, platform.Platform)
+			}
+			if archive.CID == "" {
+				continue
+			}
+
+			// each listed archive is complete
+			if !sha.MatchString(archive.SHA) {
+				t.Errorf("Blender v%v (%v): invalid SHA-256 checksum '%v'", version.Version, platform.Platform, archive.SHA)
+			}
+			if !strings.HasPrefix(archive.Filename, "blender-"+version.Version) || !strings.Contains(archive.Filename, archive.Commit) {
+				t.Errorf("Blender v%v (%v): the file name '%v' does not match the version and commit '%v'", version.Version, platform.Platform, archive.Filename, archive.Commit)
+			}
+			if archive.Size < 0 {
+				t.Errorf("Blender v%v (%v): negative size %v", version.Version, platform.Platform, archive.Size)
+			}
+		}
+	}
+}
+
+func TestArchiveSize(t *testing.T) {
+	logger.Manager.Init()
+
+	if size := _archiveSize(0); size != "unknown" {
+		t.Errorf("got size %q, want unknown", size)
+	}
+	if size := _archiveSize(300 * 1024 * 1024); size != "300.0 MiB" {
+		t.Errorf("got size %q, want 300.0 MiB", size)
+	}
+
+	// a listed size is used, and an archive without CID has no size
+	if size := _archiveSizeOf(BlenderArchiveFile{CID: "bafy", Size: 42}); size != 42 {
+		t.Errorf("got size %v, want the listed size", size)
+	}
+	if size := _archiveSizeOf(BlenderArchiveFile{}); size != 0 {
+		t.Errorf("got size %v, want no size", size)
+	}
+}
@@ -0,0 +1,570 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the Blender versions installed on this node.
+
+The Blender binaries are a resource of the node and not of a render offer. Each
+version is installed in its own directory '<version>-<commit>' of the Blender
+binaries directory. The registry scans this directory on start, so that the
+installed versions can be listed, installed, and removed without an active
+render offer. The render offers only reference the versions of the registry:
+Adding a Blender version to a render offer installs it, if needed, and the
+render offers loaded from the repository get their Blender binaries from the
+registry. A version can only be removed from the node, if no render offer uses
+it anymore.
+
+*/
+
+import (
+
+	// standard
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	// external
+	"github.com/spf13/cobra"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/ipfs"
+	"renderhive/logger"
+)
+
+// Blender version installed on this node
+type BlenderInstallation struct {
+	Version   string // Blender version (e.g., "4.0.2")
+	Commit    string // commit hash of the build (from the directory name)
+	Directory string // installation directory of the version
+	Path      string // path of the Blender executable
+	Supported bool   // the build is the one of the Renderhive archive
+}
+
+// Registry of the Blender versions installed on this node
+type BlenderRegistry struct {
+	Directory string                          // directory of the Blender binaries
+	Versions  map[string]*BlenderInstallation // installed Blender versions by version
+
+	mutex sync.RWMutex
+}
+
+// BLENDER VERSION REGISTRY
+// #############################################################################
+// Create a registry for the Blender versions in the given directory
+func NewBlenderRegistry(directory string) *BlenderRegistry {
+
+	return &BlenderRegistry{Directory: directory, Versions: map[string]*BlenderInstallation{}}
+
+}
+
+// Scan the directory of the registry for installed Blender versions
+// NOTE: If several builds of a version are installed, the build of the
+// Renderhive archive is preferred.
+func (registry *BlenderRegistry) Scan() error {
+
+	// lock the registry
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	registry.Versions = map[string]*BlenderInstallation{}
+	entries, err := os.ReadDir(registry.Directory)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		installation, ok := registry._installation(entry.Name())
+		if !ok {
+			continue
+		}
+		if existing, ok := registry.Versions[installation.Version]; ok && existing.Supported {
+			continue
+		}
+		registry.Versions[installation.Version] = installation
+	}
+
+	return nil
+
+}
+
+// Register an installed build of a Blender version
+func (registry *BlenderRegistry) Add(version string, commit string) (*BlenderInstallation, error) {
+
+	// lock the registry
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	installation, ok := registry._installation(version + "-" + commit)
+	if !ok {
+		return nil, newRenderError(ErrDocumentNotFound, "Could not find the Blender executable of v%v in '%v'.", version, filepath.Join(registry.Directory, version+"-"+commit))
+	}
+	registry.Versions[version] = installation
+
+	return installation, nil
+
+}
+
+// Delete a Blender version from the registry and the file system
+func (registry *BlenderRegistry) Remove(version string) error {
+
+	// lock the registry
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	installation, ok := registry.Versions[version]
+	if !ok {
+		return newRenderError(ErrUnsupportedVersion, "Blender v%v is not installed on this node.", version)
+	}
+
+	err := os.RemoveAll(installation.Directory)
+	if err != nil {
+		return err
+	}
+	delete(registry.Versions, version)
+
+	return nil
+
+}
+
+// Get an installed Blender version
+func (registry *BlenderRegistry) Get(version string) (*BlenderInstallation, bool) {
+
+	// lock the registry
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	installation, ok := registry.Versions[version]
+
+	return installation, ok
+
+}
+
+// List the installed Blender versions ordered by version
+func (registry *BlenderRegistry) List() []*BlenderInstallation {
+
+	// lock the registry
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	list := make([]*BlenderInstallation, 0, len(registry.Versions))
+	for _, installation := range registry.Versions {
+		list = append(list, installation)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return CompareBlenderVersions(list[i].Version, list[j].Version) < 0
+	})
+
+	return list
+
+}
+
+// helper function to read the installation in a directory named '<version>-<commit>'
+func (registry *BlenderRegistry) _installation(name string) (*BlenderInstallation, bool) {
+
+	version, commit, ok := strings.Cut(name, "-")
+	if !ok || version == "" || commit == "" {
+		return nil, false
+	}
+
+	// the directory must contain the Blender executable
+	directory := filepath.Join(registry.Directory, name)
+	path := filepath.Join(directory, "blender")
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return nil, false
+	}
+
+	archive, ok := GetBlenderArchive(version)
+	return &BlenderInstallation{
+		Version:   version,
+		Commit:    commit,
+		Directory: directory,
+		Path:      path,
+		Supported: ok && strings.EqualFold(archive.Linux.Commit, commit),
+	}, true
+
+}
+
+// BLENDER VERSIONS OF THE NODE
+// #############################################################################
+// Initialize the registry of the Blender versions installed on this node
+func (nm *PackageManager) InitBlenderVersions() error {
+
+	nm.Renderer.Blender = NewBlenderRegistry(RENDERHIVE_APP_DIRECTORY_BLENDER_BINARIES)
+	err := nm.Renderer.Blender.Scan()
+	if err != nil {
+		return fmt.Errorf("Could not scan the Blender versions: %w", err)
+	}
+
+	return nil
+
+}
+
+// Get a Blender version of this node
+// NOTE: The Blender version of an active render offer is preferred, since it
+// has the render settings of the offer. Otherwise, the installed version is used.
+func (nm *PackageManager) GetBlender(version string) (BlenderAppData, bool) {
+
+	if blender, ok := nm._offeredBlender(version); ok {
+		return blender, true
+	}
+	if nm.Renderer.Blender != nil {
+		if installation, ok := nm.Renderer.Blender.Get(version); ok {
+			return BlenderAppData{Path: installation.Path, BuildVersion: version, BenchmarkTool: &BlenderBenchmarkTool{}}, true
+		}
+	}
+
+	return BlenderAppData{}, false
+
+}
+
+// Install a Blender version of the Renderhive archive on this node (if not installed yet)
+func (nm *PackageManager) InstallBlenderVersion(version string) (*BlenderInstallation, error) {
+	var err error
+
+	// a requester-only node does not render, so it needs no Blender versions
+	err = nm._requireRenderNode("install a Blender version")
+	if err != nil {
+		return nil, err
+	}
+
+	if nm.Renderer.Blender == nil {
+		nm.Renderer.Blender = NewBlenderRegistry(RENDERHIVE_APP_DIRECTORY_BLENDER_BINARIES)
+	}
+
+	// get the blender version from the map of valid Blender versions
+	err = ValidateBlenderVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	blender_bin, _ := GetBlenderArchive(version)
+
+	// check if the Blender binary is already available on the local file system
+	if installation, err := nm.Renderer.Blender.Add(version, blender_bin.Linux.Commit); err == nil {
+
+		// log info event
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Blender binary for version v%v found: %v", version, installation.Path))
+
+		return installation, nil
+
+	}
+
+	// log info event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Fetching Blender binary for version v%v from IPFS: %v (%v)", version, blender_bin.Linux.Filename, blender_bin.Linux.CID))
+
+	// check if the archive is also NOT available on the local file system
+	blender_tar_path := filepath.Join(RENDERHIVE_APP_DIRECTORY_TEMP, blender_bin.Linux.Filename)
+	if _, err := os.Stat(blender_tar_path); os.IsNotExist(err) {
+
+		// define the channel for the download progress
+		progress_channel := make(chan float64)
+
+		// download the Blender binary from IPFS in a separate goroutine
+		var download_err error
+		go func() {
+			download_err = ipfs.Manager.DownloadFromGateway(blender_bin.Linux.CID, blender_tar_path, progress_channel)
+			close(progress_channel)
+		}()
+
+		// get progress updates from the channel
+		for p := range progress_channel {
+			logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Progress: %.1f %%", p))
+		}
+		if download_err != nil {
+			os.Remove(blender_tar_path)
+			return nil, newRenderError(ErrNetworkUnavailable, "Could not download the Blender binary v%v: %w", version, download_err)
+		}
+
+	}
+
+	// log info event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Blender binary for version v%v downloaded to: %v", version, blender_tar_path))
+
+	// extract the archive into the installation directory
+	// NOTE: The archive is extracted into a temporary directory first, so that an
+	//       interrupted extraction does not leave a broken installation.
+	directory := filepath.Join(nm.Renderer.Blender.Directory, version+"-"+blender_bin.Linux.Commit)
+	err = os.RemoveAll(directory + ".tmp")
+	if err == nil {
+		err = os.MkdirAll(directory+".tmp", 0755)
+	}
+	if err != nil {
+		return nil, err
+	}
+	output, err := exec.Command("tar", "-xJf", blender_tar_path, "-C", directory+".tmp", "--strip-components=1").CombinedOutput()
+	if err != nil {
+		os.RemoveAll(directory + ".tmp")
+		return nil, fmt.Errorf("Could not extract the Blender archive '%v': %v (%v)", blender_tar_path, err, strings.TrimSpace(string(output)))
+	}
+	err = os.Rename(directory+".tmp", directory)
+	if err != nil {
+		os.RemoveAll(directory + ".tmp")
+		return nil, err
+	}
+
+	// delete the archive after extraction
+	err = os.Remove(blender_tar_path)
+	if err != nil {
+		logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Could not delete the Blender archive '%v': %v", blender_tar_path, err))
+	}
+
+	return nm.Renderer.Blender.Add(version, blender_bin.Linux.Commit)
+
+}
+
+// Remove a Blender version from this node, if no render offer uses it
+func (nm *PackageManager) RemoveBlenderVersion(version string) error {
+
+	if nm.Renderer.Blender == nil {
+		return newRenderError(ErrUnsupportedVersion, "Blender v%v is not installed on this node.", version)
+	}
+
+	// the render offers must not use the version anymore
+	offers := nm._offersUsingBlenderVersion(version)
+	if len(offers) != 0 {
+		return newRenderError(ErrInvalidArgument, "Blender v%v is used by the render offers %v. Remove it from the render offers first.", version, strings.Join(offers, ", "))
+	}
+
+	return nm.Renderer.Blender.Remove(version)
+
+}
+
+// helper function to get the render offers, which use a Blender version
+func (nm *PackageManager) _offersUsingBlenderVersion(version string) []string {
+
+	offers := []string{}
+	for cid, offer := range nm.Renderer.Offers {
+		for _, blender := range offer.BlenderVersions {
+			if blender.Version == version {
+				offers = append(offers, cid)
+				break
+			}
+		}
+	}
+	for _, offer := range nm.GetActiveRenderOffers() {
+		if _, ok := offer.Blender[version]; ok && offer.DocumentCID == "" {
+			offers = append(offers, "(active render offer)")
+		}
+	}
+	sort.Strings(offers)
+
+	return offers
+
+}
+
+// helper function to get the Blender binaries of a loaded render offer from the registry
+func (nm *PackageManager) _linkBlenderVersions(offer *RenderOffer) {
+
+	if nm.Renderer.Blender == nil {
+		return
+	}
+
+	for _, version := range offer.BlenderVersions {
+		if _, ok := offer.Blender[version.Version]; ok {
+			continue
+		}
+		installation, ok := nm.Renderer.Blender.Get(version.Version)
+		if !ok {
+			logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Blender v%v of render offer '%v' is not installed on this node.", version.Version, offer.DocumentCID))
+			continue
+		}
+		offer.Blender[version.Version] = BlenderAppData{
+			Path:          installation.Path,
+			BuildVersion:  version.Version,
+			BuildHash:     version.BuildHash,
+			Verified:      version.Verified,
+			Engines:       version.Engines,
+			FeatureSets:   version.FeatureSets,
+			Devices:       version.Devices,
+			Threads:       version.Threads,
+			BenchmarkTool: &BlenderBenchmarkTool{},
+		}
+	}
+
+}
+
+// COMMAND LINE INTERFACE - BLENDER VERSIONS
+// #############################################################################
+// Create the CLI command to manage the Blender versions installed on this node
+func (nm *PackageManager) CreateCommandBlender_Versions() *cobra.Command {
+
+	// create a 'blender versions' command for the node
+	command := &cobra.Command{
+		Use:   "versions",
+		Short: "Manage the Blender versions installed on this node",
+		Long:  "This command is for listing, installing, and removing the Blender versions installed on this node. The installed versions are independent of the node's render offers.",
+	}
+
+	// add the subcommands
+	command.AddCommand(nm.CreateCommandBlender_VersionsList())
+	command.AddCommand(nm.CreateCommandBlender_VersionsInstall())
+	command.AddCommand(nm.CreateCommandBlender_VersionsRemove())
+
+	return command
+
+}
+
+// Create the CLI command to list the Blender versions installed on this node
+func (nm *PackageManager) CreateCommandBlender_VersionsList() *cobra.Command {
+
+	// create a 'blender versions list' command for the node
+	command := &cobra.Command{
+		Use:   "list",
+		Short: "List the Blender versions installed on this node",
+		Long:  "This command lists the Blender versions found in the Blender binaries directory and the render offers, which use them.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// scan the directory again to find changes since the start
+			if nm.Renderer.Blender == nil {
+				nm.Renderer.Blender = NewBlenderRegistry(RENDERHIVE_APP_DIRECTORY_BLENDER_BINARIES)
+			}
+			err := nm.Renderer.Blender.Scan()
+			if err != nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not scan the Blender versions: %w", err)
+
+			}
+
+			logger.Manager.Println("")
+			installations := nm.Renderer.Blender.List()
+			if len(installations) == 0 {
+				logger.Manager.Printf("There are no Blender versions installed in '%v'.\n", nm.Renderer.Blender.Directory)
+				logger.Manager.Println("")
+				return nil
+			}
+
+			logger.Manager.Println("The following Blender versions are installed on this node:")
+			for _, installation := range installations {
+				offers := nm._offersUsingBlenderVersion(installation.Version)
+				logger.Manager.Resultf(" [#] Version: %v (Commit: %v | Supported build: %v | Render offers: %v | Path: %v) \n", installation.Version, installation.Commit, installation.Supported, len(offers), installation.Path)
+			}
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	return command
+
+}
+
+// Create the CLI command to install a Blender version on this node
+func (nm *PackageManager) CreateCommandBlender_VersionsInstall() *cobra.Command {
+
+	// flags for the 'blender versions install' command
+	var version string
+
+	// create a 'blender versions install' command for the node
+	command := &cobra.Command{
+		Use:   "install",
+		Short: "Install a Blender version on this node",
+		Long:  "This command downloads the Blender archive of the given version from IPFS and extracts it into the Blender binaries directory. The version is not added to a render offer.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			if len(version) == 0 {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Missing a required parameter: Blender version (--version).")
+
+			}
+
+			installation, err := nm.InstallBlenderVersion(version)
+			if err != nil {
+
+				logger.Manager.Println("")
+				return err
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Printf("Installed Blender v%v on this node. \n", installation.Version)
+			logger.Manager.Resultf(" [#] Path: %v\n", installation.Path)
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().StringVarP(&version, "version", "v", "", "The Blender version to install")
+
+	return command
+
+}
+
+// Create the CLI command to remove a Blender version from this node
+func (nm *PackageManager) CreateCommandBlender_VersionsRemove() *cobra.Command {
+
+	// flags for the 'blender versions remove' command
+	var version string
+
+	// create a 'blender versions remove' command for the node
+	command := &cobra.Command{
+		Use:   "remove",
+		Short: "Remove a Blender version from this node",
+		Long:  "This command deletes the installation directory of the given Blender version. A version, which is used by a render offer, cannot be removed.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			if len(version) == 0 {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Missing a required parameter: Blender version (--version).")
+
+			}
+
+			err := nm.RemoveBlenderVersion(version)
+			if err != nil {
+
+				logger.Manager.Println("")
+				return err
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Printf("Removed Blender v%v from this node. \n", version)
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().StringVarP(&version, "version", "v", "", "The Blender version to remove")
+
+	return command
+
+}
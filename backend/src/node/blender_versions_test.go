@@ -0,0 +1,233 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// helper function to install a fake Blender build in '<version>-<commit>' of a directory
+func _installTestBlender(t *testing.T, directory string, version string, commit string) string {
+	t.Helper()
+
+	path := filepath.Join(directory, version+"-"+commit)
+	if err := os.MkdirAll(path, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(path, "blender"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// helper function to get a Blender version of the Renderhive archive and its commit
+func _testSupportedBlender(t *testing.T) (string, string) {
+	t.Helper()
+
+	for version, archive := range GetBlenderArchiveFiles() {
+		if archive.Linux.Commit != "" {
+			return version, archive.Linux.Commit
+		}
+	}
+	t.Skip("the Renderhive archive has no Blender version")
+
+	return "", ""
+}
+
+func TestBlenderRegistryScan(t *testing.T) {
+	directory := t.TempDir()
+	supported, commit := _testSupportedBlender(t)
+
+	// installed builds, of which only the ones with an executable are found
+	_installTestBlender(t, directory, "4.1.0", "abc123")
+	_installTestBlender(t, directory, "3.6.0", "def456")
+	_installTestBlender(t, directory, supported, "aaa000")
+	_installTestBlender(t, directory, supported, commit)
+	_installTestBlender(t, directory, supported, "zzz999")
+	if err := os.MkdirAll(filepath.Join(directory, "2.93.0-empty"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	_installTestBlender(t, directory, "noversion", "")
+	if err := os.WriteFile(filepath.Join(directory, "4.2.0-file"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := NewBlenderRegistry(directory)
+	if err := registry.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	if len(registry.Versions) != 3 {
+		t.Fatalf("got %v versions, want 3: %v", len(registry.Versions), registry.Versions)
+	}
+	installation, ok := registry.Get("4.1.0")
+	if !ok || installation.Commit != "abc123" || installation.Path != filepath.Join(directory, "4.1.0-abc123", "blender") || installation.Supported {
+		t.Errorf("unexpected installation of v4.1.0: %+v", installation)
+	}
+	if _, ok := registry.Get("2.93.0"); ok {
+		t.Error("expected a build without an executable to be skipped")
+	}
+
+	// the build of the Renderhive archive is preferred
+	installation, ok = registry.Get(supported)
+	if !ok || installation.Commit != commit || !installation.Supported {
+		t.Errorf("expected the build of the Renderhive archive of v%v, got %+v", supported, installation)
+	}
+
+	// the versions are listed in order
+	list := registry.List()
+	for i := 1; i < len(list); i++ {
+		if CompareBlenderVersions(list[i-1].Version, list[i].Version) >= 0 {
+			t.Errorf("expected the versions in order, got v%v before v%v", list[i-1].Version, list[i].Version)
+		}
+	}
+
+	// a rescan finds removed builds gone
+	if err := os.RemoveAll(filepath.Join(directory, "3.6.0-def456")); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := registry.Get("3.6.0"); ok {
+		t.Error("expected the removed build to be gone after the rescan")
+	}
+}
+
+func TestBlenderRegistryScanWithoutDirectory(t *testing.T) {
+	registry := NewBlenderRegistry(filepath.Join(t.TempDir(), "missing"))
+	if err := registry.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	if list := registry.List(); len(list) != 0 {
+		t.Errorf("got %v versions, want none", len(list))
+	}
+}
+
+func TestBlenderRegistryAddAndRemove(t *testing.T) {
+	directory := t.TempDir()
+	registry := NewBlenderRegistry(directory)
+
+	// a build, which is not installed, cannot be added
+	if _, err := registry.Add("4.1.0", "abc123"); !errors.Is(err, ErrDocumentNotFound) {
+		t.Errorf("got %v, want a missing build to be rejected", err)
+	}
+
+	// an installed build is added
+	path := _installTestBlender(t, directory, "4.1.0", "abc123")
+	installation, err := registry.Add("4.1.0", "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := registry.Get("4.1.0"); !ok || got != installation || installation.Directory != path {
+		t.Errorf("expected the added build to be registered, got %+v", got)
+	}
+
+	// a removed version is deleted from the file system
+	if err := registry.Remove("4.1.0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := registry.Get("4.1.0"); ok {
+		t.Error("expected the removed version not to be registered")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the directory of the removed version to be deleted: %v", err)
+	}
+	if err := registry.Remove("4.1.0"); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("got %v, want a version, which is not installed, to be rejected", err)
+	}
+}
+
+func TestRemoveBlenderVersionOfRenderOffers(t *testing.T) {
+	directory := t.TempDir()
+	_installTestBlender(t, directory, "4.1.0", "abc123")
+	_installTestBlender(t, directory, "3.6.0", "def456")
+
+	nm := &PackageManager{}
+	if err := nm.RemoveBlenderVersion("4.1.0"); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("got %v, want no version to be removed without a registry", err)
+	}
+	nm.Renderer.Blender = NewBlenderRegistry(directory)
+	if err := nm.Renderer.Blender.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	nm.Renderer.Offers = map[string]*RenderOffer{
+		"offer": {BlenderVersions: []RenderOfferBlenderVersions{{Version: "4.1.0"}}},
+	}
+
+	// a version of a render offer is kept
+	if err := nm.RemoveBlenderVersion("4.1.0"); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("got %v, want the version of the render offer to be kept", err)
+	}
+	if _, ok := nm.Renderer.Blender.Get("4.1.0"); !ok {
+		t.Error("expected the version of the render offer to be installed")
+	}
+
+	// other versions are removed
+	if err := nm.RemoveBlenderVersion("3.6.0"); err != nil {
+		t.Errorf("expected the unused version to be removed: %v", err)
+	}
+}
+
+func TestRenderOffersReferenceTheRegistry(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+	directory := t.TempDir()
+	_installTestBlender(t, directory, "4.1.0", "abc123")
+
+	nm := &PackageManager{}
+	nm.Renderer.Blender = NewBlenderRegistry(directory)
+	if err := nm.Renderer.Blender.Scan(); err != nil {
+		t.Fatal(err)
+	}
+
+	// an installed version is available without a render offer
+	blender, ok := nm.GetBlender("4.1.0")
+	if !ok || blender.Path != filepath.Join(directory, "4.1.0-abc123", "blender") {
+		t.Errorf("expected the installed version without a render offer, got %+v", blender)
+	}
+	if _, ok := nm.GetBlender("3.6.0"); ok {
+		t.Error("expected no Blender for a version, which is not installed")
+	}
+
+	// a loaded render offer gets the binaries of the registry
+	offer := &RenderOffer{
+		DocumentCID:     "offer",
+		BlenderVersions: []RenderOfferBlenderVersions{{Version: "4.1.0", Threads: 8}, {Version: "3.6.0"}},
+		Blender:         map[string]BlenderAppData{},
+	}
+	nm._linkBlenderVersions(offer)
+	if linked, ok := offer.Blender["4.1.0"]; !ok || linked.Path != blender.Path || linked.Threads != 8 {
+		t.Errorf("expected the installed version to be linked with the offer settings, got %+v", linked)
+	}
+	if _, ok := offer.Blender["3.6.0"]; ok {
+		t.Error("expected a version, which is not installed, not to be linked")
+	}
+}
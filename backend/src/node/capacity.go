@@ -0,0 +1,226 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the estimation of the render capacity of the render hive.
+
+The snapshot is derived from the render offer announcements of the job queue
+topic and from the render hive queue of this node:
+
+  - active nodes: operators with at least one active render offer
+  - render power: the sum of the benchmark scores (samples per minute) of the
+    active nodes, where a node with several offers (e.g., price tiers) counts
+    with its best offer only, since the offers share the same hardware
+  - open requests: render requests with queued or claimed render jobs
+
+An announcement is stale, if the offer was not re-announced within the maximum
+age (see RENDERHIVE_CONFIG_NETWORK_OFFER_MAX_AGE). A render offer announced
+several times (also in another CID version or by several accounts) is counted
+once. The render power of an offer is the benchmark score of its announcement
+(the highest sum of the benchmark scores of a Blender version). Offers announced
+without benchmark scores (e.g., by older versions) are only rated, if they are
+render offers of this node. Therefore, the snapshot is a lower bound of the
+network capacity.
+
+*/
+
+import (
+
+	// standard
+	"time"
+
+	// external
+	gocid "github.com/ipfs/go-cid"
+
+	// internal
+	. "renderhive/globals"
+)
+
+// Snapshot of the render capacity of the render hive
+type NetworkCapacity struct {
+	Timestamp    time.Time // time of the snapshot
+	ActiveNodes  int       // operators with at least one active render offer
+	ActiveOffers int       // active render offers
+	StaleOffers  int       // render offers, which were not re-announced within the maximum age
+	RatedNodes   int       // active nodes with a known render power
+	RenderPower  float64   // sum of the render power of the rated nodes (in samples per minute)
+	OpenRequests int       // render requests with queued or claimed render jobs
+	QueuedJobs   int       // render jobs waiting in the render hive queue
+	ClaimedJobs  int       // render jobs claimed for rendering
+}
+
+// NETWORK CAPACITY
+// #############################################################################
+// Estimate the render capacity of the render hive from the render offer
+// announcements and the render jobs of the render hive queue
+// NOTE: The render power function returns the benchmark score of a render offer,
+// which was announced without benchmark scores (false, if it is not known).
+func EstimateNetworkCapacity(announcements []OfferAnnouncement, jobs []*RenderJob, renderPower func(cid string) (float64, bool), now time.Time, maxAge time.Duration) NetworkCapacity {
+
+	capacity := NetworkCapacity{Timestamp: now}
+
+	// get the newest announcement of each render offer
+	newest := map[string]OfferAnnouncement{}
+	for _, announcement := range announcements {
+		key := _cidKey(announcement.RenderOfferCID)
+		if known, ok := newest[key]; !ok || announcement.SubmittedTimestamp.After(known.SubmittedTimestamp) {
+			newest[key] = announcement
+		}
+	}
+
+	// get the best render power of each operator with active render offers
+	operators := map[string]float64{}
+	rated := map[string]bool{}
+	for _, announcement := range newest {
+		if !announcement.PausedTimestamp.IsZero() {
+			continue
+		}
+		if maxAge > 0 && now.Sub(announcement.SubmittedTimestamp) > maxAge {
+			capacity.StaleOffers++
+			continue
+		}
+		capacity.ActiveOffers++
+
+		power, ok := _announcedRenderPower(announcement.Benchmarks)
+		if !ok && renderPower != nil {
+			power, ok = renderPower(announcement.RenderOfferCID)
+		}
+		if ok && (!rated[announcement.Operator] || power > operators[announcement.Operator]) {
+			operators[announcement.Operator] = power
+			rated[announcement.Operator] = true
+		} else if _, known := operators[announcement.Operator]; !known {
+			operators[announcement.Operator] = 0
+		}
+	}
+	capacity.ActiveNodes = len(operators)
+	capacity.RatedNodes = len(rated)
+	for operator, power := range operators {
+		if rated[operator] {
+			capacity.RenderPower += power
+		}
+	}
+
+	// count the open render jobs and their render requests
+	requests := map[string]bool{}
+	for _, job := range jobs {
+		if job == nil || job.Request == nil {
+			continue
+		}
+		switch job.State {
+		case RENDER_JOB_STATE_QUEUED:
+			capacity.QueuedJobs++
+		case RENDER_JOB_STATE_CLAIMED, RENDER_JOB_STATE_RENDERING:
+			capacity.ClaimedJobs++
+		default:
+			continue
+		}
+		requests[_cidKey(job.Request.DocumentCID)] = true
+	}
+	capacity.OpenRequests = len(requests)
+
+	return capacity
+
+}
+
+// Estimate the render capacity of the render hive as seen by this node
+func (nm *PackageManager) NetworkCapacity() NetworkCapacity {
+
+	// copy the render offer announcements of all operators
+	var announcements []OfferAnnouncement
+	nm.networkOffersMutex.Lock()
+	for _, offers := range nm.networkOffers {
+		for _, announcement := range offers {
+			announcements = append(announcements, *announcement)
+		}
+	}
+	nm.networkOffersMutex.Unlock()
+
+	// the render power of offers announced without benchmark scores is only
+	// known for the render offers of this node
+	renderPower := func(cid string) (float64, bool) {
+		offer, err := nm.GetRenderOffer(cid)
+		if err != nil {
+			return 0, false
+		}
+		return _offerRenderPower(offer)
+	}
+
+	return EstimateNetworkCapacity(announcements, nm.NetworkQueue, renderPower, time.Now(), RENDERHIVE_CONFIG_NETWORK_OFFER_MAX_AGE)
+
+}
+
+// helper function to get the render power of a render offer (i.e., the highest
+// benchmark score of its Blender versions)
+func _offerRenderPower(offer *RenderOffer) (float64, bool) {
+
+	power := 0.0
+	for _, blender := range offer.Blender {
+		if blender.BenchmarkTool == nil {
+			continue
+		}
+
+		// sum up the samples per minute of all benchmark scenes
+		score := 0.0
+		for _, result := range blender.BenchmarkTool.GetResult() {
+			score += result.Stats.SamplesPerMinute
+		}
+		if score > power {
+			power = score
+		}
+	}
+
+	return power, power > 0
+
+}
+
+// helper function to get the render power of announced benchmark scores (i.e.,
+// the highest sum of the benchmark scores of a Blender version)
+func _announcedRenderPower(benchmarks []RenderOfferBenchmark) (float64, bool) {
+
+	scores := map[string]float64{}
+	for _, benchmark := range benchmarks {
+		scores[benchmark.Version] += benchmark.SamplesPerMinute
+	}
+
+	power := 0.0
+	for _, score := range scores {
+		if score > power {
+			power = score
+		}
+	}
+
+	return power, power > 0
+
+}
+
+// helper function to get a key of a CID, which is the same for all CID versions
+func _cidKey(cid string) string {
+
+	parsed, err := gocid.Parse(cid)
+	if err != nil {
+		return cid
+	}
+
+	return parsed.Hash().String()
+
+}
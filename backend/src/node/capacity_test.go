@@ -0,0 +1,112 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"testing"
+	"time"
+
+	// external
+	gocid "github.com/ipfs/go-cid"
+
+	// internal
+	. "renderhive/globals"
+)
+
+const testOfferCID2 = "QmZ4tDuvesekSs4qM5ZBKpXiZGun7S2CYtEZRB3DYXkjGx"
+
+func TestEstimateNetworkCapacitySumsAnnouncedBenchmarks(t *testing.T) {
+	now := time.Unix(10000, 0)
+	parsed, err := gocid.Decode(testOfferCID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cidV1 := gocid.NewCidV1(parsed.Type(), parsed.Hash()).String()
+
+	announcements := []OfferAnnouncement{
+
+		// an operator with two offers counts with its best offer
+		{Operator: "0.0.1001", RenderOfferCID: testOfferCID, SubmittedTimestamp: now.Add(-time.Minute), Benchmarks: []RenderOfferBenchmark{
+			{Version: "4.1.0", Device: "CPU", SamplesPerMinute: 100},
+			{Version: "4.1.0", Device: "GPU", SamplesPerMinute: 300},
+			{Version: "4.0.0", Device: "GPU", SamplesPerMinute: 350},
+		}},
+		{Operator: "0.0.1001", RenderOfferCID: testOfferCID2, SubmittedTimestamp: now.Add(-time.Minute), Benchmarks: []RenderOfferBenchmark{
+			{Version: "4.1.0", Device: "CPU", SamplesPerMinute: 50},
+		}},
+
+		// an older announcement in another CID version is counted once
+		{Operator: "0.0.1001", RenderOfferCID: cidV1, SubmittedTimestamp: now.Add(-time.Hour)},
+
+		// an operator without benchmark scores is active, but not rated
+		{Operator: "0.0.1002", RenderOfferCID: "offer-unrated", SubmittedTimestamp: now.Add(-time.Minute)},
+
+		// an operator with a stale and a paused offer is not active
+		{Operator: "0.0.1003", RenderOfferCID: "offer-stale", SubmittedTimestamp: now.Add(-2 * time.Hour), Benchmarks: []RenderOfferBenchmark{{Version: "4.1.0", SamplesPerMinute: 1000}}},
+		{Operator: "0.0.1003", RenderOfferCID: "offer-paused", SubmittedTimestamp: now.Add(-time.Minute), PausedTimestamp: now, Benchmarks: []RenderOfferBenchmark{{Version: "4.1.0", SamplesPerMinute: 1000}}},
+
+		// another rated operator
+		{Operator: "0.0.1004", RenderOfferCID: "offer-rated", SubmittedTimestamp: now.Add(-time.Minute), Benchmarks: []RenderOfferBenchmark{{Version: "4.1.0", Device: "GPU", SamplesPerMinute: 600}}},
+	}
+
+	capacity := EstimateNetworkCapacity(announcements, nil, nil, now, time.Hour)
+	if capacity.ActiveNodes != 3 || capacity.ActiveOffers != 4 || capacity.StaleOffers != 1 {
+		t.Fatalf("unexpected nodes and offers: %+v", capacity)
+	}
+	if capacity.RatedNodes != 2 || capacity.RenderPower != 1000 {
+		t.Errorf("got %v rated nodes with %v samples per minute, want 2 with 1000", capacity.RatedNodes, capacity.RenderPower)
+	}
+
+	// offers without benchmark scores are rated with the render power function
+	renderPower := func(cid string) (float64, bool) {
+		if cid == "offer-unrated" {
+			return 200, true
+		}
+		return 0, false
+	}
+	capacity = EstimateNetworkCapacity(announcements, nil, renderPower, now, time.Hour)
+	if capacity.RatedNodes != 3 || capacity.RenderPower != 1200 {
+		t.Errorf("got %v rated nodes with %v samples per minute, want 3 with 1200", capacity.RatedNodes, capacity.RenderPower)
+	}
+}
+
+func TestEstimateNetworkCapacityCountsOpenJobs(t *testing.T) {
+	first := &RenderRequest{DocumentCID: testOfferCID}
+	second := &RenderRequest{DocumentCID: testOfferCID2}
+	jobs := []*RenderJob{
+		{Request: first, State: RENDER_JOB_STATE_QUEUED},
+		{Request: first, State: RENDER_JOB_STATE_CLAIMED},
+		{Request: second, State: RENDER_JOB_STATE_RENDERING},
+		{Request: second, State: RENDER_JOB_STATE_COMPLETED},
+		{State: RENDER_JOB_STATE_QUEUED},
+		nil,
+	}
+
+	capacity := EstimateNetworkCapacity(nil, jobs, nil, time.Now(), time.Hour)
+	if capacity.OpenRequests != 2 || capacity.QueuedJobs != 1 || capacity.ClaimedJobs != 2 {
+		t.Errorf("unexpected open jobs: %+v", capacity)
+	}
+	if capacity.ActiveNodes != 0 || capacity.RenderPower != 0 {
+		t.Errorf("unexpected capacity without announcements: %+v", capacity)
+	}
+}
@@ -0,0 +1,251 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the collection of the rendered frames of a render job into
+a render result.
+
+Blender writes each rendered frame of a job into the output directory of the
+job and adds the frame number to the file name (e.g., 'frame_0001.png'). After
+the rendering, the output directory is walked and each file is associated with
+its frame number (the last number in the file name). Each frame file is hashed,
+and the frame number → CID mappings are written into the result document
+('result.json') of the output directory. Then the directory is added to IPFS.
+
+Frames of the frame range of the job without an output file (render gaps) are
+listed as missing frames in the result document, so the requester sees them.
+The frames may be post-processed before the directory is added to IPFS (see
+postprocess.go). The previews are not collected as frames. Finally, the work
+proof of the job is created and added to IPFS (see workproof.go).
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/ipfs"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// frame number in the name of a frame file (the last number before the extension)
+var frameNumberPattern = regexp.MustCompile(`(\d+)\D*$`)
+
+// RESULT COLLECTION
+// #############################################################################
+// Get the local directory of the rendered frames of the render job
+func (job *RenderJob) OutputDirectory() string {
+
+	name := job.Request.DocumentCID
+	if job.Subtask != nil {
+		name = fmt.Sprintf("%v-%v", name, job.Subtask.Index)
+	}
+
+	return filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_RENDER_OUTPUT, name)
+
+}
+
+// Collect the rendered frames of the render job into a render result
+// NOTE: Writes the result document into the output directory of the job and
+// adds the directory to IPFS.
+func (nm *PackageManager) CollectRenderResult(job *RenderJob) (*RenderResult, *RenderResultDocument, error) {
+	var err error
+
+	directory := job.OutputDirectory()
+
+	// log event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Collecting the rendered frames of render job '%v' from '%v' ...", job.Request.DocumentCID, directory))
+
+	// hash the frame files
+	document, err := CollectRenderResultFiles(job.FrameSettings(), directory, ipfs.Manager.GetHashFromPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	document.RenderRequestCID = job.Request.DocumentCID
+	document.BlenderFileCID = job.Request.BlenderFile.CID
+	document.OperatorAccountID = nm.User.UserAccount.AccountID.String()
+	document.CreatedTimestamp = time.Now()
+	if len(document.MissingFrames) > 0 {
+		logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Render job '%v' is missing %v frame(s): %v", job.Request.DocumentCID, len(document.MissingFrames), document.MissingFrames))
+	}
+
+	// post-process the frames (a failed post-processing does not fail the job)
+	err = PostProcessRenderResultFiles(nm.GetPostProcessingSettings(), directory, document, ipfs.Manager.GetHashFromPath)
+	if err != nil {
+		logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Could not post-process all frames of render job '%v': %v", job.Request.DocumentCID, err))
+	}
+
+	// write the result document and add the directory to IPFS
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	err = os.WriteFile(filepath.Join(directory, RENDERHIVE_RESULT_DOCUMENT_FILENAME), data, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	resultCID, err := ipfs.Manager.AddObjectFromPath(directory, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// create the render result
+	result := &RenderResult{
+		OperatorAccountID: document.OperatorAccountID,
+		ResultCID:         resultCID,
+		FrameHashes:       map[int]string{},
+	}
+	for _, frame := range document.Frames {
+		result.FrameHashes[frame.Frame] = frame.CID
+		if frame.PreviewCID != "" {
+			if result.PreviewHashes == nil {
+				result.PreviewHashes = map[int]string{}
+			}
+			result.PreviewHashes[frame.Frame] = frame.PreviewCID
+		}
+	}
+
+	// prove the work with a work proof document
+	proof, err := NewWorkProof(job, result, document)
+	if err != nil {
+		return nil, nil, err
+	}
+	result.WorkProofCID, err = proof.Deploy(job.WorkProofPath())
+	if err != nil {
+		return nil, nil, newRenderError(ErrNetworkUnavailable, "Could not add the work proof of render job '%v': %w", job.Request.DocumentCID, err)
+	}
+	result.JobRoot = proof.JobRoot
+
+	// log event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Collected render result of %v frame(s): %v (work proof: %v, job root: %v)", len(document.Frames), resultCID, result.WorkProofCID, result.JobRoot))
+
+	return result, document, nil
+
+}
+
+// Collect the frame files of an output directory into a result document
+// NOTE: The hash function calculates the CID of a frame file. Files outside
+// of the frame range are skipped.
+func CollectRenderResultFiles(settings RenderSettings, directory string, hash func(string) (string, error)) (*RenderResultDocument, error) {
+
+	document := &RenderResultDocument{
+		Frames:        []RenderResultFrame{},
+		MissingFrames: []int{},
+	}
+
+	// find the frame file of each frame
+	files := map[int]string{}
+	err := filepath.WalkDir(directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && path == filepath.Join(directory, RENDERHIVE_RESULT_PREVIEW_DIRECTORY) {
+			return filepath.SkipDir
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		file, err := filepath.Rel(directory, path)
+		if err != nil || file == RENDERHIVE_RESULT_DOCUMENT_FILENAME {
+			return err
+		}
+
+		// get the frame number from the file name
+		frame, ok := _frameNumber(file)
+		if !ok {
+			logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Skipping output file without frame number: %v", file))
+			return nil
+		}
+		if settings.FrameEnd >= settings.FrameStart && !_inFrameRange(settings, frame) {
+			logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Skipping output file outside of the frame range: %v", file))
+			return nil
+		}
+		if other, ok := files[frame]; ok {
+			return newRenderError(ErrDocumentMismatch, "Frame %v has more than one output file ('%v' and '%v').", frame, other, file)
+		}
+		files[frame] = file
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// hash the frame files
+	for frame, file := range files {
+		cid, err := hash(filepath.Join(directory, file))
+		if err != nil {
+			return nil, fmt.Errorf("Frame file '%v' could not be hashed: %w", file, err)
+		}
+		document.Frames = append(document.Frames, RenderResultFrame{Frame: frame, File: filepath.ToSlash(file), CID: cid})
+	}
+	sort.Slice(document.Frames, func(i, j int) bool {
+		return document.Frames[i].Frame < document.Frames[j].Frame
+	})
+
+	// find the frames of the frame range without output file
+	if settings.FrameEnd >= settings.FrameStart {
+		step := settings.FrameStep
+		if step < 1 {
+			step = 1
+		}
+		for frame := settings.FrameStart; frame <= settings.FrameEnd; frame += step {
+			if _, ok := files[frame]; !ok {
+				document.MissingFrames = append(document.MissingFrames, frame)
+			}
+		}
+	}
+
+	return document, nil
+
+}
+
+// helper function to get the frame number from the name of a frame file
+func _frameNumber(file string) (int, bool) {
+
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	match := frameNumberPattern.FindStringSubmatch(name)
+	if match == nil {
+		return 0, false
+	}
+	frame, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, false
+	}
+
+	return frame, true
+
+}
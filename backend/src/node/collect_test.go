@@ -0,0 +1,166 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// helper function to write fake frame files into an output directory
+func _writeFrameFiles(t *testing.T, directory string, names ...string) {
+	t.Helper()
+
+	for _, name := range names {
+		path := filepath.Join(directory, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCollectRenderResultFiles(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// frames 1-6 with a gap at frame 4, a file outside of the range, a file
+	// without a frame number, a preview, and an old result document
+	directory := t.TempDir()
+	_writeFrameFiles(t, directory, "frame_0001.png", "frame_0002.png", "frame_0003.png", "layers/frame_0005.exr", "frame_0006.png",
+		"frame_0007.png", "notes.txt", RENDERHIVE_RESULT_PREVIEW_DIRECTORY+"/frame_0002.jpg", RENDERHIVE_RESULT_DOCUMENT_FILENAME)
+	settings := RenderSettings{FrameStart: 1, FrameEnd: 6, FrameStep: 1}
+
+	document, err := CollectRenderResultFiles(settings, directory, _testHashFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each frame is mapped to the CID of its file
+	files := map[int]string{1: "frame_0001.png", 2: "frame_0002.png", 3: "frame_0003.png", 5: "layers/frame_0005.exr", 6: "frame_0006.png"}
+	if len(document.Frames) != len(files) {
+		t.Fatalf("got frames %+v, want %v", document.Frames, files)
+	}
+	previous := 0
+	for _, frame := range document.Frames {
+		cid, err := _testHashFile(filepath.Join(directory, filepath.FromSlash(files[frame.Frame])))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if frame.File != files[frame.Frame] || frame.CID != cid {
+			t.Errorf("frame %v: got %v (%v), want %v (%v)", frame.Frame, frame.File, frame.CID, files[frame.Frame], cid)
+		}
+		if frame.Frame <= previous {
+			t.Errorf("frame %v is not sorted", frame.Frame)
+		}
+		previous = frame.Frame
+	}
+
+	// the render gap is reported
+	if !reflect.DeepEqual(document.MissingFrames, []int{4}) {
+		t.Errorf("got missing frames %v, want [4]", document.MissingFrames)
+	}
+
+	// the collected result is verified against its render request
+	request := &RenderRequest{DocumentCID: testResultRequestCID}
+	request.BlenderFile.CID, request.BlenderFile.Settings = testResultBlendCID, settings
+	document.RenderRequestCID, document.BlenderFileCID = testResultRequestCID, testResultBlendCID
+	verification, err := VerifyRenderResultFiles(request, document, directory, _testHashFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(verification.MissingFrames, []int{4}) || verification.Passed {
+		t.Errorf("unexpected verification: %+v", verification)
+	}
+}
+
+func TestCollectRenderResultFilesWithFrameStep(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// only every second frame is part of the frame range
+	directory := t.TempDir()
+	_writeFrameFiles(t, directory, "0001.png", "0002.png", "0003.png", "0005.png")
+	document, err := CollectRenderResultFiles(RenderSettings{FrameStart: 1, FrameEnd: 7, FrameStep: 2}, directory, _testHashFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := []int{}
+	for _, frame := range document.Frames {
+		frames = append(frames, frame.Frame)
+	}
+	if !reflect.DeepEqual(frames, []int{1, 3, 5}) || !reflect.DeepEqual(document.MissingFrames, []int{7}) {
+		t.Errorf("got frames %v and missing frames %v, want [1 3 5] and [7]", frames, document.MissingFrames)
+	}
+}
+
+func TestCollectRenderResultFilesErrors(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	settings := RenderSettings{FrameStart: 1, FrameEnd: 2, FrameStep: 1}
+
+	// a frame with two output files
+	directory := t.TempDir()
+	_writeFrameFiles(t, directory, "frame_0001.png", "frame_0001.exr")
+	if _, err := CollectRenderResultFiles(settings, directory, _testHashFile); !errors.Is(err, ErrDocumentMismatch) {
+		t.Errorf("got %v, want an error for a frame with two files", err)
+	}
+
+	// a frame file that cannot be hashed
+	directory = t.TempDir()
+	_writeFrameFiles(t, directory, "frame_0001.png")
+	failed := errors.New("hash failed")
+	if _, err := CollectRenderResultFiles(settings, directory, func(string) (string, error) { return "", failed }); !errors.Is(err, failed) {
+		t.Errorf("got %v, want the hash error", err)
+	}
+
+	// an output directory that does not exist
+	if _, err := CollectRenderResultFiles(settings, filepath.Join(t.TempDir(), "missing"), _testHashFile); err == nil {
+		t.Error("expected an error for a missing output directory")
+	}
+}
+
+func TestFrameNumber(t *testing.T) {
+	for file, want := range map[string]int{
+		"frame_0001.png":       1,
+		"shot2_frame_0250.exr": 250,
+		"layers/0042.png":      42,
+		"frame0007_final.png":  7,
+	} {
+		if frame, ok := _frameNumber(file); !ok || frame != want {
+			t.Errorf("%v: got frame %v (%v), want %v", file, frame, ok, want)
+		}
+	}
+	if _, ok := _frameNumber("notes.txt"); ok {
+		t.Error("expected no frame number for a file without a number")
+	}
+}
@@ -100,6 +100,10 @@ const (
 	METHOD_NODE_CREATE_RENDER_OFFER
 	METHOD_NODE_SUBMIT_RENDER_OFFER
 	METHOD_NODE_PAUSE_RENDER_OFFER
+	METHOD_NODE_RELEASE_RENDER_JOB
+	METHOD_NODE_RESOLVE_DISPUTE
+	METHOD_NODE_SUBMIT_RENDER_RESULT
+	METHOD_NODE_ANNOUNCE_RENDER_JOB_CLAIM
 )
 
 // define the default message structure for the renderhive JSON-RPC
@@ -162,6 +166,14 @@ func (nm *PackageManager) GetMethodName(method int) string {
 		return "SubmitRenderOffer"
 	case METHOD_NODE_PAUSE_RENDER_OFFER:
 		return "PauseRenderOffer"
+	case METHOD_NODE_RELEASE_RENDER_JOB:
+		return "ReleaseRenderJob"
+	case METHOD_NODE_RESOLVE_DISPUTE:
+		return "ResolveDispute"
+	case METHOD_NODE_SUBMIT_RENDER_RESULT:
+		return "SubmitRenderResult"
+	case METHOD_NODE_ANNOUNCE_RENDER_JOB_CLAIM:
+		return "AnnounceRenderJobClaim"
 	default:
 		return "Unknown"
 	}
@@ -207,6 +219,14 @@ func (nm *PackageManager) GetServiceAndMethodInt(methodString string) (int, int,
 		method = METHOD_NODE_SUBMIT_RENDER_OFFER
 	case "PauseRenderOffer":
 		method = METHOD_NODE_PAUSE_RENDER_OFFER
+	case "ReleaseRenderJob":
+		method = METHOD_NODE_RELEASE_RENDER_JOB
+	case "ResolveDispute":
+		method = METHOD_NODE_RESOLVE_DISPUTE
+	case "SubmitRenderResult":
+		method = METHOD_NODE_SUBMIT_RENDER_RESULT
+	case "AnnounceRenderJobClaim":
+		method = METHOD_NODE_ANNOUNCE_RENDER_JOB_CLAIM
 	}
 
 	return service, method, nil
@@ -0,0 +1,439 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the crash detection of the Blender processes and the restart
+policy for crashed render jobs.
+
+A Blender process crashed, if it exited with a nonzero exit code or was killed
+by a signal, regardless of its output. A segmentation fault or the OOM killer
+of the system stop Blender without printing 'Blender quit', so the status of a
+process is always taken from its exit code. Processes stopped by the node itself
+(e.g., when a job is released) did not crash.
+
+A failed render attempt is either retryable or permanent:
+
+  - A crash due to a lack of memory is restarted with tiles of half the size,
+    which need less memory (until the minimum tile size is reached).
+  - Any other crash of Blender is transient and is restarted with the same
+    render settings.
+  - All other failures are permanent (e.g., an unsupported Blender version or a
+    Blender app, which cannot be started) and are not restarted.
+
+Before a restart, the node waits for the backoff delay, which doubles with each
+restart. A job is not restarted, if the delay would exceed its deadline. Each
+attempt is recorded in the render job. When a job failed permanently or ran out
+of restarts, it is released to the network, so that another node can render it.
+
+Restarts are disabled by default and can be enabled in the optional
+'restarts.json' file of the configuration directory:
+
+    {"max_restarts": 2, "min_tile_size": 64, "backoff": "30s"}
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/hedera"
+	"renderhive/logger"
+)
+
+// output of Blender, if it ran out of memory (CPU or GPU)
+var blenderOutOfMemory = regexp.MustCompile(`(?i)out of memory|std::bad_alloc|failed to allocate`)
+
+// enum for the kinds of failed render attempts
+const (
+	RENDER_FAILURE_PERMANENT     int = iota // failure, which is not restarted (e.g., an unsupported Blender version)
+	RENDER_FAILURE_TRANSIENT                // crash, which is restarted with the same render settings
+	RENDER_FAILURE_OUT_OF_MEMORY            // crash due to a lack of memory, which is restarted with smaller tiles
+)
+
+// Restart policy of crashed render jobs
+type BlenderRestartPolicy struct {
+	MaxRestarts int    `json:"max_restarts"`  // restarts of a job after a crash (0 = no restarts)
+	MinTileSize int    `json:"min_tile_size"` // smallest tile size of a restart in pixels
+	Backoff     string `json:"backoff"`       // delay before the first restart (e.g., "30s"; doubles with each restart)
+}
+
+// Render attempt of a render job on this node
+type RenderJobAttempt struct {
+	Started   time.Time // The datetime the attempt was started
+	Finished  time.Time // The datetime the attempt finished
+	TileX     int       // Tile width of the attempt (0 = tile size of the Blender file)
+	TileY     int       // Tile height of the attempt (0 = tile size of the Blender file)
+	Error     string    `json:",omitempty"` // Error of the failed attempt (empty, if the attempt succeeded)
+	Failure   int       // Kind of the failure (RENDER_FAILURE_*; only if the attempt failed)
+	Restarted bool      // True, if the job was restarted after this attempt
+}
+
+// BLENDER CRASH DETECTION
+// #############################################################################
+// Wait for the Blender process to finish
+// NOTE: Returns an error of the kind ErrBlenderCrashed, if the process crashed.
+func (b *BlenderAppData) Wait() error {
+
+	if b.done == nil {
+		return newRenderError(ErrInvalidArgument, "Blender v%v was not started.", b.BuildVersion)
+	}
+	<-b.done
+
+	return b.exitErr
+
+}
+
+// Stop the Blender process, if it is still running
+// NOTE: A stopped process is not considered as crashed.
+func (b *BlenderAppData) Stop() error {
+
+	if !b.Running || b.Cmd == nil || b.Cmd.Process == nil {
+		return nil
+	}
+	b.stopped = true
+
+	return b.Cmd.Process.Kill()
+
+}
+
+// helper function to reconcile the status of a finished Blender process with its exit code
+func (b *BlenderAppData) _exited(cmd *exec.Cmd, waitErr error) {
+
+	b.Running = false
+	b.ExitCode = -1
+	if cmd.ProcessState != nil {
+		b.ExitCode = cmd.ProcessState.ExitCode()
+		if !b.stopped && _killedBySystem(cmd.ProcessState) {
+			b.OutOfMemory = true
+		}
+	}
+
+	// the process was stopped by this node
+	if b.stopped {
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf("Blender v%v process (pid: %v) was stopped.", b.BuildVersion, b.PID))
+		return
+	}
+
+	// the process crashed
+	if waitErr != nil {
+		reason := "crashed"
+		if b.OutOfMemory {
+			reason = "ran out of memory"
+		}
+		b.exitErr = newRenderError(ErrBlenderCrashed, "Blender v%v process (pid: %v) %v (exit code: %v). (Error: %w)", b.BuildVersion, b.PID, reason, b.ExitCode, waitErr)
+
+		// log event
+		logger.Manager.Package["node"].Error().Msg(b.exitErr.Error())
+		return
+	}
+
+	// log event
+	logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf("Blender v%v process (pid: %v) exited.", b.BuildVersion, b.PID))
+
+}
+
+// RESTART POLICY
+// #############################################################################
+// Get the default restart policy of crashed render jobs
+func DefaultBlenderRestartPolicy() BlenderRestartPolicy {
+	return BlenderRestartPolicy{
+		MaxRestarts: RENDERHIVE_CONFIG_RENDER_MAX_RESTARTS,
+		MinTileSize: RENDERHIVE_CONFIG_RENDER_MIN_TILE_SIZE,
+		Backoff:     RENDERHIVE_CONFIG_RENDER_RESTART_BACKOFF.String(),
+	}
+}
+
+// Read the restart policy from the configuration file
+func (policy *BlenderRestartPolicy) Read() error {
+	var err error
+
+	// Open the configuration file
+	file, err := os.Open(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "restarts.json"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Read the file content
+	fileData, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	// get the file content into a structure
+	err = json.Unmarshal(fileData, policy)
+	if err != nil {
+		return err
+	}
+
+	return policy.Validate()
+
+}
+
+// Check the restart policy for invalid values
+func (policy *BlenderRestartPolicy) Validate() error {
+
+	if policy.MaxRestarts < 0 || policy.MinTileSize < 0 {
+		return newRenderError(ErrInvalidArgument, "The restart policy must not contain negative values.")
+	}
+	_, err := policy.BackoffDuration()
+
+	return err
+
+}
+
+// Get the delay before the first restart
+func (policy *BlenderRestartPolicy) BackoffDuration() (time.Duration, error) {
+
+	if policy.Backoff == "" {
+		return RENDERHIVE_CONFIG_RENDER_RESTART_BACKOFF, nil
+	}
+
+	backoff, err := time.ParseDuration(policy.Backoff)
+	if err != nil {
+		return 0, newRenderError(ErrInvalidArgument, "Invalid restart backoff '%v': %w", policy.Backoff, err)
+	}
+	if backoff < 0 {
+		return 0, newRenderError(ErrInvalidArgument, "The restart backoff must not be negative.")
+	}
+
+	return backoff, nil
+
+}
+
+// Get the delay before the given restart (0 = the first restart)
+// NOTE: The delay doubles with each restart up to RENDERHIVE_CONFIG_RENDER_RESTART_MAX_BACKOFF.
+func (policy *BlenderRestartPolicy) Delay(restarts int) time.Duration {
+
+	delay, err := policy.BackoffDuration()
+	if err != nil {
+		delay = RENDERHIVE_CONFIG_RENDER_RESTART_BACKOFF
+	}
+	for i := 0; i < restarts && delay < RENDERHIVE_CONFIG_RENDER_RESTART_MAX_BACKOFF; i++ {
+		delay *= 2
+	}
+	if delay > RENDERHIVE_CONFIG_RENDER_RESTART_MAX_BACKOFF {
+		delay = RENDERHIVE_CONFIG_RENDER_RESTART_MAX_BACKOFF
+	}
+
+	return delay
+
+}
+
+// Get the restart policy of this node (the configured or the default policy)
+func (nm *PackageManager) GetBlenderRestartPolicy() BlenderRestartPolicy {
+
+	// read the configured policy
+	policy := DefaultBlenderRestartPolicy()
+	err := policy.Read()
+	if err != nil {
+		return DefaultBlenderRestartPolicy()
+	}
+
+	return policy
+
+}
+
+// Get the kind of a failed render attempt (RENDER_FAILURE_*)
+func ClassifyRenderFailure(err error, outOfMemory bool) int {
+
+	if !errors.Is(err, ErrBlenderCrashed) {
+		return RENDER_FAILURE_PERMANENT
+	}
+	if outOfMemory {
+		return RENDER_FAILURE_OUT_OF_MEMORY
+	}
+
+	return RENDER_FAILURE_TRANSIENT
+
+}
+
+// Get the render settings for the restart of a failed render job
+// NOTE: Returns false, if the failure is permanent, the job was restarted too
+// often, or the tiles of a job without memory cannot get any smaller.
+func (policy BlenderRestartPolicy) Restart(settings RenderSettings, failure int, restarts int) (RenderSettings, bool) {
+
+	if failure == RENDER_FAILURE_PERMANENT || restarts >= policy.MaxRestarts {
+		return settings, false
+	}
+	if failure == RENDER_FAILURE_TRANSIENT {
+		return settings, true
+	}
+
+	// halve the tile size
+	tileX, tileY := settings.TileX, settings.TileY
+	if tileX <= 0 {
+		tileX = RENDERHIVE_CONFIG_RENDER_DEFAULT_TILE_SIZE
+	}
+	if tileY <= 0 {
+		tileY = RENDERHIVE_CONFIG_RENDER_DEFAULT_TILE_SIZE
+	}
+	if tileX <= policy.MinTileSize && tileY <= policy.MinTileSize {
+		return settings, false
+	}
+	settings.TileX, settings.TileY = tileX/2, tileY/2
+	if settings.TileX < policy.MinTileSize {
+		settings.TileX = policy.MinTileSize
+	}
+	if settings.TileY < policy.MinTileSize {
+		settings.TileY = policy.MinTileSize
+	}
+
+	return settings, true
+
+}
+
+// Render a render job and restart Blender after crashes
+// NOTE: The command line arguments of Blender are created from the render
+// settings of each attempt (e.g., with smaller tiles after a lack of memory). The job is marked as failed, if it was not rendered
+// and not restarted. A failed job, which was claimed on the network, is released.
+func (nm *PackageManager) RenderJobWithRestarts(job *RenderJob, arguments func(settings RenderSettings) ([]string, error)) error {
+	var err error
+
+	// run Blender in the environment of the job
+	err = nm.PrepareBlender(job)
+	if err != nil {
+		return err
+	}
+
+	// get the verified python setup script of the request
+	script := ""
+	if job.Request.SetupScriptCID != "" {
+		script, err = nm.FetchSetupScript(job.Request.SetupScriptCID)
+		if err != nil {
+			return err
+		}
+	}
+	job._setState(RENDER_JOB_STATE_RENDERING)
+	job.Blender.RenderJob = job.Request.DocumentCID
+	nm.Renderer.Busy = true
+
+	policy := nm.GetBlenderRestartPolicy()
+	settings := job.Request.BlenderFile.Settings
+	for restarts := 0; ; restarts++ {
+		attempt := RenderJobAttempt{Started: time.Now(), TileX: settings.TileX, TileY: settings.TileY}
+		var args []string
+		args, err = arguments(settings)
+		if err == nil {
+			err = job.Blender.Execute(_withSetupScript(args, script))
+		}
+		if err == nil {
+			err = job.Blender.Wait()
+		}
+		attempt.Finished = time.Now()
+		if err == nil {
+			job.RenderAttempts = append(job.RenderAttempts, attempt)
+			break
+		}
+		attempt.Error = err.Error()
+		attempt.Failure = ClassifyRenderFailure(err, job.Blender.OutOfMemory)
+
+		// restart retryable failures (with smaller tiles after a lack of memory)
+		var restart bool
+		settings, restart = policy.Restart(settings, attempt.Failure, restarts)
+		delay := policy.Delay(restarts)
+		if restart && !job.Deadline.IsZero() && time.Now().Add(delay).After(job.Deadline) {
+			restart = false
+		}
+		attempt.Restarted = restart && job._state() == RENDER_JOB_STATE_RENDERING
+		job.RenderAttempts = append(job.RenderAttempts, attempt)
+		job.Save()
+		if !attempt.Restarted {
+			break
+		}
+
+		// log event
+		logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Restarting render job '%v' in %v with tiles of %vx%v pixels (restart %v of %v).", job.Request.DocumentCID, delay, settings.TileX, settings.TileY, restarts+1, policy.MaxRestarts))
+
+		// wait before the restart
+		if !_waitForRestart(job, delay) {
+			break
+		}
+	}
+
+	// the job was released, while it was rendered
+	if job._state() != RENDER_JOB_STATE_RENDERING {
+		return err
+	}
+
+	// the job failed
+	if err != nil {
+		job._setState(RENDER_JOB_STATE_FAILED)
+		nm.Renderer.Busy = false
+		job.Save()
+
+		// log event
+		logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Render job '%v' failed after %v attempt(s): %v", job.Request.DocumentCID, len(job.RenderAttempts), err))
+
+		// release the job to the network, so another node can render it
+		if _isClaimAnnounced(job) {
+			releaseErr := nm.ReleaseRenderJob(job, "render failed")
+			if releaseErr != nil {
+				logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not release render job '%v': %v", job.Request.DocumentCID, releaseErr))
+			}
+		}
+
+		return err
+	}
+
+	return nil
+
+}
+
+// helper function to wait for the restart of a render job
+// NOTE: Returns false, if the job is not rendered anymore (e.g., because it was released).
+func _waitForRestart(job *RenderJob, delay time.Duration) bool {
+
+	deadline := time.Now().Add(delay)
+	for time.Now().Before(deadline) {
+		if job._state() != RENDER_JOB_STATE_RENDERING {
+			return false
+		}
+		step := time.Until(deadline)
+		if step > time.Second {
+			step = time.Second
+		}
+		time.Sleep(step)
+	}
+
+	return job._state() == RENDER_JOB_STATE_RENDERING
+
+}
+
+// helper function to check if the claim of the render job was announced on the network
+func _isClaimAnnounced(job *RenderJob) bool {
+
+	return len(hedera.Manager.History.JobTransactions(job.Request.DocumentCID, job.SubtaskIndex())) > 0
+
+}
@@ -0,0 +1,270 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// fake Blender binary, which runs out of memory in the first run and records
+// the arguments of each run (in a single line)
+const testOutOfMemoryBlender = `#!/bin/sh
+dir="$(dirname "$0")"
+echo "$@" | tr '\n' ' ' >> "$dir/runs.txt" && echo >> "$dir/runs.txt"
+if [ ! -f "$dir/crashed" ]; then
+	touch "$dir/crashed"
+	echo "Error: Out of memory in CUDA queue enqueue"
+	exit 1
+fi
+`
+
+// fake Blender binary, which always crashes
+const testCrashingBlender = `#!/bin/sh
+echo "$@" >> "$(dirname "$0")/runs.txt"
+exit 139
+`
+
+// helper function to create a render job, which is rendered by a fake Blender binary
+func _testCrashingJob(t *testing.T, script string, policy string) (*PackageManager, *RenderJob, string) {
+	t.Helper()
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// write the restart policy into the configuration directory
+	_chdirTemp(t)
+	if err := os.MkdirAll(RENDERHIVE_APP_DIRECTORY_CONFIG, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "restarts.json"), []byte(policy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	directory := t.TempDir()
+	blender := filepath.Join(directory, "blender")
+	if err := os.WriteFile(blender, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	request := &RenderRequest{DocumentCID: testOfferCID}
+	request.BlenderFile.Path = filepath.Join(directory, "scene.blend")
+	job := &RenderJob{Request: request, State: RENDER_JOB_STATE_CLAIMED, Blender: &BlenderAppData{Path: blender}}
+
+	return &PackageManager{}, job, filepath.Join(directory, "runs.txt")
+}
+
+// helper function to create the Blender arguments of a render attempt
+func _testRenderArguments(job *RenderJob) func(settings RenderSettings) ([]string, error) {
+	return func(settings RenderSettings) ([]string, error) {
+		settings.FrameStart, settings.FrameEnd = 1, 1
+		return BlenderRenderArguments(job.Request.BlenderFile.Path, filepath.Join(job.OutputDirectory(), "frame_####"), settings)
+	}
+}
+
+func TestRenderJobWithRestartsHalvesTilesAfterOutOfMemory(t *testing.T) {
+	nm, job, runs := _testCrashingJob(t, testOutOfMemoryBlender, `{"max_restarts": 2, "min_tile_size": 64, "backoff": "1ms"}`)
+
+	err := nm.RenderJobWithRestarts(job, _testRenderArguments(job))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.State != RENDER_JOB_STATE_RENDERING {
+		t.Errorf("got state %v, want the job in rendering", job.State)
+	}
+
+	// the second run renders with half of the default tile size
+	if len(job.RenderAttempts) != 2 {
+		t.Fatalf("got %v attempts, want 2", len(job.RenderAttempts))
+	}
+	if failure := job.RenderAttempts[0].Failure; failure != RENDER_FAILURE_OUT_OF_MEMORY || !job.RenderAttempts[0].Restarted {
+		t.Errorf("got failure %v, want a restarted out-of-memory failure", failure)
+	}
+	tile := RENDERHIVE_CONFIG_RENDER_DEFAULT_TILE_SIZE / 2
+	if attempt := job.RenderAttempts[1]; attempt.TileX != tile || attempt.TileY != tile {
+		t.Errorf("got tiles of %vx%v, want %vx%v", attempt.TileX, attempt.TileY, tile, tile)
+	}
+
+	// the tile size is passed to Blender
+	data, err := os.ReadFile(runs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %v runs of Blender, want 2: %q", len(lines), lines)
+	}
+	if strings.Contains(lines[0], "tile_size") {
+		t.Error("the first run must use the tile size of the Blender file")
+	}
+	if !strings.Contains(lines[1], "--python-expr") || !strings.Contains(lines[1], "tile_size = True, 1024") {
+		t.Errorf("the second run must set the tile size: %v", lines[1])
+	}
+}
+
+func TestRenderJobWithRestartsFailsAfterMaxRestarts(t *testing.T) {
+	nm, job, runs := _testCrashingJob(t, testCrashingBlender, `{"max_restarts": 1, "backoff": "1ms"}`)
+
+	err := nm.RenderJobWithRestarts(job, _testRenderArguments(job))
+	if err == nil {
+		t.Fatal("the crashed render job must fail")
+	}
+	if job.State != RENDER_JOB_STATE_FAILED || nm.Renderer.Busy {
+		t.Errorf("got state %v (busy: %v), want a failed job on an idle node", job.State, nm.Renderer.Busy)
+	}
+
+	// the job is restarted once (without smaller tiles)
+	data, err := os.ReadFile(runs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(strings.Split(strings.TrimSpace(string(data)), "\n")); n != 2 {
+		t.Errorf("got %v runs of Blender, want 2", n)
+	}
+	if len(job.RenderAttempts) != 2 || job.RenderAttempts[0].Failure != RENDER_FAILURE_TRANSIENT || job.RenderAttempts[1].Restarted {
+		t.Errorf("got %+v, want a restarted transient failure and a final failure", job.RenderAttempts)
+	}
+}
+
+func TestBlenderRenderArgumentsTileSize(t *testing.T) {
+	args, err := BlenderRenderArguments("scene.blend", "frame_####", RenderSettings{FrameStart: 1, FrameEnd: 1, TileX: 256, TileY: 128})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the expression is added after the denylist check and before the render
+	n := len(args)
+	if args[n-1] != "-a" || args[n-3] != "--python-expr" {
+		t.Fatalf("got %v, want the tile size expression before the render", args)
+	}
+	expression := args[n-2]
+	if !strings.Contains(expression, "s.render.tile_x, s.render.tile_y = 256, 128") || !strings.Contains(expression, "s.cycles.tile_size = True, 128") {
+		t.Errorf("unexpected tile size expression: %v", expression)
+	}
+
+	// without a tile size, Blender uses the tile size of the file
+	args, err = BlenderRenderArguments("scene.blend", "frame_####", RenderSettings{FrameStart: 1, FrameEnd: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, arg := range args {
+		if arg == "--python-expr" {
+			t.Error("the tile size must not be set without tiles")
+		}
+	}
+}
+
+func TestRenderJobWithRestartsStopsBeforeTheDeadline(t *testing.T) {
+	nm, job, _ := _testCrashingJob(t, testCrashingBlender, `{"max_restarts": 3, "backoff": "1h"}`)
+	job.Deadline = time.Now().Add(time.Minute)
+
+	err := nm.RenderJobWithRestarts(job, _testRenderArguments(job))
+	if err == nil {
+		t.Fatal("the crashed render job must fail")
+	}
+	if len(job.RenderAttempts) != 1 || job.RenderAttempts[0].Restarted {
+		t.Errorf("got %+v, want a single attempt without restart", job.RenderAttempts)
+	}
+}
+
+func TestBlenderRestartPolicyDelay(t *testing.T) {
+	policy := BlenderRestartPolicy{MaxRestarts: 10, Backoff: "10s"}
+
+	tests := map[int]time.Duration{
+		0: 10 * time.Second,
+		1: 20 * time.Second,
+		2: 40 * time.Second,
+		9: RENDERHIVE_CONFIG_RENDER_RESTART_MAX_BACKOFF,
+	}
+	for restarts, want := range tests {
+		if got := policy.Delay(restarts); got != want {
+			t.Errorf("restart %v: got %v, want %v", restarts, got, want)
+		}
+	}
+
+	// an invalid backoff is rejected and falls back to the default backoff
+	policy.Backoff = "-1s"
+	if err := policy.Validate(); err == nil {
+		t.Error("a negative backoff must be rejected")
+	}
+	policy.Backoff = "soon"
+	if got := policy.Delay(0); got != RENDERHIVE_CONFIG_RENDER_RESTART_BACKOFF {
+		t.Errorf("got %v, want the default backoff", got)
+	}
+}
+
+func TestBlenderRestartPolicyRestart(t *testing.T) {
+	policy := BlenderRestartPolicy{MaxRestarts: 2, MinTileSize: 64}
+	settings := RenderSettings{TileX: 256, TileY: 100}
+
+	// permanent failures and exhausted restarts are not restarted
+	if _, ok := policy.Restart(settings, RENDER_FAILURE_PERMANENT, 0); ok {
+		t.Error("a permanent failure must not be restarted")
+	}
+	if _, ok := policy.Restart(settings, RENDER_FAILURE_TRANSIENT, 2); ok {
+		t.Error("the job must not be restarted more than the maximum")
+	}
+
+	// transient failures are restarted with the same settings
+	if got, ok := policy.Restart(settings, RENDER_FAILURE_TRANSIENT, 0); !ok || got != settings {
+		t.Errorf("got %+v (%v), want a restart with the same settings", got, ok)
+	}
+
+	// a lack of memory halves the tiles down to the minimum tile size
+	got, ok := policy.Restart(settings, RENDER_FAILURE_OUT_OF_MEMORY, 0)
+	if !ok || got.TileX != 128 || got.TileY != 64 {
+		t.Errorf("got tiles of %vx%v (%v), want 128x64", got.TileX, got.TileY, ok)
+	}
+	if _, ok := policy.Restart(RenderSettings{TileX: 64, TileY: 64}, RENDER_FAILURE_OUT_OF_MEMORY, 0); ok {
+		t.Error("the tiles must not get smaller than the minimum tile size")
+	}
+}
+
+func TestWaitForRestartStopsWhenReleased(t *testing.T) {
+	job := &RenderJob{State: RENDER_JOB_STATE_RENDERING}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		job._setState(RENDER_JOB_STATE_RELEASED)
+	}()
+
+	started := time.Now()
+	if _waitForRestart(job, time.Minute) {
+		t.Error("a released job must not be restarted")
+	}
+	if time.Since(started) > 5*time.Second {
+		t.Error("the wait did not stop, when the job was released")
+	}
+
+	// a job in rendering is restarted after the delay
+	job._setState(RENDER_JOB_STATE_RENDERING)
+	if !_waitForRestart(job, time.Millisecond) {
+		t.Error("the job must be restarted after the delay")
+	}
+}
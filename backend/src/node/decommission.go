@@ -0,0 +1,532 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the decommissioning of this node, i.e. its removal from the
+render hive. The decommissioning consists of several steps, which are performed
+in this order:
+
+  1. pause-offers: pause the active render offers on the job queue topic and
+     deactivate them, so that no render jobs are claimed anymore
+  2. stop-serving: stop republishing the IPNS records of this node, so that its
+     names expire
+  3. remove-node: remove the node from the Renderhive smart contract
+  4. withdraw-stake: withdraw the node stake from the smart contract
+  5. withdraw-funds: withdraw operator funds from the smart contract (optional)
+
+The offers are paused first, so that the node does not get new render jobs while
+it is removed. The stake can only be withdrawn after the node was removed.
+
+The transactions of the steps are returned unsigned for the wallet of the user.
+Each step is confirmed before it is performed. The steps before the removal of
+the node are reversible: If a step is declined or fails before the removal, the
+completed steps are undone (i.e., the render offers are activated and announced
+again and the IPNS records are published again). The removal of the node on the
+smart contract cannot be undone.
+
+*/
+
+import (
+
+	// standard
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+	"github.com/spf13/cobra"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/hedera"
+	"renderhive/ipfs"
+	"renderhive/logger"
+)
+
+// steps of the decommissioning (in the order they are performed)
+const (
+	DECOMMISSION_STEP_PAUSE_OFFERS   = "pause-offers"
+	DECOMMISSION_STEP_STOP_SERVING   = "stop-serving"
+	DECOMMISSION_STEP_REMOVE_NODE    = "remove-node"
+	DECOMMISSION_STEP_WITHDRAW_STAKE = "withdraw-stake"
+	DECOMMISSION_STEP_WITHDRAW_FUNDS = "withdraw-funds"
+)
+
+// Options of the decommissioning
+type DecommissionOptions struct {
+	ContractID string // ID of the Renderhive smart contract
+	Withdraw   string // amount of operator funds to withdraw after the removal (empty = none)
+	Gas        uint64 // gas limit of the contract transactions
+}
+
+// Step of the decommissioning
+type DecommissionStep struct {
+	Name         string   // name of the step (DECOMMISSION_STEP_*)
+	Description  string   // description of the step
+	Reversible   bool     // true, if the step can be undone
+	Done         bool     // true, if the step was performed
+	Transactions [][]byte // transactions of the step for the signature of the user's wallet
+
+	run    func(step *DecommissionStep) error
+	undo   func() error
+	verify func() error // checks that the signed transactions were executed
+}
+
+// Decommissioning plan of this node
+type DecommissionPlan struct {
+	Options DecommissionOptions
+	Steps   []*DecommissionStep
+}
+
+// DECOMMISSIONING
+// #############################################################################
+// Check the order of the decommissioning steps
+// NOTE: The node must be removed, the offers must be paused and the content must
+// no longer be served before the removal, and the funds must be withdrawn after it.
+func CheckDecommissionOrder(steps []string) error {
+
+	position := map[string]int{}
+	for i, step := range steps {
+		switch step {
+		case DECOMMISSION_STEP_PAUSE_OFFERS, DECOMMISSION_STEP_STOP_SERVING, DECOMMISSION_STEP_REMOVE_NODE, DECOMMISSION_STEP_WITHDRAW_STAKE, DECOMMISSION_STEP_WITHDRAW_FUNDS:
+		default:
+			return newRenderError(ErrInvalidArgument, "Unknown decommissioning step '%v'.", step)
+		}
+		if _, ok := position[step]; ok {
+			return newRenderError(ErrInvalidArgument, "The decommissioning step '%v' is performed twice.", step)
+		}
+		position[step] = i
+	}
+
+	removal, ok := position[DECOMMISSION_STEP_REMOVE_NODE]
+	if !ok {
+		return newRenderError(ErrInvalidArgument, "The decommissioning does not remove the node.")
+	}
+	for _, step := range []string{DECOMMISSION_STEP_PAUSE_OFFERS, DECOMMISSION_STEP_STOP_SERVING} {
+		if i, ok := position[step]; ok && i > removal {
+			return newRenderError(ErrInvalidArgument, "The decommissioning step '%v' must be performed before the node is removed.", step)
+		}
+	}
+	for _, step := range []string{DECOMMISSION_STEP_WITHDRAW_STAKE, DECOMMISSION_STEP_WITHDRAW_FUNDS} {
+		if i, ok := position[step]; ok && i < removal {
+			return newRenderError(ErrInvalidArgument, "The decommissioning step '%v' must be performed after the node is removed.", step)
+		}
+	}
+
+	return nil
+
+}
+
+// Plan the decommissioning of this node
+func (nm *PackageManager) PlanDecommission(options DecommissionOptions) (*DecommissionPlan, error) {
+
+	// check the options
+	contractID, err := hederasdk.ContractIDFromString(options.ContractID)
+	if err != nil {
+		return nil, newRenderError(ErrInvalidArgument, "Invalid contract ID '%v': %w", options.ContractID, err)
+	}
+	nodeAccountID, err := hederasdk.AccountIDFromString(nm.Node.HederaAccount.AccountID)
+	if err != nil {
+		return nil, newRenderError(ErrInvalidArgument, "The node has no valid account ID '%v': %w", nm.Node.HederaAccount.AccountID, err)
+	}
+	var amount hederasdk.Hbar
+	if options.Withdraw != "" {
+		amount, err = hederasdk.HbarFromString(options.Withdraw)
+		if err != nil {
+			return nil, newRenderError(ErrInvalidArgument, "Invalid withdrawal amount '%v': %w", options.Withdraw, err)
+		}
+		if amount.AsTinybar() <= 0 {
+			return nil, newRenderError(ErrInvalidArgument, "The withdrawal amount must be positive.")
+		}
+	}
+
+	// the render jobs of this node must be finished or released first
+	for _, job := range nm.Renderer.NodeQueue {
+		if job.State == RENDER_JOB_STATE_CLAIMED || job.State == RENDER_JOB_STATE_RENDERING {
+			return nil, newRenderError(ErrInvalidArgument, "The node still renders render job '%v'. Finish or release its render jobs first.", job.Request.DocumentCID)
+		}
+	}
+
+	plan := &DecommissionPlan{Options: options}
+	contract := hedera.HederaSmartContract{ID: contractID}
+
+	// pause the active render offers
+	var paused []*RenderOffer
+	offers := []*RenderOffer{}
+	for _, offer := range nm.GetActiveRenderOffers() {
+		if _isAnnounced(offer) {
+			offers = append(offers, offer)
+		}
+	}
+	if len(offers) > 0 {
+		plan.Steps = append(plan.Steps, &DecommissionStep{
+			Name:        DECOMMISSION_STEP_PAUSE_OFFERS,
+			Description: fmt.Sprintf("Pause and deactivate the %v active render offer(s)", len(offers)),
+			Reversible:  true,
+			run: func(step *DecommissionStep) error {
+				if nm.JobQueueTopic == nil {
+					return newRenderError(ErrNetworkUnavailable, "The render offers could not be paused: Not subscribed to the job queue topic.")
+				}
+				for _, offer := range offers {
+					_, transactionBytes, err := offer.Pause()
+					if err != nil {
+						return err
+					}
+					step.Transactions = append(step.Transactions, transactionBytes)
+					nm.UnsetActiveRenderOffer(offer.DocumentCID)
+					paused = append(paused, offer)
+				}
+				return nil
+			},
+			undo: func() error {
+				var errs []error
+				for _, offer := range paused {
+					nm.SetActiveRenderOffer(offer)
+					if offer.Paused {
+						offer.Paused = false
+						offer.PausedTimestamp = time.Time{}
+						offer.Save()
+					}
+					errs = append(errs, nm.AnnounceRenderOffer(offer))
+				}
+				return errors.Join(errs...)
+			},
+		})
+	}
+
+	// stop serving the content of this node
+	var records []ipfs.IPNSRecord
+	if len(ipfs.Manager.GetIPNSRecords()) > 0 {
+		plan.Steps = append(plan.Steps, &DecommissionStep{
+			Name:        DECOMMISSION_STEP_STOP_SERVING,
+			Description: "Stop republishing the IPNS records of this node",
+			Reversible:  true,
+			run: func(step *DecommissionStep) error {
+				records = ipfs.Manager.StopIPNSRepublish()
+				return nil
+			},
+			undo: func() error {
+				var errs []error
+				for _, record := range records {
+					_, err := ipfs.Manager.PublishIPNS(record.CID, record.Key)
+					errs = append(errs, err)
+				}
+				return errors.Join(errs...)
+			},
+		})
+	}
+
+	// remove the node from the smart contract
+	plan.Steps = append(plan.Steps, &DecommissionStep{
+		Name:        DECOMMISSION_STEP_REMOVE_NODE,
+		Description: fmt.Sprintf("Remove the node %v from the smart contract %v", nodeAccountID, contractID),
+		run: func(step *DecommissionStep) error {
+			params, err := hederasdk.NewContractFunctionParameters().AddAddress(nodeAccountID.ToSolidityAddress())
+			if err != nil {
+				return err
+			}
+			_, _, transactionBytes, err := contract.CallFunction("removeNode", params, options.Gas, hedera.TransactionOptions.SetExecute(false, nm.User.UserAccount.AccountID))
+			if err != nil {
+				return newRenderError(ErrTransactionFailed, "The node could not be removed: %w", err)
+			}
+			step.Transactions = append(step.Transactions, transactionBytes)
+			return nil
+		},
+		verify: func() error {
+			return nm._awaitNodeRemoval(contract, nodeAccountID, options.Gas)
+		},
+	})
+
+	// withdraw the node stake
+	plan.Steps = append(plan.Steps, &DecommissionStep{
+		Name:        DECOMMISSION_STEP_WITHDRAW_STAKE,
+		Description: fmt.Sprintf("Withdraw the stake of the node %v", nodeAccountID),
+		run: func(step *DecommissionStep) error {
+			params, err := hederasdk.NewContractFunctionParameters().AddAddress(nodeAccountID.ToSolidityAddress())
+			if err != nil {
+				return err
+			}
+			_, _, transactionBytes, err := contract.CallFunction("withdrawNodeStake", params, options.Gas, hedera.TransactionOptions.SetExecute(false, nm.User.UserAccount.AccountID))
+			hedera.Manager.AuditTransaction(hedera.AUDIT_OPERATION_STAKE_WITHDRAWAL, "", nodeAccountID.String(), transactionBytes, err)
+			if err != nil {
+				return newRenderError(ErrTransactionFailed, "The node stake could not be withdrawn: %w", err)
+			}
+			step.Transactions = append(step.Transactions, transactionBytes)
+			return nil
+		},
+	})
+
+	// withdraw the operator funds
+	if options.Withdraw != "" {
+		plan.Steps = append(plan.Steps, &DecommissionStep{
+			Name:        DECOMMISSION_STEP_WITHDRAW_FUNDS,
+			Description: fmt.Sprintf("Withdraw %v of the operator funds", amount),
+			run: func(step *DecommissionStep) error {
+				params := hederasdk.NewContractFunctionParameters().AddUint256BigInt(new(big.Int).SetInt64(amount.AsTinybar()))
+				_, _, transactionBytes, err := contract.CallFunction("withdrawOperatorFunds", params, options.Gas, hedera.TransactionOptions.SetExecute(false, nm.User.UserAccount.AccountID))
+				hedera.Manager.AuditTransaction(hedera.AUDIT_OPERATION_WITHDRAWAL, options.Withdraw, nm.User.UserAccount.AccountID.String(), transactionBytes, err)
+				if err != nil {
+					return newRenderError(ErrTransactionFailed, "The operator funds could not be withdrawn: %w", err)
+				}
+				step.Transactions = append(step.Transactions, transactionBytes)
+				return nil
+			},
+		})
+	}
+
+	return plan, CheckDecommissionOrder(plan.StepNames())
+
+}
+
+// helper function to wait until the node is no longer registered in the smart contract
+// NOTE: The removal transaction is signed and executed by the user's wallet, so
+// the smart contract is queried until it no longer knows the node.
+func (nm *PackageManager) _awaitNodeRemoval(contract hedera.HederaSmartContract, nodeAccountID hederasdk.AccountID, gas uint64) error {
+
+	operatorAccountID := nm.User.UserAccount.AccountID
+	params, err := hederasdk.NewContractFunctionParameters().AddAddress(operatorAccountID.ToSolidityAddress())
+	if err != nil {
+		return err
+	}
+	params, err = params.AddAddress(nodeAccountID.ToSolidityAddress())
+	if err != nil {
+		return err
+	}
+
+	// log event
+	logger.Manager.Package["node"].Info().Msg(fmt.Sprintf("Waiting for the removal of the node %v from the smart contract %v ...", nodeAccountID, contract.ID))
+
+	deadline := time.Now().Add(RENDERHIVE_CONFIG_DECOMMISSION_REMOVAL_TIMEOUT)
+	for {
+		functionResult, err := contract.CallFunctionLocal("isNode", params, gas)
+		if err == nil && !functionResult.GetBool(0) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			if err != nil {
+				return newRenderError(ErrTransactionFailed, "Could not confirm the removal of the node: %w", err)
+			}
+			return newRenderError(ErrTransactionFailed, "The node is still registered in the smart contract. The removal transaction was not executed.")
+		}
+		time.Sleep(RENDERHIVE_CONFIG_TRANSACTION_POLL_INTERVAL)
+	}
+
+}
+
+// Get the names of the steps of the decommissioning plan
+func (plan *DecommissionPlan) StepNames() []string {
+
+	names := []string{}
+	for _, step := range plan.Steps {
+		names = append(names, step.Name)
+	}
+
+	return names
+
+}
+
+// Perform the steps of the decommissioning plan one after another
+// NOTE: Each step is confirmed before it is performed. The done function is
+// called after each performed step (e.g., to pass its transactions to the
+// wallet). A step with transactions is only done, when their execution was
+// verified. Before the removal of the node, a declined or failed step undoes
+// the completed steps.
+func (plan *DecommissionPlan) Run(confirm func(step *DecommissionStep) bool, done func(step *DecommissionStep)) error {
+
+	err := CheckDecommissionOrder(plan.StepNames())
+	if err != nil {
+		return err
+	}
+
+	for _, step := range plan.Steps {
+
+		// the user may abort before each step
+		if !confirm(step) {
+			return plan._abort(fmt.Errorf("The decommissioning was aborted before step '%v'.", step.Name))
+		}
+
+		// log event
+		logger.Manager.Package["node"].Info().Msg(fmt.Sprintf("Decommissioning step '%v': %v ...", step.Name, step.Description))
+
+		err = step.run(step)
+		if err != nil {
+			return plan._abort(fmt.Errorf("The decommissioning step '%v' failed: %w", step.Name, err))
+		}
+		if done != nil {
+			done(step)
+		}
+
+		// wait until the transactions of the step were executed
+		if step.verify != nil {
+			err = step.verify()
+			if err != nil {
+				return plan._abort(fmt.Errorf("The decommissioning step '%v' failed: %w", step.Name, err))
+			}
+		}
+		step.Done = true
+
+	}
+
+	return nil
+
+}
+
+// Check if the node was already removed from the smart contract
+func (plan *DecommissionPlan) Removed() bool {
+
+	for _, step := range plan.Steps {
+		if step.Name == DECOMMISSION_STEP_REMOVE_NODE {
+			return step.Done
+		}
+	}
+
+	return false
+
+}
+
+// helper function to undo the completed steps, if the node was not removed yet
+func (plan *DecommissionPlan) _abort(err error) error {
+
+	if plan.Removed() {
+		return err
+	}
+
+	// undo the completed steps in reverse order
+	for i := len(plan.Steps) - 1; i >= 0; i-- {
+		step := plan.Steps[i]
+		if !step.Done || step.undo == nil {
+			continue
+		}
+
+		// log event
+		logger.Manager.Package["node"].Info().Msg(fmt.Sprintf("Undoing decommissioning step '%v' ...", step.Name))
+
+		undoErr := step.undo()
+		if undoErr != nil {
+			err = errors.Join(err, fmt.Errorf("The decommissioning step '%v' could not be undone: %w", step.Name, undoErr))
+			continue
+		}
+		step.Done = false
+	}
+
+	return err
+
+}
+
+// COMMAND LINE INTERFACE - DECOMMISSIONING
+// #############################################################################
+// Create the CLI command to remove this node from the render hive
+func (nm *PackageManager) CreateCommandDecommission() *cobra.Command {
+
+	// flags for the 'decommission' command
+	var options DecommissionOptions
+	var dry_run bool
+	var yes bool
+
+	// create a 'decommission' command for the node
+	command := &cobra.Command{
+		Use:   "decommission",
+		Short: "Remove this node from the render hive",
+		Long:  "This command removes this node from the render hive in the correct order: It pauses the active render offers, stops republishing the IPNS records of the node, removes the node from the smart contract, and withdraws the node stake (and optionally operator funds). The transactions are printed for the signature with your wallet. Each step is confirmed before it is performed. Until the node is removed from the smart contract, aborting undoes the completed steps.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			plan, err := nm.PlanDecommission(options)
+			if err != nil {
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not plan the decommissioning: %w", err)
+			}
+
+			// print the plan
+			logger.Manager.Println("")
+			logger.Manager.Println("The node is decommissioned in the following steps:")
+			for i, step := range plan.Steps {
+				reversible := ""
+				if !step.Reversible {
+					reversible = " (cannot be undone)"
+				}
+				logger.Manager.Resultf(" [#] %v. %v: %v%v\n", i+1, step.Name, step.Description, reversible)
+			}
+			logger.Manager.Println("")
+			if dry_run {
+				return nil
+			}
+
+			// confirm each step
+			reader := bufio.NewReader(cmd.InOrStdin())
+			confirm := func(step *DecommissionStep) bool {
+				if yes {
+					return true
+				}
+				logger.Manager.Printf("Continue with step '%v'? [y/N] ", step.Name)
+				answer, _ := reader.ReadString('\n')
+				answer = strings.ToLower(strings.TrimSpace(answer))
+				return answer == "y" || answer == "yes"
+			}
+
+			// print the transactions of each step for the wallet
+			done := func(step *DecommissionStep) {
+				if len(step.Transactions) == 0 {
+					logger.Manager.Printf("Step '%v' is done.\n", step.Name)
+					return
+				}
+				logger.Manager.Printf("Step '%v' is prepared.\n", step.Name)
+				for _, transaction := range step.Transactions {
+					logger.Manager.Resultf(" [#] Transaction for your wallet: %v\n", hex.EncodeToString(transaction))
+				}
+				if step.verify != nil {
+					logger.Manager.Println("Sign and execute the transaction(s) with your wallet. The decommissioning continues, when they were executed.")
+				} else {
+					logger.Manager.Println("Sign and execute the transaction(s) with your wallet before you continue.")
+				}
+			}
+
+			err = plan.Run(confirm, done)
+			if err != nil {
+				logger.Manager.Println("")
+				return err
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Println("The node was decommissioned.")
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().StringVarP(&options.ContractID, "contract", "c", RENDERHIVE_TESTNET_SMART_CONTRACT, "The ID of the smart contract (default: Renderhive testnet contract)")
+	command.Flags().StringVarP(&options.Withdraw, "withdraw", "w", "", "The amount of operator funds to withdraw after the removal (e.g., '10 ℏ'; default: none)")
+	command.Flags().Uint64VarP(&options.Gas, "gas", "g", RENDERHIVE_CONFIG_CONTRACT_TRANSACTION_GAS, "The gas limit of the contract transactions")
+	command.Flags().BoolVarP(&dry_run, "dry-run", "n", false, "Only print the steps of the decommissioning")
+	command.Flags().BoolVarP(&yes, "yes", "y", false, "Perform all steps without confirmation")
+
+	return command
+
+}
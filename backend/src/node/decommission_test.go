@@ -0,0 +1,125 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+	// standard
+	"errors"
+	"testing"
+
+	// internal
+	"renderhive/logger"
+)
+
+func TestCheckDecommissionOrder(t *testing.T) {
+	tests := []struct {
+		steps []string
+		valid bool
+	}{
+		{[]string{DECOMMISSION_STEP_REMOVE_NODE}, true},
+		{[]string{DECOMMISSION_STEP_PAUSE_OFFERS, DECOMMISSION_STEP_STOP_SERVING, DECOMMISSION_STEP_REMOVE_NODE, DECOMMISSION_STEP_WITHDRAW_STAKE, DECOMMISSION_STEP_WITHDRAW_FUNDS}, true},
+		{[]string{DECOMMISSION_STEP_STOP_SERVING, DECOMMISSION_STEP_PAUSE_OFFERS, DECOMMISSION_STEP_REMOVE_NODE, DECOMMISSION_STEP_WITHDRAW_FUNDS, DECOMMISSION_STEP_WITHDRAW_STAKE}, true},
+		{[]string{}, false},
+		{[]string{DECOMMISSION_STEP_PAUSE_OFFERS, DECOMMISSION_STEP_WITHDRAW_STAKE}, false},
+		{[]string{DECOMMISSION_STEP_REMOVE_NODE, DECOMMISSION_STEP_PAUSE_OFFERS}, false},
+		{[]string{DECOMMISSION_STEP_REMOVE_NODE, DECOMMISSION_STEP_STOP_SERVING}, false},
+		{[]string{DECOMMISSION_STEP_WITHDRAW_STAKE, DECOMMISSION_STEP_REMOVE_NODE}, false},
+		{[]string{DECOMMISSION_STEP_WITHDRAW_FUNDS, DECOMMISSION_STEP_REMOVE_NODE}, false},
+		{[]string{DECOMMISSION_STEP_REMOVE_NODE, DECOMMISSION_STEP_REMOVE_NODE}, false},
+		{[]string{DECOMMISSION_STEP_REMOVE_NODE, "unknown"}, false},
+	}
+	for _, test := range tests {
+		err := CheckDecommissionOrder(test.steps)
+		if (err == nil) != test.valid {
+			t.Errorf("CheckDecommissionOrder(%v) = %v, want valid=%v", test.steps, err, test.valid)
+		}
+	}
+}
+
+// helper function to create a decommissioning plan, which records its performed steps
+func _testDecommissionPlan(verify func() error) (*DecommissionPlan, *[]string) {
+	performed := []string{}
+	record := func(name string) func(step *DecommissionStep) error {
+		return func(step *DecommissionStep) error {
+			performed = append(performed, name)
+			step.Transactions = append(step.Transactions, []byte(name))
+			return nil
+		}
+	}
+
+	plan := &DecommissionPlan{Steps: []*DecommissionStep{
+		{Name: DECOMMISSION_STEP_PAUSE_OFFERS, Reversible: true, run: record(DECOMMISSION_STEP_PAUSE_OFFERS), undo: func() error {
+			performed = append(performed, "undo")
+			return nil
+		}},
+		{Name: DECOMMISSION_STEP_REMOVE_NODE, run: record(DECOMMISSION_STEP_REMOVE_NODE), verify: verify},
+		{Name: DECOMMISSION_STEP_WITHDRAW_STAKE, run: record(DECOMMISSION_STEP_WITHDRAW_STAKE)},
+	}}
+
+	return plan, &performed
+}
+
+func TestDecommissionWaitsForTheRemoval(t *testing.T) {
+	logger.Manager.Init()
+
+	// the stake is only withdrawn after the removal was executed
+	plan, performed := _testDecommissionPlan(func() error { return nil })
+	err := plan.Run(func(step *DecommissionStep) bool { return true }, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Removed() || len(*performed) != 3 {
+		t.Fatalf("got steps %v (removed: %v), want all steps", *performed, plan.Removed())
+	}
+
+	// a removal, which was never executed, is not done and undoes the completed steps
+	plan, performed = _testDecommissionPlan(func() error { return errors.New("not executed") })
+	err = plan.Run(func(step *DecommissionStep) bool { return true }, nil)
+	if err == nil {
+		t.Fatal("the decommissioning must fail without the removal")
+	}
+	if plan.Removed() {
+		t.Error("the node must not be marked as removed")
+	}
+	want := []string{DECOMMISSION_STEP_PAUSE_OFFERS, DECOMMISSION_STEP_REMOVE_NODE, "undo"}
+	if len(*performed) != len(want) {
+		t.Fatalf("got steps %v, want %v", *performed, want)
+	}
+	for i := range want {
+		if (*performed)[i] != want[i] {
+			t.Fatalf("got steps %v, want %v", *performed, want)
+		}
+	}
+}
+
+func TestDecommissionDeclinedAfterRemoval(t *testing.T) {
+	logger.Manager.Init()
+
+	// declining the withdrawal does not undo the steps before the removal
+	plan, performed := _testDecommissionPlan(func() error { return nil })
+	err := plan.Run(func(step *DecommissionStep) bool { return step.Name != DECOMMISSION_STEP_WITHDRAW_STAKE }, nil)
+	if err == nil {
+		t.Fatal("the declined step must abort the decommissioning")
+	}
+	if len(*performed) != 2 || !plan.Steps[0].Done {
+		t.Errorf("got steps %v, want the steps before the withdrawal without undo", *performed)
+	}
+}
@@ -0,0 +1,303 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the progress of the render request deployment.
+
+The deployment of a render request consists of several steps, which may fail
+independently (e.g., because of a transient IPFS failure):
+
+  (1) add the render request directory to IPFS
+  (2) create the render request document
+  (3) add the render request document to IPFS
+
+The completed steps are stored in a progress file next to the render request
+document ('request-<owner>-<created>.deploy'), so that a failed deployment is
+resumed from the failed step, even after a restart of the app. The progress
+file is removed, when the deployment succeeded.
+
+The files of a resumed deployment are added to the local IPFS node again, since
+they may have changed since the failed attempt. If the CID of the directory is
+unchanged, the deployment continues with the failed step. Otherwise, the render
+request document of the previous attempt is removed and created again with the
+new directory.
+
+The files of the render request are closed after the upload of the directory,
+also if the upload failed, so that no file descriptors leak on long-running
+nodes. Errors of closing the files are collected without stopping to close the
+other files. The local files are opened again for the next attempt.
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	// external
+	"github.com/ipfs/boxo/files"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// completed steps of a render request deployment
+const (
+	DEPLOY_STEP_NONE      = ""          // nothing was deployed yet
+	DEPLOY_STEP_DIRECTORY = "directory" // the render request directory was added to IPFS
+	DEPLOY_STEP_DOCUMENT  = "document"  // the render request document was created
+)
+
+// Progress of a render request deployment
+type RenderRequestDeployment struct {
+	Step             string    `json:"step"`          // last completed step
+	DirectoryCID     string    `json:"directory_cid"` // CID of the render request directory
+	DocumentPath     string    `json:"document_path"` // local path of the render request document
+	UpdatedTimestamp time.Time `json:"updated_timestamp"`
+}
+
+// DEPLOYMENT PROGRESS
+// #############################################################################
+// helper function to get the file name of the render request document (without extension)
+func (request *RenderRequest) _documentName() string {
+	return fmt.Sprintf("request-%v-%v", strings.ReplaceAll(request.Owner.String(), ".", "_"), request.CreatedTimestamp.Unix())
+}
+
+// helper function to get the path of the progress file of the deployment
+func (request *RenderRequest) _deploymentPath() string {
+	return filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_LOCAL_REQUESTS, request._documentName()+".deploy")
+}
+
+// helper function to load the progress of a previous deployment (empty, if there is none)
+func (request *RenderRequest) _loadDeployment() (RenderRequestDeployment, error) {
+	var deployment RenderRequestDeployment
+
+	data, err := os.ReadFile(request._deploymentPath())
+	if os.IsNotExist(err) {
+		return deployment, nil
+	} else if err != nil {
+		return deployment, err
+	}
+
+	err = json.Unmarshal(data, &deployment)
+	if err != nil {
+		return RenderRequestDeployment{}, fmt.Errorf("Invalid deployment progress '%v': %v", request._deploymentPath(), err)
+	}
+
+	// the document needs to be created again, if it was removed
+	if deployment.Step == DEPLOY_STEP_DOCUMENT {
+		if _, err := os.Stat(deployment.DocumentPath); err != nil {
+			deployment.Step = DEPLOY_STEP_DIRECTORY
+			deployment.DocumentPath = ""
+		}
+	}
+
+	return deployment, nil
+
+}
+
+// helper function to store the progress of the deployment
+func (request *RenderRequest) _saveDeployment(deployment *RenderRequestDeployment) error {
+
+	deployment.UpdatedTimestamp = time.Now()
+	data, err := json.MarshalIndent(deployment, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(request._deploymentPath()), 0700)
+	if err != nil {
+		return err
+	}
+
+	// write the file atomically, so that a crash does not leave a partial file
+	tmp := request._deploymentPath() + ".tmp"
+	err = os.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, request._deploymentPath())
+
+}
+
+// helper function to deploy the render request directory and store the progress
+func (nm *PackageManager) _deployDirectory(request *RenderRequest, deployment *RenderRequestDeployment) error {
+	var err error
+
+	// check the external file dependencies of the Blender file (in the first attempt)
+	if deployment.DirectoryCID == "" {
+		err = nm.CheckRenderRequestDependencies(request)
+		if err != nil {
+			return err
+		}
+	}
+
+	// add the render request directory
+	add := nm.addRequestDirectory
+	if add == nil {
+		add = (*RenderRequest)._addDirectory
+	}
+	request.DirectoryCID, err = add(request)
+	if err != nil {
+		return err
+	}
+
+	// the directory of the previous attempt is unchanged
+	if deployment.DirectoryCID == request.DirectoryCID {
+		return nil
+	}
+
+	// the files changed, so the document of the previous attempt is outdated
+	if deployment.DirectoryCID != "" {
+		logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("The files of render request '%v' changed since the last deployment attempt (directory: %v, before: %v).", request._documentName(), request.DirectoryCID, deployment.DirectoryCID))
+	}
+	if deployment.DocumentPath != "" {
+		err = os.Remove(deployment.DocumentPath)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Could not remove the outdated render request document: %v", err)
+		}
+	}
+
+	// store the progress
+	deployment.Step = DEPLOY_STEP_DIRECTORY
+	deployment.DirectoryCID = request.DirectoryCID
+	deployment.DocumentPath = ""
+	err = request._saveDeployment(deployment)
+	if err != nil {
+		return fmt.Errorf("Could not store the deployment progress: %v", err)
+	}
+
+	return nil
+
+}
+
+// helper function to add the render request directory to the local IPFS node
+// NOTE: The directory is assembled in an MFS workspace, so nested file names
+// are written to subdirectories (see AssembleWorkspace). The files are closed afterwards, also if the upload failed. A failed
+// upload opens the files again in the next attempt.
+func (request *RenderRequest) _addDirectory() (cid string, err error) {
+
+	// open the files again, if a previous attempt closed them
+	if request.Directory != nil {
+		err = request._reopenFiles()
+		if err != nil {
+			return "", newRenderError(ErrNetworkUnavailable, "Could not create render request directory: %w", err)
+		}
+	}
+
+	// close all files and free the memory (also, if the upload failed)
+	defer func() {
+		closeErr := request._closeFiles()
+		if closeErr == nil {
+			return
+		}
+		if err != nil {
+			err = errors.Join(err, closeErr)
+			return
+		}
+
+		// the upload succeeded, so the deployment continues
+		logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not close the files of render request '%v': %v", request._documentName(), closeErr))
+	}()
+
+	// make the render request directory
+	err = request.MakeDirectory(true)
+	if err != nil {
+		return "", newRenderError(ErrNetworkUnavailable, "Could not create render request directory: %w", err)
+	}
+
+	// assemble the directory in the MFS workspace of the render request
+	return request.AssembleWorkspace()
+
+}
+
+// helper function to close all files of the render request
+// NOTE: All files are closed, even if some of them fail to close.
+func (request *RenderRequest) _closeFiles() error {
+	var errs []error
+
+	for name, file := range request.Files {
+		err := file.Close()
+		if err != nil && !errors.Is(err, os.ErrClosed) {
+			errs = append(errs, fmt.Errorf("Could not close file '%v': %v", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+
+}
+
+// helper function to open the files of the render request again (e.g., after a failed upload)
+func (request *RenderRequest) _reopenFiles() error {
+
+	for name, node := range request.Files {
+
+		// open the local files again
+		if path, ok := request.filePaths[name]; ok {
+			node.Close()
+			stat, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			file, err := files.NewSerialFile(path, false, stat)
+			if err != nil {
+				return err
+			}
+			request.Files[name] = file
+			continue
+		}
+
+		// read the files from the file data from the start
+		if file, ok := node.(files.File); ok {
+			_, err := file.Seek(0, io.SeekStart)
+			if err != nil {
+				return fmt.Errorf("Could not read file '%v' again: %v", name, err)
+			}
+		}
+
+	}
+
+	return nil
+
+}
+
+// helper function to remove the progress file after the deployment succeeded
+func (request *RenderRequest) _clearDeployment() error {
+
+	err := os.Remove(request._deploymentPath())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+
+}
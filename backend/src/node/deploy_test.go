@@ -0,0 +1,201 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+	"github.com/ipfs/boxo/files"
+
+	// internal
+	"renderhive/logger"
+)
+
+func TestResumedDeploymentComparesTheDirectory(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	owner := hederasdk.AccountID{Account: 1234}
+	request := &RenderRequest{Owner: &owner, CreatedTimestamp: time.Unix(1700000000, 0)}
+	directoryCID := "directory-1"
+	added := 0
+	nm := &PackageManager{}
+	nm.addRequestDirectory = func(request *RenderRequest) (string, error) {
+		added++
+		return directoryCID, nil
+	}
+
+	// the first attempt adds the directory, but fails afterwards
+	deployment, err := request._loadDeployment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nm._deployDirectory(request, &deployment); err != nil {
+		t.Fatal(err)
+	}
+	documentPath := filepath.Join(t.TempDir(), "request.json")
+	if err := os.WriteFile(documentPath, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	deployment.Step = DEPLOY_STEP_DOCUMENT
+	deployment.DocumentPath = documentPath
+	if err := request._saveDeployment(&deployment); err != nil {
+		t.Fatal(err)
+	}
+
+	// the retry with unchanged files resumes with the document
+	deployment, err = request._loadDeployment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nm._deployDirectory(request, &deployment); err != nil {
+		t.Fatal(err)
+	}
+	if deployment.Step != DEPLOY_STEP_DOCUMENT || deployment.DocumentPath != documentPath || request.DirectoryCID != directoryCID {
+		t.Fatalf("got %+v (directory: %v), want the resumed document step", deployment, request.DirectoryCID)
+	}
+
+	// the retry with changed files creates the document again
+	directoryCID = "directory-2"
+	deployment, err = request._loadDeployment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nm._deployDirectory(request, &deployment); err != nil {
+		t.Fatal(err)
+	}
+	if deployment.Step != DEPLOY_STEP_DIRECTORY || deployment.DirectoryCID != "directory-2" || deployment.DocumentPath != "" {
+		t.Fatalf("got %+v, want the new directory without a document", deployment)
+	}
+	if _, err := os.Stat(documentPath); !os.IsNotExist(err) {
+		t.Fatal("the outdated document was not removed")
+	}
+	stored, err := request._loadDeployment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.DirectoryCID != "directory-2" {
+		t.Fatalf("stored directory %v, want directory-2", stored.DirectoryCID)
+	}
+	if added != 3 {
+		t.Fatalf("added the directory %v times, want 3", added)
+	}
+}
+
+func TestFailedDirectoryIsNotRecorded(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	owner := hederasdk.AccountID{Account: 1234}
+	request := &RenderRequest{Owner: &owner, CreatedTimestamp: time.Unix(1700000000, 0)}
+	nm := &PackageManager{}
+	nm.addRequestDirectory = func(request *RenderRequest) (string, error) {
+		return "", errors.New("injected failure")
+	}
+
+	// a failed upload stores no progress
+	var deployment RenderRequestDeployment
+	if err := nm._deployDirectory(request, &deployment); err == nil {
+		t.Fatal("the injected failure was not returned")
+	}
+	stored, err := request._loadDeployment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Step != DEPLOY_STEP_NONE {
+		t.Fatalf("got step %q, want no progress", stored.Step)
+	}
+}
+
+// file, which counts its closes and fails to close with the given error
+type testCloseFile struct {
+	files.File
+	closes int
+	err    error
+}
+
+func (file *testCloseFile) Close() error {
+	file.closes++
+	return file.err
+}
+
+func TestCloseFilesReportsErrorsAndClosesTheRest(t *testing.T) {
+	failing := &testCloseFile{File: files.NewBytesFile([]byte("a")), err: errors.New("disk detached")}
+	closed := &testCloseFile{File: files.NewBytesFile([]byte("b")), err: os.ErrClosed}
+	others := []*testCloseFile{{File: files.NewBytesFile([]byte("c"))}, {File: files.NewBytesFile([]byte("d"))}}
+	request := &RenderRequest{Files: map[string]files.Node{
+		"scene.blend":       failing,
+		"textures/wood.png": closed,
+		"textures/a.png":    others[0],
+		"textures/b.png":    others[1],
+	}}
+
+	// the close error is surfaced with the file name
+	err := request._closeFiles()
+	if err == nil || !strings.Contains(err.Error(), "scene.blend") || !strings.Contains(err.Error(), "disk detached") {
+		t.Fatalf("expected the close error of scene.blend, got %v", err)
+	}
+
+	// an already closed file is no error
+	if strings.Contains(err.Error(), "wood.png") {
+		t.Errorf("expected an already closed file to be ignored, got %v", err)
+	}
+
+	// the other files are closed anyway
+	for _, file := range append(others, failing, closed) {
+		if file.closes != 1 {
+			t.Errorf("expected each file to be closed once, got %v", file.closes)
+		}
+	}
+}
+
+func TestFailedUploadClosesTheFiles(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// the directory cannot be made, so nothing is uploaded
+	failing := &testCloseFile{File: files.NewBytesFile([]byte("a")), err: errors.New("disk detached")}
+	other := &testCloseFile{File: files.NewBytesFile([]byte("b"))}
+	owner := hederasdk.AccountID{Account: 1234}
+	request := &RenderRequest{
+		Owner:   &owner,
+		Receipt: &hederasdk.TransactionReceipt{Status: hederasdk.StatusSuccess},
+		Files:   map[string]files.Node{"scene.blend": failing, "textures/wood.png": other},
+	}
+
+	// the files are closed and the close error is reported with the upload error
+	_, err := request._addDirectory()
+	if !errors.Is(err, ErrAlreadySubmitted) || !strings.Contains(err.Error(), "disk detached") {
+		t.Fatalf("expected the upload and the close error, got %v", err)
+	}
+	if failing.closes != 1 || other.closes != 1 {
+		t.Errorf("expected all files to be closed once, got %v and %v", failing.closes, other.closes)
+	}
+}
@@ -0,0 +1,308 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the functions to raise a dispute, if the results of the
+render nodes for a render job disagree.
+
+Dispute life cycle:
+  A participant of the render job (i.e., the requesting node or a node that
+  rendered the job) packages the disagreeing render results into an evidence
+  document, which is added to IPFS. The CID of the evidence document is passed
+  to the Renderhive smart contract, which resolves the dispute. The resolution
+  is announced on the job queue topic and each node updates its dispute state.
+  Only resolutions announced by the requester of the render job (or by this
+  node) are accepted.
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/hedera"
+	"renderhive/ipfs"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// enum for the dispute states
+const (
+
+	// skip the 0 value (reserved for error)
+	_ int = iota
+
+	// dispute states
+	DISPUTE_STATE_RAISED   // the dispute was raised on the smart contract
+	DISPUTE_STATE_RESOLVED // the dispute was resolved and a result was accepted
+)
+
+// Result of a render node for a render job
+type RenderResult struct {
+	OperatorAccountID string         // Account ID of the operator who submitted the result
+	ResultCID         string         // Content identifier (CID) of the render result on the IPFS
+	FrameHashes       map[int]string // Hashes of the rendered frames (by frame number)
+	PreviewHashes     map[int]string `json:",omitempty"` // Hashes of the frame previews (by frame number; if post-processed)
+	WorkProofCID      string         `json:",omitempty"` // CID of the work proof document on the IPFS (see workproof.go)
+	JobRoot           string         `json:",omitempty"` // Merkle root of the work proof ("0x" + hex)
+
+	SettlementTransactionID string `json:",omitempty"` // transaction ID of the claimRenderJob call, which settled the render job
+}
+
+// Evidence document of a dispute
+type DisputeEvidence struct {
+	RenderRequestCID string         // CID of the disputed render request document
+	RaisedBy         string         // Account ID of the operator who raised the dispute
+	Reason           string         // Reason for the dispute
+	CreatedTimestamp time.Time      // The datetime the evidence was packaged
+	Results          []RenderResult // The disagreeing render results
+}
+
+// Dispute raised by this node
+type Dispute struct {
+	RenderRequestCID  string    // CID of the disputed render request document
+	EvidenceCID       string    // CID of the evidence document on the IPFS
+	EvidencePath      string    // Local path of the evidence document on this node
+	State             int       // State of the dispute (DISPUTE_STATE_*)
+	AcceptedResultCID string    // CID of the render result accepted by the resolution
+	RaisedTimestamp   time.Time // The datetime the dispute was raised
+	ResolvedTimestamp time.Time // The datetime the dispute was resolved
+}
+
+// DISPUTES
+// #############################################################################
+// Check if this node is a participant of the render job
+func (nm *PackageManager) IsJobParticipant(requestCID string) bool {
+
+	// the node requested the render job
+	request, ok := nm.Renderer.Requests[requestCID]
+	if ok && request.Owner != nil && request.Owner.String() == nm.User.UserAccount.AccountID.String() {
+		return true
+	}
+
+	// the node rendered the render job
+	for _, job := range nm.Renderer.NodeQueue {
+		if job.Request != nil && job.Request.DocumentCID == requestCID {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// Package the disagreeing render results into an evidence document and add it to IPFS
+func (nm *PackageManager) PackageDisputeEvidence(requestCID string, results []RenderResult, reason string) (*Dispute, error) {
+	var err error
+
+	// at least two results are required for a disagreement
+	if len(results) < 2 {
+		return nil, newRenderError(ErrInvalidArgument, "A dispute requires at least two render results (got %v).", len(results))
+	}
+
+	// the results must actually disagree
+	divergent := false
+	for _, result := range results {
+		if result.ResultCID == "" {
+			return nil, newRenderError(ErrInvalidArgument, "Render result of operator '%v' has no CID.", result.OperatorAccountID)
+		}
+		if result.ResultCID != results[0].ResultCID {
+			divergent = true
+		}
+	}
+	if !divergent {
+		return nil, newRenderError(ErrInvalidArgument, "The render results do not disagree.")
+	}
+
+	// prepare the evidence document
+	evidence := DisputeEvidence{
+		RenderRequestCID: requestCID,
+		RaisedBy:         nm.User.UserAccount.AccountID.String(),
+		Reason:           reason,
+		CreatedTimestamp: time.Now(),
+		Results:          results,
+	}
+	data, err := json.MarshalIndent(evidence, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	// write the evidence document into the local disputes directory
+	evidence_document_directory := filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_LOCAL_DISPUTES)
+	err = os.MkdirAll(evidence_document_directory, 0700)
+	if err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+	evidence_document_filename := fmt.Sprintf("dispute-%v-%v.json", strings.ReplaceAll(evidence.RaisedBy, ".", "_"), evidence.CreatedTimestamp.Unix())
+	dispute := &Dispute{
+		RenderRequestCID: requestCID,
+		EvidencePath:     filepath.Join(evidence_document_directory, evidence_document_filename),
+	}
+	err = os.WriteFile(dispute.EvidencePath, data, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	// add the evidence document to the local IPFS node
+	dispute.EvidenceCID, err = ipfs.Manager.AddObjectFromPath(dispute.EvidencePath, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// log event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf("Packaged dispute evidence for render job '%v': %v", requestCID, dispute.EvidenceCID))
+
+	return dispute, err
+
+}
+
+// Raise a dispute for a render job on the Renderhive smart contract
+func (nm *PackageManager) RaiseDispute(contractID string, requestCID string, results []RenderResult, reason string, gas uint64) (*Dispute, []byte, error) {
+	var err error
+
+	// only participants of the render job may raise a dispute
+	if !nm.IsJobParticipant(requestCID) {
+		return nil, nil, newRenderError(ErrInvalidArgument, "This node is not a participant of the render job '%v'.", requestCID)
+	}
+
+	// check if a dispute was already raised
+	if dispute, ok := nm.Renderer.Disputes[requestCID]; ok && dispute.State == DISPUTE_STATE_RAISED {
+		return nil, nil, newRenderError(ErrAlreadyExists, "A dispute for render job '%v' was already raised.", requestCID)
+	}
+
+	// prepare the contract object
+	id, err := hederasdk.ContractIDFromString(contractID)
+	if err != nil {
+		return nil, nil, err
+	}
+	contract := hedera.HederaSmartContract{ID: id}
+
+	// package the evidence
+	dispute, err := nm.PackageDisputeEvidence(requestCID, results, reason)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// call the contract function
+	params := hederasdk.NewContractFunctionParameters().AddString(requestCID).AddString(dispute.EvidenceCID)
+	_, _, transactionBytes, err := contract.CallFunction("raiseDispute", params, gas, hedera.TransactionOptions.SetExecute(false, nm.User.UserAccount.AccountID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// track the dispute
+	dispute.State = DISPUTE_STATE_RAISED
+	dispute.RaisedTimestamp = time.Now()
+	if nm.Renderer.Disputes == nil {
+		nm.Renderer.Disputes = make(map[string]*Dispute)
+	}
+	nm.Renderer.Disputes[requestCID] = dispute
+
+	// store the disagreeing results
+	for i := range results {
+		if err := nm.Repository.SaveResult(requestCID, &results[i]); err != nil {
+			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf(" [#] Could not store render result of operator '%v': %v", results[i].OperatorAccountID, err))
+		}
+	}
+
+	return dispute, transactionBytes, err
+
+}
+
+// helper function to check if an account may announce the resolution of a
+// dispute (i.e., the requester of the render job or the account of this node)
+func (nm *PackageManager) _isDisputeResolver(requestCID string, sender string) bool {
+
+	if sender == "" {
+		return false
+	}
+
+	// the resolution was announced by this node
+	if sender == nm.User.UserAccount.AccountID.String() {
+		return true
+	}
+
+	// the resolution was announced by the requester of the render job
+	if request, ok := nm.Renderer.Requests[requestCID]; ok && request.Owner != nil {
+		return request.Owner.String() == sender
+	}
+	for _, queue := range [][]*RenderJob{nm.Renderer.NodeQueue, nm.NetworkQueue} {
+		for _, job := range queue {
+			if job.Request != nil && job.Request.DocumentCID == requestCID && job.Request.Owner != nil {
+				return job.Request.Owner.String() == sender
+			}
+		}
+	}
+
+	return false
+
+}
+
+// Update the dispute state from a dispute resolution message
+func (nm *PackageManager) ResolveDispute(resolution *ResolveDisputeArgs) {
+
+	// only disputes tracked by this node are of interest
+	dispute, ok := nm.Renderer.Disputes[resolution.RenderRequestCID]
+	if !ok {
+		return
+	}
+
+	// update the dispute state
+	dispute.State = DISPUTE_STATE_RESOLVED
+	dispute.AcceptedResultCID = resolution.AcceptedResultCID
+	dispute.ResolvedTimestamp = time.Now()
+
+	// log event
+	logger.Manager.Package["node"].Info().Msg(fmt.Sprintf("Dispute for render job '%v' was resolved (accepted result: %v, reason: %v).", dispute.RenderRequestCID, dispute.AcceptedResultCID, resolution.Reason))
+
+	// flag the job, if the result of this node was not accepted
+	for _, job := range nm.Renderer.NodeQueue {
+		if job.Request != nil && job.Request.DocumentCID == dispute.RenderRequestCID && job.Result != nil {
+			if job.Result.ResultCID != dispute.AcceptedResultCID {
+				job.Flagged = true
+				job.Save()
+				logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf(" [#] The result of this node (%v) was not accepted.", job.Result.ResultCID))
+			}
+		}
+	}
+
+	// the evidence is not required anymore
+	if dispute.EvidenceCID != "" {
+		_, err := ipfs.Manager.UnPinObject(dispute.EvidenceCID)
+		if err != nil {
+			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf(" [#] Could not unpin dispute evidence: %v", err))
+		}
+	}
+
+}
@@ -0,0 +1,63 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"testing"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+)
+
+func TestIsDisputeResolver(t *testing.T) {
+	requester := hederasdk.AccountID{Account: 1001}
+	nm := &PackageManager{}
+	nm.User.UserAccount.AccountID = hederasdk.AccountID{Account: 3003}
+	nm.NetworkQueue = []*RenderJob{
+		{Request: &RenderRequest{DocumentCID: "request-a", Owner: &requester}},
+	}
+
+	tests := []struct {
+		request string
+		sender  string
+		want    bool
+	}{
+		{"request-a", "0.0.1001", true},  // the requester
+		{"request-a", "0.0.3003", true},  // this node
+		{"request-a", "0.0.2002", false}, // another operator
+		{"request-a", "", false},         // unknown sender
+		{"request-b", "0.0.1001", false}, // unknown render job
+	}
+	for _, test := range tests {
+		if got := nm._isDisputeResolver(test.request, test.sender); got != test.want {
+			t.Errorf("%v by %q: got %v, want %v", test.request, test.sender, got, test.want)
+		}
+	}
+
+	// the render requests of this node take precedence
+	other := hederasdk.AccountID{Account: 4004}
+	nm.Renderer.Requests = map[string]*RenderRequest{"request-a": {DocumentCID: "request-a", Owner: &other}}
+	if nm._isDisputeResolver("request-a", "0.0.1001") {
+		t.Error("the owner of the local render request must be the resolver")
+	}
+}
@@ -0,0 +1,131 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the detection of duplicate render offers.
+
+Each deploy of a render offer creates a new render offer document. Therefore, a
+node could accidentally announce several render offers with the same terms on
+the job queue topic. A render offer is a duplicate, if an active render offer of
+this node, which was already submitted and is not paused, has the same terms:
+
+  - the same owner and price
+  - the same Blender versions with the same engines, feature sets, devices,
+    and threads (independent of their order)
+
+The build info of the Blender apps is not part of the terms. A duplicate is not
+deployed or submitted. Instead, the existing render offer stays active.
+
+*/
+
+import (
+
+	// standard
+	"fmt"
+	"sort"
+	"strings"
+
+	// internal
+	"renderhive/logger"
+)
+
+// DUPLICATE RENDER OFFERS
+// #############################################################################
+// Check if two render offers have the same terms
+func (offer *RenderOffer) SameTerms(other *RenderOffer) bool {
+
+	if offer == nil || other == nil {
+		return false
+	}
+	if (offer.Owner == nil) != (other.Owner == nil) || (offer.Owner != nil && offer.Owner.String() != other.Owner.String()) {
+		return false
+	}
+	if offer.Price != other.Price || len(offer.BlenderVersions) != len(other.BlenderVersions) {
+		return false
+	}
+
+	// compare the Blender versions independent of their order
+	terms := map[string]int{}
+	for _, blender := range offer.BlenderVersions {
+		terms[_blenderTerms(blender)]++
+	}
+	for _, blender := range other.BlenderVersions {
+		key := _blenderTerms(blender)
+		if terms[key] == 0 {
+			return false
+		}
+		terms[key]--
+	}
+
+	return true
+
+}
+
+// Get the active and submitted render offer, which has the same terms as the
+// given render offer (nil, if there is none)
+// NOTE: The render offer itself is not a duplicate.
+func (nm *PackageManager) FindDuplicateRenderOffer(offer *RenderOffer) *RenderOffer {
+
+	for _, active := range nm.GetActiveRenderOffers() {
+		if active == offer || (offer.DocumentCID != "" && _sameCID(active.DocumentCID, offer.DocumentCID)) {
+			continue
+		}
+		if !_isAnnounced(active) {
+			continue
+		}
+		if active.SameTerms(offer) {
+			return active
+		}
+	}
+
+	return nil
+
+}
+
+// helper function to refuse a render offer, which duplicates an active render offer
+func (nm *PackageManager) _refuseDuplicateRenderOffer(offer *RenderOffer, action string) error {
+
+	duplicate := nm.FindDuplicateRenderOffer(offer)
+	if duplicate == nil {
+		return nil
+	}
+
+	// log event
+	logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Prevented to %v a duplicate of the active render offer '%v'.", action, duplicate.DocumentCID))
+
+	return newRenderError(ErrAlreadyExists, "The render offer has the same terms as the active render offer '%v'. Change the price or the Blender versions, or keep the active render offer.", duplicate.DocumentCID)
+
+}
+
+// helper function to get the terms of an offered Blender version as a comparable key
+func _blenderTerms(blender RenderOfferBlenderVersions) string {
+
+	sorted := func(values []string) string {
+		values = append([]string(nil), values...)
+		sort.Strings(values)
+		return strings.Join(values, ",")
+	}
+
+	return fmt.Sprintf("%v|%v|%v|%v|%v", blender.Version, sorted(blender.Engines), sorted(blender.FeatureSets), sorted(blender.Devices), blender.Threads)
+
+}
@@ -0,0 +1,126 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"errors"
+	"testing"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	"renderhive/logger"
+)
+
+// helper function to create a render offer with two Blender versions
+func _testDuplicateOffer(cid string, price float64) *RenderOffer {
+	return &RenderOffer{
+		DocumentCID: cid,
+		Owner:       &hederasdk.AccountID{Account: 1234},
+		Price:       price,
+		BlenderVersions: []RenderOfferBlenderVersions{
+			{Version: "4.1.0", Engines: []string{"CYCLES", "EEVEE"}, Devices: []string{"CPU", "GPU"}, Threads: 8, BuildHash: "abc", Verified: true},
+			{Version: "3.6.0", Engines: []string{"CYCLES"}, Devices: []string{"CPU"}, Threads: 8},
+		},
+	}
+}
+
+func TestSameTerms(t *testing.T) {
+	offer := _testDuplicateOffer(testOfferCID, 1.5)
+
+	// the same terms in a different order and with a different build info
+	same := _testDuplicateOffer(testOfferCID2, 1.5)
+	same.BlenderVersions[0], same.BlenderVersions[1] = same.BlenderVersions[1], same.BlenderVersions[0]
+	same.BlenderVersions[1].Engines = []string{"EEVEE", "CYCLES"}
+	same.BlenderVersions[1].BuildHash, same.BlenderVersions[1].Verified = "def", false
+	if !offer.SameTerms(same) || !same.SameTerms(offer) {
+		t.Error("expected render offers with the same terms to be duplicates")
+	}
+
+	// different terms
+	for name, modify := range map[string]func(offer *RenderOffer){
+		"price":    func(offer *RenderOffer) { offer.Price = 2 },
+		"owner":    func(offer *RenderOffer) { offer.Owner = &hederasdk.AccountID{Account: 5678} },
+		"no owner": func(offer *RenderOffer) { offer.Owner = nil },
+		"version":  func(offer *RenderOffer) { offer.BlenderVersions[1].Version = "3.6.1" },
+		"engines":  func(offer *RenderOffer) { offer.BlenderVersions[0].Engines = []string{"CYCLES"} },
+		"devices":  func(offer *RenderOffer) { offer.BlenderVersions[1].Devices = []string{"GPU"} },
+		"threads":  func(offer *RenderOffer) { offer.BlenderVersions[0].Threads = 4 },
+		"feature sets": func(offer *RenderOffer) {
+			offer.BlenderVersions[0].FeatureSets = []string{"EXPERIMENTAL"}
+		},
+		"versions": func(offer *RenderOffer) { offer.BlenderVersions = offer.BlenderVersions[:1] },
+		"duplicate version": func(offer *RenderOffer) {
+			offer.BlenderVersions[1] = offer.BlenderVersions[0]
+		},
+	} {
+		other := _testDuplicateOffer(testOfferCID2, 1.5)
+		modify(other)
+		if offer.SameTerms(other) || other.SameTerms(offer) {
+			t.Errorf("%v: expected render offers with different terms not to be duplicates", name)
+		}
+	}
+	if offer.SameTerms(nil) {
+		t.Error("expected no render offer to have the same terms as nil")
+	}
+}
+
+func TestFindDuplicateRenderOffer(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+
+	active := _testDuplicateOffer(testOfferCID, 1.5)
+	nm := &PackageManager{}
+	nm.Renderer.ActiveOffers = []*RenderOffer{active}
+
+	// a render offer that was not announced yet is no duplicate target
+	offer := _testDuplicateOffer("", 1.5)
+	if duplicate := nm.FindDuplicateRenderOffer(offer); duplicate != nil {
+		t.Errorf("expected no duplicate of an unannounced render offer, got %v", duplicate.DocumentCID)
+	}
+
+	// the announced render offer is found, but not for itself
+	active.SubmittedTimestamp = time.Now()
+	if duplicate := nm.FindDuplicateRenderOffer(offer); duplicate != active {
+		t.Errorf("expected the active render offer as duplicate, got %v", duplicate)
+	}
+	if duplicate := nm.FindDuplicateRenderOffer(active); duplicate != nil {
+		t.Error("expected a render offer not to duplicate itself")
+	}
+	if err := nm._refuseDuplicateRenderOffer(offer, "deploy"); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("expected the duplicate to be refused, got %v", err)
+	}
+
+	// a paused render offer or different terms are no duplicate
+	offer.Price = 2
+	if err := nm._refuseDuplicateRenderOffer(offer, "deploy"); err != nil {
+		t.Errorf("expected a render offer with different terms to be accepted, got %v", err)
+	}
+	offer.Price = 1.5
+	active.Paused = true
+	if duplicate := nm.FindDuplicateRenderOffer(offer); duplicate != nil {
+		t.Error("expected a paused render offer not to be a duplicate")
+	}
+}
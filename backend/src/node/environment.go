@@ -0,0 +1,251 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the process environment of the Blender instances of this
+node.
+
+Blender inherits the environment of the app. In addition, each render job gets
+its own scratch directory, which is the working and temporary directory of its
+Blender process, and only sees the GPUs assigned to it. This way, several jobs
+can be rendered on different GPUs of the same computer at the same time.
+
+The environment can be set in the optional 'environment.json' file of the
+configuration directory:
+
+    {
+      "scratch_directory": "/mnt/fast-disk/renderhive",
+      "gpus": ["0", "1"],
+      "variables": {"OMP_NUM_THREADS": "8"}
+    }
+
+The GPUs are the device IDs of CUDA and HIP. Jobs rendered on the CPU do not
+see any GPU.
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	// internal
+	. "renderhive/globals"
+	. "renderhive/utility"
+)
+
+// Environment variables that select the GPUs visible to Blender
+var blenderGPUVariables = []string{"CUDA_VISIBLE_DEVICES", "HIP_VISIBLE_DEVICES"}
+
+// Environment variables that select the temporary directory of Blender
+var blenderTempVariables = []string{"TMPDIR", "TEMP", "TMP"}
+
+// Process environment of the Blender instances of this node
+type BlenderEnvironment struct {
+	ScratchDirectory string            `json:"scratch_directory"` // parent directory of the scratch directories (empty = app data directory)
+	GPUs             []string          `json:"gpus"`              // GPUs Blender may use (empty = all GPUs)
+	Variables        map[string]string `json:"variables"`         // further environment variables of Blender
+}
+
+// BLENDER PROCESS ENVIRONMENT
+// #############################################################################
+// Get the default process environment of Blender
+func DefaultBlenderEnvironment() BlenderEnvironment {
+	return BlenderEnvironment{GPUs: []string{}, Variables: map[string]string{}}
+}
+
+// Read the process environment of Blender from the configuration file
+func (env *BlenderEnvironment) Read() error {
+	var err error
+
+	// Open the configuration file
+	file, err := os.Open(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "environment.json"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Read the file content
+	fileData, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	// get the file content into a structure
+	err = json.Unmarshal(fileData, env)
+	if err != nil {
+		return err
+	}
+
+	return env.Validate()
+
+}
+
+// Check the process environment of Blender for invalid values
+func (env *BlenderEnvironment) Validate() error {
+
+	for _, gpu := range env.GPUs {
+		if strings.TrimSpace(gpu) == "" || strings.Contains(gpu, ",") {
+			return newRenderError(ErrInvalidArgument, "Invalid GPU '%v' (expected a device ID, e.g. '0').", gpu)
+		}
+	}
+	for name := range env.Variables {
+		if name == "" || strings.ContainsAny(name, "= \t\n") {
+			return newRenderError(ErrInvalidArgument, "Invalid name '%v' of an environment variable.", name)
+		}
+	}
+
+	return nil
+
+}
+
+// Get the process environment of Blender (the configured or the default environment)
+func (nm *PackageManager) GetBlenderEnvironment() BlenderEnvironment {
+
+	// read the configured environment
+	env := DefaultBlenderEnvironment()
+	err := env.Read()
+	if err != nil {
+		return DefaultBlenderEnvironment()
+	}
+
+	return env
+
+}
+
+// Get the parent directory of the scratch directories
+func (env BlenderEnvironment) ScratchPath() string {
+
+	if env.ScratchDirectory != "" {
+		return env.ScratchDirectory
+	}
+
+	return filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_BLENDER_SCRATCH)
+
+}
+
+// Get the working directory and the environment variables of a Blender process
+// NOTE: The device is the render device of the job ("CPU" hides all GPUs) and
+// the GPUs are the GPUs assigned to it (empty = all configured GPUs).
+func (env BlenderEnvironment) Process(name string, device string, gpus []string) (string, []string, error) {
+
+	// the scratch directory of the process
+	if name == "" || filepath.Base(name) != name || name == "." || name == ".." {
+		return "", nil, newRenderError(ErrInvalidArgument, "Invalid name '%v' of a scratch directory.", name)
+	}
+	dir := filepath.Join(env.ScratchPath(), name)
+
+	// the configured variables (sorted for a reproducible environment)
+	variables := []string{}
+	names := make([]string, 0, len(env.Variables))
+	for variable := range env.Variables {
+		names = append(names, variable)
+	}
+	sort.Strings(names)
+	for _, variable := range names {
+		variables = append(variables, variable+"="+env.Variables[variable])
+	}
+
+	// the temporary directory
+	for _, variable := range blenderTempVariables {
+		variables = append(variables, variable+"="+dir)
+	}
+
+	// the visible GPUs
+	visible := env.GPUs
+	if len(gpus) != 0 {
+		for _, gpu := range gpus {
+			if len(env.GPUs) != 0 && !_containsFold(env.GPUs, gpu) {
+				return "", nil, newRenderError(ErrInvalidArgument, "GPU '%v' is not available for rendering (available: %v).", gpu, strings.Join(env.GPUs, ", "))
+			}
+		}
+		visible = gpus
+	}
+	if strings.EqualFold(device, "CPU") || len(visible) != 0 {
+		value := strings.Join(visible, ",")
+		if strings.EqualFold(device, "CPU") {
+			value = ""
+		}
+		for _, variable := range blenderGPUVariables {
+			variables = append(variables, variable+"="+value)
+		}
+	}
+
+	return dir, variables, nil
+
+}
+
+// Set the process environment of the Blender instance rendering a render job
+func (nm *PackageManager) PrepareBlender(job *RenderJob) error {
+
+	if job.Blender == nil || job.Request == nil {
+		return newRenderError(ErrInvalidArgument, "The render job has no Blender instance or render request.")
+	}
+
+	// each job gets its own scratch directory
+	name := job.Request.DocumentCID
+	if job.Subtask != nil {
+		name = fmt.Sprintf("%v_%v", name, job.Subtask.Index)
+	}
+
+	dir, variables, err := nm.GetBlenderEnvironment().Process(name, job.Request.BlenderFile.Settings.Device, job.GPUs)
+	if err != nil {
+		return err
+	}
+	job.Blender.Dir = dir
+	job.Blender.Env = variables
+
+	return nil
+
+}
+
+// helper function to create the command of a Blender process
+func (b *BlenderAppData) _command(args []string) (*exec.Cmd, error) {
+
+	cmd := exec.Command(b.Path, append([]string{"-b"}, args...)...)
+
+	// the working directory of the process
+	if b.Dir != "" {
+		err := os.MkdirAll(b.Dir, 0700)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Dir = b.Dir
+	}
+
+	// the environment of the app and the variables of the process
+	// NOTE: Later values of a variable replace earlier ones.
+	if len(b.Env) != 0 {
+		cmd.Env = append(os.Environ(), b.Env...)
+	}
+
+	return cmd, nil
+
+}
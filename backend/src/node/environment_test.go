@@ -0,0 +1,193 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	// internal
+	. "renderhive/globals"
+)
+
+// fake Blender binary printing its working directory and environment
+const testEnvironmentBlender = `#!/bin/sh
+pwd -P
+echo "TMPDIR=$TMPDIR"
+echo "CUDA_VISIBLE_DEVICES=$CUDA_VISIBLE_DEVICES"
+echo "OMP_NUM_THREADS=$OMP_NUM_THREADS"
+echo "RENDERHIVE_TEST=$RENDERHIVE_TEST"
+`
+
+// helper function to get the value of an environment variable (the last value wins)
+func _lookupVariable(variables []string, name string) (string, bool) {
+	value, found := "", false
+	for _, variable := range variables {
+		if key, v, ok := strings.Cut(variable, "="); ok && key == name {
+			value, found = v, true
+		}
+	}
+
+	return value, found
+}
+
+func TestBlenderEnvironmentPrecedence(t *testing.T) {
+	env := BlenderEnvironment{
+		ScratchDirectory: "/scratch",
+		GPUs:             []string{"0", "1"},
+		Variables:        map[string]string{"OMP_NUM_THREADS": "8", "TMPDIR": "/configured", "CUDA_VISIBLE_DEVICES": "5"},
+	}
+
+	dir, variables, err := env.Process("job", "GPU", []string{"1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir != filepath.Join("/scratch", "job") {
+		t.Errorf("got scratch directory %v, want /scratch/job", dir)
+	}
+
+	// the configured variables are set, but the scratch directory and the
+	// GPUs of the job replace the configured values
+	for name, want := range map[string]string{"OMP_NUM_THREADS": "8", "TMPDIR": dir, "TEMP": dir, "CUDA_VISIBLE_DEVICES": "1", "HIP_VISIBLE_DEVICES": "1"} {
+		if value, _ := _lookupVariable(variables, name); value != want {
+			t.Errorf("%v: got %q, want %q", name, value, want)
+		}
+	}
+
+	// without GPUs of the job, all configured GPUs are visible
+	_, variables, _ = env.Process("job", "GPU", nil)
+	if value, _ := _lookupVariable(variables, "CUDA_VISIBLE_DEVICES"); value != "0,1" {
+		t.Errorf("got visible GPUs %q, want the configured GPUs", value)
+	}
+
+	// jobs rendered on the CPU do not see any GPU
+	_, variables, _ = env.Process("job", "cpu", []string{"1"})
+	if value, found := _lookupVariable(variables, "CUDA_VISIBLE_DEVICES"); !found || value != "" {
+		t.Errorf("got visible GPUs %q (%v), want none", value, found)
+	}
+
+	// without configured GPUs, the visible GPUs of the app are not changed
+	_, variables, _ = DefaultBlenderEnvironment().Process("job", "GPU", nil)
+	if _, found := _lookupVariable(variables, "CUDA_VISIBLE_DEVICES"); found {
+		t.Error("expected the visible GPUs not to be set")
+	}
+}
+
+func TestBlenderEnvironmentRejectsInvalidValues(t *testing.T) {
+	env := BlenderEnvironment{GPUs: []string{"0"}}
+	if _, _, err := env.Process("job", "GPU", []string{"2"}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("got %v, want an error for a GPU that is not configured", err)
+	}
+	for _, name := range []string{"", ".", "..", "../job", "a/b"} {
+		if _, _, err := env.Process(name, "CPU", nil); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%q: got %v, want an invalid scratch directory", name, err)
+		}
+	}
+	for _, env := range []BlenderEnvironment{{GPUs: []string{"0,1"}}, {GPUs: []string{" "}}, {Variables: map[string]string{"A=B": "c"}}} {
+		if err := env.Validate(); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%+v: got %v, want an invalid environment", env, err)
+		}
+	}
+}
+
+func TestBlenderEnvironmentScratchDirectory(t *testing.T) {
+	_chdirTemp(t)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	nm := &PackageManager{}
+
+	// the scratch directories are in the app data directory by default
+	env := nm.GetBlenderEnvironment()
+	if want := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), RENDERHIVE_APP_DIRECTORY, RENDERHIVE_APP_DIRECTORY_BLENDER_SCRATCH); env.ScratchPath() != want {
+		t.Errorf("got scratch path %v, want %v", env.ScratchPath(), want)
+	}
+
+	// the configured directory replaces the app data directory
+	if err := os.MkdirAll(RENDERHIVE_APP_DIRECTORY_CONFIG, 0700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "environment.json")
+	if err := os.WriteFile(path, []byte(`{"scratch_directory": "/mnt/fast-disk", "variables": {"OMP_NUM_THREADS": "8"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env = nm.GetBlenderEnvironment()
+	if env.ScratchPath() != "/mnt/fast-disk" || env.Variables["OMP_NUM_THREADS"] != "8" {
+		t.Errorf("got environment %+v, want the configured environment", env)
+	}
+
+	// an invalid configuration is ignored
+	if err := os.WriteFile(path, []byte(`{"scratch_directory": "/mnt/fast-disk", "gpus": ["0,1"]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if env = nm.GetBlenderEnvironment(); env.ScratchDirectory != "" {
+		t.Errorf("got environment %+v, want the default environment", env)
+	}
+}
+
+func TestBlenderCommandGetsTheEnvironment(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "blender")
+	if err := os.WriteFile(path, []byte(testEnvironmentBlender), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// the app's environment is inherited, but the variables of the process replace it
+	t.Setenv("RENDERHIVE_TEST", "app")
+	t.Setenv("OMP_NUM_THREADS", "1")
+	t.Setenv("CUDA_VISIBLE_DEVICES", "0,1,2,3")
+	env := BlenderEnvironment{ScratchDirectory: filepath.Join(directory, "scratch"), GPUs: []string{"2", "3"}, Variables: map[string]string{"OMP_NUM_THREADS": "8"}}
+	dir, variables, err := env.Process("job", "GPU", []string{"3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blender := &BlenderAppData{Path: path, Dir: dir, Env: variables}
+	cmd, err := blender._command(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the scratch directory is created and is the working directory
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{realDir, "TMPDIR=" + dir, "CUDA_VISIBLE_DEVICES=3", "OMP_NUM_THREADS=8", "RENDERHIVE_TEST=app"}, "\n") + "\n"
+	if string(output) != want {
+		t.Errorf("got the process output\n%v\nwant\n%v", string(output), want)
+	}
+
+	// without a process environment, Blender runs in the app's environment
+	blender = &BlenderAppData{Path: path}
+	if cmd, err = blender._command(nil); err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Dir != "" || cmd.Env != nil {
+		t.Errorf("got directory %q and environment %v, want the app's", cmd.Dir, cmd.Env)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file defines the kinds of errors returned by the render offer and render
+request functions. Each error keeps its descriptive message, but wraps one of
+the sentinel errors, so that callers can distinguish the error kinds with
+errors.Is (or get the RenderError with errors.As).
+
+*/
+
+import (
+
+	// standard
+	"errors"
+	"fmt"
+)
+
+// kinds of render errors
+var (
+	ErrOfferNotFound        = errors.New("render offer not found")
+	ErrRequestNotFound      = errors.New("render request not found")
+	ErrDocumentNotFound     = errors.New("render document not found")
+	ErrAlreadySubmitted     = errors.New("already submitted")
+	ErrAlreadyExists        = errors.New("already exists")
+	ErrDocumentMismatch     = errors.New("render document does not match")
+	ErrUnsupportedVersion   = errors.New("unsupported Blender version")
+	ErrInvalidArgument      = errors.New("invalid argument")
+	ErrNetworkUnavailable   = errors.New("network unavailable")
+	ErrTransactionFailed    = errors.New("transaction failed")
+	ErrBenchmarkUnavailable = errors.New("benchmark unavailable")
+	ErrBenchmarkCanceled    = errors.New("benchmark canceled")
+	ErrJobInfeasible        = errors.New("render job infeasible")
+	ErrBlenderCrashed       = errors.New("Blender crashed")
+	ErrUnsupportedSchema    = errors.New("unsupported document schema version")
+	ErrUntrustedScript      = errors.New("untrusted setup script")
+	ErrRequesterOnly        = errors.New("requester-only mode")
+	ErrStorageFailed        = errors.New("local storage failed")
+)
+
+// Error of a render offer or render request function
+type RenderError struct {
+	Kind    error  // one of the sentinel errors (Err*)
+	Message string // descriptive error message
+	Err     error  // underlying error (if any)
+}
+
+// RENDER ERRORS
+// #############################################################################
+// Get the descriptive error message
+func (e *RenderError) Error() string {
+	return e.Message
+}
+
+// Get the kind and the underlying error for errors.Is and errors.As
+func (e *RenderError) Unwrap() []error {
+
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+
+	return []error{e.Kind}
+
+}
+
+// Create a render error of the given kind
+func newRenderError(kind error, format string, args ...interface{}) error {
+
+	// keep the underlying error wrapped by '%w'
+	wrapped := fmt.Errorf(format, args...)
+
+	return &RenderError{Kind: kind, Message: wrapped.Error(), Err: errors.Unwrap(wrapped)}
+
+}
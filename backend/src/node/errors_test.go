@@ -0,0 +1,160 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+	// standard
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// render repository, which cannot read its documents
+type failingRenderRepository struct {
+	*JsonRenderRepository
+}
+
+func (r failingRenderRepository) LoadOffers() ([]RenderDocument, error) {
+	return nil, errors.New("disk failure")
+}
+
+func (r failingRenderRepository) LoadRequests() ([]RenderDocument, error) {
+	return nil, errors.New("disk failure")
+}
+
+func TestRenderErrorKinds(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	submitted := &RenderRequest{Receipt: &hederasdk.TransactionReceipt{Status: hederasdk.StatusSuccess}}
+	nm := &PackageManager{Repository: failingRenderRepository{NewJsonRenderRepository()}}
+	nm.Renderer.Requests = map[string]*RenderRequest{}
+
+	// a node with a render offer of Blender v4.1.0
+	offering := &PackageManager{}
+	offering.Renderer.ActiveOffers = []*RenderOffer{{Blender: map[string]BlenderAppData{"4.1.0": {Path: "blender"}}}}
+
+	// a node, which already raised a dispute for the render job it rendered
+	disputing := &PackageManager{}
+	disputing.Renderer.NodeQueue = []*RenderJob{{Request: &RenderRequest{DocumentCID: "request"}}}
+	disputing.Renderer.Disputes = map[string]*Dispute{"request": {State: DISPUTE_STATE_RAISED}}
+
+	tests := []struct {
+		name string
+		call func() error
+		kind error
+	}{
+		{"unknown Blender version of an offer", func() error { return (&RenderOffer{}).DeleteBlenderVersion("4.1.0") }, ErrUnsupportedVersion},
+		{"unknown file of a request", func() error { return (&RenderRequest{}).RemoveFile("scene.blend") }, ErrDocumentNotFound},
+		{"file of a submitted request", func() error { return submitted.RemoveFile("scene.blend") }, ErrAlreadySubmitted},
+		{"missing directory of a request", func() error { return (&RenderRequest{}).RemoveDirectory() }, ErrDocumentNotFound},
+		{"uploaded directory of a request", func() error { return (&RenderRequest{DirectoryCID: "cid"}).RemoveDirectory() }, ErrAlreadySubmitted},
+		{"unknown render request", func() error { return nm.RemoveRenderRequest(42) }, ErrRequestNotFound},
+		{"unreadable render offers", nm.InitRenderOffers, ErrStorageFailed},
+		{"unreadable render requests", nm.InitRenderRequests, ErrStorageFailed},
+		{"inspection without render offer", func() error {
+			_, err := nm.InspectRenderRequest(&RenderRequest{Version: "4.1.0"}, nil)
+			return err
+		}, ErrUnsupportedVersion},
+		{"inspection with an unknown Blender version", func() error {
+			_, err := offering.InspectRenderRequest(&RenderRequest{Version: "3.6.0"}, nil)
+			return err
+		}, ErrUnsupportedVersion},
+		{"packing without render offer", func() error { return nm.PackRenderRequest(&RenderRequest{Version: "4.1.0"}) }, ErrUnsupportedVersion},
+		{"packing with an unknown Blender version", func() error { return offering.PackRenderRequest(&RenderRequest{Version: "3.6.0"}) }, ErrUnsupportedVersion},
+		{"dispute of a single result", func() error {
+			_, err := nm.PackageDisputeEvidence("request", []RenderResult{{}}, "")
+			return err
+		}, ErrInvalidArgument},
+		{"dispute of a non-participant", func() error {
+			_, _, err := nm.RaiseDispute("0.0.1", "other", nil, "", 0)
+			return err
+		}, ErrInvalidArgument},
+		{"dispute raised twice", func() error {
+			_, _, err := disputing.RaiseDispute("0.0.1", "request", nil, "", 0)
+			return err
+		}, ErrAlreadyExists},
+		{"invalid region grid", func() error {
+			_, err := SplitFrame(0, 2)
+			return err
+		}, ErrInvalidArgument},
+		{"empty region", (&RenderRegion{MinX: 0.5, MaxX: 0.5, MaxY: 1}).Validate, ErrInvalidArgument},
+		{"region out of the frame", (&RenderRegion{MaxX: 1.5, MaxY: 1}).Validate, ErrInvalidArgument},
+		{"short archive passphrase", func() error { return _validateArchivePassphrase("short") }, ErrInvalidArgument},
+		{"file without node archive", func() error {
+			_, err := _decryptNodeArchive([]byte("not an archive"), "passphrase")
+			return err
+		}, ErrInvalidArgument},
+		{"unknown repository backend", func() error { return _writeRepositoryConfig(t, `{"backend": "leveldb"}`, nm.InitRepository) }, ErrInvalidArgument},
+		{"invalid repository configuration", func() error { return _writeRepositoryConfig(t, `{"backend": `, nm.InitRepository) }, ErrInvalidArgument},
+	}
+	for _, test := range tests {
+		err := test.call()
+		if !errors.Is(err, test.kind) {
+			t.Errorf("%v: got %v, want an error of the kind %q", test.name, err, test.kind)
+			continue
+		}
+
+		// the error keeps its descriptive message
+		var renderErr *RenderError
+		if !errors.As(err, &renderErr) || renderErr.Error() == test.kind.Error() {
+			t.Errorf("%v: got %v, want a descriptive render error", test.name, err)
+		}
+	}
+}
+
+// helper function to call a function with the given repository configuration
+func _writeRepositoryConfig(t *testing.T, data string, call func() error) error {
+	t.Helper()
+
+	_chdirTemp(t)
+	if err := os.MkdirAll(RENDERHIVE_APP_DIRECTORY_CONFIG, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "repository.json"), []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return call()
+}
+
+func TestRenderErrorWrapsTheUnderlyingError(t *testing.T) {
+	cause := errors.New("disk failure")
+	err := newRenderError(ErrStorageFailed, "Could not load render offers: %w", cause)
+
+	if !errors.Is(err, ErrStorageFailed) || !errors.Is(err, cause) {
+		t.Errorf("got %v, want the kind and the underlying error", err)
+	}
+	if errors.Is(err, ErrOfferNotFound) {
+		t.Error("expected the error not to be of another kind")
+	}
+	if err.Error() != "Could not load render offers: disk failure" {
+		t.Errorf("got the message %q", err.Error())
+	}
+}
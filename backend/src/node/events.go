@@ -0,0 +1,317 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the events of the node, which are pushed to the frontend.
+
+The render worker, the job queue topic callback, the offer announcements, and
+the balance watcher publish typed events on the event bus of the node. The
+subscribers (e.g., the WebSocket connections of the JSON-RPC server) receive the
+events of the types they subscribed to. Each subscriber has a buffer of events
+and events are dropped for a subscriber, which does not read them fast enough,
+so that a slow client never blocks the node.
+
+Every event has the same envelope:
+
+  {"type": "job.claimed", "timestamp": "2024-05-01T12:00:00Z", "data": {...}}
+
+*/
+
+import (
+
+	// standard
+	"fmt"
+	"sync"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/hedera"
+	"renderhive/logger"
+)
+
+// types of the node events
+const (
+	EVENT_JOB_RECEIVED     = "job.received"     // a render request was received on the job queue (data: JobEventData)
+	EVENT_JOB_CLAIMED      = "job.claimed"      // this node claimed a render job (data: JobEventData)
+	EVENT_RENDER_PROGRESS  = "render.progress"  // Blender started to render the next frame of a job (data: RenderProgressEventData)
+	EVENT_RESULT_SUBMITTED = "result.submitted" // this node submitted a render result (data: ResultEventData)
+	EVENT_BALANCE_CHANGED  = "balance.changed"  // the balance of the operator account changed (data: BalanceEventData)
+	EVENT_OFFER_EXPIRED    = "offer.expired"    // an active offer was not announced in time (data: OfferEventData)
+)
+
+// all types of the node events
+var EventTypes = []string{
+	EVENT_JOB_RECEIVED,
+	EVENT_JOB_CLAIMED,
+	EVENT_RENDER_PROGRESS,
+	EVENT_RESULT_SUBMITTED,
+	EVENT_BALANCE_CHANGED,
+	EVENT_OFFER_EXPIRED,
+}
+
+// Event of the node
+type Event struct {
+	Type      string      `json:"type"`      // type of the event (e.g., "job.claimed")
+	Timestamp time.Time   `json:"timestamp"` // the datetime the event occurred
+	Data      interface{} `json:"data"`      // data of the event (depends on the type)
+}
+
+// Data of the render job events
+type JobEventData struct {
+	RenderRequestCID string    `json:"render_request_cid"`
+	Subtask          int       `json:"subtask"`            // index of the subtask (-1 = the whole request)
+	Operator         string    `json:"operator,omitempty"` // account ID of the operator, which claimed the job
+	Deadline         time.Time `json:"deadline"`           // render deadline of the claim (zero, if not claimed)
+}
+
+// Data of the render progress events
+type RenderProgressEventData struct {
+	RenderRequestCID string `json:"render_request_cid"`
+	Frame            string `json:"frame"`  // current frame number
+	Memory           string `json:"memory"` // current memory usage
+	Peak             string `json:"peak"`   // peak memory usage
+	Time             string `json:"time"`   // render time
+}
+
+// Data of the render result events
+type ResultEventData struct {
+	RenderRequestCID string `json:"render_request_cid"`
+	Subtask          int    `json:"subtask"` // index of the subtask (-1 = the whole request)
+	ResultCID        string `json:"result_cid"`
+}
+
+// Data of the balance events
+type BalanceEventData struct {
+	AccountID string  `json:"account_id"`
+	Balance   float64 `json:"balance"`  // new balance in HBAR
+	Previous  float64 `json:"previous"` // previous balance in HBAR
+}
+
+// Data of the render offer events
+type OfferEventData struct {
+	RenderOfferCID string `json:"render_offer_cid"`
+	Reason         string `json:"reason"`
+}
+
+// Event bus of the node
+type EventBus struct {
+	mutex       sync.Mutex
+	subscribers map[*EventSubscription]struct{}
+}
+
+// Subscription to the events of the node
+type EventSubscription struct {
+	Events  chan Event      // events of the subscribed types
+	Dropped int             // number of events dropped, since the buffer was full (guarded by the bus)
+	types   map[string]bool // subscribed event types (empty = all types)
+}
+
+// EVENT BUS
+// #############################################################################
+// Check if an event type is known
+func IsEventType(eventType string) bool {
+
+	for _, known := range EventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// Subscribe to events of the given types (all types, if none are given)
+func (bus *EventBus) Subscribe(types []string) (*EventSubscription, error) {
+
+	subscription := &EventSubscription{
+		Events: make(chan Event, RENDERHIVE_CONFIG_EVENT_BUFFER),
+		types:  map[string]bool{},
+	}
+	for _, eventType := range types {
+		if !IsEventType(eventType) {
+			return nil, newRenderError(ErrInvalidArgument, "Unknown event type '%v'.", eventType)
+		}
+		subscription.types[eventType] = true
+	}
+
+	// lock the bus
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	if bus.subscribers == nil {
+		bus.subscribers = make(map[*EventSubscription]struct{})
+	}
+	bus.subscribers[subscription] = struct{}{}
+
+	return subscription, nil
+
+}
+
+// Cancel a subscription and close its event channel
+func (bus *EventBus) Unsubscribe(subscription *EventSubscription) {
+
+	// lock the bus
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	if _, ok := bus.subscribers[subscription]; ok {
+		delete(bus.subscribers, subscription)
+		close(subscription.Events)
+	}
+
+}
+
+// Get the number of subscriptions
+func (bus *EventBus) Subscribers() int {
+
+	// lock the bus
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	return len(bus.subscribers)
+
+}
+
+// Send an event to the subscribers of its type
+// NOTE: The event is dropped for subscribers with a full buffer.
+func (bus *EventBus) Publish(event Event) {
+
+	// lock the bus
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	for subscription := range bus.subscribers {
+		if len(subscription.types) > 0 && !subscription.types[event.Type] {
+			continue
+		}
+		select {
+		case subscription.Events <- event:
+		default:
+			subscription.Dropped++
+		}
+	}
+
+}
+
+// Publish an event of the node
+func (nm *PackageManager) PublishEvent(eventType string, data interface{}) {
+
+	nm.Events.Publish(Event{
+		Type:      eventType,
+		Timestamp: time.Now(),
+		Data:      data,
+	})
+
+}
+
+// BALANCE WATCHER
+// #############################################################################
+// Check the balance of the operator account in the background and publish its changes
+// NOTE: The balance is only queried, while there are subscribers.
+func (nm *PackageManager) CheckOperatorBalance() error {
+
+	nm.balanceMutex.Lock()
+	defer nm.balanceMutex.Unlock()
+
+	// check at most once per poll interval and not during a running query
+	if nm.balanceChecking || time.Since(nm.lastBalanceCheck) < RENDERHIVE_CONFIG_BALANCE_POLL_INTERVAL {
+		return nil
+	}
+	nm.lastBalanceCheck = time.Now()
+	if nm.Events.Subscribers() == 0 {
+		return nil
+	}
+
+	// the operator needs to be signed in
+	query := nm.queryOperatorBalance
+	if query == nil {
+		if hedera.Manager.NetworkClient == nil || hedera.Manager.Operator.AccountID.Account == 0 {
+			return nil
+		}
+		query = _queryOperatorBalance
+	}
+
+	nm.balanceChecking = true
+	go func() {
+		err := nm._checkOperatorBalance(query)
+		if err != nil {
+			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Error in operator balance check: %v", err))
+		}
+
+		nm.balanceMutex.Lock()
+		nm.balanceChecking = false
+		nm.balanceMutex.Unlock()
+	}()
+
+	return nil
+
+}
+
+// helper function to query the balance of the operator account and publish its change
+func (nm *PackageManager) _checkOperatorBalance(query func() (hederasdk.Hbar, error)) error {
+
+	balance, err := query()
+	if err != nil {
+		return fmt.Errorf("Could not query the operator balance: %v", err)
+	}
+
+	nm.balanceMutex.Lock()
+	previous, known := nm.operatorBalance, nm.balanceKnown
+	nm.operatorBalance = balance
+	nm.balanceKnown = true
+	nm.balanceMutex.Unlock()
+
+	// the first query only initializes the balance
+	if !known || balance.AsTinybar() == previous.AsTinybar() {
+		return nil
+	}
+
+	nm.PublishEvent(EVENT_BALANCE_CHANGED, BalanceEventData{
+		AccountID: hedera.Manager.Operator.AccountID.String(),
+		Balance:   balance.As(hederasdk.HbarUnits.Hbar),
+		Previous:  previous.As(hederasdk.HbarUnits.Hbar),
+	})
+
+	// log event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf("The operator balance changed from %v to %v.", previous.String(), balance.String()))
+
+	return nil
+
+}
+
+// helper function to query the balance of the operator account from the Hedera network
+func _queryOperatorBalance() (hederasdk.Hbar, error) {
+
+	_, err := hedera.Manager.Operator.QueryBalance(&hedera.Manager)
+	if err != nil {
+		return hederasdk.Hbar{}, err
+	}
+
+	return hedera.Manager.Operator.GetBalance(), nil
+
+}
@@ -0,0 +1,102 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+	// standard
+	"testing"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	"renderhive/logger"
+)
+
+// helper function to run a balance check and wait for its background query
+func _testBalanceCheck(t *testing.T, nm *PackageManager) {
+	t.Helper()
+	nm.lastBalanceCheck = time.Time{}
+	if err := nm.CheckOperatorBalance(); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		nm.balanceMutex.Lock()
+		checking := nm.balanceChecking
+		nm.balanceMutex.Unlock()
+		if !checking {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the balance check did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCheckOperatorBalanceDeliversChanges(t *testing.T) {
+	logger.Manager.Init()
+	nm := &PackageManager{}
+	balance := hederasdk.NewHbar(10)
+	queries := 0
+	nm.queryOperatorBalance = func() (hederasdk.Hbar, error) {
+		queries++
+		return balance, nil
+	}
+
+	// the balance is not queried without subscribers
+	_testBalanceCheck(t, nm)
+	if queries != 0 {
+		t.Fatalf("the balance was queried %v times without subscribers", queries)
+	}
+	subscription, err := nm.Events.Subscribe([]string{EVENT_BALANCE_CHANGED})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nm.Events.Unsubscribe(subscription)
+
+	// the first query only initializes the balance
+	_testBalanceCheck(t, nm)
+	if queries != 1 || len(subscription.Events) != 0 {
+		t.Fatalf("got %v queries and %v events after the first check", queries, len(subscription.Events))
+	}
+
+	// an unchanged balance is not published
+	_testBalanceCheck(t, nm)
+	if len(subscription.Events) != 0 {
+		t.Fatalf("an unchanged balance was published")
+	}
+
+	// a changed balance is delivered to the subscriber
+	balance = hederasdk.NewHbar(7.5)
+	_testBalanceCheck(t, nm)
+	select {
+	case event := <-subscription.Events:
+		data, ok := event.Data.(BalanceEventData)
+		if event.Type != EVENT_BALANCE_CHANGED || !ok || data.Balance != 7.5 || data.Previous != 10 {
+			t.Errorf("got event %+v, want the change from 10 to 7.5 HBAR", event)
+		}
+	default:
+		t.Fatalf("the balance change was not delivered")
+	}
+}
@@ -0,0 +1,360 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the feasibility check of the render jobs this node could
+claim. Before a job is claimed, its resource needs are estimated from the
+render settings of the request and the benchmark results of this node:
+
+  - peak memory: the memory used by Blender itself (or the peak memory of the
+    benchmark, if larger) plus the scene data (a multiple of the Blender file
+    size) plus the render buffers (a fixed number of bytes per pixel)
+  - render time: the samples of all pixels of all frames divided by the
+    benchmark throughput of this node (samples per minute of a benchmark scene
+    at the reference resolution):
+
+        minutes = frames × samples × pixels / reference pixels / throughput
+
+    where 'pixels' is the resolution of the frame (reduced to the render
+    region, if any), 'reference pixels' is the resolution of the benchmark
+    scenes (1920 × 1080), and 'throughput' is the average samples per minute
+    of the benchmark scenes of the Blender version of the request
+
+If the resolution, the samples, or the benchmark results are not known, the
+render time falls back to the average render time per frame observed on this
+node. Jobs that exceed the memory or render time limit of this node, or that
+cannot be rendered before their deadline, are declined. The limits can be set
+in the optional 'limits.json' file of the configuration directory:
+
+    {"max_memory": 16384, "max_render_time": 720}
+
+The maximum memory is given in MB (default: a fraction of the system memory)
+and the maximum render time in minutes.
+
+*/
+
+import (
+
+	// standard
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	// internal
+	. "renderhive/globals"
+)
+
+// Limits of the render jobs claimed by this node
+type RenderLimits struct {
+	MaxMemory     float64 `json:"max_memory"`      // maximum peak memory in MB (0 = fraction of the system memory)
+	MaxRenderTime float64 `json:"max_render_time"` // maximum render time in minutes (0 = unlimited)
+}
+
+// Estimated resource needs of a render job on this node
+type RenderEstimate struct {
+	Frames     int           // number of frames of the job
+	Pixels     int           // number of pixels per frame (0, if not known)
+	Samples    int           // number of samples per pixel (0, if not known)
+	PeakMemory float64       // estimated peak memory in MB
+	Duration   time.Duration // estimated total render time
+	Benchmark  bool          // true, if the render time is based on the benchmark results
+}
+
+// RENDER JOB FEASIBILITY
+// #############################################################################
+// Get the default limits of the render jobs
+func DefaultRenderLimits() RenderLimits {
+	return RenderLimits{
+		MaxMemory:     0,
+		MaxRenderTime: RENDERHIVE_CONFIG_RENDER_LIMIT_DURATION.Minutes(),
+	}
+}
+
+// Read the limits of the render jobs from the configuration file
+func (limits *RenderLimits) Read() error {
+	var err error
+
+	// Open the configuration file
+	file, err := os.Open(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "limits.json"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Read the file content
+	fileData, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	// get the file content into a structure
+	err = json.Unmarshal(fileData, limits)
+	if err != nil {
+		return err
+	}
+
+	return limits.Validate()
+
+}
+
+// Check the limits of the render jobs
+func (limits *RenderLimits) Validate() error {
+
+	if limits.MaxMemory < 0 || limits.MaxRenderTime < 0 {
+		return newRenderError(ErrInvalidArgument, "The render job limits must not be negative.")
+	}
+
+	return nil
+
+}
+
+// Get the render job limits of this node (the configured or the default limits)
+func (nm *PackageManager) GetRenderLimits() RenderLimits {
+
+	// read the configured limits
+	limits := DefaultRenderLimits()
+	err := limits.Read()
+	if err != nil {
+		return DefaultRenderLimits()
+	}
+
+	return limits
+
+}
+
+// Get the maximum peak memory of a render job in MB
+// NOTE: Returns 0, if neither a maximum is configured nor the system memory is known.
+func (limits RenderLimits) Memory() float64 {
+
+	if limits.MaxMemory > 0 {
+		return limits.MaxMemory
+	}
+	if total, ok := _systemMemory(); ok {
+		return RENDERHIVE_CONFIG_RENDER_LIMIT_MEMORY_FRACTION * total
+	}
+
+	return 0
+
+}
+
+// Get the size of the Blender file in bytes
+// NOTE: Returns 0, if the size is not known.
+func (b *BlenderFileData) FileSize() int64 {
+
+	if b.Size > 0 {
+		return b.Size
+	}
+	if b.Path == "" {
+		return 0
+	}
+	info, err := os.Stat(b.Path)
+	if err != nil {
+		return 0
+	}
+
+	return info.Size()
+
+}
+
+// Estimate the resource needs of the render job on this node
+func (nm *PackageManager) EstimateRenderResources(job *RenderJob) RenderEstimate {
+
+	settings := job.FrameSettings()
+	estimate := RenderEstimate{
+		Frames:  job.Frames(),
+		Pixels:  _framePixels(settings),
+		Samples: settings.Samples,
+	}
+
+	// get the benchmark results of this node
+	throughput, peakMemory, ok := nm._benchmarkData(job.Offer, job.Request.Version)
+
+	// peak memory: Blender itself, the scene data, and the render buffers
+	base := RENDERHIVE_CONFIG_RENDER_ESTIMATE_BASE_MEMORY
+	if ok && peakMemory > base {
+		base = peakMemory
+	}
+	scene := RENDERHIVE_CONFIG_RENDER_ESTIMATE_SCENE_MEMORY_FACTOR * float64(job.Request.BlenderFile.FileSize()) / (1024 * 1024)
+	buffers := RENDERHIVE_CONFIG_RENDER_ESTIMATE_PIXEL_MEMORY * float64(estimate.Pixels) / (1024 * 1024)
+	estimate.PeakMemory = base + scene + buffers
+
+	// render time: all samples of all frames at the benchmark throughput
+	if minutes, known := _frameRenderTime(settings, throughput); ok && known {
+		estimate.Duration = time.Duration(float64(estimate.Frames) * minutes * float64(time.Minute))
+		estimate.Benchmark = true
+	} else {
+		frameDuration := nm.Renderer.FrameDuration
+		if frameDuration <= 0 {
+			frameDuration = RENDERHIVE_CONFIG_RENDER_JOB_FRAME_DURATION
+		}
+		estimate.Duration = time.Duration(estimate.Frames) * frameDuration
+	}
+
+	return estimate
+
+}
+
+// Check if this node can render the job within its limits and before the deadline
+// NOTE: The error explains why the job is declined.
+func (nm *PackageManager) CheckRenderFeasibility(job *RenderJob) (RenderEstimate, error) {
+
+	estimate := nm.EstimateRenderResources(job)
+	limits := nm.GetRenderLimits()
+
+	// check if this node trusts the python setup script
+	if job.Request.SetupScriptCID != "" {
+		settings := nm.GetTrustedScriptSettings()
+		if !settings.IsTrusted(job.Request.SetupScriptCID) {
+			return estimate, newRenderError(ErrJobInfeasible, "Setup script '%v' is not trusted by this node.", job.Request.SetupScriptCID)
+		}
+	}
+
+	// check the memory limit
+	if maxMemory := limits.Memory(); maxMemory > 0 && estimate.PeakMemory > maxMemory {
+		return estimate, newRenderError(ErrJobInfeasible, "Estimated peak memory of %.0f MB exceeds the limit of %.0f MB.", estimate.PeakMemory, maxMemory)
+	}
+
+	// check the render time limit
+	maxDuration := time.Duration(limits.MaxRenderTime * float64(time.Minute))
+	if maxDuration > 0 && estimate.Duration > maxDuration {
+		return estimate, newRenderError(ErrJobInfeasible, "Estimated render time of %v exceeds the limit of %v.", estimate.Duration.Round(time.Second), maxDuration)
+	}
+
+	// check the deadline
+	if !job.Request.Deadline.IsZero() {
+		finish := time.Now().Add(nm.QueueDuration() + nm.ReservedDuration(job, nm.GetOwnRequestSettings()) + estimate.Duration)
+		if finish.After(job.Request.Deadline) {
+			return estimate, newRenderError(ErrJobInfeasible, "Deadline %v cannot be met (estimated finish: %v).", job.Request.Deadline.Format(time.RFC3339), finish.Format(time.RFC3339))
+		}
+	}
+
+	return estimate, nil
+
+}
+
+// helper function to get the benchmark throughput (samples per minute of a
+// benchmark scene) and the benchmark peak memory (in MB) of this node
+// NOTE: If the Blender version is not known, the slowest version is used. If
+// the render offer is not known, all active offers are used.
+func (nm *PackageManager) _benchmarkData(offer *RenderOffer, version string) (float64, float64, bool) {
+
+	offers := nm.GetActiveRenderOffers()
+	if offer != nil {
+		offers = []*RenderOffer{offer}
+	}
+
+	throughput, peakMemory, found := 0.0, 0.0, false
+	for _, offer := range offers {
+		for v, blender := range offer.Blender {
+			if (version != "" && v != version) || blender.BenchmarkTool == nil {
+				continue
+			}
+			results := blender.BenchmarkTool.GetResult()
+			for _, result := range results {
+				if result.Stats.DevicePeakMemory > peakMemory {
+					peakMemory = result.Stats.DevicePeakMemory
+				}
+			}
+			average, ok := _benchmarkThroughput(results)
+			if ok && (!found || average < throughput) {
+				throughput, found = average, true
+			}
+		}
+	}
+
+	return throughput, peakMemory, found
+
+}
+
+// helper function to get the average samples per minute of the benchmark scenes
+func _benchmarkThroughput(results []BlenderBenchmarkResult) (float64, bool) {
+
+	if len(results) == 0 {
+		return 0, false
+	}
+
+	sum := 0.0
+	for _, result := range results {
+		sum += result.Stats.SamplesPerMinute
+	}
+	average := sum / float64(len(results))
+
+	return average, average > 0
+
+}
+
+// helper function to get the number of rendered pixels per frame (0, if not known)
+func _framePixels(settings RenderSettings) int {
+
+	pixels := settings.ResolutionX * settings.ResolutionY
+	if settings.Region != nil {
+		pixels = int(float64(pixels) * (settings.Region.MaxX - settings.Region.MinX) * (settings.Region.MaxY - settings.Region.MinY))
+	}
+
+	return pixels
+
+}
+
+// helper function to get the render time of a single frame in minutes at the
+// given benchmark throughput
+func _frameRenderTime(settings RenderSettings, throughput float64) (float64, bool) {
+
+	pixels := _framePixels(settings)
+	if throughput <= 0 || pixels <= 0 || settings.Samples <= 0 {
+		return 0, false
+	}
+
+	return float64(settings.Samples) * float64(pixels) / RENDERHIVE_CONFIG_RENDER_ESTIMATE_REFERENCE_PIXELS / throughput, true
+
+}
+
+// helper function to get the total system memory in MB
+// NOTE: The system memory is only known on Linux.
+func _systemMemory() (float64, bool) {
+
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var kilobytes float64
+		if strings.HasPrefix(scanner.Text(), "MemTotal:") {
+			_, err = fmt.Sscanf(scanner.Text(), "MemTotal: %f kB", &kilobytes)
+			if err != nil {
+				return 0, false
+			}
+			return kilobytes / 1024, true
+		}
+	}
+
+	return 0, false
+
+}
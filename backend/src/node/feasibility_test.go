@@ -0,0 +1,158 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	// internal
+	. "renderhive/globals"
+)
+
+// helper function to create render jobs with a benchmark of 1000 samples per minute
+func _testEstimateJobs(t *testing.T, rows int, columns int) (*PackageManager, []*RenderJob) {
+	t.Helper()
+
+	result := BlenderBenchmarkResult{}
+	result.Stats.SamplesPerMinute = 1000
+	offer := &RenderOffer{Blender: map[string]BlenderAppData{
+		"4.0.0": {BenchmarkTool: &BlenderBenchmarkTool{Result: []BlenderBenchmarkResult{result}}},
+	}}
+
+	nm := &PackageManager{}
+	jobs := nm.CreateRenderJobs(&SubmitRenderRequestArgs{
+		RenderRequestCID: testOfferCID,
+		BlenderVersion:   "4.0.0",
+		FrameStart:       1,
+		FrameEnd:         10,
+		FrameStep:        1,
+		RegionRows:       rows,
+		RegionColumns:    columns,
+		ResolutionX:      1920,
+		ResolutionY:      1080,
+		Samples:          100,
+	}, time.Now())
+	for _, job := range jobs {
+		job.Offer = offer
+	}
+
+	return nm, jobs
+}
+
+func TestEstimateRenderResourcesDuration(t *testing.T) {
+	nm, jobs := _testEstimateJobs(t, 0, 0)
+
+	// 10 frames × 100 samples at the reference resolution and 1000 samples per minute
+	estimate := nm.EstimateRenderResources(jobs[0])
+	if !estimate.Benchmark || estimate.Duration != time.Minute {
+		t.Errorf("got %v (benchmark: %v), want 1m0s from the benchmark", estimate.Duration, estimate.Benchmark)
+	}
+	if estimate.Frames != 10 || estimate.Pixels != 1920*1080 {
+		t.Errorf("got %v frames of %v pixels, want 10 frames of the full resolution", estimate.Frames, estimate.Pixels)
+	}
+}
+
+func TestEstimateRenderResourcesRegion(t *testing.T) {
+	nm, jobs := _testEstimateJobs(t, 2, 2)
+
+	// a region subtask renders a quarter of each frame
+	estimate := nm.EstimateRenderResources(jobs[0])
+	if estimate.Duration != 15*time.Second || estimate.Pixels != 1920*1080/4 {
+		t.Errorf("got %v for %v pixels, want 15s for a quarter of the frame", estimate.Duration, estimate.Pixels)
+	}
+}
+
+// helper function to write the render job limits of this node
+func _writeRenderLimits(t *testing.T, limits RenderLimits) {
+	t.Helper()
+
+	data, err := json.Marshal(limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(RENDERHIVE_APP_DIRECTORY_CONFIG, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "limits.json"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckRenderFeasibilityMemoryLimit(t *testing.T) {
+	_chdirTemp(t)
+	nm, jobs := _testEstimateJobs(t, 0, 0)
+	estimate := nm.EstimateRenderResources(jobs[0])
+
+	// a job just at the limit is accepted
+	_writeRenderLimits(t, RenderLimits{MaxMemory: estimate.PeakMemory})
+	if _, err := nm.CheckRenderFeasibility(jobs[0]); err != nil {
+		t.Errorf("expected a job at the memory limit to be feasible: %v", err)
+	}
+
+	// a job just over the limit is declined
+	_writeRenderLimits(t, RenderLimits{MaxMemory: estimate.PeakMemory - 1})
+	if _, err := nm.CheckRenderFeasibility(jobs[0]); !errors.Is(err, ErrJobInfeasible) {
+		t.Errorf("got %v, want a job over the memory limit to be infeasible", err)
+	}
+}
+
+func TestCheckRenderFeasibilityRenderTimeLimit(t *testing.T) {
+	_chdirTemp(t)
+	nm, jobs := _testEstimateJobs(t, 0, 0)
+
+	// the job is estimated to render for 1 minute
+	_writeRenderLimits(t, RenderLimits{MaxRenderTime: 1})
+	if _, err := nm.CheckRenderFeasibility(jobs[0]); err != nil {
+		t.Errorf("expected a job at the render time limit to be feasible: %v", err)
+	}
+	_writeRenderLimits(t, RenderLimits{MaxRenderTime: 0.99})
+	if _, err := nm.CheckRenderFeasibility(jobs[0]); !errors.Is(err, ErrJobInfeasible) {
+		t.Errorf("got %v, want a job over the render time limit to be infeasible", err)
+	}
+
+	// negative limits are invalid and the defaults are used instead
+	_writeRenderLimits(t, RenderLimits{MaxRenderTime: -1})
+	if limits := nm.GetRenderLimits(); limits != DefaultRenderLimits() {
+		t.Errorf("got limits %+v, want the default limits", limits)
+	}
+}
+
+func TestCheckRenderFeasibilityDeadline(t *testing.T) {
+	_chdirTemp(t)
+	nm, jobs := _testEstimateJobs(t, 0, 0)
+
+	// the job is estimated to render for 1 minute
+	jobs[0].Request.Deadline = time.Now().Add(2 * time.Minute)
+	if _, err := nm.CheckRenderFeasibility(jobs[0]); err != nil {
+		t.Errorf("expected a job before its deadline to be feasible: %v", err)
+	}
+	jobs[0].Request.Deadline = time.Now().Add(50 * time.Second)
+	if _, err := nm.CheckRenderFeasibility(jobs[0]); !errors.Is(err, ErrJobInfeasible) {
+		t.Errorf("got %v, want a job after its deadline to be infeasible", err)
+	}
+}
@@ -0,0 +1,145 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the funding of the render jobs requested by this node.
+
+A render job is funded once, when it is added to the Renderhive smart contract
+('addRenderJob'). If the render job needs more funding (e.g., because it takes
+longer than estimated), the requester can top up its funding with the payable
+'topUpRenderJob' function of the smart contract, which takes the CID of the
+render request document.
+
+Only the owner of a submitted render request, which was neither cancelled nor
+closed, can top up its funding. The transaction is returned unsigned for the
+wallet of the user and is recorded in the audit log of the node. It is also
+linked to the render request in the job accounting of the transaction history,
+so its fee counts towards the costs of the render request.
+
+*/
+
+import (
+
+	// standard
+	"context"
+	"fmt"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	"renderhive/hedera"
+	"renderhive/logger"
+)
+
+// RENDER JOB FUNDING
+// #############################################################################
+// Check if the funding of the render job can be topped up by the user of this node
+// NOTE: The caller must hold the lock of the render data.
+func (nm *PackageManager) CheckRenderJobTopUp(requestCID string, amount string) error {
+
+	// the amount must be positive
+	hbar, err := hederasdk.HbarFromString(amount)
+	if err != nil {
+		return newRenderError(ErrInvalidArgument, "Invalid top-up amount '%v': %w", amount, err)
+	}
+	if hbar.AsTinybar() <= 0 {
+		return newRenderError(ErrInvalidArgument, "The top-up amount must be positive.")
+	}
+
+	// the render request must exist and belong to the user
+	request, ok := nm.Renderer.Requests[requestCID]
+	if !ok {
+		return newRenderError(ErrRequestNotFound, "Render request '%v' does not exist on this node.", requestCID)
+	}
+	if request.Owner == nil || request.Owner.String() != nm.User.UserAccount.AccountID.String() {
+		return newRenderError(ErrInvalidArgument, "Render request '%v' does not belong to the account %v.", requestCID, nm.User.UserAccount.AccountID)
+	}
+
+	// the render job must be open
+	if request._isCancelled() {
+		return newRenderError(ErrInvalidArgument, "Render request '%v' was cancelled.", requestCID)
+	}
+	if !request.ClosedTimestamp.IsZero() {
+		return newRenderError(ErrInvalidArgument, "Render request '%v' was already closed.", requestCID)
+	}
+	if request.SubmittedTimestamp.IsZero() && !request._isSubmitted() {
+		return newRenderError(ErrInvalidArgument, "Render request '%v' was not submitted yet.", requestCID)
+	}
+
+	return nil
+
+}
+
+// Get the parameters of the 'topUpRenderJob' function of the smart contract
+func TopUpRenderJobParameters(requestCID string) *hederasdk.ContractFunctionParameters {
+	return hederasdk.NewContractFunctionParameters().AddString(requestCID)
+}
+
+// Top up the funding of a render job of this node in the smart contract
+// NOTE: Returns the transaction bytes for the signature of the user's wallet.
+// The render data is locked, until the transaction is prepared (or the
+// context is done).
+func (nm *PackageManager) TopUpRenderJob(ctx context.Context, contractID string, requestCID string, amount string, gas uint64) ([]byte, error) {
+	var err error
+
+	// lock the render data
+	err = nm.Renderer.Mutex.LockContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer nm.Renderer.Mutex.Unlock()
+
+	err = nm.CheckRenderJobTopUp(requestCID, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	// prepare the contract object
+	id, err := hederasdk.ContractIDFromString(contractID)
+	if err != nil {
+		return nil, newRenderError(ErrInvalidArgument, "Invalid contract ID '%v': %w", contractID, err)
+	}
+	contract := hedera.HederaSmartContract{ID: id}
+
+	// call the payable contract function
+	_, _, transactionBytes, err := contract.CallPayableFunction("topUpRenderJob", amount, TopUpRenderJobParameters(requestCID), gas, hedera.TransactionOptions.SetContext(ctx), hedera.TransactionOptions.SetExecute(false, nm.User.UserAccount.AccountID))
+	hedera.Manager.AuditJobFunding(hedera.AUDIT_OPERATION_JOB_TOP_UP, requestCID, amount, nm.User.UserAccount.AccountID.String(), transactionBytes, err)
+	if err != nil {
+		return nil, err
+	}
+
+	// count the fee of the top-up towards the costs of the render request
+	transactionID, err := hedera.TransactionIDFromBytes(transactionBytes)
+	if err != nil {
+		logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Could not link the top-up of render job '%v' to its accounting: %v", requestCID, err))
+	} else {
+		hedera.Manager.History.AddJobTransaction(requestCID, 0, transactionID)
+	}
+
+	// log event
+	logger.Manager.Package["node"].Info().Msg(fmt.Sprintf("Prepared the top-up of render job '%v' by %v.", requestCID, amount))
+
+	return transactionBytes, nil
+
+}
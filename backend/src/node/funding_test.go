@@ -0,0 +1,102 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+	// standard
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	// external
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+)
+
+func TestTopUpRenderJobParameters(t *testing.T) {
+	requestCID := "bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"
+
+	// the parameters are the ABI encoding of topUpRenderJob(string)
+	contractABI, err := abi.JSON(strings.NewReader(`[{"type":"function","name":"topUpRenderJob","stateMutability":"payable","inputs":[{"name":"jobCID","type":"string"}],"outputs":[]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := contractABI.Pack("topUpRenderJob", requestCID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := hederasdk.NewContractExecuteTransaction().SetFunction("topUpRenderJob", TopUpRenderJobParameters(requestCID)).GetFunctionParameters()
+	if !bytes.Equal(got, want) {
+		t.Errorf("got parameters %x, want %x", got, want)
+	}
+}
+
+func TestCheckRenderJobTopUp(t *testing.T) {
+	owner, _ := hederasdk.AccountIDFromString("0.0.1001")
+	other, _ := hederasdk.AccountIDFromString("0.0.1002")
+	nm := &PackageManager{}
+	nm.User.UserAccount.AccountID = owner
+	nm.Renderer.Requests = map[string]*RenderRequest{
+		"open":      {Owner: &owner, SubmittedTimestamp: time.Now()},
+		"foreign":   {Owner: &other, SubmittedTimestamp: time.Now()},
+		"cancelled": {Owner: &owner, SubmittedTimestamp: time.Now(), Cancelled: true},
+		"closed":    {Owner: &owner, SubmittedTimestamp: time.Now(), ClosedTimestamp: time.Now()},
+		"created":   {Owner: &owner},
+	}
+
+	tests := []struct {
+		request string
+		amount  string
+		want    error
+	}{
+		{"open", "10", nil},
+		{"open", "0", ErrInvalidArgument},
+		{"open", "ten", ErrInvalidArgument},
+		{"missing", "10", ErrRequestNotFound},
+		{"foreign", "10", ErrInvalidArgument},
+		{"cancelled", "10", ErrInvalidArgument},
+		{"closed", "10", ErrInvalidArgument},
+		{"created", "10", ErrInvalidArgument},
+	}
+	for _, test := range tests {
+		err := nm.CheckRenderJobTopUp(test.request, test.amount)
+		if (test.want == nil && err != nil) || (test.want != nil && !errors.Is(err, test.want)) {
+			t.Errorf("CheckRenderJobTopUp(%v, %v) = %v, want %v", test.request, test.amount, err, test.want)
+		}
+	}
+}
+
+func TestTopUpRenderJobWaitsForTheLock(t *testing.T) {
+	nm := &PackageManager{}
+	nm.Renderer.Mutex.Lock()
+	defer nm.Renderer.Mutex.Unlock()
+
+	// the render data is locked by another request
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := nm.TopUpRenderJob(ctx, "0.0.1", "request", "10", 100000)
+	if err == nil {
+		t.Error("the top-up must not read the render requests without the lock")
+	}
+}
@@ -46,6 +46,7 @@ import (
 	. "renderhive/globals"
 	"renderhive/hedera"
 	"renderhive/logger"
+	"renderhive/metrics"
 )
 
 // structure for the time synchronization
@@ -85,6 +86,9 @@ func (hc *HiveCycle) MessageCallback() func(message hederasdk.TopicMessage) {
 	return func(message hederasdk.TopicMessage) {
 		var err error
 
+		// record the received message
+		metrics.Manager.ObserveTopicMessage("hive_cycle_synchronization")
+
 		//
 		// Import and parse the compiled contract from the contract file
 		jsonData := message.Contents
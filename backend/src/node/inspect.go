@@ -0,0 +1,635 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the functions to inspect a Blender file before a render
+request is created. A headless Blender instance loads the file and executes a
+vetted internal python script, which dumps the render settings of all scenes
+or the external file dependencies as JSON, or packs the external data into a
+copy of the file. The scripts are embedded into the
+service app and pinned by their CID, i.e. a script is only executed if its CID
+matches the CID known to the app.
+
+*/
+
+import (
+
+	// standard
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	// external
+	"github.com/ipfs/boxo/files"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/ipfs"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// python script to dump the render settings of a Blender file
+//
+//go:embed scripts/inspect_render_settings.py
+var inspectRenderSettingsScript []byte
+
+// prefix of the output line, which contains the render settings
+const inspectRenderSettingsPrefix = "RENDERHIVE_RENDER_SETTINGS:"
+
+// python script to pack the external data into a copy of a Blender file
+//
+//go:embed scripts/pack_external_data.py
+var packExternalDataScript []byte
+
+// prefix of the output line, which confirms the packing
+const packExternalDataPrefix = "RENDERHIVE_PACKED:"
+
+// python script to list the external file dependencies of a Blender file
+//
+//go:embed scripts/scan_dependencies.py
+var scanDependenciesScript []byte
+
+// prefix of the output line, which contains the file dependencies
+const scanDependenciesPrefix = "RENDERHIVE_DEPENDENCIES:"
+
+// Render settings of a single scene of a Blender file
+type BlenderSceneSettings struct {
+	Name     string         // Name of the scene
+	Settings RenderSettings // Render settings of the scene
+}
+
+// External file dependency of a Blender file (texture, linked library, cache, etc.)
+type BlenderFileDependency struct {
+	Path         string `json:"path"`          // Path as stored in the Blender file ('//' marks paths relative to the file)
+	AbsolutePath string `json:"absolute_path"` // Absolute path on the node that scanned the file
+	Relative     bool   `json:"relative"`      // True, if the path is relative to the Blender file
+	Exists       bool   `json:"exists"`        // True, if the file exists on the node that scanned the file
+	Portable     bool   `json:"-"`             // True, if the path can be resolved on other nodes
+	Included     bool   `json:"-"`             // True, if the file is included in the render request
+}
+
+// JSON output of the inspection script
+type blenderInspectionJSON struct {
+	ActiveScene string `json:"active_scene"`
+	Scenes      []struct {
+		Name        string `json:"name"`
+		Engine      string `json:"engine"`
+		FeatureSet  string `json:"feature_set"`
+		Device      string `json:"device"`
+		ResolutionX int    `json:"resolution_x"`
+		ResolutionY int    `json:"resolution_y"`
+		TileX       int    `json:"tile_x"`
+		TileY       int    `json:"tile_y"`
+		Samples     int    `json:"samples"`
+		FrameStart  int    `json:"frame_start"`
+		FrameEnd    int    `json:"frame_end"`
+		FrameStep   int    `json:"frame_step"`
+		OutputPath  string `json:"output_path"`
+		FileFormat  string `json:"file_format"`
+	} `json:"scenes"`
+}
+
+// BLENDER FILE INSPECTION
+// #############################################################################
+// Inspect the render settings of all scenes of a Blender file
+func (b *BlenderAppData) InspectFile(blend_file string) (string, []BlenderSceneSettings, error) {
+	var err error
+	var inspection blenderInspectionJSON
+
+	// log event
+	logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf("Inspecting Blender file '%v' ...", blend_file))
+
+	// execute the inspection script
+	output, err := b._executeScript(blend_file, inspectRenderSettingsScript, BLENDER_SCRIPT_INSPECT_RENDER_SETTINGS_CID, inspectRenderSettingsPrefix)
+	if err != nil {
+		return "", nil, err
+	}
+	err = json.Unmarshal(output, &inspection)
+	if err != nil {
+		return "", nil, newRenderError(ErrInvalidArgument, "Could not parse the render settings: %w", err)
+	}
+
+	// convert the scene settings
+	scenes := []BlenderSceneSettings{}
+	for _, scene := range inspection.Scenes {
+		scenes = append(scenes, BlenderSceneSettings{
+			Name: scene.Name,
+			Settings: RenderSettings{
+				Engine:      _getEngineName(scene.Engine),
+				FeatureSet:  scene.FeatureSet,
+				Device:      scene.Device,
+				ResolutionX: scene.ResolutionX,
+				ResolutionY: scene.ResolutionY,
+				TileX:       scene.TileX,
+				TileY:       scene.TileY,
+				Samples:     scene.Samples,
+				FrameStart:  scene.FrameStart,
+				FrameEnd:    scene.FrameEnd,
+				FrameStep:   scene.FrameStep,
+				OutputPath:  scene.OutputPath,
+				FileFormat:  scene.FileFormat,
+			},
+		})
+	}
+
+	return inspection.ActiveScene, scenes, err
+
+}
+
+// Inspect the Blender file of a render request and populate its render settings
+// NOTE: The declared settings may be nil. Otherwise, conflicts with the settings
+// of the file are returned as warnings.
+func (nm *PackageManager) InspectRenderRequest(request *RenderRequest, declared *RenderSettings) ([]string, error) {
+	var err error
+	var warnings []string
+
+	// get the requested Blender version of this node
+	if len(nm.GetActiveRenderOffers()) == 0 {
+		return nil, newRenderError(ErrUnsupportedVersion, "No render offer available for inspecting the Blender file.")
+	}
+	blender, ok := nm._offeredBlender(request.Version)
+	if !ok {
+		return nil, newRenderError(ErrUnsupportedVersion, "Blender v'%v' is not available on this node for inspecting the Blender file.", request.Version)
+	}
+
+	// inspect the file
+	activeScene, scenes, err := blender.InspectFile(request.BlenderFile.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// populate the render settings of the Blender file
+	request.BlenderFile.Scene = activeScene
+	request.BlenderFile.Scenes = scenes
+	for _, scene := range scenes {
+		if scene.Name == activeScene {
+			request.BlenderFile.Settings = scene.Settings
+		}
+	}
+
+	// log event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Blender file has %v scene(s) (active scene: %v)", len(scenes), activeScene))
+	if len(scenes) > 1 {
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Only the active scene '%v' will be rendered.", activeScene))
+	}
+
+	// compare the declared settings with the settings of the file
+	if declared != nil {
+		settings := request.BlenderFile.Settings
+		if declared.Engine != "" && !strings.EqualFold(declared.Engine, settings.Engine) {
+			warnings = append(warnings, fmt.Sprintf("Declared engine '%v' differs from the engine '%v' of the file.", declared.Engine, settings.Engine))
+		}
+		// NOTE: The file only distinguishes between CPU and GPU rendering
+		if declared.Device != "" && settings.Device != "" && strings.EqualFold(declared.Device, "CPU") != strings.EqualFold(settings.Device, "CPU") {
+			warnings = append(warnings, fmt.Sprintf("Declared device '%v' differs from the device '%v' of the file.", declared.Device, settings.Device))
+		}
+		if (declared.ResolutionX != 0 && declared.ResolutionX != settings.ResolutionX) || (declared.ResolutionY != 0 && declared.ResolutionY != settings.ResolutionY) {
+			warnings = append(warnings, fmt.Sprintf("Declared resolution %vx%v differs from the resolution %vx%v of the file.", declared.ResolutionX, declared.ResolutionY, settings.ResolutionX, settings.ResolutionY))
+		}
+		if (declared.FrameStart != 0 && declared.FrameStart != settings.FrameStart) || (declared.FrameEnd != 0 && declared.FrameEnd != settings.FrameEnd) {
+			warnings = append(warnings, fmt.Sprintf("Declared frame range %v-%v differs from the frame range %v-%v of the file.", declared.FrameStart, declared.FrameEnd, settings.FrameStart, settings.FrameEnd))
+		}
+	}
+
+	// log the warnings
+	for _, warning := range warnings {
+		logger.Manager.Package["node"].Warn().Msg(warning)
+	}
+
+	return warnings, err
+
+}
+
+// List the external file dependencies of a Blender file
+func (b *BlenderAppData) ScanDependencies(blend_file string) ([]BlenderFileDependency, error) {
+	var err error
+	var dependencies []BlenderFileDependency
+
+	// log event
+	logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf("Scanning dependencies of Blender file '%v' ...", blend_file))
+
+	// execute the dependency scan script
+	output, err := b._executeScript(blend_file, scanDependenciesScript, BLENDER_SCRIPT_SCAN_DEPENDENCIES_CID, scanDependenciesPrefix)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(output, &dependencies)
+	if err != nil {
+		return nil, newRenderError(ErrInvalidArgument, "Could not parse the dependencies: %w", err)
+	}
+
+	return dependencies, err
+
+}
+
+// Check the external file dependencies of the Blender file of a render request
+// NOTE: Dependencies found next to the Blender file are added to the request.
+// Missing and unportable (absolute or outside of the project directory)
+// dependencies are returned as an error.
+func (nm *PackageManager) CheckRenderRequestDependencies(request *RenderRequest) error {
+	var err error
+	var missing []string
+
+	// get the requested Blender version of this node
+	// NOTE: Nodes without the Blender version cannot scan the file
+	if len(nm.GetActiveRenderOffers()) == 0 {
+		logger.Manager.Package["node"].Warn().Msg("Skipped dependency scan: No render offer available.")
+		return nil
+	}
+	blender, ok := nm._offeredBlender(request.Version)
+	if !ok {
+		logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Skipped dependency scan: Blender v'%v' is not available on this node.", request.Version))
+		return nil
+	}
+
+	// get the Blender file on the local file system
+	// NOTE: Relative dependencies can only be found at the original location
+	blend_file := request.BlenderFile.Path
+	original := true
+	if _, err = os.Stat(blend_file); blend_file == "" || err != nil {
+		blend_file, err = request._writeBlenderFile()
+		if err != nil {
+			return err
+		}
+		defer os.Remove(blend_file)
+		original = false
+	}
+
+	// scan the dependencies
+	dependencies, err := blender.ScanDependencies(blend_file)
+	if err != nil {
+		return err
+	}
+
+	// check each dependency
+	for i, dependency := range dependencies {
+
+		// absolute paths cannot be resolved on other nodes
+		if !dependency.Relative {
+			missing = append(missing, fmt.Sprintf("%v (absolute path)", dependency.Path))
+			continue
+		}
+
+		// relative paths must not leave the project directory
+		name := filepath.ToSlash(filepath.Clean(strings.TrimPrefix(dependency.Path, "//")))
+		if name == ".." || strings.HasPrefix(name, "../") {
+			missing = append(missing, fmt.Sprintf("%v (outside of the project directory)", dependency.Path))
+			continue
+		}
+		if err := ipfs.ValidateRelativePath(name); err != nil {
+			missing = append(missing, fmt.Sprintf("%v (invalid file name)", dependency.Path))
+			continue
+		}
+		dependencies[i].Portable = true
+
+		// the dependency is already part of the request
+		if _, ok := request.Files[name]; ok {
+			dependencies[i].Included = true
+			continue
+		}
+
+		// include the dependency found next to the Blender file
+		if original && dependency.Exists {
+			err = request.AddFile(dependency.AbsolutePath, name)
+			if err != nil {
+				return err
+			}
+			dependencies[i].Included = true
+			logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Included dependency: %v", name))
+			continue
+		}
+
+		missing = append(missing, fmt.Sprintf("%v (missing)", dependency.Path))
+
+	}
+
+	// store the dependencies in the render request
+	request.BlenderFile.Dependencies = dependencies
+
+	// if any dependency is missing
+	if len(missing) > 0 {
+		return newRenderError(ErrInvalidArgument, "Blender file has missing or unportable dependencies:\n - %v", strings.Join(missing, "\n - "))
+	}
+
+	return err
+
+}
+
+// Pack all external data into a copy of a Blender file
+func (b *BlenderAppData) PackFile(blend_file string, output_file string) error {
+	var err error
+
+	// log event
+	logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf("Packing external data of Blender file '%v' ...", blend_file))
+
+	// execute the packing script
+	_, err = b._executeScript(blend_file, packExternalDataScript, BLENDER_SCRIPT_PACK_EXTERNAL_DATA_CID, packExternalDataPrefix, output_file)
+	if err != nil {
+		return err
+	}
+
+	// check if the packed file was written
+	if _, err = os.Stat(output_file); err != nil {
+		return newRenderError(ErrBlenderCrashed, "Packed Blender file was not written: %w", err)
+	}
+
+	return err
+
+}
+
+// Pack the external data of the Blender file of a render request
+// NOTE: The original Blender file remains untouched. The render request uses
+// a packed copy of the file afterwards.
+func (nm *PackageManager) PackRenderRequest(request *RenderRequest) error {
+	var err error
+
+	// get the requested Blender version of this node
+	if len(nm.GetActiveRenderOffers()) == 0 {
+		return newRenderError(ErrUnsupportedVersion, "No render offer available for packing the Blender file.")
+	}
+	blender, ok := nm._offeredBlender(request.Version)
+	if !ok {
+		return newRenderError(ErrUnsupportedVersion, "Blender v'%v' is not available on this node for packing the Blender file.", request.Version)
+	}
+
+	// get the Blender file on the local file system and its name in the request
+	// NOTE: Without the original file, the Blender file is packed in a copy of
+	//       the request directory, so that the external data is found.
+	// NOTE: The Blender file may be in a subdirectory of the request files.
+	blend_file := request.BlenderFile.Path
+	name := ""
+	if _, err = os.Stat(blend_file); blend_file == "" || err != nil {
+		var directory string
+		directory, name, err = request._writeRequestDirectory()
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(directory)
+		blend_file = filepath.Join(directory, filepath.FromSlash(name))
+	} else {
+		for key, node := range request.Files {
+			if _, ok := node.(files.File); !ok || filepath.Base(key) != filepath.Base(blend_file) {
+				continue
+			}
+			if name == "" || request.filePaths[key] == blend_file {
+				name = key
+			}
+		}
+	}
+
+	// prepare the path of the packed copy
+	packed_directory := filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_PACKED_REQUESTS)
+	err = os.MkdirAll(packed_directory, 0700)
+	if err != nil && !os.IsExist(err) {
+		return err
+	}
+	base := filepath.Base(blend_file)
+	packed_file := filepath.Join(packed_directory, fmt.Sprintf("%v-%v-packed.blend", strings.TrimSuffix(base, filepath.Ext(base)), time.Now().Unix()))
+
+	// pack the external data
+	err = blender.PackFile(blend_file, packed_file)
+	if err != nil {
+		return err
+	}
+
+	// verify that no external dependencies remain
+	dependencies, err := blender.ScanDependencies(packed_file)
+	if err != nil {
+		return err
+	}
+	if len(dependencies) > 0 {
+		remaining := []string{}
+		for _, dependency := range dependencies {
+			remaining = append(remaining, dependency.Path)
+		}
+		return newRenderError(ErrInvalidArgument, "Blender file could not be packed completely. Remaining dependencies:\n - %v", strings.Join(remaining, "\n - "))
+	}
+
+	// use the packed file for the render request
+	request.BlenderFile.Path = packed_file
+	request.BlenderFile.Dependencies = []BlenderFileDependency{}
+	if name != "" {
+		err = request.AddFile(packed_file, name)
+		if err != nil {
+			return err
+		}
+	}
+	request.BlenderFile.CID, err = ipfs.Manager.GetHashFromPath(packed_file)
+	if err != nil {
+		return err
+	}
+
+	// log event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Packed Blender file: %v", packed_file))
+
+	return err
+
+}
+
+// helper function to write the files of a render request into a temporary directory
+// and get the name of the Blender file in the directory
+// NOTE: The files keep their names in the render request, so that Blender finds
+// the external data at its relative path. The caller must remove the directory.
+func (request *RenderRequest) _writeRequestDirectory() (string, string, error) {
+
+	// find the Blender file in the request files (preferably the file of the request)
+	blend_name := ""
+	for name, node := range request.Files {
+		if _, ok := node.(files.File); !ok || strings.ToLower(filepath.Ext(name)) != ".blend" {
+			continue
+		}
+		if blend_name == "" || filepath.Base(name) == filepath.Base(request.BlenderFile.Path) {
+			blend_name = name
+		}
+	}
+	if blend_name == "" {
+		return "", "", newRenderError(ErrDocumentNotFound, "No .blend file was added to the render request.")
+	}
+
+	// write the files into a temporary directory
+	directory, err := os.MkdirTemp(RENDERHIVE_APP_DIRECTORY_TEMP, "renderhive_request_*")
+	if err != nil {
+		return "", "", err
+	}
+	for name, node := range request.Files {
+		err = ipfs.ValidateRelativePath(name)
+		if err == nil {
+			path := filepath.Join(directory, filepath.FromSlash(name))
+			err = os.MkdirAll(filepath.Dir(path), 0700)
+			if err == nil {
+				err = files.WriteTo(node, path)
+			}
+		}
+
+		// seek back to the start of the file object
+		if seeker, ok := node.(io.Seeker); ok && err == nil {
+			_, err = seeker.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			os.RemoveAll(directory)
+			return "", "", fmt.Errorf("Could not write file '%v' of the render request: %v", name, err)
+		}
+	}
+
+	return directory, blend_name, nil
+
+}
+
+// helper function to write the Blender file of a render request into a temporary file
+func (request *RenderRequest) _writeBlenderFile() (string, error) {
+
+	// find the Blender file in the request files
+	for name, node := range request.Files {
+		if strings.ToLower(filepath.Ext(name)) != ".blend" {
+			continue
+		}
+		file, ok := node.(files.File)
+		if !ok {
+			continue
+		}
+
+		// write the file data into a temporary file
+		blend_file, err := os.CreateTemp(RENDERHIVE_APP_DIRECTORY_TEMP, "renderhive_request_*.blend")
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(blend_file, file)
+		blend_file.Close()
+		if err != nil {
+			os.Remove(blend_file.Name())
+			return "", err
+		}
+
+		// seek back to the start of the file object
+		if seeker, ok := node.(io.Seeker); ok {
+			_, err = seeker.Seek(0, io.SeekStart)
+			if err != nil {
+				os.Remove(blend_file.Name())
+				return "", err
+			}
+		}
+
+		return blend_file.Name(), nil
+
+	}
+
+	return "", newRenderError(ErrDocumentNotFound, "No .blend file was added to the render request.")
+
+}
+
+// helper function to convert Blender's engine identifiers to the engine names of the service app
+func _getEngineName(engine string) string {
+
+	switch engine {
+	case "CYCLES":
+		return "CYCLES"
+	case "BLENDER_EEVEE":
+		return "EEVEE"
+	case "BLENDER_EEVEE_NEXT":
+		return "EEVEE_NEXT"
+	}
+
+	return engine
+
+}
+
+// helper function to execute a vetted internal python script on a Blender file
+// NOTE: Returns the JSON data of the output line starting with the prefix. An
+// empty path executes the script on the factory startup file.
+func (b *BlenderAppData) _executeScript(blend_file string, script []byte, scriptCID string, prefix string, args ...string) ([]byte, error) {
+	var err error
+
+	// Check if the paths are pointing to existing files
+	if _, err = os.Stat(b.Path); os.IsNotExist(err) {
+		return nil, err
+	}
+	if blend_file != "" {
+		if _, err = os.Stat(blend_file); os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	// write the script into a temporary file
+	scriptFile, err := os.CreateTemp(RENDERHIVE_APP_DIRECTORY_TEMP, "renderhive_script_*.py")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(scriptFile.Name())
+	_, err = scriptFile.Write(script)
+	if err != nil {
+		scriptFile.Close()
+		return nil, err
+	}
+	err = scriptFile.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// only execute the script, if it is the vetted one
+	cid, err := ipfs.Manager.GetHashFromPath(scriptFile.Name())
+	if err != nil {
+		return nil, err
+	}
+	if cid != scriptCID {
+		return nil, newRenderError(ErrUntrustedScript, "Script has an unexpected CID '%v' (expected: %v).", cid, scriptCID)
+	}
+
+	// Execute Blender in background mode without executing scripts of the file
+	ctx, cancel := context.WithTimeout(context.Background(), RENDERHIVE_CONFIG_BLENDER_INSPECTION_TIMEOUT)
+	defer cancel()
+	parameters := []string{"-b", "--factory-startup", "--disable-autoexec"}
+	if blend_file != "" {
+		parameters = append(parameters, blend_file)
+	}
+	parameters = append(parameters, "--python", scriptFile.Name(), "--python-exit-code", "1")
+	if len(args) > 0 {
+		parameters = append(append(parameters, "--"), args...)
+	}
+	output, err := exec.CommandContext(ctx, b.Path, parameters...).Output()
+	if err != nil {
+		return nil, newRenderError(ErrBlenderCrashed, "Blender could not execute the script: %w", err)
+	}
+
+	// find the output line of the script
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, prefix) {
+			return []byte(strings.TrimPrefix(line, prefix)), nil
+		}
+	}
+
+	return nil, newRenderError(ErrBlenderCrashed, "Blender returned no script output.")
+
+}
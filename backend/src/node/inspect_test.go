@@ -0,0 +1,136 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	// external
+	"github.com/ipfs/boxo/files"
+
+	// internal
+	. "renderhive/globals"
+	. "renderhive/utility"
+)
+
+// fake Blender binary, which copies the Blender file for the packing script
+// and reports no remaining dependencies for the dependency scan
+const testPackBlender = `#!/bin/sh
+case "$*" in
+	*" -- "*)
+		for last; do :; done
+		cp "$4" "$last"
+		echo 'RENDERHIVE_PACKED:{}'
+		;;
+	*--python*)
+		echo 'RENDERHIVE_DEPENDENCIES:[]'
+		;;
+esac
+`
+
+func TestWriteRequestDirectoryKeepsTheExternalData(t *testing.T) {
+	request := &RenderRequest{Files: map[string]files.Node{
+		"scene.blend":       files.NewBytesFile([]byte("blend")),
+		"textures/wood.png": files.NewBytesFile([]byte("wood")),
+	}}
+
+	directory, name, err := request._writeRequestDirectory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	// the Blender file and its external data are written at their relative paths
+	if name != "scene.blend" {
+		t.Fatalf("got Blender file %q, want scene.blend", name)
+	}
+	for path, want := range map[string]string{"scene.blend": "blend", "textures/wood.png": "wood"} {
+		data, err := os.ReadFile(filepath.Join(directory, filepath.FromSlash(path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Fatalf("%v contains %q, want %q", path, data, want)
+		}
+	}
+
+	// the files can be read again for the upload
+	data, err := io.ReadAll(request.Files["textures/wood.png"].(files.File))
+	if err != nil || string(data) != "wood" {
+		t.Fatalf("got %q (%v), want the file data again", data, err)
+	}
+}
+
+func TestWriteRequestDirectoryWithoutBlenderFile(t *testing.T) {
+	request := &RenderRequest{Files: map[string]files.Node{
+		"textures/wood.png": files.NewBytesFile([]byte("wood")),
+	}}
+
+	directory, _, err := request._writeRequestDirectory()
+	if err == nil {
+		os.RemoveAll(directory)
+		t.Fatal("got no error for a render request without a Blender file")
+	}
+}
+
+func TestPackRenderRequestKeepsTheNestedBlenderFile(t *testing.T) {
+	nm, directory := _testRenderCIDManager(t)
+	if err := os.WriteFile(filepath.Join(directory, "blender"), []byte(testPackBlender), 0755); err != nil {
+		t.Fatal(err)
+	}
+	_testMockIPFS(t, []byte("BLENDER-v401 fixture"))
+
+	// the Blender file was added in a subdirectory of the render request
+	blend_file := filepath.Join(t.TempDir(), "scenes", "shot.blend")
+	if err := os.MkdirAll(filepath.Dir(blend_file), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(blend_file, []byte("BLENDER-v401 shot"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	request := &RenderRequest{Version: "4.1.0", Files: map[string]files.Node{}}
+	request.BlenderFile.Path = blend_file
+	if err := request.AddFile(blend_file, "scenes/shot.blend"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := nm.PackRenderRequest(request); err != nil {
+		t.Fatal(err)
+	}
+
+	// the packed copy replaces the file under its nested name
+	if len(request.Files) != 1 || request.filePaths["scenes/shot.blend"] != request.BlenderFile.Path {
+		t.Fatalf("got the files %v (%v), want the packed file as scenes/shot.blend", request.filePaths, len(request.Files))
+	}
+	packed_directory := filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_PACKED_REQUESTS)
+	if filepath.Dir(request.BlenderFile.Path) != packed_directory {
+		t.Errorf("got the packed file %v, want it in %v", request.BlenderFile.Path, packed_directory)
+	}
+	data, err := os.ReadFile(request.BlenderFile.Path)
+	if err != nil || string(data) != "BLENDER-v401 shot" {
+		t.Errorf("got the packed data %q (%v)", data, err)
+	}
+}
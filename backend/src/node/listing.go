@@ -0,0 +1,265 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the functions to list the render offers and render requests
+of this node page by page. The listings are sorted by their creation time with
+the newest documents first and can be filtered by state, Blender version,
+owner, and creation date. With the SQLite repository, the stored documents
+matching the filter are found with the indexes of the database.
+
+*/
+
+import (
+
+	// standard
+	"sort"
+	"strings"
+	"time"
+)
+
+// Filter and page of a render offer or render request listing
+type RenderListFilter struct {
+	State   string    // only documents in this repository state (REPOSITORY_STATE_*)
+	Version string    // only documents supporting / requesting this Blender version
+	Owner   string    // only documents of this owner account ID
+	From    time.Time // only documents created at or after this time
+	To      time.Time // only documents created before this time
+	Offset  int       // number of matching documents to skip
+	Limit   int       // maximum number of documents in the page (0 = no limit)
+}
+
+// RENDER LISTINGS
+// #############################################################################
+// Check the filter for invalid values
+func (filter *RenderListFilter) Validate() error {
+
+	if filter.Offset < 0 {
+		return newRenderError(ErrInvalidArgument, "Invalid offset '%v'.", filter.Offset)
+	}
+	if filter.Limit < 0 {
+		return newRenderError(ErrInvalidArgument, "Invalid limit '%v'.", filter.Limit)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return newRenderError(ErrInvalidArgument, "The end of the date range must not be before its start.")
+	}
+	switch strings.ToLower(filter.State) {
+	case "", REPOSITORY_STATE_CREATED, REPOSITORY_STATE_SUBMITTED, REPOSITORY_STATE_PAUSED, REPOSITORY_STATE_INACTIVE, REPOSITORY_STATE_CANCELLED:
+	default:
+		return newRenderError(ErrInvalidArgument, "Unknown state '%v'.", filter.State)
+	}
+
+	return nil
+
+}
+
+// List the render offers of this node matching the filter
+// NOTE: Returns the requested page and the total number of matching offers.
+func (nm *PackageManager) ListRenderOffers(filter RenderListFilter) ([]*RenderOffer, int, error) {
+	var offers []*RenderOffer
+
+	// check the filter
+	err := filter.Validate()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// collect the matching offers
+	candidates := _uniqueOffers(nm.Renderer.Offers)
+	if repository, ok := nm.Repository.(RenderListingRepository); ok {
+		cids, err := repository.FindOffers(filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		candidates = _storedCandidates(candidates, cids, func(offer *RenderOffer) string { return offer.DocumentCID })
+	}
+	for _, offer := range candidates {
+
+		if !filter._matches(offer.RepositoryState(), _ownerString(offer.Owner), offer.CreatedTimestamp) {
+			continue
+		}
+		if filter.Version != "" {
+			supported := false
+			for _, blender := range offer.BlenderVersions {
+				supported = supported || blender.Version == filter.Version
+			}
+			if !supported {
+				continue
+			}
+		}
+
+		offers = append(offers, offer)
+	}
+
+	// sort the offers (newest first)
+	sort.Slice(offers, func(i, j int) bool {
+		if offers[i].CreatedTimestamp.Equal(offers[j].CreatedTimestamp) {
+			return offers[i].DocumentCID < offers[j].DocumentCID
+		}
+		return offers[i].CreatedTimestamp.After(offers[j].CreatedTimestamp)
+	})
+
+	// get the page
+	start, end := filter._page(len(offers))
+
+	return offers[start:end], len(offers), nil
+
+}
+
+// List the render requests of this node matching the filter
+// NOTE: Returns the requested page and the total number of matching requests.
+func (nm *PackageManager) ListRenderRequests(filter RenderListFilter) ([]*RenderRequest, int, error) {
+	var requests []*RenderRequest
+
+	// check the filter
+	err := filter.Validate()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// collect the matching requests
+	candidates := _uniqueRequests(nm.Renderer.Requests)
+	if repository, ok := nm.Repository.(RenderListingRepository); ok {
+		cids, err := repository.FindRequests(filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		candidates = _storedCandidates(candidates, cids, func(request *RenderRequest) string { return request.DocumentCID })
+	}
+	for _, request := range candidates {
+
+		if !filter._matches(request.RepositoryState(), _ownerString(request.Owner), request.CreatedTimestamp) {
+			continue
+		}
+		if filter.Version != "" && request.Version != filter.Version {
+			continue
+		}
+
+		requests = append(requests, request)
+	}
+
+	// sort the requests (newest first)
+	sort.Slice(requests, func(i, j int) bool {
+		if requests[i].CreatedTimestamp.Equal(requests[j].CreatedTimestamp) {
+			return requests[i].DocumentCID < requests[j].DocumentCID
+		}
+		return requests[i].CreatedTimestamp.After(requests[j].CreatedTimestamp)
+	})
+
+	// get the page
+	start, end := filter._page(len(requests))
+
+	return requests[start:end], len(requests), nil
+
+}
+
+// Check the common filter criteria
+func (filter *RenderListFilter) _matches(state string, owner string, created time.Time) bool {
+
+	if filter.State != "" && !strings.EqualFold(filter.State, state) {
+		return false
+	}
+	if filter.Owner != "" && filter.Owner != owner {
+		return false
+	}
+	if !filter.From.IsZero() && created.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && !created.Before(filter.To) {
+		return false
+	}
+
+	return true
+
+}
+
+// Get the bounds of the page for the given number of documents
+func (filter *RenderListFilter) _page(total int) (int, int) {
+
+	start := filter.Offset
+	if start > total {
+		start = total
+	}
+	end := total
+	if filter.Limit > 0 && start+filter.Limit < total {
+		end = start + filter.Limit
+	}
+
+	return start, end
+
+}
+
+// Get the documents found by the repository and the documents, which were not
+// stored yet (i.e., documents without a CID)
+// NOTE: The found documents are still checked against the filter, since the
+// repository only narrows the candidates down with its indexes.
+func _storedCandidates[T any](documents []T, cids []string, cid func(T) string) []T {
+	var candidates []T
+
+	found := make(map[string]bool, len(cids))
+	for _, c := range cids {
+		found[c] = true
+	}
+	for _, document := range documents {
+		if cid(document) == "" || found[cid(document)] {
+			candidates = append(candidates, document)
+		}
+	}
+
+	return candidates
+
+}
+
+// Get each render offer only once
+// NOTE: An offer may be referenced by more than one key of the map.
+func _uniqueOffers(offers map[string]*RenderOffer) []*RenderOffer {
+	var unique []*RenderOffer
+
+	seen := make(map[*RenderOffer]bool)
+	for _, offer := range offers {
+		if offer != nil && !seen[offer] {
+			seen[offer] = true
+			unique = append(unique, offer)
+		}
+	}
+
+	return unique
+
+}
+
+// Get each render request only once
+// NOTE: A request is referenced by its ID and, after the deploy, also by its CID.
+func _uniqueRequests(requests map[string]*RenderRequest) []*RenderRequest {
+	var unique []*RenderRequest
+
+	seen := make(map[*RenderRequest]bool)
+	for _, request := range requests {
+		if request != nil && !seen[request] {
+			seen[request] = true
+			unique = append(unique, request)
+		}
+	}
+
+	return unique
+
+}
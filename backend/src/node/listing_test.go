@@ -0,0 +1,186 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	"renderhive/logger"
+)
+
+// creation time of the first document of the test listings
+var testListingCreated = time.Unix(1700000000, 0)
+
+// helper function to create a render node with five render offers and requests
+// NOTE: Documents 1-3 belong to account 0.0.1001, documents 4-5 to 0.0.1002.
+// Documents 2 and 4 are submitted, document 5 requests / offers Blender v3.6.0.
+func _testListingManager(t *testing.T, sqlite bool) *PackageManager {
+	t.Helper()
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	nm := &PackageManager{}
+	nm.Renderer.Offers = map[string]*RenderOffer{}
+	nm.Renderer.Requests = map[string]*RenderRequest{}
+	if sqlite {
+		repository, err := OpenSQLiteRenderRepository(filepath.Join(t.TempDir(), "renderhive.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { repository.Close() })
+		nm.Repository = repository
+	}
+
+	for i := 1; i <= 5; i++ {
+		owner, _ := hederasdk.AccountIDFromString("0.0.1001")
+		if i > 3 {
+			owner, _ = hederasdk.AccountIDFromString("0.0.1002")
+		}
+		version := "4.1.0"
+		if i == 5 {
+			version = "3.6.0"
+		}
+		created := testListingCreated.Add(time.Duration(i) * time.Hour)
+
+		offer := &RenderOffer{DocumentCID: _testListingCID("offer", i), Owner: &owner, CreatedTimestamp: created}
+		offer.BlenderVersions = []RenderOfferBlenderVersions{{Version: version}}
+		request := &RenderRequest{DocumentCID: _testListingCID("request", i), Owner: &owner, Version: version, CreatedTimestamp: created}
+		if i == 2 || i == 4 {
+			offer.SubmittedTimestamp = created
+			request.SubmittedTimestamp = created
+		}
+		nm.Renderer.Offers[offer.DocumentCID] = offer
+		nm.Renderer.Requests[request.DocumentCID] = request
+		if sqlite {
+			if err := nm.Repository.SaveOffer(offer); err != nil {
+				t.Fatal(err)
+			}
+			if err := nm.Repository.SaveRequest(request); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	return nm
+}
+
+// helper function to get the CID of a document of the test listings
+func _testListingCID(kind string, i int) string {
+	return kind + "-" + string(rune('0'+i))
+}
+
+// helper function to list the CIDs of the render offers and requests matching the filter
+// NOTE: Fails the test, if the offers and requests do not match each other.
+func _testListing(t *testing.T, nm *PackageManager, filter RenderListFilter) (string, int) {
+	t.Helper()
+
+	offers, total, err := nm.ListRenderOffers(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests, requestTotal, err := nm.ListRenderRequests(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offerIndexes, requestIndexes := []string{}, []string{}
+	for _, offer := range offers {
+		offerIndexes = append(offerIndexes, strings.TrimPrefix(offer.DocumentCID, "offer-"))
+	}
+	for _, request := range requests {
+		requestIndexes = append(requestIndexes, strings.TrimPrefix(request.DocumentCID, "request-"))
+	}
+	if strings.Join(offerIndexes, ",") != strings.Join(requestIndexes, ",") || total != requestTotal {
+		t.Fatalf("%+v: got offers %v (total %v) and requests %v (total %v)", filter, offerIndexes, total, requestIndexes, requestTotal)
+	}
+
+	return strings.Join(offerIndexes, ","), total
+}
+
+func TestListRenderDocuments(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter RenderListFilter
+		page   string
+		total  int
+	}{
+		{"all documents (newest first)", RenderListFilter{}, "5,4,3,2,1", 5},
+		{"first page", RenderListFilter{Limit: 2}, "5,4", 5},
+		{"second page", RenderListFilter{Offset: 2, Limit: 2}, "3,2", 5},
+		{"last page", RenderListFilter{Offset: 4, Limit: 2}, "1", 5},
+		{"page after the end", RenderListFilter{Offset: 10, Limit: 2}, "", 5},
+		{"single page", RenderListFilter{Limit: 10}, "5,4,3,2,1", 5},
+		{"owner", RenderListFilter{Owner: "0.0.1001"}, "3,2,1", 3},
+		{"owner and state", RenderListFilter{Owner: "0.0.1001", State: REPOSITORY_STATE_SUBMITTED}, "2", 1},
+		{"state (case-insensitive)", RenderListFilter{State: strings.ToUpper(REPOSITORY_STATE_CREATED)}, "5,3,1", 3},
+		{"version", RenderListFilter{Version: "3.6.0"}, "5", 1},
+		{"date range", RenderListFilter{From: testListingCreated.Add(2 * time.Hour), To: testListingCreated.Add(4 * time.Hour)}, "3,2", 2},
+		{"filtered pages", RenderListFilter{Version: "4.1.0", Offset: 1, Limit: 2}, "3,2", 4},
+		{"no match", RenderListFilter{Owner: "0.0.1003"}, "", 0},
+	}
+	for _, sqlite := range []bool{false, true} {
+		nm := _testListingManager(t, sqlite)
+		for _, test := range tests {
+			page, total := _testListing(t, nm, test.filter)
+			if page != test.page || total != test.total {
+				t.Errorf("%v (SQLite: %v): got %q of %v documents, want %q of %v", test.name, sqlite, page, total, test.page, test.total)
+			}
+		}
+	}
+}
+
+func TestListRenderDocumentsOfAnEmptyNode(t *testing.T) {
+	nm := &PackageManager{}
+	offers, total, err := nm.ListRenderOffers(RenderListFilter{Limit: 10})
+	if err != nil || len(offers) != 0 || total != 0 {
+		t.Errorf("got %v offers of %v (%v), want an empty listing", len(offers), total, err)
+	}
+	requests, total, err := nm.ListRenderRequests(RenderListFilter{Limit: 10})
+	if err != nil || len(requests) != 0 || total != 0 {
+		t.Errorf("got %v requests of %v (%v), want an empty listing", len(requests), total, err)
+	}
+}
+
+func TestListRenderDocumentsRejectsInvalidFilters(t *testing.T) {
+	nm := &PackageManager{}
+	for _, filter := range []RenderListFilter{
+		{Offset: -1},
+		{Limit: -1},
+		{State: "unknown"},
+		{From: testListingCreated, To: testListingCreated.Add(-time.Hour)},
+	} {
+		if _, _, err := nm.ListRenderOffers(filter); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%+v: got %v, want an invalid argument", filter, err)
+		}
+		if _, _, err := nm.ListRenderRequests(filter); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%+v: got %v, want an invalid argument", filter, err)
+		}
+	}
+}
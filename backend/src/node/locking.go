@@ -0,0 +1,136 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the lock of the render data, which is shared by the
+JSON-RPC methods of the node service and the background tasks of the node
+(e.g., the render worker, the topic handler, and the sweeps of the render
+documents and jobs).
+
+The lock protects the render data of the node (i.e., the render offers and
+requests, the render jobs, and the disputes). It does NOT protect the session,
+the data of the operator, or the sequence of the transactions the node submits
+for its operator, which are locked by the lock of the JSON-RPC methods (see
+jsonrpc/locking.go), nor the Hedera client, the transaction history, or the
+metrics, which synchronize themselves.
+
+Readers take a shared (read) lock and run in parallel. All other callers take
+the exclusive lock, which waits for the running readers and blocks new ones.
+Waiting callers are served in the order they arrived, so a stream of readers
+cannot starve a writer. A deploy releases the lock while it waits up to
+RENDERHIVE_CONFIG_DEPLOY_PROVIDER_TIMEOUT for the DHT providers of the deployed
+files. Network calls (e.g., IPFS and topic messages) should be made after the
+lock was released, whenever possible.
+
+*/
+import (
+
+	// standard
+	"context"
+	"fmt"
+	"sync"
+
+	// external
+	"golang.org/x/sync/semaphore"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/ipfs"
+)
+
+// Read-write lock of the render data, which can be locked with a context
+// NOTE: The zero value is an unlocked lock.
+type RenderMutex struct {
+	once      sync.Once
+	semaphore *semaphore.Weighted
+}
+
+// RENDER LOCK
+// #############################################################################
+// Lock the mutex exclusively
+func (m *RenderMutex) Lock() {
+	m._semaphore().Acquire(context.Background(), RENDERHIVE_CONFIG_JSONRPC_MAX_PARALLEL_QUERIES)
+}
+
+// Lock the mutex exclusively or give up, when the context is done
+func (m *RenderMutex) LockContext(ctx context.Context) error {
+
+	err := m._semaphore().Acquire(ctx, RENDERHIVE_CONFIG_JSONRPC_MAX_PARALLEL_QUERIES)
+	if err != nil {
+		return fmt.Errorf("The request timed out, while waiting for another request: %w", err)
+	}
+
+	return nil
+
+}
+
+// Unlock the exclusively locked mutex
+func (m *RenderMutex) Unlock() {
+	m._semaphore().Release(RENDERHIVE_CONFIG_JSONRPC_MAX_PARALLEL_QUERIES)
+}
+
+// Lock the mutex for a read-only query or give up, when the context is done
+func (m *RenderMutex) RLockContext(ctx context.Context) error {
+
+	err := m._semaphore().Acquire(ctx, 1)
+	if err != nil {
+		return fmt.Errorf("The request timed out, while waiting for another request: %w", err)
+	}
+
+	return nil
+
+}
+
+// Unlock the mutex after a read-only query
+func (m *RenderMutex) RUnlock() {
+	m._semaphore().Release(1)
+}
+
+// helper function to get the semaphore of the mutex
+func (m *RenderMutex) _semaphore() *semaphore.Weighted {
+
+	m.once.Do(func() {
+		m.semaphore = semaphore.NewWeighted(RENDERHIVE_CONFIG_JSONRPC_MAX_PARALLEL_QUERIES)
+	})
+
+	return m.semaphore
+
+}
+
+// helper function to wait for a probe of the DHT providers without the lock of
+// the render data, so that a deploy does not block the other requests
+// NOTE: The caller must hold the lock of the render data. The deployed document
+// is only added to the render data after the probe.
+func (nm *PackageManager) _awaitProviders(probe <-chan ipfs.ProviderProbe) (int, error) {
+
+	nm.Renderer.Mutex.Unlock()
+	defer nm.Renderer.Mutex.Lock()
+
+	select {
+	case result := <-probe:
+		return result.Providers, result.Err
+	case <-nm.Context().Done():
+		return 0, newRenderError(ErrNetworkUnavailable, "The node is shutting down.")
+	}
+
+}
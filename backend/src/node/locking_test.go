@@ -0,0 +1,128 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+	// standard
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	// internal
+	"renderhive/ipfs"
+	"renderhive/logger"
+)
+
+func TestAwaitProvidersReleasesTheLock(t *testing.T) {
+	logger.Manager.Init()
+	nm := &PackageManager{}
+	nm.ctx, nm.cancel = context.WithCancel(context.Background())
+	defer nm.cancel()
+	nm.Renderer.Mutex.Lock()
+	defer nm.Renderer.Mutex.Unlock()
+
+	probe := make(chan ipfs.ProviderProbe, 1)
+	result := make(chan int)
+	go func() {
+		count, _ := nm._awaitProviders(probe)
+		result <- count
+	}()
+
+	// other requests get the lock during the probe
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := nm.Renderer.Mutex.LockContext(ctx); err != nil {
+		t.Fatalf("the lock was held during the probe: %v", err)
+	}
+	nm.Renderer.Mutex.Unlock()
+
+	// the deploy continues with the lock after the probe
+	probe <- ipfs.ProviderProbe{Providers: 3}
+	if count := <-result; count != 3 {
+		t.Errorf("got %v providers, want 3", count)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := nm.Renderer.Mutex.LockContext(ctx); err == nil {
+		nm.Renderer.Mutex.Unlock()
+		t.Errorf("the lock was not taken again after the probe")
+	}
+}
+
+func TestAwaitProvidersStopsOnShutdown(t *testing.T) {
+	logger.Manager.Init()
+	nm := &PackageManager{}
+	nm.ctx, nm.cancel = context.WithCancel(context.Background())
+	nm.Renderer.Mutex.Lock()
+	defer nm.Renderer.Mutex.Unlock()
+
+	// a probe error is returned
+	probe := make(chan ipfs.ProviderProbe, 1)
+	probe <- ipfs.ProviderProbe{Err: errors.New("no provider")}
+	if _, err := nm._awaitProviders(probe); err == nil {
+		t.Errorf("the probe error was not returned")
+	}
+
+	// the deploy does not wait for the probe on shutdown
+	nm.cancel()
+	if _, err := nm._awaitProviders(make(chan ipfs.ProviderProbe)); err == nil {
+		t.Errorf("the deploy waited for the probe on shutdown")
+	}
+}
+
+func TestRenderMutexSerializesWrites(t *testing.T) {
+	var mutex RenderMutex
+	var active, maximum atomic.Int32
+
+	// the exclusive lock waits for the running queries
+	if err := mutex.RLockContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := mutex.LockContext(ctx); err == nil {
+		t.Fatalf("the exclusive lock was taken during a query")
+	}
+	mutex.RUnlock()
+
+	// state-changing methods never run at the same time
+	done := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		go func() {
+			mutex.Lock()
+			if count := active.Add(1); count > maximum.Load() {
+				maximum.Store(count)
+			}
+			time.Sleep(time.Millisecond)
+			active.Add(-1)
+			mutex.Unlock()
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		<-done
+	}
+	if maximum.Load() != 1 {
+		t.Errorf("%v state-changing methods ran at the same time", maximum.Load())
+	}
+}
@@ -0,0 +1,604 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the export and the import of a node, which enable the
+migration of a node to a new computer.
+
+The node archive contains everything that identifies the node, but none of the
+data it can fetch again from the network:
+
+  - the configuration directory (including the encrypted keystore files)
+  - the local render offer and render request documents
+  - the peer identity and the IPNS keys of the local IPFS node
+
+The archive is a gzipped tar archive, encrypted with AES-256-GCM and a key
+derived from a passphrase (scrypt). Its first entry is a manifest with the
+SHA-256 checksum of each file, so a damaged or tampered archive is refused
+before anything is restored.
+
+*/
+
+import (
+
+	// standard
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	// external
+	"github.com/ipfs/kubo/config"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/scrypt"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/hedera"
+	"renderhive/ipfs"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// version and file signature of the node archive
+const nodeArchiveVersion = 1
+const nodeArchiveMagic = "RENDERHIVE-NODE-ARCHIVE"
+
+// name of the archive entries that are not restored as files
+const nodeArchiveManifest = "manifest.json"
+const nodeArchiveIdentity = "ipfs/identity.json"
+
+// Manifest of a node archive
+type NodeArchiveManifest struct {
+	Version int               `json:"version"` // version of the archive format
+	Created time.Time         `json:"created"` // time of the export
+	Files   map[string]string `json:"files"`   // SHA-256 checksum of each archive entry
+}
+
+// directory of the node data and its prefix in the node archive
+type nodeArchiveSource struct {
+	Prefix    string
+	Directory string
+}
+
+// NODE EXPORT & IMPORT
+// #############################################################################
+// Export the configuration, keys, and render documents of this node into an encrypted archive
+// NOTE: Returns the number of exported files.
+func (nm *PackageManager) ExportNode(archivePath string, passphrase string) (int, error) {
+	var err error
+
+	err = _validateArchivePassphrase(passphrase)
+	if err != nil {
+		return 0, err
+	}
+
+	// collect the files of the node
+	files := map[string][]byte{}
+	for _, source := range _nodeArchiveSources() {
+		err = _collectArchiveFiles(source, files)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if len(files) == 0 {
+		return 0, newRenderError(ErrDocumentNotFound, "This node has no configuration to export.")
+	}
+
+	// add the peer identity of the local IPFS node
+	identity, err := ipfs.ReadIdentity()
+	if err != nil && !os.IsNotExist(err) {
+		return 0, newRenderError(ErrStorageFailed, "Could not read the IPFS peer identity: %w", err)
+	}
+	if err == nil {
+		files[nodeArchiveIdentity], err = json.Marshal(identity)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// pack and encrypt the archive
+	data, err := _packNodeArchive(files)
+	if err != nil {
+		return 0, err
+	}
+	data, err = _encryptNodeArchive(data, passphrase)
+	if err != nil {
+		return 0, err
+	}
+
+	// write the archive (without overwriting an existing file)
+	file, err := os.OpenFile(archivePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return 0, err
+	}
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+		os.Remove(archivePath)
+		return 0, err
+	}
+	err = file.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	// log event
+	logger.Manager.Package["node"].Info().Msg(fmt.Sprintf("Exported %v files of this node to '%v'.", len(files), archivePath))
+
+	return len(files), nil
+
+}
+
+// Import the configuration, keys, and render documents of a node from an encrypted archive
+// NOTE: Refuses to replace the configuration of an already configured node,
+// unless 'overwrite' is set. Returns the number of imported files.
+func (nm *PackageManager) ImportNode(archivePath string, passphrase string, overwrite bool) (int, error) {
+	var err error
+
+	if IsConfiguredNode() && !overwrite {
+		return 0, newRenderError(ErrAlreadyExists, "This node is already configured. Use '--force' to overwrite its configuration and keys.")
+	}
+
+	// read, decrypt, and validate the archive
+	data, err := os.ReadFile(archivePath)
+	if err != nil {
+		return 0, err
+	}
+	data, err = _decryptNodeArchive(data, passphrase)
+	if err != nil {
+		return 0, err
+	}
+	files, err := _unpackNodeArchive(data)
+	if err != nil {
+		return 0, err
+	}
+	var identity *config.Identity
+	if content, ok := files[nodeArchiveIdentity]; ok {
+		identity = &config.Identity{}
+		err = json.Unmarshal(content, identity)
+		if err == nil {
+			err = ipfs.ValidateIdentity(*identity)
+		}
+		if err != nil {
+			return 0, newRenderError(ErrInvalidArgument, "Invalid node archive: %w", err)
+		}
+		delete(files, nodeArchiveIdentity)
+	}
+	targets := map[string]string{}
+	for name := range files {
+		targets[name], err = _archiveTarget(name)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// restore the files
+	for name, content := range files {
+		err = os.MkdirAll(filepath.Dir(targets[name]), 0700)
+		if err != nil {
+			return 0, err
+		}
+		err = os.WriteFile(targets[name], content, 0600)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// restore the peer identity of the local IPFS node
+	count := len(files)
+	if identity != nil {
+		err = ipfs.WriteIdentity(*identity)
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	// log event
+	logger.Manager.Package["node"].Info().Msg(fmt.Sprintf("Imported %v files of a node from '%v'.", count, archivePath))
+
+	return count, nil
+
+}
+
+// Check if this node already has a configuration or a keystore
+func IsConfiguredNode() bool {
+
+	entries, err := os.ReadDir(RENDERHIVE_APP_DIRECTORY_CONFIG)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if entry.Name() == "node.json" || filepath.Ext(entry.Name()) == ".key" {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// helper function to get the directories of the node data in the archive
+func _nodeArchiveSources() []nodeArchiveSource {
+
+	return []nodeArchiveSource{
+		{Prefix: "config/", Directory: RENDERHIVE_APP_DIRECTORY_CONFIG},
+		{Prefix: "appdata/" + RENDERHIVE_APP_DIRECTORY_LOCAL_OFFERS, Directory: filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_LOCAL_OFFERS)},
+		{Prefix: "appdata/" + RENDERHIVE_APP_DIRECTORY_LOCAL_REQUESTS, Directory: filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_LOCAL_REQUESTS)},
+		{Prefix: "appdata/" + RENDERHIVE_APP_DIRECTORY_IPFS_REPO + "keystore/", Directory: filepath.Join(ipfs.RepoPath(), "keystore")},
+	}
+
+}
+
+// helper function to get the local path of an archive entry
+// NOTE: Entries outside the directories of the node data are refused.
+func _archiveTarget(name string) (string, error) {
+
+	if path.Clean(name) != name || strings.Contains(name, "\\") {
+		return "", newRenderError(ErrInvalidArgument, "Invalid node archive: the path '%v' is not allowed.", name)
+	}
+	for _, source := range _nodeArchiveSources() {
+		if relative, ok := strings.CutPrefix(name, source.Prefix); ok && relative != "" {
+			return filepath.Join(source.Directory, filepath.FromSlash(relative)), nil
+		}
+	}
+
+	return "", newRenderError(ErrInvalidArgument, "Invalid node archive: the path '%v' is not allowed.", name)
+
+}
+
+// helper function to read the files of a directory of the node data
+func _collectArchiveFiles(source nodeArchiveSource, files map[string][]byte) error {
+
+	err := filepath.WalkDir(source.Directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		relative, err := filepath.Rel(source.Directory, path)
+		if err != nil {
+			return err
+		}
+		files[source.Prefix+filepath.ToSlash(relative)], err = os.ReadFile(path)
+		return err
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+
+}
+
+// helper function to check the passphrase of a node archive
+func _validateArchivePassphrase(passphrase string) error {
+
+	if len(passphrase) < RENDERHIVE_CONFIG_NODE_ARCHIVE_MINIMUM_PASSPHRASE {
+		return newRenderError(ErrInvalidArgument, "The passphrase must have at least %v characters.", RENDERHIVE_CONFIG_NODE_ARCHIVE_MINIMUM_PASSPHRASE)
+	}
+
+	return nil
+
+}
+
+// helper function to pack the files into a gzipped tar archive with a manifest
+func _packNodeArchive(files map[string][]byte) ([]byte, error) {
+
+	// create the manifest
+	manifest := NodeArchiveManifest{Version: nodeArchiveVersion, Created: time.Now().UTC(), Files: map[string]string{}}
+	for name, content := range files {
+		checksum := sha256.Sum256(content)
+		manifest.Files[name] = hex.EncodeToString(checksum[:])
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	// write the manifest and the files
+	var buffer bytes.Buffer
+	compressed := gzip.NewWriter(&buffer)
+	writer := tar.NewWriter(compressed)
+	write := func(name string, content []byte) error {
+		err := writer.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), ModTime: manifest.Created, Typeflag: tar.TypeReg})
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write(content)
+		return err
+	}
+	err = write(nodeArchiveManifest, manifestData)
+	if err != nil {
+		return nil, err
+	}
+	for name, content := range files {
+		err = write(name, content)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	err = compressed.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+
+}
+
+// helper function to unpack and validate the files of a gzipped tar archive
+func _unpackNodeArchive(data []byte) (map[string][]byte, error) {
+
+	compressed, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, newRenderError(ErrInvalidArgument, "Invalid node archive: %w", err)
+	}
+	defer compressed.Close()
+
+	// read the manifest and the files
+	var manifest *NodeArchiveManifest
+	files := map[string][]byte{}
+	reader := tar.NewReader(compressed)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, newRenderError(ErrInvalidArgument, "Invalid node archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil, newRenderError(ErrInvalidArgument, "Invalid node archive: '%v' is not a regular file.", header.Name)
+		}
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, newRenderError(ErrInvalidArgument, "Invalid node archive: %w", err)
+		}
+		if manifest == nil {
+			if header.Name != nodeArchiveManifest {
+				return nil, newRenderError(ErrInvalidArgument, "Invalid node archive: the manifest is missing.")
+			}
+			manifest = &NodeArchiveManifest{}
+			err = json.Unmarshal(content, manifest)
+			if err != nil {
+				return nil, newRenderError(ErrInvalidArgument, "Invalid node archive: %w", err)
+			}
+			if manifest.Version != nodeArchiveVersion {
+				return nil, newRenderError(ErrUnsupportedSchema, "Unsupported node archive version '%v' (supported: %v).", manifest.Version, nodeArchiveVersion)
+			}
+			continue
+		}
+		files[header.Name] = content
+	}
+	if manifest == nil {
+		return nil, newRenderError(ErrInvalidArgument, "Invalid node archive: the manifest is missing.")
+	}
+
+	// compare the files with the manifest
+	if len(files) != len(manifest.Files) {
+		return nil, newRenderError(ErrInvalidArgument, "Invalid node archive: the manifest lists %v files, but the archive contains %v.", len(manifest.Files), len(files))
+	}
+	for name, content := range files {
+		checksum := sha256.Sum256(content)
+		if manifest.Files[name] != hex.EncodeToString(checksum[:]) {
+			return nil, newRenderError(ErrInvalidArgument, "Invalid node archive: the checksum of '%v' does not match the manifest.", name)
+		}
+	}
+
+	return files, nil
+
+}
+
+// helper function to encrypt a node archive with a passphrase
+// NOTE: The encrypted archive consists of the file signature, the scrypt salt,
+// the AES-GCM nonce, and the ciphertext.
+func _encryptNodeArchive(data []byte, passphrase string) ([]byte, error) {
+
+	salt := make([]byte, 16)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := _nodeArchiveCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append(append([]byte(nodeArchiveMagic), salt...), nonce...)
+
+	return aead.Seal(header, nonce, data, []byte(nodeArchiveMagic)), nil
+
+}
+
+// helper function to decrypt a node archive with a passphrase
+func _decryptNodeArchive(data []byte, passphrase string) ([]byte, error) {
+
+	if !bytes.HasPrefix(data, []byte(nodeArchiveMagic)) {
+		return nil, newRenderError(ErrInvalidArgument, "The file is not a Renderhive node archive.")
+	}
+	data = data[len(nodeArchiveMagic):]
+	if len(data) < 16 {
+		return nil, newRenderError(ErrInvalidArgument, "Invalid node archive: the file is truncated.")
+	}
+	salt, data := data[:16], data[16:]
+	aead, err := _nodeArchiveCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, newRenderError(ErrInvalidArgument, "Invalid node archive: the file is truncated.")
+	}
+	nonce, data := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, data, []byte(nodeArchiveMagic))
+	if err != nil {
+		return nil, newRenderError(ErrInvalidArgument, "Could not decrypt the node archive: the passphrase is wrong or the archive is damaged.")
+	}
+
+	return plaintext, nil
+
+}
+
+// helper function to derive the AES-GCM cipher of a node archive from a passphrase
+func _nodeArchiveCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+
+}
+
+// COMMAND LINE INTERFACE – NODE EXPORT & IMPORT
+// #############################################################################
+// Create the CLI command to export this node into an encrypted archive
+func (nm *PackageManager) CreateCommandExport() *cobra.Command {
+
+	// create a 'export' command for the node
+	command := &cobra.Command{
+		Use:   "export <archive>",
+		Short: "Export the configuration and keys of this node into an encrypted archive",
+		Long:  "This command bundles the configuration directory (including the keystore), the local render offer and render request documents, and the IPFS peer identity of this node into an encrypted archive. Use it to migrate the node to a new computer. The IPFS datastore is not exported. The passphrase of the archive is read from the RENDERHIVE_PASSPHRASE environment variable or prompted for.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// read the passphrase (and confirm it, if it was prompted for)
+			passphrase, err := hedera.ReadPassphrase(cmd, "Passphrase for the archive")
+			if err != nil {
+				return err
+			}
+			if !hedera.PassphraseInEnvironment() {
+				confirmation, err := hedera.PromptPassphrase(cmd, "Repeat the passphrase")
+				if err != nil {
+					return err
+				}
+				if confirmation != passphrase {
+					return newRenderError(ErrInvalidArgument, "The passphrases do not match.")
+				}
+			}
+
+			count, err := nm.ExportNode(args[0], passphrase)
+			if err != nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not export the node: %w", err)
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Println("Exported the node:")
+			logger.Manager.Resultf(" [#] Archive: %v\n", args[0])
+			logger.Manager.Resultf(" [#] Files: %v\n", count)
+			logger.Manager.Println("")
+			logger.Manager.Println("WARNING: The archive contains the private keys of the node account and the IPFS node.")
+			logger.Manager.Println("Anyone with the archive and the passphrase controls the node and its funds. Keep both")
+			logger.Manager.Println("in a safe place and delete the archive after the migration.")
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	return command
+
+}
+
+// Create the CLI command to import a node from an encrypted archive
+func (nm *PackageManager) CreateCommandImport() *cobra.Command {
+
+	// flags for the 'import' command
+	var force bool
+
+	// create a 'import' command for the node
+	command := &cobra.Command{
+		Use:   "import <archive>",
+		Short: "Import the configuration and keys of a node from an encrypted archive",
+		Long:  "This command restores the configuration directory (including the keystore), the local render offer and render request documents, and the IPFS peer identity from an archive created with 'node export'. The archive is validated before any file is restored. An already configured node is only overwritten with '--force'. The passphrase of the archive is read from the RENDERHIVE_PASSPHRASE environment variable or prompted for.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// read the passphrase
+			passphrase, err := hedera.ReadPassphrase(cmd, "Passphrase of the archive")
+			if err != nil {
+				return err
+			}
+
+			count, err := nm.ImportNode(args[0], passphrase, force)
+			if err != nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not import the node: %w", err)
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Println("Imported the node:")
+			logger.Manager.Resultf(" [#] Archive: %v\n", args[0])
+			logger.Manager.Resultf(" [#] Files: %v\n", count)
+			logger.Manager.Println("")
+			logger.Manager.Println("Restart the Renderhive Service App to use the imported node. The archive contains")
+			logger.Manager.Println("the private keys of the node, so delete it, if it is no longer needed.")
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().BoolVarP(&force, "force", "f", false, "Overwrite the configuration and keys of an already configured node")
+
+	return command
+
+}
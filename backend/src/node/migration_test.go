@@ -0,0 +1,142 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+	// standard
+	"os"
+	"path/filepath"
+	"testing"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// helper function to write a file of the node data
+func _writeNodeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// helper function to get the files of the node data in the current data directories
+func _testNodeFiles() map[string]string {
+	return map[string]string{
+		filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "node.json"):                              `{"node":1}`,
+		filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "0.0.1234.key"):                           `{"crypto":"keystore"}`,
+		filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_LOCAL_OFFERS, "offer.json"):     `{"offer":1}`,
+		filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_LOCAL_REQUESTS, "request.json"): `{"request":1}`,
+	}
+}
+
+func TestExportImportNodeRoundTrip(t *testing.T) {
+	logger.Manager.Init()
+	nm := &PackageManager{}
+
+	// the node on the old computer
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	_chdirTemp(t)
+	for path, content := range _testNodeFiles() {
+		_writeNodeFile(t, path, content)
+	}
+	archive := filepath.Join(t.TempDir(), "node.archive")
+	count, err := nm.ExportNode(archive, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != len(_testNodeFiles()) {
+		t.Errorf("exported %v files, want %v", count, len(_testNodeFiles()))
+	}
+
+	// an existing archive is not overwritten
+	if _, err := nm.ExportNode(archive, "correct horse"); err == nil {
+		t.Error("expected the existing archive not to be overwritten")
+	}
+
+	// the node on the new computer
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	_chdirTemp(t)
+	if _, err := nm.ImportNode(archive, "wrong passphrase", false); err == nil {
+		t.Fatal("expected the archive not to be decrypted with a wrong passphrase")
+	}
+	count, err = nm.ImportNode(archive, "correct horse", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != len(_testNodeFiles()) {
+		t.Errorf("imported %v files, want %v", count, len(_testNodeFiles()))
+	}
+	for path, content := range _testNodeFiles() {
+		restored, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("%v was not restored: %v", path, err)
+			continue
+		}
+		if string(restored) != content {
+			t.Errorf("%v = %q, want %q", path, restored, content)
+		}
+	}
+
+	// a configured node is only overwritten on request
+	if _, err := nm.ImportNode(archive, "correct horse", false); err == nil {
+		t.Error("expected the configured node not to be overwritten")
+	}
+	if _, err := nm.ImportNode(archive, "correct horse", true); err != nil {
+		t.Errorf("expected the configured node to be overwritten: %v", err)
+	}
+}
+
+func TestArchiveTargetRejectsPathTraversal(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// entries in the directories of the node data are restored
+	for _, name := range []string{"config/node.json", "config/keys/0.0.1234.key", "appdata/" + RENDERHIVE_APP_DIRECTORY_LOCAL_OFFERS + "offer.json"} {
+		if _, err := _archiveTarget(name); err != nil {
+			t.Errorf("_archiveTarget(%q) = %v, want no error", name, err)
+		}
+	}
+
+	// entries outside of them are refused
+	for _, name := range []string{
+		"../node.json",
+		"config/../../node.json",
+		"config/../node.json",
+		"/etc/passwd",
+		"config/",
+		"config",
+		"config//node.json",
+		"config/./node.json",
+		`config\..\..\node.json`,
+		"appdata/data/other/file.json",
+		"unknown/node.json",
+		"",
+	} {
+		if target, err := _archiveTarget(name); err == nil {
+			t.Errorf("_archiveTarget(%q) = %v, want an error", name, target)
+		}
+	}
+}
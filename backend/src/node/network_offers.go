@@ -0,0 +1,287 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the render offers of other operators in the render hive.
+
+The job queue topic callback records the render offer announcements and the
+pause messages of all operators by the account, which paid for the message. The
+render offers of an operator are looked up from these announcements and their
+render offer documents are fetched from IPFS (and cached in the network offers
+directory). Therefore, only the announcements since the start of the job queue
+subscription are known (see jobqueue.json).
+
+*/
+
+import (
+
+	// standard
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/ipfs"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// Render offer announcement observed on the job queue topic
+type OfferAnnouncement struct {
+	Operator           string                 // account ID of the announcing operator
+	RenderOfferCID     string                 // CID of the render offer document
+	Benchmarks         []RenderOfferBenchmark // benchmark scores of the last announcement
+	SubmittedTimestamp time.Time              // consensus time of the last announcement
+	PausedTimestamp    time.Time              // consensus time of the pause message (zero, if active)
+}
+
+// NETWORK RENDER OFFERS
+// #############################################################################
+// helper function to record a render offer announcement of an operator
+func (nm *PackageManager) _recordOfferAnnouncement(operator string, cid string, benchmarks []RenderOfferBenchmark, timestamp time.Time) {
+
+	// lock the announcements
+	nm.networkOffersMutex.Lock()
+	defer nm.networkOffersMutex.Unlock()
+
+	if nm.networkOffers == nil {
+		nm.networkOffers = make(map[string]map[string]*OfferAnnouncement)
+	}
+	if nm.networkOffers[operator] == nil {
+		nm.networkOffers[operator] = make(map[string]*OfferAnnouncement)
+	}
+
+	// a re-announcement activates the offer again
+	nm.networkOffers[operator][cid] = &OfferAnnouncement{
+		Operator:           operator,
+		RenderOfferCID:     cid,
+		Benchmarks:         benchmarks,
+		SubmittedTimestamp: timestamp,
+	}
+
+}
+
+// helper function to record the pause of a render offer of an operator
+func (nm *PackageManager) _recordOfferPause(operator string, cid string, timestamp time.Time) {
+
+	// lock the announcements
+	nm.networkOffersMutex.Lock()
+	defer nm.networkOffersMutex.Unlock()
+
+	// NOTE: Only the owner can pause its render offer.
+	if announcement, ok := nm.networkOffers[operator][cid]; ok && announcement.SubmittedTimestamp.Before(timestamp) {
+		announcement.PausedTimestamp = timestamp
+	}
+
+}
+
+// Get the active render offer announcements of an operator (newest first)
+func (nm *PackageManager) GetOfferAnnouncements(accountID string) []OfferAnnouncement {
+	var announcements []OfferAnnouncement
+
+	// lock the announcements
+	nm.networkOffersMutex.Lock()
+	defer nm.networkOffersMutex.Unlock()
+
+	for _, announcement := range nm.networkOffers[accountID] {
+		if announcement.PausedTimestamp.IsZero() {
+			announcements = append(announcements, *announcement)
+		}
+	}
+	sort.Slice(announcements, func(i, j int) bool {
+		return announcements[i].SubmittedTimestamp.After(announcements[j].SubmittedTimestamp)
+	})
+
+	return announcements
+
+}
+
+// Get the active render offers of an operator from the network (newest first)
+// NOTE: An operator without active render offers has an empty list.
+func (nm *PackageManager) GetOperatorOffers(accountID string) ([]*RenderOffer, error) {
+	var offers []*RenderOffer
+	var lastErr error
+
+	// check the account ID
+	operator, err := hederasdk.AccountIDFromString(accountID)
+	if err != nil {
+		return nil, newRenderError(ErrInvalidArgument, "Invalid account ID '%v': %w", accountID, err)
+	}
+
+	announcements := nm.GetOfferAnnouncements(operator.String())
+	for _, announcement := range announcements {
+
+		// fetch the render offer document
+		offer, err := nm._fetchNetworkOffer(announcement.RenderOfferCID)
+		if err != nil {
+			logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Could not fetch render offer '%v' of operator '%v': %v", announcement.RenderOfferCID, operator.String(), err))
+			lastErr = err
+			continue
+		}
+
+		// ignore documents, which were announced by another account than their owner
+		if offer.Owner == nil || offer.Owner.String() != operator.String() {
+			logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Render offer '%v' was announced by '%v', but is not owned by this operator.", announcement.RenderOfferCID, operator.String()))
+			continue
+		}
+		offer.SubmittedTimestamp = announcement.SubmittedTimestamp
+		if len(offer.Blender) == 0 {
+			offer.Benchmarks = announcement.Benchmarks
+		}
+
+		offers = append(offers, offer)
+
+	}
+
+	// none of the render offer documents could be fetched
+	if len(offers) == 0 && lastErr != nil {
+		return nil, newRenderError(ErrNetworkUnavailable, "Could not fetch the render offers of operator '%v': %w", operator.String(), lastErr)
+	}
+
+	return offers, nil
+
+}
+
+// Get the active render offers of all operators from the network
+// NOTE: Render offers, which could not be fetched, are left out.
+func (nm *PackageManager) GetNetworkOffers() []*RenderOffer {
+	var offers []*RenderOffer
+
+	// get the operators with render offer announcements
+	nm.networkOffersMutex.Lock()
+	operators := make([]string, 0, len(nm.networkOffers))
+	for operator := range nm.networkOffers {
+		operators = append(operators, operator)
+	}
+	nm.networkOffersMutex.Unlock()
+	sort.Strings(operators)
+
+	for _, operator := range operators {
+		operatorOffers, err := nm.GetOperatorOffers(operator)
+		if err != nil {
+			continue
+		}
+		offers = append(offers, operatorOffers...)
+	}
+
+	return offers
+
+}
+
+// Get an active render offer of another operator from the network
+func (nm *PackageManager) GetNetworkOffer(cid string) (*RenderOffer, error) {
+
+	// find the operator, who announced the render offer
+	nm.networkOffersMutex.Lock()
+	operator := ""
+	for accountID, announcements := range nm.networkOffers {
+		for _, announcement := range announcements {
+			if _cidKey(announcement.RenderOfferCID) == _cidKey(cid) && announcement.PausedTimestamp.IsZero() {
+				operator = accountID
+			}
+		}
+	}
+	nm.networkOffersMutex.Unlock()
+	if operator == "" {
+		return nil, newRenderError(ErrOfferNotFound, "Render offer with CID '%v' was not announced on the network.", cid)
+	}
+
+	offers, err := nm.GetOperatorOffers(operator)
+	if err != nil {
+		return nil, err
+	}
+	for _, offer := range offers {
+		if _cidKey(offer.DocumentCID) == _cidKey(cid) {
+			return offer, nil
+		}
+	}
+
+	return nil, newRenderError(ErrOfferNotFound, "Render offer with CID '%v' could not be fetched from the network.", cid)
+
+}
+
+// helper function to get a render offer document from the local cache or IPFS
+// NOTE: The CID is received from other operators. Therefore, it is parsed
+// before it becomes part of the cache path.
+func (nm *PackageManager) _fetchNetworkOffer(announcedCID string) (*RenderOffer, error) {
+
+	parsed, err := ParseCID(announcedCID)
+	if err != nil {
+		return nil, newRenderError(ErrInvalidArgument, "Invalid render offer CID: %w", err)
+	}
+	cid := parsed.String()
+
+	// the render offer may be an offer of this node
+	if offer, err := nm.GetRenderOffer(cid); err == nil {
+		local := *offer
+		return &local, nil
+	}
+
+	path := filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_NETWORK_OFFERS, cid+".json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+
+		// download the render offer document
+		fetch := nm.fetchNetworkObject
+		if fetch == nil {
+			if ipfs.Manager.IpfsAPI == nil {
+				return nil, newRenderError(ErrNetworkUnavailable, "The IPFS node is not running.")
+			}
+			fetch = func(cid string, path string) error {
+				_, err := ipfs.Manager.GetObject(cid, path)
+				return err
+			}
+		}
+		err = os.MkdirAll(filepath.Dir(path), 0700)
+		if err != nil {
+			return nil, err
+		}
+		err = fetch(cid, path)
+		if err != nil {
+			return nil, newRenderError(ErrNetworkUnavailable, "Render offer '%v' could not be downloaded: %w", cid, err)
+		}
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+	}
+
+	offer, err := DecodeRenderOffer(cid, path, data)
+	if err != nil {
+
+		// do not keep an invalid document in the cache
+		os.Remove(path)
+		return nil, newRenderError(ErrInvalidArgument, "Render offer '%v' could not be decoded: %w", cid, err)
+
+	}
+
+	return offer, nil
+
+}
@@ -0,0 +1,106 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	// internal
+	"renderhive/logger"
+)
+
+// CID of an empty directory, which is used as announced render offer document
+const testOfferCID = "QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn"
+
+func TestGetOperatorOffersFetchesAnnouncedDocuments(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// the document is downloaded with the mocked fetcher
+	var fetched []string
+	nm := &PackageManager{}
+	nm.fetchNetworkObject = func(cid string, path string) error {
+		fetched = append(fetched, cid)
+		return os.WriteFile(path, []byte(`{"SchemaVersion":1,"Price":2.5,"Owner":{"Shard":0,"Realm":0,"Account":1001}}`), 0600)
+	}
+	nm._recordOfferAnnouncement("0.0.1001", testOfferCID, nil, time.Unix(100, 0))
+
+	offers, err := nm.GetOperatorOffers("0.0.1001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offers) != 1 || offers[0].Price != 2.5 || offers[0].Owner.String() != "0.0.1001" {
+		t.Fatalf("unexpected offers: %+v", offers)
+	}
+
+	// the cached document is not downloaded again
+	if _, err = nm.GetOperatorOffers("0.0.1001"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fetched) != 1 {
+		t.Fatalf("expected one download, got %v", fetched)
+	}
+
+	// a document of another owner is ignored
+	nm._recordOfferAnnouncement("0.0.2002", testOfferCID, nil, time.Unix(200, 0))
+	offers, err = nm.GetOperatorOffers("0.0.2002")
+	if err != nil || len(offers) != 0 {
+		t.Fatalf("expected no offers of another owner, got %+v, %v", offers, err)
+	}
+}
+
+func TestFetchNetworkOfferRejectsInvalidCIDs(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	nm := &PackageManager{}
+	nm.fetchNetworkObject = func(cid string, path string) error {
+		t.Errorf("the invalid CID '%v' was fetched to '%v'", cid, path)
+		return nil
+	}
+
+	for _, cid := range []string{"../../config/node", "/etc/passwd", "Qm..", ""} {
+		_, err := nm._fetchNetworkOffer(cid)
+		if !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%q: expected an invalid argument error, got %v", cid, err)
+		}
+	}
+
+	// an operator with only invalid announcements has no offers
+	nm._recordOfferAnnouncement("0.0.1001", "../../../etc/passwd", nil, time.Unix(100, 0))
+	if _, err := nm.GetOperatorOffers("0.0.1001"); err == nil {
+		t.Fatal("expected an error for an invalid announcement")
+	}
+
+	// a failed download is reported
+	nm.fetchNetworkObject = func(cid string, path string) error {
+		return fmt.Errorf("not found")
+	}
+	if _, err := nm._fetchNetworkOffer(testOfferCID); !errors.Is(err, ErrNetworkUnavailable) {
+		t.Fatalf("expected a network error, got %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the scheduling of the render requests of this node.
+
+A node receives its own render requests from the job queue topic like the
+render requests of other nodes. If the requester set 'ThisNode' on a render
+request, the node renders the jobs of the request itself:
+
+  - the priority of its own jobs is raised by the own request priority, so
+    that the node picks them before the jobs of other nodes with the same or
+    a slightly higher priority
+  - the node reserves capacity for its own jobs: The estimated render time of
+    its own jobs, which wait in the render hive queue, is added to the queue
+    duration, when the node checks the deadline of another job. Thus, the node
+    does not claim jobs of other nodes, which it could only finish in time by
+    delaying its own jobs.
+
+Without 'ThisNode', the node does not render its own render requests. Its own
+jobs are claimed and announced like any other job, so that the claim flow of
+the smart contract is the same. The settings can be changed in the optional
+'scheduling.json' file of the configuration directory:
+
+    {"own_request_priority": 1000, "reserve_own_requests": true}
+
+An own request priority of 0 schedules the own jobs like the jobs of other
+nodes.
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// Scheduling settings of the render requests of this node
+type OwnRequestSettings struct {
+	Priority int  `json:"own_request_priority"` // added to the priority of the own jobs (0 = no preference)
+	Reserve  bool `json:"reserve_own_requests"` // reserve the render time of the waiting own jobs
+}
+
+// OWN REQUEST SETTINGS
+// #############################################################################
+// Get the default scheduling settings of the render requests of this node
+func DefaultOwnRequestSettings() OwnRequestSettings {
+	return OwnRequestSettings{
+		Priority: RENDERHIVE_CONFIG_OWN_REQUEST_PRIORITY,
+		Reserve:  true,
+	}
+}
+
+// Read the scheduling settings from the configuration file
+func (settings *OwnRequestSettings) Read() error {
+	var err error
+
+	// Open the configuration file
+	file, err := os.Open(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "scheduling.json"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Read the file content
+	fileData, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	// get the file content into a structure
+	err = json.Unmarshal(fileData, settings)
+	if err != nil {
+		return err
+	}
+
+	return settings.Validate()
+
+}
+
+// Check the scheduling settings for invalid values
+func (settings *OwnRequestSettings) Validate() error {
+
+	if settings.Priority < 0 {
+		return newRenderError(ErrInvalidArgument, "The own request priority must not be negative.")
+	}
+
+	return nil
+
+}
+
+// Get the scheduling settings of this node (the configured or the default settings)
+func (nm *PackageManager) GetOwnRequestSettings() OwnRequestSettings {
+
+	// read the configured settings
+	settings := DefaultOwnRequestSettings()
+	err := settings.Read()
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Invalid scheduling settings (using the default settings): %v", err))
+		}
+		return DefaultOwnRequestSettings()
+	}
+
+	return settings
+
+}
+
+// OWN RENDER REQUESTS
+// #############################################################################
+// Get the render request of this node, which the render job belongs to
+// NOTE: Returns nil, if the render request was created by another node.
+func (nm *PackageManager) OwnRenderRequest(job *RenderJob) *RenderRequest {
+
+	request, err := nm.GetRenderRequest(job.Request.DocumentCID)
+	if err != nil {
+		return nil
+	}
+
+	return request
+
+}
+
+// Check if this node renders the render job of its own render request
+func (nm *PackageManager) IsOwnRenderJob(job *RenderJob) bool {
+
+	request := nm.OwnRenderRequest(job)
+	return request != nil && request.ThisNode
+
+}
+
+// Get the priority of the render job on this node
+func (nm *PackageManager) SchedulingPriority(job *RenderJob, settings OwnRequestSettings) int {
+
+	if nm.IsOwnRenderJob(job) {
+		return job.Request.Priority + settings.Priority
+	}
+
+	return job.Request.Priority
+
+}
+
+// Estimate the render time reserved for the own jobs, which wait in the render
+// hive queue (zero for the own jobs themselves)
+func (nm *PackageManager) ReservedDuration(job *RenderJob, settings OwnRequestSettings) time.Duration {
+
+	if !settings.Reserve || nm.IsOwnRenderJob(job) {
+		return 0
+	}
+
+	total := time.Duration(0)
+	for _, own := range nm.NetworkQueue {
+		if own.State != RENDER_JOB_STATE_QUEUED || own.Flagged || nm._isClaimed(own) || !nm.IsOwnRenderJob(own) {
+			continue
+		}
+
+		// only the jobs this node can render need capacity
+		offer := nm.BestActiveRenderOffer(own.Request)
+		if offer == nil {
+			continue
+		}
+		previous := own.Offer
+		own.Offer = offer
+		total += nm.EstimateRenderDuration(own)
+		own.Offer = previous
+	}
+
+	return total
+
+}
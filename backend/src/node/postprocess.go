@@ -0,0 +1,288 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the post-processing of the rendered frames of a render job.
+
+After the frames were collected and before the render result is added to IPFS,
+the node can post-process the frames. Currently, the only operation is the
+generation of a low-resolution preview of each frame, so that a requester can
+look at the result before downloading the full frames. The previews are written
+as JPEG files into the 'previews' directory of the result and their CIDs are
+added to the frames of the result document and to the render result.
+
+The post-processing is opt-in and is configured in the optional
+'postprocess.json' file of the configuration directory:
+
+    {"preview": true, "preview_size": 320, "preview_quality": 75}
+
+The operations are built into the node and no external commands are executed.
+Frames in formats, which cannot be decoded (e.g., OpenEXR), are skipped and
+frames larger than RENDERHIVE_CONFIG_PREVIEW_MAX_PIXELS are not decoded. A failed
+preview never fails the render job: the frame is just published without one.
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// Post-processing settings of the render results of this node
+type PostProcessingSettings struct {
+	Preview        bool `json:"preview"`         // generate a low-resolution preview of each frame
+	PreviewSize    int  `json:"preview_size"`    // maximum width and height of a preview in pixels
+	PreviewQuality int  `json:"preview_quality"` // JPEG quality of the previews (1 - 100)
+}
+
+// POST-PROCESSING SETTINGS
+// #############################################################################
+// Get the default post-processing settings (no post-processing)
+func DefaultPostProcessingSettings() PostProcessingSettings {
+	return PostProcessingSettings{
+		Preview:        false,
+		PreviewSize:    RENDERHIVE_CONFIG_PREVIEW_SIZE,
+		PreviewQuality: RENDERHIVE_CONFIG_PREVIEW_QUALITY,
+	}
+}
+
+// Read the post-processing settings from the configuration file
+func (settings *PostProcessingSettings) Read() error {
+	var err error
+
+	// Open the configuration file
+	file, err := os.Open(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "postprocess.json"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Read the file content
+	fileData, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	// get the file content into a structure
+	err = json.Unmarshal(fileData, settings)
+	if err != nil {
+		return err
+	}
+
+	return settings.Validate()
+
+}
+
+// Check the post-processing settings for invalid values
+func (settings *PostProcessingSettings) Validate() error {
+
+	if settings.PreviewSize < 1 {
+		return newRenderError(ErrInvalidArgument, "The preview size must be at least 1 pixel.")
+	}
+	if settings.PreviewQuality < 1 || settings.PreviewQuality > 100 {
+		return newRenderError(ErrInvalidArgument, "The preview quality must be between 1 and 100.")
+	}
+
+	return nil
+
+}
+
+// Get the post-processing settings of this node (the configured or the default settings)
+func (nm *PackageManager) GetPostProcessingSettings() PostProcessingSettings {
+
+	// read the configured settings
+	settings := DefaultPostProcessingSettings()
+	err := settings.Read()
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Invalid post-processing settings (post-processing is disabled): %v", err))
+		}
+		return DefaultPostProcessingSettings()
+	}
+
+	return settings
+
+}
+
+// POST-PROCESSING
+// #############################################################################
+// Post-process the collected frames of a result document
+// NOTE: The hash function calculates the CID of a preview file. The returned
+// error lists the frames without preview, but the frames are still valid.
+func PostProcessRenderResultFiles(settings PostProcessingSettings, directory string, document *RenderResultDocument, hash func(string) (string, error)) error {
+	var errs []error
+
+	if !settings.Preview {
+		return nil
+	}
+
+	skipped := 0
+	for i := range document.Frames {
+		frame := &document.Frames[i]
+		frame.Preview = ""
+		frame.PreviewCID = ""
+
+		// generate the preview
+		name := strings.TrimSuffix(filepath.Base(frame.File), filepath.Ext(frame.File)) + ".jpg"
+		preview := filepath.Join(RENDERHIVE_RESULT_PREVIEW_DIRECTORY, name)
+		os.Remove(filepath.Join(directory, preview))
+		err := _writePreview(filepath.Join(directory, frame.File), filepath.Join(directory, preview), settings)
+		if errors.Is(err, image.ErrFormat) {
+			skipped++
+			continue
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("No preview of frame %v: %v", frame.Frame, err))
+			continue
+		}
+
+		cid, err := hash(filepath.Join(directory, preview))
+		if err != nil {
+			os.Remove(filepath.Join(directory, preview))
+			errs = append(errs, fmt.Errorf("No preview of frame %v: The preview could not be hashed: %v", frame.Frame, err))
+			continue
+		}
+		frame.Preview = filepath.ToSlash(preview)
+		frame.PreviewCID = cid
+	}
+
+	// log event
+	if skipped > 0 {
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Skipped the previews of %v frame(s) in a format without preview support.", skipped))
+	}
+
+	return errors.Join(errs...)
+
+}
+
+// helper function to write the low-resolution preview of a frame file
+// NOTE: A panic of an image decoder is returned as an error.
+func _writePreview(path string, preview string, settings PostProcessingSettings) (err error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("The frame could not be decoded: %v", r)
+		}
+	}()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// check the image size before decoding the image
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return err
+	}
+	if config.Width < 1 || config.Height < 1 || config.Width*config.Height > RENDERHIVE_CONFIG_PREVIEW_MAX_PIXELS {
+		return fmt.Errorf("The frame size %vx%v is not supported.", config.Width, config.Height)
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return err
+	}
+
+	// write the preview
+	err = os.MkdirAll(filepath.Dir(preview), 0700)
+	if err != nil {
+		return err
+	}
+	output, err := os.Create(preview)
+	if err != nil {
+		return err
+	}
+	err = jpeg.Encode(output, _downscale(img, settings.PreviewSize), &jpeg.Options{Quality: settings.PreviewQuality})
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(preview)
+	}
+
+	return err
+
+}
+
+// helper function to scale an image down to fit into a square of the given size
+// NOTE: Each pixel of the scaled image is the average of the pixels it covers.
+func _downscale(img image.Image, size int) image.Image {
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+
+	// keep the aspect ratio
+	scaledWidth, scaledHeight := size, height*size/width
+	if height > width {
+		scaledWidth, scaledHeight = width*size/height, size
+	}
+	if scaledWidth < 1 {
+		scaledWidth = 1
+	}
+	if scaledHeight < 1 {
+		scaledHeight = 1
+	}
+
+	scaled := image.NewRGBA(image.Rect(0, 0, scaledWidth, scaledHeight))
+	for y := 0; y < scaledHeight; y++ {
+		y0, y1 := y*height/scaledHeight, (y+1)*height/scaledHeight
+		for x := 0; x < scaledWidth; x++ {
+			x0, x1 := x*width/scaledWidth, (x+1)*width/scaledWidth
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(bounds.Min.X+sx, bounds.Min.Y+sy).RGBA()
+					r, g, b, a, n = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa), n+1
+				}
+			}
+			scaled.SetRGBA(x, y, color.RGBA{R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(b / n >> 8), A: uint8(a / n >> 8)})
+		}
+	}
+
+	return scaled
+
+}
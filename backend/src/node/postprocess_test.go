@@ -0,0 +1,271 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// helper function to write a PNG frame file of the given size
+func _writeTestFrame(t *testing.T, path string, width int, height int) {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// helper function to write the post-processing settings of this node
+func _writePostProcessing(t *testing.T, data string) {
+	t.Helper()
+
+	if err := os.MkdirAll(RENDERHIVE_APP_DIRECTORY_CONFIG, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "postprocess.json"), []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// helper function to collect the frames 1-4 of a result directory: two PNG
+// frames, a frame in a format without preview support, and a corrupt frame
+func _testPostProcessingFrames(t *testing.T) (string, *RenderResultDocument) {
+	t.Helper()
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	directory := t.TempDir()
+	_writeTestFrame(t, filepath.Join(directory, "frame_0001.png"), 640, 480)
+	_writeTestFrame(t, filepath.Join(directory, "frame_0002.png"), 100, 50)
+	_writeFrameFiles(t, directory, "frame_0003.exr")
+	if err := os.WriteFile(filepath.Join(directory, "frame_0004.png"), []byte("\x89PNG\r\n\x1a\ncorrupt"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	document, err := CollectRenderResultFiles(RenderSettings{FrameStart: 1, FrameEnd: 4, FrameStep: 1}, directory, _testHashFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return directory, document
+}
+
+func TestPostProcessingIsOptIn(t *testing.T) {
+	directory, document := _testPostProcessingFrames(t)
+
+	called := false
+	hash := func(path string) (string, error) {
+		called = true
+		return _testHashFile(path)
+	}
+	if err := PostProcessRenderResultFiles(DefaultPostProcessingSettings(), directory, document, hash); err != nil {
+		t.Fatal(err)
+	}
+	if called {
+		t.Error("expected no hook to be invoked without post-processing")
+	}
+	for _, frame := range document.Frames {
+		if frame.Preview != "" || frame.PreviewCID != "" {
+			t.Errorf("frame %v: unexpected preview %v (%v)", frame.Frame, frame.Preview, frame.PreviewCID)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(directory, RENDERHIVE_RESULT_PREVIEW_DIRECTORY)); !os.IsNotExist(err) {
+		t.Errorf("expected no preview directory: %v", err)
+	}
+}
+
+func TestPostProcessRenderResultFiles(t *testing.T) {
+	directory, document := _testPostProcessingFrames(t)
+	settings := DefaultPostProcessingSettings()
+	settings.Preview = true
+
+	// the hook hashes each generated preview
+	hashed := map[string]string{}
+	hash := func(path string) (string, error) {
+		cid, err := _testHashFile(path)
+		hashed[path] = cid
+		return cid, err
+	}
+	err := PostProcessRenderResultFiles(settings, directory, document, hash)
+
+	// the corrupt frame is reported, but does not stop the other frames
+	if err == nil || !strings.Contains(err.Error(), "frame 4") || strings.Contains(err.Error(), "frame 3") {
+		t.Errorf("got %v, want only an error of the corrupt frame", err)
+	}
+	if len(hashed) != 2 {
+		t.Errorf("got %v hashed previews, want 2: %v", len(hashed), hashed)
+	}
+
+	// the previews fit into the preview size and keep the aspect ratio
+	sizes := map[int][2]int{1: {320, 240}, 2: {100, 50}}
+	for _, frame := range document.Frames {
+		size, ok := sizes[frame.Frame]
+		if !ok {
+			if frame.Preview != "" || frame.PreviewCID != "" {
+				t.Errorf("frame %v: expected no preview, got %v", frame.Frame, frame.Preview)
+			}
+			continue
+		}
+
+		path := filepath.Join(directory, filepath.FromSlash(frame.Preview))
+		if frame.Preview != RENDERHIVE_RESULT_PREVIEW_DIRECTORY+"/"+strings.TrimSuffix(frame.File, ".png")+".jpg" || frame.PreviewCID != hashed[path] {
+			t.Errorf("frame %v: got the preview %v (%v), want the hashed JPEG file", frame.Frame, frame.Preview, frame.PreviewCID)
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config, err := jpeg.DecodeConfig(file)
+		file.Close()
+		if err != nil {
+			t.Fatalf("frame %v: %v", frame.Frame, err)
+		}
+		if config.Width != size[0] || config.Height != size[1] {
+			t.Errorf("frame %v: got a preview of %vx%v, want %vx%v", frame.Frame, config.Width, config.Height, size[0], size[1])
+		}
+	}
+}
+
+func TestPostProcessingFailureKeepsTheFrames(t *testing.T) {
+	directory, document := _testPostProcessingFrames(t)
+	settings := DefaultPostProcessingSettings()
+	settings.Preview = true
+	frames := append([]RenderResultFrame(nil), document.Frames...)
+
+	// a failing hook does not publish the previews
+	failed := errors.New("hash failed")
+	err := PostProcessRenderResultFiles(settings, directory, document, func(path string) (string, error) { return "", failed })
+	if err == nil || !strings.Contains(err.Error(), failed.Error()) {
+		t.Errorf("got %v, want the error of the hook", err)
+	}
+	for i, frame := range document.Frames {
+		if frame != frames[i] {
+			t.Errorf("frame %v: got %+v, want the frame without preview %+v", frame.Frame, frame, frames[i])
+		}
+	}
+	if matches, _ := filepath.Glob(filepath.Join(directory, RENDERHIVE_RESULT_PREVIEW_DIRECTORY, "*")); len(matches) != 0 {
+		t.Errorf("expected the unpublished previews to be removed, got %v", matches)
+	}
+}
+
+func TestGetPostProcessingSettings(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	_chdirTemp(t)
+	nm := &PackageManager{}
+
+	tests := []struct {
+		name     string
+		data     string // empty, if there is no configuration file
+		settings PostProcessingSettings
+	}{
+		{"no configuration", "", DefaultPostProcessingSettings()},
+		{"preview", `{"preview": true}`, PostProcessingSettings{Preview: true, PreviewSize: RENDERHIVE_CONFIG_PREVIEW_SIZE, PreviewQuality: RENDERHIVE_CONFIG_PREVIEW_QUALITY}},
+		{"custom preview", `{"preview": true, "preview_size": 128, "preview_quality": 90}`, PostProcessingSettings{Preview: true, PreviewSize: 128, PreviewQuality: 90}},
+		{"invalid size", `{"preview": true, "preview_size": 0}`, DefaultPostProcessingSettings()},
+		{"invalid quality", `{"preview": true, "preview_quality": 101}`, DefaultPostProcessingSettings()},
+		{"invalid file", `{"preview": `, DefaultPostProcessingSettings()},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			os.RemoveAll(RENDERHIVE_APP_DIRECTORY_CONFIG)
+			if test.data != "" {
+				_writePostProcessing(t, test.data)
+			}
+			if settings := nm.GetPostProcessingSettings(); settings != test.settings {
+				t.Errorf("got %+v, want %+v", settings, test.settings)
+			}
+		})
+	}
+}
+
+func TestCollectRenderResultWithPreviews(t *testing.T) {
+	nm, _ := _testRenderCIDManager(t)
+	_testMockIPFS(t, nil)
+	_writePostProcessing(t, `{"preview": true}`)
+
+	// a render job with the frames 1-2
+	request := &RenderRequest{DocumentCID: testResultRequestCID, Version: "4.1.0"}
+	request.BlenderFile.CID = testResultBlendCID
+	request.BlenderFile.Settings = RenderSettings{FrameStart: 1, FrameEnd: 2, FrameStep: 1}
+	job := &RenderJob{Request: request}
+	_writeTestFrame(t, filepath.Join(job.OutputDirectory(), "frame_0001.png"), 640, 480)
+	_writeTestFrame(t, filepath.Join(job.OutputDirectory(), "frame_0002.png"), 640, 480)
+
+	result, document, err := nm.CollectRenderResult(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the render result includes the preview CIDs, but not the previews as frames
+	if len(document.Frames) != 2 || len(result.FrameHashes) != 2 || len(result.PreviewHashes) != 2 {
+		t.Fatalf("got %v frames with %v previews, want 2 frames with 2 previews", len(result.FrameHashes), len(result.PreviewHashes))
+	}
+	for _, frame := range document.Frames {
+		if frame.PreviewCID == "" || result.PreviewHashes[frame.Frame] != frame.PreviewCID || result.FrameHashes[frame.Frame] == frame.PreviewCID {
+			t.Errorf("frame %v: got the preview %v in the result, want %v", frame.Frame, result.PreviewHashes[frame.Frame], frame.PreviewCID)
+		}
+	}
+
+	// the result document lists the previews
+	data, err := os.ReadFile(filepath.Join(job.OutputDirectory(), RENDERHIVE_RESULT_DOCUMENT_FILENAME))
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := RenderResultDocument{}
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatal(err)
+	}
+	for i, frame := range written.Frames {
+		if frame.Preview == "" || frame.PreviewCID != document.Frames[i].PreviewCID {
+			t.Errorf("frame %v: got the preview %v (%v) in the result document", frame.Frame, frame.Preview, frame.PreviewCID)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the probe of the Blender binaries added to the render offer.
+
+The probe starts the Blender binary in background mode with the '-v' option
+and reads the version and build info from its output. The probe is stopped
+after a timeout, since Blender may hang in some container environments (e.g.,
+Docker on Apple Silicon). If the probe fails, the Blender version is still
+added, but its build info is marked as unverified.
+
+The build info of a successful probe is cached by the SHA-256 checksum of the
+Blender binary ('data/blender/probes/probes.json'). The same binary is not
+started again, when the render offer is loaded on the next start. A changed
+binary has another checksum and is probed again.
+
+Optionally, a quick benchmark can be rendered after a Blender version was
+added. The benchmark result provides an initial render score and the build
+info, if the probe failed before.
+
+*/
+
+import (
+
+	// standard
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// name of the probe cache in the probe directory
+const blenderProbeCacheFile = "probes.json"
+
+// Cached build info of a probed Blender binary
+type BlenderProbeCacheEntry struct {
+	BuildVersion string    `json:"build_version"`
+	BuildDate    string    `json:"build_date"`
+	BuildTime    string    `json:"build_time"`
+	BuildHash    string    `json:"build_hash"`
+	Probed       time.Time `json:"probed"` // datetime of the probe
+}
+
+// lock of the probe cache file
+var blenderProbeMutex sync.Mutex
+
+// regular expressions for the version and build info lines of 'blender -v'
+var (
+	blenderVersionLine   = regexp.MustCompile(`^Blender (\d+\.\d+(?:\.\d+)?)`)
+	blenderBuildDateLine = regexp.MustCompile(`^build date:\s*(.+)$`)
+	blenderBuildTimeLine = regexp.MustCompile(`^build time:\s*(\d{1,2}:\d{2}(?::\d{2})?)`)
+	blenderBuildHashLine = regexp.MustCompile(`^build hash:\s*([0-9a-fA-F]+)`)
+)
+
+// BLENDER VERSION PROBE
+// #############################################################################
+// Query the version and build info from the Blender binary
+func (b *BlenderAppData) ProbeVersion(timeout time.Duration) error {
+	var err error
+
+	// the build info is unverified until the probe succeeded
+	b.Verified = false
+
+	// check if the path is pointing to an existing file
+	if _, err = os.Stat(b.Path); err != nil {
+		return newRenderError(ErrUnsupportedVersion, "Blender binary '%v' is not available: %w", b.Path, err)
+	}
+
+	// start Blender in background mode and stop it after the timeout
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, b.Path, "-b", "--factory-startup", "-v")
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return newRenderError(ErrUnsupportedVersion, "Blender binary '%v' did not respond within %v.", b.Path, timeout)
+	}
+
+	// parse the output (even if Blender exited with an error after printing its version)
+	parseErr := b.ParseVersionOutput(string(output))
+	if parseErr != nil {
+		if err != nil {
+			return newRenderError(ErrUnsupportedVersion, "Blender binary '%v' could not be started: %w", b.Path, err)
+		}
+		return parseErr
+	}
+	b.Verified = true
+
+	return nil
+
+}
+
+// Read the version and build info from the output of 'blender -v'
+func (b *BlenderAppData) ParseVersionOutput(output string) error {
+
+	version, date, clock, hash := "", "", "", ""
+
+	// scan the lines and ignore any other messages (e.g., warnings)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if match := blenderVersionLine.FindStringSubmatch(line); match != nil && version == "" {
+			version = match[1]
+		} else if match := blenderBuildDateLine.FindStringSubmatch(line); match != nil {
+			date = strings.TrimSpace(match[1])
+		} else if match := blenderBuildTimeLine.FindStringSubmatch(line); match != nil {
+			clock = match[1]
+		} else if match := blenderBuildHashLine.FindStringSubmatch(line); match != nil {
+			hash = match[1]
+		}
+	}
+
+	// the version line is required
+	if version == "" {
+		return newRenderError(ErrUnsupportedVersion, "The Blender output does not contain a version.")
+	}
+
+	b.BuildVersion = version
+	b.BuildDate = date
+	b.BuildTime = clock
+	b.BuildHash = hash
+
+	return nil
+
+}
+
+// Check if the build info matches the Blender version of the Renderhive archive
+func (b *BlenderAppData) MatchesArchive(version string) bool {
+
+	archive, ok := GetBlenderArchive(version)
+	if !ok || b.BuildVersion != version {
+		return false
+	}
+
+	// the build hash is only checked, if the archive defines it
+	return archive.Linux.Commit == "" || strings.EqualFold(b.BuildHash, archive.Linux.Commit)
+
+}
+
+// helper function to probe the Blender binary and fall back to the declared version
+func (b *BlenderAppData) _probe(version string) {
+
+	// log event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Probing the Blender binary for version v%v ...", version))
+
+	// use the build info of a previous probe of the same binary
+	var err error
+	checksum, hashErr := _sha256File(b.Path)
+	if entry, ok := _readBlenderProbeCache()[checksum]; hashErr == nil && ok {
+		b.BuildVersion = entry.BuildVersion
+		b.BuildDate = entry.BuildDate
+		b.BuildTime = entry.BuildTime
+		b.BuildHash = entry.BuildHash
+		b.Verified = true
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Using the cached build info of the Blender binary for version v%v.", version))
+	} else {
+		err = b.ProbeVersion(RENDERHIVE_CONFIG_BLENDER_PROBE_TIMEOUT)
+		if err == nil && hashErr == nil {
+			_recordBlenderProbe(checksum, b)
+		}
+	}
+	if err == nil && !b.MatchesArchive(version) {
+		b.Verified = false
+		logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf(" [#] Blender binary reports v%v (build hash: %v), which does not match v%v of the Renderhive archive.", b.BuildVersion, b.BuildHash, version))
+	} else if err != nil {
+		logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf(" [#] Build info of Blender v%v is unverified: %v", version, err))
+	}
+
+	// use the declared version, if the binary did not report one
+	if b.BuildVersion == "" {
+		b.BuildVersion = version
+	}
+
+}
+
+// helper function to get the directory of the probe cache
+func _blenderProbePath() string {
+
+	return filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_BLENDER_PROBES)
+
+}
+
+// helper function to read the probe cache (empty, if it does not exist)
+func _readBlenderProbeCache() map[string]BlenderProbeCacheEntry {
+
+	blenderProbeMutex.Lock()
+	defer blenderProbeMutex.Unlock()
+
+	cache := map[string]BlenderProbeCacheEntry{}
+	data, err := os.ReadFile(filepath.Join(_blenderProbePath(), blenderProbeCacheFile))
+	if err != nil {
+		return cache
+	}
+	err = json.Unmarshal(data, &cache)
+	if err != nil {
+		logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Could not read the Blender probe cache: %v", err))
+		return map[string]BlenderProbeCacheEntry{}
+	}
+
+	return cache
+
+}
+
+// helper function to record the build info of a probed Blender binary
+func _recordBlenderProbe(checksum string, b *BlenderAppData) {
+
+	blenderProbeMutex.Lock()
+	defer blenderProbeMutex.Unlock()
+
+	directory := _blenderProbePath()
+	cache := map[string]BlenderProbeCacheEntry{}
+	if data, err := os.ReadFile(filepath.Join(directory, blenderProbeCacheFile)); err == nil {
+		json.Unmarshal(data, &cache)
+	}
+	cache[checksum] = BlenderProbeCacheEntry{
+		BuildVersion: b.BuildVersion,
+		BuildDate:    b.BuildDate,
+		BuildTime:    b.BuildTime,
+		BuildHash:    b.BuildHash,
+		Probed:       time.Now(),
+	}
+
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(directory, 0700)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(directory, blenderProbeCacheFile), data, 0600)
+	}
+	if err != nil {
+		logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Could not record the Blender probe: %v", err))
+	}
+
+}
+
+// QUICK BENCHMARK
+// #############################################################################
+// Render a quick benchmark for a Blender version of the render offer
+// NOTE: The build info is taken from the benchmark result, if it was not verified before.
+func (ro *RenderOffer) QuickBenchmark(ctx context.Context, version string) error {
+	var err error
+
+	// get the Blender version of the render offer
+	blender, ok := ro.Blender[version]
+	if !ok {
+		return newRenderError(ErrUnsupportedVersion, "Blender v'%v' is not in the node's render offer.", version)
+	}
+	if blender.BenchmarkTool == nil {
+		blender.BenchmarkTool = &BlenderBenchmarkTool{}
+	}
+
+	// log event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Rendering a quick benchmark for Blender v%v (scene: %v) ...", version, RENDERHIVE_CONFIG_BENCHMARK_QUICK_SCENE))
+
+	// render the benchmark scene on the CPU
+	err = blender.BenchmarkTool.Run(ctx, ro, version, "CPU", RENDERHIVE_CONFIG_BENCHMARK_QUICK_SCENE)
+	if err != nil {
+		return err
+	}
+	results := blender.BenchmarkTool.GetResult()
+	if len(results) == 0 {
+		return newRenderError(ErrBenchmarkUnavailable, "The quick benchmark for Blender v%v returned no result.", version)
+	}
+	result := results[0]
+
+	// use the build info of the benchmark, if the probe failed
+	if !blender.Verified {
+		blender.BuildVersion = result.BlenderVersion.Version
+		blender.BuildDate = result.BlenderVersion.BuildDate
+		blender.BuildTime = result.BlenderVersion.BuildTime
+		blender.BuildHash = result.BlenderVersion.BuildHash
+		blender.Verified = blender.MatchesArchive(version)
+	}
+
+	// update the Blender version of the render offer
+	ro.Blender[version] = blender
+	for i := range ro.BlenderVersions {
+		if ro.BlenderVersions[i].Version == version {
+			ro.BlenderVersions[i].BuildHash = blender.BuildHash
+			ro.BlenderVersions[i].Verified = blender.Verified
+		}
+	}
+
+	// log event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Initial render score of Blender v%v: %v samples / min", version, result.Stats.SamplesPerMinute))
+
+	return err
+
+}
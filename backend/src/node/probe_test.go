@@ -0,0 +1,85 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+	// standard
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	// internal
+	"renderhive/logger"
+)
+
+// fake Blender binary, which counts its starts and prints its version
+const testProbedBlender = `#!/bin/sh
+echo run >> "$(dirname "$0")/starts"
+echo "Blender 4.1.1"
+echo "build hash: %v"
+`
+
+// helper function to write the fake Blender binary with a build hash
+func _testProbedBlender(t *testing.T, path string, hash string) {
+	if err := os.WriteFile(path, []byte(strings.Replace(testProbedBlender, "%v", hash, 1)), 0700); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// helper function to count the starts of the fake Blender binary
+func _testBlenderStarts(dir string) int {
+	data, _ := os.ReadFile(filepath.Join(dir, "starts"))
+	return strings.Count(string(data), "run")
+}
+
+func TestProbeCache(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "blender")
+	_testProbedBlender(t, path, "abc123")
+
+	// the first probe starts the binary
+	first := BlenderAppData{Path: path}
+	first._probe("4.1.1")
+	if _testBlenderStarts(dir) != 1 || first.BuildHash != "abc123" {
+		t.Fatalf("got %v starts and build hash %q after the first probe", _testBlenderStarts(dir), first.BuildHash)
+	}
+
+	// the same binary is not started again
+	second := BlenderAppData{Path: path}
+	second._probe("4.1.1")
+	if _testBlenderStarts(dir) != 1 {
+		t.Errorf("the binary was started again for a cached probe")
+	}
+	if second.BuildVersion != "4.1.1" || second.BuildHash != "abc123" {
+		t.Errorf("got build info %v (%v) from the cache", second.BuildVersion, second.BuildHash)
+	}
+
+	// a changed binary is probed again
+	_testProbedBlender(t, path, "def456")
+	third := BlenderAppData{Path: path}
+	third._probe("4.1.1")
+	if _testBlenderStarts(dir) != 2 || third.BuildHash != "def456" {
+		t.Errorf("got %v starts and build hash %q after the binary changed", _testBlenderStarts(dir), third.BuildHash)
+	}
+}
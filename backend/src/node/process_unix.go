@@ -0,0 +1,62 @@
+//go:build !windows
+
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the termination of external processes on Unix systems.
+
+The Blender benchmark tool starts Blender as a child process. Killing only the
+tool would leave Blender running, so the tool is started in its own process
+group and the whole group is killed.
+
+A process killed with SIGKILL, which the node did not stop itself, was usually
+killed by the OOM killer of the system.
+
+*/
+
+import (
+
+	// standard
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// helper function to kill the process group of a command, when its context is canceled
+func _terminateProcessGroup(cmd *exec.Cmd) {
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+
+}
+
+// helper function to check if a process was killed with SIGKILL
+func _killedBySystem(state *os.ProcessState) bool {
+
+	status, ok := state.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGKILL
+
+}
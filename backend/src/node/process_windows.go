@@ -0,0 +1,55 @@
+//go:build windows
+
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the termination of external processes on Windows.
+
+Windows has no process groups to kill, so only the process itself is killed,
+when its context is canceled. Windows does not report, if a process was killed
+by the system, so crashes due to a lack of memory are only detected from the
+output of the process.
+
+*/
+
+import (
+
+	// standard
+	"os"
+	"os/exec"
+)
+
+// helper function to kill a command, when its context is canceled
+func _terminateProcessGroup(cmd *exec.Cmd) {
+
+	cmd.Cancel = func() error {
+		return cmd.Process.Kill()
+	}
+
+}
+
+// helper function to check if a process was killed by the system (not detectable on Windows)
+func _killedBySystem(state *os.ProcessState) bool {
+	return false
+}
@@ -0,0 +1,355 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the ranking of render offers for a render request. Each
+render offer that matches the render request is scored by a weighted average
+of three partial scores between 0 and 1:
+
+  - price: the lowest price of all matching offers divided by the price of the
+    offer (i.e., the cheapest offer scores 1)
+  - throughput: the benchmark score (samples per minute) of the offered Blender
+    version divided by the highest benchmark score of all matching offers (the
+    benchmark scores of the render offers of other nodes are taken from their
+    announcements on the job queue topic)
+  - reliability: the historical success rate of the node offering the render
+    power (see the reputation tracker)
+
+    score = (w_price * price + w_throughput * throughput + w_reliability * reliability)
+            / (w_price + w_throughput + w_reliability)
+
+Partial scores that are not available for an offer (e.g., no benchmark result)
+count with the missing score (see RENDERHIVE_CONFIG_RANKING_MISSING_SCORE), so
+an offer does not gain from an unknown throughput or reliability. The weights
+and the missing score can be set in the optional 'ranking.json' file of the
+configuration directory.
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	// internal
+	. "renderhive/globals"
+)
+
+// Weights of the partial scores of the offer ranking
+type RankingWeights struct {
+	Price        float64 `json:"price"`
+	Throughput   float64 `json:"throughput"`
+	Reliability  float64 `json:"reliability"`
+	MissingScore float64 `json:"missing_score"` // partial score of an unknown throughput or reliability
+}
+
+// A render offer scored for a render request
+type ScoredOffer struct {
+	Offer *RenderOffer
+
+	// total score (0 ... 1)
+	Score float64
+
+	// partial scores (0 ... 1)
+	PriceScore       float64
+	ThroughputScore  float64
+	ReliabilityScore float64
+
+	// availability of the partial scores
+	HasThroughput  bool
+	HasReliability bool
+
+	// raw values
+	Throughput  float64 // benchmark score of the offered Blender version (samples per minute)
+	SuccessRate float64 // historical success rate of the node (0 ... 1)
+}
+
+// OFFER RANKING
+// #############################################################################
+// Get the default weights of the offer ranking
+func DefaultRankingWeights() RankingWeights {
+	return RankingWeights{
+		Price:        RENDERHIVE_CONFIG_RANKING_WEIGHT_PRICE,
+		Throughput:   RENDERHIVE_CONFIG_RANKING_WEIGHT_THROUGHPUT,
+		Reliability:  RENDERHIVE_CONFIG_RANKING_WEIGHT_RELIABILITY,
+		MissingScore: RENDERHIVE_CONFIG_RANKING_MISSING_SCORE,
+	}
+}
+
+// Read the weights of the offer ranking from the configuration file
+func (weights *RankingWeights) Read() error {
+	var err error
+
+	// Open the configuration file
+	file, err := os.Open(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "ranking.json"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Read the file content
+	fileData, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	// get the file content into a structure
+	err = json.Unmarshal(fileData, weights)
+	if err != nil {
+		return err
+	}
+
+	return weights.Validate()
+
+}
+
+// Check the weights of the offer ranking
+func (weights *RankingWeights) Validate() error {
+
+	if weights.Price < 0 || weights.Throughput < 0 || weights.Reliability < 0 {
+		return newRenderError(ErrInvalidArgument, "The ranking weights must not be negative.")
+	}
+	if weights.Price+weights.Throughput+weights.Reliability == 0 {
+		return newRenderError(ErrInvalidArgument, "At least one ranking weight must be greater than zero.")
+	}
+	if weights.MissingScore < 0 || weights.MissingScore > 1 {
+		return newRenderError(ErrInvalidArgument, "The missing score of the ranking must be between 0 and 1.")
+	}
+
+	return nil
+
+}
+
+// Get the ranking weights of this node (the configured or the default weights)
+func (nm *PackageManager) GetRankingWeights() RankingWeights {
+
+	// read the configured weights
+	weights := DefaultRankingWeights()
+	err := weights.Read()
+	if err != nil {
+		return DefaultRankingWeights()
+	}
+
+	return weights
+
+}
+
+// Rank the render offers matching the render request (best offer first)
+func (nm *PackageManager) RankOffers(request *RenderRequest, offers []*RenderOffer) []ScoredOffer {
+	return RankOffersWithWeights(request, offers, nm.GetRankingWeights(), nm.OperatorSuccessRate)
+}
+
+// Rank the render offers matching the render request with the given weights (best offer first)
+func RankOffersWithWeights(request *RenderRequest, offers []*RenderOffer, weights RankingWeights, successRate func(accountID string) (float64, bool)) []ScoredOffer {
+	var scored []ScoredOffer
+
+	// collect the raw values of the matching offers
+	minPrice, maxThroughput := -1.0, 0.0
+	for _, offer := range offers {
+		if !OfferMatchesRequest(offer, request) {
+			continue
+		}
+
+		entry := ScoredOffer{Offer: offer}
+		entry.Throughput, entry.HasThroughput = _offerThroughput(offer, request)
+		if successRate != nil && offer.Owner != nil {
+			entry.SuccessRate, entry.HasReliability = successRate(offer.Owner.String())
+		}
+
+		if minPrice < 0 || offer.Price < minPrice {
+			minPrice = offer.Price
+		}
+		if entry.Throughput > maxThroughput {
+			maxThroughput = entry.Throughput
+		}
+
+		scored = append(scored, entry)
+	}
+
+	// calculate the scores
+	for i := range scored {
+		entry := &scored[i]
+
+		// price score (free offers and the cheapest offer score 1)
+		entry.PriceScore = 1.0
+		if entry.Offer.Price > 0 {
+			entry.PriceScore = minPrice / entry.Offer.Price
+		}
+
+		// throughput score (an unknown throughput counts with the missing score)
+		entry.ThroughputScore = weights.MissingScore
+		if entry.HasThroughput && maxThroughput > 0 {
+			entry.ThroughputScore = entry.Throughput / maxThroughput
+		}
+
+		// reliability score (an unknown reliability counts with the missing score)
+		entry.ReliabilityScore = weights.MissingScore
+		if entry.HasReliability {
+			entry.ReliabilityScore = entry.SuccessRate
+		}
+
+		total := weights.Price*entry.PriceScore + weights.Throughput*entry.ThroughputScore + weights.Reliability*entry.ReliabilityScore
+		weight := weights.Price + weights.Throughput + weights.Reliability
+		if weight > 0 {
+			entry.Score = total / weight
+		}
+	}
+
+	// sort the offers by score (and by price, if the scores are equal)
+	sort.SliceStable(scored, func(i, j int) bool {
+		if scored[i].Score == scored[j].Score {
+			return scored[i].Offer.Price < scored[j].Offer.Price
+		}
+		return scored[i].Score > scored[j].Score
+	})
+
+	return scored
+
+}
+
+// Check if the render offer can render the render request
+func OfferMatchesRequest(offer *RenderOffer, request *RenderRequest) bool {
+
+	// paused offers are not available
+	if offer == nil || offer.Paused {
+		return false
+	}
+
+	// the price must not exceed the price limit of the request
+	if request.Price > 0 && offer.Price > request.Price {
+		return false
+	}
+
+	// the Blender version, render engine, and device must be supported
+	settings := request.BlenderFile.Settings
+	for _, blender := range offer.BlenderVersions {
+		if blender.Version != request.Version {
+			continue
+		}
+		if settings.Engine != "" && !_containsFold(blender.Engines, _getEngineName(settings.Engine)) {
+			return false
+		}
+		if settings.Device != "" && !_containsFold(blender.Devices, settings.Device) {
+			return false
+		}
+		if settings.FeatureSet != "" && !_containsFold(_offeredFeatureSets(blender), settings.FeatureSet) {
+			return false
+		}
+		return true
+	}
+
+	return false
+
+}
+
+// helper function to get the feature sets of an offered Blender version
+// NOTE: Offers created before the feature sets were offered support the default
+// feature set.
+func _offeredFeatureSets(blender RenderOfferBlenderVersions) []string {
+
+	if len(blender.FeatureSets) == 0 {
+		return []string{BLENDER_DEFAULT_FEATURE_SET}
+	}
+
+	return blender.FeatureSets
+
+}
+
+// Get the benchmark score of the offered Blender version for the render request
+// NOTE: The benchmark results are only known for the offers of this node. The
+// offers of other nodes have the benchmark scores of their announcement.
+func _offerThroughput(offer *RenderOffer, request *RenderRequest) (float64, bool) {
+
+	benchmarks := offer.Benchmarks
+	if len(offer.Blender) > 0 {
+		benchmarks = _offerBenchmarks(offer)
+	}
+
+	// sum up the benchmark scores of the offered Blender version (on the requested device)
+	throughput := 0.0
+	for _, benchmark := range benchmarks {
+		if benchmark.Version != request.Version {
+			continue
+		}
+		if request.BlenderFile.Settings.Device != "" && !strings.EqualFold(benchmark.Device, request.BlenderFile.Settings.Device) {
+			continue
+		}
+		throughput += benchmark.SamplesPerMinute
+	}
+
+	return throughput, throughput > 0
+
+}
+
+// helper function to get the benchmark scores of the Blender versions of a
+// render offer of this node (per Blender version and device)
+func _offerBenchmarks(offer *RenderOffer) []RenderOfferBenchmark {
+	var benchmarks []RenderOfferBenchmark
+
+	for version, blender := range offer.Blender {
+		if blender.BenchmarkTool == nil {
+			continue
+		}
+
+		// sum up the samples per minute of all benchmark scenes of each device
+		for _, result := range blender.BenchmarkTool.GetResult() {
+			index := -1
+			for i, benchmark := range benchmarks {
+				if benchmark.Version == version && strings.EqualFold(benchmark.Device, result.DeviceInfo.DeviceType) {
+					index = i
+				}
+			}
+			if index < 0 {
+				benchmarks = append(benchmarks, RenderOfferBenchmark{Version: version, Device: result.DeviceInfo.DeviceType})
+				index = len(benchmarks) - 1
+			}
+			benchmarks[index].SamplesPerMinute += result.Stats.SamplesPerMinute
+		}
+	}
+
+	// sort the benchmark scores, so that the announcement does not depend on the map order
+	sort.Slice(benchmarks, func(i, j int) bool {
+		if benchmarks[i].Version == benchmarks[j].Version {
+			return benchmarks[i].Device < benchmarks[j].Device
+		}
+		return benchmarks[i].Version < benchmarks[j].Version
+	})
+
+	return benchmarks
+
+}
+
+// Check if the list contains the value (case insensitive)
+func _containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
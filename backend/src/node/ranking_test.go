@@ -0,0 +1,191 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"os"
+	"testing"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+func _testBenchmarkResult(device string, samplesPerMinute float64) BlenderBenchmarkResult {
+	var result BlenderBenchmarkResult
+	result.DeviceInfo.DeviceType = device
+	result.Stats.SamplesPerMinute = samplesPerMinute
+	return result
+}
+
+func _testRankedOffer(cid string, owner uint64, price float64, benchmarks ...RenderOfferBenchmark) *RenderOffer {
+	return &RenderOffer{
+		DocumentCID:     cid,
+		Price:           price,
+		Owner:           &hederasdk.AccountID{Account: owner},
+		BlenderVersions: []RenderOfferBlenderVersions{{Version: "4.1.0", Devices: []string{"CPU", "GPU"}}},
+		Benchmarks:      benchmarks,
+	}
+}
+
+func _rankedCIDs(scored []ScoredOffer) []string {
+	var cids []string
+	for _, entry := range scored {
+		cids = append(cids, entry.Offer.DocumentCID)
+	}
+	return cids
+}
+
+func TestRankOffersOrdersMatchingOffers(t *testing.T) {
+	request := &RenderRequest{Version: "4.1.0", Price: 3}
+	paused := _testRankedOffer("offer-paused", 1003, 0.5)
+	paused.Paused = true
+	offers := []*RenderOffer{
+		_testRankedOffer("offer-cheap", 1001, 1, RenderOfferBenchmark{Version: "4.1.0", Device: "CPU", SamplesPerMinute: 100}),
+		_testRankedOffer("offer-fast", 1002, 2, RenderOfferBenchmark{Version: "4.1.0", Device: "GPU", SamplesPerMinute: 400}),
+		_testRankedOffer("offer-expensive", 1004, 5, RenderOfferBenchmark{Version: "4.1.0", Device: "GPU", SamplesPerMinute: 1000}),
+		paused,
+	}
+
+	// the throughput outweighs the price
+	weights := RankingWeights{Price: 0.5, Throughput: 0.5, MissingScore: 0.25}
+	scored := RankOffersWithWeights(request, offers, weights, nil)
+	if cids := _rankedCIDs(scored); len(cids) != 2 || cids[0] != "offer-fast" || cids[1] != "offer-cheap" {
+		t.Fatalf("unexpected ranking: %v", cids)
+	}
+	if scored[0].Score != 0.75 || scored[1].Score != 0.625 {
+		t.Errorf("unexpected scores: %v, %v", scored[0].Score, scored[1].Score)
+	}
+
+	// the price outweighs the throughput
+	weights = RankingWeights{Price: 1, Throughput: 0.1, MissingScore: 0.25}
+	if cids := _rankedCIDs(RankOffersWithWeights(request, offers, weights, nil)); cids[0] != "offer-cheap" {
+		t.Errorf("unexpected ranking: %v", cids)
+	}
+
+	// the benchmark score of the requested device counts
+	request.BlenderFile.Settings.Device = "CPU"
+	weights = RankingWeights{Price: 0.5, Throughput: 0.5, MissingScore: 0.25}
+	if cids := _rankedCIDs(RankOffersWithWeights(request, offers, weights, nil)); cids[0] != "offer-cheap" {
+		t.Errorf("unexpected ranking on the CPU: %v", cids)
+	}
+}
+
+func TestRankOffersPenalizesMissingScores(t *testing.T) {
+	request := &RenderRequest{Version: "4.1.0"}
+	offers := []*RenderOffer{
+		_testRankedOffer("offer-unknown", 1001, 1),
+		_testRankedOffer("offer-benchmarked", 1002, 1, RenderOfferBenchmark{Version: "4.1.0", Device: "CPU", SamplesPerMinute: 100}),
+	}
+
+	// an unknown throughput does not win over a known throughput
+	weights := DefaultRankingWeights()
+	weights.Reliability = 0
+	scored := RankOffersWithWeights(request, offers, weights, nil)
+	if cids := _rankedCIDs(scored); cids[0] != "offer-benchmarked" {
+		t.Fatalf("unexpected ranking: %v", cids)
+	}
+	if scored[1].HasThroughput || scored[1].ThroughputScore != weights.MissingScore {
+		t.Errorf("unexpected throughput score of the unknown offer: %+v", scored[1])
+	}
+
+	// an unknown reliability counts with the missing score
+	successRate := func(accountID string) (float64, bool) {
+		if accountID == "0.0.1001" {
+			return 0.9, true
+		}
+		return 0, false
+	}
+	weights = RankingWeights{Price: 0.5, Reliability: 0.5, MissingScore: 0.25}
+	scored = RankOffersWithWeights(request, offers, weights, successRate)
+	if cids := _rankedCIDs(scored); cids[0] != "offer-unknown" {
+		t.Fatalf("unexpected ranking: %v", cids)
+	}
+	if scored[1].ReliabilityScore != 0.25 || scored[1].Score != 0.625 {
+		t.Errorf("unexpected reliability score: %+v", scored[1])
+	}
+}
+
+func TestOfferBenchmarksSumScenesPerDevice(t *testing.T) {
+	tool := &BlenderBenchmarkTool{}
+	tool.SetResult([]BlenderBenchmarkResult{
+		_testBenchmarkResult("GPU", 300),
+		_testBenchmarkResult("GPU", 200),
+		_testBenchmarkResult("CPU", 50),
+	})
+	offer := &RenderOffer{Blender: map[string]BlenderAppData{"4.1.0": {BenchmarkTool: tool}}}
+
+	benchmarks := _offerBenchmarks(offer)
+	if len(benchmarks) != 2 || benchmarks[0].Device != "CPU" || benchmarks[0].SamplesPerMinute != 50 || benchmarks[1].SamplesPerMinute != 500 {
+		t.Fatalf("unexpected benchmarks: %+v", benchmarks)
+	}
+
+	// the benchmark results of this node are used instead of announced scores
+	offer.Benchmarks = []RenderOfferBenchmark{{Version: "4.1.0", Device: "GPU", SamplesPerMinute: 1}}
+	if throughput, ok := _offerThroughput(offer, &RenderRequest{Version: "4.1.0"}); !ok || throughput != 550 {
+		t.Errorf("got throughput %v, want 550", throughput)
+	}
+}
+
+func TestNetworkOffersHaveAnnouncedBenchmarks(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	nm := &PackageManager{}
+	nm.fetchNetworkObject = func(cid string, path string) error {
+		return os.WriteFile(path, []byte(`{"SchemaVersion":1,"Price":2.5,"BlenderVersions":[{"Version":"4.1.0"}],"Owner":{"Shard":0,"Realm":0,"Account":1001}}`), 0600)
+	}
+	nm._recordOfferAnnouncement("0.0.1001", testOfferCID, []RenderOfferBenchmark{{Version: "4.1.0", Device: "GPU", SamplesPerMinute: 700}}, time.Unix(100, 0))
+
+	offers := nm.GetNetworkOffers()
+	if len(offers) != 1 || offers[0].DocumentCID != testOfferCID {
+		t.Fatalf("unexpected network offers: %+v", offers)
+	}
+	scored := RankOffersWithWeights(&RenderRequest{Version: "4.1.0"}, offers, DefaultRankingWeights(), nil)
+	if len(scored) != 1 || !scored[0].HasThroughput || scored[0].Throughput != 700 {
+		t.Fatalf("unexpected ranking of the network offer: %+v", scored)
+	}
+
+	// a single network offer can be looked up by its CID
+	if offer, err := nm.GetNetworkOffer(testOfferCID); err != nil || offer.Price != 2.5 {
+		t.Errorf("unexpected network offer: %+v, %v", offer, err)
+	}
+	if _, err := nm.GetNetworkOffer("QmZ4tDuvesekSs4qM5ZBKpXiZGun7S2CYtEZRB3DYXkjGx"); err == nil {
+		t.Error("expected an error for an offer that was not announced")
+	}
+}
+
+func TestRankingWeightsValidateMissingScore(t *testing.T) {
+	weights := DefaultRankingWeights()
+	if err := weights.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	weights.MissingScore = 1.5
+	if err := weights.Validate(); err == nil {
+		t.Error("expected an error for a missing score above 1")
+	}
+}
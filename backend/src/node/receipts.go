@@ -0,0 +1,241 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the status handling of the transactions, which change the
+state of a render offer or render request (submit, pause, and cancel).
+
+The state of a render document is only changed after its transaction succeeded:
+
+  (1) If the transaction was executed by this node, the status of its receipt
+      is checked right away. Any status other than SUCCESS is an error and the
+      render document is not changed.
+
+  (2) If the transaction was returned for the signature by the operator's
+      wallet, there is no receipt yet. The state change is deferred, until the
+      status check of the pending transactions finds the transaction with the
+      status SUCCESS on the mirror node (see the transaction history). Failed
+      and expired transactions are discarded without changing the document.
+
+A state change is refused, while the same state change of the render document
+waits for the confirmation of its transaction. The deferred state changes are
+applied under the renderer lock, like the state changes of the JSON-RPC
+methods.
+
+The pending transactions are only kept in memory. If the app is restarted
+before the status of a transaction is known, the state change is lost and the
+transaction needs to be repeated.
+
+*/
+
+import (
+
+	// standard
+	"fmt"
+	"sort"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/hedera"
+	"renderhive/logger"
+)
+
+// Transaction of a render document, which was returned for signing
+type PendingTransaction struct {
+	TransactionID    string    // ID of the transaction
+	Document         string    // CID of the render document
+	Action           string    // state change of the render document (e.g., "submit")
+	CreatedTimestamp time.Time // the datetime the transaction was returned for signing
+
+	apply func(receipt *hederasdk.TransactionReceipt) // state change after the transaction succeeded
+}
+
+// TRANSACTION RECEIPTS
+// #############################################################################
+// Apply the state change of a render document after its transaction succeeded
+// NOTE: Without a receipt, the transaction was returned for signing and the
+// state change is deferred until the transaction is confirmed.
+func (nm *PackageManager) ApplyTransaction(receipt *hederasdk.TransactionReceipt, transactionBytes []byte, document string, action string, apply func(receipt *hederasdk.TransactionReceipt)) error {
+
+	// the transaction was executed by this node
+	if receipt != nil {
+		if receipt.Status != hederasdk.StatusSuccess {
+			return newRenderError(ErrTransactionFailed, "Could not %v '%v': Receipt status '%v'.", action, document, receipt.Status.String())
+		}
+		apply(receipt)
+		return nil
+	}
+
+	// the transaction was returned for signing
+	if len(transactionBytes) == 0 {
+		return newRenderError(ErrTransactionFailed, "Could not %v '%v': The transaction returned neither a receipt nor transaction bytes.", action, document)
+	}
+	transactionID, err := hedera.TransactionIDFromBytes(transactionBytes)
+	if err != nil {
+		return newRenderError(ErrTransactionFailed, "Could not %v '%v': %w", action, document, err)
+	}
+
+	// lock the pending transactions
+	nm.pendingMutex.Lock()
+	defer nm.pendingMutex.Unlock()
+
+	if nm.pendingTransactions == nil {
+		nm.pendingTransactions = make(map[string]*PendingTransaction)
+	}
+	nm.pendingTransactions[transactionID] = &PendingTransaction{
+		TransactionID:    transactionID,
+		Document:         document,
+		Action:           action,
+		CreatedTimestamp: time.Now(),
+		apply:            apply,
+	}
+
+	// log event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf("Waiting for the confirmation of transaction %v to %v '%v'.", transactionID, action, document))
+
+	return nil
+
+}
+
+// Check the status of the pending transactions on the mirror node
+func (nm *PackageManager) CheckPendingTransactions() error {
+
+	// check at most once per poll interval
+	if time.Since(nm.lastTransactionCheck) < RENDERHIVE_CONFIG_TRANSACTION_POLL_INTERVAL {
+		return nil
+	}
+	nm.lastTransactionCheck = time.Now()
+
+	// skip the mirror node query, if nothing is pending
+	nm.pendingMutex.Lock()
+	pending := len(nm.pendingTransactions)
+	nm.pendingMutex.Unlock()
+	if pending == 0 || hedera.Manager.MirrorNode.URL == "" {
+		return nil
+	}
+
+	// get the status of the transactions from the mirror node
+	err := hedera.Manager.History.Refresh(&hedera.Manager.MirrorNode)
+	if err != nil {
+		return err
+	}
+	nm.ResolvePendingTransactions(hedera.Manager.History.Status)
+
+	return nil
+
+}
+
+// Apply or discard the pending transactions with a known status
+func (nm *PackageManager) ResolvePendingTransactions(status func(transactionID string) (string, bool)) {
+
+	// collect the transactions with a known status
+	nm.pendingMutex.Lock()
+	resolved := []*PendingTransaction{}
+	results := map[string]string{}
+	for transactionID, transaction := range nm.pendingTransactions {
+		result, ok := status(transactionID)
+		if !ok || result == hedera.TRANSACTION_STATUS_PENDING {
+			continue
+		}
+		resolved = append(resolved, transaction)
+		results[transactionID] = result
+		delete(nm.pendingTransactions, transactionID)
+	}
+	nm.pendingMutex.Unlock()
+
+	// apply the state changes outside of the lock of the pending transactions,
+	// since they save the documents, but under the lock of the render data
+	for _, transaction := range resolved {
+		result := results[transaction.TransactionID]
+		if result != hederasdk.StatusSuccess.String() {
+
+			// log event
+			logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Transaction %v to %v '%v' failed with status '%v'. The render document was not changed.", transaction.TransactionID, transaction.Action, transaction.Document, result))
+			continue
+
+		}
+
+		receipt := &hederasdk.TransactionReceipt{Status: hederasdk.StatusSuccess}
+		if transactionID, err := hederasdk.TransactionIdFromString(transaction.TransactionID); err == nil {
+			receipt.TransactionID = &transactionID
+		}
+		nm.Renderer.Mutex.Lock()
+		transaction.apply(receipt)
+		nm.Renderer.Mutex.Unlock()
+
+		// log event
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf("Transaction %v to %v '%v' was confirmed.", transaction.TransactionID, transaction.Action, transaction.Document))
+	}
+
+}
+
+// Get the pending transactions ordered by their creation
+func (nm *PackageManager) PendingTransactions() []PendingTransaction {
+
+	// lock the pending transactions
+	nm.pendingMutex.Lock()
+	defer nm.pendingMutex.Unlock()
+
+	list := make([]PendingTransaction, 0, len(nm.pendingTransactions))
+	for _, transaction := range nm.pendingTransactions {
+		list = append(list, *transaction)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedTimestamp.Before(list[j].CreatedTimestamp)
+	})
+
+	return list
+
+}
+
+// helper function to refuse a state change of a render document, which waits for its transaction
+func (nm *PackageManager) _refusePending(document string, action string) error {
+
+	if transactionID, ok := nm._isPending(document, action); ok {
+		return newRenderError(ErrAlreadySubmitted, "Could not %v '%v': It waits for the confirmation of transaction %v.", action, document, transactionID)
+	}
+
+	return nil
+
+}
+
+// helper function to check if a state change of a render document waits for its transaction
+func (nm *PackageManager) _isPending(document string, action string) (string, bool) {
+
+	// lock the pending transactions
+	nm.pendingMutex.Lock()
+	defer nm.pendingMutex.Unlock()
+
+	for transactionID, transaction := range nm.pendingTransactions {
+		if transaction.Document == document && transaction.Action == action {
+			return transactionID, true
+		}
+	}
+
+	return "", false
+
+}
@@ -0,0 +1,114 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	"renderhive/hedera"
+	"renderhive/logger"
+)
+
+// helper function to create a render node with pending transactions
+func _testPendingManager(t *testing.T, applied map[string]bool, transactionIDs ...string) *PackageManager {
+	t.Helper()
+	logger.Manager.Init()
+
+	nm := &PackageManager{pendingTransactions: make(map[string]*PendingTransaction)}
+	for _, transactionID := range transactionIDs {
+		transactionID := transactionID
+		nm.pendingTransactions[transactionID] = &PendingTransaction{
+			TransactionID:    transactionID,
+			Document:         "document-" + transactionID,
+			Action:           "submit the render request",
+			CreatedTimestamp: time.Now(),
+			apply: func(receipt *hederasdk.TransactionReceipt) {
+
+				// the render data is locked while the state changes
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				defer cancel()
+				if err := nm.Renderer.Mutex.LockContext(ctx); err == nil {
+					nm.Renderer.Mutex.Unlock()
+					t.Errorf("transaction %v was applied without the renderer lock", transactionID)
+				}
+				applied[transactionID] = receipt.Status == hederasdk.StatusSuccess
+			},
+		}
+	}
+
+	return nm
+}
+
+func TestResolvePendingTransactions(t *testing.T) {
+	applied := map[string]bool{}
+	nm := _testPendingManager(t, applied, "success", "failed", "pending", "unknown")
+	status := map[string]string{
+		"success": hederasdk.StatusSuccess.String(),
+		"failed":  hederasdk.StatusInvalidSignature.String(),
+		"pending": hedera.TRANSACTION_STATUS_PENDING,
+	}
+	nm.ResolvePendingTransactions(func(transactionID string) (string, bool) {
+		result, ok := status[transactionID]
+		return result, ok
+	})
+
+	// only the successful transaction changes the render document
+	if len(applied) != 1 || !applied["success"] {
+		t.Errorf("got applied transactions %v, want only the successful one", applied)
+	}
+
+	// the transactions without a final status wait for the next check
+	pending := nm.PendingTransactions()
+	if len(pending) != 2 {
+		t.Fatalf("got %v pending transactions, want 2", len(pending))
+	}
+	for _, transaction := range pending {
+		if transaction.TransactionID != "pending" && transaction.TransactionID != "unknown" {
+			t.Errorf("transaction %v must not be pending anymore", transaction.TransactionID)
+		}
+	}
+}
+
+func TestRefusePendingStateChange(t *testing.T) {
+	nm := _testPendingManager(t, map[string]bool{}, "tx")
+
+	// the same state change of the document waits for its transaction
+	if err := nm._refusePending("document-tx", "submit the render request"); !errors.Is(err, ErrAlreadySubmitted) {
+		t.Errorf("got %v, want the state change to be refused", err)
+	}
+
+	// other state changes and documents are not affected
+	if err := nm._refusePending("document-tx", "cancel the render request"); err != nil {
+		t.Errorf("got %v for another state change", err)
+	}
+	if err := nm._refusePending("other", "submit the render request"); err != nil {
+		t.Errorf("got %v for another document", err)
+	}
+}
@@ -0,0 +1,257 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the functions to split a single frame into regions, which are
+rendered by different nodes, and to composite the rendered regions into the full
+frame again.
+
+Region subtasks:
+  A frame is split into a grid of rows x columns regions. The regions are indexed
+  row by row, starting at the top left of the frame. Each region is a subtask of
+  the render request (numbered region index + 1), which is claimed and rendered
+  like any other subtask (see subtasks.go). The region is passed to Blender as
+  the render border and the cropped region images are composited into the full
+  frame, when the subtask results are aggregated.
+
+Consensus hash:
+  Each region result is identified by the CID of its image file. The hash of the
+  combined result is the SHA-256 hash of the region CIDs concatenated in region
+  order (separated by a newline). It does not depend on how the final frame is
+  encoded and can be verified by each node from the region CIDs alone.
+
+*/
+
+import (
+
+	// standard
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	// external
+
+	// internal
+	"renderhive/ipfs"
+	"renderhive/logger"
+)
+
+// Render region of a frame
+// NOTE: The coordinates are relative to the frame size (0.0 ... 1.0) like in Blender (y-axis starts at the bottom).
+type RenderRegion struct {
+	Index int     // Index of the region in the frame
+	MinX  float64 // Left border of the region
+	MaxX  float64 // Right border of the region
+	MinY  float64 // Bottom border of the region
+	MaxY  float64 // Top border of the region
+
+	// Region result
+	CID  string // Content identifier (CID) of the rendered region on the IPFS
+	Path string // Local path of the rendered region
+}
+
+// RENDER REGIONS
+// #############################################################################
+// Split a frame into a grid of regions
+func SplitFrame(rows int, columns int) ([]RenderRegion, error) {
+
+	// check the grid size
+	if rows < 1 || columns < 1 {
+		return nil, newRenderError(ErrInvalidArgument, "Invalid region grid '%vx%v'.", rows, columns)
+	}
+
+	// create the regions row by row, starting at the top of the frame
+	regions := []RenderRegion{}
+	for row := 0; row < rows; row++ {
+		for column := 0; column < columns; column++ {
+			regions = append(regions, RenderRegion{
+				Index: row*columns + column,
+				MinX:  float64(column) / float64(columns),
+				MaxX:  float64(column+1) / float64(columns),
+				MinY:  1.0 - float64(row+1)/float64(rows),
+				MaxY:  1.0 - float64(row)/float64(rows),
+			})
+		}
+	}
+
+	return regions, nil
+
+}
+
+// Validate the region coordinates
+func (region *RenderRegion) Validate() error {
+
+	// check the value range
+	for _, value := range []float64{region.MinX, region.MaxX, region.MinY, region.MaxY} {
+		if math.IsNaN(value) || value < 0.0 || value > 1.0 {
+			return newRenderError(ErrInvalidArgument, "Invalid region %v: Coordinates must be between 0.0 and 1.0.", region.Index)
+		}
+	}
+
+	// check the region size
+	if region.MinX >= region.MaxX || region.MinY >= region.MaxY {
+		return newRenderError(ErrInvalidArgument, "Invalid region %v: Region is empty.", region.Index)
+	}
+
+	return nil
+
+}
+
+// Get the Blender command line arguments to render only this region
+func (region *RenderRegion) BlenderArgs() ([]string, error) {
+	var err error
+
+	// NOTE: Blender has no command line flag for the render border. Therefore, the
+	//       border is set by a Python expression, which is generated internally
+	//       from the numeric coordinates only (no user input is passed to Python).
+
+	// validate the coordinates
+	err = region.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	// generate the Python expression
+	expression := fmt.Sprintf(
+		"import bpy; r = bpy.context.scene.render; r.use_border = True; r.use_crop_to_border = True; r.border_min_x = %f; r.border_max_x = %f; r.border_min_y = %f; r.border_max_y = %f",
+		region.MinX, region.MaxX, region.MinY, region.MaxY,
+	)
+
+	return []string{"--python-expr", expression}, err
+
+}
+
+// Get the pixel rectangle of the region in a frame of the given resolution
+func (region *RenderRegion) Rectangle(resolutionX int, resolutionY int) image.Rectangle {
+
+	// convert the relative coordinates to pixels (the image y-axis starts at the top)
+	x0 := int(math.Round(region.MinX * float64(resolutionX)))
+	x1 := int(math.Round(region.MaxX * float64(resolutionX)))
+	y0 := int(math.Round((1.0 - region.MaxY) * float64(resolutionY)))
+	y1 := int(math.Round((1.0 - region.MinY) * float64(resolutionY)))
+
+	return image.Rect(x0, y0, x1, y1)
+
+}
+
+// COMPOSITING
+// #############################################################################
+// Composite the rendered regions (cropped PNG files) into the full frame
+// NOTE: The CID of each region file is verified before it is used
+func CompositeRegions(regions []RenderRegion, resolutionX int, resolutionY int, outputPath string) (string, error) {
+	var err error
+
+	// log event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf("Compositing %v render regions into '%v' ...", len(regions), outputPath))
+
+	// create the full frame
+	frame := image.NewRGBA(image.Rect(0, 0, resolutionX, resolutionY))
+
+	// sort the regions by index
+	sorted := make([]RenderRegion, len(regions))
+	copy(sorted, regions)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Index < sorted[j].Index })
+
+	// draw each region
+	for _, region := range sorted {
+
+		// verify the CID of the region file
+		cid, err := ipfs.Manager.GetHashFromPath(region.Path)
+		if err != nil {
+			return "", err
+		}
+		if cid != region.CID {
+			return "", newRenderError(ErrDocumentMismatch, "Region %v could not be verified: Expected CID '%v', but got '%v'.", region.Index, region.CID, cid)
+		}
+
+		// decode the region image
+		file, err := os.Open(region.Path)
+		if err != nil {
+			return "", err
+		}
+		regionImage, err := png.Decode(file)
+		file.Close()
+		if err != nil {
+			return "", newRenderError(ErrInvalidArgument, "Region %v could not be decoded: %w", region.Index, err)
+		}
+
+		// check the size of the region image
+		rectangle := region.Rectangle(resolutionX, resolutionY)
+		if regionImage.Bounds().Dx() != rectangle.Dx() || regionImage.Bounds().Dy() != rectangle.Dy() {
+			return "", newRenderError(ErrDocumentMismatch, "Region %v has an unexpected size of %vx%v pixels.", region.Index, regionImage.Bounds().Dx(), regionImage.Bounds().Dy())
+		}
+
+		// draw the region into the frame
+		draw.Draw(frame, rectangle, regionImage, regionImage.Bounds().Min, draw.Src)
+
+		// log event
+		logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf(" [#] Region %v: %v", region.Index, region.CID))
+
+	}
+
+	// write the frame
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	err = png.Encode(file, frame)
+	if err != nil {
+		return "", err
+	}
+
+	// calculate the hash of the combined result
+	hash := RegionsHash(sorted)
+
+	// log event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Combined result hash: %v", hash))
+
+	return hash, err
+
+}
+
+// Calculate the consensus hash of the combined result from the region CIDs
+func RegionsHash(regions []RenderRegion) string {
+
+	// sort the regions by index
+	sorted := make([]RenderRegion, len(regions))
+	copy(sorted, regions)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Index < sorted[j].Index })
+
+	// concatenate the CIDs in region order
+	cids := []string{}
+	for _, region := range sorted {
+		cids = append(cids, region.CID)
+	}
+	hash := sha256.Sum256([]byte(strings.Join(cids, "\n")))
+
+	return hex.EncodeToString(hash[:])
+
+}
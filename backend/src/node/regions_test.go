@@ -0,0 +1,153 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	// internal
+	. "renderhive/globals"
+)
+
+func TestCreateRenderJobsSplitsRegions(t *testing.T) {
+	nm := &PackageManager{}
+	jobs := nm.CreateRenderJobs(&SubmitRenderRequestArgs{
+		RenderRequestCID: testOfferCID,
+		FrameStart:       1,
+		FrameEnd:         10,
+		FrameStep:        1,
+		RegionRows:       2,
+		RegionColumns:    3,
+	}, time.Now())
+	if len(jobs) != 6 {
+		t.Fatalf("got %v jobs, want one per region", len(jobs))
+	}
+
+	for i, job := range jobs {
+		if job.SubtaskIndex() != i+1 || job.Subtask.Region == nil || job.Subtask.Region.Index != i {
+			t.Fatalf("job %v: unexpected subtask %+v", i, job.Subtask)
+		}
+		if job.Subtask.FrameStart != 1 || job.Subtask.FrameEnd != 10 {
+			t.Errorf("job %v: a region subtask must render the full frame range", i)
+		}
+		if job.Subtask.Share != 1.0/6.0 {
+			t.Errorf("job %v: got share %v, want 1/6", i, job.Subtask.Share)
+		}
+	}
+
+	// the first region is at the top left of the frame
+	region := jobs[0].Subtask.Region
+	if region.MinX != 0 || region.MaxY != 1 {
+		t.Errorf("unexpected first region %+v", region)
+	}
+}
+
+func TestCreateRenderJobsWithoutRegions(t *testing.T) {
+	nm := &PackageManager{}
+	jobs := nm.CreateRenderJobs(&SubmitRenderRequestArgs{RenderRequestCID: testOfferCID, FrameStart: 1, FrameEnd: 10, FrameStep: 1}, time.Now())
+	if len(jobs) != 1 || jobs[0].Subtask != nil {
+		t.Fatalf("got %v jobs, want a single job", len(jobs))
+	}
+}
+
+func TestFrameSettingsRegion(t *testing.T) {
+	nm := &PackageManager{}
+	jobs := nm.CreateRenderJobs(&SubmitRenderRequestArgs{
+		RenderRequestCID: testOfferCID,
+		FrameStart:       1,
+		FrameEnd:         1,
+		FrameStep:        1,
+		RegionRows:       2,
+		RegionColumns:    2,
+	}, time.Now())
+	settings := jobs[3].FrameSettings()
+	if settings.Region == nil || settings.Region.Index != 3 || settings.FileFormat != "PNG" {
+		t.Fatalf("unexpected settings of a region subtask: %+v", settings)
+	}
+
+	// the settings hold a copy of the region
+	if settings.Region == jobs[3].Subtask.Region {
+		t.Error("the settings must not share the region of the subtask")
+	}
+}
+
+func TestBlenderRenderArgumentsRegion(t *testing.T) {
+	region := &RenderRegion{Index: 1, MinX: 0.5, MaxX: 1, MinY: 0.5, MaxY: 1}
+	args, err := BlenderRenderArguments("scene.blend", "frame_####", RenderSettings{FrameStart: 1, FrameEnd: 1, Region: region})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the render border is set after the denylist check and before the render
+	n := len(args)
+	if args[n-1] != "-a" || args[n-3] != "--python-expr" {
+		t.Fatalf("got %v, want the render border before the render", args)
+	}
+	if !strings.Contains(args[n-2], "r.use_border = True") || !strings.Contains(args[n-2], "r.border_min_x = 0.500000") {
+		t.Errorf("unexpected render border expression: %v", args[n-2])
+	}
+
+	// an invalid region is rejected
+	_, err = BlenderRenderArguments("scene.blend", "frame_####", RenderSettings{FrameStart: 1, FrameEnd: 1, Region: &RenderRegion{MinX: 1, MaxX: 0.5, MaxY: 1}})
+	if !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("got %v, want an invalid argument error", err)
+	}
+}
+
+func TestSetRegionGrid(t *testing.T) {
+	request := &RenderRequest{}
+	if err := request.SetRegionGrid(-1, 2); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("got %v, want an error for a negative grid", err)
+	}
+
+	// a single dimension does not split the other one
+	if err := request.SetRegionGrid(2, 0); err != nil {
+		t.Fatal(err)
+	}
+	if request.RegionRows != 2 || request.RegionColumns != 1 {
+		t.Errorf("got grid %vx%v, want 2x1", request.RegionRows, request.RegionColumns)
+	}
+
+	// regions and frame chunks cannot be combined
+	if err := request.SetFramesPerTask(5); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("got %v, want an error for frame chunks of a split frame", err)
+	}
+	request = &RenderRequest{FramesPerTask: 5}
+	if err := request.SetRegionGrid(2, 2); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("got %v, want an error for regions of frame chunks", err)
+	}
+}
+
+func TestRegionsHashIsIndependentOfTheOrder(t *testing.T) {
+	regions := []RenderRegion{{Index: 0, CID: "a"}, {Index: 1, CID: "b"}}
+	reversed := []RenderRegion{regions[1], regions[0]}
+	if RegionsHash(regions) != RegionsHash(reversed) {
+		t.Error("the hash must not depend on the order of the regions")
+	}
+	if RegionsHash(regions) == RegionsHash([]RenderRegion{{Index: 0, CID: "b"}, {Index: 1, CID: "a"}}) {
+		t.Error("the hash must depend on the region CIDs")
+	}
+}
@@ -24,18 +24,20 @@ import (
 
 	// standard
 	"bufio"
+	"context"
 	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	gopath "path"
 	"path/filepath"
+	"reflect"
 	"regexp"
-	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	// external
@@ -53,6 +55,7 @@ import (
 	"renderhive/hedera"
 	"renderhive/ipfs"
 	"renderhive/logger"
+	"renderhive/metrics"
 	. "renderhive/utility"
 )
 
@@ -68,19 +71,30 @@ type BlenderAppData struct {
 	BuildHash    string // Build hash of this Blender app
 	BuildDate    string // Build date of this Blender app
 	BuildTime    string // Build time of this Blender app
+	Verified     bool   // True, if the build info was read from the Blender app and matches the Renderhive archive
 
 	// Render settings supported by this node's Blender instance
-	Engines []string // Supported render engines
-	Devices []string // Supported devices
-	Threads uint8    // Supported number of threads
+	Engines     []string // Supported render engines
+	FeatureSets []string // Supported feature sets of the render engines
+	Devices     []string // Supported devices
+	Threads     uint8    // Supported number of threads
+
+	// Process environment
+	Dir string   // working directory of the Blender process (empty = working directory of the app)
+	Env []string // environment variables of the Blender process in addition to the app's environment ("KEY=value")
 
 	// Process status
-	Cmd     *exec.Cmd     // pointer to the exec.Command type
-	Param   []string      // Command line options this Blender process was called with
-	PID     int           // PID of the process
-	Running bool          // Is the process still running
-	StdOut  io.ReadCloser // Command-line standard output of the Blender app
-	StdErr  io.ReadCloser // Command-line error output of the Blender app
+	Cmd         *exec.Cmd     // pointer to the exec.Command type
+	Param       []string      // Command line options this Blender process was called with
+	PID         int           // PID of the process
+	Running     bool          // Is the process still running
+	ExitCode    int           // Exit code of the finished process (-1, if it was killed by a signal)
+	OutOfMemory bool          // True, if the process ran out of memory
+	StdOut      io.ReadCloser // Command-line standard output of the Blender app
+	StdErr      io.ReadCloser // Command-line error output of the Blender app
+	done        chan struct{} // closed, when the process finished
+	stopped     bool          // True, if the process was stopped by this node
+	exitErr     error         // error of the finished process (nil, if it did not crash)
 
 	// Blender render status
 	Frame  string // Current frame number
@@ -89,6 +103,9 @@ type BlenderAppData struct {
 	Time   string // Render time
 	Note   string // Render status note
 
+	// CID of the render request document, which is rendered (empty, if no render job)
+	RenderJob string
+
 	// Blender benchmarks
 	BenchmarkTool *BlenderBenchmarkTool // Blender benchmark results
 
@@ -101,9 +118,15 @@ type BlenderFileData struct {
 	// General info
 	CID  string // Content identifier (CID) of the .blend file on the IPFS
 	Path string // Local path to the Blender file
+	Size int64  // Size of the Blender file in bytes (0, if not known)
 
 	// Render settings
-	Settings RenderSettings // Render settings of this Blender file
+	Settings RenderSettings         // Render settings of this Blender file (of the active scene)
+	Scene    string                 // Name of the active scene of this Blender file
+	Scenes   []BlenderSceneSettings // Render settings of all scenes of this Blender file
+
+	// External dependencies
+	Dependencies []BlenderFileDependency // External file dependencies of this Blender file
 
 }
 
@@ -171,7 +194,19 @@ type BlenderBenchmarkResult struct {
 }
 
 type BlenderBenchmarkTool struct {
+	// NOTE: Use GetResult() to read the result, while a benchmark may run.
 	Result []BlenderBenchmarkResult
+
+	// Synchronization of concurrent benchmarks and result access
+	mutex   sync.RWMutex // guards the result
+	running sync.Mutex   // held while a benchmark runs
+
+	// Download cache of the Blender versions and benchmark scenes
+	CacheDirectory string `json:"-"` // cache directory (empty = default directory in the app data)
+	NoCache        bool   `json:"-"` // download the Blender versions and scenes again, even if cached
+
+	// Blender benchmark launcher
+	LauncherFile string `json:"-"` // launcher passed by the operator (empty = find or download the launcher)
 }
 
 // Blender render settings
@@ -186,11 +221,29 @@ type RenderSettings struct {
 	ResolutionY int    // y resolution of the render result
 	TileX       int    // x resolution of tiles to be rendered
 	TileY       int    // y resolution of tiles to be rendered
+	Samples     int    // number of samples per pixel (0, if not known)
+	FrameStart  int    // first frame to be rendered
+	FrameEnd    int    // last frame to be rendered
+	FrameStep   int    // number of frames between two rendered frames
+
+	// Render region (border) of the frame assigned to this node
+	Region *RenderRegion // nil, if the full frame is rendered
 
 	OutputPath string // Output path (includes file naming)
+	FileFormat string // File format of the render result (e.g., PNG, OPEN_EXR)
 
 }
 
+// enum for the render job states
+const (
+	RENDER_JOB_STATE_QUEUED    int = iota // job is waiting in the queue
+	RENDER_JOB_STATE_CLAIMED              // job was claimed by this node
+	RENDER_JOB_STATE_RENDERING            // job is rendered by this node
+	RENDER_JOB_STATE_COMPLETED            // job was rendered successfully
+	RENDER_JOB_STATE_RELEASED             // job was abandoned and released to the network
+	RENDER_JOB_STATE_FAILED               // job failed, because the Blender process crashed
+)
+
 // a render job claimed for rendering on the render hive by this node
 type RenderJob struct {
 
@@ -200,9 +253,25 @@ type RenderJob struct {
 
 	// Request data
 	Request *RenderRequest // Render request
-	// Job status
-	// ...
 
+	// Job status
+	State            int                // State of the render job (RENDER_JOB_STATE_*)
+	ClaimedTimestamp time.Time          // The datetime this job was claimed by this node
+	Deadline         time.Time          // The datetime after which this node abandons the job
+	Attempts         int                // Number of render attempts that timed out (network wide)
+	Flagged          bool               // True, if the job timed out too often
+	RenderAttempts   []RenderJobAttempt // Render attempts of this node (see crashes.go)
+	Blender          *BlenderAppData    // Blender instance rendering this job
+	Offer            *RenderOffer       // Render offer of this node the job is rendered for (nil, if not claimed by this node)
+	GPUs             []string           // GPUs assigned to this job (empty = all GPUs of the node)
+	Result           *RenderResult      // Result of this node for the render job
+
+	// Subtask data
+	Subtask  *RenderSubtask // Frames of the render request rendered by this job (nil, if the request is not split)
+	Operator string         // Account ID of the node that claimed or completed this job
+
+	// guards the state, while the job is rendered (see _state and _setState)
+	stateMutex sync.Mutex
 }
 
 // a render job that is requested by this node for rendering on the render hive
@@ -213,9 +282,12 @@ type RenderRequest struct {
 	ID int `json:"-"` // Internal ID for the render request management
 
 	// General info
+	SchemaVersion      int       // Schema version of the render request document
 	DocumentCID        string    `json:"-"` // content identifier (CID) of the render request document on the IPFS
 	DocumentPath       string    `json:"-"` // local path of the render request document on this node
 	DirectoryCID       string    // content identifier (CID) of the render request directory on IPFS
+	Providers          int       // Number of DHT providers of the render request directory observed at deploy
+	ProvidersTimestamp time.Time // The datetime the DHT providers were observed
 	CreatedTimestamp   time.Time // The datetime this request was created
 	ModifiedTimestamp  time.Time // The datetime this request was last modified
 	SubmittedTimestamp time.Time `json:"-"` // The datetime this request was submitted to the network
@@ -224,14 +296,26 @@ type RenderRequest struct {
 	// Project files
 	Files       map[string]files.Node `json:"-"`
 	Directory   files.Directory       `json:"-"`
+	filePaths   map[string]string     // local paths of the files added with AddFile (to open them again)
 	BlenderFile BlenderFileData       // data of the Blender file to be rendered
 
 	// Render request data
 	// TODO: Prices need to be implemented using Decimals instead float ("apd" package or "currency" package?)
-	Version   string  // Blender version the job should be rendered on
-	Price     float64 // Price maximum in cents (USD) per BBP
-	ThisNode  bool    // True, if this node participates in rendering this job
-	Cancelled bool    `json:"-"` // True, if the render request was cancelled
+	Version       string    // Blender version the job should be rendered on
+	Price         float64   // Price maximum in cents (USD) per BBP
+	ThisNode      bool      // True, if this node participates in rendering this job
+	Priority      int       // Priority of this request (jobs with higher priority are rendered first)
+	Deadline      time.Time // The datetime by which all frames must be rendered (zero, if there is none)
+	FramesPerTask int       // Number of frames per subtask (0, if the request is rendered as a single job)
+	RegionRows    int       `json:",omitempty"` // Number of region rows each frame is split into (0, if the frames are not split)
+	RegionColumns int       `json:",omitempty"` // Number of region columns each frame is split into (0, if the frames are not split)
+	Cancelled     bool      `json:"-"`          // True, if the render request was cancelled
+
+	// CID of a python script executed by Blender before rendering (empty, if there is none)
+	SetupScriptCID string `json:",omitempty"`
+
+	// Validation of the Blender file on this node
+	Validation *BlendFileValidation `json:"-"` // nil, if the file was not validated yet
 
 	// Hedera data
 	Owner   *hederasdk.AccountID          // Account ID of the operator who created this render request
@@ -245,27 +329,37 @@ type RenderRequestMessage struct {
 	// General info
 	DocumentCID    string `json:"document_cid"`     // Render request document CID
 	BlenderFileCID string `json:"blender_file_cid"` // Blender file CID
+	Priority       int    `json:"priority"`         // Priority of the render request
+	Deadline       int64  `json:"deadline"`         // Deadline of the render request (unix time, 0 = none)
 
 }
 
 // Supported Blender versions
 type RenderOfferBlenderVersions struct {
-	Version string   // Blender version
-	Engines []string // Render engines supported with this offer
-	Devices []string // Devices supported with this offer
-	Threads uint8    // Threads supported with this offer
+	Version     string   // Blender version
+	Engines     []string // Render engines supported with this offer
+	FeatureSets []string // Feature sets of the render engines supported with this offer
+	Devices     []string // Devices supported with this offer
+	Threads     uint8    // Threads supported with this offer
+	BuildHash   string   // Build hash reported by the Blender app
+	Verified    bool     // True, if the build info of the Blender app was verified
 }
 
 // a render offer that is provided by this node for rendering on the render hive
 type RenderOffer struct {
 
 	// General offer information
+	SchemaVersion      int       // Schema version of the render offer document
 	DocumentCID        string    `json:"-"` // content identifier (CID) of the render offer document on the IPFS
 	DocumentPath       string    `json:"-"` // local path of the render offer document on this node
 	CreatedTimestamp   time.Time // The datetime this offer was created
 	ModifiedTimestamp  time.Time // The datetime this offer was last modified
 	SubmittedTimestamp time.Time `json:"-"` // The datetime this offer was submitted to the network
 	PausedTimestamp    time.Time `json:"-"` // The datetime this offer was paused
+	Providers          int       `json:"-"` // Number of DHT providers of the render offer document observed at deploy
+	ProvidersTimestamp time.Time `json:"-"` // The datetime the DHT providers were observed
+	IPNSKey            string    `json:"-"` // IPNS key pointed at the render offer document on deploy (empty = none)
+	IPNSName           string    `json:"-"` // IPNS name of the render offer (if published)
 
 	// Render offer data
 	// TODO: Prices need to be implemented using Decimals instead float ("apd" package or "currency" package?)
@@ -278,7 +372,9 @@ type RenderOffer struct {
 	// 	Value       float64 // Tax value in %
 
 	// }
-	Paused bool `json:"-"` // True, if the offer is currently paused
+	Benchmarks []RenderOfferBenchmark `json:"-"` // Benchmark scores announced for a render offer of another node
+	Paused     bool                   `json:"-"` // True, if the offer is currently paused
+	Inactive   bool                   `json:"-"` // True, if the offer was deactivated on this node (see 'offer deactivate')
 
 	// Terms of Service
 	// Each node can allow/disallow certain
@@ -299,58 +395,67 @@ func (nm *PackageManager) InitRenderOffers() error {
 
 	// initialize the node's render offers
 	nm.Renderer.Offers = make(map[string]*RenderOffer)
+	nm.Renderer.activeMutex.Lock()
+	nm.Renderer.ActiveOffers = nil
+	nm.Renderer.activeMutex.Unlock()
+
+	// a requester-only node has no render offers
+	if nm.IsRequesterOnly() {
+		return nil
+	}
 
 	// load the render offers from the local file system
 	err = nm.LoadRenderOffers()
 	if err != nil {
-		return errors.New(fmt.Sprintf("Could not load render offers: %v", err))
+		return newRenderError(ErrStorageFailed, "Could not load render offers: %w", err)
 	}
 
 	return err
 
 }
 
-// Load a render offer into memory
+// Load the render offers from the repository into memory
 func (nm *PackageManager) LoadRenderOffers() error {
 	var err error
 
-	// path to the local render offer documents
-	offer_document_directory := filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_LOCAL_OFFERS)
-
-	// if the directory does NOT exist
-	if _, err := os.Stat(offer_document_directory); os.IsNotExist(err) {
-		return errors.New(fmt.Sprintf("Render offer directory '%v' does not exist.", offer_document_directory))
+	// get the render offer documents from the repository
+	documents, err := nm.Repository.LoadOffers()
+	if err != nil {
+		return err
 	}
 
-	// go through all files in the directory
-	err = filepath.Walk(offer_document_directory, func(path string, info os.FileInfo, err error) error {
-
-		// if the file is a regular file
-		if info.Mode().IsRegular() {
-
-			// if the file is a render offer document
-			if matched, _ := regexp.MatchString(`^offer-.*\.json$`, info.Name()); matched {
-
-				// load the render offer from the file
-				err = nm.LoadRenderOfferFromFile(path)
-
-			}
-
-		}
-
-		// log error event
+	// load each render offer
+	for _, document := range documents {
+		err = nm.LoadRenderOfferFromData(document.CID, document.Path, document.Data)
 		if err != nil {
-			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not load render offer %v: %v", path, err))
+			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not load render offer %v: %v", document.Path, err))
+			continue
 		}
 
-		// reset error
-		err = nil
+		// restore the state that is not part of the document
+		offer := nm.Renderer.Offers[document.CID]
+		offer.SubmittedTimestamp = document.SubmittedTimestamp
+		offer.PausedTimestamp = document.ClosedTimestamp
+		offer.Paused = document.State == REPOSITORY_STATE_PAUSED
+		offer.Inactive = document.State == REPOSITORY_STATE_INACTIVE
+
+		// restore the IPNS record, which points to the render offer document
+		if record, ok := ipfs.Manager.GetIPNSRecordByCID(document.CID); ok {
+			offer.IPNSKey = record.Key
+			offer.IPNSName = record.Name
+		}
 
-		return nil
+		// get the Blender binaries of the render offer from the installed versions
+		nm._linkBlenderVersions(offer)
 
-	})
+		// the submitted offers are active again (also the paused ones, which can be
+		// resumed), unless they were deactivated on this node
+		if !offer.SubmittedTimestamp.IsZero() && !offer.Inactive {
+			nm.SetActiveRenderOffer(offer)
+		}
+	}
 
-	return err
+	return nil
 
 }
 
@@ -363,7 +468,7 @@ func (nm *PackageManager) LoadRenderOfferFromFile(path string) error {
 
 	// if the offer does NOT exist
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return errors.New(fmt.Sprintf("Render offer document '%v' does not exist.", path))
+		return newRenderError(ErrDocumentNotFound, "Render offer document '%v' does not exist.", path)
 	}
 
 	// get the CID of the render offer document
@@ -372,17 +477,49 @@ func (nm *PackageManager) LoadRenderOfferFromFile(path string) error {
 		return err
 	}
 
-	// load the render offer document file
-	offer_document_file, err := os.Open(path)
+	// read the render offer document file
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return nm.LoadRenderOfferFromData(offer_document_cid, path, data)
+
+}
+
+// Load a render offer into memory from the render offer document data
+func (nm *PackageManager) LoadRenderOfferFromData(offer_document_cid string, path string, data []byte) error {
+	var err error
+
+	// decode the render offer document
+	offer, err := DecodeRenderOffer(offer_document_cid, path, data)
 	if err != nil {
 		return err
 	}
-	defer offer_document_file.Close()
+
+	// add the render offer to the node's render offers
+	nm.Renderer.Offers[offer_document_cid] = offer
+
+	// add all Blender versions to the offer
+	for _, blender := range offer.BlenderVersions {
+		err = offer.AddBlenderVersion(blender.Version, &blender.Engines, &blender.FeatureSets, &blender.Devices, blender.Threads)
+		if err != nil {
+			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not add Blender v%v to render offer '%v': %v", blender.Version, offer_document_cid, err))
+		}
+	}
+
+	return nil
+
+}
+
+// Create a render offer object from the render offer document data
+func DecodeRenderOffer(offer_document_cid string, path string, data []byte) (*RenderOffer, error) {
+	var err error
 
 	// decode the render offer data from the file
 	// NOTE: The *hedera.AccountID is not supported by the JSON decoder.
 	//		 Therefore, the Owner field is decoded manually using this workaround.
-	var offer struct {
+	var document struct {
 		RenderOffer
 		Owner struct {
 			Shard   uint64 `json:"Shard"`
@@ -394,35 +531,36 @@ func (nm *PackageManager) LoadRenderOfferFromFile(path string) error {
 			// AliasEvmAddress []byte              `json:"AliasEvmAddress"`
 		} `json:"Owner"`
 	}
-	decoder := json.NewDecoder(offer_document_file)
-	err = decoder.Decode(&offer)
+	err = json.Unmarshal(data, &document)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// create the render offer object
-	nm.Renderer.Offers[offer_document_cid] = &RenderOffer{
+	offer := &RenderOffer{
+		SchemaVersion:     document.SchemaVersion,
 		DocumentCID:       offer_document_cid,
 		DocumentPath:      path,
-		CreatedTimestamp:  offer.CreatedTimestamp,
-		ModifiedTimestamp: offer.ModifiedTimestamp,
-		BlenderVersions:   offer.BlenderVersions,
-		Price:             offer.Price,
+		CreatedTimestamp:  document.CreatedTimestamp,
+		ModifiedTimestamp: document.ModifiedTimestamp,
+		BlenderVersions:   document.BlenderVersions,
+		Price:             document.Price,
 		Blender:           make(map[string]BlenderAppData),
 		Owner: &hederasdk.AccountID{
-			Shard:   offer.Owner.Shard,
-			Realm:   offer.Owner.Realm,
-			Account: offer.Owner.Account,
+			Shard:   document.Owner.Shard,
+			Realm:   document.Owner.Realm,
+			Account: document.Owner.Account,
 		},
-		Receipt: offer.Receipt,
+		Receipt: document.Receipt,
 	}
 
-	// add all Blender versions to the offer
-	for _, blender := range offer.BlenderVersions {
-		nm.Renderer.Offers[offer_document_cid].AddBlenderVersion(blender.Version, &blender.Engines, &blender.Devices, blender.Threads)
+	// migrate the render offer from older schema versions
+	err = offer.MigrateSchema()
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return offer, nil
 
 }
 
@@ -430,8 +568,15 @@ func (nm *PackageManager) LoadRenderOfferFromFile(path string) error {
 func (nm *PackageManager) NewRenderOffer(render_price float64) (*RenderOffer, error) {
 	var err error
 
+	// a requester-only node does not render for the render hive
+	err = nm._requireRenderNode("create a render offer")
+	if err != nil {
+		return nil, err
+	}
+
 	// create the render offer object
 	offer := &RenderOffer{
+		SchemaVersion:     RENDERHIVE_DOCUMENT_SCHEMA_VERSION,
 		CreatedTimestamp:  time.Now(),
 		ModifiedTimestamp: time.Now(),
 		BlenderVersions:   []RenderOfferBlenderVersions{},
@@ -445,24 +590,181 @@ func (nm *PackageManager) NewRenderOffer(render_price float64) (*RenderOffer, er
 
 }
 
-// Set the node's active render offer object
+// Add a render offer to the node's active render offers
+// NOTE: Each active offer is paused and announced independently.
 func (nm *PackageManager) SetActiveRenderOffer(offer *RenderOffer) error {
-	var err error
-	var ok bool
 
 	// if the offer does NOT exist
-	if nm.Renderer.ActiveOffer, ok = nm.Renderer.Offers[offer.DocumentCID]; !ok {
-		return errors.New(fmt.Sprintf("Render offer with CID '%v' does not exist.", offer.DocumentCID))
+	if _, ok := nm.Renderer.Offers[offer.DocumentCID]; !ok {
+		return newRenderError(ErrOfferNotFound, "Render offer with CID '%v' does not exist.", offer.DocumentCID)
 	}
 
-	return err
+	// lock the active render offers
+	nm.Renderer.activeMutex.Lock()
+	defer nm.Renderer.activeMutex.Unlock()
+
+	// if the offer is already active
+	for _, active := range nm.Renderer.ActiveOffers {
+		if active == offer {
+			return nil
+		}
+	}
+	nm.Renderer.ActiveOffers = append(nm.Renderer.ActiveOffers, offer)
+
+	return nil
+
+}
+
+// Remove a render offer from the node's active render offers
+func (nm *PackageManager) UnsetActiveRenderOffer(document_cid string) error {
+
+	// lock the active render offers
+	nm.Renderer.activeMutex.Lock()
+	defer nm.Renderer.activeMutex.Unlock()
+
+	for i, offer := range nm.Renderer.ActiveOffers {
+		if _sameCID(offer.DocumentCID, document_cid) {
+			nm.Renderer.ActiveOffers = append(nm.Renderer.ActiveOffers[:i], nm.Renderer.ActiveOffers[i+1:]...)
+			delete(nm.expiredOffers, offer.DocumentCID)
+			return nil
+		}
+	}
+
+	return newRenderError(ErrOfferNotFound, "Render offer with CID '%v' is not active.", document_cid)
+
+}
+
+// Activate a render offer of this node and keep it active after a restart
+func (nm *PackageManager) ActivateRenderOffer(offer *RenderOffer) error {
+
+	err := nm.SetActiveRenderOffer(offer)
+	if err != nil {
+		return err
+	}
+	if offer.Inactive {
+		offer.Inactive = false
+		offer.Save()
+	}
+
+	return nil
+
+}
+
+// Deactivate a render offer of this node and keep it inactive after a restart
+func (nm *PackageManager) DeactivateRenderOffer(document_cid string) error {
+
+	offer, err := nm.GetActiveRenderOffer(document_cid)
+	if err != nil {
+		return err
+	}
+	err = nm.UnsetActiveRenderOffer(offer.DocumentCID)
+	if err != nil {
+		return err
+	}
+	offer.Inactive = true
+	offer.Save()
+
+	return nil
+
+}
+
+// Get the node's active render offer objects
+func (nm *PackageManager) GetActiveRenderOffers() []*RenderOffer {
+
+	// lock the active render offers
+	nm.Renderer.activeMutex.RLock()
+	defer nm.Renderer.activeMutex.RUnlock()
+
+	return append([]*RenderOffer(nil), nm.Renderer.ActiveOffers...)
+
+}
+
+// Get an active render offer object of the node by its document CID
+// NOTE: Without a CID, the only active offer of the node is returned.
+func (nm *PackageManager) GetActiveRenderOffer(document_cid string) (*RenderOffer, error) {
+
+	offers := nm.GetActiveRenderOffers()
+	if len(offers) == 0 {
+		return nil, newRenderError(ErrOfferNotFound, "The node has no active render offer.")
+	}
+
+	// get the only active offer
+	if document_cid == "" {
+		if len(offers) > 1 {
+			return nil, newRenderError(ErrInvalidArgument, "The node has %v active render offers. Select one by its CID.", len(offers))
+		}
+		return offers[0], nil
+	}
+
+	for _, offer := range offers {
+		if _sameCID(offer.DocumentCID, document_cid) {
+			return offer, nil
+		}
+	}
+
+	return nil, newRenderError(ErrOfferNotFound, "Render offer with CID '%v' is not active.", document_cid)
+
+}
+
+// Get the active render offer of this node, which applies best to a render request
+// NOTE: Returns nil, if none of the active (and not paused) offers matches.
+func (nm *PackageManager) BestActiveRenderOffer(request *RenderRequest) *RenderOffer {
+
+	// older render requests do not announce their Blender version, so the
+	// cheapest offer within the price limit applies
+	offers := nm.GetActiveRenderOffers()
+	if request.Version == "" {
+		var best *RenderOffer
+		for _, offer := range offers {
+			if offer.Paused || (request.Price > 0 && offer.Price > request.Price) {
+				continue
+			}
+			if best == nil || offer.Price < best.Price {
+				best = offer
+			}
+		}
+		return best
+	}
+
+	ranked := nm.RankOffers(request, offers)
+	if len(ranked) == 0 {
+		return nil
+	}
+
+	return ranked[0].Offer
+
+}
+
+// helper function to get the name of an active render offer (e.g., for the CLI)
+func _activeOfferName(offer *RenderOffer) string {
+
+	if offer.DocumentCID == "" {
+		return "(not deployed)"
+	}
+
+	return offer.DocumentCID
 
 }
 
-// Get the node's active render offer object
-func (nm *PackageManager) GetActiveRenderOffer() *RenderOffer {
+// helper function to get a Blender version of the active render offers
+// NOTE: If several offers have the Blender version, the offers that are not
+// paused are preferred and then the offer with the smallest CID is used.
+func (nm *PackageManager) _offeredBlender(version string) (BlenderAppData, bool) {
+
+	var found *RenderOffer
+	for _, offer := range nm.GetActiveRenderOffers() {
+		if _, ok := offer.Blender[version]; !ok {
+			continue
+		}
+		if found == nil || (found.Paused && !offer.Paused) || (found.Paused == offer.Paused && offer.DocumentCID < found.DocumentCID) {
+			found = offer
+		}
+	}
+	if found == nil {
+		return BlenderAppData{}, false
+	}
 
-	return nm.Renderer.ActiveOffer
+	return found.Blender[version], true
 
 }
 
@@ -473,7 +775,21 @@ func (nm *PackageManager) GetRenderOffer(document_cid string) (*RenderOffer, err
 	// Get the Render Offer from the CID of the render offer document
 	offer, ok := nm.Renderer.Offers[document_cid]
 	if !ok {
-		return nil, errors.New(fmt.Sprintf("Render offer with CID '%v' does not exist.", document_cid))
+
+		// the CID may be given in another CID version
+		if _, err = ParseCID(document_cid); err != nil {
+			return nil, newRenderError(ErrInvalidArgument, "Invalid render offer CID: %w", err)
+		}
+		for cid, o := range nm.Renderer.Offers {
+			if _sameCID(cid, document_cid) {
+				offer, ok = o, true
+				break
+			}
+		}
+
+	}
+	if !ok {
+		return nil, newRenderError(ErrOfferNotFound, "Render offer with CID '%v' does not exist.", document_cid)
 	}
 
 	// create the render offer object
@@ -492,7 +808,7 @@ func (offer *RenderOffer) AddDocument() error {
 
 	// check if the document was already added
 	if offer.DocumentCID != "" {
-		return errors.New(fmt.Sprintf("Render offer document '%v' already exists.", offer.DocumentCID))
+		return newRenderError(ErrAlreadyExists, "Render offer document '%v' already exists.", offer.DocumentCID)
 	}
 
 	// Prepare the creation of a local render offer document file
@@ -517,9 +833,21 @@ func (offer *RenderOffer) AddDocument() error {
 		defer offer_document_file.Close()
 
 		// write the render offer data into the file in JSON format
+		offer.SchemaVersion = RENDERHIVE_DOCUMENT_SCHEMA_VERSION
 		encoder := json.NewEncoder(offer_document_file)
 		encoder.SetIndent("", "  ")
-		encoder.Encode(offer)
+		err = encoder.Encode(offer)
+		if err != nil {
+			offer._discardDocument()
+			return err
+		}
+
+		// check that other nodes decode the same render offer from the document
+		err = offer._verifyDocument()
+		if err != nil {
+			offer._discardDocument()
+			return err
+		}
 
 		// add the CID of the render offer document to the offer data
 		offer.DocumentCID, err = ipfs.Manager.GetHashFromPath(offer.DocumentPath)
@@ -528,7 +856,7 @@ func (offer *RenderOffer) AddDocument() error {
 		}
 
 	} else {
-		return errors.New(fmt.Sprintf("Render offer document '%v' already exists.", offer.DocumentPath))
+		return newRenderError(ErrAlreadyExists, "Render offer document '%v' already exists.", offer.DocumentPath)
 	}
 
 	return err
@@ -539,14 +867,22 @@ func (offer *RenderOffer) AddDocument() error {
 // NOTE:
 // This makes the render offer document available to the IPFS network.
 // Anyone, who knows the CID, the render offer document.
-// However, the CID is not shared at this point with anyone.
+// However, the CID is not shared at this point with anyone. The caller must
+// hold the lock of the render data, which is released while the DHT providers
+// are probed.
 func (offer *RenderOffer) Deploy() (string, error) {
 	var err error
 
+	// do not deploy a duplicate of an active render offer
+	err = Manager._refuseDuplicateRenderOffer(offer, "deploy")
+	if err != nil {
+		return "", err
+	}
+
 	// add the render request document to the file list
 	err = offer.AddDocument()
 	if err != nil {
-		return "", errors.New(fmt.Sprintf("Could not add render request document: %v", err))
+		return "", newRenderError(ErrNetworkUnavailable, "Could not add render request document: %w", err)
 	}
 
 	// Upload the render request document file to IPFS
@@ -555,10 +891,97 @@ func (offer *RenderOffer) Deploy() (string, error) {
 		return "", err
 	}
 
+	// wait until the render offer document is reachable in the network
+	// NOTE: The document cannot contain its own provider count. Therefore, the
+	//       observation is only kept with the offer on this node.
+	offer.Providers, err = Manager._awaitProviders(ipfs.Manager.ProbeProvidersAsync(offer.DocumentCID, RENDERHIVE_CONFIG_DEPLOY_PROVIDER_TIMEOUT))
+	if err != nil {
+		offer._discardDocument()
+		return "", newRenderError(ErrNetworkUnavailable, "Render offer document is not reachable in the network: %w", err)
+	}
+	offer.ProvidersTimestamp = time.Now()
+
+	// pin the document on the remote pinning service (if enabled)
+	go ipfs.Manager.AutoPinObjectRemote(offer.DocumentCID, filepath.Base(offer.DocumentPath))
+
 	// add the offer to the node's render offers
 	Manager.Renderer.Offers[offer.DocumentCID] = offer
+	offer.Save()
+
+	// point the IPNS record at the latest render offer document (if enabled)
+	// NOTE: The render offer is deployed, even if the record is not published.
+	if offer.IPNSKey != "" {
+		offer.IPNSName, err = ipfs.Manager.PublishIPNS(offer.DocumentCID, offer.IPNSKey)
+		if err != nil {
+			logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Could not publish the IPNS record of render offer '%v': %v", offer.DocumentCID, err))
+		}
+	}
+
+	return offer.DocumentCID, nil
+
+}
+
+// helper function to remove the local render offer document, so the deploy can be repeated
+func (offer *RenderOffer) _discardDocument() {
+
+	// remove the document file
+	err := os.Remove(offer.DocumentPath)
+	if err != nil && !os.IsNotExist(err) {
+		logger.Manager.Package["node"].Error().Msg(err.Error())
+	}
+
+	// reset the document data
+	offer.DocumentCID = ""
+	offer.DocumentPath = ""
+
+}
+
+// helper function to check that the render offer document decodes to the render offer
+func (offer *RenderOffer) _verifyDocument() error {
+	var err error
+
+	// decode the written document as other nodes would
+	data, err := os.ReadFile(offer.DocumentPath)
+	if err != nil {
+		return err
+	}
+	decoded, err := DecodeRenderOffer(offer.DocumentCID, offer.DocumentPath, data)
+	if err != nil {
+		return newRenderError(ErrDocumentMismatch, "Render offer document could not be decoded: %w", err)
+	}
+
+	return offer._compareDocument(decoded)
+
+}
+
+// helper function to compare the key fields of the render offer with a decoded render offer
+func (offer *RenderOffer) _compareDocument(decoded *RenderOffer) error {
 
-	return offer.DocumentCID, err
+	if !decoded.CreatedTimestamp.Equal(offer.CreatedTimestamp) {
+		return newRenderError(ErrDocumentMismatch, "Render offer document does not match the render offer: CreatedTimestamp '%v' != '%v'.", decoded.CreatedTimestamp, offer.CreatedTimestamp)
+	}
+	if !decoded.ModifiedTimestamp.Equal(offer.ModifiedTimestamp) {
+		return newRenderError(ErrDocumentMismatch, "Render offer document does not match the render offer: ModifiedTimestamp '%v' != '%v'.", decoded.ModifiedTimestamp, offer.ModifiedTimestamp)
+	}
+	if decoded.Price != offer.Price {
+		return newRenderError(ErrDocumentMismatch, "Render offer document does not match the render offer: Price '%v' != '%v'.", decoded.Price, offer.Price)
+	}
+	if !reflect.DeepEqual(decoded.BlenderVersions, offer.BlenderVersions) {
+		return newRenderError(ErrDocumentMismatch, "Render offer document does not match the render offer: BlenderVersions '%v' != '%v'.", decoded.BlenderVersions, offer.BlenderVersions)
+	}
+
+	// the owner is decoded without its alias
+	if offer.Owner == nil {
+		return newRenderError(ErrDocumentMismatch, "Render offer has no owner.")
+	}
+	if offer.Owner.AliasKey != nil || offer.Owner.AliasEvmAddress != nil {
+		return newRenderError(ErrDocumentMismatch, "Render offer owner '%v' uses an account alias, which is not supported by the render offer document.", offer.Owner.String())
+	}
+	if decoded.Owner.Shard != offer.Owner.Shard || decoded.Owner.Realm != offer.Owner.Realm || decoded.Owner.Account != offer.Owner.Account {
+		return newRenderError(ErrDocumentMismatch, "Render offer document does not match the render offer: Owner '%v' != '%v'.", decoded.Owner.String(), offer.Owner.String())
+	}
+
+	return nil
 
 }
 
@@ -581,118 +1004,69 @@ func (ro *RenderOffer) GetPrice() float64 {
 }
 
 // Add a Blender version to the render offer
-func (ro *RenderOffer) AddBlenderVersion(version string, engines *[]string, devices *[]string, threads uint8) error {
+// NOTE: Empty engines select all engines of the Blender version and empty
+// feature sets the default feature set.
+func (ro *RenderOffer) AddBlenderVersion(version string, engines *[]string, featureSets *[]string, devices *[]string, threads uint8) error {
 	var err error
 
 	// log event
 	logger.Manager.Package["node"].Debug().Msg("Start adding a Blender version for this node:")
 	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Internal name: %v", version))
 	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Engines: %v", strings.Join(*engines, ",")))
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Feature sets: %v", strings.Join(*featureSets, ",")))
 	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Devices: %v", strings.Join(*devices, ",")))
 
 	// check if the version WAS already added before
 	if _, ok := ro.Blender[version]; ok {
-		return errors.New(fmt.Sprintf("Blender version '%v' already exists. Use 'blender edit' to change parameters.", version))
+		return newRenderError(ErrAlreadyExists, "Blender version '%v' already exists. Use 'blender edit' to change parameters.", version)
 	}
 
 	// check if given engines and devices are valid
 	_, err = GetBlenderEngineEnum(*engines)
 	if err != nil {
-		return errors.New(fmt.Sprintf("At least one of the defined engines '%v' is not valid.", *engines))
+		return newRenderError(ErrInvalidArgument, "At least one of the defined engines '%v' is not valid.", *engines)
 	}
-	_, err = GetBlenderDeviceEnum(*devices)
+	err = CheckBlenderCapabilities(version, engines, featureSets)
 	if err != nil {
-		return errors.New(fmt.Sprintf("At least one of the defined devices '%v' is not valid.", *devices))
+		return err
 	}
-
-	// get the blender version from the map of valid Blender versions
-	blender_bin, ok := RENDERHIVE_BLENDER_ARCHIVE_FILES[version]
-	if !ok {
-		return errors.New(fmt.Sprintf("Blender version '%v' is not supported by the Renderhive network.", version))
+	_, err = GetBlenderDeviceEnum(*devices)
+	if err != nil {
+		return newRenderError(ErrInvalidArgument, "At least one of the defined devices '%v' is not valid.", *devices)
 	}
 
-	// check if the Blender binary is already available on the local file system
-	blender_bin_path := filepath.Join(RENDERHIVE_APP_DIRECTORY_BLENDER_BINARIES, version+"-"+blender_bin.Linux.Commit, "blender")
-	blender_tar_path := filepath.Join(RENDERHIVE_APP_DIRECTORY_TEMP, blender_bin.Linux.Filename)
-	if _, err := os.Stat(blender_bin_path); os.IsNotExist(err) {
-
-		// log info event
-		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Fetching Blender binary for version v%v from IPFS: %v (%v)", version, blender_bin.Linux.Filename, blender_bin.Linux.CID))
-
-		// check if the archiveis also NOT available on the local file system
-		if _, err := os.Stat(blender_tar_path); os.IsNotExist(err) {
-
-			// // download the Blender binary from IPFS
-			// _, err := ipfs.Manager.GetObject(blender_bin.Linux.CID, filepath.Join(RENDERHIVE_APP_DIRECTORY_TEMP, blender_bin.Linux.Filename))
-			// if err != nil {
-			// 	return err
-			// }
-
-			// define the channel for the download progress
-			progress_channel := make(chan float64)
-
-			// download the Blender binary from IPFS in a separate goroutine
-			go func() {
-				err := ipfs.Manager.DownloadFromGateway(blender_bin.Linux.CID, blender_tar_path, progress_channel)
-				if err != nil {
-					logger.Manager.Package["node"].Error().Msg(fmt.Sprintf(" [#] Error downloading the Blender binary v%v: %v", version, err))
-				}
-				close(progress_channel)
-			}()
-
-			// get progress updates from the channel
-			for p := range progress_channel {
-				logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Progress: %.1f %%", p))
-			}
-
-		}
-
-		// log info event
-		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Blender binary for version v%v downloaded to: %v", version, blender_tar_path))
-
-		// TODO: Extract the archive and move the binary to the correct location
-
-		// TODO: Delete the archive after extraction
-
-	} else {
-
-		// log info event
-		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Blender binary for version v%v found: %v", version, blender_bin_path))
-
+	// get the Blender binary from the versions installed on this node
+	// NOTE: The Blender version is installed, if it is not installed yet.
+	installation, err := Manager.InstallBlenderVersion(version)
+	if err != nil {
+		return err
 	}
+	blender_bin_path := installation.Path
 
 	// create the BlenderAppData instance for the new version
 	blender := BlenderAppData{
 		Path:          blender_bin_path,
 		Engines:       *engines,
+		FeatureSets:   *featureSets,
 		Devices:       *devices,
 		Threads:       threads,
 		BenchmarkTool: &BlenderBenchmarkTool{},
 	}
 
-	// // log info event
-	// logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Start Blender v%v and get app data.", version))
-
-	// TODO: Does currently not work in Docker on Apple Silicon, because of Pixar/USD bug,
-	//	     which makes it unable to run Blender (/proc/cpuinfo does not deliver the correct CPU info)
-	// // start this Blender version and query its version and build info
-	// err = blender.Execute([]string{"-v"})
-	// if err != nil {
-	// 	return err
-	// }
-
-	// // wait until the command execution finished
-	// err = blender.Cmd.Wait()
-	// if err != nil {
-	// 	return err
-	// }
+	// query the version and build info of this Blender version
+	// NOTE: Blender may not run in some container environments (e.g., Docker on
+	//       Apple Silicon), in which case the build info remains unverified.
+	blender._probe(version)
 
 	// append to the list of supported Blender versions
 	ro.BlenderVersions = append(ro.BlenderVersions, RenderOfferBlenderVersions{
-		Version: version,
-		Engines: *engines,
-		Devices: *devices,
-		Threads: threads,
+		Version:     version,
+		Engines:     *engines,
+		FeatureSets: *featureSets,
+		Devices:     *devices,
+		Threads:     threads,
+		BuildHash:   blender.BuildHash,
+		Verified:    blender.Verified,
 	})
 
 	// add the new BlenderAppData to the map
@@ -703,11 +1077,63 @@ func (ro *RenderOffer) AddBlenderVersion(version string, engines *[]string, devi
 	logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf(" [#] Build Date: %v", blender.BuildDate))
 	logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf(" [#] Build Time: %v", blender.BuildTime))
 	logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf(" [#] Build Hash: %v", blender.BuildHash))
+	logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf(" [#] Verified: %v", blender.Verified))
 
 	return err
 
 }
 
+// Check the render engines and feature sets against the capabilities of a Blender version
+// NOTE: Empty engines are set to all engines of the version and empty feature
+// sets to the default feature set, if an engine has feature sets.
+func CheckBlenderCapabilities(version string, engines *[]string, featureSets *[]string) error {
+
+	capabilities := GetBlenderCapabilities(version)
+	if len(capabilities) == 0 {
+		return newRenderError(ErrUnsupportedVersion, "The render engines of Blender version '%v' are not known.", version)
+	}
+
+	// select all engines of the version
+	supported := []string{}
+	for _, capability := range capabilities {
+		supported = append(supported, capability.Engine)
+	}
+	if len(*engines) == 0 {
+		*engines = supported
+	}
+
+	// each engine must be supported by the version (the names are normalized to upper case)
+	offered := []string{}
+	for i, engine := range *engines {
+		found := false
+		for _, capability := range capabilities {
+			if strings.EqualFold(capability.Engine, engine) {
+				offered = append(offered, capability.FeatureSets...)
+				(*engines)[i] = capability.Engine
+				found = true
+			}
+		}
+		if !found {
+			return newRenderError(ErrInvalidArgument, "Blender v%v does not support the engine '%v' (supported: %v).", version, engine, strings.Join(supported, ", "))
+		}
+	}
+
+	// select the default feature set
+	if len(*featureSets) == 0 && len(offered) != 0 {
+		*featureSets = []string{BLENDER_DEFAULT_FEATURE_SET}
+	}
+
+	// each feature set must belong to one of the engines
+	for _, featureSet := range *featureSets {
+		if !_containsFold(offered, featureSet) {
+			return newRenderError(ErrInvalidArgument, "Blender v%v does not support the feature set '%v' with the engines %v.", version, featureSet, strings.Join(*engines, ", "))
+		}
+	}
+
+	return nil
+
+}
+
 // Delete a Blender version from the render offer
 func (ro *RenderOffer) DeleteBlenderVersion(version string) error {
 
@@ -739,7 +1165,7 @@ func (ro *RenderOffer) DeleteBlenderVersion(version string) error {
 
 	}
 
-	return errors.New(fmt.Sprintf(" [#] Blender v'%v' could not be removed from the node's render offer.", blender.BuildVersion))
+	return newRenderError(ErrUnsupportedVersion, "Blender v'%v' could not be removed from the node's render offer.", blender.BuildVersion)
 
 }
 
@@ -751,20 +1177,32 @@ func (ro *RenderOffer) DeleteBlenderVersion(version string) error {
 func (offer *RenderOffer) Submit() (*hederasdk.TransactionReceipt, []byte, error) {
 	var err error
 	var transactionBytes []byte
+	var receipt *hederasdk.TransactionReceipt
 
 	// // check if the render offer was already submitted
 	// if offer._isSubmitted() {
 	// 	return nil, nil, errors.New(fmt.Sprintf("Render offer was already submitted and cannot be modified."))
 	// }
 
-	// Submit the message to the render hive network
-	// Prepare the HCS message
-	jsonMessage, err := Manager.EncodeCommand(
+	// do not announce a duplicate of an active render offer
+	err = Manager._refuseDuplicateRenderOffer(offer, "submit")
+	if err != nil {
+		return nil, nil, err
+	}
+	err = Manager._refusePending(offer.DocumentCID, "submit the render offer")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Submit the message to the render hive network
+	// Prepare the HCS message
+	jsonMessage, err := Manager.EncodeCommand(
 		[]string{},
 		SERVICE_NODE,
 		METHOD_NODE_SUBMIT_RENDER_OFFER,
 		&SubmitRenderOfferArgs{
 			RenderOfferCID: offer.DocumentCID,
+			Benchmarks:     _offerBenchmarks(offer),
 		},
 	)
 
@@ -774,20 +1212,26 @@ func (offer *RenderOffer) Submit() (*hederasdk.TransactionReceipt, []byte, error
 	} else {
 
 		// send it to the Renderhive Job Queue topic on Hedera
-		offer.Receipt, transactionBytes, err = Manager.JobQueueTopic.SubmitMessage(string(jsonMessage), "renderhive-v0.1.0::submit-render-offer", nil, hedera.TransactionOptions.SetExecute(false, Manager.User.UserAccount.AccountID))
+		receipt, transactionBytes, err = Manager.JobQueueTopic.SubmitMessage(string(jsonMessage), "renderhive-v0.1.0::submit-render-offer", nil, hedera.TransactionOptions.SetExecute(false, Manager.User.UserAccount.AccountID))
 		if err != nil {
 			logger.Manager.Package["hedera"].Error().Err(err).Msg("")
-			return nil, nil, errors.New(fmt.Sprintf("Render offer %v could not be submitted: %v.", nil, err.Error()))
+			return nil, nil, newRenderError(ErrTransactionFailed, "Render offer %v could not be submitted: %w.", nil, err)
 		}
 
 	}
 
-	// TODO: Temporary workaround – Does not account for failed transactions
-	// 		 We may safe the transactions ID and query the status later?
-	// update the submitted timestamp
-	offer._updateSubmittedTimestamp()
+	// update the submitted timestamp, when the transaction succeeded
+	err = Manager.ApplyTransaction(receipt, transactionBytes, offer.DocumentCID, "submit the render offer", func(receipt *hederasdk.TransactionReceipt) {
+		offer.Receipt = receipt
+		offer._updateSubmittedTimestamp()
+		offer.Inactive = false
+		offer.Save()
+
+		// a submitted offer is one of the node's active offers
+		Manager.SetActiveRenderOffer(offer)
+	})
 
-	return offer.Receipt, transactionBytes, err
+	return receipt, transactionBytes, err
 
 }
 
@@ -797,6 +1241,12 @@ func (offer *RenderOffer) Pause() (*hederasdk.TransactionReceipt, []byte, error)
 	var transactionBytes []byte
 	var receipt *hederasdk.TransactionReceipt
 
+	// check if the render offer waits for the confirmation of a pause
+	err = Manager._refusePending(offer.DocumentCID, "pause the render offer")
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Submit the render offer message to the job queue topic
 	// Prepare the HCS message
 	jsonMessage, err := Manager.EncodeCommand(
@@ -817,14 +1267,16 @@ func (offer *RenderOffer) Pause() (*hederasdk.TransactionReceipt, []byte, error)
 		receipt, transactionBytes, err = Manager.JobQueueTopic.SubmitMessage(string(jsonMessage), "renderhive-v0.1.0::pause-render-offer", nil, hedera.TransactionOptions.SetExecute(false, Manager.User.UserAccount.AccountID))
 		if err != nil {
 			logger.Manager.Package["hedera"].Error().Err(err).Msg("")
-			return nil, nil, errors.New(fmt.Sprintf("Command could not be submitted: %v.", nil, err.Error()))
+			return nil, nil, newRenderError(ErrTransactionFailed, "Command could not be submitted: %w.", err)
 		}
 
 	}
 
-	// TODO: Temporary workaround – Does not account for failed transactions
-	// update the cancelled status and closed timestamp
-	offer._updatePausedTimestamp()
+	// update the paused status and timestamp, when the transaction succeeded
+	err = Manager.ApplyTransaction(receipt, transactionBytes, offer.DocumentCID, "pause the render offer", func(receipt *hederasdk.TransactionReceipt) {
+		offer._updatePausedTimestamp()
+		offer.Save()
+	})
 
 	return receipt, transactionBytes, err
 
@@ -886,57 +1338,56 @@ func (nm *PackageManager) InitRenderRequests() error {
 	// initialize the node's render requests
 	nm.Renderer.Requests = make(map[string]*RenderRequest)
 
+	// load the IDs of the render requests
+	nm.Renderer.RequestIDs, err = LoadRenderRequestIDs(RenderRequestIDsPath())
+	if err != nil {
+		logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not load the render request IDs: %v", err))
+	}
+
 	// load the render requests from the local file system
 	err = nm.LoadRenderRequests()
 	if err != nil {
-		return errors.New(fmt.Sprintf("Could not load render requests: %v", err))
+		return newRenderError(ErrStorageFailed, "Could not load render requests: %w", err)
 	}
 
 	return err
 
 }
 
-// Load a render request into memory
+// Load the render requests from the repository into memory
 func (nm *PackageManager) LoadRenderRequests() error {
 	var err error
 
-	// path to the local render request documents
-	request_document_directory := filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_LOCAL_REQUESTS)
-
-	// if the directory does NOT exist
-	if _, err := os.Stat(request_document_directory); os.IsNotExist(err) {
-		return errors.New(fmt.Sprintf("Render request directory '%v' does not exist.", request_document_directory))
+	// get the render request documents from the repository
+	documents, err := nm.Repository.LoadRequests()
+	if err != nil {
+		return err
 	}
 
-	// go through all files in the directory
-	err = filepath.Walk(request_document_directory, func(path string, info os.FileInfo, err error) error {
-
-		// if the file is a regular file
-		if info.Mode().IsRegular() {
-
-			// if the file is a render request document
-			if matched, _ := regexp.MatchString(`^request-.*\.json$`, info.Name()); matched {
-
-				// load the render request from the file
-				err = nm.LoadRenderRequestFromFile(path)
-
-			}
-
-		}
-
-		// log error event
+	// load each render request
+	for _, document := range documents {
+		err = nm.LoadRenderRequestFromData(document.CID, document.Path, document.Data)
 		if err != nil {
-			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not load render request %v: %v", path, err))
+			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not load render request %v: %v", document.Path, err))
+			continue
 		}
 
-		// reset error
-		err = nil
+		// restore the state that is not part of the document
+		request := nm.Renderer.Requests[document.CID]
+		request.SubmittedTimestamp = document.SubmittedTimestamp
+		request.ClosedTimestamp = document.ClosedTimestamp
+		request.Cancelled = document.State == REPOSITORY_STATE_CANCELLED
 
-		return nil
+		// assign the persistent ID (new ID for documents without an ID)
+		nm._assignRequestID(request)
+	}
 
-	})
+	// remove the IDs of the documents, which no longer exist
+	if nm.Renderer.RequestIDs != nil {
+		nm.Renderer.RequestIDs.Prune()
+	}
 
-	return err
+	return nil
 
 }
 
@@ -949,7 +1400,8 @@ func (nm *PackageManager) NewRenderRequest(blender_version string, render_price
 	// create the render request object
 	return &RenderRequest{
 
-		Files: make(map[string]files.Node),
+		SchemaVersion: RENDERHIVE_DOCUMENT_SCHEMA_VERSION,
+		Files:         make(map[string]files.Node),
 
 		CreatedTimestamp:  time.Now(),
 		ModifiedTimestamp: time.Now(),
@@ -970,7 +1422,7 @@ func (nm *PackageManager) LoadRenderRequestFromFile(path string) error {
 
 	// if the request does NOT exist
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return errors.New(fmt.Sprintf("Render request document '%v' does not exist.", path))
+		return newRenderError(ErrDocumentNotFound, "Render request document '%v' does not exist.", path)
 	}
 
 	// get the CID of the render request document
@@ -979,17 +1431,40 @@ func (nm *PackageManager) LoadRenderRequestFromFile(path string) error {
 		return err
 	}
 
-	// load the render request document file
-	request_document_file, err := os.Open(path)
+	// read the render request document file
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return nm.LoadRenderRequestFromData(request_document_cid, path, data)
+
+}
+
+// Load a render request into memory from the render request document data
+func (nm *PackageManager) LoadRenderRequestFromData(request_document_cid string, path string, data []byte) error {
+	var err error
+
+	// decode the render request document
+	request, err := DecodeRenderRequest(request_document_cid, path, data)
 	if err != nil {
 		return err
 	}
-	defer request_document_file.Close()
+
+	// add the render request to the node's render requests
+	nm.Renderer.Requests[request_document_cid] = request
+
+	return nil
+}
+
+// Create a render request object from the render request document data
+func DecodeRenderRequest(request_document_cid string, path string, data []byte) (*RenderRequest, error) {
+	var err error
 
 	// decode the render offer data from the file
 	// NOTE: The *hedera.AccountID is not supported by the JSON decoder.
 	//		 Therefore, the Owner field is decoded manually using this workaround.
-	var request struct {
+	var document struct {
 		RenderRequest
 		Owner struct {
 			Shard   uint64 `json:"Shard"`
@@ -1001,32 +1476,44 @@ func (nm *PackageManager) LoadRenderRequestFromFile(path string) error {
 			// AliasEvmAddress []byte              `json:"AliasEvmAddress"`
 		} `json:"Owner"`
 	}
-	decoder := json.NewDecoder(request_document_file)
-	err = decoder.Decode(&request)
+	err = json.Unmarshal(data, &document)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// create the render offer object
-	nm.Renderer.Requests[request_document_cid] = &RenderRequest{
+	request := &RenderRequest{
+		SchemaVersion:     document.SchemaVersion,
 		DocumentCID:       request_document_cid,
 		DocumentPath:      path,
-		DirectoryCID:      request.DirectoryCID,
-		CreatedTimestamp:  request.CreatedTimestamp,
-		ModifiedTimestamp: request.ModifiedTimestamp,
-		BlenderFile:       request.BlenderFile,
-		Version:           request.Version,
-		Price:             request.Price,
-		ThisNode:          request.ThisNode,
+		DirectoryCID:      document.DirectoryCID,
+		CreatedTimestamp:  document.CreatedTimestamp,
+		ModifiedTimestamp: document.ModifiedTimestamp,
+		BlenderFile:       document.BlenderFile,
+		Version:           document.Version,
+		Price:             document.Price,
+		ThisNode:          document.ThisNode,
+		Priority:          document.Priority,
+		Deadline:          document.Deadline,
+		FramesPerTask:     document.FramesPerTask,
+		RegionRows:        document.RegionRows,
+		RegionColumns:     document.RegionColumns,
+		SetupScriptCID:    document.SetupScriptCID,
 		Owner: &hederasdk.AccountID{
-			Shard:   request.Owner.Shard,
-			Realm:   request.Owner.Realm,
-			Account: request.Owner.Account,
+			Shard:   document.Owner.Shard,
+			Realm:   document.Owner.Realm,
+			Account: document.Owner.Account,
 		},
-		Receipt: request.Receipt,
+		Receipt: document.Receipt,
 	}
 
-	return nil
+	// migrate the render request from older schema versions
+	err = request.MigrateSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
 }
 
 // Get the render request object from a CID
@@ -1036,7 +1523,21 @@ func (nm *PackageManager) GetRenderRequest(document_cid string) (*RenderRequest,
 	// Get the Render Request from the CID of the render request document
 	request, ok := nm.Renderer.Requests[document_cid]
 	if !ok {
-		return nil, errors.New(fmt.Sprintf("Render request with CID '%v' does not exist.", document_cid))
+
+		// the CID may be given in another CID version
+		if _, err = ParseCID(document_cid); err != nil {
+			return nil, newRenderError(ErrInvalidArgument, "Invalid render request CID: %w", err)
+		}
+		for cid, r := range nm.Renderer.Requests {
+			if _sameCID(cid, document_cid) {
+				request, ok = r, true
+				break
+			}
+		}
+
+	}
+	if !ok {
+		return nil, newRenderError(ErrRequestNotFound, "Render request with CID '%v' does not exist.", document_cid)
 	}
 
 	// create the render request object
@@ -1051,7 +1552,13 @@ func (request *RenderRequest) AddFile(path string, filename string) error {
 
 	// check if the render request was already submitted
 	if request._isSubmitted() {
-		return errors.New(fmt.Sprintf("Render request was already submitted and cannot be modified."))
+		return newRenderError(ErrAlreadySubmitted, "Render request was already submitted and cannot be modified.")
+	}
+
+	// the file name must not escape the directory of the render request
+	err = ipfs.ValidateRelativePath(filename)
+	if err != nil {
+		return newRenderError(ErrInvalidArgument, "Invalid file name: %w", err)
 	}
 
 	// check if the file exists
@@ -1067,6 +1574,10 @@ func (request *RenderRequest) AddFile(path string, filename string) error {
 
 	// add the file to the list of files
 	request.Files[filename] = file
+	if request.filePaths == nil {
+		request.filePaths = make(map[string]string)
+	}
+	request.filePaths[filename] = path
 
 	// update the modified timestamp
 	request._updateModifiedTimestamp()
@@ -1081,17 +1592,21 @@ func (request *RenderRequest) AddFileFromBytes(filename string, data []byte) err
 
 	// check if the render request was already submitted
 	if request._isSubmitted() {
-		return errors.New(fmt.Sprintf("Render request was already submitted and cannot be modified."))
+		return newRenderError(ErrAlreadySubmitted, "Render request was already submitted and cannot be modified.")
 	}
 
-	// Create a File from a byte array of file data
-	file := files.NewBytesFile(data)
+	// the file name must not escape the directory of the render request
+	err = ipfs.ValidateRelativePath(filename)
 	if err != nil {
-		return err
+		return newRenderError(ErrInvalidArgument, "Invalid file name: %w", err)
 	}
 
+	// Create a File from a byte array of file data
+	file := files.NewBytesFile(data)
+
 	// add the file to the list of files
 	request.Files[filename] = file
+	delete(request.filePaths, filename)
 
 	// update the modified timestamp
 	request._updateModifiedTimestamp()
@@ -1106,15 +1621,16 @@ func (request *RenderRequest) RemoveFile(filename string) error {
 
 	// check if the render request was already submitted
 	if request._isSubmitted() {
-		return errors.New(fmt.Sprintf("Render request was already submitted and cannot be modified."))
+		return newRenderError(ErrAlreadySubmitted, "Render request was already submitted and cannot be modified.")
 	}
 
 	// delete the element from the map, if it exists
 	_, ok := request.Files[filename]
 	if ok {
 		delete(request.Files, filename)
+		delete(request.filePaths, filename)
 	} else {
-		err = errors.New(fmt.Sprintf("File '%v' could not be removed from the render request.", filename))
+		err = newRenderError(ErrDocumentNotFound, "File '%v' could not be removed from the render request.", filename)
 	}
 
 	// update the modified timestamp
@@ -1129,7 +1645,7 @@ func (request *RenderRequest) MakeDirectory(overwrite bool) error {
 
 	// check if the render request was already submitted
 	if request._isSubmitted() {
-		return errors.New(fmt.Sprintf("Render request was already submitted and cannot be modified."))
+		return newRenderError(ErrAlreadySubmitted, "Render request was already submitted and cannot be modified.")
 	}
 
 	// if there is no directory yet or it should be overwritten
@@ -1137,15 +1653,15 @@ func (request *RenderRequest) MakeDirectory(overwrite bool) error {
 
 		// if there are NO files
 		if len(request.Files) == 0 {
-			return errors.New(fmt.Sprintf("No files were added to the render request."))
+			return newRenderError(ErrInvalidArgument, "No files were added to the render request.")
 		}
 
 		// create a new directory from the files
-		request.Directory = files.NewMapDirectory(request.Files)
+		request.Directory = _makeDirectoryTree(request.Files)
 
 	} else {
 
-		err = errors.New(fmt.Sprintf("Directory already exists."))
+		err = newRenderError(ErrAlreadyExists, "Directory already exists.")
 
 	}
 
@@ -1156,19 +1672,99 @@ func (request *RenderRequest) MakeDirectory(overwrite bool) error {
 
 }
 
+// Assemble the render request directory in an MFS workspace and add it to the local IPFS node
+// NOTE: Nested file names (e.g., 'textures/wood.png') are written to subdirectories.
+func (request *RenderRequest) AssembleWorkspace() (string, error) {
+	var err error
+
+	// check if the render request was already submitted
+	if request._isSubmitted() {
+		return "", newRenderError(ErrAlreadySubmitted, "Render request was already submitted and cannot be modified.")
+	}
+
+	// if there are NO files
+	if len(request.Files) == 0 {
+		return "", newRenderError(ErrInvalidArgument, "No files were added to the render request.")
+	}
+
+	// start with an empty workspace
+	workspace := gopath.Join(RENDERHIVE_IPFS_MFS_WORKSPACE_REQUESTS, fmt.Sprintf("request-%v", request.ID))
+	if _, err := ipfs.Manager.FilesStat(workspace); err == nil {
+		err = ipfs.Manager.FilesRm(workspace)
+		if err != nil {
+			return "", err
+		}
+	}
+	err = ipfs.Manager.FilesMkdir(workspace, true)
+	if err != nil {
+		return "", err
+	}
+
+	// write the files into the workspace
+	for name, file := range request.Files {
+		err = ipfs.Manager.FilesWrite(gopath.Join(workspace, name), file)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// flush the workspace and pin the directory
+	request.DirectoryCID, err = ipfs.Manager.FilesFlush(workspace)
+	if err != nil {
+		return "", err
+	}
+	_, err = ipfs.Manager.PinObject(request.DirectoryCID)
+	if err != nil {
+		return "", err
+	}
+
+	// log event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Assembled the render request directory in the MFS workspace '%v': %v", workspace, request.DirectoryCID))
+
+	return request.DirectoryCID, nil
+
+}
+
+// helper function to create a directory tree from file names with subdirectories (e.g., 'textures/wood.png')
+func _makeDirectoryTree(fileMap map[string]files.Node) files.Directory {
+
+	// sort the files into the entries of this directory and its subdirectories
+	entries := make(map[string]files.Node)
+	subdirectories := make(map[string]map[string]files.Node)
+	for name, file := range fileMap {
+		parts := strings.SplitN(name, "/", 2)
+		if len(parts) == 2 {
+			if subdirectories[parts[0]] == nil {
+				subdirectories[parts[0]] = make(map[string]files.Node)
+			}
+			subdirectories[parts[0]][parts[1]] = file
+		} else {
+			entries[name] = file
+		}
+	}
+
+	// create the subdirectories
+	for name, subdirectory := range subdirectories {
+		entries[name] = _makeDirectoryTree(subdirectory)
+	}
+
+	return files.NewMapDirectory(entries)
+
+}
+
 // Remove the directory mapping from the files to the render request
 func (request *RenderRequest) RemoveDirectory() error {
 	var err error
 
 	// check if the render request was already submitted
 	if request._isSubmitted() {
-		return errors.New(fmt.Sprintf("Render request was already submitted and cannot be modified."))
+		return newRenderError(ErrAlreadySubmitted, "Render request was already submitted and cannot be modified.")
 	}
 
 	// TODO: Implement the removal of the directory from the request
 	// check if the directory was already uploaded
 	if request.DirectoryCID != "" {
-		return errors.New(fmt.Sprintf("Directory was already uploaded and cannot be removed."))
+		return newRenderError(ErrAlreadySubmitted, "Directory was already uploaded and cannot be removed.")
 	}
 
 	// if there is a directory
@@ -1179,7 +1775,7 @@ func (request *RenderRequest) RemoveDirectory() error {
 
 	} else {
 
-		err = errors.New(fmt.Sprintf("Directory does not exist."))
+		err = newRenderError(ErrDocumentNotFound, "Directory does not exist.")
 	}
 
 	// update the modified timestamp
@@ -1195,16 +1791,16 @@ func (request *RenderRequest) AddDocument() error {
 
 	// check if the render request was already submitted
 	if request._isSubmitted() {
-		return errors.New(fmt.Sprintf("Render request was already submitted and cannot be modified."))
+		return newRenderError(ErrAlreadySubmitted, "Render request was already submitted and cannot be modified.")
 	}
 
 	// check if the document was already added
 	if request.DocumentCID != "" {
-		return errors.New(fmt.Sprintf("Render request document '%v' already exists.", request.DocumentCID))
+		return newRenderError(ErrAlreadyExists, "Render request document '%v' already exists.", request.DocumentCID)
 	}
 
 	// Prepare the creation of a local render request document file
-	request_document_filename := fmt.Sprintf("request-%v-%v.json", strings.ReplaceAll(request.Owner.String(), ".", "_"), request.CreatedTimestamp.Unix())
+	request_document_filename := request._documentName() + ".json"
 	request_document_directory := filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_LOCAL_REQUESTS)
 	request.DocumentPath = filepath.Join(request_document_directory, request_document_filename)
 
@@ -1225,9 +1821,21 @@ func (request *RenderRequest) AddDocument() error {
 		defer request_document_file.Close()
 
 		// write the render request data into the file in JSON format
+		request.SchemaVersion = RENDERHIVE_DOCUMENT_SCHEMA_VERSION
 		encoder := json.NewEncoder(request_document_file)
 		encoder.SetIndent("", "  ")
-		encoder.Encode(request)
+		err = encoder.Encode(request)
+		if err != nil {
+			request._discardDocument()
+			return err
+		}
+
+		// check that other nodes decode the same render request from the document
+		err = request._verifyDocument()
+		if err != nil {
+			request._discardDocument()
+			return err
+		}
 
 		// add the CID of the render request document to the request data
 		request.DocumentCID, err = ipfs.Manager.GetHashFromPath(request.DocumentPath)
@@ -1236,7 +1844,7 @@ func (request *RenderRequest) AddDocument() error {
 		}
 
 	} else {
-		return errors.New(fmt.Sprintf("Render request document '%v' already exists.", request.DocumentPath))
+		return newRenderError(ErrAlreadyExists, "Render request document '%v' already exists.", request.DocumentPath)
 	}
 
 	return err
@@ -1247,36 +1855,59 @@ func (request *RenderRequest) AddDocument() error {
 // NOTE:
 // This makes the render request document and all files available to the IPFS network.
 // Anyone, who knows the CID, can access the files and the render request document.
-// However, the CID is not shared at this point with anyone.
+// However, the CID is not shared at this point with anyone. A failed deployment
+// is resumed from the failed step (see deploy.go). The caller must hold the lock
+// of the render data, which is released while the DHT providers are probed.
 func (request *RenderRequest) Deploy() (string, error) {
 	var err error
 
 	// check if the render request was already submitted
 	if request._isSubmitted() {
-		return "", errors.New(fmt.Sprintf("Render request was already submitted and cannot be modified."))
+		return "", newRenderError(ErrAlreadySubmitted, "Render request was already submitted and cannot be modified.")
 	}
 
-	// make the render request directory
-	err = request.MakeDirectory(false)
+	// get the progress of a previous attempt
+	deployment, err := request._loadDeployment()
 	if err != nil {
-		return "", errors.New(fmt.Sprintf("Could not create render request directory: %v", err))
+		return "", err
+	}
+	if deployment.Step == DEPLOY_STEP_NONE && request.DocumentCID != "" {
+		return "", newRenderError(ErrAlreadyExists, "Render request document '%v' already exists.", request.DocumentCID)
 	}
 
-	// Upload the request directory to the local IPFS node
-	request.DirectoryCID, err = ipfs.Manager.AddObject(request.Directory, true)
+	// Upload the request directory to the local IPFS node (again, if a
+	// previous attempt uploaded it, since the files may have changed)
+	err = Manager._deployDirectory(request, &deployment)
 	if err != nil {
 		return "", err
 	}
 
-	// close all files and free the memory
-	for _, file := range request.Files {
-		file.Close()
+	// wait until the render request directory is reachable in the network
+	// NOTE: The document contains the provider count of the directory, so the
+	//       directory is probed before the document is created.
+	request.Providers, err = Manager._awaitProviders(ipfs.Manager.ProbeProvidersAsync(request.DirectoryCID, RENDERHIVE_CONFIG_DEPLOY_PROVIDER_TIMEOUT))
+	if err != nil {
+		return "", newRenderError(ErrNetworkUnavailable, "Render request directory is not reachable in the network: %w", err)
 	}
+	request.ProvidersTimestamp = time.Now()
 
-	// add the render request document to the file list
-	err = request.AddDocument()
-	if err != nil {
-		return "", errors.New(fmt.Sprintf("Could not add render request document: %v", err))
+	// add the render request document to the file list (unless it was created in a previous attempt)
+	if deployment.Step == DEPLOY_STEP_DOCUMENT {
+		request.DocumentPath = deployment.DocumentPath
+	} else {
+
+		// NOTE: A request loaded after a restart has the CID of its draft document.
+		request.DocumentCID = ""
+		err = request.AddDocument()
+		if err != nil {
+			return "", newRenderError(ErrNetworkUnavailable, "Could not add render request document: %w", err)
+		}
+		deployment.Step = DEPLOY_STEP_DOCUMENT
+		deployment.DocumentPath = request.DocumentPath
+		err = request._saveDeployment(&deployment)
+		if err != nil {
+			return "", fmt.Errorf("Could not store the deployment progress: %v", err)
+		}
 	}
 
 	// Upload the render request document file to IPFS
@@ -1285,10 +1916,29 @@ func (request *RenderRequest) Deploy() (string, error) {
 		return "", err
 	}
 
+	// wait until the render request document is reachable in the network
+	// NOTE: The document is kept, so that the next attempt uploads it again.
+	_, err = Manager._awaitProviders(ipfs.Manager.ProbeProvidersAsync(request.DocumentCID, RENDERHIVE_CONFIG_DEPLOY_PROVIDER_TIMEOUT))
+	if err != nil {
+		return "", newRenderError(ErrNetworkUnavailable, "Render request document is not reachable in the network: %w", err)
+	}
+
+	// pin the request files on the remote pinning service (if enabled)
+	go ipfs.Manager.AutoPinObjectRemote(request.DirectoryCID, strings.TrimSuffix(filepath.Base(request.DocumentPath), ".json"))
+	go ipfs.Manager.AutoPinObjectRemote(request.DocumentCID, filepath.Base(request.DocumentPath))
+
 	// add the request to the node's render requests
 	Manager.Renderer.Requests[request.DocumentCID] = request
+	Manager._assignRequestID(request)
+	request.Save()
 
-	return request.DocumentCID, err
+	// the deployment is complete
+	err = request._clearDeployment()
+	if err != nil {
+		logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not remove the deployment progress: %v", err))
+	}
+
+	return request.DocumentCID, nil
 
 }
 
@@ -1303,10 +1953,15 @@ func (request *RenderRequest) Deploy() (string, error) {
 func (request *RenderRequest) Submit() (*hederasdk.TransactionReceipt, []byte, error) {
 	var err error
 	var transactionBytes []byte
+	var receipt *hederasdk.TransactionReceipt
 
 	// check if the render request was already submitted
 	if request._isSubmitted() {
-		return nil, nil, errors.New(fmt.Sprintf("Render request was already submitted and cannot be modified."))
+		return nil, nil, newRenderError(ErrAlreadySubmitted, "Render request was already submitted and cannot be modified.")
+	}
+	err = Manager._refusePending(request.DocumentCID, "submit the render request")
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Submit the message to the render hive network
@@ -1318,6 +1973,20 @@ func (request *RenderRequest) Submit() (*hederasdk.TransactionReceipt, []byte, e
 		&SubmitRenderRequestArgs{
 			RenderRequestCID: request.DocumentCID,
 			BlenderFileCID:   request.BlenderFile.CID,
+			BlenderVersion:   request.Version,
+			Priority:         request.Priority,
+			Deadline:         _unixTime(request.Deadline),
+			FrameStart:       request.BlenderFile.Settings.FrameStart,
+			FrameEnd:         request.BlenderFile.Settings.FrameEnd,
+			FrameStep:        request.BlenderFile.Settings.FrameStep,
+			FramesPerTask:    request.FramesPerTask,
+			RegionRows:       request.RegionRows,
+			RegionColumns:    request.RegionColumns,
+			SetupScriptCID:   request.SetupScriptCID,
+			ResolutionX:      request.BlenderFile.Settings.ResolutionX,
+			ResolutionY:      request.BlenderFile.Settings.ResolutionY,
+			Samples:          request.BlenderFile.Settings.Samples,
+			BlenderFileSize:  request.BlenderFile.FileSize(),
 		},
 	)
 
@@ -1327,19 +1996,22 @@ func (request *RenderRequest) Submit() (*hederasdk.TransactionReceipt, []byte, e
 	} else {
 
 		// send it to the Renderhive Job Queue topic on Hedera
-		request.Receipt, transactionBytes, err = Manager.JobQueueTopic.SubmitMessage(string(jsonMessage), "renderhive-v0.1.0::submit-render-request", nil, hedera.TransactionOptions.SetExecute(false, Manager.User.UserAccount.AccountID))
+		receipt, transactionBytes, err = Manager.JobQueueTopic.SubmitMessage(string(jsonMessage), "renderhive-v0.1.0::submit-render-request", nil, hedera.TransactionOptions.SetExecute(false, Manager.User.UserAccount.AccountID))
 		if err != nil {
 			logger.Manager.Package["hedera"].Error().Err(err).Msg("")
-			return nil, nil, errors.New(fmt.Sprintf("Render request %v could not be submitted: %v.", nil, err.Error()))
+			return nil, nil, newRenderError(ErrTransactionFailed, "Render request %v could not be submitted: %w.", nil, err)
 		}
 
 	}
 
-	// TODO: Temporary workaround – Does not account for failed transactions
-	// update the submitted timestamp
-	request._updateSubmittedTimestamp()
+	// update the submitted timestamp, when the transaction succeeded
+	err = Manager.ApplyTransaction(receipt, transactionBytes, request.DocumentCID, "submit the render request", func(receipt *hederasdk.TransactionReceipt) {
+		request.Receipt = receipt
+		request._updateSubmittedTimestamp()
+		request.Save()
+	})
 
-	return request.Receipt, transactionBytes, err
+	return receipt, transactionBytes, err
 
 }
 
@@ -1349,6 +2021,12 @@ func (request *RenderRequest) Cancel() (*hederasdk.TransactionReceipt, []byte, e
 	var transactionBytes []byte
 	var receipt *hederasdk.TransactionReceipt
 
+	// check if the render request waits for the confirmation of a cancellation
+	err = Manager._refusePending(request.DocumentCID, "cancel the render request")
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Submit the render request message to the job queue topic
 	// Prepare the HCS message
 	jsonMessage, err := Manager.EncodeCommand(
@@ -1369,20 +2047,106 @@ func (request *RenderRequest) Cancel() (*hederasdk.TransactionReceipt, []byte, e
 		receipt, transactionBytes, err = Manager.JobQueueTopic.SubmitMessage(string(jsonMessage), "renderhive-v0.1.0::cancel-render-request", nil, hedera.TransactionOptions.SetExecute(false, Manager.User.UserAccount.AccountID))
 		if err != nil {
 			logger.Manager.Package["hedera"].Error().Err(err).Msg("")
-			return nil, nil, errors.New(fmt.Sprintf("Command could not be submitted: %v.", nil, err.Error()))
+			return nil, nil, newRenderError(ErrTransactionFailed, "Command could not be submitted: %w.", err)
 		}
 
 	}
 
-	// if the transaction was successful
-	if receipt.Status == hederasdk.StatusSuccess {
-
-		// update the cancelled status and closed timestamp
+	// update the cancelled status and closed timestamp, when the transaction succeeded
+	err = Manager.ApplyTransaction(receipt, transactionBytes, request.DocumentCID, "cancel the render request", func(receipt *hederasdk.TransactionReceipt) {
 		request._updateCancelledTimestamp()
+		request.Save()
+	})
+
+	return receipt, transactionBytes, err
 
+}
+
+// helper function to remove the local render request document, so the deploy can be repeated
+func (request *RenderRequest) _discardDocument() {
+
+	// remove the document file
+	err := os.Remove(request.DocumentPath)
+	if err != nil && !os.IsNotExist(err) {
+		logger.Manager.Package["node"].Error().Msg(err.Error())
 	}
 
-	return receipt, transactionBytes, err
+	// reset the document data
+	request.DocumentCID = ""
+	request.DocumentPath = ""
+
+}
+
+// helper function to check that the render request document decodes to the render request
+func (request *RenderRequest) _verifyDocument() error {
+	var err error
+
+	// decode the written document as other nodes would
+	data, err := os.ReadFile(request.DocumentPath)
+	if err != nil {
+		return err
+	}
+	decoded, err := DecodeRenderRequest(request.DocumentCID, request.DocumentPath, data)
+	if err != nil {
+		return newRenderError(ErrDocumentMismatch, "Render request document could not be decoded: %w", err)
+	}
+
+	return request._compareDocument(decoded)
+
+}
+
+// helper function to compare the key fields of the render request with a decoded render request
+func (request *RenderRequest) _compareDocument(decoded *RenderRequest) error {
+
+	if decoded.DirectoryCID != request.DirectoryCID {
+		return newRenderError(ErrDocumentMismatch, "Render request document does not match the render request: DirectoryCID '%v' != '%v'.", decoded.DirectoryCID, request.DirectoryCID)
+	}
+	if !decoded.CreatedTimestamp.Equal(request.CreatedTimestamp) {
+		return newRenderError(ErrDocumentMismatch, "Render request document does not match the render request: CreatedTimestamp '%v' != '%v'.", decoded.CreatedTimestamp, request.CreatedTimestamp)
+	}
+	if !decoded.ModifiedTimestamp.Equal(request.ModifiedTimestamp) {
+		return newRenderError(ErrDocumentMismatch, "Render request document does not match the render request: ModifiedTimestamp '%v' != '%v'.", decoded.ModifiedTimestamp, request.ModifiedTimestamp)
+	}
+	if decoded.BlenderFile.CID != request.BlenderFile.CID || decoded.BlenderFile.Scene != request.BlenderFile.Scene {
+		return newRenderError(ErrDocumentMismatch, "Render request document does not match the render request: BlenderFile '%v' != '%v'.", decoded.BlenderFile.CID, request.BlenderFile.CID)
+	}
+	if !reflect.DeepEqual(decoded.BlenderFile.Settings, request.BlenderFile.Settings) {
+		return newRenderError(ErrDocumentMismatch, "Render request document does not match the render request: BlenderFile.Settings '%v' != '%v'.", decoded.BlenderFile.Settings, request.BlenderFile.Settings)
+	}
+	if decoded.Version != request.Version {
+		return newRenderError(ErrDocumentMismatch, "Render request document does not match the render request: Version '%v' != '%v'.", decoded.Version, request.Version)
+	}
+	if decoded.Price != request.Price {
+		return newRenderError(ErrDocumentMismatch, "Render request document does not match the render request: Price '%v' != '%v'.", decoded.Price, request.Price)
+	}
+	if decoded.Priority != request.Priority {
+		return newRenderError(ErrDocumentMismatch, "Render request document does not match the render request: Priority '%v' != '%v'.", decoded.Priority, request.Priority)
+	}
+	if !decoded.Deadline.Equal(request.Deadline) {
+		return newRenderError(ErrDocumentMismatch, "Render request document does not match the render request: Deadline '%v' != '%v'.", decoded.Deadline, request.Deadline)
+	}
+	if decoded.FramesPerTask != request.FramesPerTask {
+		return newRenderError(ErrDocumentMismatch, "Render request document does not match the render request: FramesPerTask '%v' != '%v'.", decoded.FramesPerTask, request.FramesPerTask)
+	}
+	if decoded.RegionRows != request.RegionRows || decoded.RegionColumns != request.RegionColumns {
+		return newRenderError(ErrDocumentMismatch, "Render request document does not match the render request: Regions '%vx%v' != '%vx%v'.", decoded.RegionRows, decoded.RegionColumns, request.RegionRows, request.RegionColumns)
+	}
+	if decoded.SetupScriptCID != request.SetupScriptCID {
+		return newRenderError(ErrDocumentMismatch, "Render request document does not match the render request: SetupScriptCID '%v' != '%v'.", decoded.SetupScriptCID, request.SetupScriptCID)
+	}
+
+	// the owner is decoded without its alias
+	if request.Owner == nil {
+		return newRenderError(ErrDocumentMismatch, "Render request has no owner.")
+	}
+	if request.Owner.AliasKey != nil || request.Owner.AliasEvmAddress != nil {
+		return newRenderError(ErrDocumentMismatch, "Render request owner '%v' uses an account alias, which is not supported by the render request document.", request.Owner.String())
+	}
+	if decoded.Owner.Shard != request.Owner.Shard || decoded.Owner.Realm != request.Owner.Realm || decoded.Owner.Account != request.Owner.Account {
+		return newRenderError(ErrDocumentMismatch, "Render request document does not match the render request: Owner '%v' != '%v'.", decoded.Owner.String(), request.Owner.String())
+	}
+
+	return nil
 
 }
 
@@ -1439,46 +2203,17 @@ func (request *RenderRequest) _updateCancelledTimestamp() {
 // Add a new render request for this node
 func (nm *PackageManager) AddRenderRequest(request *RenderRequest, overwrite bool) (int, error) {
 	var err error
-	var newID int
-
-	// initialize ID
-	newID = 0
 
 	// if no request was passed
 	if request == nil {
-		return 0, errors.New(fmt.Sprintf("No request was passed."))
+		return 0, newRenderError(ErrInvalidArgument, "No request was passed.")
 	}
 
-	// if the node has any render requests
-	if nm.Renderer.Requests != nil {
-
-		// convert map to slice of keys
-		ids := make([]string, 0, len(nm.Renderer.Requests))
-		for id := range nm.Renderer.Requests {
-			ids = append(ids, id)
-		}
-
-		// sort the slice of IDs
-		sort.Strings(ids)
-
-		// Get the ID assigned to the last render request and add 1 for the new ID
-		if len(ids) > 0 {
-			newID = len(ids) + 1
-		}
-
-	} else {
-
-		// initialized the map first
+	// initialize the map first, if the node has no render requests yet
+	if nm.Renderer.Requests == nil {
 		nm.Renderer.Requests = make(map[string]*RenderRequest)
-
 	}
 
-	// Update the ID
-	request.ID = newID
-
-	// Append the request to the list of requests of this node
-	nm.Renderer.Requests[strconv.Itoa(newID)] = request
-
 	// Add the CID of the Blender file to the render request data
 	request.BlenderFile.CID, err = ipfs.Manager.GetHashFromPath(request.BlenderFile.Path)
 	if err != nil {
@@ -1505,14 +2240,20 @@ func (nm *PackageManager) AddRenderRequest(request *RenderRequest, overwrite boo
 		defer request_document_file.Close()
 
 		// write the render request data into the file in JSON format
+		request.SchemaVersion = RENDERHIVE_DOCUMENT_SCHEMA_VERSION
 		encoder := json.NewEncoder(request_document_file)
 		encoder.Encode(request)
 
 	} else {
-		return 0, errors.New(fmt.Sprintf("Render request document '%v' already exists.", request.DocumentPath))
+		return 0, newRenderError(ErrAlreadyExists, "Render request document '%v' already exists.", request.DocumentPath)
 	}
 
-	return newID, err
+	// Assign the persistent ID of the render request document and append the
+	// request to the list of requests of this node
+	nm._assignRequestID(request)
+	nm.Renderer.Requests[strconv.Itoa(request.ID)] = request
+
+	return request.ID, err
 
 }
 
@@ -1524,12 +2265,18 @@ func (nm *PackageManager) RemoveRenderRequest(id int) error {
 	logger.Manager.Package["node"].Trace().Msg("Removing a render request from the node:")
 
 	// delete the element from the map, if it exists
-	request, ok := nm.Renderer.Requests[strconv.Itoa(id)]
+	// NOTE: The ID stays assigned to the render request document, so that the
+	//       request keeps its ID, when it is loaded again.
+	request, ok := nm.GetRenderRequestByID(id)
 	if ok {
 		logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf(" [#] ID: %v", request.ID))
-		delete(nm.Renderer.Requests, strconv.Itoa(id))
+		for key, value := range nm.Renderer.Requests {
+			if value == request {
+				delete(nm.Renderer.Requests, key)
+			}
+		}
 	} else {
-		err = errors.New(fmt.Sprintf("Render request %v could not be removed from the node.", id))
+		err = newRenderError(ErrRequestNotFound, "Render request %v could not be removed from the node.", id)
 	}
 
 	return err
@@ -1545,7 +2292,7 @@ func (nm *PackageManager) SubmitRenderRequest(id int) error {
 	logger.Manager.Package["node"].Trace().Msg("Submitting a render request for this node to the render hive:")
 
 	// if the render request exists
-	request, ok := nm.Renderer.Requests[strconv.Itoa(id)]
+	request, ok := nm.GetRenderRequestByID(id)
 	if ok {
 
 		// log trace event
@@ -1577,6 +2324,8 @@ func (nm *PackageManager) SubmitRenderRequest(id int) error {
 		message := RenderRequestMessage{
 			DocumentCID:    request.DocumentCID,
 			BlenderFileCID: request.BlenderFile.CID,
+			Priority:       request.Priority,
+			Deadline:       _unixTime(request.Deadline),
 		}
 
 		// Encode the message as JSON
@@ -1589,12 +2338,12 @@ func (nm *PackageManager) SubmitRenderRequest(id int) error {
 			request.Receipt, _, err = nm.JobQueueTopic.SubmitMessage(string(jsonMessage), "renderhive-v0.1.0::submit-render-request", nil)
 			if err != nil {
 				logger.Manager.Package["hedera"].Error().Err(err).Msg("")
-				return errors.New(fmt.Sprintf("Render request %v could not be submitted: %v.", id, err.Error()))
+				return newRenderError(ErrTransactionFailed, "Render request %v could not be submitted: %w.", id, err)
 			}
 			if request.Receipt != nil {
 				logger.Manager.Package["hedera"].Trace().Msg(fmt.Sprintf(" [#] [*] Receipt: %s (Status: %s)", request.Receipt.TransactionID.String(), request.Receipt.Status))
 				if !strings.EqualFold(request.Receipt.Status.String(), "SUCCESS") {
-					err = errors.New(fmt.Sprintf("Render request %v could not be submitted to Hedera: Receipt status '%v'.", id, request.Receipt.Status.String()))
+					err = newRenderError(ErrTransactionFailed, "Render request %v could not be submitted to Hedera: Receipt status '%v'.", id, request.Receipt.Status.String())
 					return err
 				}
 			}
@@ -1602,7 +2351,7 @@ func (nm *PackageManager) SubmitRenderRequest(id int) error {
 		}
 
 	} else {
-		err = errors.New(fmt.Sprintf("Render request could not be submitted: Request ID %v does not exist.", id))
+		err = newRenderError(ErrRequestNotFound, "Render request could not be submitted: Request ID %v does not exist.", id)
 		return err
 	}
 
@@ -1610,127 +2359,657 @@ func (nm *PackageManager) SubmitRenderRequest(id int) error {
 
 }
 
+// RENDER JOB DEADLINES
+// #############################################################################
+// Claim a render job for rendering on this node and set its render deadline
+func (job *RenderJob) Claim(estimatedDuration time.Duration) {
+
+	// calculate the deadline from the estimated render time and a safety factor
+	timeout := time.Duration(float64(estimatedDuration) * RENDERHIVE_CONFIG_RENDER_JOB_DEADLINE_SAFETY_FACTOR)
+	if timeout < RENDERHIVE_CONFIG_RENDER_JOB_DEADLINE_MINIMUM {
+		timeout = RENDERHIVE_CONFIG_RENDER_JOB_DEADLINE_MINIMUM
+	}
+
+	// update the job status
+	job._setState(RENDER_JOB_STATE_CLAIMED)
+	job.ClaimedTimestamp = time.Now()
+	job.Deadline = job.ClaimedTimestamp.Add(timeout)
+	job.Save()
+
+	// log event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf("Claimed render job '%v' (deadline: %v).", job.Request.DocumentCID, job.Deadline))
+
+}
+
+// helper function to get the state of the render job
+// NOTE: The state of a rendered job is changed by other goroutines (e.g., when
+// the job is released after its deadline).
+func (job *RenderJob) _state() int {
+
+	job.stateMutex.Lock()
+	defer job.stateMutex.Unlock()
+
+	return job.State
+
+}
+
+// helper function to set the state of the render job
+func (job *RenderJob) _setState(state int) {
+
+	job.stateMutex.Lock()
+	defer job.stateMutex.Unlock()
+
+	job.State = state
+
+}
+
+// Check if the render deadline of the job has passed
+func (job *RenderJob) IsOverdue() bool {
+
+	// only claimed jobs or jobs in rendering can be overdue
+	if state := job._state(); state != RENDER_JOB_STATE_CLAIMED && state != RENDER_JOB_STATE_RENDERING {
+		return false
+	}
+
+	return !job.Deadline.IsZero() && time.Now().After(job.Deadline)
+
+}
+
+// Check the render deadlines of all jobs claimed by this node and release the overdue jobs
+// NOTE: The network is notified after the lock of the render data was released.
+func (nm *PackageManager) CheckRenderJobDeadlines() error {
+	var err error
+
+	// check at most once per interval
+	if time.Since(nm.lastDeadlineCheck) < RENDERHIVE_CONFIG_RENDER_DEADLINE_CHECK_INTERVAL {
+		return nil
+	}
+	nm.lastDeadlineCheck = time.Now()
+
+	nm.Renderer.Mutex.Lock()
+
+	// release the jobs of this node, whose deadline passed
+	released := []*RenderJob{}
+	unpin := []bool{}
+	for _, job := range nm.Renderer.NodeQueue {
+		if job.IsOverdue() {
+			released = append(released, job)
+			unpin = append(unpin, nm._releaseRenderJob(job, "timeout"))
+		}
+	}
+
+	// remove the released jobs from the queue of this node
+	queue := []*RenderJob{}
+	for _, job := range nm.Renderer.NodeQueue {
+		if job.State != RENDER_JOB_STATE_RELEASED {
+			queue = append(queue, job)
+		}
+	}
+	nm.Renderer.NodeQueue = queue
+
+	nm.Renderer.Mutex.Unlock()
+
+	// notify the network that the jobs are available again
+	for i, job := range released {
+		err = nm._announceRenderJobRelease(job, "timeout", unpin[i])
+		if err != nil {
+			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not release render job '%v': %v", job.Request.DocumentCID, err))
+		}
+	}
+
+	return err
+
+}
+
+// Abandon a render job on this node and release it to the network
+// NOTE: The caller must not hold the lock of the render data.
+func (nm *PackageManager) ReleaseRenderJob(job *RenderJob, reason string) error {
+
+	nm.Renderer.Mutex.Lock()
+	unpin := nm._releaseRenderJob(job, reason)
+	nm.Renderer.Mutex.Unlock()
+
+	return nm._announceRenderJobRelease(job, reason, unpin)
+
+}
+
+// helper function to abandon a render job on this node
+// NOTE: The caller holds the lock of the render data. Returns true, if the
+// job files are not needed by other jobs of this node anymore.
+func (nm *PackageManager) _releaseRenderJob(job *RenderJob, reason string) bool {
+
+	// log event
+	logger.Manager.Package["node"].Info().Msg(fmt.Sprintf("Releasing render job '%v' (reason: %v) ...", job.Request.DocumentCID, reason))
+
+	// stop the Blender process, if it is still running
+	if job.Blender != nil {
+		err := job.Blender.Stop()
+		if err != nil {
+			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf(" [#] Could not stop Blender process (pid: %v): %v", job.Blender.PID, err))
+		}
+	}
+
+	// update the job status
+	// NOTE: The node is only idle again, if it was rendering this job.
+	rendering := job._state() == RENDER_JOB_STATE_RENDERING
+	job._setState(RENDER_JOB_STATE_RELEASED)
+	job.Attempts += 1
+	if rendering {
+		nm.Renderer.Busy = false
+	}
+	job.Save()
+
+	// the files are kept, if this node still renders other subtasks of the render request
+	return !nm._rendersRequest(job)
+
+}
+
+// helper function to unpin the files of a released render job and notify the network
+// NOTE: Called without the lock of the render data, since it waits for the
+// IPFS node and the Hedera network.
+func (nm *PackageManager) _announceRenderJobRelease(job *RenderJob, reason string, unpin bool) error {
+	var err error
+
+	// unpin the job files from the local IPFS node
+	if unpin {
+		_, err = ipfs.Manager.UnPinObject(job.Request.DocumentCID)
+		if err != nil {
+			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf(" [#] Could not unpin render request document: %v", err))
+		}
+		if job.Request.BlenderFile.CID != "" {
+			_, err = ipfs.Manager.UnPinObject(job.Request.BlenderFile.CID)
+			if err != nil {
+				logger.Manager.Package["node"].Error().Msg(fmt.Sprintf(" [#] Could not unpin Blender file: %v", err))
+			}
+		}
+	}
+
+	// notify the network that the job is available again
+	jsonMessage, err := nm.EncodeCommand(
+		[]string{},
+		SERVICE_NODE,
+		METHOD_NODE_RELEASE_RENDER_JOB,
+		&ReleaseRenderJobArgs{
+			RenderRequestCID: job.Request.DocumentCID,
+			Subtask:          job.SubtaskIndex(),
+			Attempts:         job.Attempts,
+			Reason:           reason,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if nm.JobQueueTopic == nil {
+		return newRenderError(ErrNetworkUnavailable, "Render job could not be released: Not subscribed to the job queue topic.")
+	}
+	receipt, _, err := nm.JobQueueTopic.SubmitMessage(jsonMessage, "renderhive-v0.1.0::release-render-job", nil)
+	if err != nil {
+		return newRenderError(ErrTransactionFailed, "Render job could not be released: %w.", err)
+	}
+	_accountJobTransaction(job, receipt)
+
+	// log event
+	logger.Manager.Package["node"].Info().Msg(fmt.Sprintf(" [#] Render job released after %v attempt(s).", job.Attempts))
+
+	return err
+
+}
+
+// Complete a render job on this node and announce the render result to the network
+func (nm *PackageManager) SubmitRenderResult(job *RenderJob, result *RenderResult) error {
+	var err error
+
+	// log event
+	logger.Manager.Package["node"].Info().Msg(fmt.Sprintf("Submitting render result '%v' of render job '%v' ...", result.ResultCID, job.Request.DocumentCID))
+
+	// update the job status
+	job._setState(RENDER_JOB_STATE_COMPLETED)
+	job.Result = result
+	nm.Renderer.Busy = false
+	job.Save()
+	nm.ObserveRenderDuration(job, time.Since(job.ClaimedTimestamp))
+
+	// pin the render result on the remote pinning service (if enabled)
+	go ipfs.Manager.AutoPinObjectRemote(result.ResultCID, fmt.Sprintf("result-%v-%v", job.Request.DocumentCID, job.SubtaskIndex()))
+	hedera.Manager.History.CompleteJob(job.Request.DocumentCID, job.SubtaskIndex(), time.Since(job.ClaimedTimestamp))
+	if nm.Repository != nil && job.Subtask == nil {
+		if err := nm.Repository.SaveResult(job.Request.DocumentCID, result); err != nil {
+			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf(" [#] Could not store render result: %v", err))
+		}
+	}
+
+	// notify the network about the render result
+	jsonMessage, err := nm.EncodeCommand(
+		[]string{},
+		SERVICE_NODE,
+		METHOD_NODE_SUBMIT_RENDER_RESULT,
+		&SubmitRenderResultArgs{
+			RenderRequestCID:        job.Request.DocumentCID,
+			Subtask:                 job.SubtaskIndex(),
+			ResultCID:               result.ResultCID,
+			SettlementTransactionID: result.SettlementTransactionID,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if nm.JobQueueTopic == nil {
+		return newRenderError(ErrNetworkUnavailable, "Render result could not be submitted: Not subscribed to the job queue topic.")
+	}
+	receipt, _, err := nm.JobQueueTopic.SubmitMessage(jsonMessage, "renderhive-v0.1.0::submit-render-result", nil)
+	if err != nil {
+		return newRenderError(ErrTransactionFailed, "Render result could not be submitted: %w.", err)
+	}
+	_accountJobTransaction(job, receipt)
+	nm.PublishEvent(EVENT_RESULT_SUBMITTED, ResultEventData{
+		RenderRequestCID: job.Request.DocumentCID,
+		Subtask:          job.SubtaskIndex(),
+		ResultCID:        result.ResultCID,
+	})
+
+	return err
+
+}
+
+// helper function to add a transaction of this node to the cost accounting of a render job
+func _accountJobTransaction(job *RenderJob, receipt *hederasdk.TransactionReceipt) {
+
+	if receipt != nil && receipt.TransactionID != nil {
+		hedera.Manager.History.AddJobTransaction(job.Request.DocumentCID, job.SubtaskIndex(), receipt.TransactionID.String())
+	}
+
+}
+
 // RENDER QUEUE
 // #############################################################################
 // Message callback to receive the job queue data from the render hive
+// NOTE: The callback only buffers the messages. They are processed in the order
+// of their consensus timestamps, while the background tasks run concurrently
+// (see topic_processing.go).
 func (nm *PackageManager) JobQueueMessageCallback() func(message hederasdk.TopicMessage) {
 
-	return func(message hederasdk.TopicMessage) {
-		var err error
+	// lock the state
+	nm.jobQueue.mutex.Lock()
+	defer nm.jobQueue.mutex.Unlock()
 
-		// decode the received command
-		command, err := nm.DecodeCommand(message.Contents)
+	// the processor is kept for later subscriptions
+	if nm.jobQueueProcessor == nil {
+		timeout, err := nm.JobQueueSettings.Timeout()
 		if err != nil {
-			logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Failed to process received command: %s", string(message.Contents)))
-			return
+			timeout = RENDERHIVE_CONFIG_JOB_QUEUE_MESSAGE_TIMEOUT
 		}
+		nm.jobQueueProcessor = NewTopicMessageProcessor(nm.Context(), nm.JobQueueSettings.WorkerCount(), timeout, RENDERHIVE_CONFIG_JOB_QUEUE_BUFFER, nm._processJobQueueMessage)
+	}
+
+	return nm.jobQueueProcessor.Handle
+
+}
+
+// helper function to process a message of the job queue topic
+func (nm *PackageManager) _processJobQueueMessage(message hederasdk.TopicMessage) {
+	var err error
+
+	// record the received message
+	// NOTE: The messages processed before the start only rebuild the state of
+	//       the node, so their background tasks and events are skipped.
+	metrics.Manager.ObserveTopicMessage("job_queue")
+	defer nm._recordJobQueueMessage(message.ConsensusTimestamp)
+	replayed := nm._isReplayedJobQueueMessage(message.ConsensusTimestamp)
+
+	// decode the received command
+	command, err := nm.DecodeCommand(message.Contents)
+	if err != nil {
+		logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Failed to process received command: %s", string(message.Contents)))
+		return
+	}
+
+	// decode rpc call from base64 to JSON
+	jsonMessage := make([]byte, base64.StdEncoding.DecodedLen(len(command.Message)))
+	n, err := base64.StdEncoding.Decode(jsonMessage, command.Message)
+	if err != nil {
+		logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Failed to decode base64 encoded JSON-RPC message: %s", string(command.Message)))
+		return
+	}
+
+	// reduce to the number of bytes actually written
+	jsonMessage = jsonMessage[:n]
+
+	// unmarshal the JSON message into a JsonRpcMessage
+	var rpcMessage JsonRpcMessage
+	err = json.Unmarshal(jsonMessage, &rpcMessage)
+	if err != nil {
+		logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Failed to retrieve JSON-RPC message (%s): %v", string(jsonMessage), err))
+		return
+	}
+
+	// Convert Params to JSON
+	params, err := json.Marshal(rpcMessage.Params)
+	if err != nil {
+		logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Failed to retrieve JSON-RPC parameters (%s): %v", rpcMessage.Params, err))
+		return
+	}
+
+	// get the service and method types
+	service, method, err := nm.GetServiceAndMethodInt(rpcMessage.Method)
+	if service == SERVICE_UNKNOWN && method == METHOD_UNKNOWN {
+		logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Unknown JSON-RPC method (%s): %v", rpcMessage.Method, err))
+		return
+	}
 
-		// decode rpc call from base64 to JSON
-		jsonMessage := make([]byte, base64.StdEncoding.DecodedLen(len(command.Message)))
-		n, err := base64.StdEncoding.Decode(jsonMessage, command.Message)
+	// TODO: Verify that the message is valid.
+	// ...
+
+	// lock the render queues while the message is processed
+	nm.Renderer.Mutex.Lock()
+	defer nm.Renderer.Mutex.Unlock()
+
+	// Process the message according to the service and method types
+	if service == SERVICE_NODE && method == METHOD_NODE_SUBMIT_RENDER_REQUEST {
+		// Unmarshal Params into SubmitRenderRequestArgs
+		var request SubmitRenderRequestArgs
+		err = json.Unmarshal(params, &request)
 		if err != nil {
-			logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Failed to decode base64 encoded JSON-RPC message: %s", string(command.Message)))
+			logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Message received but not processed: %s", string(message.Contents)))
 			return
 		}
 
-		// reduce to the number of bytes actually written
-		jsonMessage = jsonMessage[:n]
+		// Pin the render request document and blender file to the local IPFS node
+		// TODO: Add a proper file management. Downloading each file, probably is
+		//       too resource intensive at larger network scales.
+		if !replayed {
+			nm._pinInBackground(request.RenderRequestCID)
+			nm._pinInBackground(request.BlenderFileCID)
+		}
+
+		// create the RenderJob elements (one per subtask) for the internal job management
+		jobs := nm.CreateRenderJobs(&request, message.ConsensusTimestamp)
+
+		// add the jobs to the slice of render jobs for the internal job management
+		nm.NetworkQueue = append(nm.NetworkQueue, jobs...)
+		if !replayed {
+			for _, job := range jobs {
+				nm.PublishEvent(EVENT_JOB_RECEIVED, JobEventData{
+					RenderRequestCID: job.Request.DocumentCID,
+					Subtask:          job.SubtaskIndex(),
+				})
+			}
+		}
+
+		// log trace event
+		logger.Manager.Package["node"].Debug().Msg("Received a new render request:")
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Render request document: %v", jobs[0].Request.DocumentCID))
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Submitted: %v", jobs[0].Request.SubmittedTimestamp))
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Priority: %v (deadline: %v)", jobs[0].Request.Priority, jobs[0].Request.Deadline))
+		if jobs[0].Subtask != nil {
+			logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Subtasks: %v (%v frames each)", len(jobs), request.FramesPerTask))
+		}
+
+	} else if service == SERVICE_NODE && method == METHOD_NODE_CANCEL_RENDER_REQUEST {
 
-		// unmarshal the JSON message into a JsonRpcMessage
-		var rpcMessage JsonRpcMessage
-		err = json.Unmarshal(jsonMessage, &rpcMessage)
+		// TODO: Implement the cancellation of a render request
+
+	} else if service == SERVICE_NODE && method == METHOD_NODE_RELEASE_RENDER_JOB {
+
+		// Unmarshal Params into ReleaseRenderJobArgs
+		var release ReleaseRenderJobArgs
+		err = json.Unmarshal(params, &release)
 		if err != nil {
-			logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Failed to retrieve JSON-RPC message (%s): %v", string(jsonMessage), err))
+			logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Message received but not processed: %s", string(message.Contents)))
+			return
+		}
+
+		// find the job in the network queue
+		job, ok := nm.GetNetworkJob(release.RenderRequestCID, release.Subtask)
+		if !ok {
+			logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf("Ignored the release of the unknown render job '%v' (subtask: %v).", release.RenderRequestCID, release.Subtask))
 			return
 		}
 
-		// Convert Params to JSON
-		params, err := json.Marshal(rpcMessage.Params)
+		// only the node that claimed the job may release it
+		if job.Operator == "" || job.Operator != _messageSender(message) {
+			logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Ignored the release of render job '%v' announced by '%v' (claimed by: '%v').", release.RenderRequestCID, _messageSender(message), job.Operator))
+			return
+		}
+
+		// make the job available again
+		job._setState(RENDER_JOB_STATE_QUEUED)
+		job.Deadline = time.Time{}
+		job.Operator = ""
+		if release.Attempts > job.Attempts {
+			job.Attempts = release.Attempts
+		}
+
+		// flag jobs that repeatedly time out
+		if job.Attempts >= RENDERHIVE_CONFIG_RENDER_JOB_MAXIMUM_ATTEMPTS {
+			job.Flagged = true
+			logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Render job '%v' was flagged after %v timed out attempts.", job.Request.DocumentCID, job.Attempts))
+		}
+
+		// the releasing node abandoned the job
+		nm.Reputation.ObserveRelease(message)
+
+		// log trace event
+		logger.Manager.Package["node"].Debug().Msg("Received a released render job:")
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Render request document: %v (subtask: %v)", release.RenderRequestCID, release.Subtask))
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Attempts: %v (reason: %v)", release.Attempts, release.Reason))
+
+	} else if service == SERVICE_NODE && method == METHOD_NODE_RESOLVE_DISPUTE {
+
+		// Unmarshal Params into ResolveDisputeArgs
+		var resolution ResolveDisputeArgs
+		err = json.Unmarshal(params, &resolution)
 		if err != nil {
-			logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Failed to retrieve JSON-RPC parameters (%s): %v", rpcMessage.Params, err))
+			logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Message received but not processed: %s", string(message.Contents)))
 			return
 		}
 
-		// get the service and method types
-		service, method, err := nm.GetServiceAndMethodInt(rpcMessage.Method)
-		if service == SERVICE_UNKNOWN && method == METHOD_UNKNOWN {
-			logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Unknown JSON-RPC method (%s): %v", rpcMessage.Method, err))
+		// only the requester or this node may announce the resolution
+		if !nm._isDisputeResolver(resolution.RenderRequestCID, _messageSender(message)) {
+			logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Ignored the resolution of the dispute for render job '%v' announced by '%v'.", resolution.RenderRequestCID, _messageSender(message)))
 			return
 		}
 
-		// TODO: Verify that the message is valid.
-		// ...
+		// update the dispute state
+		nm.ResolveDispute(&resolution)
+		nm.Reputation.ObserveResolution(message, resolution.RenderRequestCID, resolution.AcceptedResultCID)
 
-		// Process the message according to the service and method types
-		if service == SERVICE_NODE && method == METHOD_NODE_SUBMIT_RENDER_REQUEST {
-			// Unmarshal Params into SubmitRenderRequestArgs
-			var request SubmitRenderRequestArgs
-			err = json.Unmarshal(params, &request)
-			if err != nil {
-				logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Message received but not processed: %s", string(message.Contents)))
-				return
+		// log trace event
+		logger.Manager.Package["node"].Debug().Msg("Received a dispute resolution:")
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Render request document: %v", resolution.RenderRequestCID))
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Accepted result: %v", resolution.AcceptedResultCID))
+
+	} else if service == SERVICE_NODE && method == METHOD_NODE_SUBMIT_RENDER_RESULT {
+
+		// Unmarshal Params into SubmitRenderResultArgs
+		var result SubmitRenderResultArgs
+		err = json.Unmarshal(params, &result)
+		if err != nil {
+			logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Message received but not processed: %s", string(message.Contents)))
+			return
+		}
+
+		// find the submission time of the render request
+		submitted := time.Time{}
+		for _, job := range nm.NetworkQueue {
+			if job.Request.DocumentCID == result.RenderRequestCID {
+				submitted = job.Request.SubmittedTimestamp
 			}
+		}
 
-			// Pin the render request document and blender file to the local IPFS node
-			// TODO: Add a proper file management. Downloading each file, probably is
-			//       too resource intensive at larger network scales.
-			go ipfs.Manager.PinObject(request.RenderRequestCID)
-			go ipfs.Manager.PinObject(request.BlenderFileCID)
-
-			// create the RenderJob element for the internal job management
-			job := &RenderJob{
-				Request: &RenderRequest{
-					DocumentCID:        request.RenderRequestCID,
-					SubmittedTimestamp: message.ConsensusTimestamp,
-				},
+		// complete the job in the network queue
+		if job, ok := nm.GetNetworkJob(result.RenderRequestCID, result.Subtask); ok {
+			job._setState(RENDER_JOB_STATE_COMPLETED)
+			job.Operator = _messageSender(message)
+			job.Result = &RenderResult{
+				OperatorAccountID: job.Operator,
+				ResultCID:         result.ResultCID,
 			}
+		}
 
-			// add the request to the slice of render jobs for the internal job management
-			nm.NetworkQueue = append(nm.NetworkQueue, job)
+		// the submitting node completed the job
+		nm.Reputation.ObserveResult(message, result.RenderRequestCID, result.ResultCID, submitted, result.SettlementTransactionID)
 
-			// log trace event
-			logger.Manager.Package["node"].Debug().Msg("Received a new render request:")
-			logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Render request document: %v", job.Request.DocumentCID))
-			logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Submitted: %v", job.Request.SubmittedTimestamp))
+		// log trace event
+		logger.Manager.Package["node"].Debug().Msg("Received a render result:")
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Render request document: %v (subtask: %v)", result.RenderRequestCID, result.Subtask))
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Render result: %v", result.ResultCID))
+
+	} else if service == SERVICE_NODE && method == METHOD_NODE_ANNOUNCE_RENDER_JOB_CLAIM {
 
-		} else if service == SERVICE_NODE && method == METHOD_NODE_CANCEL_RENDER_REQUEST {
+		// Unmarshal Params into AnnounceRenderJobClaimArgs
+		var claim AnnounceRenderJobClaimArgs
+		err = json.Unmarshal(params, &claim)
+		if err != nil {
+			logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Message received but not processed: %s", string(message.Contents)))
+			return
+		}
 
-			// TODO: Implement the cancellation of a render request
+		// assign the job in the network queue to the claiming node
+		// NOTE: The first claim is accepted, later claims of the same job are ignored.
+		if job, ok := nm.GetNetworkJob(claim.RenderRequestCID, claim.Subtask); ok && job._state() == RENDER_JOB_STATE_QUEUED {
+			job._setState(RENDER_JOB_STATE_CLAIMED)
+			job.Operator = _messageSender(message)
+			job.ClaimedTimestamp = message.ConsensusTimestamp
+			job.Deadline = _timeFromUnix(claim.Deadline)
+		}
 
-		} else if service == SERVICE_NODE && method == METHOD_NODE_SUBMIT_RENDER_OFFER {
+		// log trace event
+		logger.Manager.Package["node"].Debug().Msg("Received a render job claim:")
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Render request document: %v (subtask: %v)", claim.RenderRequestCID, claim.Subtask))
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Claimed by: %v", _messageSender(message)))
 
-			// Unmarshal Params into SubmitRenderOfferArgs
-			var offer SubmitRenderOfferArgs
-			err = json.Unmarshal(params, &offer)
-			if err != nil {
-				logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Message received but not processed: %s", string(message.Contents)))
-				return
-			}
+	} else if service == SERVICE_NODE && method == METHOD_NODE_SUBMIT_RENDER_OFFER {
 
-			// Pin the render offer document to the local IPFS node
-			go ipfs.Manager.PinObject(offer.RenderOfferCID)
+		// Unmarshal Params into SubmitRenderOfferArgs
+		var offer SubmitRenderOfferArgs
+		err = json.Unmarshal(params, &offer)
+		if err != nil {
+			logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Message received but not processed: %s", string(message.Contents)))
+			return
+		}
 
-			// create the RenderOffer element for the internal job management
-			ro := &RenderOffer{
-				DocumentCID:        offer.RenderOfferCID,
-				SubmittedTimestamp: message.ConsensusTimestamp,
-			}
+		// Pin the render offer document to the local IPFS node
+		if !replayed {
+			nm._pinInBackground(offer.RenderOfferCID)
+		}
 
-			// log trace event
-			logger.Manager.Package["node"].Debug().Msg("Received a new render offer:")
-			logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Render offer document: %v", ro.DocumentCID))
-			logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Submitted: %v", ro.SubmittedTimestamp))
+		// create the RenderOffer element for the internal job management
+		ro := &RenderOffer{
+			DocumentCID:        offer.RenderOfferCID,
+			SubmittedTimestamp: message.ConsensusTimestamp,
+		}
+
+		// remember the render offer of the operator
+		nm._recordOfferAnnouncement(_messageSender(message), offer.RenderOfferCID, offer.Benchmarks, message.ConsensusTimestamp)
 
+		// log trace event
+		logger.Manager.Package["node"].Debug().Msg("Received a new render offer:")
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Render offer document: %v", ro.DocumentCID))
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Submitted: %v", ro.SubmittedTimestamp))
+
+	} else if service == SERVICE_NODE && method == METHOD_NODE_PAUSE_RENDER_OFFER {
+
+		// Unmarshal Params into PauseRenderOfferArgs
+		var offer PauseRenderOfferArgs
+		err = json.Unmarshal(params, &offer)
+		if err != nil {
+			logger.Manager.Package["hedera"].Error().Msg(fmt.Sprintf("Message received but not processed: %s", string(message.Contents)))
+			return
 		}
 
+		// the render offer of the operator is no longer active
+		nm._recordOfferPause(_messageSender(message), offer.RenderOfferCID, message.ConsensusTimestamp)
+
+		// log trace event
+		logger.Manager.Package["node"].Debug().Msg("Received a paused render offer:")
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Render offer document: %v", offer.RenderOfferCID))
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Paused by: %v", _messageSender(message)))
+
 	}
 
 }
 
+// helper function to pin a document of a job queue message in the background
+func (nm *PackageManager) _pinInBackground(cid string) {
+
+	if nm.jobQueueProcessor == nil {
+		return
+	}
+	nm.jobQueueProcessor.Submit("pin "+cid, func(ctx context.Context) error {
+		_, err := ipfs.Manager.PinObjectContext(ctx, cid)
+		return err
+	})
+
+}
+
 // BLENDER BENCHMARK TOOL CONTROL
 // #############################################################################
+// running benchmarks of this node by tool
+var runningBenchmarks = struct {
+	sync.Mutex
+	runs map[*BlenderBenchmarkTool]benchmarkRun
+}{runs: map[*BlenderBenchmarkTool]benchmarkRun{}}
+
+// a running benchmark
+type benchmarkRun struct {
+	Version string             // Blender version of the benchmark
+	Cancel  context.CancelFunc // stops the benchmark
+}
+
+// Get a copy of the benchmark result
+func (tool *BlenderBenchmarkTool) GetResult() []BlenderBenchmarkResult {
+
+	tool.mutex.RLock()
+	defer tool.mutex.RUnlock()
+
+	return append([]BlenderBenchmarkResult(nil), tool.Result...)
+
+}
+
+// Replace the benchmark result
+func (tool *BlenderBenchmarkTool) SetResult(result []BlenderBenchmarkResult) {
+
+	tool.mutex.Lock()
+	defer tool.mutex.Unlock()
+
+	tool.Result = result
+
+}
+
+// Encode the benchmark tool as JSON (e.g., as part of a render offer)
+// NOTE: The result is read under the lock, so a running benchmark does not
+// change it during the encoding.
+func (tool *BlenderBenchmarkTool) MarshalJSON() ([]byte, error) {
+
+	tool.mutex.RLock()
+	defer tool.mutex.RUnlock()
+
+	return json.Marshal(struct {
+		Result []BlenderBenchmarkResult
+	}{tool.Result})
+
+}
+
+// Decode the benchmark tool from JSON
+func (tool *BlenderBenchmarkTool) UnmarshalJSON(data []byte) error {
+
+	var decoded struct {
+		Result []BlenderBenchmarkResult
+	}
+	err := json.Unmarshal(data, &decoded)
+	if err != nil {
+		return err
+	}
+	tool.SetResult(decoded.Result)
+
+	return nil
+
+}
+
 // Execute the command line interface for the Blender benchmark tool
-func (tool *BlenderBenchmarkTool) _execute(path string, args []string) (string, error) {
+// NOTE: The tool is terminated (including its child processes), when the
+// context is canceled (e.g., when the app shuts down).
+func (tool *BlenderBenchmarkTool) _execute(ctx context.Context, path string, args []string) (string, error) {
 	var err error
 
 	// Check if 'path' is pointing to an existing file
@@ -1739,25 +3018,116 @@ func (tool *BlenderBenchmarkTool) _execute(path string, args []string) (string,
 	}
 
 	// get supported blender versions
-	cmd := exec.Command(path, args...)
+	// NOTE: The downloads of the tool are kept in the cache directory.
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Env = append(os.Environ(), "XDG_CACHE_HOME="+tool.CachePath())
+	_terminateProcessGroup(cmd)
+	cmd.WaitDelay = RENDERHIVE_CONFIG_BENCHMARK_WAIT_DELAY
 	output, err := cmd.Output()
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if err != nil {
+		logger.Manager.Errorln("Error:", err)
+		return "", err
+	}
+
+	return string(output), err
+
+}
+
+// Run the Blender benchmark tool with the specified Blender version and
+// rendering device
+// NOTE: Only one benchmark of the tool runs at a time; further calls wait for
+// it. The benchmark is stopped, when the context is canceled or with
+// CancelBenchmarks(). A stopped benchmark does not change the result.
+func (tool *BlenderBenchmarkTool) Run(ctx context.Context, ro *RenderOffer, benchmark_version string, benchmark_device string, benchmark_scene string) error {
+	var err error
+
+	tool.running.Lock()
+	defer tool.running.Unlock()
+
+	// register the benchmark, so it can be canceled
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	runningBenchmarks.Lock()
+	runningBenchmarks.runs[tool] = benchmarkRun{Version: benchmark_version, Cancel: cancel}
+	runningBenchmarks.Unlock()
+	defer func() {
+		runningBenchmarks.Lock()
+		delete(runningBenchmarks.runs, tool)
+		runningBenchmarks.Unlock()
+	}()
+
+	err = tool._run(ctx, ro, benchmark_version, benchmark_device, benchmark_scene)
+	if err != nil && ctx.Err() != nil {
+		return newRenderError(ErrBenchmarkCanceled, "The benchmark of Blender v%v was canceled. (Error: %w)", benchmark_version, ctx.Err())
+	}
+
+	return err
+
+}
+
+// Cancel the running benchmarks of a Blender version (or of all versions, if
+// the version is empty)
+// NOTE: Returns the number of canceled benchmarks.
+func CancelBenchmarks(version string) int {
+
+	runningBenchmarks.Lock()
+	defer runningBenchmarks.Unlock()
+
+	canceled := 0
+	for _, run := range runningBenchmarks.runs {
+		if version == "" || run.Version == version {
+			run.Cancel()
+			canceled++
+		}
+	}
+
+	// log event
+	if canceled > 0 {
+		logger.Manager.Package["node"].Info().Msg(fmt.Sprintf("Canceled %v running benchmark(s).", canceled))
+	}
+
+	return canceled
+
+}
+
+// helper function to parse the JSON output of a benchmark rendering
+// NOTE: The launcher may print an error as plain text instead of the JSON array,
+// so the output is checked before the result is used.
+func _parseBenchmarkOutput(output string) ([]BlenderBenchmarkResult, error) {
+	var result []BlenderBenchmarkResult
+
+	// a snippet of the output for the diagnosis
+	snippet := strings.TrimSpace(output)
+	if len(snippet) > RENDERHIVE_CONFIG_BENCHMARK_OUTPUT_SNIPPET {
+		snippet = snippet[:RENDERHIVE_CONFIG_BENCHMARK_OUTPUT_SNIPPET] + " ..."
+	}
+
+	if strings.TrimSpace(output) == "" {
+		return nil, fmt.Errorf("The benchmark tool returned no output.")
+	}
+	err := json.Unmarshal([]byte(output), &result)
 	if err != nil {
-		fmt.Println("Error:", err)
-		return "", err
+		return nil, fmt.Errorf("The benchmark tool returned an unexpected output: %v (Output: '%v')", err, snippet)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("The benchmark tool returned an empty result. (Output: '%v')", snippet)
 	}
 
-	return string(output), err
+	return result, nil
 
 }
 
-// Run the Blender benchmark tool with the specified Blender version and
-// rendering device
-func (tool *BlenderBenchmarkTool) Run(ro *RenderOffer, benchmark_version string, benchmark_device string, benchmark_scene string) error {
+// helper function to run the Blender benchmark tool (see Run())
+func (tool *BlenderBenchmarkTool) _run(ctx context.Context, ro *RenderOffer, benchmark_version string, benchmark_device string, benchmark_scene string) error {
 	var err error
 	var versions []string
 	var device_names []string
 	var device_types []string
 	var scenes []string
+	var result []BlenderBenchmarkResult
 	var ok bool
 
 	// if the Blender version is supported by this node
@@ -1767,18 +3137,16 @@ func (tool *BlenderBenchmarkTool) Run(ro *RenderOffer, benchmark_version string,
 		// log event
 		logger.Manager.Package["node"].Debug().Msg("Benchmarking a supported Blender version:")
 
-		// path to te benchmark tool
-		path, _ := filepath.Abs("benchmark/benchmark-launcher-cli")
-
-		// Check if 'path' is pointing to an existing file
-		if _, err = os.Stat(path); os.IsNotExist(err) {
+		// path to the benchmark tool
+		path, err := tool.LauncherPath()
+		if err != nil {
 			return err
 		}
 
 		// get list of Blender versions supported by this tool version
-		output, err := tool._execute(path, []string{"blender", "list"})
+		output, err := tool._execute(ctx, path, []string{"blender", "list"})
 		if err != nil {
-			return errors.New(fmt.Sprintf("Could not retrieve Blender benchmark tool version list. (Error: %v)", err))
+			return newRenderError(ErrBenchmarkUnavailable, "Could not retrieve Blender benchmark tool version list. (Error: %w)", err)
 		} else {
 
 			// scan lines
@@ -1793,22 +3161,22 @@ func (tool *BlenderBenchmarkTool) Run(ro *RenderOffer, benchmark_version string,
 		// check if 'benchmark_version' is supported
 		ok = InStringSlice(versions, benchmark_version)
 		if !ok {
-			return errors.New(fmt.Sprintf("Blender v%v is not supported by this Blender benchmark tool.", benchmark_version))
+			return newRenderError(ErrUnsupportedVersion, "Blender v%v is not supported by this Blender benchmark tool.", benchmark_version)
 		}
 
-		// download the suitable Blender version
-		output, err = tool._execute(path, []string{"blender", "download", benchmark_version})
+		// download the suitable Blender version (if not cached)
+		err = tool._download(ctx, path, "blender/"+benchmark_version, []string{"blender", "download", benchmark_version})
 		if err != nil {
-			return errors.New(fmt.Sprintf("Could not download blender version %v. (Error: %v)", benchmark_version, err))
+			return newRenderError(ErrBenchmarkUnavailable, "Could not download blender version %v. (Error: %w)", benchmark_version, err)
 		}
 
 		// log trace event
 		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Retrieving supported devices for Blender version: %v", benchmark_version))
 
 		// get list of devices
-		output, err = tool._execute(path, []string{"devices", "--blender-version", benchmark_version, "list"})
+		output, err = tool._execute(ctx, path, []string{"devices", "--blender-version", benchmark_version, "list"})
 		if err != nil {
-			return errors.New(fmt.Sprintf("Could not retrieve Blender benchmark tool device list. (Error: %v)", err))
+			return newRenderError(ErrBenchmarkUnavailable, "Could not retrieve Blender benchmark tool device list. (Error: %w)", err)
 		} else {
 
 			// scan lines
@@ -1830,18 +3198,18 @@ func (tool *BlenderBenchmarkTool) Run(ro *RenderOffer, benchmark_version string,
 		// check if 'benchmark_device' is supported
 		ok = (InStringSlice(device_names, benchmark_device) || InStringSlice(device_types, benchmark_device))
 		if !ok {
-			return errors.New(fmt.Sprintf("Device '%v' is not supported by this Blender benchmark tool.", benchmark_device))
+			return newRenderError(ErrInvalidArgument, "Device '%v' is not supported by this Blender benchmark tool.", benchmark_device)
 		} else if benchmark_device == "" {
-			return errors.New(fmt.Sprintf("No device was specified for the benchmark rendering."))
+			return newRenderError(ErrInvalidArgument, "No device was specified for the benchmark rendering.")
 		}
 
 		// log trace event
 		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Retrieving supported scenes for Blender version: %v", benchmark_version))
 
 		// get list of benchmark scenes
-		output, err = tool._execute(path, []string{"scenes", "--blender-version", benchmark_version, "list"})
+		output, err = tool._execute(ctx, path, []string{"scenes", "--blender-version", benchmark_version, "list"})
 		if err != nil {
-			return errors.New(fmt.Sprintf("Could not retrieve Blender benchmark tool scene list. (Error: %v)", err))
+			return newRenderError(ErrBenchmarkUnavailable, "Could not retrieve Blender benchmark tool scene list. (Error: %w)", err)
 		} else {
 
 			// scan lines
@@ -1861,31 +3229,35 @@ func (tool *BlenderBenchmarkTool) Run(ro *RenderOffer, benchmark_version string,
 		// check if 'benchmark_scene' is supported
 		ok = InStringSlice(scenes, benchmark_scene)
 		if !ok {
-			return errors.New(fmt.Sprintf("Scene '%v' is not supported by this Blender benchmark tool.", benchmark_scene))
+			return newRenderError(ErrInvalidArgument, "Scene '%v' is not supported by this Blender benchmark tool.", benchmark_scene)
 		} else if benchmark_scene == "" {
-			return errors.New(fmt.Sprintf("No scene was specified for the benchmark rendering."))
+			return newRenderError(ErrInvalidArgument, "No scene was specified for the benchmark rendering.")
 		} else {
 
-			// download the scene
-			output, err = tool._execute(path, []string{"scenes", "download", "--blender-version", benchmark_version, benchmark_scene})
+			// download the scene (if not cached)
+			err = tool._download(ctx, path, "scene/"+benchmark_version+"/"+benchmark_scene, []string{"scenes", "download", "--blender-version", benchmark_version, benchmark_scene})
 			if err != nil {
-				return errors.New(fmt.Sprintf("Could not download Blender benchmark scene '%v'. (Error: %v)", benchmark_scene, err))
+				return newRenderError(ErrBenchmarkUnavailable, "Could not download Blender benchmark scene '%v'. (Error: %w)", benchmark_scene, err)
 			}
 
 			// log trace event
 			logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Downloaded Benchmark scene '%v' and started benchmark rendering ...", benchmark_scene))
 
 			// start the benchmark
-			output, err = tool._execute(path, []string{"benchmark", "--blender-version", benchmark_version, "--device-type", "CPU", "--json", benchmark_scene})
+			output, err = tool._execute(ctx, path, []string{"benchmark", "--blender-version", benchmark_version, "--device-type", "CPU", "--json", benchmark_scene})
 			if err != nil {
-				return errors.New(fmt.Sprintf("Failed to execute benchmark rendering for scene '%v'. (Error: %v)", benchmark_scene, err))
+				return newRenderError(ErrBenchmarkUnavailable, "Failed to execute benchmark rendering for scene '%v'. (Error: %w)", benchmark_scene, err)
 			} else {
 
 				// parse the benchmark result
-				json.Unmarshal([]byte(output), &tool.Result)
+				result, err = _parseBenchmarkOutput(output)
+				if err != nil {
+					return newRenderError(ErrBenchmarkUnavailable, "The benchmark rendering for scene '%v' returned no valid result. (Error: %w)", benchmark_scene, err)
+				}
+				tool.SetResult(result)
 
 				// log trace event
-				logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] [*] Benchmark result: %v samples / min", tool.Result[0].Stats.SamplesPerMinute))
+				logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] [*] Benchmark result: %v samples / min", result[0].Stats.SamplesPerMinute))
 
 			}
 
@@ -1911,10 +3283,10 @@ func (tool *BlenderBenchmarkTool) Run(ro *RenderOffer, benchmark_version string,
 
 		// write the render request data into the file in JSON format
 		encoder := json.NewEncoder(benchmar_result_file)
-		encoder.Encode(tool.Result)
+		encoder.Encode(result)
 
 	} else {
-		err = errors.New(fmt.Sprintf("Blender v'%v' is not in the node's render offer.", blender.BuildVersion))
+		err = newRenderError(ErrUnsupportedVersion, "Blender v'%v' is not in the node's render offer.", blender.BuildVersion)
 	}
 
 	return err
@@ -1923,14 +3295,6 @@ func (tool *BlenderBenchmarkTool) Run(ro *RenderOffer, benchmark_version string,
 
 // BLENDER CONTROL
 // #############################################################################
-// TODO: Some notes on things to implement
-//
-//       1) A set of Python scripts could be stored on IPFS (immutability
-//          guarenteed by CID), which nodes can execute with the "--python" flag
-//          of Blender. These could be things like a script to set the region to
-//          render.
-//
-
 // Start Blender with command line flags and render the given blend_file
 func (b *BlenderAppData) Execute(args []string) error {
 	var err error
@@ -1948,13 +3312,17 @@ func (b *BlenderAppData) Execute(args []string) error {
 	// if ...
 
 	// Execute Blender in background mode
-	b.Cmd = exec.Command(b.Path, append([]string{"-b"}, args...)...)
+	b.Cmd, err = b._command(args)
+	if err != nil {
+		return err
+	}
 	b.Param = args
+	b.ExitCode, b.OutOfMemory, b.stopped, b.exitErr = 0, false, false, nil
 	b.StdOut, _ = b.Cmd.StdoutPipe()
 	b.StdErr, _ = b.Cmd.StderrPipe()
 	err = b.Cmd.Start()
 	if err != nil {
-		fmt.Println(err)
+		logger.Manager.Errorln(err)
 		return err
 	}
 
@@ -1965,9 +3333,42 @@ func (b *BlenderAppData) Execute(args []string) error {
 	// Print the process ID of the running Blender instance
 	logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf(" [#] PID: %v", b.Cmd.Process.Pid))
 
+	// check if this is a render (i.e., not just a version query or similar)
+	render := false
+	for _, arg := range args {
+		if arg == "-f" || arg == "--render-frame" || arg == "-a" || arg == "--render-anim" {
+			render = true
+			break
+		}
+	}
+
 	// check for both Blender output in go routine
-	go b.ProcessOutput("StdOut", b.StdOut)
-	go b.ProcessOutput("StdErr", b.StdErr)
+	var outputWG sync.WaitGroup
+	outputWG.Add(2)
+	go func() {
+		defer outputWG.Done()
+		b.ProcessOutput("StdOut", b.StdOut)
+	}()
+	go func() {
+		defer outputWG.Done()
+		b.ProcessOutput("StdErr", b.StdErr)
+	}()
+
+	// wait for the process to finish, detect crashes, and record the render metrics
+	// NOTE: Wait must only be called after all output was read
+	startTime := time.Now()
+	cmd := b.Cmd
+	done := make(chan struct{})
+	b.done = done
+	go func() {
+		outputWG.Wait()
+		waitErr := cmd.Wait()
+		b._exited(cmd, waitErr)
+		if render {
+			metrics.Manager.ObserveRender(time.Since(startTime), waitErr == nil)
+		}
+		close(done)
+	}()
 
 	return err
 
@@ -2054,53 +3455,50 @@ func (b *BlenderAppData) ProcessOutput(name string, output io.ReadCloser) error
 			// log event message
 			logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf("Blender v%v process (pid: %v) finished with 'Blender quit'.", b.BuildVersion, b.PID))
 
-			// store internally that the process is finished
-			b.Running = false
+			// NOTE: The output is read until the process closes it, since the
+			//       process is only finished, when it exited (see Wait()).
+			continue
+		}
 
-			// stop the loop
-			break
+		// OUT OF MEMORY
+		// ***********************************************************************
+		if blenderOutOfMemory.MatchString(line) {
+			b.OutOfMemory = true
 		}
 
 		// RENDER STATUS
 		// ***********************************************************************
-		// separate status line into substrings
-		str = strings.Split(line, "|")
-		if len(str) == 3 {
-
-			// Compile regular expression to match the values
-			statustest := regexp.MustCompile("Fra:[0-9]+ Mem:[0-9]*\\.[0-9]+M \\(Peak [0-9]*\\.[0-9]+M\\)")
-			timetest := regexp.MustCompile("(?i)Time:\\d\\d:\\d\\d\\.\\d\\d")
-
-			for _, substr := range str {
-				// if the expression matches
-				if statustest.MatchString(substr) {
-
-					// extract the values
-					re := regexp.MustCompile("(?:([0-9]*\\.[0-9]+M)|[0-9]+)")
-					matches := re.FindAllString(substr, -1)
-					b.Frame = matches[0]
-					b.Memory = matches[1]
-					b.Peak = matches[2]
-
-				} else if timetest.MatchString(substr) {
-
-					// extract the values
-					re := regexp.MustCompile("(?i)\\d\\d:\\d\\d\\.\\d\\d")
-					matches := re.FindAllString(substr, -1)
-					b.Time = matches[0]
+		// extract the render status from the status lines (e.g., "Fra:1 Mem:... | Time:... | ...")
+		if strings.HasPrefix(strings.TrimSpace(line), "Fra:") {
+
+			frame := b.Frame
+			if b.ParseStatusLine(line) {
+
+				// publish the progress of a render job for each frame
+				if b.RenderJob != "" && b.Frame != frame {
+					Manager.PublishEvent(EVENT_RENDER_PROGRESS, RenderProgressEventData{
+						RenderRequestCID: b.RenderJob,
+						Frame:            b.Frame,
+						Memory:           b.Memory,
+						Peak:             b.Peak,
+						Time:             b.Time,
+					})
 				}
-			}
 
-			// extract the status note
-			b.Note = str[2]
+				// log event message
+				logger.Manager.Package["node"].Trace().Msg("The current render status is:")
+				logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf(" [#] Current frame: %v", b.Frame))
+				logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf(" [#] Memory usage: %v", b.Memory))
+				logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf(" [#] Peak memory usage: %v", b.Peak))
+				logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf(" [#] Render time: %v", b.Time))
+				logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf(" [#] Status note: %v", b.Note))
 
-			// log event message
-			logger.Manager.Package["node"].Trace().Msg("The current render status is:")
-			logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf(" [#] Current frame: %v", b.Frame))
-			logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf(" [#] Memory usage: %v", b.Memory))
-			logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf(" [#] Peak memory usage: %v", b.Peak))
-			logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf(" [#] Render time: %v", b.Time))
-			logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf(" [#] Status note: %v", b.Note))
+			} else {
+
+				// log event message
+				logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf("Unrecognized Blender status line: %v", line))
+
+			}
 
 		}
 
@@ -2113,57 +3511,314 @@ func (b *BlenderAppData) ProcessOutput(name string, output io.ReadCloser) error
 
 }
 
+// regular expressions for the segments of the Blender status lines
+var (
+	blenderStatusFrame  = regexp.MustCompile(`^Fra:\s*(\d+)`)
+	blenderStatusMemory = regexp.MustCompile(`Mem:\s*([0-9.]+[KMG]?)\s*\(Peak\s*([0-9.]+[KMG]?)\)`)
+	blenderStatusDevice = regexp.MustCompile(`^Mem:\s*[0-9.]+[KMG]?,\s*Peak:\s*[0-9.]+[KMG]?$`)
+	blenderStatusTime   = regexp.MustCompile(`(?i)^Time:\s*((?:\d+:)?\d{1,2}:\d{2}\.\d{2})`)
+	blenderStatusLeft   = regexp.MustCompile(`(?i)^Remaining:`)
+)
+
+// Extract the render status from a Blender status line
+// NOTE:
+// The layout of the status line differs between the Blender versions and render
+// engines. The segments are therefore matched independently of their position
+// and the fields of absent segments keep their previous values. Returns false,
+// if the line contains neither a frame nor a render time.
+func (b *BlenderAppData) ParseStatusLine(line string) bool {
+
+	recognized := false
+	notes := []string{}
+	for i, segment := range strings.Split(line, "|") {
+		segment = strings.TrimSpace(segment)
+
+		// the first segment contains the frame and the memory usage
+		if i == 0 {
+			if match := blenderStatusFrame.FindStringSubmatch(segment); match != nil {
+				b.Frame = match[1]
+				recognized = true
+			}
+			if match := blenderStatusMemory.FindStringSubmatch(segment); match != nil {
+				b.Memory = match[1]
+				b.Peak = match[2]
+			}
+			continue
+		}
+
+		if match := blenderStatusTime.FindStringSubmatch(segment); match != nil {
+			b.Time = match[1]
+			recognized = true
+		} else if match := blenderStatusMemory.FindStringSubmatch(segment); match != nil {
+			b.Memory = match[1]
+			b.Peak = match[2]
+		} else if !blenderStatusDevice.MatchString(segment) && !blenderStatusLeft.MatchString(segment) && segment != "" {
+			notes = append(notes, segment)
+		}
+	}
+
+	// the remaining segments describe the render status
+	if recognized && len(notes) > 0 {
+		b.Note = strings.Join(notes, " | ")
+	}
+
+	return recognized
+
+}
+
 // COMMAND LINE INTERFACE - RENDER REQUESTS & OFFERS
 // #############################################################################
+// Create the CLI command to manage the render offers of this node
+func (nm *PackageManager) CreateCommandOffer() *cobra.Command {
+
+	// flags for the 'offer' command
+	var list bool
+	var filter RenderListFilter
+	var from, to string
+
+	// create a 'offer' command for the node
+	command := &cobra.Command{
+		Use:   "offer",
+		Short: "Manage the node's render offers",
+		Long:  "This command is for listing the render offers of this node.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			// list the render offers
+			if list {
+
+				// get the date range
+				filter.From, filter.To, err = _parseDateRange(from, to)
+				if err == nil {
+					var offers []*RenderOffer
+					var total int
+					offers, total, err = nm.ListRenderOffers(filter)
+					if err == nil {
+
+						logger.Manager.Println("")
+						if total == 0 {
+							logger.Manager.Println("The node has no matching render offers.")
+							logger.Manager.Println("")
+							return nil
+						}
+						logger.Manager.Printf("The node has %v matching render offers (showing %v from offset %v):\n", total, len(offers), filter.Offset)
+
+						for _, offer := range offers {
+							versions := []string{}
+							for _, blender := range offer.BlenderVersions {
+								versions = append(versions, blender.Version)
+							}
+							logger.Manager.Resultf(" [#] CID: %v (Blender: %v, Price: %v, State: %v, Created: %v) \n", offer.DocumentCID, strings.Join(versions, ", "), offer.Price, offer.RepositoryState(), offer.CreatedTimestamp.Format(time.DateTime))
+						}
+						logger.Manager.Println("")
+
+					}
+				}
+				if err != nil {
+
+					logger.Manager.Println("")
+					return fmt.Errorf("Could not list the render offers: %w", err)
+
+				}
+
+			}
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().BoolVarP(&list, "list", "l", false, "List the render offers of the node")
+	_addListFilterFlags(command, &filter, &from, &to)
+
+	// add the subcommands
+	command.AddCommand(nm.CreateCommandOfferShow())
+	command.AddCommand(nm.CreateCommandOfferActivate())
+	command.AddCommand(nm.CreateCommandOfferDeactivate())
+
+	return command
+
+}
+
+// Create the CLI command to add a render offer to the node's active render offers
+func (nm *PackageManager) CreateCommandOfferActivate() *cobra.Command {
+
+	// create an 'activate' command for the render offers
+	command := &cobra.Command{
+		Use:   "activate <cid>",
+		Short: "Activate a render offer of this node",
+		Long:  "This command adds a render offer of this node to its active render offers. The node may have several active render offers at once (e.g., a CPU-only offer and a GPU offer with different prices). Each render job is rendered for the active offer, which applies best to its render request.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			offer, err := nm.GetRenderOffer(args[0])
+			if err == nil {
+				err = nm.ActivateRenderOffer(offer)
+			}
+			if err != nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not activate the render offer: %w", err)
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Printf("Render offer '%v' is active. The node has %v active render offers.\n", offer.DocumentCID, len(nm.GetActiveRenderOffers()))
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	return command
+
+}
+
+// Create the CLI command to remove a render offer from the node's active render offers
+func (nm *PackageManager) CreateCommandOfferDeactivate() *cobra.Command {
+
+	// create a 'deactivate' command for the render offers
+	command := &cobra.Command{
+		Use:   "deactivate <cid>",
+		Short: "Deactivate a render offer of this node",
+		Long:  "This command removes a render offer from the active render offers of this node, so that no render jobs are claimed for it anymore. The offer stays inactive after a restart, until it is activated again. To also let the network know, pause the render offer.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			err := nm.DeactivateRenderOffer(args[0])
+			if err != nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not deactivate the render offer: %w", err)
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Printf("Render offer '%v' is no longer active. The node has %v active render offers.\n", args[0], len(nm.GetActiveRenderOffers()))
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	return command
+
+}
+
+// Create the CLI command to show the render offers of an operator in the render hive
+func (nm *PackageManager) CreateCommandOfferShow() *cobra.Command {
+
+	// create a 'show' command for the render offers
+	command := &cobra.Command{
+		Use:   "show <accountid>",
+		Short: "Show the render offers of an operator",
+		Long:  "This command shows the active render offers of an operator in the render hive. The offers are looked up from the announcements on the job queue topic and the render offer documents are fetched from IPFS.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			offers, err := nm.GetOperatorOffers(args[0])
+			if err != nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not get the render offers: %w", err)
+
+			}
+
+			logger.Manager.Println("")
+			if len(offers) == 0 {
+				logger.Manager.Printf("The operator '%v' has no active render offers.\n", args[0])
+				logger.Manager.Println("")
+				return nil
+			}
+			logger.Manager.Printf("The operator '%v' has %v active render offers:\n", args[0], len(offers))
+
+			for _, offer := range offers {
+				versions := []string{}
+				for _, blender := range offer.BlenderVersions {
+					versions = append(versions, blender.Version)
+				}
+				logger.Manager.Resultf(" [#] CID: %v (Blender: %v, Price: %v, Created: %v, Submitted: %v) \n", offer.DocumentCID, strings.Join(versions, ", "), offer.Price, offer.CreatedTimestamp.Format(time.DateTime), offer.SubmittedTimestamp.Format(time.DateTime))
+			}
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	return command
+
+}
+
 // Create the CLI command to manage the render requests of this node
 func (nm *PackageManager) CreateCommandRequest() *cobra.Command {
 
 	// flags for the 'request' command
 	var list bool
+	var filter RenderListFilter
+	var from, to string
 
 	// create a 'blender' command for the node
 	command := &cobra.Command{
 		Use:   "request",
 		Short: "Manage the node's render requests",
 		Long:  "This command is for adding/removing/editing the render requests of this node.",
-		Run: func(cmd *cobra.Command, args []string) {
-
-			// if a render offer exists
-			if nm.Renderer.Requests != nil && len(nm.Renderer.Requests) > 0 {
-
-				// list all Blender versions
-				if list {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			// list the render requests
+			if list {
+
+				// get the date range
+				filter.From, filter.To, err = _parseDateRange(from, to)
+				if err == nil {
+					var requests []*RenderRequest
+					var total int
+					requests, total, err = nm.ListRenderRequests(filter)
+					if err == nil {
+
+						logger.Manager.Println("")
+						if total == 0 {
+							logger.Manager.Println("The node has no matching render requests.")
+							logger.Manager.Println("")
+							return nil
+						}
+						logger.Manager.Printf("The node has %v matching render requests (showing %v from offset %v):\n", total, len(requests), filter.Offset)
 
-					fmt.Println("")
-					fmt.Println("The node has the following render requests:")
+						for _, request := range requests {
+							logger.Manager.Resultf(" [#] ID: %v for Blender file '%v' (Blender: %v, State: %v, Created: %v) \n", request.ID, request.BlenderFile.Path, request.Version, request.RepositoryState(), request.CreatedTimestamp.Format(time.DateTime))
+						}
+						logger.Manager.Println("")
 
-					// find each Blender version added to the node
-					for _, request := range nm.Renderer.Requests {
-						fmt.Printf(" [#] ID: %v for Blender file '%v' (Submitted: %v) \n", request.ID, request.BlenderFile.Path, (request.Receipt != nil))
 					}
-					fmt.Println("")
-
 				}
+				if err != nil {
 
-			} else {
+					logger.Manager.Println("")
+					return fmt.Errorf("Could not list the render requests: %w", err)
+
+				}
 
-				fmt.Println("")
-				fmt.Println(fmt.Errorf("The node has no render requests."))
-				fmt.Println("")
 			}
 
-			return
+			return nil
 
 		},
 	}
 
 	// add command flags
-	command.Flags().BoolVarP(&list, "list", "l", false, "List all the render requests of the node")
+	command.Flags().BoolVarP(&list, "list", "l", false, "List the render requests of the node")
+	_addListFilterFlags(command, &filter, &from, &to)
 
 	// add the subcommands
 	command.AddCommand(nm.CreateCommandRequest_Add())
 	command.AddCommand(nm.CreateCommandRequest_Remove())
 	command.AddCommand(nm.CreateCommandRequest_Submit())
+	command.AddCommand(nm.CreateCommandRequest_Verify())
+	command.AddCommand(nm.CreateCommandRequest_Aggregate())
 	// command.AddCommand(nm.CreateCommandRequest_Pause())
 	// command.AddCommand(nm.CreateCommandRequest_Revoke())
 
@@ -2171,6 +3826,41 @@ func (nm *PackageManager) CreateCommandRequest() *cobra.Command {
 
 }
 
+// helper function to add the filter flags of the listing commands
+func _addListFilterFlags(command *cobra.Command, filter *RenderListFilter, from *string, to *string) {
+
+	command.Flags().StringVarP(&filter.State, "state", "s", "", "Only list documents in this state (created, submitted, paused, cancelled)")
+	command.Flags().StringVarP(&filter.Version, "version", "v", "", "Only list documents of this Blender version")
+	command.Flags().StringVarP(&filter.Owner, "owner", "o", "", "Only list documents of this owner account ID")
+	command.Flags().StringVarP(from, "from", "f", "", "Only list documents created on or after this date (YYYY-MM-DD)")
+	command.Flags().StringVarP(to, "to", "t", "", "Only list documents created before this date (YYYY-MM-DD)")
+	command.Flags().IntVarP(&filter.Offset, "offset", "", 0, "The number of matching documents to skip")
+	command.Flags().IntVarP(&filter.Limit, "limit", "n", 20, "The maximum number of documents to list (0 = all)")
+
+}
+
+// helper function to parse the date range of the listing commands
+func _parseDateRange(from string, to string) (time.Time, time.Time, error) {
+	var err error
+	var fromTime, toTime time.Time
+
+	if from != "" {
+		fromTime, err = time.ParseInLocation(time.DateOnly, from, time.Local)
+		if err != nil {
+			return fromTime, toTime, newRenderError(ErrInvalidArgument, "Invalid date '%v'.", from)
+		}
+	}
+	if to != "" {
+		toTime, err = time.ParseInLocation(time.DateOnly, to, time.Local)
+		if err != nil {
+			return fromTime, toTime, newRenderError(ErrInvalidArgument, "Invalid date '%v'.", to)
+		}
+	}
+
+	return fromTime, toTime, nil
+
+}
+
 // Create the CLI command to add a new render request for this node
 func (nm *PackageManager) CreateCommandRequest_Add() *cobra.Command {
 
@@ -2179,35 +3869,39 @@ func (nm *PackageManager) CreateCommandRequest_Add() *cobra.Command {
 	var blender_file string
 	var render_price float64
 	var this_node bool
+	var pack bool
+	var priority int
+	var deadline string
+	var frames_per_task int
+	var region_rows int
+	var region_columns int
+	var setup_script string
 
 	// create a 'request add' command for the node
 	command := &cobra.Command{
 		Use:   "add",
 		Short: "Add a Blender version to the node's render offer",
 		Long:  "This command is for adding a Blender version to the node's render offer.",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
 			// if the map was not correctly initialized
 			if nm.Renderer.Requests != nil {
 
 				// add a Blender version
 				if blender_version != "" && blender_file != "" && render_price > 0 {
-					fmt.Println("")
+					logger.Manager.Println("")
 
 					// Check if path is pointing to an existing blender file
 					fileInfo, err := os.Stat(blender_file)
 					if !os.IsNotExist(err) {
 
 						if !fileInfo.Mode().IsRegular() || !strings.HasSuffix(fileInfo.Name(), ".blend") {
-							fmt.Println(fmt.Errorf("The given path '%v' is not pointing to a regular '.blend' file. \n", blender_file))
-							return
+							return fmt.Errorf("The given path '%v' is not pointing to a regular '.blend' file.", blender_file)
 						}
 
 					} else {
 
-						fmt.Println(fmt.Errorf(err.Error()))
-						fmt.Println("")
-						return
+						return err
 
 					}
 
@@ -2223,50 +3917,104 @@ func (nm *PackageManager) CreateCommandRequest_Add() *cobra.Command {
 						ThisNode:    this_node,
 					}
 
+					// Set the priority and the deadline of the render request
+					deadlineTime := time.Time{}
+					if deadline != "" {
+						deadlineTime, err = time.Parse(time.RFC3339, deadline)
+						if err != nil {
+							return fmt.Errorf("Invalid deadline '%v' (expected format: 2006-01-02T15:04:05Z07:00).", deadline)
+						}
+					}
+					err = request.SetSchedule(priority, deadlineTime)
+					if err != nil {
+						return err
+					}
+
+					// Split the frame range into subtasks
+					err = request.SetFramesPerTask(frames_per_task)
+					if err != nil {
+						return err
+					}
+
+					// Split each frame into regions
+					err = request.SetRegionGrid(region_rows, region_columns)
+					if err != nil {
+						return err
+					}
+
+					// Set the python setup script
+					err = request.SetSetupScript(setup_script)
+					if err != nil {
+						return err
+					}
+
+					// Pack the external data into a copy of the Blender file
+					if pack {
+						err = nm.PackRenderRequest(request)
+						if err != nil {
+							return fmt.Errorf("Could not pack the Blender file: %w", err)
+						}
+					}
+
+					// Inspect the render settings of the Blender file
+					_, err = nm.InspectRenderRequest(request, nil)
+					if err != nil {
+						logger.Manager.Errorln(fmt.Errorf("Could not inspect the Blender file: %v", err))
+					}
+
 					// Add the render request to the node
 					id, err := nm.AddRenderRequest(request, true)
 
 					if err != nil {
-						fmt.Println(err)
+						return err
 					} else {
 
-						fmt.Println("Added a new render request to the node:")
-						fmt.Printf(" [#] ID: %v\n", id)
-						fmt.Printf(" [#] Blender file: %v\n", blender_file)
-						fmt.Printf(" [#] Blender file CID: %v\n", request.BlenderFile.CID)
-						fmt.Printf(" [#] Requested Blender version: %v\n", blender_version)
-						fmt.Printf(" [#] Maximum price: %v USD / BBP \n", render_price)
-						fmt.Printf(" [#] Node participates: %v \n", this_node)
+						logger.Manager.Println("Added a new render request to the node:")
+						logger.Manager.Resultf(" [#] ID: %v\n", id)
+						logger.Manager.Resultf(" [#] Blender file: %v\n", blender_file)
+						if pack {
+							logger.Manager.Resultf(" [#] Packed Blender file: %v\n", request.BlenderFile.Path)
+						}
+						logger.Manager.Resultf(" [#] Blender file CID: %v\n", request.BlenderFile.CID)
+						logger.Manager.Resultf(" [#] Requested Blender version: %v\n", blender_version)
+						logger.Manager.Resultf(" [#] Maximum price: %v USD / BBP \n", render_price)
+						logger.Manager.Resultf(" [#] Node participates: %v \n", this_node)
+						logger.Manager.Resultf(" [#] Priority: %v \n", priority)
+						if !deadlineTime.IsZero() {
+							logger.Manager.Resultf(" [#] Deadline: %v \n", deadlineTime.Format(time.RFC3339))
+						}
+						if frames_per_task > 0 {
+							settings := request.BlenderFile.Settings
+							logger.Manager.Resultf(" [#] Subtasks: %v (%v frames each) \n", len(SplitFrameRange(settings.FrameStart, settings.FrameEnd, settings.FrameStep, frames_per_task)), frames_per_task)
+						}
+						if setup_script != "" {
+							logger.Manager.Resultf(" [#] Setup script: %v \n", setup_script)
+						}
 
 					}
-					fmt.Println("")
+					logger.Manager.Println("")
 
 				} else {
 
-					fmt.Println("")
-					fmt.Println(fmt.Errorf("Failed to create the render request."))
+					logger.Manager.Println("")
 					if blender_version == "" {
-						fmt.Println(fmt.Errorf(" [#] Missing a required parameter: Blender version (--blender-version)."))
+						return fmt.Errorf("Failed to create the render request: Missing a required parameter: Blender version (--blender-version).")
 					}
 					if blender_file == "" {
-						fmt.Println(fmt.Errorf(" [#] Missing a required parameter: Blender file (--blender-file)."))
-					}
-					if render_price == 0 {
-						fmt.Println(fmt.Errorf(" [#] Missing a required parameter: Maximum render price (--render-price)."))
+						return fmt.Errorf("Failed to create the render request: Missing a required parameter: Blender file (--blender-file).")
 					}
-					fmt.Println("")
+					return fmt.Errorf("Failed to create the render request: The maximum render price (--render-price) must be positive.")
 
 				}
 
 			} else {
 
-				fmt.Println("")
-				fmt.Println(fmt.Errorf("There was an error in initializing the render requests."))
-				fmt.Println("")
+				logger.Manager.Println("")
+				return fmt.Errorf("There was an error in initializing the render requests.")
 
 			}
 
-			return
+			return nil
 
 		},
 	}
@@ -2276,6 +4024,13 @@ func (nm *PackageManager) CreateCommandRequest_Add() *cobra.Command {
 	command.Flags().StringVarP(&blender_file, "blender-file", "f", "", "The path to the Blender file to be rendered")
 	command.Flags().Float64VarP(&render_price, "render-price", "p", 0, "The maximum price the node will pay for rendering")
 	command.Flags().BoolVarP(&this_node, "this-node", "t", false, "Set if this node shall participate in rendering its own request")
+	command.Flags().BoolVarP(&pack, "pack", "k", false, "Pack all external data into a copy of the Blender file before the request is added")
+	command.Flags().IntVarP(&priority, "priority", "r", 0, "The priority of the render request (higher values are rendered first)")
+	command.Flags().StringVarP(&deadline, "deadline", "d", "", "The datetime by which all frames must be rendered (RFC 3339)")
+	command.Flags().IntVarP(&frames_per_task, "frames-per-task", "n", 0, "Split the frame range into subtasks of this many frames, which are rendered by different nodes (0 = single job)")
+	command.Flags().IntVar(&region_rows, "region-rows", 0, "Split each frame into this many rows of regions, which are rendered by different nodes (0 = full frame)")
+	command.Flags().IntVar(&region_columns, "region-columns", 0, "Split each frame into this many columns of regions, which are rendered by different nodes (0 = full frame)")
+	command.Flags().StringVarP(&setup_script, "setup-script", "s", "", "The CID of a python script, which Blender executes before rendering (only executed by nodes that trust it)")
 
 	return command
 
@@ -2292,7 +4047,7 @@ func (nm *PackageManager) CreateCommandRequest_Remove() *cobra.Command {
 		Use:   "remove",
 		Short: "Remove a render request from this node",
 		Long:  "This command is for removing a render request from this node. In case it was submitted to the network, it will be cancelled and revoked.",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
 			// if render requests exists
 			if nm.Renderer.Requests != nil {
@@ -2301,53 +4056,44 @@ func (nm *PackageManager) CreateCommandRequest_Remove() *cobra.Command {
 				if id != -1 {
 
 					// if the parsed version is supported by the node
-					_, ok := nm.Renderer.Requests[strconv.Itoa(id)]
+					_, ok := nm.GetRenderRequestByID(id)
 					if ok {
 
 						// Remove the render request
 						err := nm.RemoveRenderRequest(id)
 						if err != nil {
 
-							fmt.Println("")
-							fmt.Println(fmt.Errorf(err.Error()))
-							fmt.Println("")
-
-							return
+							logger.Manager.Println("")
+							return err
 
 						}
 
-						fmt.Println("")
-						fmt.Printf("Removed render request with ID %v from this node. \n", id)
-						fmt.Println("")
+						logger.Manager.Println("")
+						logger.Manager.Printf("Removed render request with ID %v from this node. \n", id)
+						logger.Manager.Println("")
 
 					} else {
 
-						fmt.Println("")
-						fmt.Println(fmt.Errorf("There is no render request with ID %v.", id))
-						fmt.Println("")
+						logger.Manager.Println("")
+						return fmt.Errorf("There is no render request with ID %v.", id)
 
 					}
 
 				} else {
 
-					fmt.Println("")
-					fmt.Println(fmt.Errorf("Failed to remove the render request."))
-					if id == -1 {
-						fmt.Println(fmt.Errorf(" [#] Missing a required parameter: Request ID (--request-id)."))
-					}
-					fmt.Println("")
+					logger.Manager.Println("")
+					return fmt.Errorf("Failed to remove the render request: Missing a required parameter: Request ID (--request-id).")
 
 				}
 
 			} else {
 
-				fmt.Println("")
-				fmt.Println(fmt.Errorf("The node has no render requests."))
-				fmt.Println("")
+				logger.Manager.Println("")
+				return fmt.Errorf("The node has no render requests.")
 
 			}
 
-			return
+			return nil
 
 		},
 	}
@@ -2370,7 +4116,7 @@ func (nm *PackageManager) CreateCommandRequest_Submit() *cobra.Command {
 		Use:   "submit",
 		Short: "Submit a render request from this node to the render hve",
 		Long:  "This command is for submitting a render request from this node to the render hive network for rendering. Several transactions will be performed to inform the smart contract and to announce the new render request on the HCS topics.",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
 			// if render requests exists
 			if nm.Renderer.Requests != nil {
@@ -2379,55 +4125,46 @@ func (nm *PackageManager) CreateCommandRequest_Submit() *cobra.Command {
 				if id != -1 {
 
 					// if the parsed version is supported by the node
-					request, ok := nm.Renderer.Requests[strconv.Itoa(id)]
+					request, ok := nm.GetRenderRequestByID(id)
 					if ok {
 
 						// Submit the render request
 						err := nm.SubmitRenderRequest(id)
 						if err != nil {
 
-							fmt.Println("")
-							fmt.Println(fmt.Errorf(err.Error()))
-							fmt.Println("")
-
-							return
+							logger.Manager.Println("")
+							return err
 
 						}
 
-						fmt.Println("")
-						fmt.Printf("Submitted render request with ID %v to the render hive. \n", id)
-						fmt.Printf(" [#] Blender file (CID): %v. \n", request.BlenderFile.CID)
-						fmt.Printf(" [#] Render request document (CID): %v. \n", request.DocumentCID)
-						fmt.Println("")
+						logger.Manager.Println("")
+						logger.Manager.Printf("Submitted render request with ID %v to the render hive. \n", id)
+						logger.Manager.Resultf(" [#] Blender file (CID): %v. \n", request.BlenderFile.CID)
+						logger.Manager.Resultf(" [#] Render request document (CID): %v. \n", request.DocumentCID)
+						logger.Manager.Println("")
 
 					} else {
 
-						fmt.Println("")
-						fmt.Println(fmt.Errorf("There is no render request with ID %v.", id))
-						fmt.Println("")
+						logger.Manager.Println("")
+						return fmt.Errorf("There is no render request with ID %v.", id)
 
 					}
 
 				} else {
 
-					fmt.Println("")
-					fmt.Println(fmt.Errorf("Failed to submit the render request."))
-					if id == -1 {
-						fmt.Println(fmt.Errorf(" [#] Missing a required parameter: Request ID (--request-id)."))
-					}
-					fmt.Println("")
+					logger.Manager.Println("")
+					return fmt.Errorf("Failed to submit the render request: Missing a required parameter: Request ID (--request-id).")
 
 				}
 
 			} else {
 
-				fmt.Println("")
-				fmt.Println(fmt.Errorf("The node has no render requests."))
-				fmt.Println("")
+				logger.Manager.Println("")
+				return fmt.Errorf("The node has no render requests.")
 
 			}
 
-			return
+			return nil
 
 		},
 	}
@@ -2452,33 +4189,35 @@ func (nm *PackageManager) CreateCommandBlender() *cobra.Command {
 		Use:   "blender",
 		Short: "Manage the node's Blender versions",
 		Long:  "This command is for adding/removing Blender versions from the node's render offer. You can also start Blender in the background mode for rendering purposes.",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
 			// if a render offer exists
-			if nm.Renderer.ActiveOffer != nil {
+			if len(nm.GetActiveRenderOffers()) > 0 {
 
 				// list all Blender versions
 				if list {
 
-					fmt.Println("")
-					fmt.Println("The node offers the following Blender versions for rendering:")
+					logger.Manager.Println("")
+					logger.Manager.Println("The node offers the following Blender versions for rendering:")
 
-					// find each Blender version added to the node
-					for _, blender := range nm.Renderer.ActiveOffer.Blender {
-						fmt.Printf(" [#] Version: %v (Engines: %v | Devices: %v) \n", blender.BuildVersion, strings.Join(blender.Engines, ", "), strings.Join(blender.Devices, ", "))
+					// find each Blender version added to the active render offers
+					for _, offer := range nm.GetActiveRenderOffers() {
+						logger.Manager.Resultf(" [#] Render offer: %v (Price: %v, Paused: %v) \n", _activeOfferName(offer), offer.Price, offer.Paused)
+						for _, blender := range offer.Blender {
+							logger.Manager.Resultf("     - Version: %v (Engines: %v | Feature sets: %v | Devices: %v) \n", blender.BuildVersion, strings.Join(blender.Engines, ", "), strings.Join(blender.FeatureSets, ", "), strings.Join(blender.Devices, ", "))
+						}
 					}
-					fmt.Println("")
+					logger.Manager.Println("")
 
 				}
 
 			} else {
 
-				fmt.Println("")
-				fmt.Println(fmt.Errorf("The node has no render offer."))
-				fmt.Println("")
+				logger.Manager.Println("")
+				return fmt.Errorf("The node has no render offer. Use 'blender versions list' to list the Blender versions installed on this node.")
 			}
 
-			return
+			return nil
 
 		},
 	}
@@ -2491,6 +4230,8 @@ func (nm *PackageManager) CreateCommandBlender() *cobra.Command {
 	command.AddCommand(nm.CreateCommandBlender_Remove())
 	command.AddCommand(nm.CreateCommandBlender_Run())
 	command.AddCommand(nm.CreateCommandBlender_Benchmark())
+	command.AddCommand(nm.CreateCommandBlender_Versions())
+	command.AddCommand(nm.CreateCommandBlender_Supported())
 
 	return command
 
@@ -2501,54 +4242,68 @@ func (nm *PackageManager) CreateCommandBlender_Add() *cobra.Command {
 
 	// flags for the 'blender' command
 	var version string
+	var offerCID string
 	var path string
 	var engines []string
+	var featureSets []string
 	var devices []string
 	var threads uint8
+	var benchmark bool
 
 	// create a 'blender add' command for the node
 	command := &cobra.Command{
 		Use:   "add",
 		Short: "Add a Blender version to the node's render offer",
 		Long:  "This command is for adding a Blender version to the node's render offer.",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
-			// if a render offer exists
-			if nm.Renderer.ActiveOffer != nil {
+			// if the render offer exists
+			offer, err := nm.GetActiveRenderOffer(offerCID)
+			if err == nil {
 
 				// add a Blender version
 				if len(version) != 0 {
-					fmt.Println("")
+					logger.Manager.Println("")
 
 					// Check if path is pointing to an existing file
 					if _, err := os.Stat(path); os.IsNotExist(err) {
-						fmt.Println(fmt.Errorf("The given path '%v' is not a valid path.", path))
-						return
+						return fmt.Errorf("The given path '%v' is not a valid path.", path)
 					}
 
 					// Add a new Blender version to the node's render offer
-					err := nm.Renderer.ActiveOffer.AddBlenderVersion(version, &engines, &devices, threads)
+					err := offer.AddBlenderVersion(version, &engines, &featureSets, &devices, threads)
 					if err != nil {
-						fmt.Println("")
-						fmt.Println(err)
+						logger.Manager.Println("")
+						return err
 					} else {
 
-						fmt.Printf("Added the Blender v'%v' with path '%v' to the render offer. \n", nm.Renderer.ActiveOffer.Blender[version].BuildVersion, path)
+						// render a quick benchmark for an initial render score
+						if benchmark {
+							err = offer.QuickBenchmark(nm.Context(), version)
+							if err != nil {
+								logger.Manager.Errorln(fmt.Errorf("Could not render the quick benchmark: %v", err))
+							}
+						}
+
+						blender := offer.Blender[version]
+						logger.Manager.Printf("Added the Blender v'%v' with path '%v' to the render offer. \n", blender.BuildVersion, path)
+						if !blender.Verified {
+							logger.Manager.Println("The build info of this Blender version could not be verified.")
+						}
 
 					}
-					fmt.Println("")
+					logger.Manager.Println("")
 
 				}
 
 			} else {
 
-				fmt.Println("")
-				fmt.Println(fmt.Errorf("The node has no render offer."))
-				fmt.Println("")
+				logger.Manager.Println("")
+				return err
 
 			}
 
-			return
+			return nil
 
 		},
 	}
@@ -2556,9 +4311,12 @@ func (nm *PackageManager) CreateCommandBlender_Add() *cobra.Command {
 	// add command flag parameters
 	command.Flags().StringVarP(&version, "version", "v", "", "The version of Blender to be used")
 	command.Flags().StringVarP(&path, "path", "p", "", "The path to a Blender executable on this computer")
-	command.Flags().StringSliceVarP(&engines, "engines", "E", GetBlenderEngineString([]uint8{BLENDER_RENDER_ENGINE_OPTIONS}), "The supported engines (only EEVEE and CYCLES)")
+	command.Flags().StringSliceVarP(&engines, "engines", "E", []string{}, "The supported engines (EEVEE, EEVEE_NEXT, or CYCLES; default: all engines of the Blender version)")
+	command.Flags().StringSliceVarP(&featureSets, "feature-sets", "F", []string{}, "The supported feature sets of Cycles (SUPPORTED or EXPERIMENTAL; default: SUPPORTED)")
 	command.Flags().StringSliceVarP(&devices, "devices", "D", GetBlenderDeviceString([]uint8{BLENDER_RENDER_DEVICE_OPTIONS}), "The supported devices for rendering (all GPU options may be combined with '+CPU' for hybrid rendering)")
 	command.Flags().Uint8VarP(&threads, "threads", "t", 1, "The supported number of threads rendered simultaneously by this Blender version (default: 1)")
+	command.Flags().BoolVarP(&benchmark, "benchmark", "b", false, "Render a quick benchmark after adding the Blender version")
+	command.Flags().StringVar(&offerCID, "offer", "", "The CID of the active render offer (default: the only active offer)")
 
 	return command
 
@@ -2569,35 +4327,36 @@ func (nm *PackageManager) CreateCommandBlender_Remove() *cobra.Command {
 
 	// flags for the 'blender remove' command
 	var version string
+	var offerCID string
 
 	// create a 'blender remove' command for the node
 	command := &cobra.Command{
 		Use:   "remove",
 		Short: "Remove a Blender version from the node's render offer",
 		Long:  "This command is for removing a Blender version from the node's render offer.",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
-			// if a render offer exists
-			if nm.Renderer.ActiveOffer != nil {
+			// if the render offer exists
+			offer, err := nm.GetActiveRenderOffer(offerCID)
+			if err == nil {
 
 				// remove the Blender version
 				if len(version) != 0 {
 
 					// if the parsed version is supported by the node
-					_, ok := nm.Renderer.ActiveOffer.Blender[version]
+					_, ok := offer.Blender[version]
 					if ok {
-						fmt.Println("")
-						fmt.Printf("Removing Blender v%v from the render offer of this node. \n", version)
-						fmt.Println("")
+						logger.Manager.Println("")
+						logger.Manager.Printf("Removing Blender v%v from the render offer of this node. \n", version)
+						logger.Manager.Println("")
 
 						// Delete the Blender version from the node's render offer
-						nm.Renderer.ActiveOffer.DeleteBlenderVersion(version)
+						offer.DeleteBlenderVersion(version)
 
 					} else {
 
-						fmt.Println("")
-						fmt.Println(fmt.Errorf("The node does not support Blender v%v.", version))
-						fmt.Println("")
+						logger.Manager.Println("")
+						return fmt.Errorf("The node does not support Blender v%v.", version)
 
 					}
 
@@ -2605,19 +4364,19 @@ func (nm *PackageManager) CreateCommandBlender_Remove() *cobra.Command {
 
 			} else {
 
-				fmt.Println("")
-				fmt.Println(fmt.Errorf("The node has no render offer."))
-				fmt.Println("")
+				logger.Manager.Println("")
+				return err
 
 			}
 
-			return
+			return nil
 
 		},
 	}
 
 	// add command flag parameters
 	command.Flags().StringVarP(&version, "version", "v", "", "The version of Blender to be used")
+	command.Flags().StringVar(&offerCID, "offer", "", "The CID of the active render offer (default: the only active offer)")
 
 	return command
 
@@ -2628,64 +4387,69 @@ func (nm *PackageManager) CreateCommandBlender_Run() *cobra.Command {
 
 	// flags for the 'blender remove' command
 	var version string
+	var offerCID string
 	var param string
+	var gpus []string
 
 	// create a 'blender remove' command for the node
 	command := &cobra.Command{
 		Use:   "run",
 		Short: "Run a Blender version from the node's render offer",
 		Long:  "This command is for starting a particular Blender version, which is in the node's render offer.",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
-			// if a render offer exists
-			if nm.Renderer.ActiveOffer != nil {
+			// if the render offer exists
+			offer, err := nm.GetActiveRenderOffer(offerCID)
+			if err == nil {
 
 				// if a version was parsed and
 				if len(version) != 0 {
 
 					// if the parsed version is supported by this node
-					if blender, ok := nm.Renderer.ActiveOffer.Blender[version]; ok {
-						fmt.Println("")
-						fmt.Printf("Starting Blender v%v. \n", blender.BuildVersion)
-						fmt.Println("")
+					if blender, ok := offer.Blender[version]; ok {
+						logger.Manager.Println("")
+						logger.Manager.Printf("Starting Blender v%v. \n", blender.BuildVersion)
+						logger.Manager.Println("")
 
 						// parse the command line parameters for Blender
 						args, err := shellwords.Parse(param)
 						// fmt.Println(args)
 						if err != nil {
-							fmt.Println("")
-							fmt.Println(fmt.Errorf("Could not parse the command line arguments for Blender."))
-							fmt.Println("")
+							logger.Manager.Println("")
+							return fmt.Errorf("Could not parse the command line arguments for Blender: %w", err)
+						}
+						// run the this Blender version in a scratch directory
+						dir, variables, err := nm.GetBlenderEnvironment().Process("run", "", gpus)
+						if err != nil {
+							logger.Manager.Println("")
+							return err
 						}
-						// run the this Blender version
+						blender.Dir, blender.Env = dir, variables
 						blender.Execute(args)
 
 					} else {
 
-						fmt.Println("")
-						fmt.Println(fmt.Errorf("The node does not support Blender v%v.", version))
-						fmt.Println("")
+						logger.Manager.Println("")
+						return fmt.Errorf("The node does not support Blender v%v.", version)
 
 					}
 
 					// if no version argument was parsed
 				} else {
 
-					fmt.Println("")
-					fmt.Println(fmt.Errorf("Cannot run Blender, because no version was passed."))
-					fmt.Println("")
+					logger.Manager.Println("")
+					return fmt.Errorf("Cannot run Blender, because no version was passed.")
 
 				}
 
 			} else {
 
-				fmt.Println("")
-				fmt.Println(fmt.Errorf("The node has no render offer."))
-				fmt.Println("")
+				logger.Manager.Println("")
+				return err
 
 			}
 
-			return
+			return nil
 
 		},
 	}
@@ -2693,6 +4457,8 @@ func (nm *PackageManager) CreateCommandBlender_Run() *cobra.Command {
 	// add command flag parameters
 	command.Flags().StringVarP(&version, "version", "v", "", "The version of Blender to be used")
 	command.Flags().StringVarP(&param, "param", "p", "", "The command line options for Blender")
+	command.Flags().StringSliceVarP(&gpus, "gpus", "g", []string{}, "The GPUs visible to Blender (default: all configured GPUs)")
+	command.Flags().StringVar(&offerCID, "offer", "", "The CID of the active render offer (default: the only active offer)")
 
 	return command
 
@@ -2704,63 +4470,152 @@ func (nm *PackageManager) CreateCommandBlender_Benchmark() *cobra.Command {
 
 	// flags for the 'blender benchmark' command
 	var version string
+	var offerCID string
 	var use_tool bool
 	var scene string
 	var device string
+	var cache_dir string
+	var no_cache bool
+	var launcher string
+	var background bool
+	var all bool
 
 	// create a 'blender remove' command for the node
 	command := &cobra.Command{
 		Use:   "benchmark",
 		Short: "Run a Blender benchmark",
-		Long:  "This command is for starting a benchmark rendering for a particular Blender version supported by this node.",
-		Run: func(cmd *cobra.Command, args []string) {
+		Long:  "This command is for starting a benchmark rendering for a particular Blender version supported by this node. With '--all', all Blender versions of the render offer are benchmarked one after another.",
+		RunE: func(cmd *cobra.Command, args []string) error {
 
-			// if a render offer exists
-			if nm.Renderer.ActiveOffer != nil {
+			// a requester-only node does not benchmark
+			err := nm._requireRenderNode("run a Blender benchmark")
+			if err != nil {
+				return err
+			}
+
+			// get the render offer
+			offer, err := nm.GetActiveRenderOffer(offerCID)
+
+			// benchmark all Blender versions of the render offer
+			if all && err == nil {
+				if len(version) != 0 {
+					return fmt.Errorf("Cannot benchmark a single Blender version and all versions at once.")
+				}
+				if len(offer.Blender) == 0 {
+					return fmt.Errorf("The render offer has no Blender versions.")
+				}
+
+				// prepare the benchmark tools of all versions
+				for _, blender := range offer.Blender {
+					if blender.BenchmarkTool != nil {
+						blender.BenchmarkTool.CacheDirectory = cache_dir
+						blender.BenchmarkTool.NoCache = no_cache
+						blender.BenchmarkTool.LauncherFile = launcher
+					}
+				}
+				run := func() RenderOfferBenchmarkProfile {
+					return offer.BenchmarkAll(nm.Context(), device, scene, func(index int, total int, benchmark BlenderVersionBenchmark) {
+						if benchmark.Err != nil {
+							logger.Manager.Resultf(" [#] (%v/%v) Blender v%v: FAILED (%v)\n", index, total, benchmark.Version, benchmark.Err)
+						} else {
+							logger.Manager.Resultf(" [#] (%v/%v) Blender v%v: %.2f samples / min (%v)\n", index, total, benchmark.Version, benchmark.SamplesPerMinute, benchmark.Duration.Round(time.Second))
+						}
+					})
+				}
+
+				// run the benchmarks in the background, so they can be canceled
+				logger.Manager.Println("")
+				if background {
+					go run()
+
+					logger.Manager.Printf("Started the benchmarks of %v Blender versions in the background.\n", len(offer.Blender))
+					logger.Manager.Println("Use 'node blender benchmark cancel' to stop them.")
+					logger.Manager.Println("")
+					return nil
+				}
+				logger.Manager.Printf("Benchmarking %v Blender versions one after another:\n", len(offer.Blender))
+				profile := run()
+
+				// print the capability profile
+				logger.Manager.Println("")
+				logger.Manager.Printf("Benchmarked %v of %v Blender versions.\n", len(profile.Succeeded), len(profile.Versions))
+				if len(profile.Succeeded) > 0 {
+					logger.Manager.Resultf(" [#] Succeeded: %v\n", strings.Join(profile.Succeeded, ", "))
+					logger.Manager.Resultf(" [#] Render score: %.2f - %.2f samples / min (mean: %.2f)\n", profile.Min, profile.Max, profile.Mean)
+					logger.Manager.Resultf(" [#] Fastest version: %v\n", profile.Fastest)
+				}
+				if len(profile.Failed) > 0 {
+					logger.Manager.Resultf(" [#] Failed: %v\n", strings.Join(profile.Failed, ", "))
+				}
+				logger.Manager.Println("")
+				if !profile.Passed() {
+					return fmt.Errorf("The benchmarks of %v Blender versions failed.", len(profile.Failed))
+				}
+
+				return nil
+			}
+
+			// if the render offer exists
+			if err == nil {
 
 				// if a version was parsed and
 				if len(version) != 0 {
 
 					// if the parsed version is supported by this node
-					if blender, ok := nm.Renderer.ActiveOffer.Blender[version]; ok {
+					if blender, ok := offer.Blender[version]; ok {
 
 						// if the official Blender benchmark tool shall be used
 						if use_tool {
 
 							// run the this Blender version
-							err := blender.BenchmarkTool.Run(nm.Renderer.ActiveOffer, version, device, scene)
-							if err != nil {
-								// log error event
-								logger.Manager.Package["node"].Error().Msg(err.Error())
+							blender.BenchmarkTool.CacheDirectory = cache_dir
+							blender.BenchmarkTool.NoCache = no_cache
+							blender.BenchmarkTool.LauncherFile = launcher
+							run := func() error {
+								err := blender.BenchmarkTool.Run(nm.Context(), offer, version, device, scene)
+								if err != nil {
+									// log error event
+									logger.Manager.Package["node"].Error().Msg(err.Error())
+								}
+								return err
+							}
+
+							// run the benchmark in the background, so it can be canceled
+							if background {
+								go run()
+
+								logger.Manager.Println("")
+								logger.Manager.Printf("Started the benchmark of Blender v%v in the background.\n", version)
+								logger.Manager.Println("Use 'node blender benchmark cancel' to stop it.")
+								logger.Manager.Println("")
+							} else {
+								return run()
 							}
 						}
 
 					} else {
 
-						fmt.Println("")
-						fmt.Println(fmt.Errorf("The node does not support Blender v%v.", version))
-						fmt.Println("")
+						logger.Manager.Println("")
+						return fmt.Errorf("The node does not support Blender v%v.", version)
 
 					}
 
 					// if no version argument was parsed
 				} else {
 
-					fmt.Println("")
-					fmt.Println(fmt.Errorf("Cannot run Blender, because no version was passed."))
-					fmt.Println("")
+					logger.Manager.Println("")
+					return fmt.Errorf("Cannot run Blender, because no version was passed.")
 
 				}
 
 			} else {
 
-				fmt.Println("")
-				fmt.Println(fmt.Errorf("The node has no render offer."))
-				fmt.Println("")
+				logger.Manager.Println("")
+				return err
 
 			}
 
-			return
+			return nil
 
 		},
 	}
@@ -2770,6 +4625,50 @@ func (nm *PackageManager) CreateCommandBlender_Benchmark() *cobra.Command {
 	command.Flags().BoolVarP(&use_tool, "blender-benchmark", "B", true, "Use the official Blender benchmark tool (default: yes)")
 	command.Flags().StringVarP(&scene, "scene", "S", "", "The scene(s) to be used for the benchmark rendering")
 	command.Flags().StringVarP(&device, "device", "D", "", "The device(s) to be used for the benchmark rendering")
+	command.Flags().StringVar(&cache_dir, "cache-dir", "", "The directory, which caches the downloaded Blender versions and scenes (default: app data directory)")
+	command.Flags().BoolVar(&no_cache, "no-cache", false, "Download the Blender version and scene again, even if they are cached")
+	command.Flags().StringVar(&launcher, "launcher", "", "The path to the Blender benchmark launcher (default: app data directory, downloaded if missing)")
+	command.Flags().BoolVarP(&background, "background", "b", false, "Run the benchmark in the background (stop it with 'node blender benchmark cancel')")
+	command.Flags().BoolVarP(&all, "all", "a", false, "Benchmark all Blender versions of the render offer one after another")
+	command.Flags().StringVar(&offerCID, "offer", "", "The CID of the active render offer (default: the only active offer)")
+
+	// add the subcommands
+	command.AddCommand(nm.CreateCommandBlender_BenchmarkCancel())
+
+	return command
+
+}
+
+// Create the CLI command to cancel the running Blender benchmarks
+func (nm *PackageManager) CreateCommandBlender_BenchmarkCancel() *cobra.Command {
+
+	// flags for the 'blender benchmark cancel' command
+	var version string
+
+	// create a 'blender benchmark cancel' command for the node
+	command := &cobra.Command{
+		Use:   "cancel",
+		Short: "Cancel the running Blender benchmarks",
+		Long:  "This command stops the running benchmarks of a Blender version (or of all versions) and terminates the Blender benchmark tool. The benchmark results are not changed.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			canceled := CancelBenchmarks(version)
+
+			logger.Manager.Println("")
+			if canceled == 0 {
+				logger.Manager.Println("No benchmark is running.")
+			} else {
+				logger.Manager.Printf("Canceled %v running benchmark(s).\n", canceled)
+			}
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	// add command flag parameters
+	command.Flags().StringVarP(&version, "version", "v", "", "Only cancel the benchmarks of this Blender version (default: all)")
 
 	return command
 
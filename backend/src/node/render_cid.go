@@ -0,0 +1,196 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the local rendering of a Blender file from IPFS.
+
+An operator can render a Blender file, which is already on IPFS, by its CID
+without creating a render request (e.g., to smoke-test the render capability of
+the node with known content). The file is downloaded, validated, and rendered
+with a Blender version of this node into a local output path. No render offer,
+render request, or HCS topic is involved. The Blender arguments are created from
+the given render settings, so that no python code is executed.
+
+*/
+
+import (
+
+	// standard
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	// external
+	"github.com/spf13/cobra"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/ipfs"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// RENDER FROM CID
+// #############################################################################
+// Render the frames of a Blender file from IPFS into the output path
+// NOTE: The output path may contain '#' characters for the frame number.
+func (nm *PackageManager) RenderCID(cid string, version string, output string, settings RenderSettings) ([]string, error) {
+	var err error
+
+	// check the CID and the IPFS node
+	_, err = ParseCID(cid)
+	if err != nil {
+		return nil, newRenderError(ErrInvalidArgument, "Invalid CID '%v': %w", cid, err)
+	}
+	if ipfs.Manager.IpfsAPI == nil {
+		return nil, newRenderError(ErrNetworkUnavailable, "The IPFS node is not running.")
+	}
+
+	// get the Blender version of this node
+	blender, ok := nm.GetBlender(version)
+	if !ok {
+		return nil, newRenderError(ErrUnsupportedVersion, "Blender v'%v' is not available on this node.", version)
+	}
+	if nm.Renderer.Busy {
+		return nil, newRenderError(ErrJobInfeasible, "The node is already rendering.")
+	}
+
+	// create the Blender arguments before anything is downloaded
+	blend_file := filepath.Join(RENDERHIVE_APP_DIRECTORY_TEMP, "renderhive-"+cid+".blend")
+	args, err := BlenderRenderArguments(blend_file, output, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	// download the Blender file
+	_, err = ipfs.Manager.GetObject(cid, blend_file)
+	if err != nil {
+		return nil, newRenderError(ErrNetworkUnavailable, "Could not get the Blender file: %w", err)
+	}
+	defer os.RemoveAll(blend_file)
+	if info, err := os.Stat(blend_file); err != nil || !info.Mode().IsRegular() {
+		return nil, newRenderError(ErrInvalidArgument, "CID '%v' is not a Blender file.", cid)
+	}
+
+	// validate the Blender file
+	renderable, warnings, err := nm.ValidateBlendFile(blend_file, version)
+	if err != nil {
+		return nil, err
+	}
+	if !renderable {
+		return warnings, newRenderError(ErrJobInfeasible, "The Blender file cannot be rendered: %v", strings.Join(warnings, "; "))
+	}
+
+	// create the output directory
+	err = os.MkdirAll(filepath.Dir(output), 0700)
+	if err != nil {
+		return warnings, err
+	}
+
+	// log event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf("Rendering Blender file '%v' with Blender v%v into '%v' ...", cid, version, output))
+
+	// render the frames
+	nm.Renderer.Busy = true
+	defer func() { nm.Renderer.Busy = false }()
+	err = blender.Execute(args)
+	if err == nil {
+		err = blender.Wait()
+	}
+
+	return warnings, err
+
+}
+
+// COMMAND LINE INTERFACE - RENDER FROM CID
+// #############################################################################
+// Create the CLI command to render a Blender file from IPFS on this node
+func (nm *PackageManager) CreateCommandRenderCID() *cobra.Command {
+
+	// flags for the 'render-cid' command
+	var version string
+	var output string
+	var engine string
+	var format string
+	var start int
+	var end int
+	var step int
+
+	// create a 'render-cid' command for the node
+	command := &cobra.Command{
+		Use:   "render-cid <cid>",
+		Short: "Render a Blender file from IPFS on this node",
+		Long:  "This command downloads the Blender file with the given CID from IPFS, validates it, and renders the frames locally into the output path. No render request is created and nothing is announced to the render hive. This is useful to test the render capability of the node.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			if len(version) == 0 {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Missing a required parameter: Blender version (--version).")
+
+			}
+			if len(output) == 0 {
+				output = filepath.Join("render-"+args[0], "frame_####")
+			}
+
+			warnings, err := nm.RenderCID(args[0], version, output, RenderSettings{
+				Engine:     engine,
+				FileFormat: format,
+				FrameStart: start,
+				FrameEnd:   end,
+				FrameStep:  step,
+			})
+			for _, warning := range warnings {
+				logger.Manager.Resultf(" [#] Warning: %v\n", warning)
+			}
+			if err != nil {
+
+				logger.Manager.Println("")
+				return err
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Printf("Rendered the Blender file '%v' with Blender v%v. \n", args[0], version)
+			logger.Manager.Resultf(" [#] Output: %v\n", output)
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().StringVarP(&version, "version", "v", "", "The Blender version to render with")
+	command.Flags().StringVarP(&output, "output", "o", "", "The output path of the frames ('#' is replaced by the frame number; default: ./render-<cid>/frame_####)")
+	command.Flags().StringVarP(&engine, "engine", "E", "", "The render engine (EEVEE, EEVEE_NEXT, or CYCLES; default: the engine of the Blender file)")
+	command.Flags().StringVarP(&format, "format", "F", "", "The file format of the frames (e.g., PNG or OPEN_EXR; default: the format of the Blender file)")
+	command.Flags().IntVarP(&start, "start", "s", 1, "The first frame to render")
+	command.Flags().IntVarP(&end, "end", "e", 1, "The last frame to render")
+	command.Flags().IntVarP(&step, "step", "j", 1, "The number of frames between two rendered frames")
+
+	return command
+
+}
@@ -0,0 +1,160 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	// external
+	"github.com/ipfs/boxo/files"
+	"github.com/ipfs/kubo/core"
+	"github.com/ipfs/kubo/core/coreapi"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/ipfs"
+	"renderhive/logger"
+)
+
+// fake Blender binary, which reports a renderable file for the validation
+// script and records the Blender file and the arguments of the rendering
+const testRenderCIDBlender = `#!/bin/sh
+dir="$(dirname "$0")"
+case "$*" in
+	*--python*)
+		cat "$4" > "$dir/validated.blend"
+		echo 'RENDERHIVE_VALIDATION:{"loaded":true,"blender_version":"4.1.0","file_version":"4.1.0","scene":"Scene","camera":true}'
+		;;
+	*)
+		echo "$@" > "$dir/render.txt"
+		;;
+esac
+`
+
+// helper function to run an offline IPFS node as the IPFS manager with a fixture file
+func _testMockIPFS(t *testing.T, fixture []byte) string {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	node, err := core.NewNode(ctx, &core.BuildCfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { node.Close() })
+	api, err := coreapi.NewCoreAPI(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// replace the IPFS node of the manager for this test
+	previousContext, previousNode, previousAPI := ipfs.Manager.IpfsContext, ipfs.Manager.IpfsNode, ipfs.Manager.IpfsAPI
+	ipfs.Manager.IpfsContext, ipfs.Manager.IpfsNode, ipfs.Manager.IpfsAPI = ctx, node, api
+	t.Cleanup(func() {
+		ipfs.Manager.IpfsContext, ipfs.Manager.IpfsNode, ipfs.Manager.IpfsAPI = previousContext, previousNode, previousAPI
+	})
+
+	added, err := api.Unixfs().Add(ctx, files.NewBytesFile(fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return added.RootCid().String()
+}
+
+// helper function to create a node with a fake Blender version
+func _testRenderCIDManager(t *testing.T) (*PackageManager, string) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+	_chdirTemp(t)
+	if err := os.MkdirAll(RENDERHIVE_APP_DIRECTORY_TEMP, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	directory := t.TempDir()
+	blender := filepath.Join(directory, "blender")
+	if err := os.WriteFile(blender, []byte(testRenderCIDBlender), 0755); err != nil {
+		t.Fatal(err)
+	}
+	nm := &PackageManager{}
+	nm.Renderer.ActiveOffers = []*RenderOffer{{Blender: map[string]BlenderAppData{"4.1.0": {Path: blender, BuildVersion: "4.1.0"}}}}
+
+	return nm, directory
+}
+
+func TestRenderCIDFromMockIPFS(t *testing.T) {
+	nm, directory := _testRenderCIDManager(t)
+	fixture := []byte("BLENDER-v401 fixture")
+	cid := _testMockIPFS(t, fixture)
+
+	output := filepath.Join(t.TempDir(), "render", "frame_####")
+	warnings, err := nm.RenderCID(cid, "4.1.0", output, RenderSettings{FrameStart: 1, FrameEnd: 1, FrameStep: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v (warnings: %v)", err, warnings)
+	}
+
+	// the Blender file of the CID was validated
+	validated, err := os.ReadFile(filepath.Join(directory, "validated.blend"))
+	if err != nil || !bytes.Equal(validated, fixture) {
+		t.Errorf("expected the fixture to be validated, got %q (%v)", validated, err)
+	}
+
+	// the Blender file was rendered into the output path without python
+	args, err := os.ReadFile(filepath.Join(directory, "render.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(args), output) || strings.Contains(string(args), "--python") {
+		t.Errorf("unexpected Blender arguments: %s", args)
+	}
+	if _, err := os.Stat(filepath.Dir(output)); err != nil {
+		t.Errorf("expected the output directory to be created: %v", err)
+	}
+
+	// the downloaded Blender file was removed
+	if matches, _ := filepath.Glob(filepath.Join(RENDERHIVE_APP_DIRECTORY_TEMP, "renderhive-*.blend")); len(matches) != 0 {
+		t.Errorf("expected the downloaded Blender file to be removed, got %v", matches)
+	}
+	if nm.Renderer.Busy {
+		t.Error("expected the node not to be busy after the rendering")
+	}
+}
+
+func TestRenderCIDRejectsInvalidInput(t *testing.T) {
+	nm, _ := _testRenderCIDManager(t)
+	cid := _testMockIPFS(t, []byte("BLENDER-v401 fixture"))
+
+	if _, err := nm.RenderCID("not-a-cid", "4.1.0", "frame_####", RenderSettings{}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected an invalid CID to be rejected, got %v", err)
+	}
+	if _, err := nm.RenderCID(cid, "9.9.9", "frame_####", RenderSettings{}); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected an unknown Blender version to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,601 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/ipfs"
+	"renderhive/logger"
+)
+
+// helper function to create a render node with a render job claimed by the given account
+func _testQueueManager(t *testing.T, operator string) (*PackageManager, *RenderJob) {
+	t.Helper()
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	_chdirTemp(t)
+
+	nm := &PackageManager{}
+	if err := nm.Reputation.Load(); err != nil {
+		t.Fatal(err)
+	}
+	job := &RenderJob{Request: &RenderRequest{DocumentCID: "request"}, Operator: operator}
+	job._setState(RENDER_JOB_STATE_CLAIMED)
+	nm.NetworkQueue = []*RenderJob{job}
+
+	return nm, job
+}
+
+// helper function to create a job queue message sent by the given account
+func _testJobQueueMessage(t *testing.T, nm *PackageManager, sender string, method int, args interface{}) hederasdk.TopicMessage {
+	t.Helper()
+
+	command, err := nm.EncodeCommand([]string{}, SERVICE_NODE, method, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := _testTopicMessage(sender, time.Now())
+	message.Contents = []byte(command)
+
+	return message
+}
+
+func TestReleaseRenderJobMessageRequiresTheClaimer(t *testing.T) {
+	nm, job := _testQueueManager(t, "0.0.1001")
+	args := &ReleaseRenderJobArgs{RenderRequestCID: "request", Attempts: 1, Reason: "test"}
+
+	// another node cannot release the job
+	nm._processJobQueueMessage(_testJobQueueMessage(t, nm, "0.0.1002", METHOD_NODE_RELEASE_RENDER_JOB, args))
+	if job._state() != RENDER_JOB_STATE_CLAIMED || job.Operator != "0.0.1001" {
+		t.Fatalf("got state %v (operator: %v), want the job to stay claimed", job._state(), job.Operator)
+	}
+	if job.Attempts != 0 {
+		t.Errorf("got %v attempts, want 0", job.Attempts)
+	}
+
+	// the claiming node releases the job
+	nm._processJobQueueMessage(_testJobQueueMessage(t, nm, "0.0.1001", METHOD_NODE_RELEASE_RENDER_JOB, args))
+	if job._state() != RENDER_JOB_STATE_QUEUED || job.Operator != "" {
+		t.Fatalf("got state %v (operator: %v), want the job to be queued again", job._state(), job.Operator)
+	}
+	if job.Attempts != 1 {
+		t.Errorf("got %v attempts, want 1", job.Attempts)
+	}
+}
+
+func TestReleaseRenderJobMessageOfAnUnclaimedJob(t *testing.T) {
+	nm, job := _testQueueManager(t, "")
+	job._setState(RENDER_JOB_STATE_QUEUED)
+
+	// nobody can release a job that was not claimed
+	nm._processJobQueueMessage(_testJobQueueMessage(t, nm, "0.0.1002", METHOD_NODE_RELEASE_RENDER_JOB, &ReleaseRenderJobArgs{RenderRequestCID: "request", Attempts: 5}))
+	if job.Attempts != 0 || job.Flagged {
+		t.Errorf("got %v attempts (flagged: %v), want the release to be ignored", job.Attempts, job.Flagged)
+	}
+}
+
+func TestReleaseRenderJobKeepsTheNodeBusy(t *testing.T) {
+	nm, job := _testQueueManager(t, "0.0.1001")
+	nm.Renderer.Busy = true
+
+	// the node still renders another job
+	nm.ReleaseRenderJob(job, "test")
+	if !nm.Renderer.Busy {
+		t.Error("releasing a job that was not rendered must not make the node idle")
+	}
+	if job._state() != RENDER_JOB_STATE_RELEASED {
+		t.Errorf("got state %v, want released", job._state())
+	}
+
+	// the node is idle after it released the job it rendered
+	job._setState(RENDER_JOB_STATE_RENDERING)
+	nm.ReleaseRenderJob(job, "test")
+	if nm.Renderer.Busy {
+		t.Error("releasing the rendered job must make the node idle")
+	}
+}
+
+func TestCheckRenderJobDeadlinesReleasesOverdueJobs(t *testing.T) {
+	nm, job := _testQueueManager(t, "0.0.1001")
+	job.Deadline = time.Now().Add(-time.Minute)
+	nm.Renderer.NodeQueue = []*RenderJob{job}
+
+	// the overdue job is released and removed from the queue of this node
+	nm.CheckRenderJobDeadlines()
+	if job._state() != RENDER_JOB_STATE_RELEASED || len(nm.Renderer.NodeQueue) != 0 {
+		t.Fatalf("got state %v and %v queued jobs, want the job to be released", job._state(), len(nm.Renderer.NodeQueue))
+	}
+
+	// the lock of the render data is released before the network is notified
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := nm.Renderer.Mutex.LockContext(ctx); err != nil {
+		t.Fatal("the lock of the render data is still held")
+	}
+	nm.Renderer.Mutex.Unlock()
+
+	// the deadlines are checked at most once per interval
+	other := &RenderJob{Request: &RenderRequest{DocumentCID: "other"}, Deadline: time.Now().Add(-time.Minute)}
+	other._setState(RENDER_JOB_STATE_CLAIMED)
+	nm.Renderer.NodeQueue = []*RenderJob{other}
+	nm.CheckRenderJobDeadlines()
+	if other._state() != RENDER_JOB_STATE_CLAIMED {
+		t.Errorf("got state %v, want the second check to be skipped", other._state())
+	}
+	nm.lastDeadlineCheck = time.Now().Add(-RENDERHIVE_CONFIG_RENDER_DEADLINE_CHECK_INTERVAL)
+	nm.CheckRenderJobDeadlines()
+	if other._state() != RENDER_JOB_STATE_RELEASED {
+		t.Errorf("got state %v, want the job to be released after the interval", other._state())
+	}
+}
+
+func TestSubmitRenderResultPinsTheResultRemotely(t *testing.T) {
+	nm, job := _testQueueManager(t, "0.0.1001")
+	job.ClaimedTimestamp = time.Now()
+
+	// the remote pinning service receives the pin request of the result
+	pinned := make(chan string, 1)
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var pin struct {
+			CID  string `json:"cid"`
+			Name string `json:"name"`
+		}
+		json.NewDecoder(r.Body).Decode(&pin)
+		pinned <- pin.CID
+		w.Write([]byte(`{"requestid":"1","status":"queued"}`))
+	}))
+	defer service.Close()
+	remotePinning := ipfs.Manager.RemotePinning
+	ipfs.Manager.RemotePinning = &ipfs.RemotePinningService{Endpoint: service.URL, AccessToken: "token", AutoPin: true}
+	defer func() { ipfs.Manager.RemotePinning = remotePinning }()
+
+	// the result is pinned, even if the result message cannot be submitted
+	nm.SubmitRenderResult(job, &RenderResult{ResultCID: testOfferCID})
+	select {
+	case cid := <-pinned:
+		if cid != testOfferCID {
+			t.Errorf("got pinned CID %v, want %v", cid, testOfferCID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the render result was not pinned on the remote pinning service")
+	}
+}
+
+// helper function to create a render node with active render offers of Blender v4.1.0
+func _testOfferManager(t *testing.T, offers ...*RenderOffer) *PackageManager {
+	t.Helper()
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	_chdirTemp(t)
+
+	nm := &PackageManager{}
+	nm.Renderer.Offers = make(map[string]*RenderOffer)
+	for _, offer := range offers {
+		offer.BlenderVersions = []RenderOfferBlenderVersions{{Version: "4.1.0", Engines: []string{"CYCLES"}, Devices: offer.BlenderVersions[0].Devices}}
+		offer.Blender = map[string]BlenderAppData{"4.1.0": {BuildVersion: "4.1.0", Path: offer.DocumentCID}}
+		offer.SubmittedTimestamp = time.Now()
+		nm.Renderer.Offers[offer.DocumentCID] = offer
+		if err := nm.SetActiveRenderOffer(offer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return nm
+}
+
+func TestBestActiveRenderOfferWithSeveralOffers(t *testing.T) {
+	cpu := &RenderOffer{DocumentCID: "offer-cpu", Price: 1, BlenderVersions: []RenderOfferBlenderVersions{{Devices: []string{"CPU"}}}}
+	gpu := &RenderOffer{DocumentCID: "offer-gpu", Price: 2, BlenderVersions: []RenderOfferBlenderVersions{{Devices: []string{"GPU"}}}}
+	nm := _testOfferManager(t, gpu, cpu)
+
+	// the request is rendered for the offer with the requested device
+	request := &RenderRequest{Version: "4.1.0"}
+	request.BlenderFile.Settings.Device = "GPU"
+	if best := nm.BestActiveRenderOffer(request); best != gpu {
+		t.Fatalf("got offer %v, want the GPU offer", best)
+	}
+
+	// without a device, the cheaper offer is ranked first
+	request.BlenderFile.Settings.Device = ""
+	if best := nm.BestActiveRenderOffer(request); best != cpu {
+		t.Fatalf("got offer %v, want the cheaper CPU offer", best)
+	}
+
+	// the price limit excludes the other offers
+	request.Price = 1.5
+	gpu.Price, cpu.Price = 1, 2
+	if best := nm.BestActiveRenderOffer(request); best != gpu {
+		t.Fatalf("got offer %v, want the offer within the price limit", best)
+	}
+
+	// paused offers are not rendered for
+	gpu.Paused = true
+	if best := nm.BestActiveRenderOffer(request); best != nil {
+		t.Errorf("got offer %v, want no offer", best)
+	}
+}
+
+func TestOfferedBlenderIsDeterministic(t *testing.T) {
+	b := &RenderOffer{DocumentCID: "offer-b", BlenderVersions: []RenderOfferBlenderVersions{{}}}
+	a := &RenderOffer{DocumentCID: "offer-a", BlenderVersions: []RenderOfferBlenderVersions{{}}}
+	nm := _testOfferManager(t, b, a)
+
+	// the offer with the smallest CID is used
+	blender, ok := nm._offeredBlender("4.1.0")
+	if !ok || blender.Path != "offer-a" {
+		t.Fatalf("got Blender of %v, want the one of offer-a", blender.Path)
+	}
+
+	// unless it is paused
+	a.Paused = true
+	if blender, _ = nm._offeredBlender("4.1.0"); blender.Path != "offer-b" {
+		t.Errorf("got Blender of %v, want the one of offer-b", blender.Path)
+	}
+	if _, ok = nm._offeredBlender("2.93.0"); ok {
+		t.Error("a version without offer must not be found")
+	}
+}
+
+func TestDeactivateRenderOfferIsPersisted(t *testing.T) {
+	offer := &RenderOffer{DocumentCID: "offer", BlenderVersions: []RenderOfferBlenderVersions{{}}}
+	nm := _testOfferManager(t, offer)
+	repository := NewJsonRenderRepository()
+
+	// the deactivated offer is stored as inactive
+	Manager.Repository = repository
+	t.Cleanup(func() { Manager.Repository = nil })
+	if err := nm.DeactivateRenderOffer("offer"); err != nil {
+		t.Fatal(err)
+	}
+	if len(nm.GetActiveRenderOffers()) != 0 {
+		t.Fatal("the offer must not be active anymore")
+	}
+	states, err := repository._readStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if states["offer"].State != REPOSITORY_STATE_INACTIVE {
+		t.Fatalf("got state %q, want %q", states["offer"].State, REPOSITORY_STATE_INACTIVE)
+	}
+
+	// the activated offer is stored as submitted again
+	if err := nm.ActivateRenderOffer(offer); err != nil {
+		t.Fatal(err)
+	}
+	states, _ = repository._readStates()
+	if states["offer"].State != REPOSITORY_STATE_SUBMITTED {
+		t.Errorf("got state %q, want %q", states["offer"].State, REPOSITORY_STATE_SUBMITTED)
+	}
+}
+
+func TestParseBenchmarkOutput(t *testing.T) {
+
+	// a valid single-result array
+	result, err := _parseBenchmarkOutput(`[{"stats": {"samples_per_minute": 123.5}}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 || result[0].Stats.SamplesPerMinute != 123.5 {
+		t.Errorf("unexpected benchmark result: %+v", result)
+	}
+
+	// empty, non-JSON, and empty array output are errors with a snippet of the output
+	for output, snippet := range map[string]string{
+		"":                                 "no output",
+		"   \n":                            "no output",
+		"ERROR: could not find the device": "could not find the device",
+		`{"stats": {}}`:                    `{"stats": {}}`,
+		"[]":                               "[]",
+		strings.Repeat("x", 1000):          strings.Repeat("x", RENDERHIVE_CONFIG_BENCHMARK_OUTPUT_SNIPPET) + " ...",
+	} {
+		_, err := _parseBenchmarkOutput(output)
+		if err == nil {
+			t.Errorf("output %q: expected an error", output)
+			continue
+		}
+		if !strings.Contains(err.Error(), snippet) {
+			t.Errorf("output %q: expected %q in the error, got %v", output, snippet, err)
+		}
+	}
+}
+
+func TestParseStatusLine(t *testing.T) {
+	tests := []struct {
+		name       string
+		line       string
+		recognized bool
+		status     BlenderAppData
+	}{
+		{
+			"Blender 3.x Cycles sample",
+			"Fra:1 Mem:160.86M (Peak 190.45M) | Time:00:04.72 | Remaining:00:12.34 | Mem:44.60M, Peak:44.60M | Scene, ViewLayer | Sample 32/128",
+			true,
+			BlenderAppData{Frame: "1", Memory: "160.86M", Peak: "190.45M", Time: "00:04.72", Note: "Scene, ViewLayer | Sample 32/128"},
+		},
+		{
+			"Blender 3.x EEVEE sample",
+			"Fra:12 Mem:120.44M (Peak 120.95M) | Time:00:00.97 | Rendering 1 / 64 samples",
+			true,
+			BlenderAppData{Frame: "12", Memory: "120.44M", Peak: "120.95M", Time: "00:00.97", Note: "Rendering 1 / 64 samples"},
+		},
+		{
+			"Blender 4.x Cycles synchronization",
+			"Fra:250 Mem:35.93M (Peak 36.50M) | Time:01:02:03.45 | Mem:0.00M, Peak:0.00M | Scene, ViewLayer | Synchronizing object | Cube",
+			true,
+			BlenderAppData{Frame: "250", Memory: "35.93M", Peak: "36.50M", Time: "01:02:03.45", Note: "Scene, ViewLayer | Synchronizing object | Cube"},
+		},
+		{
+			"Blender 4.x Cycles sample without note",
+			"Fra:3 Mem:1.2G (Peak 1.5G) | Time:00:10.00",
+			true,
+			BlenderAppData{Frame: "3", Memory: "1.2G", Peak: "1.5G", Time: "00:10.00"},
+		},
+		{
+			"saved frame",
+			"Saved: '/tmp/render/frame_0001.png'",
+			false,
+			BlenderAppData{},
+		},
+		{
+			"render time summary",
+			" Time: 00:12.43 (Saving: 00:00.02)",
+			false,
+			BlenderAppData{},
+		},
+		{
+			"error",
+			"Error: Cannot read file '/tmp/scene.blend': No such file or directory",
+			false,
+			BlenderAppData{},
+		},
+		{
+			"malformed status line",
+			"Fra:abc Mem:lots | Sample",
+			false,
+			BlenderAppData{},
+		},
+		{
+			"empty line",
+			"",
+			false,
+			BlenderAppData{},
+		},
+	}
+	for _, test := range tests {
+		status := BlenderAppData{}
+		recognized := status.ParseStatusLine(test.line)
+		if recognized != test.recognized {
+			t.Errorf("%v: recognized = %v, want %v", test.name, recognized, test.recognized)
+		}
+		if status.Frame != test.status.Frame || status.Memory != test.status.Memory || status.Peak != test.status.Peak || status.Time != test.status.Time || status.Note != test.status.Note {
+			t.Errorf("%v: got frame %q, memory %q (peak %q), time %q, note %q, want %q, %q (%q), %q, %q", test.name,
+				status.Frame, status.Memory, status.Peak, status.Time, status.Note,
+				test.status.Frame, test.status.Memory, test.status.Peak, test.status.Time, test.status.Note)
+		}
+	}
+}
+
+// fake Blender benchmark launcher, which supports Blender v4.1.0 on the CPU
+const testFakeBenchmarkLauncher = `#!/bin/sh
+case "$1" in
+blender) [ "$2" = "list" ] && echo "4.1.0" ;;
+devices) echo "Test CPU" ;;
+scenes) [ "$4" = "list" ] && echo "monster" ;;
+benchmark) echo '[{"stats": {"samples_per_minute": 123.5}}]' ;;
+esac
+exit 0
+`
+
+func TestBenchmarkRunWhileReadingTheResult(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	launcher := filepath.Join(t.TempDir(), "benchmark-launcher-cli")
+	if err := os.WriteFile(launcher, []byte(testFakeBenchmarkLauncher), 0755); err != nil {
+		t.Fatal(err)
+	}
+	tool := &BlenderBenchmarkTool{LauncherFile: launcher, CacheDirectory: t.TempDir()}
+	offer := &RenderOffer{Blender: map[string]BlenderAppData{"4.1.0": {BenchmarkTool: tool}}}
+
+	// two benchmarks run, while the result is read and the tool is encoded (run with -race)
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := tool.Run(context.Background(), offer, "4.1.0", "CPU", "monster"); err != nil {
+				t.Errorf("the benchmark failed: %v", err)
+			}
+		}()
+	}
+	readers := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if result := tool.GetResult(); len(result) > 1 {
+					t.Errorf("got %v benchmark results, want at most one", len(result))
+				}
+				if _, err := json.Marshal(tool); err != nil {
+					t.Errorf("could not encode the benchmark tool: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	readers.Wait()
+
+	// the result of the benchmarks was stored
+	result := tool.GetResult()
+	if len(result) != 1 || result[0].Stats.SamplesPerMinute != 123.5 {
+		t.Errorf("got the benchmark result %+v", result)
+	}
+}
+
+// fake Blender benchmark launcher, which hangs in a download or the benchmark
+// NOTE: The launcher writes its process ID, before it hangs.
+const testHangingBenchmarkLauncher = `#!/bin/sh
+case "$1" in
+blender) [ "$2" = "list" ] && echo "4.1.0" ;;
+devices) echo "Test CPU" ;;
+scenes)
+	if [ "$2" = "download" ] && [ "$TEST_HANG" = "download" ]; then
+		echo "partial" > "$XDG_CACHE_HOME/partial.blend"
+		echo $$ > "$TEST_PID_FILE"
+		exec sleep 30
+	fi
+	[ "$4" = "list" ] && echo "monster" ;;
+benchmark)
+	echo $$ > "$TEST_PID_FILE"
+	exec sleep 30 ;;
+esac
+exit 0
+`
+
+// helper function to cancel a benchmark, as soon as the launcher hangs
+// NOTE: Returns the process ID of the launcher.
+func _cancelHangingBenchmark(t *testing.T, pidFile string) int {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, err := os.ReadFile(pidFile); err == nil && strings.HasSuffix(string(data), "\n") {
+			pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if canceled := CancelBenchmarks("4.1.0"); canceled != 1 {
+				t.Errorf("canceled %v benchmarks, want 1", canceled)
+			}
+			return pid
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("the benchmark launcher did not start")
+
+	return 0
+}
+
+// helper function to run a benchmark with the hanging launcher and cancel it
+func _testCanceledBenchmark(t *testing.T, hang string) (*BlenderBenchmarkTool, int, time.Duration, error) {
+	t.Helper()
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	directory := t.TempDir()
+	launcher := filepath.Join(directory, "benchmark-launcher-cli")
+	if err := os.WriteFile(launcher, []byte(testHangingBenchmarkLauncher), 0755); err != nil {
+		t.Fatal(err)
+	}
+	pidFile := filepath.Join(directory, "launcher.pid")
+	t.Setenv("TEST_HANG", hang)
+	t.Setenv("TEST_PID_FILE", pidFile)
+
+	// a previous benchmark result
+	previous := BlenderBenchmarkResult{}
+	previous.Stats.SamplesPerMinute = 42
+	tool := &BlenderBenchmarkTool{LauncherFile: launcher, CacheDirectory: t.TempDir(), Result: []BlenderBenchmarkResult{previous}}
+	offer := &RenderOffer{Blender: map[string]BlenderAppData{"4.1.0": {BenchmarkTool: tool}}}
+
+	start := time.Now()
+	done := make(chan error)
+	go func() {
+		done <- tool.Run(context.Background(), offer, "4.1.0", "CPU", "monster")
+	}()
+	pid := _cancelHangingBenchmark(t, pidFile)
+
+	select {
+	case err := <-done:
+		return tool, pid, time.Since(start), err
+	case <-time.After(20 * time.Second):
+		t.Fatal("the canceled benchmark did not return")
+	}
+
+	return nil, 0, 0, nil
+}
+
+func TestCanceledBenchmarkStopsTheLauncher(t *testing.T) {
+	tool, pid, elapsed, err := _testCanceledBenchmark(t, "benchmark")
+	if !errors.Is(err, ErrBenchmarkCanceled) {
+		t.Errorf("got %v, want a canceled benchmark", err)
+	}
+	if elapsed > 10*time.Second {
+		t.Errorf("the canceled benchmark returned after %v", elapsed)
+	}
+
+	// the launcher was terminated
+	if err := syscall.Kill(pid, 0); !errors.Is(err, syscall.ESRCH) {
+		t.Errorf("got %v for the launcher process, want it to be terminated", err)
+	}
+
+	// the previous result is kept and the benchmark is not running anymore
+	if result := tool.GetResult(); len(result) != 1 || result[0].Stats.SamplesPerMinute != 42 {
+		t.Errorf("got the benchmark result %+v, want the previous result", result)
+	}
+	if canceled := CancelBenchmarks(""); canceled != 0 {
+		t.Errorf("canceled %v benchmarks, want none to be running", canceled)
+	}
+}
+
+func TestCanceledBenchmarkRemovesThePartialDownload(t *testing.T) {
+	tool, _, _, err := _testCanceledBenchmark(t, "download")
+	if !errors.Is(err, ErrBenchmarkCanceled) {
+		t.Errorf("got %v, want a canceled benchmark", err)
+	}
+
+	// the partial scene is neither kept nor recorded in the cache
+	if _, err := os.Stat(filepath.Join(tool.CachePath(), "partial.blend")); !os.IsNotExist(err) {
+		t.Errorf("got %v for the partial download, want it to be removed", err)
+	}
+	for key := range _readBenchmarkCache(tool.CachePath()) {
+		if strings.HasPrefix(key, "scene/") {
+			t.Errorf("the canceled download '%v' was recorded in the cache", key)
+		}
+	}
+}
@@ -0,0 +1,462 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+The render repository stores the local state of the render offers, render
+requests, render jobs, and render results of this node. The JSON documents
+remain the canonical artifacts, which are added to IPFS. The repository only
+keeps track of them and of the state that is not part of the documents.
+
+Repository backends:
+  - json: scans the local document directories on start (default). The state
+    of the documents (e.g., paused or cancelled) is kept in a small state file
+    in the app data directory.
+  - sqlite: keeps an indexed SQLite database in the app data directory. The
+    existing JSON documents are imported once, when the database is created.
+
+The backend is selected in the optional 'repository.json' file of the
+configuration directory.
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/ipfs"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// repository backends
+const (
+	REPOSITORY_BACKEND_JSON   = "json"
+	REPOSITORY_BACKEND_SQLITE = "sqlite"
+)
+
+// repository states of the render offers and render requests
+const (
+	REPOSITORY_STATE_CREATED   = "created"   // the document was created, but not submitted
+	REPOSITORY_STATE_SUBMITTED = "submitted" // the document was submitted to the network
+	REPOSITORY_STATE_PAUSED    = "paused"    // the render offer was paused
+	REPOSITORY_STATE_INACTIVE  = "inactive"  // the submitted render offer was deactivated on this node
+	REPOSITORY_STATE_CANCELLED = "cancelled" // the render request was cancelled
+	REPOSITORY_STATE_ARCHIVED  = "archived"  // the closed document was moved into the archive
+)
+
+// Configuration of the render repository
+type RenderRepositoryConfig struct {
+	Backend string `json:"backend"` // repository backend (REPOSITORY_BACKEND_*)
+	Path    string `json:"path"`    // path of the SQLite database (default: in the app data directory)
+
+	// Retention of closed documents
+	Retention     string `json:"retention"`      // time closed documents are kept active (e.g., "720h")
+	UnpinArchived bool   `json:"unpin_archived"` // unpin the IPFS objects of archived documents
+}
+
+// A render offer or render request document stored in the repository
+type RenderDocument struct {
+	CID                string    // content identifier (CID) of the document
+	Path               string    // local path of the document on this node
+	Data               []byte    // content of the document
+	State              string    // repository state (REPOSITORY_STATE_*)
+	SubmittedTimestamp time.Time // The datetime the document was submitted to the network
+	ClosedTimestamp    time.Time // The datetime the document was paused or cancelled
+}
+
+// Persistence layer for the render data of this node
+type RenderRepository interface {
+
+	// Get the stored render offer and render request documents
+	LoadOffers() ([]RenderDocument, error)
+	LoadRequests() ([]RenderDocument, error)
+
+	// Store the current state of render data
+	SaveOffer(offer *RenderOffer) error
+	SaveRequest(request *RenderRequest) error
+	SaveJob(job *RenderJob) error
+	SaveResult(requestCID string, result *RenderResult) error
+
+	// Mark a closed render offer or render request document as archived
+	Archive(cid string, path string) error
+
+	// Release the resources of the repository
+	Close() error
+}
+
+// Render repository, which finds the documents of the listings with its indexes
+type RenderListingRepository interface {
+
+	// Get the CIDs of the stored documents matching the filter (newest first)
+	FindOffers(filter RenderListFilter) ([]string, error)
+	FindRequests(filter RenderListFilter) ([]string, error)
+}
+
+// RENDER REPOSITORY
+// #############################################################################
+// Initialize the render repository of this node
+func (nm *PackageManager) InitRepository() error {
+	var err error
+
+	// read the repository configuration (the JSON backend is the default)
+	config := RenderRepositoryConfig{Backend: REPOSITORY_BACKEND_JSON}
+	err = config.Read()
+	if err != nil && !os.IsNotExist(err) {
+		return newRenderError(ErrInvalidArgument, "Could not read the repository configuration: %w", err)
+	}
+
+	_, err = config.RetentionWindow()
+	if err != nil {
+		return err
+	}
+	nm.RepositoryConfig = config
+
+	switch config.Backend {
+	case REPOSITORY_BACKEND_JSON:
+
+		nm.Repository = NewJsonRenderRepository()
+
+	case REPOSITORY_BACKEND_SQLITE:
+
+		// use the default database path
+		if config.Path == "" {
+			config.Path = filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_DATABASE, "renderhive.db")
+		}
+
+		// open the database (and import the existing JSON documents)
+		repository, err := OpenSQLiteRenderRepository(config.Path)
+		if err != nil {
+			return newRenderError(ErrStorageFailed, "Could not open the SQLite repository '%v': %w", config.Path, err)
+		}
+		nm.Repository = repository
+
+	default:
+		return newRenderError(ErrInvalidArgument, "Unknown repository backend '%v'.", config.Backend)
+	}
+
+	// log information
+	logger.Manager.Package["node"].Info().Msg(fmt.Sprintf(" [#] Render repository: %v", config.Backend))
+
+	return nil
+
+}
+
+// Read the repository configuration from the configuration file
+func (config *RenderRepositoryConfig) Read() error {
+	var err error
+
+	// Open the configuration file
+	file, err := os.Open(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "repository.json"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Read the file content
+	fileData, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	// get the file content into a structure
+	err = json.Unmarshal(fileData, config)
+	if err != nil {
+		return err
+	}
+
+	return err
+
+}
+
+// Get the repository state of the render offer
+// NOTE: A paused offer stays paused, even if it is also deactivated, since a
+// paused offer is not rendered for either.
+func (offer *RenderOffer) RepositoryState() string {
+
+	if offer._isPaused() {
+		return REPOSITORY_STATE_PAUSED
+	} else if offer.Inactive && !offer.SubmittedTimestamp.IsZero() {
+		return REPOSITORY_STATE_INACTIVE
+	} else if !offer.SubmittedTimestamp.IsZero() {
+		return REPOSITORY_STATE_SUBMITTED
+	}
+
+	return REPOSITORY_STATE_CREATED
+
+}
+
+// Get the repository state of the render request
+func (request *RenderRequest) RepositoryState() string {
+
+	if request._isCancelled() {
+		return REPOSITORY_STATE_CANCELLED
+	} else if !request.SubmittedTimestamp.IsZero() {
+		return REPOSITORY_STATE_SUBMITTED
+	}
+
+	return REPOSITORY_STATE_CREATED
+
+}
+
+// Store the render offer in the repository of this node
+func (offer *RenderOffer) Save() {
+
+	if Manager.Repository == nil || offer.DocumentCID == "" {
+		return
+	}
+
+	err := Manager.Repository.SaveOffer(offer)
+	if err != nil {
+		logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not store render offer '%v' in the repository: %v", offer.DocumentCID, err))
+	}
+
+}
+
+// Store the render request in the repository of this node
+func (request *RenderRequest) Save() {
+
+	if Manager.Repository == nil || request.DocumentCID == "" {
+		return
+	}
+
+	err := Manager.Repository.SaveRequest(request)
+	if err != nil {
+		logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not store render request '%v' in the repository: %v", request.DocumentCID, err))
+	}
+
+}
+
+// Store the render job in the repository of this node
+func (job *RenderJob) Save() {
+
+	if Manager.Repository == nil || job.Request == nil || job.Request.DocumentCID == "" {
+		return
+	}
+
+	err := Manager.Repository.SaveJob(job)
+	if err != nil {
+		logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not store render job '%v' in the repository: %v", job.Request.DocumentCID, err))
+	}
+
+}
+
+// JSON REPOSITORY
+// #############################################################################
+// Render repository based on the local JSON documents
+// NOTE: Only the documents and their state are persisted. The state of the
+// render jobs and results is kept in memory.
+type JsonRenderRepository struct {
+	Path string // path of the state file (empty = the state is not persisted)
+
+	mutex sync.Mutex
+}
+
+// State of a render offer or render request document in the state file
+type jsonDocumentState struct {
+	State              string    `json:"state"`
+	SubmittedTimestamp time.Time `json:"submitted"`
+	ClosedTimestamp    time.Time `json:"closed"`
+}
+
+// Create a JSON repository with the state file in the app data directory
+func NewJsonRenderRepository() *JsonRenderRepository {
+	return &JsonRenderRepository{Path: filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_DATABASE, "documents.json")}
+}
+
+// Get the render offer documents from the local render offer directory
+func (repository *JsonRenderRepository) LoadOffers() ([]RenderDocument, error) {
+	return repository._loadDocuments(filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_LOCAL_OFFERS), `^offer-.*\.json$`)
+}
+
+// Get the render request documents from the local render request directory
+func (repository *JsonRenderRepository) LoadRequests() ([]RenderDocument, error) {
+	return repository._loadDocuments(filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_LOCAL_REQUESTS), `^request-.*\.json$`)
+}
+
+// Store the state of the render offer (the document is written by the offer itself)
+func (repository *JsonRenderRepository) SaveOffer(offer *RenderOffer) error {
+	return repository._saveState(offer.DocumentCID, &jsonDocumentState{State: offer.RepositoryState(), SubmittedTimestamp: offer.SubmittedTimestamp, ClosedTimestamp: offer.PausedTimestamp})
+}
+
+// Store the state of the render request (the document is written by the request itself)
+func (repository *JsonRenderRepository) SaveRequest(request *RenderRequest) error {
+	return repository._saveState(request.DocumentCID, &jsonDocumentState{State: request.RepositoryState(), SubmittedTimestamp: request.SubmittedTimestamp, ClosedTimestamp: request.ClosedTimestamp})
+}
+
+// Render jobs are not persisted by the JSON repository
+func (repository *JsonRenderRepository) SaveJob(job *RenderJob) error {
+	return nil
+}
+
+// Render results are not persisted by the JSON repository
+func (repository *JsonRenderRepository) SaveResult(requestCID string, result *RenderResult) error {
+	return nil
+}
+
+// Archived documents are not found in the local document directories anymore
+func (repository *JsonRenderRepository) Archive(cid string, path string) error {
+	return repository._saveState(cid, nil)
+}
+
+// Nothing to release
+func (repository *JsonRenderRepository) Close() error {
+	return nil
+}
+
+// Get all documents matching the file name pattern from a local directory
+func (repository *JsonRenderRepository) _loadDocuments(directory string, pattern string) ([]RenderDocument, error) {
+	var err error
+	var documents []RenderDocument
+
+	// get the stored state of the documents
+	states, err := repository._readStates()
+	if err != nil {
+		return nil, err
+	}
+
+	// if the directory does NOT exist
+	if _, err := os.Stat(directory); os.IsNotExist(err) {
+		return nil, newRenderError(ErrStorageFailed, "Document directory '%v' does not exist.", directory)
+	}
+
+	// go through all files in the directory
+	err = filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+
+		// if the file is a regular document file
+		if err == nil && info.Mode().IsRegular() {
+			if matched, _ := regexp.MatchString(pattern, info.Name()); matched {
+
+				// get the CID and the content of the document
+				document := RenderDocument{Path: path, State: REPOSITORY_STATE_CREATED}
+				document.CID, err = ipfs.Manager.GetHashFromPath(path)
+				if err == nil {
+					document.Data, err = os.ReadFile(path)
+				}
+				if state, ok := states[document.CID]; ok && err == nil {
+					document.State = state.State
+					document.SubmittedTimestamp = state.SubmittedTimestamp
+					document.ClosedTimestamp = state.ClosedTimestamp
+				}
+				if err == nil {
+					documents = append(documents, document)
+				}
+
+			}
+		}
+
+		// log error event
+		if err != nil {
+			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not read document %v: %v", path, err))
+		}
+
+		return nil
+
+	})
+
+	return documents, err
+
+}
+
+// helper function to read the stored state of the documents
+func (repository *JsonRenderRepository) _readStates() (map[string]jsonDocumentState, error) {
+
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	return repository._loadStates()
+
+}
+
+// helper function to load the state file (the caller holds the mutex)
+func (repository *JsonRenderRepository) _loadStates() (map[string]jsonDocumentState, error) {
+	states := make(map[string]jsonDocumentState)
+
+	if repository.Path == "" {
+		return states, nil
+	}
+
+	data, err := os.ReadFile(repository.Path)
+	if os.IsNotExist(err) {
+		return states, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &states)
+	if err != nil {
+		return nil, newRenderError(ErrStorageFailed, "Could not read the document states '%v': %w", repository.Path, err)
+	}
+
+	return states, nil
+
+}
+
+// helper function to store (or remove, if nil) the state of a document
+func (repository *JsonRenderRepository) _saveState(cid string, state *jsonDocumentState) error {
+
+	if repository.Path == "" || cid == "" {
+		return nil
+	}
+
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	states, err := repository._loadStates()
+	if err != nil {
+		return err
+	}
+
+	if state == nil {
+		delete(states, cid)
+	} else {
+		states[cid] = *state
+	}
+
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// replace the state file atomically
+	err = os.MkdirAll(filepath.Dir(repository.Path), 0755)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(repository.Path+".tmp", data, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(repository.Path+".tmp", repository.Path)
+
+}
@@ -0,0 +1,563 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+The SQLite render repository keeps the render data of this node in a single
+database file with indexes on the CIDs, owners, and states. The pure Go SQLite
+driver is used, so the app can still be built without cgo.
+
+*/
+
+import (
+
+	// standard
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+	_ "modernc.org/sqlite"
+
+	// internal
+	"renderhive/logger"
+)
+
+// database schema of the render repository
+const sqliteRenderRepositorySchema = `
+CREATE TABLE IF NOT EXISTS meta (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS offers (
+	cid       TEXT PRIMARY KEY,
+	path      TEXT NOT NULL,
+	owner     TEXT NOT NULL,
+	state     TEXT NOT NULL,
+	created   INTEGER NOT NULL,
+	modified  INTEGER NOT NULL,
+	submitted INTEGER NOT NULL DEFAULT 0,
+	closed    INTEGER NOT NULL DEFAULT 0,
+	document  BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS offers_owner ON offers (owner);
+CREATE INDEX IF NOT EXISTS offers_state ON offers (state);
+CREATE INDEX IF NOT EXISTS offers_created ON offers (created);
+
+CREATE TABLE IF NOT EXISTS requests (
+	cid           TEXT PRIMARY KEY,
+	path          TEXT NOT NULL,
+	directory_cid TEXT NOT NULL,
+	owner         TEXT NOT NULL,
+	state         TEXT NOT NULL,
+	version       TEXT NOT NULL,
+	price         REAL NOT NULL,
+	created       INTEGER NOT NULL,
+	modified      INTEGER NOT NULL,
+	submitted     INTEGER NOT NULL DEFAULT 0,
+	closed        INTEGER NOT NULL DEFAULT 0,
+	document      BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS requests_owner ON requests (owner);
+CREATE INDEX IF NOT EXISTS requests_state ON requests (state);
+CREATE INDEX IF NOT EXISTS requests_created ON requests (created);
+
+CREATE TABLE IF NOT EXISTS jobs (
+	request_cid TEXT PRIMARY KEY,
+	state       INTEGER NOT NULL,
+	claimed     INTEGER NOT NULL DEFAULT 0,
+	deadline    INTEGER NOT NULL DEFAULT 0,
+	attempts    INTEGER NOT NULL DEFAULT 0,
+	flagged     INTEGER NOT NULL DEFAULT 0,
+	result_cid  TEXT NOT NULL DEFAULT '',
+	updated     INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS jobs_state ON jobs (state);
+
+CREATE TABLE IF NOT EXISTS subtasks (
+	request_cid TEXT NOT NULL,
+	subtask     INTEGER NOT NULL,
+	frame_start INTEGER NOT NULL,
+	frame_end   INTEGER NOT NULL,
+	state       INTEGER NOT NULL,
+	operator    TEXT NOT NULL DEFAULT '',
+	claimed     INTEGER NOT NULL DEFAULT 0,
+	deadline    INTEGER NOT NULL DEFAULT 0,
+	attempts    INTEGER NOT NULL DEFAULT 0,
+	flagged     INTEGER NOT NULL DEFAULT 0,
+	result_cid  TEXT NOT NULL DEFAULT '',
+	updated     INTEGER NOT NULL,
+	PRIMARY KEY (request_cid, subtask)
+);
+CREATE INDEX IF NOT EXISTS subtasks_state ON subtasks (state);
+
+CREATE TABLE IF NOT EXISTS results (
+	request_cid  TEXT NOT NULL,
+	operator     TEXT NOT NULL,
+	result_cid   TEXT NOT NULL,
+	frame_hashes TEXT NOT NULL,
+	updated      INTEGER NOT NULL,
+	PRIMARY KEY (request_cid, operator)
+);
+CREATE INDEX IF NOT EXISTS results_result_cid ON results (result_cid);
+`
+
+// meta key marking the one-time import of the JSON documents
+const sqliteRenderRepositoryImportKey = "json_import"
+
+// Render repository based on a SQLite database
+type SQLiteRenderRepository struct {
+	Path string
+	DB   *sql.DB
+}
+
+// SQLITE REPOSITORY
+// #############################################################################
+// Open the SQLite render repository and import the JSON documents on first use
+func OpenSQLiteRenderRepository(path string) (*SQLiteRenderRepository, error) {
+	var err error
+
+	// create the directory, if it does not exist
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	// open the database
+	// NOTE: The busy timeout avoids errors, if the topic callbacks and the
+	// JSON-RPC handlers write at the same time.
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	repository := &SQLiteRenderRepository{Path: path, DB: db}
+
+	// create the tables and indexes
+	_, err = db.Exec(sqliteRenderRepositorySchema)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// import the existing JSON documents
+	err = repository._importJsonDocuments()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return repository, nil
+
+}
+
+// Get the render offer documents from the database
+func (repository *SQLiteRenderRepository) LoadOffers() ([]RenderDocument, error) {
+	return repository._loadDocuments("SELECT cid, path, document, state, submitted, closed FROM offers WHERE state != 'archived' ORDER BY created")
+}
+
+// Get the render request documents from the database
+func (repository *SQLiteRenderRepository) LoadRequests() ([]RenderDocument, error) {
+	return repository._loadDocuments("SELECT cid, path, document, state, submitted, closed FROM requests WHERE state != 'archived' ORDER BY created")
+}
+
+// Store the render offer in the database
+func (repository *SQLiteRenderRepository) SaveOffer(offer *RenderOffer) error {
+	var err error
+
+	// get the canonical document
+	data, err := _readDocument(offer.DocumentPath, offer)
+	if err != nil {
+		return err
+	}
+
+	_, err = repository.DB.Exec(`
+		INSERT INTO offers (cid, path, owner, state, created, modified, submitted, closed, document)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (cid) DO UPDATE SET
+			path = excluded.path, state = excluded.state, modified = excluded.modified,
+			submitted = excluded.submitted, closed = excluded.closed, document = excluded.document`,
+		offer.DocumentCID, offer.DocumentPath, _ownerString(offer.Owner), offer.RepositoryState(),
+		_unixTime(offer.CreatedTimestamp), _unixTime(offer.ModifiedTimestamp),
+		_unixTime(offer.SubmittedTimestamp), _unixTime(offer.PausedTimestamp), data)
+
+	return err
+
+}
+
+// Store the render request in the database
+func (repository *SQLiteRenderRepository) SaveRequest(request *RenderRequest) error {
+	var err error
+
+	// get the canonical document
+	data, err := _readDocument(request.DocumentPath, request)
+	if err != nil {
+		return err
+	}
+
+	_, err = repository.DB.Exec(`
+		INSERT INTO requests (cid, path, directory_cid, owner, state, version, price, created, modified, submitted, closed, document)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (cid) DO UPDATE SET
+			path = excluded.path, directory_cid = excluded.directory_cid, state = excluded.state,
+			modified = excluded.modified, submitted = excluded.submitted, closed = excluded.closed,
+			document = excluded.document`,
+		request.DocumentCID, request.DocumentPath, request.DirectoryCID, _ownerString(request.Owner),
+		request.RepositoryState(), request.Version, request.Price,
+		_unixTime(request.CreatedTimestamp), _unixTime(request.ModifiedTimestamp),
+		_unixTime(request.SubmittedTimestamp), _unixTime(request.ClosedTimestamp), data)
+
+	return err
+
+}
+
+// Store the render job (and its result) in the database
+func (repository *SQLiteRenderRepository) SaveJob(job *RenderJob) error {
+	var err error
+
+	resultCID := ""
+	if job.Result != nil {
+		resultCID = job.Result.ResultCID
+	}
+
+	// the subtasks of a render request are stored with their result CID
+	if job.Subtask != nil {
+		_, err = repository.DB.Exec(`
+			INSERT INTO subtasks (request_cid, subtask, frame_start, frame_end, state, operator, claimed, deadline, attempts, flagged, result_cid, updated)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (request_cid, subtask) DO UPDATE SET
+				state = excluded.state, operator = excluded.operator, claimed = excluded.claimed,
+				deadline = excluded.deadline, attempts = excluded.attempts, flagged = excluded.flagged,
+				result_cid = excluded.result_cid, updated = excluded.updated`,
+			job.Request.DocumentCID, job.Subtask.Index, job.Subtask.FrameStart, job.Subtask.FrameEnd,
+			job._state(), job.Operator, _unixTime(job.ClaimedTimestamp), _unixTime(job.Deadline),
+			job.Attempts, job.Flagged, resultCID, time.Now().Unix())
+		return err
+	}
+
+	_, err = repository.DB.Exec(`
+		INSERT INTO jobs (request_cid, state, claimed, deadline, attempts, flagged, result_cid, updated)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (request_cid) DO UPDATE SET
+			state = excluded.state, claimed = excluded.claimed, deadline = excluded.deadline,
+			attempts = excluded.attempts, flagged = excluded.flagged, result_cid = excluded.result_cid,
+			updated = excluded.updated`,
+		job.Request.DocumentCID, job._state(), _unixTime(job.ClaimedTimestamp), _unixTime(job.Deadline),
+		job.Attempts, job.Flagged, resultCID, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+
+	// store the result of the job
+	if job.Result != nil {
+		err = repository.SaveResult(job.Request.DocumentCID, job.Result)
+	}
+
+	return err
+
+}
+
+// Store a render result in the database
+func (repository *SQLiteRenderRepository) SaveResult(requestCID string, result *RenderResult) error {
+	var err error
+
+	frameHashes, err := json.Marshal(result.FrameHashes)
+	if err != nil {
+		return err
+	}
+
+	_, err = repository.DB.Exec(`
+		INSERT INTO results (request_cid, operator, result_cid, frame_hashes, updated)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (request_cid, operator) DO UPDATE SET
+			result_cid = excluded.result_cid, frame_hashes = excluded.frame_hashes, updated = excluded.updated`,
+		requestCID, result.OperatorAccountID, result.ResultCID, string(frameHashes), time.Now().Unix())
+
+	return err
+
+}
+
+// Get the CIDs of the stored render offers matching the filter (newest first)
+// NOTE: The Blender versions of a render offer are only stored in its document,
+// so the version filter and the page are applied by the caller.
+func (repository *SQLiteRenderRepository) FindOffers(filter RenderListFilter) ([]string, error) {
+	return repository._findDocuments("offers", filter, false)
+}
+
+// Get the CIDs of the stored render requests matching the filter (newest first)
+// NOTE: The page is applied by the caller.
+func (repository *SQLiteRenderRepository) FindRequests(filter RenderListFilter) ([]string, error) {
+	return repository._findDocuments("requests", filter, true)
+}
+
+// Mark the render offer or render request as archived in the database
+func (repository *SQLiteRenderRepository) Archive(cid string, path string) error {
+	var err error
+
+	for _, table := range []string{"offers", "requests"} {
+		_, err = repository.DB.Exec("UPDATE "+table+" SET state = ?, path = ? WHERE cid = ?", REPOSITORY_STATE_ARCHIVED, path, cid)
+		if err != nil {
+			return err
+		}
+	}
+
+	return err
+
+}
+
+// Close the database
+func (repository *SQLiteRenderRepository) Close() error {
+	return repository.DB.Close()
+}
+
+// Get the documents returned by the query from the database
+func (repository *SQLiteRenderRepository) _loadDocuments(query string) ([]RenderDocument, error) {
+	var err error
+	var documents []RenderDocument
+
+	rows, err := repository.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var document RenderDocument
+		var submitted, closed int64
+		err = rows.Scan(&document.CID, &document.Path, &document.Data, &document.State, &submitted, &closed)
+		if err != nil {
+			return nil, err
+		}
+		document.SubmittedTimestamp = _timeFromUnix(submitted)
+		document.ClosedTimestamp = _timeFromUnix(closed)
+		documents = append(documents, document)
+	}
+
+	return documents, rows.Err()
+
+}
+
+// Get the CIDs of the documents in the table matching the filter (newest first)
+// NOTE: The creation times are stored in seconds, so the date range is widened
+// to full seconds. The caller checks the exact times.
+func (repository *SQLiteRenderRepository) _findDocuments(table string, filter RenderListFilter, version bool) ([]string, error) {
+	var err error
+	var cids []string
+
+	// build the conditions (each one uses an index of the table)
+	conditions := []string{"state != ?"}
+	args := []interface{}{REPOSITORY_STATE_ARCHIVED}
+	if filter.State != "" {
+		conditions = append(conditions, "state = ?")
+		args = append(args, strings.ToLower(filter.State))
+	}
+	if filter.Owner != "" {
+		conditions = append(conditions, "owner = ?")
+		args = append(args, filter.Owner)
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "created >= ?")
+		args = append(args, filter.From.Unix())
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "created <= ?")
+		args = append(args, filter.To.Unix())
+	}
+	if version && filter.Version != "" {
+		conditions = append(conditions, "version = ?")
+		args = append(args, filter.Version)
+	}
+
+	rows, err := repository.DB.Query("SELECT cid FROM "+table+" WHERE "+strings.Join(conditions, " AND ")+" ORDER BY created DESC, cid", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid string
+		err = rows.Scan(&cid)
+		if err != nil {
+			return nil, err
+		}
+		cids = append(cids, cid)
+	}
+
+	return cids, rows.Err()
+
+}
+
+// Import the local JSON documents once into the database
+func (repository *SQLiteRenderRepository) _importJsonDocuments() error {
+	var err error
+
+	// check if the documents were already imported
+	var imported string
+	err = repository.DB.QueryRow("SELECT value FROM meta WHERE key = ?", sqliteRenderRepositoryImportKey).Scan(&imported)
+	if err == nil {
+		return nil
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+
+	// log event
+	logger.Manager.Package["node"].Info().Msg(fmt.Sprintf("Importing the render documents into the SQLite repository '%v' ...", repository.Path))
+
+	// get the JSON documents
+	// NOTE: Missing document directories only mean, that there is nothing to import.
+	jsonRepository := NewJsonRenderRepository()
+	offers, err := jsonRepository.LoadOffers()
+	if err != nil {
+		logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf(" [#] %v", err))
+	}
+	requests, err := jsonRepository.LoadRequests()
+	if err != nil {
+		logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf(" [#] %v", err))
+	}
+
+	// import all documents in a single transaction
+	tx, err := repository.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, document := range offers {
+		header, err := _decodeDocumentHeader(document.Data)
+		if err != nil {
+			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf(" [#] Could not import render offer %v: %v", document.Path, err))
+			continue
+		}
+		_, err = tx.Exec(`
+			INSERT OR IGNORE INTO offers (cid, path, owner, state, created, modified, submitted, closed, document)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			document.CID, document.Path, header.owner(), document.State,
+			_unixTime(header.CreatedTimestamp), _unixTime(header.ModifiedTimestamp),
+			_unixTime(document.SubmittedTimestamp), _unixTime(document.ClosedTimestamp), document.Data)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, document := range requests {
+		header, err := _decodeDocumentHeader(document.Data)
+		if err != nil {
+			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf(" [#] Could not import render request %v: %v", document.Path, err))
+			continue
+		}
+		_, err = tx.Exec(`
+			INSERT OR IGNORE INTO requests (cid, path, directory_cid, owner, state, version, price, created, modified, submitted, closed, document)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			document.CID, document.Path, header.DirectoryCID, header.owner(), document.State,
+			header.Version, header.Price, _unixTime(header.CreatedTimestamp), _unixTime(header.ModifiedTimestamp),
+			_unixTime(document.SubmittedTimestamp), _unixTime(document.ClosedTimestamp), document.Data)
+		if err != nil {
+			return err
+		}
+	}
+
+	// mark the import as done
+	_, err = tx.Exec("INSERT INTO meta (key, value) VALUES (?, ?)", sqliteRenderRepositoryImportKey, time.Now().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	// log event
+	logger.Manager.Package["node"].Info().Msg(fmt.Sprintf(" [#] Imported %v render offers and %v render requests.", len(offers), len(requests)))
+
+	return nil
+
+}
+
+// INTERNAL HELPER FUNCTIONS
+// #############################################################################
+// Fields of the render offer and render request documents used for the indexes
+type documentHeader struct {
+	CreatedTimestamp  time.Time
+	ModifiedTimestamp time.Time
+	DirectoryCID      string
+	Version           string
+	Price             float64
+	Owner             *struct {
+		Shard   uint64 `json:"Shard"`
+		Realm   uint64 `json:"Realm"`
+		Account uint64 `json:"Account"`
+	} `json:"Owner"`
+}
+
+// Decode the index fields from a document
+func _decodeDocumentHeader(data []byte) (*documentHeader, error) {
+	var header documentHeader
+	err := json.Unmarshal(data, &header)
+	return &header, err
+}
+
+// Get the owner account ID of the document header
+func (header *documentHeader) owner() string {
+	if header.Owner == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v.%v.%v", header.Owner.Shard, header.Owner.Realm, header.Owner.Account)
+}
+
+// Get the account ID as string (empty, if not set)
+func _ownerString(owner *hederasdk.AccountID) string {
+	if owner == nil {
+		return ""
+	}
+	return owner.String()
+}
+
+// Read the canonical document from the local file (or encode the object, if the file is missing)
+func _readDocument(path string, object interface{}) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return json.MarshalIndent(object, "", "  ")
+	}
+	return data, err
+}
+
+// Convert a time into unix seconds (0 for the zero time)
+func _unixTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// Convert unix seconds into a time (the zero time for 0)
+func _timeFromUnix(seconds int64) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
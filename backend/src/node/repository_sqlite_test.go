@@ -0,0 +1,181 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	"renderhive/logger"
+)
+
+func TestSQLiteRepositoryStoresJobsAndResults(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	repository, err := OpenSQLiteRenderRepository(filepath.Join(t.TempDir(), "renderhive.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repository.Close()
+
+	// a completed render job, a subtask, and a disagreeing result
+	request := &RenderRequest{DocumentCID: "request-1"}
+	job := &RenderJob{Request: request, State: RENDER_JOB_STATE_COMPLETED, Attempts: 1, Result: &RenderResult{OperatorAccountID: "0.0.1001", ResultCID: "result-1", FrameHashes: map[int]string{1: "hash-1"}}}
+	subtask := &RenderJob{Request: request, State: RENDER_JOB_STATE_CLAIMED, Subtask: &RenderSubtask{Index: 2, FrameStart: 11, FrameEnd: 20}, Operator: "0.0.1002"}
+	for _, job := range []*RenderJob{job, subtask} {
+		if err := repository.SaveJob(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repository.SaveResult("request-1", &RenderResult{OperatorAccountID: "0.0.1003", ResultCID: "result-2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// an update replaces the stored state
+	job.Flagged = true
+	if err := repository.SaveJob(job); err != nil {
+		t.Fatal(err)
+	}
+
+	var state, attempts int
+	var flagged bool
+	var resultCID string
+	err = repository.DB.QueryRow("SELECT state, attempts, flagged, result_cid FROM jobs WHERE request_cid = ?", "request-1").Scan(&state, &attempts, &flagged, &resultCID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != RENDER_JOB_STATE_COMPLETED || attempts != 1 || !flagged || resultCID != "result-1" {
+		t.Errorf("got job state %v, attempts %v, flagged %v, result %v", state, attempts, flagged, resultCID)
+	}
+
+	var operator string
+	err = repository.DB.QueryRow("SELECT state, operator FROM subtasks WHERE request_cid = ? AND subtask = ?", "request-1", 2).Scan(&state, &operator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != RENDER_JOB_STATE_CLAIMED || operator != "0.0.1002" {
+		t.Errorf("got subtask state %v of operator %v", state, operator)
+	}
+
+	var results int
+	err = repository.DB.QueryRow("SELECT COUNT(*) FROM results WHERE request_cid = ?", "request-1").Scan(&results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results != 2 {
+		t.Errorf("got %v results, want 2", results)
+	}
+}
+
+func TestSQLiteRepositoryFindsDocumentsWithItsIndexes(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	repository, err := OpenSQLiteRenderRepository(filepath.Join(t.TempDir(), "renderhive.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repository.Close()
+
+	// the owner and state filters are answered by the indexes
+	for _, query := range []string{
+		"SELECT cid FROM offers WHERE owner = 'x'",
+		"SELECT cid FROM offers WHERE state = 'x'",
+		"SELECT cid FROM requests WHERE owner = 'x'",
+		"SELECT cid FROM requests WHERE state = 'x'",
+	} {
+		var id, parent, unused int
+		var detail string
+		err := repository.DB.QueryRow("EXPLAIN QUERY PLAN "+query).Scan(&id, &parent, &unused, &detail)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(detail, "USING INDEX") {
+			t.Errorf("%v: got plan '%v', want an index", query, detail)
+		}
+	}
+
+	// the requests are found by owner, state, version, and date (newest first)
+	created := time.Unix(1700000000, 0)
+	owner, _ := hederasdk.AccountIDFromString("0.0.1001")
+	for i, cid := range []string{"request-1", "request-2", "request-3"} {
+		request := &RenderRequest{DocumentCID: cid, Owner: &owner, Version: "4.1.0", CreatedTimestamp: created.Add(time.Duration(i) * time.Hour)}
+		if cid == "request-3" {
+			request.Version = "3.6.0"
+		}
+		if err := repository.SaveRequest(request); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repository.Archive("request-1", "archive/request-1.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	cids, err := repository.FindRequests(RenderListFilter{Owner: "0.0.1001", State: REPOSITORY_STATE_CREATED})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(cids, ",") != "request-3,request-2" {
+		t.Errorf("got %v, want the active requests (newest first)", cids)
+	}
+	cids, _ = repository.FindRequests(RenderListFilter{Version: "4.1.0", From: created})
+	if strings.Join(cids, ",") != "request-2" {
+		t.Errorf("got %v, want the active requests of Blender v4.1.0", cids)
+	}
+	cids, _ = repository.FindRequests(RenderListFilter{Owner: "0.0.1002"})
+	if len(cids) != 0 {
+		t.Errorf("got %v, want no requests of another owner", cids)
+	}
+}
+
+func TestSQLiteRepositoryLoadsTheRequestsInOrder(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	repository, err := OpenSQLiteRenderRepository(filepath.Join(t.TempDir(), "renderhive.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repository.Close()
+
+	// store the requests in reverse order
+	created := time.Unix(1700000000, 0)
+	for i, cid := range []string{"request-2", "request-1"} {
+		request := &RenderRequest{DocumentCID: cid, CreatedTimestamp: created.Add(time.Duration(-i) * time.Hour)}
+		if err := repository.SaveRequest(request); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	documents, err := repository.LoadRequests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(documents) != 2 || documents[0].CID != "request-1" || documents[1].CID != "request-2" {
+		t.Fatalf("got %+v, want the requests in the order of their creation", documents)
+	}
+}
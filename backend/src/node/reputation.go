@@ -0,0 +1,503 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+The reputation tracker derives the reliability of the other render nodes from
+the commands observed on the job queue topic. A node is identified by the
+operator account, which paid for the topic message.
+
+Observed events:
+  - result submission: the node completed a render job. The turnaround is the
+    time between the submission of the render request and of the result. The
+    completion only counts, if the result names the transaction, which settled
+    the render job on the smart contract, and the mirror node confirms that
+    the node paid for this successful contract call. Each settlement counts
+    only once. Anyone can submit messages to the topic, so unsettled results
+    are only remembered for a later dispute resolution.
+  - job release: the node abandoned a claimed render job (e.g., a timeout).
+  - dispute resolution: each node whose settled result for the render job was
+    not accepted lost the dispute.
+
+Metrics:
+  - completion rate: completions / (completions + releases)
+  - dispute rate: lost disputes / completions
+  - average turnaround: sum of turnarounds / completions
+  - success rate: (completions - lost disputes + p * k) / (completions + releases + k)
+
+The success rate feeds the reliability score of the offer ranking. It starts
+at the neutral prior p (0.5) for nodes without history and moves towards the
+observed rate as events are observed (k = 2 pseudo events).
+
+All counters decay exponentially with a half-life of 30 days, so that recent
+events weigh more than old events. The tracker is stored in the app data
+directory and messages already observed are skipped when the topic is replayed
+on the next start.
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/hedera"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// Reputation of a render node
+// NOTE: The counters are decayed to the updated timestamp.
+type NodeReputation struct {
+	AccountID        string    `json:"account_id"`
+	Completions      float64   `json:"completions"`       // decayed number of submitted render results
+	Releases         float64   `json:"releases"`          // decayed number of released (e.g., timed out) render jobs
+	Disputes         float64   `json:"disputes"`          // decayed number of lost disputes
+	Turnaround       float64   `json:"turnaround"`        // decayed sum of the turnarounds in seconds
+	UpdatedTimestamp time.Time `json:"updated_timestamp"` // The datetime of the last observed event
+}
+
+// Render result observed on the job queue topic
+type ObservedResult struct {
+	AccountID          string    `json:"account_id"`
+	ResultCID          string    `json:"result_cid"`
+	ConsensusTimestamp time.Time `json:"consensus_timestamp"`
+	Settled            bool      `json:"settled"` // the settlement of the render job was confirmed
+}
+
+// Reputation tracker of the render nodes
+type ReputationTracker struct {
+	Mutex sync.Mutex `json:"-"`
+
+	LastObserved time.Time                   `json:"last_observed"` // consensus timestamp of the last observed message
+	Nodes        map[string]*NodeReputation  `json:"nodes"`         // reputation by operator account ID
+	Results      map[string][]ObservedResult `json:"results"`       // observed render results by render request CID
+	Settlements  map[string]time.Time        `json:"settlements"`   // counted settlement transactions (by transaction ID)
+
+	// confirms that the account paid for the successful settlement transaction
+	// (nil = no result counts as completion)
+	VerifySettlement func(transactionID string, accountID string) bool `json:"-"`
+}
+
+// REPUTATION TRACKER
+// #############################################################################
+// Get the path of the reputation file
+func (tracker *ReputationTracker) Path() string {
+	return filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_REPUTATION, "reputation.json")
+}
+
+// Load the reputation tracker from the local file
+func (tracker *ReputationTracker) Load() error {
+	var err error
+
+	// lock the tracker
+	tracker.Mutex.Lock()
+	defer tracker.Mutex.Unlock()
+
+	tracker.Nodes = make(map[string]*NodeReputation)
+	tracker.Results = make(map[string][]ObservedResult)
+	tracker.Settlements = make(map[string]time.Time)
+
+	// read the file (a missing file is an empty tracker)
+	data, err := os.ReadFile(tracker.Path())
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// get the file content into a structure
+	err = json.Unmarshal(data, tracker)
+	if err != nil {
+		return err
+	}
+	if tracker.Nodes == nil {
+		tracker.Nodes = make(map[string]*NodeReputation)
+	}
+	if tracker.Results == nil {
+		tracker.Results = make(map[string][]ObservedResult)
+	}
+	if tracker.Settlements == nil {
+		tracker.Settlements = make(map[string]time.Time)
+	}
+
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Loaded the reputation of %v nodes.", len(tracker.Nodes)))
+
+	return err
+
+}
+
+// Observe a render result submitted to the job queue topic
+// NOTE: The turnaround is only known, if the render request was observed. The
+// result only counts as completion, if its settlement transaction is confirmed.
+func (tracker *ReputationTracker) ObserveResult(message hederasdk.TopicMessage, requestCID string, resultCID string, requestTimestamp time.Time, settlementTransactionID string) {
+
+	accountID := _messageSender(message)
+	if accountID == "" {
+		return
+	}
+
+	// confirm the settlement before the tracker is locked (mirror node query)
+	settled := false
+	if settlementTransactionID != "" && tracker.VerifySettlement != nil {
+		settled = tracker.VerifySettlement(settlementTransactionID, accountID)
+	}
+
+	// lock the tracker
+	tracker.Mutex.Lock()
+	defer tracker.Mutex.Unlock()
+
+	if !tracker._observe(message) {
+		return
+	}
+
+	// each settlement counts only once
+	if _, ok := tracker.Settlements[settlementTransactionID]; settled && ok {
+		settled = false
+	}
+
+	// update the reputation of the node
+	if settled {
+		if tracker.Settlements == nil {
+			tracker.Settlements = make(map[string]time.Time)
+		}
+		tracker.Settlements[settlementTransactionID] = message.ConsensusTimestamp
+
+		reputation := tracker._get(accountID, message.ConsensusTimestamp)
+		reputation.Completions += 1
+		if !requestTimestamp.IsZero() && message.ConsensusTimestamp.After(requestTimestamp) {
+			reputation.Turnaround += message.ConsensusTimestamp.Sub(requestTimestamp).Seconds()
+		}
+	}
+
+	// remember the result for a later dispute resolution
+	tracker.Results[requestCID] = append(tracker.Results[requestCID], ObservedResult{
+		AccountID:          accountID,
+		ResultCID:          resultCID,
+		ConsensusTimestamp: message.ConsensusTimestamp,
+		Settled:            settled,
+	})
+
+	tracker._save()
+
+}
+
+// Observe a render job released to the job queue topic
+func (tracker *ReputationTracker) ObserveRelease(message hederasdk.TopicMessage) {
+
+	accountID := _messageSender(message)
+	if accountID == "" {
+		return
+	}
+
+	// lock the tracker
+	tracker.Mutex.Lock()
+	defer tracker.Mutex.Unlock()
+
+	if !tracker._observe(message) {
+		return
+	}
+
+	// update the reputation of the node
+	reputation := tracker._get(accountID, message.ConsensusTimestamp)
+	reputation.Releases += 1
+
+	tracker._save()
+
+}
+
+// Observe a dispute resolution on the job queue topic
+func (tracker *ReputationTracker) ObserveResolution(message hederasdk.TopicMessage, requestCID string, acceptedResultCID string) {
+
+	// lock the tracker
+	tracker.Mutex.Lock()
+	defer tracker.Mutex.Unlock()
+
+	if !tracker._observe(message) {
+		return
+	}
+
+	// each node whose settled result was not accepted lost the dispute
+	for _, result := range tracker.Results[requestCID] {
+		if result.Settled && result.ResultCID != acceptedResultCID {
+			reputation := tracker._get(result.AccountID, message.ConsensusTimestamp)
+			reputation.Disputes += 1
+		}
+	}
+	delete(tracker.Results, requestCID)
+
+	tracker._save()
+
+}
+
+// Get the current reputation of a node (decayed to now)
+func (tracker *ReputationTracker) Get(accountID string) (NodeReputation, bool) {
+
+	// lock the tracker
+	tracker.Mutex.Lock()
+	defer tracker.Mutex.Unlock()
+
+	reputation, ok := tracker.Nodes[accountID]
+	if !ok {
+		return NodeReputation{AccountID: accountID}, false
+	}
+
+	return reputation._decayed(time.Now()), true
+
+}
+
+// Get the current reputation of all known nodes (decayed to now)
+func (tracker *ReputationTracker) List() []NodeReputation {
+	var reputations []NodeReputation
+
+	// lock the tracker
+	tracker.Mutex.Lock()
+	defer tracker.Mutex.Unlock()
+
+	for _, reputation := range tracker.Nodes {
+		reputations = append(reputations, reputation._decayed(time.Now()))
+	}
+
+	return reputations
+
+}
+
+// Get the completion rate of the node (0 ... 1)
+func (reputation *NodeReputation) CompletionRate() (float64, bool) {
+
+	if reputation.Completions+reputation.Releases <= 0 {
+		return 0, false
+	}
+
+	return reputation.Completions / (reputation.Completions + reputation.Releases), true
+
+}
+
+// Get the dispute rate of the node (0 ... 1)
+func (reputation *NodeReputation) DisputeRate() (float64, bool) {
+
+	if reputation.Completions <= 0 {
+		return 0, false
+	}
+
+	return math.Min(reputation.Disputes/reputation.Completions, 1.0), true
+
+}
+
+// Get the average turnaround of the node
+func (reputation *NodeReputation) AverageTurnaround() (time.Duration, bool) {
+
+	if reputation.Completions <= 0 || reputation.Turnaround <= 0 {
+		return 0, false
+	}
+
+	return time.Duration(reputation.Turnaround / reputation.Completions * float64(time.Second)), true
+
+}
+
+// Get the success rate of the node (0 ... 1; the neutral prior without history)
+func (reputation *NodeReputation) SuccessRate() float64 {
+
+	prior := RENDERHIVE_CONFIG_REPUTATION_NEUTRAL_SUCCESS_RATE
+	weight := RENDERHIVE_CONFIG_REPUTATION_PRIOR_WEIGHT
+	successes := math.Max(reputation.Completions-reputation.Disputes, 0)
+
+	return (successes + prior*weight) / (reputation.Completions + reputation.Releases + weight)
+
+}
+
+// Get the historical success rate of a node operator
+// NOTE: Nodes without history get the neutral success rate and false.
+func (nm *PackageManager) OperatorSuccessRate(accountID string) (float64, bool) {
+
+	reputation, ok := nm.Reputation.Get(accountID)
+	if !ok || reputation.Completions+reputation.Releases <= 0 {
+		return reputation.SuccessRate(), false
+	}
+
+	return reputation.SuccessRate(), true
+
+}
+
+// Record the transaction, which settled a render job of this node on the smart contract
+// NOTE: The transaction ID is announced with the render result, so that the
+// other nodes count the completion. The payout (nil, if it is not known) is
+// split between the settled subtasks of this node in the job accounting.
+func (nm *PackageManager) RecordSettlement(requestCID string, transactionID string, payout *hederasdk.Hbar) {
+
+	settled := []*RenderJob{}
+	for _, job := range nm.Renderer.NodeQueue {
+		if job.Request != nil && job.Request.DocumentCID == requestCID && job.Result != nil {
+			job.Result.SettlementTransactionID = transactionID
+			job.Save()
+			settled = append(settled, job)
+		}
+	}
+
+	// record the settlement in the job accounting
+	for i, job := range settled {
+		hedera.Manager.History.AddJobTransaction(requestCID, job.SubtaskIndex(), transactionID)
+		hedera.Manager.History.CompleteJob(requestCID, job.SubtaskIndex(), time.Since(job.ClaimedTimestamp))
+		if payout == nil {
+			continue
+		}
+		share := payout.AsTinybar() / int64(len(settled))
+		if i == 0 {
+			share += payout.AsTinybar() % int64(len(settled))
+		}
+		hedera.Manager.History.SetJobPayout(requestCID, job.SubtaskIndex(), hederasdk.HbarFromTinybar(share))
+	}
+
+}
+
+// helper function to confirm with the mirror node, that the account paid for a
+// successful call of the Renderhive smart contract
+func _settlementConfirmed(transactionID string, accountID string) bool {
+
+	// the payer of the transaction is part of the transaction ID
+	parsed, err := hederasdk.TransactionIdFromString(transactionID)
+	if err != nil || parsed.AccountID == nil || parsed.AccountID.String() != accountID {
+		return false
+	}
+
+	info, err := hedera.Manager.MirrorNode.GetTransactionInfo(parsed.String())
+	if err != nil {
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf("Could not confirm the settlement transaction '%v': %v", transactionID, err))
+		return false
+	}
+
+	return info.Result == "SUCCESS" && info.Name == "CONTRACTCALL" && info.EntityID == RENDERHIVE_TESTNET_SMART_CONTRACT
+
+}
+
+// Check if the message was not observed yet and mark it as observed
+// NOTE: The caller must hold the mutex.
+func (tracker *ReputationTracker) _observe(message hederasdk.TopicMessage) bool {
+
+	// the topic is replayed from the start on each start of the node
+	if !message.ConsensusTimestamp.After(tracker.LastObserved) {
+		return false
+	}
+	tracker.LastObserved = message.ConsensusTimestamp
+
+	// forget unresolved results and settlements, whose counters have mostly decayed
+	for requestCID, results := range tracker.Results {
+		if len(results) > 0 && message.ConsensusTimestamp.Sub(results[0].ConsensusTimestamp) > 4*RENDERHIVE_CONFIG_REPUTATION_HALF_LIFE {
+			delete(tracker.Results, requestCID)
+		}
+	}
+	for transactionID, timestamp := range tracker.Settlements {
+		if message.ConsensusTimestamp.Sub(timestamp) > 4*RENDERHIVE_CONFIG_REPUTATION_HALF_LIFE {
+			delete(tracker.Settlements, transactionID)
+		}
+	}
+
+	return true
+
+}
+
+// Get the reputation of a node decayed to the given time (and create it, if it is unknown)
+// NOTE: The caller must hold the mutex.
+func (tracker *ReputationTracker) _get(accountID string, timestamp time.Time) *NodeReputation {
+
+	reputation, ok := tracker.Nodes[accountID]
+	if !ok {
+		reputation = &NodeReputation{AccountID: accountID, UpdatedTimestamp: timestamp}
+		tracker.Nodes[accountID] = reputation
+	}
+	*reputation = reputation._decayed(timestamp)
+
+	return reputation
+
+}
+
+// Get the reputation with its counters decayed to the given time
+func (reputation NodeReputation) _decayed(timestamp time.Time) NodeReputation {
+
+	if !timestamp.After(reputation.UpdatedTimestamp) {
+		return reputation
+	}
+
+	// exponential decay with the configured half-life
+	factor := math.Pow(0.5, float64(timestamp.Sub(reputation.UpdatedTimestamp))/float64(RENDERHIVE_CONFIG_REPUTATION_HALF_LIFE))
+	reputation.Completions *= factor
+	reputation.Releases *= factor
+	reputation.Disputes *= factor
+	reputation.Turnaround *= factor
+	reputation.UpdatedTimestamp = timestamp
+
+	return reputation
+
+}
+
+// Write the reputation tracker to the local file
+// NOTE: The caller must hold the mutex.
+func (tracker *ReputationTracker) _save() error {
+	var err error
+
+	// create the directory, if it does not exist
+	err = os.MkdirAll(filepath.Dir(tracker.Path()), 0700)
+	if err != nil {
+		return err
+	}
+
+	// write the tracker to a temporary file and replace the old file
+	data, err := json.MarshalIndent(tracker, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(tracker.Path()+".tmp", data, 0600)
+	if err != nil {
+		logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not write the reputation tracker: %v", err))
+		return err
+	}
+	err = os.Rename(tracker.Path()+".tmp", tracker.Path())
+	if err != nil {
+		logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not write the reputation tracker: %v", err))
+		return err
+	}
+
+	return err
+
+}
+
+// Get the operator account, which paid for the topic message
+func _messageSender(message hederasdk.TopicMessage) string {
+
+	if message.TransactionID == nil || message.TransactionID.AccountID == nil {
+		return ""
+	}
+
+	return message.TransactionID.AccountID.String()
+
+}
@@ -0,0 +1,174 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"testing"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/hedera"
+	"renderhive/logger"
+)
+
+// helper function to create a topic message paid by the given account
+func _testTopicMessage(accountID string, timestamp time.Time) hederasdk.TopicMessage {
+	account, _ := hederasdk.AccountIDFromString(accountID)
+	transactionID := hederasdk.TransactionIDGenerate(account)
+
+	return hederasdk.TopicMessage{ConsensusTimestamp: timestamp, TransactionID: &transactionID}
+}
+
+// helper function to create an empty reputation tracker in a temporary app data directory
+func _testReputationTracker(t *testing.T) *ReputationTracker {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	tracker := &ReputationTracker{}
+	if err := tracker.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	return tracker
+}
+
+func TestReputationCountsOnlySettledResults(t *testing.T) {
+	tracker := _testReputationTracker(t)
+	now := time.Now()
+
+	// the settlement of node A is confirmed, the one of node B is not
+	var verified []string
+	tracker.VerifySettlement = func(transactionID string, accountID string) bool {
+		verified = append(verified, transactionID)
+		return accountID == "0.0.1001"
+	}
+
+	// a result without settlement does not count
+	tracker.ObserveResult(_testTopicMessage("0.0.1001", now.Add(1*time.Second)), "request-1", "result-a", time.Time{}, "")
+	if reputation, _ := tracker.Get("0.0.1001"); reputation.Completions != 0 {
+		t.Fatalf("an unsettled result was counted: %+v", reputation)
+	}
+	if len(verified) != 0 {
+		t.Fatal("a result without settlement must not be verified")
+	}
+
+	// a confirmed settlement counts
+	tracker.ObserveResult(_testTopicMessage("0.0.1001", now.Add(2*time.Second)), "request-2", "result-a", now, "0.0.1001@1.1")
+	if reputation, _ := tracker.Get("0.0.1001"); reputation.Completions < 0.99 || reputation.Turnaround <= 0 {
+		t.Fatalf("a settled result was not counted: %+v", reputation)
+	}
+
+	// the same settlement counts only once
+	tracker.ObserveResult(_testTopicMessage("0.0.1001", now.Add(3*time.Second)), "request-3", "result-a", time.Time{}, "0.0.1001@1.1")
+	if reputation, _ := tracker.Get("0.0.1001"); reputation.Completions > 1.01 {
+		t.Fatalf("a settlement was counted twice: %+v", reputation)
+	}
+
+	// an unconfirmed settlement does not count
+	tracker.ObserveResult(_testTopicMessage("0.0.2002", now.Add(4*time.Second)), "request-2", "result-b", time.Time{}, "0.0.2002@1.1")
+	if reputation, _ := tracker.Get("0.0.2002"); reputation.Completions != 0 {
+		t.Fatalf("an unconfirmed settlement was counted: %+v", reputation)
+	}
+
+	// only the settled result can lose a dispute
+	tracker.ObserveResolution(_testTopicMessage("0.0.3003", now.Add(5*time.Second)), "request-2", "result-c")
+	if reputation, _ := tracker.Get("0.0.1001"); reputation.Disputes < 0.99 {
+		t.Fatalf("the settled result did not lose the dispute: %+v", reputation)
+	}
+	if reputation, _ := tracker.Get("0.0.2002"); reputation.Disputes != 0 {
+		t.Fatalf("the unsettled result lost the dispute: %+v", reputation)
+	}
+}
+
+func TestReputationWithoutVerifierCountsNothing(t *testing.T) {
+	tracker := _testReputationTracker(t)
+
+	tracker.ObserveResult(_testTopicMessage("0.0.1001", time.Now()), "request-1", "result-a", time.Time{}, "0.0.1001@1.1")
+	if reputation, _ := tracker.Get("0.0.1001"); reputation.Completions != 0 {
+		t.Fatalf("a result was counted without verification: %+v", reputation)
+	}
+}
+
+func TestOperatorSuccessRateWithoutData(t *testing.T) {
+	_testReputationTracker(t)
+	nm := &PackageManager{}
+	if err := nm.Reputation.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	rate, ok := nm.OperatorSuccessRate("0.0.1001")
+	if ok {
+		t.Fatal("an operator without history must have no success rate")
+	}
+	if rate != RENDERHIVE_CONFIG_REPUTATION_NEUTRAL_SUCCESS_RATE {
+		t.Fatalf("unexpected neutral success rate: %v", rate)
+	}
+
+	// a release is data
+	nm.Reputation.ObserveRelease(_testTopicMessage("0.0.1001", time.Now()))
+	if rate, ok = nm.OperatorSuccessRate("0.0.1001"); !ok || rate >= RENDERHIVE_CONFIG_REPUTATION_NEUTRAL_SUCCESS_RATE {
+		t.Fatalf("unexpected success rate after a release: %v (%v)", rate, ok)
+	}
+}
+
+func TestRecordSettlementAccountsThePayout(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	request := &RenderRequest{DocumentCID: "settled-request"}
+	nm := &PackageManager{}
+	nm.Renderer.NodeQueue = []*RenderJob{
+		{Request: request, Subtask: &RenderSubtask{Index: 1}, Result: &RenderResult{}, ClaimedTimestamp: time.Now().Add(-time.Hour)},
+		{Request: request, Subtask: &RenderSubtask{Index: 2}, Result: &RenderResult{}, ClaimedTimestamp: time.Now().Add(-time.Hour)},
+		{Request: request, Subtask: &RenderSubtask{Index: 3}},
+	}
+
+	// the completed job keeps its render time
+	hedera.Manager.History.CompleteJob(request.DocumentCID, 1, time.Minute)
+
+	// the payout is split between the settled subtasks
+	payout := hederasdk.HbarFromTinybar(101)
+	nm.RecordSettlement(request.DocumentCID, "0.0.1001@1700000000.000000000", &payout)
+	balances := map[int]hedera.JobBalance{}
+	for _, balance := range hedera.Manager.History.JobBalances(time.Time{}, time.Time{}, hedera.AccountingSettings{}) {
+		if balance.RenderRequestCID == request.DocumentCID {
+			balances[balance.Subtask] = balance
+		}
+	}
+	if len(balances) != 2 {
+		t.Fatalf("got %v job accounts, want the 2 settled subtasks", len(balances))
+	}
+	if balances[1].Payout != 51 || balances[2].Payout != 50 || !balances[1].PayoutKnown || !balances[2].PayoutKnown {
+		t.Errorf("got payouts %v and %v, want 51 and 50 tinybar", balances[1].Payout, balances[2].Payout)
+	}
+	if balances[1].RenderTime != time.Minute || balances[2].CompletedTimestamp.IsZero() {
+		t.Error("the settled subtasks must be completed once")
+	}
+	if nm.Renderer.NodeQueue[0].Result.SettlementTransactionID != "0.0.1001@1700000000.000000000" {
+		t.Error("the settlement transaction must be recorded in the render result")
+	}
+}
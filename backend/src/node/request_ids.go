@@ -0,0 +1,296 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the integer IDs of the render requests of this node.
+
+The command line interface addresses the render requests of this node with
+integer IDs (e.g., 'request submit -i 1'), while the render requests are stored
+by the CID of their document. Since the CID of a document changes with each
+modification until the request is submitted, the IDs are mapped to the local
+path of the render request document, which does not change. The mapping is
+stored in the 'ids.json' file of the local render request directory, so that
+the IDs stay valid after a restart. An ID is never reused for another document.
+
+A render request keeps its ID, when its document is replaced by the deployed
+document. The IDs of archived render requests and of documents, which no longer
+exist, are removed from the mapping.
+
+Render request documents without an ID (e.g., created before the IDs were
+stored) get a new ID, when they are loaded.
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// Persistent mapping of the render request IDs to the local document paths
+type RenderRequestIDs struct {
+	NextID int            `json:"next_id"` // ID of the next render request
+	Paths  map[int]string `json:"paths"`   // local paths of the render request documents by ID
+
+	mutex sync.Mutex
+	path  string // path of the mapping file (empty = not persisted)
+}
+
+// RENDER REQUEST IDS
+// #############################################################################
+// Load the mapping of the render request IDs from a file (empty, if the file does not exist)
+func LoadRenderRequestIDs(path string) (*RenderRequestIDs, error) {
+
+	ids := &RenderRequestIDs{NextID: 1, Paths: map[int]string{}, path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ids, nil
+	} else if err != nil {
+		return ids, err
+	}
+
+	err = json.Unmarshal(data, ids)
+	if err != nil {
+		return &RenderRequestIDs{NextID: 1, Paths: map[int]string{}, path: path}, err
+	}
+	if ids.Paths == nil {
+		ids.Paths = map[int]string{}
+	}
+
+	// the next ID must not collide with a stored ID
+	for id := range ids.Paths {
+		if id >= ids.NextID {
+			ids.NextID = id + 1
+		}
+	}
+
+	return ids, nil
+
+}
+
+// Get the ID of a render request document (a new ID is assigned, if it has none)
+func (ids *RenderRequestIDs) Assign(documentPath string) int {
+
+	// lock the mapping
+	ids.mutex.Lock()
+	defer ids.mutex.Unlock()
+
+	for id, path := range ids.Paths {
+		if path == documentPath {
+			return id
+		}
+	}
+
+	id := ids.NextID
+	ids.NextID++
+	ids.Paths[id] = documentPath
+	ids._save()
+
+	return id
+
+}
+
+// Map an assigned ID to another render request document (false, if the ID was not assigned)
+func (ids *RenderRequestIDs) Move(id int, documentPath string) bool {
+
+	// lock the mapping
+	ids.mutex.Lock()
+	defer ids.mutex.Unlock()
+
+	path, ok := ids.Paths[id]
+	if !ok {
+		return false
+	}
+	if path != documentPath {
+		ids.Paths[id] = documentPath
+		ids._save()
+	}
+
+	return true
+
+}
+
+// Remove the ID of a render request (the ID is not reused)
+func (ids *RenderRequestIDs) Remove(id int) {
+
+	// lock the mapping
+	ids.mutex.Lock()
+	defer ids.mutex.Unlock()
+
+	if _, ok := ids.Paths[id]; ok {
+		delete(ids.Paths, id)
+		ids._save()
+	}
+
+}
+
+// Remove the IDs of the render request documents, which no longer exist, and
+// get the number of removed IDs
+func (ids *RenderRequestIDs) Prune() int {
+
+	// lock the mapping
+	ids.mutex.Lock()
+	defer ids.mutex.Unlock()
+
+	pruned := 0
+	for id, path := range ids.Paths {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			delete(ids.Paths, id)
+			pruned++
+		}
+	}
+	if pruned > 0 {
+		ids._save()
+	}
+
+	return pruned
+
+}
+
+// Get the local path of the render request document with the given ID
+func (ids *RenderRequestIDs) Path(id int) (string, bool) {
+
+	// lock the mapping
+	ids.mutex.Lock()
+	defer ids.mutex.Unlock()
+
+	path, ok := ids.Paths[id]
+
+	return path, ok
+
+}
+
+// Get the assigned IDs in ascending order
+func (ids *RenderRequestIDs) IDs() []int {
+
+	// lock the mapping
+	ids.mutex.Lock()
+	defer ids.mutex.Unlock()
+
+	list := make([]int, 0, len(ids.Paths))
+	for id := range ids.Paths {
+		list = append(list, id)
+	}
+	sort.Ints(list)
+
+	return list
+
+}
+
+// helper function to write the mapping to its file
+// NOTE: The caller must hold the mutex.
+func (ids *RenderRequestIDs) _save() error {
+
+	if ids.path == "" {
+		return nil
+	}
+
+	// create the directory, if it does not exist
+	err := os.MkdirAll(filepath.Dir(ids.path), 0700)
+	if err != nil {
+		return err
+	}
+
+	// write the mapping to a temporary file and replace the old file
+	data, err := json.MarshalIndent(ids, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(ids.path+".tmp", data, 0600)
+	if err == nil {
+		err = os.Rename(ids.path+".tmp", ids.path)
+	}
+	if err != nil {
+		logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not write the render request IDs: %v", err))
+	}
+
+	return err
+
+}
+
+// Get the path of the file with the render request IDs of this node
+func RenderRequestIDsPath() string {
+	return filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_LOCAL_REQUESTS, "ids.json")
+}
+
+// Get the render request of this node with the given ID
+func (nm *PackageManager) GetRenderRequestByID(id int) (*RenderRequest, bool) {
+
+	if nm.Renderer.RequestIDs == nil {
+		return nil, false
+	}
+	path, ok := nm.Renderer.RequestIDs.Path(id)
+	if !ok {
+		return nil, false
+	}
+
+	// the requests added since the start are also stored by their ID
+	if request, ok := nm.Renderer.Requests[strconv.Itoa(id)]; ok && request.DocumentPath == path {
+		return request, true
+	}
+	for _, request := range nm.Renderer.Requests {
+		if request.DocumentPath == path {
+			return request, true
+		}
+	}
+
+	return nil, false
+
+}
+
+// helper function to assign the ID of a render request of this node
+// NOTE: A render request with an ID keeps it, when its document path changes.
+func (nm *PackageManager) _assignRequestID(request *RenderRequest) {
+
+	if nm.Renderer.RequestIDs == nil {
+		nm.Renderer.RequestIDs = &RenderRequestIDs{NextID: 1, Paths: map[int]string{}}
+	}
+	if request.DocumentPath == "" {
+		return
+	}
+	if request.ID != 0 && nm.Renderer.RequestIDs.Move(request.ID, request.DocumentPath) {
+		return
+	}
+	request.ID = nm.Renderer.RequestIDs.Assign(request.DocumentPath)
+
+}
+
+// helper function to remove the ID of a closed render request of this node
+func (nm *PackageManager) _releaseRequestID(request *RenderRequest) {
+
+	if nm.Renderer.RequestIDs != nil && request.ID != 0 {
+		nm.Renderer.RequestIDs.Remove(request.ID)
+	}
+
+}
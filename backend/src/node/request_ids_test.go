@@ -0,0 +1,104 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRequestKeepsItsIDAfterDeploy(t *testing.T) {
+	directory := t.TempDir()
+	ids, err := LoadRenderRequestIDs(filepath.Join(directory, "ids.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nm := &PackageManager{}
+	nm.Renderer.RequestIDs = ids
+
+	// the draft document gets the first ID
+	request := &RenderRequest{DocumentPath: filepath.Join(directory, "request-draft.json")}
+	nm._assignRequestID(request)
+	if request.ID != 1 {
+		t.Fatalf("got ID %v, want 1", request.ID)
+	}
+
+	// the deployed document keeps the ID
+	request.DocumentPath = filepath.Join(directory, "request-deployed.json")
+	nm._assignRequestID(request)
+	if request.ID != 1 || len(ids.IDs()) != 1 {
+		t.Fatalf("got ID %v and IDs %v, want only ID 1", request.ID, ids.IDs())
+	}
+	if path, _ := ids.Path(1); path != request.DocumentPath {
+		t.Fatalf("ID 1 maps to %v, want the deployed document", path)
+	}
+
+	// the mapping is stored
+	loaded, err := LoadRenderRequestIDs(filepath.Join(directory, "ids.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path, _ := loaded.Path(1); path != request.DocumentPath {
+		t.Fatalf("stored ID 1 maps to %v, want the deployed document", path)
+	}
+}
+
+func TestRequestIDsArePruned(t *testing.T) {
+	directory := t.TempDir()
+	ids, err := LoadRenderRequestIDs(filepath.Join(directory, "ids.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := filepath.Join(directory, "request-existing.json")
+	if err := os.WriteFile(existing, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	existingID := ids.Assign(existing)
+	missingID := ids.Assign(filepath.Join(directory, "request-missing.json"))
+	closedID := ids.Assign(filepath.Join(directory, "request-closed.json"))
+
+	// the closed request is removed
+	nm := &PackageManager{}
+	nm.Renderer.RequestIDs = ids
+	nm._releaseRequestID(&RenderRequest{ID: closedID})
+	if _, ok := ids.Path(closedID); ok {
+		t.Fatal("the ID of the closed request is still assigned")
+	}
+
+	// the document that no longer exists is removed
+	if pruned := ids.Prune(); pruned != 1 {
+		t.Fatalf("pruned %v IDs, want 1", pruned)
+	}
+	if _, ok := ids.Path(missingID); ok {
+		t.Fatal("the ID of the missing document is still assigned")
+	}
+	if _, ok := ids.Path(existingID); !ok {
+		t.Fatal("the ID of the existing document was removed")
+	}
+
+	// the removed IDs are not reused
+	if id := ids.Assign(filepath.Join(directory, "request-new.json")); id != closedID+1 {
+		t.Fatalf("got ID %v, want %v", id, closedID+1)
+	}
+}
@@ -0,0 +1,54 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the requester-only mode of the node. In this mode, the node
+only submits render requests and retrieves their results, but does not render
+for the render hive: The installed Blender versions are not scanned, the render
+offers are not loaded, and no render jobs are claimed. IPFS, Hedera, and the
+render requests are initialized as usual.
+
+The mode is enabled with the 'RequesterOnly' field of the node configuration
+(node.json) or with the '--requester-only' flag of the service app.
+
+*/
+
+// REQUESTER-ONLY MODE
+// #############################################################################
+// Check if the node runs in requester-only mode (i.e., without rendering)
+func (nm *PackageManager) IsRequesterOnly() bool {
+
+	return nm.RequesterOnly || nm.Node.RequesterOnly
+
+}
+
+// helper function to reject the render node operations in requester-only mode
+func (nm *PackageManager) _requireRenderNode(operation string) error {
+
+	if nm.IsRequesterOnly() {
+		return newRenderError(ErrRequesterOnly, "Cannot %v: This node runs in requester-only mode.", operation)
+	}
+
+	return nil
+
+}
@@ -0,0 +1,73 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+	// standard
+	"context"
+	"errors"
+	"testing"
+
+	// internal
+	"renderhive/logger"
+)
+
+func TestRequesterOnlyMode(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	_chdirTemp(t)
+
+	nm := &PackageManager{}
+	nm.RequesterOnly = true
+
+	// no render offers are initialized
+	err := nm.InitRenderOffers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nm.Renderer.Offers) != 0 || len(nm.GetActiveRenderOffers()) != 0 {
+		t.Error("a requester-only node must not load render offers")
+	}
+
+	// the render worker returns immediately, although the context is never done
+	nm.RunRenderWorker(context.Background())
+
+	// the render node operations are rejected
+	_, err = nm.NewRenderOffer(1)
+	if !errors.Is(err, ErrRequesterOnly) {
+		t.Errorf("NewRenderOffer: got %v, want %v", err, ErrRequesterOnly)
+	}
+	_, err = nm.InstallBlenderVersion("4.1.0")
+	if !errors.Is(err, ErrRequesterOnly) {
+		t.Errorf("InstallBlenderVersion: got %v, want %v", err, ErrRequesterOnly)
+	}
+	_, err = nm.RenderJobRoot("request")
+	if !errors.Is(err, ErrRequesterOnly) {
+		t.Errorf("RenderJobRoot: got %v, want %v", err, ErrRequesterOnly)
+	}
+	err = nm.CreateCommandSelftest().RunE(nil, nil)
+	if !errors.Is(err, ErrRequesterOnly) {
+		t.Errorf("selftest: got %v, want %v", err, ErrRequesterOnly)
+	}
+	if nm.Renderer.Blender != nil {
+		t.Error("a requester-only node must not initialize the Blender registry")
+	}
+}
@@ -0,0 +1,376 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the verification of the render results downloaded from
+the render hive.
+
+A render result is a directory on IPFS, which contains the rendered frames and
+a result document ('result.json'). The result document references the render
+request and the Blender file that were rendered, and it declares the CID of
+each rendered frame.
+
+A requester can verify a render result before accepting it: The result
+directory is downloaded, the references of the result document are compared
+with the render request, and each downloaded frame is hashed again and
+compared with its declared CID. Frames of the requested frame range that are
+not part of the result are reported as missing.
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	// external
+	gocid "github.com/ipfs/go-cid"
+	"github.com/spf13/cobra"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/ipfs"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// Frame of a render result
+type RenderResultFrame struct {
+	Frame      int    // Frame number
+	File       string // Path of the frame file relative to the result directory
+	CID        string // Content identifier (CID) of the frame file
+	Preview    string `json:",omitempty"` // Path of the low-resolution preview relative to the result directory (if any)
+	PreviewCID string `json:",omitempty"` // Content identifier (CID) of the preview file (if any)
+
+	// Consensus hash of the composited regions of the frame (see regions.go)
+	RegionsHash string `json:",omitempty"` // empty, if the frame was not split into regions
+}
+
+// Result document of a render result
+type RenderResultDocument struct {
+	RenderRequestCID  string              // CID of the rendered render request document
+	BlenderFileCID    string              // CID of the rendered Blender file
+	OperatorAccountID string              // Account ID of the operator who rendered the result
+	CreatedTimestamp  time.Time           // The datetime the result document was created
+	Frames            []RenderResultFrame // The rendered frames
+	MissingFrames     []int               `json:",omitempty"` // Frames of the frame range, which were not rendered
+}
+
+// Verification of a single frame of a render result
+type RenderResultFrameCheck struct {
+	Frame    int    // Frame number
+	File     string // Path of the frame file relative to the result directory
+	Declared string // CID declared in the result document
+	Actual   string // CID of the downloaded frame file
+	Passed   bool   // True, if the frame file matches the declared CID
+	Reason   string // Reason, if the check failed
+}
+
+// Verification of a render result
+type RenderResultVerification struct {
+	RenderRequestCID   string                   // CID of the render request document
+	ResultCID          string                   // CID of the render result directory
+	RequestMatches     bool                     // True, if the result references the render request
+	BlenderFileMatches bool                     // True, if the result references the Blender file of the render request
+	Frames             []RenderResultFrameCheck // Checks of the declared frames (by frame number)
+	MissingFrames      []int                    // Requested frames, which are not part of the result
+	Passed             bool                     // True, if all checks passed
+}
+
+// RESULT VERIFICATION
+// #############################################################################
+// Download a render result and verify it against its render request
+func (nm *PackageManager) VerifyRenderResult(requestCID string, resultCID string) (*RenderResultVerification, error) {
+	var err error
+
+	// check the CIDs
+	if _, err = ParseCID(requestCID); err != nil {
+		return nil, newRenderError(ErrInvalidArgument, "Invalid render request CID: %w", err)
+	}
+	if _, err = ParseCID(resultCID); err != nil {
+		return nil, newRenderError(ErrInvalidArgument, "Invalid render result CID: %w", err)
+	}
+
+	// log event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Verifying render result '%v' of render request '%v' ...", resultCID, requestCID))
+
+	// download into a temporary directory
+	directory, err := os.MkdirTemp(RENDERHIVE_APP_DIRECTORY_TEMP, "renderhive_result_*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(directory)
+
+	// get the render request (known to this node or from IPFS)
+	request, err := nm._verificationRequest(requestCID, directory)
+	if err != nil {
+		return nil, err
+	}
+
+	// download the render result directory
+	resultPath := filepath.Join(directory, "result")
+	_, err = ipfs.Manager.GetObject(resultCID, resultPath)
+	if err != nil {
+		return nil, newRenderError(ErrDocumentNotFound, "Render result '%v' could not be downloaded: %w", resultCID, err)
+	}
+
+	// read the result document
+	document, err := _readResultDocument(resultPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// verify the result
+	verification, err := VerifyRenderResultFiles(request, document, resultPath, ipfs.Manager.GetHashFromPath)
+	if err != nil {
+		return nil, err
+	}
+	verification.RenderRequestCID = requestCID
+	verification.ResultCID = resultCID
+
+	// log event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Render result '%v' passed the verification: %v", resultCID, verification.Passed))
+
+	return verification, nil
+
+}
+
+// Verify a downloaded render result directory against its render request
+// NOTE: The hash function calculates the CID of a frame file.
+func VerifyRenderResultFiles(request *RenderRequest, document *RenderResultDocument, directory string, hash func(string) (string, error)) (*RenderResultVerification, error) {
+
+	if request == nil || document == nil {
+		return nil, newRenderError(ErrInvalidArgument, "Render request and result document are required.")
+	}
+
+	verification := &RenderResultVerification{
+		RenderRequestCID:   request.DocumentCID,
+		RequestMatches:     _sameCID(document.RenderRequestCID, request.DocumentCID),
+		BlenderFileMatches: _sameCID(document.BlenderFileCID, request.BlenderFile.CID),
+		Frames:             []RenderResultFrameCheck{},
+		MissingFrames:      []int{},
+	}
+	passed := verification.RequestMatches && verification.BlenderFileMatches
+
+	// check each declared frame
+	declared := map[int]bool{}
+	settings := request.BlenderFile.Settings
+	for _, frame := range document.Frames {
+		check := RenderResultFrameCheck{
+			Frame:    frame.Frame,
+			File:     frame.File,
+			Declared: frame.CID,
+		}
+
+		if declared[frame.Frame] {
+			check.Reason = "Frame is declared more than once."
+		} else if settings.FrameEnd >= settings.FrameStart && !_inFrameRange(settings, frame.Frame) {
+			check.Reason = "Frame is not part of the requested frame range."
+		} else if !filepath.IsLocal(frame.File) {
+			check.Reason = "Frame file is outside of the result directory."
+		} else if actual, err := hash(filepath.Join(directory, frame.File)); err != nil {
+			check.Reason = fmt.Sprintf("Frame file could not be hashed: %v", err)
+		} else {
+			check.Actual = actual
+			check.Passed = _sameCID(actual, frame.CID)
+			if !check.Passed {
+				check.Reason = "Frame file does not match the declared CID."
+			}
+		}
+		declared[frame.Frame] = true
+
+		passed = passed && check.Passed
+		verification.Frames = append(verification.Frames, check)
+	}
+	sort.SliceStable(verification.Frames, func(i, j int) bool {
+		return verification.Frames[i].Frame < verification.Frames[j].Frame
+	})
+
+	// find the requested frames, which are not part of the result
+	if settings.FrameEnd >= settings.FrameStart {
+		step := settings.FrameStep
+		if step < 1 {
+			step = 1
+		}
+		for frame := settings.FrameStart; frame <= settings.FrameEnd; frame += step {
+			if !declared[frame] {
+				verification.MissingFrames = append(verification.MissingFrames, frame)
+			}
+		}
+	}
+	verification.Passed = passed && len(verification.MissingFrames) == 0 && len(verification.Frames) > 0
+
+	return verification, nil
+
+}
+
+// helper function to get the render request of a render result verification
+func (nm *PackageManager) _verificationRequest(requestCID string, directory string) (*RenderRequest, error) {
+
+	// use the render request known to this node
+	if request, ok := nm.Renderer.Requests[requestCID]; ok {
+		return request, nil
+	}
+
+	// download the render request document
+	path := filepath.Join(directory, "request.json")
+	_, err := ipfs.Manager.GetObject(requestCID, path)
+	if err != nil {
+		return nil, newRenderError(ErrRequestNotFound, "Render request '%v' could not be downloaded: %w", requestCID, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeRenderRequest(requestCID, path, data)
+
+}
+
+// helper function to read the result document of a downloaded render result
+func _readResultDocument(directory string) (*RenderResultDocument, error) {
+
+	data, err := os.ReadFile(filepath.Join(directory, RENDERHIVE_RESULT_DOCUMENT_FILENAME))
+	if err != nil {
+		return nil, newRenderError(ErrDocumentNotFound, "Render result has no result document: %w", err)
+	}
+	document := &RenderResultDocument{}
+	err = json.Unmarshal(data, document)
+	if err != nil {
+		return nil, newRenderError(ErrInvalidArgument, "Result document could not be decoded: %w", err)
+	}
+
+	return document, nil
+
+}
+
+// helper function to check if a frame is part of the frame range
+func _inFrameRange(settings RenderSettings, frame int) bool {
+
+	step := settings.FrameStep
+	if step < 1 {
+		step = 1
+	}
+
+	return frame >= settings.FrameStart && frame <= settings.FrameEnd && (frame-settings.FrameStart)%step == 0
+
+}
+
+// helper function to compare two CIDs independent of their version and encoding
+func _sameCID(a string, b string) bool {
+
+	if a == "" || b == "" {
+		return false
+	}
+
+	cidA, errA := gocid.Parse(a)
+	cidB, errB := gocid.Parse(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+
+	return cidA.Hash().String() == cidB.Hash().String()
+
+}
+
+// COMMAND LINE INTERFACE - RESULT VERIFICATION
+// #############################################################################
+// Create the CLI command to verify a render result of a render request
+func (nm *PackageManager) CreateCommandRequest_Verify() *cobra.Command {
+
+	// flags for the 'request verify' command
+	var requestCID string
+	var resultCID string
+
+	// create a 'request verify' command for the node
+	command := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify a render result of a render request",
+		Long:  "This command is for verifying a render result before accepting it. The render result is downloaded from IPFS, its result document is compared with the render request, and each frame is hashed again and compared with its declared CID.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// check the required parameters
+			if requestCID == "" || resultCID == "" {
+
+				logger.Manager.Println("")
+				if requestCID == "" {
+					return fmt.Errorf("Failed to verify the render result: Missing a required parameter: Render request CID (--request).")
+				}
+				return fmt.Errorf("Failed to verify the render result: Missing a required parameter: Render result CID (--result).")
+
+			}
+
+			// verify the render result
+			verification, err := nm.VerifyRenderResult(requestCID, resultCID)
+			if err != nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not verify the render result: %w", err)
+
+			}
+
+			logger.Manager.Println("")
+			logger.Manager.Printf("Verification of render result '%v':\n", resultCID)
+			logger.Manager.Resultf(" [#] References render request: %v \n", _passFail(verification.RequestMatches))
+			logger.Manager.Resultf(" [#] References Blender file: %v \n", _passFail(verification.BlenderFileMatches))
+			for _, frame := range verification.Frames {
+				if frame.Passed {
+					logger.Manager.Resultf(" [#] Frame %v (%v): PASS \n", frame.Frame, frame.File)
+				} else {
+					logger.Manager.Resultf(" [#] Frame %v (%v): FAIL - %v \n", frame.Frame, frame.File, frame.Reason)
+				}
+			}
+			if len(verification.MissingFrames) > 0 {
+				logger.Manager.Resultf(" [#] Missing frames: %v \n", verification.MissingFrames)
+			}
+			logger.Manager.Resultf(" [#] Result: %v \n", _passFail(verification.Passed))
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	// add command flag parameters
+	command.Flags().StringVarP(&requestCID, "request", "r", "", "The CID of the render request document")
+	command.Flags().StringVarP(&resultCID, "result", "c", "", "The CID of the render result directory")
+
+	return command
+
+}
+
+// helper function to get the text of a check result
+func _passFail(passed bool) string {
+
+	if passed {
+		return "PASS"
+	}
+
+	return "FAIL"
+
+}
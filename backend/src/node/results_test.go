@@ -0,0 +1,157 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"os"
+	"path/filepath"
+	"testing"
+
+	// external
+	gocid "github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// CIDs of the render request and Blender file of the test results
+const (
+	testResultRequestCID = "bafybeidzlot4bs7cjmpz2fj54xzyzt3dixdlcttenfyhf4amlr2npxbxem"
+	testResultBlendCID   = "bafkreieehljcbl5lnncnyryvfwd5hm7xsukz45kdhynlgkj7yibbs2v37y"
+)
+
+// helper function to calculate the CID of a file without an IPFS node
+func _testHashFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	hash, err := mh.Sum(data, mh.SHA2_256, -1)
+	if err != nil {
+		return "", err
+	}
+
+	return gocid.NewCidV1(gocid.Raw, hash).String(), nil
+}
+
+// helper function to create a render result of the frames 1-3 and its render request
+func _testRenderResult(t *testing.T) (*RenderRequest, *RenderResultDocument, string) {
+	t.Helper()
+
+	request := &RenderRequest{DocumentCID: testResultRequestCID}
+	request.BlenderFile.CID = testResultBlendCID
+	request.BlenderFile.Settings.FrameStart, request.BlenderFile.Settings.FrameEnd, request.BlenderFile.Settings.FrameStep = 1, 3, 1
+
+	directory := t.TempDir()
+	document := &RenderResultDocument{RenderRequestCID: testResultRequestCID, BlenderFileCID: testResultBlendCID}
+	for frame := 1; frame <= 3; frame++ {
+		file := filepath.Join("frames", "frame_000"+string(rune('0'+frame))+".png")
+		if err := os.MkdirAll(filepath.Join(directory, "frames"), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(directory, file), []byte(file), 0600); err != nil {
+			t.Fatal(err)
+		}
+		cid, err := _testHashFile(filepath.Join(directory, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		document.Frames = append(document.Frames, RenderResultFrame{Frame: frame, File: file, CID: cid})
+	}
+
+	return request, document, directory
+}
+
+func TestVerifyRenderResultAcceptsAMatchingResult(t *testing.T) {
+	request, document, directory := _testRenderResult(t)
+
+	verification, err := VerifyRenderResultFiles(request, document, directory, _testHashFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verification.Passed || !verification.RequestMatches || !verification.BlenderFileMatches || len(verification.MissingFrames) != 0 {
+		t.Fatalf("unexpected verification: %+v", verification)
+	}
+	for _, frame := range verification.Frames {
+		if !frame.Passed {
+			t.Errorf("frame %v failed: %v", frame.Frame, frame.Reason)
+		}
+	}
+
+	// the CIDs are compared independent of their version
+	document.RenderRequestCID = "QmWWP6qFQ9y43KvWxY3sqtn2VR4s5RwGso7cUT2ZQHXo5t"
+	request.DocumentCID = "bafybeidzlot4bs7cjmpz2fj54xzyzt3dixdlcttenfyhf4amlr2npxbxem"
+	if verification, _ = VerifyRenderResultFiles(request, document, directory, _testHashFile); !verification.RequestMatches {
+		t.Error("expected the CIDv0 of the render request to match its CIDv1")
+	}
+}
+
+func TestVerifyRenderResultRejectsATamperedResult(t *testing.T) {
+	tests := map[string]func(request *RenderRequest, document *RenderResultDocument, directory string){
+		"tampered frame": func(request *RenderRequest, document *RenderResultDocument, directory string) {
+			os.WriteFile(filepath.Join(directory, document.Frames[1].File), []byte("tampered"), 0600)
+		},
+		"other request": func(request *RenderRequest, document *RenderResultDocument, directory string) {
+			document.RenderRequestCID = testResultBlendCID
+		},
+		"other Blender file": func(request *RenderRequest, document *RenderResultDocument, directory string) {
+			document.BlenderFileCID = testResultRequestCID
+		},
+		"missing frame": func(request *RenderRequest, document *RenderResultDocument, directory string) {
+			document.Frames = document.Frames[:2]
+		},
+		"duplicate frame": func(request *RenderRequest, document *RenderResultDocument, directory string) {
+			document.Frames = append(document.Frames, document.Frames[0])
+		},
+		"frame out of range": func(request *RenderRequest, document *RenderResultDocument, directory string) {
+			document.Frames[2].Frame = 4
+		},
+		"file outside of the result": func(request *RenderRequest, document *RenderResultDocument, directory string) {
+			document.Frames[0].File = "../frame_0001.png"
+		},
+	}
+	for name, tamper := range tests {
+		request, document, directory := _testRenderResult(t)
+		tamper(request, document, directory)
+
+		verification, err := VerifyRenderResultFiles(request, document, directory, _testHashFile)
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		if verification.Passed {
+			t.Errorf("%v: expected the verification to fail: %+v", name, verification)
+		}
+	}
+
+	// only the tampered frame fails
+	request, document, directory := _testRenderResult(t)
+	os.WriteFile(filepath.Join(directory, document.Frames[1].File), []byte("tampered"), 0600)
+	verification, _ := VerifyRenderResultFiles(request, document, directory, _testHashFile)
+	if !verification.Frames[0].Passed || verification.Frames[1].Passed || !verification.Frames[2].Passed {
+		t.Errorf("unexpected frame checks: %+v", verification.Frames)
+	}
+
+	// a result without frames does not pass
+	document.Frames = nil
+	if verification, _ = VerifyRenderResultFiles(request, document, directory, _testHashFile); verification.Passed {
+		t.Error("expected a result without frames to fail")
+	}
+}
@@ -0,0 +1,316 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the sweep of closed render documents. Render requests that
+were cancelled and render offers that were paused longer than the retention
+window ago are moved from the local document directories into the archive
+directories and are removed from the active render data of this node. Render
+jobs that finished (i.e., completed, released, or failed) longer than the
+retention window ago are removed from the job queue of this node.
+
+The sweep runs on start and periodically afterwards. The retention window is
+set in the optional 'repository.json' file of the configuration directory
+('retention', default: 30 days). The IPFS pins of the archived documents are
+kept, unless 'unpin_archived' is enabled.
+
+The sweep takes the lock of the render data, since the JSON-RPC methods read
+and change the same render offers, render requests, and render jobs.
+
+*/
+
+import (
+
+	// standard
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	// external
+	"github.com/spf13/cobra"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/ipfs"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// RETENTION
+// #############################################################################
+// Get the retention window of closed render documents
+func (config *RenderRepositoryConfig) RetentionWindow() (time.Duration, error) {
+
+	if config.Retention == "" {
+		return RENDERHIVE_CONFIG_REPOSITORY_RETENTION, nil
+	}
+
+	retention, err := time.ParseDuration(config.Retention)
+	if err != nil || retention < 0 {
+		return 0, newRenderError(ErrInvalidArgument, "Invalid retention window '%v'.", config.Retention)
+	}
+
+	return retention, nil
+
+}
+
+// Sweep the closed render documents periodically
+func (nm *PackageManager) CheckRenderDocumentRetention() error {
+
+	// sweep at most once per interval
+	if time.Since(nm.lastSweep) < RENDERHIVE_CONFIG_REPOSITORY_SWEEP_INTERVAL {
+		return nil
+	}
+
+	retention, err := nm.RepositoryConfig.RetentionWindow()
+	if err != nil {
+		return err
+	}
+
+	_, _, _, err = nm.SweepRenderDocuments(retention)
+
+	return err
+
+}
+
+// Archive the render offers and render requests closed before the retention window
+// NOTE: Returns the number of archived render offers and render requests, and
+// the number of removed render jobs.
+func (nm *PackageManager) SweepRenderDocuments(retention time.Duration) (int, int, int, error) {
+	var lastErr error
+
+	nm.Renderer.Mutex.Lock()
+	defer nm.Renderer.Mutex.Unlock()
+
+	nm.lastSweep = time.Now()
+	cutoff := time.Now().Add(-retention)
+
+	// archive the paused render offers
+	archivedOffers := 0
+	for _, offer := range _uniqueOffers(nm.Renderer.Offers) {
+		if !offer.Paused || offer.PausedTimestamp.IsZero() || !offer.PausedTimestamp.Before(cutoff) {
+			continue
+		}
+
+		// move the document into the archive
+		path, err := nm._archiveDocument(offer.DocumentPath, RENDERHIVE_APP_DIRECTORY_ARCHIVED_OFFERS)
+		if err == nil && nm.Repository != nil {
+			err = nm.Repository.Archive(offer.DocumentCID, path)
+		}
+		if err != nil {
+			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not archive render offer '%v': %v", offer.DocumentCID, err))
+			lastErr = err
+			continue
+		}
+		offer.DocumentPath = path
+
+		// remove the offer from the active render data
+		for key, value := range nm.Renderer.Offers {
+			if value == offer {
+				delete(nm.Renderer.Offers, key)
+			}
+		}
+		nm.UnsetActiveRenderOffer(offer.DocumentCID)
+		if nm.RepositoryConfig.UnpinArchived {
+			nm._unpinArchived(offer.DocumentCID)
+		}
+		archivedOffers++
+	}
+
+	// archive the cancelled render requests
+	archivedRequests := 0
+	for _, request := range _uniqueRequests(nm.Renderer.Requests) {
+		if !request.Cancelled || request.ClosedTimestamp.IsZero() || !request.ClosedTimestamp.Before(cutoff) {
+			continue
+		}
+
+		// move the document into the archive
+		path, err := nm._archiveDocument(request.DocumentPath, RENDERHIVE_APP_DIRECTORY_ARCHIVED_REQUESTS)
+		if err == nil && nm.Repository != nil {
+			err = nm.Repository.Archive(request.DocumentCID, path)
+		}
+		if err != nil {
+			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not archive render request '%v': %v", request.DocumentCID, err))
+			lastErr = err
+			continue
+		}
+		request.DocumentPath = path
+
+		// remove the request from the active render data
+		for key, value := range nm.Renderer.Requests {
+			if value == request {
+				delete(nm.Renderer.Requests, key)
+			}
+		}
+		nm._releaseRequestID(request)
+		if nm.RepositoryConfig.UnpinArchived {
+			nm._unpinArchived(request.DocumentCID, request.DirectoryCID, request.BlenderFile.CID)
+		}
+		archivedRequests++
+	}
+
+	// remove the finished render jobs from the job queue
+	queue := nm.Renderer.NodeQueue[:0]
+	for _, job := range nm.Renderer.NodeQueue {
+		finished := job._finishedTimestamp()
+		if finished.IsZero() || !finished.Before(cutoff) {
+			queue = append(queue, job)
+		}
+	}
+	removedJobs := len(nm.Renderer.NodeQueue) - len(queue)
+	for i := len(queue); i < len(nm.Renderer.NodeQueue); i++ {
+		nm.Renderer.NodeQueue[i] = nil
+	}
+	nm.Renderer.NodeQueue = queue
+
+	// log event
+	if archivedOffers > 0 || archivedRequests > 0 || removedJobs > 0 {
+		logger.Manager.Package["node"].Info().Msg(fmt.Sprintf("Archived %v render offers and %v render requests, and removed %v render jobs closed before %v.", archivedOffers, archivedRequests, removedJobs, cutoff.Format(time.RFC3339)))
+	}
+
+	return archivedOffers, archivedRequests, removedJobs, lastErr
+
+}
+
+// helper function to get the datetime a render job finished
+// NOTE: Returns the zero time, if the job did not finish (yet).
+func (job *RenderJob) _finishedTimestamp() time.Time {
+	var finished time.Time
+
+	if job.State != RENDER_JOB_STATE_COMPLETED && job.State != RENDER_JOB_STATE_RELEASED && job.State != RENDER_JOB_STATE_FAILED {
+		return finished
+	}
+
+	// the end of the last render attempt (or the claim, if the job was never rendered)
+	for _, attempt := range job.RenderAttempts {
+		if attempt.Finished.After(finished) {
+			finished = attempt.Finished
+		}
+	}
+	if finished.IsZero() {
+		finished = job.ClaimedTimestamp
+	}
+
+	return finished
+
+}
+
+// Move a render document into an archive directory
+// NOTE: Returns the new path of the document.
+func (nm *PackageManager) _archiveDocument(path string, archiveDirectory string) (string, error) {
+	var err error
+
+	// the document is only known in the repository
+	if path == "" {
+		return "", nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", nil
+	}
+
+	// create the archive directory, if it does not exist
+	directory := filepath.Join(GetAppDataPath(), archiveDirectory)
+	err = os.MkdirAll(directory, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	// move the document
+	archivePath := filepath.Join(directory, filepath.Base(path))
+	err = os.Rename(path, archivePath)
+	if err != nil {
+		return "", err
+	}
+
+	return archivePath, nil
+
+}
+
+// Unpin the IPFS artifacts of an archived render document
+func (nm *PackageManager) _unpinArchived(cids ...string) {
+
+	for _, cid := range cids {
+		if cid == "" {
+			continue
+		}
+		_, err := ipfs.Manager.UnPinObject(cid)
+		if err != nil {
+			logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf(" [#] Could not unpin archived object '%v': %v", cid, err))
+		}
+	}
+
+}
+
+// RETENTION COMMAND LINE INTERFACE
+// #############################################################################
+// Create the CLI command to archive the closed render documents
+func (nm *PackageManager) CreateCommandSweep() *cobra.Command {
+
+	// flags for the 'sweep' command
+	var keep time.Duration
+
+	// create a 'sweep' command for the node
+	command := &cobra.Command{
+		Use:   "sweep",
+		Short: "Archive the closed render documents of the node",
+		Long:  "This command moves the cancelled render requests and paused render offers, which were closed before the retention window, into the archive and removes the render jobs finished before the retention window.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+
+			// use the configured retention window by default
+			if !cmd.Flags().Changed("keep") {
+				keep, err = nm.RepositoryConfig.RetentionWindow()
+			}
+			if err == nil {
+				var offers, requests, jobs int
+				offers, requests, jobs, err = nm.SweepRenderDocuments(keep)
+				if err == nil {
+
+					logger.Manager.Println("")
+					logger.Manager.Println("Archived the closed render documents:")
+					logger.Manager.Resultf(" [#] Render offers: %v\n", offers)
+					logger.Manager.Resultf(" [#] Render requests: %v\n", requests)
+					logger.Manager.Resultf(" [#] Render jobs: %v\n", jobs)
+					logger.Manager.Println("")
+
+				}
+			}
+			if err != nil {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Could not archive the closed render documents: %w", err)
+
+			}
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().DurationVarP(&keep, "keep", "k", RENDERHIVE_CONFIG_REPOSITORY_RETENTION, "Keep documents closed within this duration active (e.g., 720h)")
+
+	return command
+
+}
@@ -0,0 +1,186 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// helper function to create a node with closed render documents
+func _testSweepManager(t *testing.T, closed time.Time) *PackageManager {
+	t.Helper()
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// create the local documents
+	directory := filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_LOCAL_OFFERS)
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		t.Fatal(err)
+	}
+	offerPath := filepath.Join(directory, "offer-paused.json")
+	if err := os.WriteFile(offerPath, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	offer := &RenderOffer{DocumentCID: "offer-paused", DocumentPath: offerPath, Paused: true, PausedTimestamp: closed}
+	request := &RenderRequest{DocumentCID: "request-cancelled", Cancelled: true, ClosedTimestamp: closed}
+
+	nm := &PackageManager{}
+	nm.Repository = &JsonRenderRepository{Path: filepath.Join(t.TempDir(), "documents.json")}
+	nm.Renderer.Offers = map[string]*RenderOffer{offer.DocumentCID: offer}
+	nm.Renderer.ActiveOffers = []*RenderOffer{offer}
+	nm.Renderer.Requests = map[string]*RenderRequest{request.DocumentCID: request}
+	nm.Renderer.NodeQueue = []*RenderJob{
+		{State: RENDER_JOB_STATE_COMPLETED, RenderAttempts: []RenderJobAttempt{{Finished: closed}}},
+		{State: RENDER_JOB_STATE_RELEASED, ClaimedTimestamp: closed},
+		{State: RENDER_JOB_STATE_RENDERING, ClaimedTimestamp: closed},
+		{State: RENDER_JOB_STATE_COMPLETED, RenderAttempts: []RenderJobAttempt{{Finished: time.Now()}}},
+	}
+
+	return nm
+}
+
+func TestSweepRenderDocuments(t *testing.T) {
+	nm := _testSweepManager(t, time.Now().Add(-48*time.Hour))
+
+	offers, requests, jobs, err := nm.SweepRenderDocuments(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offers != 1 || requests != 1 || jobs != 2 {
+		t.Fatalf("got %v offers, %v requests, %v jobs, want 1, 1, 2", offers, requests, jobs)
+	}
+	if len(nm.Renderer.Offers) != 0 || len(nm.Renderer.ActiveOffers) != 0 || len(nm.Renderer.Requests) != 0 {
+		t.Error("the archived documents must be removed from the render data")
+	}
+	if len(nm.Renderer.NodeQueue) != 2 || nm.Renderer.NodeQueue[0].State != RENDER_JOB_STATE_RENDERING {
+		t.Errorf("only the running and the recently finished job must be kept, got %v jobs", len(nm.Renderer.NodeQueue))
+	}
+
+	// the offer document is moved into the archive
+	archived := filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_ARCHIVED_OFFERS, "offer-paused.json")
+	if _, err := os.Stat(archived); err != nil {
+		t.Errorf("the offer document was not archived: %v", err)
+	}
+}
+
+func TestSweepRenderDocumentsKeepsRecentDocuments(t *testing.T) {
+	nm := _testSweepManager(t, time.Now().Add(-time.Hour))
+
+	offers, requests, jobs, err := nm.SweepRenderDocuments(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offers != 0 || requests != 0 || jobs != 0 {
+		t.Fatalf("got %v offers, %v requests, %v jobs, want nothing archived", offers, requests, jobs)
+	}
+}
+
+func TestSweepRenderDocumentsReturnsError(t *testing.T) {
+	nm := _testSweepManager(t, time.Now().Add(-48*time.Hour))
+
+	// the archive directory cannot be created
+	blocker := filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_ARCHIVED_OFFERS)
+	if err := os.MkdirAll(filepath.Dir(filepath.Clean(blocker)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Clean(blocker), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	offers, _, _, err := nm.SweepRenderDocuments(24 * time.Hour)
+	if err == nil {
+		t.Fatal("the sweep must report the archive error")
+	}
+	if offers != 0 || len(nm.Renderer.Offers) != 1 {
+		t.Error("the offer must stay active, if it could not be archived")
+	}
+}
+
+func TestSweepRenderDocumentsWaitsForTheRenderLock(t *testing.T) {
+	nm := _testSweepManager(t, time.Now().Add(-48*time.Hour))
+
+	nm.Renderer.Mutex.Lock()
+	done := make(chan struct{})
+	go func() {
+		nm.SweepRenderDocuments(24 * time.Hour)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("the sweep must wait for the lock of the render data")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	nm.Renderer.Mutex.Unlock()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the sweep did not finish after the lock was released")
+	}
+}
+
+func TestJsonRenderRepositoryPersistsState(t *testing.T) {
+	repository := &JsonRenderRepository{Path: filepath.Join(t.TempDir(), "documents.json")}
+	closed := time.Now().Add(-time.Hour).Round(time.Second)
+
+	request := &RenderRequest{DocumentCID: "request-cancelled", Cancelled: true, SubmittedTimestamp: closed, ClosedTimestamp: closed}
+	request.Receipt = &hederasdk.TransactionReceipt{Status: hederasdk.StatusSuccess}
+	if err := repository.SaveRequest(request); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new repository instance (i.e., after a restart) reads the state
+	states, err := (&JsonRenderRepository{Path: repository.Path})._readStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, ok := states["request-cancelled"]
+	if !ok || state.State != REPOSITORY_STATE_CANCELLED || !state.ClosedTimestamp.Equal(closed) {
+		t.Fatalf("got %+v, want the cancelled state closed at %v", state, closed)
+	}
+
+	// archived documents are removed from the state file
+	if err := repository.Archive("request-cancelled", ""); err != nil {
+		t.Fatal(err)
+	}
+	states, err = repository._readStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := states["request-cancelled"]; ok {
+		t.Error("the archived document must be removed from the state file")
+	}
+}
@@ -35,19 +35,23 @@ import (
 
 	// standard
 
+	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	// "os"
-	// "time"
 
 	// external
 
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
 	"github.com/spf13/cobra"
 
 	// internal
@@ -64,10 +68,12 @@ type NodeData struct {
 	// Configuration
 	ClientNode    bool // True, if the node acts as a client node
 	RenderNode    bool // True, if the node acts as a render node
+	RequesterOnly bool // True, if the node only requests renderings (without rendering for the render hive)
 	HederaAccount struct {
 		AccountID string // ID of the node's Hedera account
 		PublicKey string // ID of the node's Hedera account
 	}
+	TopicID string // ID of the node's HCS topic (empty, if not created yet)
 }
 
 // Define the JSON data structure for the node data
@@ -76,25 +82,39 @@ type NodeDataJSON struct {
 	Name          string `json:"Name"`
 	ClientNode    bool   `json:"ClientNode"`
 	RenderNode    bool   `json:"RenderNode"`
+	RequesterOnly bool   `json:"RequesterOnly,omitempty"`
 	HederaAccount struct {
 		AccountID string `json:"AccountID"`
 		PublicKey string `json:"PublicKey"`
 	} `json:"HederaAccount"`
+	TopicID string `json:"TopicID,omitempty"`
 }
 
 // Render data of the node running this service app instance
 type RenderData struct {
 
 	// Render requests and offers
-	ActiveOffer *RenderOffer              // Active render offer of this node
-	Offers      map[string]*RenderOffer   // Render offers of this node
-	Requests    map[string]*RenderRequest // Render jobs requested by this node
+	ActiveOffers []*RenderOffer            // Active render offers of this node (e.g., for different devices or price tiers)
+	activeMutex  sync.RWMutex              // guards the active render offers (use GetActiveRenderOffers to read them)
+	Offers       map[string]*RenderOffer   // Render offers of this node
+	Requests     map[string]*RenderRequest // Render jobs requested by this node
+	RequestIDs   *RenderRequestIDs         // Persistent IDs of the render requests of this node
+
+	// Blender versions
+	Blender *BlenderRegistry // Blender versions installed on this node
 
 	// Job queues
 	NodeQueue []*RenderJob // Queue of render jobs to be performed on this node
 
+	// Disputes
+	Disputes map[string]*Dispute // Disputes raised by this node (by render request CID)
+
+	// Lock of the render data (see locking.go)
+	Mutex RenderMutex
+
 	// Node status
-	Busy bool // True, if the node is already rendering
+	Busy          bool          // True, if the node is already rendering
+	FrameDuration time.Duration // Average render time per frame observed on this node (zero, if unknown)
 
 }
 
@@ -106,9 +126,55 @@ type PackageManager struct {
 	Node     NodeData
 	Renderer RenderData
 
+	// Persistence of the render data
+	Repository        RenderRepository
+	RepositoryConfig  RenderRepositoryConfig
+	lastSweep         time.Time // last sweep of the closed render documents
+	lastDeadlineCheck time.Time // last check of the render deadlines
+
+	// Run without rendering (e.g., with the '--requester-only' flag)
+	// NOTE: This is set before the node manager is initialized.
+	RequesterOnly bool
+
+	// Announcements of the active render offers
+	KeepOffersOnShutdown  bool            // do not withdraw the active offers on shutdown (e.g., for quick restarts)
+	lastAnnouncement      time.Time       // last announcement of the active render offers
+	lastAnnouncementCheck time.Time       // last check of the announcement interval
+	expiredOffers         map[string]bool // active offers, whose expiry was published (by CID)
+	announcing            bool            // true, while the offers are announced in the background
+	announceMutex         sync.Mutex
+	announceGroup         sync.WaitGroup
+	submitOfferMessage    func(message string, memo string) error // submits a render offer message (nil = on the job queue topic)
+
+	// Render offer announcements of the operators in the render hive (by account ID and CID)
+	networkOffers      map[string]map[string]*OfferAnnouncement
+	networkOffersMutex sync.Mutex
+	fetchNetworkObject func(cid string, path string) error // downloads a render offer document (nil = from the IPFS node)
+
+	// Render request deployments (see deploy.go)
+	addRequestDirectory func(request *RenderRequest) (string, error) // adds the render request directory (nil = to the IPFS node)
+
+	// Render worker, which renders the render jobs of the render hive queue (see worker.go)
+	workerStages renderWorkerStages
+
+	// Transactions of the render documents, which were returned for signing
+	pendingTransactions  map[string]*PendingTransaction // pending transactions by transaction ID
+	pendingMutex         sync.Mutex
+	lastTransactionCheck time.Time // last status check of the pending transactions
+
+	// Events pushed to the frontend
+	Events               EventBus
+	lastBalanceCheck     time.Time      // last balance check of the operator account
+	balanceKnown         bool           // true, if the balance of the operator account was queried
+	operatorBalance      hederasdk.Hbar // last queried balance of the operator account
+	balanceChecking      bool           // true, while the balance is queried in the background
+	balanceMutex         sync.Mutex
+	queryOperatorBalance func() (hederasdk.Hbar, error) // queries the operator balance (nil = from the Hedera network)
+
 	// Network data
 	HiveCycle    HiveCycle
-	NetworkQueue []*RenderJob // Queue of render jobs on the render hive
+	NetworkQueue []*RenderJob      // Queue of render jobs on the render hive
+	Reputation   ReputationTracker // Reputation of the render nodes on the render hive
 
 	// Hedera consensus service topics
 	// Hive cycle topics
@@ -117,14 +183,21 @@ type PackageManager struct {
 	HiveCycleValidationTopic      *hedera.HederaTopic
 
 	// Render job topics
-	JobQueueTopic *hedera.HederaTopic
-	JobTopics     []*hedera.HederaTopic
+	JobQueueTopic     *hedera.HederaTopic
+	JobQueueSettings  JobQueueSettings
+	JobTopics         []*hedera.HederaTopic
+	jobQueue          jobQueueState
+	jobQueueProcessor *TopicMessageProcessor
 
 	// Command line interface
 	Command      *cobra.Command
 	CommandFlags struct {
 		FlagPlaceholder bool
 	}
+
+	// Long-running operations (e.g., benchmarks) are canceled on shutdown
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // NODE MANAGER
@@ -139,20 +212,79 @@ func (nm *PackageManager) Init() error {
 	// log information
 	logger.Manager.Package["node"].Info().Msg("Initializing the node manager ...")
 
+	// create the context of the long-running operations
+	nm.ctx, nm.cancel = context.WithCancel(context.Background())
+
 	// Read the node configuration
 	err = nm.LoadConfiguration()
 	if err != nil {
 		return err
 	}
 
+	// Read the settings of the render job queue topic
+	err = nm.LoadJobQueueSettings()
+	if err != nil {
+		return err
+	}
+	err = nm._loadJobQueueState()
+	if err != nil {
+		logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not load the last processed message of the job queue: %v", err))
+	}
+
+	// Check the key type of the operator account for the contract operations
+	err = nm.CheckOperatorSigner()
+	if err != nil {
+		logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Operator account check: %v", err))
+	}
+
+	// Initialize the render repository
+	err = nm.InitRepository()
+	if err != nil {
+		return err
+	}
+
+	// Load the reputation of the render nodes
+	nm.Reputation.VerifySettlement = _settlementConfirmed
+	err = nm.Reputation.Load()
+	if err != nil {
+		logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not load the reputation tracker: %v", err))
+	}
+
+	// Load the Blender versions supported by the render hive
+	// NOTE: The manifest is loaded in the background, so that a slow IPFS
+	// download does not delay the start. Until then, only the built-in
+	// versions are supported.
+	go func() {
+		if err := nm.LoadBlenderArchiveManifest(); err != nil {
+			logger.Manager.Package["node"].Warn().Msg(err.Error())
+		}
+	}()
+
+	// Initialize the Blender versions installed on this node
+	// NOTE: A requester-only node does not render, so it skips the scan.
+	if nm.IsRequesterOnly() {
+		logger.Manager.Package["node"].Info().Msg("Running in requester-only mode: The Blender versions and render offers are not initialized and no render jobs are claimed.")
+	} else {
+		err = nm.InitBlenderVersions()
+		if err != nil {
+			logger.Manager.Package["node"].Error().Msg(err.Error())
+		}
+	}
+
 	// Initialize the render offer
 	nm.InitRenderOffers()
 
 	// Initialize the render requests
 	nm.InitRenderRequests()
 
+	// Archive the closed render documents
+	err = nm.CheckRenderDocumentRetention()
+	if err != nil {
+		logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not sweep the closed render documents: %v", err))
+	}
+
 	// // Add a Blender version to the node's render offer
-	// nm.Renderer.ActiveOffer.AddBlenderVersion("3.2.1", &[]string{"CYCLES", "EEVEE"}, &[]string{"CPU"}, 4)
+	// nm.Renderer.ActiveOffer.AddBlenderVersion("3.2.1", &[]string{"CYCLES", "EEVEE"}, &[]string{"SUPPORTED"}, &[]string{"CPU"}, 4)
 
 	// // start a benchmark with this version
 	// err = nm.Renderer.ActiveOffer.Blender["3.2.1"].BenchmarkTool.Run(nm.Renderer.ActiveOffer, "3.2.1", "CPU")
@@ -172,10 +304,49 @@ func (nm *PackageManager) DeInit() error {
 	// log event
 	logger.Manager.Package["node"].Debug().Msg("Deinitializing the node manager ...")
 
+	// stop the long-running operations
+	nm.Cancel()
+
+	// withdraw the active render offer from the network
+	if !nm.KeepOffersOnShutdown && !nm.GetRenderOfferAnnouncementSettings().KeepOnShutdown {
+		if err := nm.WithdrawRenderOffer(); err != nil {
+			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not withdraw the render offer: %v", err))
+		}
+	}
+
+	// store the last processed message of the job queue
+	nm._saveJobQueueState()
+
+	// close the render repository
+	if nm.Repository != nil {
+		err = nm.Repository.Close()
+	}
+
 	return err
 
 }
 
+// Get the context of the long-running operations of the node
+func (nm *PackageManager) Context() context.Context {
+
+	if nm.ctx == nil {
+		return context.Background()
+	}
+
+	return nm.ctx
+
+}
+
+// Cancel the long-running operations of the node (e.g., running benchmarks)
+// NOTE: This is called when the app shuts down.
+func (nm *PackageManager) Cancel() {
+
+	if nm.cancel != nil {
+		nm.cancel()
+	}
+
+}
+
 // Write the details of the node to the configuration file
 func (nm *PackageManager) WriteNodeData(id int, name string, client_node bool, render_node bool, accountid string, publicKey string) error {
 	var err error
@@ -191,6 +362,8 @@ func (nm *PackageManager) WriteNodeData(id int, name string, client_node bool, r
 	node.RenderNode = render_node
 	node.HederaAccount.AccountID = accountid
 	node.HederaAccount.PublicKey = publicKey
+	node.RequesterOnly = nm.Node.RequesterOnly
+	node.TopicID = nm.Node.TopicID
 
 	// store the operator data in a file, which can be loaded the next time
 	data, err := json.MarshalIndent(node, "", "  ")
@@ -251,20 +424,38 @@ func (nm *PackageManager) ReadNodeData(allowSkipping bool) error {
 	nm.Node.Name = node.Name
 	nm.Node.ClientNode = node.ClientNode
 	nm.Node.RenderNode = node.RenderNode
+	nm.Node.RequesterOnly = node.RequesterOnly
 	nm.Node.HederaAccount.AccountID = node.HederaAccount.AccountID
 	nm.Node.HederaAccount.PublicKey = node.HederaAccount.PublicKey
+	nm.Node.TopicID = node.TopicID
 
 	// log event
 	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] [*] Node ID: %v", nm.Node.ID))
 	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] [*] Name: %v", nm.Node.Name))
 	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] [*] Client node: %v", nm.Node.ClientNode))
 	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] [*] Render node: %v", nm.Node.RenderNode))
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] [*] Requester only: %v", nm.Node.RequesterOnly))
 	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] [*] Public Key: %v", nm.Node.HederaAccount.PublicKey))
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] [*] Topic ID: %v", nm.Node.TopicID))
 
 	return err
 
 }
 
+// Set the HCS topic of the node in the configuration file
+func (nm *PackageManager) SetNodeTopic(topicID string) error {
+
+	_, err := hederasdk.TopicIDFromString(topicID)
+	if err != nil {
+		return newRenderError(ErrInvalidArgument, "Invalid topic ID '%v': %w", topicID, err)
+	}
+
+	nm.Node.TopicID = topicID
+
+	return nm.WriteNodeData(nm.Node.ID, nm.Node.Name, nm.Node.ClientNode, nm.Node.RenderNode, nm.Node.HederaAccount.AccountID, nm.Node.HederaAccount.PublicKey)
+
+}
+
 // Hash the node data from the configuration file
 func (nm *PackageManager) HashNodeData() ([]byte, error) {
 	var err error
@@ -313,6 +504,33 @@ func (nm *PackageManager) LoadConfiguration() error {
 
 }
 
+// Check if the smart contract recognizes the operator account as sender of its calls
+// NOTE: See 'hedera/signers.go' for the key type requirements.
+func (nm *PackageManager) CheckOperatorSigner() error {
+
+	// the operator did not sign up yet
+	account := nm.User.UserAccount.AccountID
+	if account.Account == 0 && account.AliasKey == nil && account.AliasEvmAddress == nil {
+		return nil
+	}
+
+	check, err := hedera.Manager.QueryContractSigner(account)
+	if err != nil {
+		return err
+	}
+
+	// log info
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Operator key type: %v", check.KeyType))
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Operator EVM address: %v", check.EvmAddress))
+
+	if !check.Compatible {
+		return errors.New(check.Reason)
+	}
+
+	return nil
+
+}
+
 // Register this node at the smart contract
 func (nm *PackageManager) RegisterNode() error {
 	var err error
@@ -334,9 +552,9 @@ func (nm *PackageManager) CreateCommand() *cobra.Command {
 		Use:   "node",
 		Short: "Commands for managing the Renderhive node",
 		Long:  "This command and its sub-commands enable the management of this Renderhive node",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
-			return
+			return nil
 
 		},
 	}
@@ -344,7 +562,14 @@ func (nm *PackageManager) CreateCommand() *cobra.Command {
 	// add the subcommands
 	nm.Command.AddCommand(nm.CreateCommandInfo())
 	nm.Command.AddCommand(nm.CreateCommandBlender())
+	nm.Command.AddCommand(nm.CreateCommandOffer())
 	nm.Command.AddCommand(nm.CreateCommandRequest())
+	nm.Command.AddCommand(nm.CreateCommandSweep())
+	nm.Command.AddCommand(nm.CreateCommandExport())
+	nm.Command.AddCommand(nm.CreateCommandImport())
+	nm.Command.AddCommand(nm.CreateCommandSelftest())
+	nm.Command.AddCommand(nm.CreateCommandRenderCID())
+	nm.Command.AddCommand(nm.CreateCommandDecommission())
 
 	return nm.Command
 
@@ -359,91 +584,111 @@ func (nm *PackageManager) CreateCommandInfo() *cobra.Command {
 	var offer bool
 	var hive_cycle bool
 	var hive_queue bool
+	var capacity bool
 
 	// create a 'info' command for the node
 	command := &cobra.Command{
 		Use:   "info",
 		Short: "Print information about this node",
 		Long:  "This command provides information about the node including those information retrieved or derived from external network data.",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 
 			// print the node data of this node
 			if this {
-				fmt.Println("")
-				fmt.Println("Available information about this node:")
-				fmt.Printf(" [#] Node ID: %v\n", nm.Node.ID)
-				fmt.Printf(" [#] Operating as client node: %v\n", nm.Node.ClientNode)
-				fmt.Printf(" [#] Operating as render node: %v\n", nm.Node.RenderNode)
-				fmt.Printf(" [#] Node Account ID (Hedera): %v\n", nm.Node.HederaAccount.AccountID)
-				fmt.Println("")
+				logger.Manager.Println("")
+				logger.Manager.Println("Available information about this node:")
+				logger.Manager.Resultf(" [#] Node ID: %v\n", nm.Node.ID)
+				logger.Manager.Resultf(" [#] Operating as client node: %v\n", nm.Node.ClientNode)
+				logger.Manager.Resultf(" [#] Operating as render node: %v\n", nm.Node.RenderNode)
+				logger.Manager.Resultf(" [#] Requester-only mode: %v\n", nm.IsRequesterOnly())
+				logger.Manager.Resultf(" [#] Node Account ID (Hedera): %v\n", nm.Node.HederaAccount.AccountID)
+				logger.Manager.Println("")
 			}
 
 			// print the user data
 			if user {
-				fmt.Println("")
-				fmt.Println("This node is registered on the following user:")
-				fmt.Printf(" [#] User ID: %v\n", nm.User.ID)
-				fmt.Printf(" [#] Username: %v\n", nm.User.Username)
-				fmt.Printf(" [#] User Account ID (Hedera): %v\n", nm.User.UserAccount.AccountID.String())
-				fmt.Println("")
+				logger.Manager.Println("")
+				logger.Manager.Println("This node is registered on the following user:")
+				logger.Manager.Resultf(" [#] User ID: %v\n", nm.User.ID)
+				logger.Manager.Resultf(" [#] Username: %v\n", nm.User.Username)
+				logger.Manager.Resultf(" [#] User Account ID (Hedera): %v\n", nm.User.UserAccount.AccountID.String())
+				logger.Manager.Println("")
 			}
 
 			// print the render offer
 			if offer {
-				// if the node has a render offer, print it
-				if nm.Renderer.ActiveOffer != nil {
-
-					fmt.Println("")
-					fmt.Println("This node offers the following render services:")
-					fmt.Printf(" [#] Render offer document (CID): %v\n", nm.Renderer.ActiveOffer.DocumentCID)
-					fmt.Printf(" [#] Supported Blender versions:\n")
-					for _, blender := range nm.Renderer.ActiveOffer.Blender {
-						fmt.Printf("     - Blender v%v (Engines: %v | Devices: %v) \n", blender.BuildVersion, strings.Join(blender.Engines, ", "), strings.Join(blender.Devices, ", "))
+				// if the node has active render offers, print them
+				if len(nm.GetActiveRenderOffers()) > 0 {
+
+					logger.Manager.Println("")
+					logger.Manager.Println("This node offers the following render services:")
+					for _, offer := range nm.GetActiveRenderOffers() {
+						logger.Manager.Resultf(" [#] Render offer document (CID): %v (Price: %v, Paused: %v)\n", _activeOfferName(offer), offer.Price, offer.Paused)
+						logger.Manager.Resultf("     Supported Blender versions:\n")
+						for _, blender := range offer.Blender {
+							logger.Manager.Resultf("     - Blender v%v (Engines: %v | Feature sets: %v | Devices: %v) \n", blender.BuildVersion, strings.Join(blender.Engines, ", "), strings.Join(blender.FeatureSets, ", "), strings.Join(blender.Devices, ", "))
+						}
 					}
-					fmt.Println("")
+					logger.Manager.Println("")
 
 				} else {
 
-					fmt.Println("")
-					fmt.Println("This node is not offering a render service.")
-					fmt.Println("")
+					logger.Manager.Println("")
+					logger.Manager.Println("This node is not offering a render service.")
+					logger.Manager.Println("")
 
 				}
 			}
 
 			// print the hive cycle
 			if hive_cycle {
-				fmt.Println("")
-				fmt.Printf("The current hive cycle of the render hive is %v.\n", nm.HiveCycle.Current)
-				fmt.Printf(" [#] Started at consensus time: %v\n", nm.HiveCycle.Clock.NetworkStartTime)
-				fmt.Printf(" [#] Started at local time: %v\n", nm.HiveCycle.Clock.LocalStartTime)
-				fmt.Println("")
+				logger.Manager.Println("")
+				logger.Manager.Resultf("The current hive cycle of the render hive is %v.\n", nm.HiveCycle.Current)
+				logger.Manager.Resultf(" [#] Started at consensus time: %v\n", nm.HiveCycle.Clock.NetworkStartTime)
+				logger.Manager.Resultf(" [#] Started at local time: %v\n", nm.HiveCycle.Clock.LocalStartTime)
+				logger.Manager.Println("")
 			}
 
 			// print the render job queue of the hive
 			if hive_queue {
 
 				if len(nm.NetworkQueue) > 0 {
-					fmt.Println("")
-					fmt.Printf("There are %v render requests in the render hive queue:\n", len(nm.NetworkQueue))
+					logger.Manager.Println("")
+					logger.Manager.Printf("There are %v render requests in the render hive queue:\n", len(nm.NetworkQueue))
 
 					// go through the list and print each queue
 					for i, job := range nm.NetworkQueue {
-						fmt.Printf(" [#] [%v] Render job #%v: %v\n", job.Request.SubmittedTimestamp, i, job.Request.DocumentCID)
+						estimate := nm.EstimateRenderDuration(job).Round(time.Second)
+						if job.Subtask != nil {
+							logger.Manager.Resultf(" [#] [%v] Render job #%v: %v (subtask %v: frames %v-%v, estimated render time: %v)\n", job.Request.SubmittedTimestamp, i, job.Request.DocumentCID, job.Subtask.Index, job.Subtask.FrameStart, job.Subtask.FrameEnd, estimate)
+						} else {
+							logger.Manager.Resultf(" [#] [%v] Render job #%v: %v (%v frame(s), estimated render time: %v)\n", job.Request.SubmittedTimestamp, i, job.Request.DocumentCID, job.Frames(), estimate)
+						}
 					}
 
-					fmt.Println("")
+					logger.Manager.Println("")
 
 				} else {
 
-					fmt.Println("")
-					fmt.Println("There are no render requests in the render hive queue.")
-					fmt.Println("")
+					logger.Manager.Println("")
+					logger.Manager.Println("There are no render requests in the render hive queue.")
+					logger.Manager.Println("")
 
 				}
 			}
 
-			return
+			// print the render capacity of the hive
+			if capacity {
+				snapshot := nm.NetworkCapacity()
+				logger.Manager.Println("")
+				logger.Manager.Println("Estimated render capacity of the render hive:")
+				logger.Manager.Resultf(" [#] Active nodes: %v (%v active render offers, %v stale)\n", snapshot.ActiveNodes, snapshot.ActiveOffers, snapshot.StaleOffers)
+				logger.Manager.Resultf(" [#] Render power: %.2f samples per minute (known for %v of %v nodes)\n", snapshot.RenderPower, snapshot.RatedNodes, snapshot.ActiveNodes)
+				logger.Manager.Resultf(" [#] Open render requests: %v (%v queued, %v claimed render jobs)\n", snapshot.OpenRequests, snapshot.QueuedJobs, snapshot.ClaimedJobs)
+				logger.Manager.Println("")
+			}
+
+			return nil
 
 		},
 	}
@@ -454,6 +699,7 @@ func (nm *PackageManager) CreateCommandInfo() *cobra.Command {
 	command.Flags().BoolVarP(&offer, "offer", "o", false, "Print the render offer of this node")
 	command.Flags().BoolVarP(&hive_cycle, "hive-cycle", "c", false, "Print the current hive cycle this node calculated")
 	command.Flags().BoolVarP(&hive_queue, "hive-queue", "q", false, "Print the render job queue of the render hive")
+	command.Flags().BoolVarP(&capacity, "capacity", "", false, "Print the estimated render capacity of the render hive")
 
 	return command
 
@@ -0,0 +1,329 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the scheduling of the render jobs on this node. A requester
+can set a priority and a deadline for a render request. When the node picks
+the next job from the render hive queue, it prefers jobs with a higher
+priority, then jobs with a sooner deadline, and finally the jobs submitted
+first.
+
+The node does not claim a job, if it cannot render the job before its
+deadline or within the limits of this node (see the feasibility check). The
+render time is estimated from the render settings and the benchmark results
+of this node, or from the number of frames and the average render time per
+frame observed on this node. The jobs already claimed by this node are
+rendered first and delay the start of the new job. The render requests of this
+node itself are scheduled with a higher priority (see own_requests.go).
+
+*/
+
+import (
+
+	// standard
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// RENDER JOB SCHEDULING
+// #############################################################################
+// Set the priority and the deadline of the render request
+func (request *RenderRequest) SetSchedule(priority int, deadline time.Time) error {
+
+	// check if the render request was already submitted
+	if request._isSubmitted() {
+		return newRenderError(ErrAlreadySubmitted, "Render request was already submitted and cannot be modified.")
+	}
+
+	// the deadline must be in the future
+	if !deadline.IsZero() && !deadline.After(time.Now()) {
+		return newRenderError(ErrInvalidArgument, "Deadline '%v' of the render request has already passed.", deadline.Format(time.RFC3339))
+	}
+
+	request.Priority = priority
+	request.Deadline = deadline
+	request._updateModifiedTimestamp()
+
+	return nil
+
+}
+
+// Get the number of frames of the render job
+// NOTE: Returns 1, if the frame range is not known.
+func (job *RenderJob) Frames() int {
+	return _frameCount(job.FrameSettings())
+}
+
+// helper function to get the number of frames of the frame range of the render settings
+func _frameCount(settings RenderSettings) int {
+
+	if settings.FrameEnd < settings.FrameStart {
+		return 1
+	}
+
+	step := settings.FrameStep
+	if step < 1 {
+		step = 1
+	}
+
+	return (settings.FrameEnd-settings.FrameStart)/step + 1
+
+}
+
+// Estimate the render time of the render job on this node
+func (nm *PackageManager) EstimateRenderDuration(job *RenderJob) time.Duration {
+	return nm.EstimateRenderResources(job).Duration
+}
+
+// Record the render time of a completed render job in the average render time per frame
+func (nm *PackageManager) ObserveRenderDuration(job *RenderJob, duration time.Duration) {
+
+	if duration <= 0 {
+		return
+	}
+	frameDuration := duration / time.Duration(job.Frames())
+
+	// start with the first observation and smooth the following ones
+	if nm.Renderer.FrameDuration <= 0 {
+		nm.Renderer.FrameDuration = frameDuration
+	} else {
+		nm.Renderer.FrameDuration += time.Duration(RENDERHIVE_CONFIG_RENDER_JOB_FRAME_DURATION_SMOOTHING * float64(frameDuration-nm.Renderer.FrameDuration))
+	}
+
+}
+
+// Estimate the remaining render time of the jobs claimed by this node
+func (nm *PackageManager) QueueDuration() time.Duration {
+
+	total := time.Duration(0)
+	for _, job := range nm.Renderer.NodeQueue {
+		if job.State != RENDER_JOB_STATE_CLAIMED && job.State != RENDER_JOB_STATE_RENDERING {
+			continue
+		}
+
+		// subtract the time the job is already rendered
+		remaining := nm.EstimateRenderDuration(job)
+		if job.State == RENDER_JOB_STATE_RENDERING && !job.ClaimedTimestamp.IsZero() {
+			remaining -= time.Since(job.ClaimedTimestamp)
+		}
+		if remaining > 0 {
+			total += remaining
+		}
+	}
+
+	return total
+
+}
+
+// Check if this node can render the job before the deadline of its render request
+func (nm *PackageManager) CanMeetDeadline(job *RenderJob) bool {
+
+	// jobs without deadline can always be rendered
+	if job.Request.Deadline.IsZero() {
+		return true
+	}
+
+	finish := time.Now().Add(nm.QueueDuration() + nm.ReservedDuration(job, nm.GetOwnRequestSettings()) + nm.EstimateRenderDuration(job))
+
+	return !finish.After(job.Request.Deadline)
+
+}
+
+// Sort the render jobs by priority, deadline, and submission time
+func SortRenderJobs(jobs []*RenderJob) {
+
+	SortRenderJobsByPriority(jobs, func(job *RenderJob) int { return job.Request.Priority })
+
+}
+
+// Sort the render jobs by the given priority, deadline, and submission time
+func SortRenderJobsByPriority(jobs []*RenderJob, priority func(job *RenderJob) int) {
+
+	priorities := make(map[*RenderJob]int, len(jobs))
+	for _, job := range jobs {
+		priorities[job] = priority(job)
+	}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return _preferRenderJob(jobs[i], jobs[j], priorities[jobs[i]], priorities[jobs[j]])
+	})
+
+}
+
+// Get the next render job of the render hive queue this node can render in time (and within its limits)
+// NOTE: Returns nil, if there is no such job.
+func (nm *PackageManager) NextRenderJob() *RenderJob {
+
+	// a requester-only node does not claim render jobs
+	if nm.IsRequesterOnly() {
+		return nil
+	}
+
+	// get the jobs that are available for rendering
+	// NOTE: The own render requests are only rendered, if the requester wants this node to participate.
+	candidates := []*RenderJob{}
+	for _, job := range nm.NetworkQueue {
+		if job.State != RENDER_JOB_STATE_QUEUED || job.Flagged || nm._isClaimed(job) {
+			continue
+		}
+		if request := nm.OwnRenderRequest(job); request != nil && !request.ThisNode {
+			continue
+		}
+		candidates = append(candidates, job)
+	}
+	settings := nm.GetOwnRequestSettings()
+	SortRenderJobsByPriority(candidates, func(job *RenderJob) int { return nm.SchedulingPriority(job, settings) })
+
+	// pick the preferred job that can be rendered within the limits and before its deadline
+	for _, job := range candidates {
+
+		// pick the active render offer, which applies best to the job
+		// NOTE: The offer is kept with the job, so that the estimates use its benchmarks.
+		job.Offer = nm.BestActiveRenderOffer(job.Request)
+		if job.Offer == nil {
+			logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf("Skipping render job '%v': None of the active render offers matches the render request.", job.Request.DocumentCID))
+			continue
+		}
+
+		estimate, err := nm.CheckRenderFeasibility(job)
+		if err != nil {
+			logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf("Skipping render job '%v': %v", job.Request.DocumentCID, err))
+			job.Offer = nil
+			continue
+		}
+
+		// skip jobs whose Blender file does not open on this node
+		// NOTE: Render requests of older nodes do not announce their Blender version.
+		if job.Request.Version != "" {
+			validation, err := nm.ValidateRenderJob(job)
+			if err != nil {
+				logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf("Skipping render job '%v': Could not validate the Blender file: %v", job.Request.DocumentCID, err))
+				job.Offer = nil
+				continue
+			}
+			if !validation.Renderable {
+				logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf("Skipping render job '%v': The Blender file cannot be rendered with Blender v%v: %v", job.Request.DocumentCID, validation.Version, strings.Join(validation.Warnings, " ")))
+				job.Offer = nil
+				continue
+			}
+		}
+
+		// log event
+		source := "average frame time"
+		if estimate.Benchmark {
+			source = "benchmark"
+		}
+		logger.Manager.Package["node"].Info().Msg(fmt.Sprintf("Render job '%v' is estimated to take %v for %v frame(s) on this node (based on the %v).", job.Request.DocumentCID, estimate.Duration.Round(time.Second), estimate.Frames, source))
+		logger.Manager.Package["node"].Info().Msg(fmt.Sprintf("Render job '%v' matches render offer '%v'.", job.Request.DocumentCID, _activeOfferName(job.Offer)))
+
+		return job
+	}
+
+	return nil
+
+}
+
+// Claim the next render job of the render hive queue for rendering on this node
+// NOTE: Returns nil, if there is no job this node can render in time.
+func (nm *PackageManager) ClaimNextRenderJob() *RenderJob {
+
+	job := nm.NextRenderJob()
+	if job == nil {
+		return nil
+	}
+
+	// claim the job and add it to the queue of this node
+	job.Claim(nm.EstimateRenderDuration(job))
+	job.Operator = nm.User.UserAccount.AccountID.String()
+	nm.Renderer.NodeQueue = append(nm.Renderer.NodeQueue, job)
+	nm.PublishEvent(EVENT_JOB_CLAIMED, JobEventData{
+		RenderRequestCID: job.Request.DocumentCID,
+		Subtask:          job.SubtaskIndex(),
+		Operator:         job.Operator,
+		Deadline:         job.Deadline,
+	})
+
+	// announce the claim, so the other nodes skip the job
+	err := nm.AnnounceRenderJobClaim(job)
+	if err != nil {
+		logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Could not announce the claim of render job '%v': %v", job.Request.DocumentCID, err))
+	}
+
+	return job
+
+}
+
+// helper function to check if the job is preferred over another job (with the given priorities)
+func _preferRenderJob(a *RenderJob, b *RenderJob, priorityA int, priorityB int) bool {
+
+	// higher priority first
+	if priorityA != priorityB {
+		return priorityA > priorityB
+	}
+
+	// sooner deadline first (jobs without deadline last)
+	if !a.Request.Deadline.Equal(b.Request.Deadline) {
+		if a.Request.Deadline.IsZero() || b.Request.Deadline.IsZero() {
+			return b.Request.Deadline.IsZero()
+		}
+		return a.Request.Deadline.Before(b.Request.Deadline)
+	}
+
+	// earlier submission first
+	return a.Request.SubmittedTimestamp.Before(b.Request.SubmittedTimestamp)
+
+}
+
+// helper function to check if the job is in the queue of this node
+func (nm *PackageManager) _isClaimed(job *RenderJob) bool {
+
+	for _, claimed := range nm.Renderer.NodeQueue {
+		if claimed == job || (claimed.Request.DocumentCID == job.Request.DocumentCID && claimed.SubtaskIndex() == job.SubtaskIndex()) {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// helper function to check if this node renders other jobs of the same render request
+func (nm *PackageManager) _rendersRequest(job *RenderJob) bool {
+
+	for _, other := range nm.Renderer.NodeQueue {
+		if other == job || other.Request.DocumentCID != job.Request.DocumentCID {
+			continue
+		}
+		if other.State == RENDER_JOB_STATE_CLAIMED || other.State == RENDER_JOB_STATE_RENDERING {
+			return true
+		}
+	}
+
+	return false
+
+}
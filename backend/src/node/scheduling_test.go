@@ -0,0 +1,73 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+	// standard
+	"testing"
+)
+
+func TestNextRenderJobSkipsOwnRequests(t *testing.T) {
+	nm, job := _testWorkerManager(t)
+
+	// this node created the render request without participating in its rendering
+	own := &RenderRequest{DocumentCID: job.Request.DocumentCID, ThisNode: false}
+	nm.Renderer.Requests = map[string]*RenderRequest{own.DocumentCID: own}
+	if next := nm.NextRenderJob(); next != nil {
+		t.Fatalf("picked up render job '%v' of the own render request", next.Request.DocumentCID)
+	}
+	if nm.IsOwnRenderJob(job) {
+		t.Error("the job must not be rendered as an own job")
+	}
+
+	// the job of another node is still picked up
+	nm.Renderer.Requests = map[string]*RenderRequest{}
+	if next := nm.NextRenderJob(); next != job {
+		t.Errorf("got %v, want the render job of another node", next)
+	}
+}
+
+func TestNextRenderJobPrefersOwnRequestsWithThisNode(t *testing.T) {
+	nm, job := _testWorkerManager(t)
+
+	// a job of another node with a higher priority
+	other := &RenderRequest{DocumentCID: "bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy", Version: job.Request.Version, Priority: 10}
+	other.BlenderFile = job.Request.BlenderFile
+	other.Validation = job.Request.Validation
+	otherJob := &RenderJob{Request: other, State: RENDER_JOB_STATE_QUEUED}
+	nm.NetworkQueue = append(nm.NetworkQueue, otherJob)
+
+	// the own render request, in which this node participates
+	own := &RenderRequest{DocumentCID: job.Request.DocumentCID, ThisNode: true}
+	nm.Renderer.Requests = map[string]*RenderRequest{own.DocumentCID: own}
+	if !nm.IsOwnRenderJob(job) {
+		t.Fatal("the job must be rendered as an own job")
+	}
+	if next := nm.NextRenderJob(); next != job {
+		t.Errorf("got %v, want the own render job first", next)
+	}
+
+	// without the own render request, the job with the higher priority is preferred
+	nm.Renderer.Requests = map[string]*RenderRequest{}
+	if next := nm.NextRenderJob(); next != otherJob {
+		t.Errorf("got %v, want the render job with the higher priority", next)
+	}
+}
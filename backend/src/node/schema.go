@@ -0,0 +1,133 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the schema versions of the render offer and render request
+documents.
+
+Each document records the schema version it was written with. Documents written
+before the schema version was introduced do not contain the field and are
+treated as version 0. When a document is loaded, it is migrated step by step to
+the current schema version, so that fields added later get their default values.
+The document file itself is not changed, since its CID would change. Documents
+of a newer schema version than this node knows are refused, because this node
+cannot tell which of their fields it would lose.
+
+Schema versions:
+
+    0: documents without a schema version
+    1: adds the schema version and the feature sets of the offered Blender
+       versions (default: SUPPORTED, if an offered engine has feature sets)
+
+*/
+
+import (
+
+	// internal
+	. "renderhive/globals"
+)
+
+// DOCUMENT SCHEMA
+// #############################################################################
+// Check the schema version of a render document
+func CheckSchemaVersion(kind string, version int) error {
+
+	if version < 0 {
+		return newRenderError(ErrUnsupportedSchema, "The %v document has an invalid schema version %v.", kind, version)
+	}
+	if version > RENDERHIVE_DOCUMENT_SCHEMA_VERSION {
+		return newRenderError(ErrUnsupportedSchema, "The %v document uses schema version %v, but this node only supports versions up to %v. Please update the Renderhive Service App.", kind, version, RENDERHIVE_DOCUMENT_SCHEMA_VERSION)
+	}
+
+	return nil
+
+}
+
+// Migrate a decoded render offer from its schema version to the current schema version
+func (offer *RenderOffer) MigrateSchema() error {
+
+	err := CheckSchemaVersion("render offer", offer.SchemaVersion)
+	if err != nil {
+		return err
+	}
+
+	// the engine names are normalized for each Blender version (e.g., offers of
+	// Blender 4.2 or later, which were stored with 'EEVEE' instead of 'EEVEE_NEXT')
+	for i := range offer.BlenderVersions {
+		for j, engine := range offer.BlenderVersions[i].Engines {
+			offer.BlenderVersions[i].Engines[j] = NormalizeBlenderEngine(offer.BlenderVersions[i].Version, engine)
+		}
+	}
+
+	// version 0 → 1: the Blender versions were offered without feature sets
+	// NOTE: Like CheckBlenderCapabilities, only versions offering an engine with
+	// feature sets (i.e., Cycles) get the default feature set.
+	if offer.SchemaVersion < 1 {
+		for i := range offer.BlenderVersions {
+			if len(offer.BlenderVersions[i].FeatureSets) == 0 && _hasEngineFeatureSets(offer.BlenderVersions[i]) {
+				offer.BlenderVersions[i].FeatureSets = []string{BLENDER_DEFAULT_FEATURE_SET}
+			}
+		}
+		offer.SchemaVersion = 1
+	}
+
+	return nil
+
+}
+
+// Migrate a decoded render request from its schema version to the current schema version
+func (request *RenderRequest) MigrateSchema() error {
+
+	err := CheckSchemaVersion("render request", request.SchemaVersion)
+	if err != nil {
+		return err
+	}
+
+	// version 0 → 1: the frame step of the render settings was not always set
+	if request.SchemaVersion < 1 {
+		if request.BlenderFile.Settings.FrameStep <= 0 {
+			request.BlenderFile.Settings.FrameStep = 1
+		}
+		request.SchemaVersion = 1
+	}
+
+	return nil
+
+}
+
+// helper function to check if an offered Blender version has an engine with
+// feature sets (an offer without engines offers all engines of the version)
+func _hasEngineFeatureSets(blender RenderOfferBlenderVersions) bool {
+
+	for _, capability := range GetBlenderCapabilities(blender.Version) {
+		if len(capability.FeatureSets) == 0 {
+			continue
+		}
+		if len(blender.Engines) == 0 || _containsFold(blender.Engines, capability.Engine) {
+			return true
+		}
+	}
+
+	return false
+
+}
@@ -0,0 +1,247 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+	// standard
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+)
+
+func TestBlenderEngineEnum(t *testing.T) {
+
+	// the values of the engines do not change, when engines are added
+	if BLENDER_RENDER_ENGINE_EEVEE != 1 || BLENDER_RENDER_ENGINE_CYCLES != 2 || BLENDER_RENDER_ENGINE_OPTIONS != 3 || BLENDER_RENDER_ENGINE_EEVEE_NEXT != 4 {
+		t.Fatal("the values of the render engines changed")
+	}
+
+	// the engine names are not case-sensitive
+	enum, err := GetBlenderEngineEnum([]string{"eevee", "Cycles", "EEVEE_NEXT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enum) != 3 || enum[0] != BLENDER_RENDER_ENGINE_EEVEE || enum[1] != BLENDER_RENDER_ENGINE_CYCLES || enum[2] != BLENDER_RENDER_ENGINE_EEVEE_NEXT {
+		t.Errorf("got engines %v", enum)
+	}
+	if _, err = GetBlenderEngineEnum([]string{"WORKBENCH"}); err == nil {
+		t.Error("an unknown engine must be rejected")
+	}
+}
+
+func TestCheckBlenderCapabilitiesNormalizesEngines(t *testing.T) {
+	engines := []string{"eevee_next", "cycles"}
+	featureSets := []string{}
+	err := CheckBlenderCapabilities("4.2.0", &engines, &featureSets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if engines[0] != "EEVEE_NEXT" || engines[1] != "CYCLES" {
+		t.Errorf("got engines %v, want upper case names", engines)
+	}
+
+	// EEVEE of Blender 4.2 is called EEVEE_NEXT
+	engines = []string{"EEVEE"}
+	if err = CheckBlenderCapabilities("4.2.0", &engines, &featureSets); err == nil {
+		t.Error("EEVEE must be rejected for Blender 4.2")
+	}
+}
+
+func TestMigrateRenderOfferEngines(t *testing.T) {
+	offer := &RenderOffer{SchemaVersion: RENDERHIVE_DOCUMENT_SCHEMA_VERSION, BlenderVersions: []RenderOfferBlenderVersions{
+		{Version: "4.1.0", Engines: []string{"eevee", "CYCLES"}},
+		{Version: "4.2.0", Engines: []string{"EEVEE", "CYCLES"}},
+		{Version: "5.0.0", Engines: []string{"EEVEE_NEXT"}},
+	}}
+	err := offer.MigrateSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{{"EEVEE", "CYCLES"}, {"EEVEE_NEXT", "CYCLES"}, {"EEVEE"}}
+	for i, blender := range offer.BlenderVersions {
+		for j, engine := range blender.Engines {
+			if engine != want[i][j] {
+				t.Errorf("Blender v%v: got engines %v, want %v", blender.Version, blender.Engines, want[i])
+				break
+			}
+		}
+
+		// the migrated engines are valid for the version
+		featureSets := []string{}
+		if err := CheckBlenderCapabilities(blender.Version, &blender.Engines, &featureSets); err != nil {
+			t.Errorf("Blender v%v: %v", blender.Version, err)
+		}
+	}
+}
+
+func TestMigrateRenderOfferFeatureSets(t *testing.T) {
+	offer := &RenderOffer{BlenderVersions: []RenderOfferBlenderVersions{
+		{Version: "4.1.0", Engines: []string{"EEVEE"}},
+		{Version: "4.1.0", Engines: []string{"EEVEE", "CYCLES"}},
+		{Version: "4.1.0"},
+		{Version: "4.1.0", Engines: []string{"CYCLES"}, FeatureSets: []string{"EXPERIMENTAL"}},
+		{Version: "4.2.0", Engines: []string{"EEVEE"}},
+	}}
+	err := offer.MigrateSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offer.SchemaVersion != 1 {
+		t.Fatalf("got schema version %v, want 1", offer.SchemaVersion)
+	}
+
+	// only versions offering Cycles get the default feature set
+	want := [][]string{nil, {"SUPPORTED"}, {"SUPPORTED"}, {"EXPERIMENTAL"}, nil}
+	for i, blender := range offer.BlenderVersions {
+		if len(blender.FeatureSets) != len(want[i]) || (len(want[i]) > 0 && blender.FeatureSets[0] != want[i][0]) {
+			t.Errorf("Blender v%v %v: got feature sets %v, want %v", blender.Version, blender.Engines, blender.FeatureSets, want[i])
+		}
+
+		// the migrated feature sets are valid for the engines
+		featureSets := append([]string{}, blender.FeatureSets...)
+		if err := CheckBlenderCapabilities(blender.Version, &blender.Engines, &featureSets); err != nil {
+			t.Errorf("Blender v%v: %v", blender.Version, err)
+		}
+	}
+
+	// documents of the current schema version keep their feature sets
+	offer = &RenderOffer{SchemaVersion: 1, BlenderVersions: []RenderOfferBlenderVersions{{Version: "4.1.0", Engines: []string{"CYCLES"}}}}
+	if err := offer.MigrateSchema(); err != nil {
+		t.Fatal(err)
+	}
+	if len(offer.BlenderVersions[0].FeatureSets) != 0 {
+		t.Errorf("got feature sets %v for a current document", offer.BlenderVersions[0].FeatureSets)
+	}
+}
+
+func TestMigrateSchemaRefusesNewerDocuments(t *testing.T) {
+	offer := &RenderOffer{SchemaVersion: RENDERHIVE_DOCUMENT_SCHEMA_VERSION + 1}
+	if err := offer.MigrateSchema(); err == nil {
+		t.Error("expected an error for a render offer of a newer schema version")
+	}
+	request := &RenderRequest{SchemaVersion: -1}
+	if err := request.MigrateSchema(); err == nil {
+		t.Error("expected an error for an invalid schema version")
+	}
+
+	// the frame step of a render request without a schema version is set
+	request = &RenderRequest{}
+	if err := request.MigrateSchema(); err != nil {
+		t.Fatal(err)
+	}
+	if request.SchemaVersion != 1 || request.BlenderFile.Settings.FrameStep != 1 {
+		t.Errorf("unexpected migrated render request: %v, frame step %v", request.SchemaVersion, request.BlenderFile.Settings.FrameStep)
+	}
+}
+
+// helper function to write a render document in JSON format as the deploy does
+func _writeRenderDocument(t *testing.T, document any) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "document.json")
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// helper function to create a render offer and a render request with all key fields set
+func _testRenderDocuments() (*RenderOffer, *RenderRequest) {
+	owner, _ := hederasdk.AccountIDFromString("0.0.1001")
+	created := time.Now()
+
+	offer := &RenderOffer{SchemaVersion: RENDERHIVE_DOCUMENT_SCHEMA_VERSION, Owner: &owner, Price: 1.5, CreatedTimestamp: created, ModifiedTimestamp: created.Add(time.Minute)}
+	offer.BlenderVersions = []RenderOfferBlenderVersions{{Version: "4.1.0", Engines: []string{"CYCLES"}, FeatureSets: []string{"SUPPORTED"}, Devices: []string{"CPU"}, Threads: 8}}
+
+	request := &RenderRequest{SchemaVersion: RENDERHIVE_DOCUMENT_SCHEMA_VERSION, Owner: &owner, DirectoryCID: "directory", Version: "4.1.0", Price: 2.5, Priority: 3}
+	request.CreatedTimestamp, request.ModifiedTimestamp, request.Deadline = created, created.Add(time.Minute), created.Add(time.Hour)
+	request.FramesPerTask, request.RegionRows, request.RegionColumns, request.SetupScriptCID = 5, 2, 3, "setup"
+	request.BlenderFile.CID, request.BlenderFile.Scene = "blend", "Scene"
+	request.BlenderFile.Settings.FrameStart, request.BlenderFile.Settings.FrameEnd, request.BlenderFile.Settings.FrameStep = 1, 250, 1
+
+	return offer, request
+}
+
+func TestRenderDocumentsRoundTrip(t *testing.T) {
+	offer, request := _testRenderDocuments()
+
+	// the written documents decode to the same render offer and request
+	offer.DocumentPath = _writeRenderDocument(t, offer)
+	if err := offer._verifyDocument(); err != nil {
+		t.Errorf("render offer: %v", err)
+	}
+	request.DocumentPath = _writeRenderDocument(t, request)
+	if err := request._verifyDocument(); err != nil {
+		t.Errorf("render request: %v", err)
+	}
+}
+
+func TestRenderDocumentsMismatchIsDetected(t *testing.T) {
+
+	// a field that is not written to the document as it is held in memory
+	offer, request := _testRenderDocuments()
+	offer.DocumentPath = _writeRenderDocument(t, offer)
+	offer.Price = 2
+	if err := offer._verifyDocument(); !errors.Is(err, ErrDocumentMismatch) || !strings.Contains(err.Error(), "Price") {
+		t.Errorf("render offer: got %v, want a price mismatch", err)
+	}
+	request.DocumentPath = _writeRenderDocument(t, request)
+	request.BlenderFile.Settings.FrameEnd = 100
+	if err := request._verifyDocument(); !errors.Is(err, ErrDocumentMismatch) || !strings.Contains(err.Error(), "Settings") {
+		t.Errorf("render request: got %v, want a settings mismatch", err)
+	}
+
+	// the account alias of the owner is not supported by the documents
+	offer, request = _testRenderDocuments()
+	alias, _ := hederasdk.AccountIDFromString("0.0.1001")
+	alias.AliasEvmAddress = &[]byte{1, 2, 3}
+	offer.Owner, request.Owner = &alias, &alias
+	offer.DocumentPath, request.DocumentPath = _writeRenderDocument(t, offer), _writeRenderDocument(t, request)
+	if err := offer._verifyDocument(); !errors.Is(err, ErrDocumentMismatch) {
+		t.Errorf("render offer: got %v, want an alias mismatch", err)
+	}
+	if err := request._verifyDocument(); !errors.Is(err, ErrDocumentMismatch) {
+		t.Errorf("render request: got %v, want an alias mismatch", err)
+	}
+
+	// a document that does not decode at all
+	offer, _ = _testRenderDocuments()
+	offer.DocumentPath = _writeRenderDocument(t, "not a render offer")
+	if err := offer._verifyDocument(); !errors.Is(err, ErrDocumentMismatch) {
+		t.Errorf("render offer: got %v, want a decoding mismatch", err)
+	}
+}
@@ -0,0 +1,57 @@
+# ************************** BEGIN LICENSE BLOCK ******************************
+#
+# Copyright © 2024 Christian Stolze
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+# http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+# ************************** END LICENSE BLOCK ********************************
+
+# This script is executed by the Renderhive Service App in a headless Blender
+# instance with the factory startup file to create the sample scene of the self
+# test. The scene is rendered with Cycles on the CPU in a low resolution, so it
+# renders in a few seconds on any node. The file is saved to the path passed
+# after '--'.
+# NOTE: The script is pinned by its CID. Any change requires an update of the
+#       CID in the Renderhive Service App.
+
+import json
+import sys
+
+import bpy
+
+output_path = sys.argv[sys.argv.index("--") + 1]
+
+# render two small frames of the default scene on the CPU
+scene = bpy.context.scene
+scene.render.engine = "CYCLES"
+scene.cycles.device = "CPU"
+scene.cycles.samples = 4
+scene.render.resolution_x = 320
+scene.render.resolution_y = 180
+scene.render.resolution_percentage = 100
+scene.render.image_settings.file_format = "PNG"
+scene.frame_start = 1
+scene.frame_end = 2
+scene.frame_step = 1
+
+# animate the cube, so the frames differ
+cube = bpy.data.objects.get("Cube")
+if cube is not None:
+    cube.rotation_euler = (0.0, 0.0, 0.0)
+    cube.keyframe_insert(data_path="rotation_euler", frame=1)
+    cube.rotation_euler = (0.0, 0.0, 0.5)
+    cube.keyframe_insert(data_path="rotation_euler", frame=2)
+
+bpy.ops.wm.save_as_mainfile(filepath=output_path, compress=True)
+
+print("RENDERHIVE_SAMPLE_SCENE:" + json.dumps({"path": output_path}))
@@ -0,0 +1,69 @@
+# ************************** BEGIN LICENSE BLOCK ******************************
+#
+# Copyright © 2024 Christian Stolze
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+# http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+# ************************** END LICENSE BLOCK ********************************
+
+# This script is executed by the Renderhive Service App in a headless Blender
+# instance to dump the render settings of all scenes of the loaded .blend file.
+# NOTE: The script is pinned by its CID. Any change requires an update of the
+#       CID in the Renderhive Service App.
+
+import json
+
+import bpy
+
+
+def scene_settings(scene):
+    render = scene.render
+    cycles = getattr(scene, "cycles", None)
+
+    # tile size (Blender 3.0+ uses a single tile size for Cycles)
+    tile_x = getattr(render, "tile_x", 0)
+    tile_y = getattr(render, "tile_y", 0)
+    if cycles is not None and hasattr(cycles, "tile_size"):
+        tile_x = tile_y = cycles.tile_size
+
+    # samples per pixel (Cycles or EEVEE)
+    samples = 0
+    if render.engine == "CYCLES" and cycles is not None:
+        samples = getattr(cycles, "samples", 0)
+    elif hasattr(scene, "eevee"):
+        samples = getattr(scene.eevee, "taa_render_samples", 0)
+
+    return {
+        "name": scene.name,
+        "engine": render.engine,
+        "feature_set": getattr(cycles, "feature_set", "") if render.engine == "CYCLES" else "",
+        "device": getattr(cycles, "device", "") if render.engine == "CYCLES" else "",
+        "resolution_x": int(render.resolution_x * render.resolution_percentage / 100),
+        "resolution_y": int(render.resolution_y * render.resolution_percentage / 100),
+        "tile_x": tile_x,
+        "tile_y": tile_y,
+        "samples": samples,
+        "frame_start": scene.frame_start,
+        "frame_end": scene.frame_end,
+        "frame_step": scene.frame_step,
+        "output_path": render.filepath,
+        "file_format": render.image_settings.file_format,
+    }
+
+
+settings = {
+    "active_scene": bpy.context.scene.name,
+    "scenes": [scene_settings(scene) for scene in bpy.data.scenes],
+}
+
+print("RENDERHIVE_RENDER_SETTINGS:" + json.dumps(settings))
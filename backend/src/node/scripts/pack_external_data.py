@@ -0,0 +1,40 @@
+# ************************** BEGIN LICENSE BLOCK ******************************
+#
+# Copyright © 2024 Christian Stolze
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+# http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+# ************************** END LICENSE BLOCK ********************************
+
+# This script is executed by the Renderhive Service App in a headless Blender
+# instance to pack all external data into the loaded .blend file. The result is
+# saved as a copy to the path passed after '--'. The loaded file is not changed.
+# NOTE: The script is pinned by its CID. Any change requires an update of the
+#       CID in the Renderhive Service App.
+
+import json
+import sys
+
+import bpy
+
+output_path = sys.argv[sys.argv.index("--") + 1]
+
+# pack linked libraries (if supported) and all other external data
+if hasattr(bpy.ops.file, "pack_libraries"):
+    bpy.ops.file.pack_libraries()
+bpy.ops.file.pack_all()
+
+# save the packed file as a copy
+bpy.ops.wm.save_as_mainfile(filepath=output_path, copy=True, compress=True)
+
+print("RENDERHIVE_PACKED:" + json.dumps({"path": output_path}))
@@ -0,0 +1,40 @@
+# ************************** BEGIN LICENSE BLOCK ******************************
+#
+# Copyright © 2024 Christian Stolze
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+# http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+# ************************** END LICENSE BLOCK ********************************
+
+# This script is executed by the Renderhive Service App in a headless Blender
+# instance to list the external file dependencies (textures, linked libraries,
+# caches, etc.) of the loaded .blend file. Packed files are not listed.
+# NOTE: The script is pinned by its CID. Any change requires an update of the
+#       CID in the Renderhive Service App.
+
+import json
+import os
+
+import bpy
+
+dependencies = []
+for path in sorted(set(bpy.utils.blend_paths(absolute=False, packed=False, local=False))):
+    absolute_path = os.path.normpath(bpy.path.abspath(path))
+    dependencies.append({
+        "path": path,
+        "absolute_path": absolute_path,
+        "relative": path.startswith("//"),
+        "exists": os.path.exists(absolute_path),
+    })
+
+print("RENDERHIVE_DEPENDENCIES:" + json.dumps(dependencies))
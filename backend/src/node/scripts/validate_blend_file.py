@@ -0,0 +1,57 @@
+# ************************** BEGIN LICENSE BLOCK ******************************
+#
+# Copyright © 2024 Christian Stolze
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+# http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+# ************************** END LICENSE BLOCK ********************************
+
+# This script is executed by the Renderhive Service App in a headless Blender
+# instance to check if the loaded .blend file can be rendered with this Blender
+# version. It reports the versions, the missing linked libraries and images,
+# and the camera of the active scene, and exits without rendering. The expected
+# path of the file is passed after '--'.
+# NOTE: The script is pinned by its CID. Any change requires an update of the
+#       CID in the Renderhive Service App.
+
+import json
+import os
+import sys
+
+import bpy
+
+expected_path = sys.argv[sys.argv.index("--") + 1]
+
+
+def version_string(version):
+    return ".".join(str(part) for part in version)
+
+
+def missing(path):
+    return path != "" and not os.path.exists(bpy.path.abspath(path))
+
+
+loaded = bpy.data.filepath != "" and os.path.realpath(bpy.data.filepath) == os.path.realpath(expected_path)
+scene = bpy.context.scene
+
+report = {
+    "loaded": loaded,
+    "blender_version": version_string(bpy.app.version),
+    "file_version": version_string(bpy.data.version) if loaded else "",
+    "missing_libraries": [library.filepath for library in bpy.data.libraries if missing(library.filepath)] if loaded else [],
+    "missing_images": [image.filepath for image in bpy.data.images if image.source in {"FILE", "SEQUENCE", "MOVIE"} and image.packed_file is None and missing(image.filepath)] if loaded else [],
+    "scene": scene.name if scene is not None else "",
+    "camera": scene is not None and scene.camera is not None,
+}
+
+print("RENDERHIVE_VALIDATION:" + json.dumps(report))
@@ -0,0 +1,539 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the self test of the node, which runs a render request
+through its whole lifecycle on this node: a sample scene is created and added
+as a render request, which is deployed to IPFS and submitted to the job queue
+topic. The node then claims the render job itself, renders it, collects the
+render result, and submits the result.
+
+Each stage is reported with its CIDs and transaction IDs. A failed stage skips
+the following stages. The mock mode only runs the local stages (Blender and
+IPFS) and skips all stages that send messages to the job queue topic, so it
+neither needs a Hedera account nor costs any fees.
+
+The self test can run repeatedly: each run starts from a clean self test
+directory and overwrites the render request document of the sample scene.
+Afterwards, the render request, the render job, the rendered frames, and the
+IPFS pins are removed, unless they are kept for inspection. The messages sent
+to the job queue topic and the transaction history remain.
+
+*/
+
+import (
+
+	// standard
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	// external
+	"github.com/spf13/cobra"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/hedera"
+	"renderhive/ipfs"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// python script to create the sample scene of the self test
+//
+//go:embed scripts/create_sample_scene.py
+var createSampleSceneScript []byte
+
+// prefix of the output line, which confirms the creation of the sample scene
+const createSampleScenePrefix = "RENDERHIVE_SAMPLE_SCENE:"
+
+// Options of the self test
+type SelftestOptions struct {
+	Version string // Blender version of the render offer used for the test
+	File    string // Blender file to render (empty = the sample scene)
+	Mock    bool   // True, if the stages sending messages to the job queue topic are skipped
+	Keep    bool   // True, if the data of the test is not removed afterwards
+}
+
+// Stage of the self test
+type SelftestStage struct {
+	Name         string            // Name of the stage
+	Skipped      bool              // True, if the stage was not run
+	Err          error             // Error of the stage (nil, if it succeeded)
+	Duration     time.Duration     // Duration of the stage
+	CIDs         map[string]string // CIDs produced by the stage (by their meaning)
+	Transactions []string          // IDs of the transactions submitted by the stage
+}
+
+// Report of the self test
+type SelftestReport struct {
+	Mock   bool             // True, if the test ran in mock mode
+	Stages []*SelftestStage // Stages in the order they ran
+}
+
+// state of a running self test
+type selftest struct {
+	options   SelftestOptions
+	report    *SelftestReport
+	directory string
+	file      string
+	blender   BlenderAppData
+	request   *RenderRequest
+	requestID int
+	job       *RenderJob
+	result    *RenderResult
+}
+
+// SELF TEST
+// #############################################################################
+// Check if all stages of the self test succeeded or were skipped in mock mode
+func (report *SelftestReport) Passed() bool {
+
+	for _, stage := range report.Stages {
+		if stage.Err != nil || (stage.Skipped && !report.Mock) {
+			return false
+		}
+	}
+
+	return len(report.Stages) > 0
+
+}
+
+// Run a render request through its whole lifecycle on this node
+func (nm *PackageManager) RunSelftest(options SelftestOptions) *SelftestReport {
+
+	test := &selftest{
+		options:   options,
+		report:    &SelftestReport{Mock: options.Mock},
+		directory: filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_SELFTEST),
+	}
+
+	// log event
+	logger.Manager.Package["node"].Info().Msg(fmt.Sprintf("Running the self test with Blender v%v (mock: %v) ...", options.Version, options.Mock))
+
+	test._stage("Create sample scene", false, nm._selftestScene)
+	test._stage("Create render request", false, nm._selftestRequest)
+	test._stage("Deploy render request to IPFS", false, nm._selftestDeploy)
+	test._stage("Submit render request", options.Mock, nm._selftestSubmit)
+	test._stage("Claim render job", false, nm._selftestClaim)
+	test._stage("Render", false, nm._selftestRender)
+	test._stage("Collect render result", false, nm._selftestCollect)
+	test._stage("Submit render result", false, nm._selftestResult)
+
+	// remove the data of the test
+	if !options.Keep {
+		stage := &SelftestStage{Name: "Clean up", CIDs: map[string]string{}, Transactions: []string{}}
+		started := time.Now()
+		stage.Err = nm._selftestCleanup(test)
+		stage.Duration = time.Since(started)
+		test.report.Stages = append(test.report.Stages, stage)
+	}
+
+	// log event
+	logger.Manager.Package["node"].Info().Msg(fmt.Sprintf("Self test finished (passed: %v).", test.report.Passed()))
+
+	return test.report
+
+}
+
+// helper function to create the sample scene or to use the given Blender file
+func (nm *PackageManager) _selftestScene(test *selftest, stage *SelftestStage) error {
+	var err error
+
+	// get the Blender version of the test
+	if len(nm.GetActiveRenderOffers()) == 0 {
+		return newRenderError(ErrOfferNotFound, "The node has no render offer.")
+	}
+	blender, ok := nm._offeredBlender(test.options.Version)
+	if !ok {
+		return newRenderError(ErrUnsupportedVersion, "The node does not support Blender v%v.", test.options.Version)
+	}
+	test.blender = blender
+
+	// start from a clean directory
+	err = os.RemoveAll(test.directory)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(test.directory, 0700)
+	if err != nil {
+		return err
+	}
+
+	// create the sample scene
+	if test.options.File == "" {
+		test.file = filepath.Join(test.directory, "sample.blend")
+		_, err = test.blender._executeScript("", createSampleSceneScript, BLENDER_SCRIPT_CREATE_SAMPLE_SCENE_CID, createSampleScenePrefix, test.file)
+		if err != nil {
+			return err
+		}
+	} else {
+		test.file, err = filepath.Abs(test.options.File)
+		if err != nil {
+			return err
+		}
+	}
+	stage.CIDs["Blender file"], err = ipfs.Manager.GetHashFromPath(test.file)
+
+	return err
+
+}
+
+// helper function to create the render request of the self test
+func (nm *PackageManager) _selftestRequest(test *selftest, stage *SelftestStage) error {
+	var err error
+
+	// create the render request of the sample scene
+	test.request, err = nm.NewRenderRequest(test.options.Version, 0)
+	if err != nil {
+		return err
+	}
+	test.request.BlenderFile.Path = test.file
+	test.request.ThisNode = true
+
+	// get the frame range of the render job from the Blender file
+	_, err = nm.InspectRenderRequest(test.request, nil)
+	if err != nil {
+		return err
+	}
+
+	// overwrite the document of a previous test
+	test.requestID, err = nm.AddRenderRequest(test.request, true)
+	if err != nil {
+		return err
+	}
+	stage.CIDs["Blender file"] = test.request.BlenderFile.CID
+
+	return err
+
+}
+
+// helper function to deploy the render request of the self test to IPFS
+func (nm *PackageManager) _selftestDeploy(test *selftest, stage *SelftestStage) error {
+	var err error
+
+	test.request.BlenderFile.CID, err = ipfs.Manager.AddObjectFromPath(test.request.BlenderFile.Path, true)
+	if err != nil {
+		return err
+	}
+	stage.CIDs["Blender file"] = test.request.BlenderFile.CID
+	test.request.DocumentCID, err = ipfs.Manager.AddObjectFromPath(test.request.DocumentPath, true)
+	if err != nil {
+		return err
+	}
+	stage.CIDs["Render request document"] = test.request.DocumentCID
+
+	return err
+
+}
+
+// helper function to submit the render request of the self test to the job queue topic
+func (nm *PackageManager) _selftestSubmit(test *selftest, stage *SelftestStage) error {
+
+	if nm.JobQueueTopic == nil {
+		return newRenderError(ErrNetworkUnavailable, "Not subscribed to the job queue topic.")
+	}
+	err := nm.SubmitRenderRequest(test.requestID)
+	if test.request.Receipt != nil && test.request.Receipt.TransactionID != nil {
+		stage.Transactions = append(stage.Transactions, test.request.Receipt.TransactionID.String())
+	}
+
+	return err
+
+}
+
+// helper function to claim the render job of the self test on this node
+// NOTE: The job is created from the submitted render request, like the jobs of
+// the job queue topic, but is claimed directly, so no other job is claimed.
+func (nm *PackageManager) _selftestClaim(test *selftest, stage *SelftestStage) error {
+
+	settings := test.request.BlenderFile.Settings
+	jobs := nm.CreateRenderJobs(&SubmitRenderRequestArgs{
+		RenderRequestCID: test.request.DocumentCID,
+		BlenderFileCID:   test.request.BlenderFile.CID,
+		BlenderVersion:   test.request.Version,
+		FrameStart:       settings.FrameStart,
+		FrameEnd:         settings.FrameEnd,
+		FrameStep:        settings.FrameStep,
+		ResolutionX:      settings.ResolutionX,
+		ResolutionY:      settings.ResolutionY,
+		Samples:          settings.Samples,
+	}, time.Now())
+	test.job = jobs[0]
+	test.job.Request.BlenderFile = test.request.BlenderFile
+
+	// claim the job
+	test.job.Claim(nm.EstimateRenderDuration(test.job))
+	test.job.Operator = nm.User.UserAccount.AccountID.String()
+	nm.Renderer.NodeQueue = append(nm.Renderer.NodeQueue, test.job)
+	if test.options.Mock {
+		return nil
+	}
+	err := nm.AnnounceRenderJobClaim(test.job)
+	stage.Transactions = hedera.Manager.History.JobTransactions(test.job.Request.DocumentCID, test.job.SubtaskIndex())
+
+	return err
+
+}
+
+// helper function to render the render job of the self test
+func (nm *PackageManager) _selftestRender(test *selftest, stage *SelftestStage) error {
+
+	// remove the frames of a previous test
+	directory := test.job.OutputDirectory()
+	err := os.RemoveAll(directory)
+	if err != nil {
+		return err
+	}
+
+	// render the frame range of the job into its output directory
+	blender := test.blender
+	test.job.Blender = &blender
+	frames := test.job.FrameSettings()
+	return nm.RenderJobWithRestarts(test.job, func(settings RenderSettings) ([]string, error) {
+		return BlenderRenderArguments(test.job.Request.BlenderFile.Path, filepath.Join(directory, "frame_####"), RenderSettings{
+			FrameStart: frames.FrameStart,
+			FrameEnd:   frames.FrameEnd,
+			FrameStep:  frames.FrameStep,
+			TileX:      settings.TileX,
+			TileY:      settings.TileY,
+		})
+	})
+
+}
+
+// helper function to collect the render result of the self test
+func (nm *PackageManager) _selftestCollect(test *selftest, stage *SelftestStage) error {
+	var err error
+
+	result, document, err := nm.CollectRenderResult(test.job)
+	if err != nil {
+		return err
+	}
+	test.result = result
+	stage.CIDs["Render result"] = result.ResultCID
+	if len(document.MissingFrames) > 0 {
+		return newRenderError(ErrDocumentMismatch, "The render result is missing %v frame(s): %v", len(document.MissingFrames), document.MissingFrames)
+	}
+
+	return err
+
+}
+
+// helper function to submit the render result of the self test
+func (nm *PackageManager) _selftestResult(test *selftest, stage *SelftestStage) error {
+
+	stage.CIDs["Render result"] = test.result.ResultCID
+
+	// complete the job without announcing the result
+	if test.options.Mock {
+		test.job.State = RENDER_JOB_STATE_COMPLETED
+		test.job.Result = test.result
+		nm.Renderer.Busy = false
+		return nil
+	}
+
+	known := len(hedera.Manager.History.JobTransactions(test.job.Request.DocumentCID, test.job.SubtaskIndex()))
+	err := nm.SubmitRenderResult(test.job, test.result)
+	transactions := hedera.Manager.History.JobTransactions(test.job.Request.DocumentCID, test.job.SubtaskIndex())
+	if len(transactions) > known {
+		stage.Transactions = transactions[known:]
+	}
+
+	return err
+
+}
+
+// helper function to remove the data of the self test
+func (nm *PackageManager) _selftestCleanup(test *selftest) error {
+	var errs []error
+
+	// remove the render job from the queue of this node
+	if test.job != nil {
+		if test.job.Blender != nil {
+			errs = append(errs, test.job.Blender.Stop())
+		}
+		queue := []*RenderJob{}
+		for _, job := range nm.Renderer.NodeQueue {
+			if job != test.job {
+				queue = append(queue, job)
+			}
+		}
+		nm.Renderer.NodeQueue = queue
+		nm.Renderer.Busy = false
+		errs = append(errs, os.RemoveAll(test.job.OutputDirectory()))
+		if test.job.Blender != nil && test.job.Blender.Dir != "" {
+			errs = append(errs, os.RemoveAll(test.job.Blender.Dir))
+		}
+	}
+
+	// unpin the render result and the render request
+	cids := []string{}
+	if test.result != nil {
+		cids = append(cids, test.result.ResultCID)
+	}
+	if test.request != nil {
+		cids = append(cids, test.request.DocumentCID)
+		if test.options.File == "" {
+			cids = append(cids, test.request.BlenderFile.CID)
+		}
+	}
+	for _, cid := range cids {
+		if cid == "" {
+			continue
+		}
+		if _, err := ipfs.Manager.UnPinObject(cid); err != nil {
+			errs = append(errs, fmt.Errorf("Could not unpin '%v': %w", cid, err))
+		}
+	}
+
+	// remove the render request
+	if test.request != nil && test.request.DocumentPath != "" {
+		errs = append(errs, nm.RemoveRenderRequest(test.requestID))
+		if err := os.Remove(test.request.DocumentPath); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+
+	// remove the sample scene
+	errs = append(errs, os.RemoveAll(test.directory))
+
+	return errors.Join(errs...)
+
+}
+
+// helper function to run a stage of the self test
+func (test *selftest) _stage(name string, skip bool, run func(test *selftest, stage *SelftestStage) error) {
+
+	stage := &SelftestStage{Name: name, CIDs: map[string]string{}, Transactions: []string{}}
+	test.report.Stages = append(test.report.Stages, stage)
+
+	// skip the stage, if a previous stage failed
+	for _, previous := range test.report.Stages[:len(test.report.Stages)-1] {
+		if previous.Err != nil || (previous.Skipped && !test.report.Mock) {
+			skip = true
+		}
+	}
+	if skip {
+		stage.Skipped = true
+		return
+	}
+
+	started := time.Now()
+	stage.Err = run(test, stage)
+	stage.Duration = time.Since(started)
+
+	// log event
+	if stage.Err != nil {
+		logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Self test stage '%v' failed: %v", name, stage.Err))
+	} else {
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf("Self test stage '%v' succeeded after %v.", name, stage.Duration.Round(time.Millisecond)))
+	}
+
+}
+
+// COMMAND LINE INTERFACE – SELF TEST
+// #############################################################################
+// Create the CLI command to run the self test of the node
+func (nm *PackageManager) CreateCommandSelftest() *cobra.Command {
+
+	// flags for the 'selftest' command
+	var options SelftestOptions
+
+	// create a 'selftest' command for the node
+	command := &cobra.Command{
+		Use:   "selftest",
+		Short: "Run a render request through its whole lifecycle on this node",
+		Long:  "This command creates a render request for a sample scene, deploys it to IPFS, submits it to the job queue topic, claims and renders it on this node, and submits the render result. Each stage is reported with its CIDs and transaction IDs. The mock mode skips the job queue topic.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// a requester-only node does not render
+			err := nm._requireRenderNode("run the self test")
+			if err != nil {
+				logger.Manager.Println("")
+				return err
+			}
+
+			// if no version was passed
+			if options.Version == "" {
+				logger.Manager.Println("")
+				return fmt.Errorf("Cannot run the self test, because no Blender version was passed.")
+			}
+
+			report := nm.RunSelftest(options)
+
+			// print the stages
+			logger.Manager.Println("")
+			if report.Mock {
+				logger.Manager.Println("Self test of this node (mock mode):")
+			} else {
+				logger.Manager.Println("Self test of this node:")
+			}
+			for i, stage := range report.Stages {
+				status := "OK"
+				if stage.Skipped {
+					status = "SKIPPED"
+				} else if stage.Err != nil {
+					status = "FAILED"
+				}
+				logger.Manager.Resultf(" [#] %v. %v: %v (%v)\n", i+1, stage.Name, status, stage.Duration.Round(time.Millisecond))
+				meanings := []string{}
+				for meaning := range stage.CIDs {
+					meanings = append(meanings, meaning)
+				}
+				sort.Strings(meanings)
+				for _, meaning := range meanings {
+					logger.Manager.Resultf("     - %v (CID): %v\n", meaning, stage.CIDs[meaning])
+				}
+				for _, transaction := range stage.Transactions {
+					logger.Manager.Resultf("     - Transaction: %v\n", transaction)
+				}
+				if stage.Err != nil {
+					logger.Manager.Errorln(fmt.Errorf("     - Error: %v", stage.Err))
+				}
+			}
+			logger.Manager.Println("")
+			if !report.Passed() {
+				return fmt.Errorf("The self test failed.")
+			}
+			logger.Manager.Println("The self test passed.")
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	// add command flags
+	command.Flags().StringVarP(&options.Version, "version", "v", "", "The Blender version of the render offer used for the test")
+	command.Flags().StringVarP(&options.File, "file", "f", "", "The Blender file to render instead of the sample scene")
+	command.Flags().BoolVarP(&options.Mock, "mock", "m", false, "Skip the stages that send messages to the job queue topic")
+	command.Flags().BoolVarP(&options.Keep, "keep", "k", false, "Keep the render request, the render result, and the IPFS pins of the test")
+
+	return command
+
+}
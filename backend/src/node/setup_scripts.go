@@ -0,0 +1,238 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the python setup scripts of render requests.
+
+A render request may name a python script on IPFS, which Blender executes with
+its '--python' flag after the Blender file was loaded and before the frames are
+rendered (e.g., to set up the render region or the compositing). Since a python
+script can execute arbitrary code on the render node, a node only executes
+scripts, whose CIDs the node operator trusts. The trusted CIDs are listed in the
+optional 'scripts.json' file of the configuration directory:
+
+    {"trusted_scripts": ["QmTaj51LQzomcJaDMnVasEsWNytU4gdNxbWrCkNkJhGuPL"]}
+
+Without this file, no setup script is trusted and render jobs with a setup
+script are declined. A trusted script is downloaded from IPFS into the app data
+and its content is hashed again before each render, so that only the content of
+the trusted CID is passed to Blender.
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/ipfs"
+	. "renderhive/utility"
+)
+
+// Trusted python setup scripts of this node
+type TrustedScriptSettings struct {
+	TrustedScripts []string `json:"trusted_scripts"` // CIDs of the setup scripts this node executes
+}
+
+// TRUSTED SCRIPTS
+// #############################################################################
+// Read the trusted setup scripts from the configuration file
+func (settings *TrustedScriptSettings) Read() error {
+	var err error
+
+	// Open the configuration file
+	file, err := os.Open(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "scripts.json"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Read the file content
+	fileData, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	// get the file content into a structure
+	err = json.Unmarshal(fileData, settings)
+	if err != nil {
+		return err
+	}
+
+	return settings.Validate()
+
+}
+
+// Check the trusted setup scripts for invalid CIDs
+func (settings *TrustedScriptSettings) Validate() error {
+
+	for _, cid := range settings.TrustedScripts {
+		if _, err := ParseCID(cid); err != nil {
+			return newRenderError(ErrInvalidArgument, "Invalid CID '%v' of a trusted setup script: %w", cid, err)
+		}
+	}
+
+	return nil
+
+}
+
+// Check if the setup script of the given CID is trusted
+func (settings *TrustedScriptSettings) IsTrusted(cid string) bool {
+
+	for _, trusted := range settings.TrustedScripts {
+		if _sameCID(trusted, cid) {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// Get the trusted setup scripts of this node (none, if not configured)
+func (nm *PackageManager) GetTrustedScriptSettings() TrustedScriptSettings {
+
+	// read the configured settings
+	settings := TrustedScriptSettings{}
+	err := settings.Read()
+	if err != nil {
+		return TrustedScriptSettings{}
+	}
+
+	return settings
+
+}
+
+// SETUP SCRIPTS
+// #############################################################################
+// Set the python setup script of the render request (empty = no script)
+func (request *RenderRequest) SetSetupScript(cid string) error {
+
+	// check if the render request was already submitted
+	if request._isSubmitted() {
+		return newRenderError(ErrAlreadySubmitted, "Render request was already submitted and cannot be modified.")
+	}
+
+	if cid != "" {
+		if _, err := ParseCID(cid); err != nil {
+			return newRenderError(ErrInvalidArgument, "Invalid CID '%v' of the setup script: %w", cid, err)
+		}
+	}
+
+	request.SetupScriptCID = cid
+	request._updateModifiedTimestamp()
+
+	return nil
+
+}
+
+// Get the local path of a trusted setup script (downloaded from IPFS, if needed)
+// NOTE: The content of the script is verified against its CID on each call.
+func (nm *PackageManager) FetchSetupScript(cid string) (string, error) {
+	var err error
+
+	// only trusted scripts are downloaded
+	settings := nm.GetTrustedScriptSettings()
+	if !settings.IsTrusted(cid) {
+		return "", newRenderError(ErrUntrustedScript, "Setup script '%v' is not trusted by this node.", cid)
+	}
+	if ipfs.Manager.IpfsAPI == nil {
+		return "", newRenderError(ErrNetworkUnavailable, "Setup script '%v' could not be verified: The IPFS node is not running.", cid)
+	}
+
+	// create the directory, if it does not exist
+	directory := filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_SETUP_SCRIPTS)
+	err = os.MkdirAll(directory, 0700)
+	if err != nil {
+		return "", err
+	}
+
+	// download the script, if it was not downloaded before
+	path := filepath.Join(directory, cid+".py")
+	if _, err = os.Lstat(path); os.IsNotExist(err) {
+		_, err = ipfs.Manager.GetObjectResumable(cid, path, nil)
+		if err != nil {
+			return "", newRenderError(ErrNetworkUnavailable, "Setup script '%v' could not be downloaded: %w", cid, err)
+		}
+	}
+
+	// verify the content of the script
+	err = _verifySetupScript(path, cid)
+	if err != nil {
+		os.RemoveAll(path)
+		return "", err
+	}
+
+	return path, nil
+
+}
+
+// helper function to check if the local file of a setup script matches its CID
+func _verifySetupScript(path string, cid string) error {
+
+	// the script must be a regular file (i.e., no directory or symlink)
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return newRenderError(ErrUntrustedScript, "Setup script '%v' is not a regular file.", cid)
+	}
+
+	// hash the content of the script
+	hash, err := ipfs.Manager.GetHashFromPath(path)
+	if err != nil {
+		return err
+	}
+	if !_sameCID(hash, cid) {
+		return newRenderError(ErrUntrustedScript, "Setup script has an unexpected CID '%v' (expected: %v).", hash, cid)
+	}
+
+	return nil
+
+}
+
+// helper function to add a setup script to the Blender command line arguments
+// NOTE: Blender processes its arguments in order. Thus, the script is executed
+// before the first render argument, i.e. after the Blender file was loaded.
+func _withSetupScript(args []string, script string) []string {
+
+	if script == "" {
+		return args
+	}
+
+	scriptArgs := []string{"--python", script, "--python-exit-code", "1"}
+	for i, arg := range args {
+		if arg == "-f" || arg == "--render-frame" || arg == "-a" || arg == "--render-anim" {
+			return append(append(append([]string{}, args[:i]...), scriptArgs...), args[i:]...)
+		}
+	}
+
+	return append(append([]string{}, args...), scriptArgs...)
+
+}
@@ -0,0 +1,203 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	// external
+	gocid "github.com/ipfs/go-cid"
+
+	// internal
+	. "renderhive/globals"
+)
+
+// python setup script of the tests
+const testSetupScript = "import bpy\nbpy.context.scene.render.use_border = True\n"
+
+// helper function to write the trusted setup scripts of this node
+func _writeTrustedScripts(t *testing.T, settings TrustedScriptSettings) {
+	t.Helper()
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(RENDERHIVE_APP_DIRECTORY_CONFIG, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "scripts.json"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// helper function to get the CIDv1 of a CID
+func _testCIDv1(t *testing.T, cid string) string {
+	t.Helper()
+
+	decoded, err := gocid.Decode(cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return gocid.NewCidV1(decoded.Type(), decoded.Hash()).String()
+}
+
+func TestTrustedScriptAllowlist(t *testing.T) {
+	nm, _ := _testRenderCIDManager(t)
+	script := _testMockIPFS(t, []byte(testSetupScript))
+
+	tests := []struct {
+		name     string
+		settings *TrustedScriptSettings // nil, if there is no configuration file
+		trusted  bool
+	}{
+		{"allowed", &TrustedScriptSettings{TrustedScripts: []string{testResultBlendCID, script}}, true},
+		{"allowed as CIDv1", &TrustedScriptSettings{TrustedScripts: []string{_testCIDv1(t, script)}}, true},
+		{"denied", &TrustedScriptSettings{TrustedScripts: []string{testResultBlendCID}}, false},
+		{"empty list", &TrustedScriptSettings{TrustedScripts: []string{}}, false},
+		{"invalid list", &TrustedScriptSettings{TrustedScripts: []string{script, "not-a-cid"}}, false},
+		{"no configuration", nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			os.RemoveAll(RENDERHIVE_APP_DIRECTORY_CONFIG)
+			if test.settings != nil {
+				_writeTrustedScripts(t, *test.settings)
+			}
+
+			settings := nm.GetTrustedScriptSettings()
+			if trusted := settings.IsTrusted(script); trusted != test.trusted {
+				t.Errorf("got trusted %v, want %v", trusted, test.trusted)
+			}
+
+			// only a trusted script is fetched from IPFS
+			path, err := nm.FetchSetupScript(script)
+			if test.trusted && err != nil {
+				t.Errorf("expected the trusted script to be fetched: %v", err)
+			}
+			if !test.trusted && (!errors.Is(err, ErrUntrustedScript) || path != "") {
+				t.Errorf("got %q (%v), want the untrusted script to be refused", path, err)
+			}
+
+			// a render job with an untrusted script is not claimed
+			_, jobs := _testEstimateJobs(t, 0, 0)
+			jobs[0].Request.SetupScriptCID = script
+			_, err = nm.CheckRenderFeasibility(jobs[0])
+			if test.trusted && err != nil {
+				t.Errorf("expected the job with the trusted script to be feasible: %v", err)
+			}
+			if !test.trusted && !errors.Is(err, ErrJobInfeasible) {
+				t.Errorf("got %v, want the job with the untrusted script to be infeasible", err)
+			}
+		})
+	}
+}
+
+func TestFetchSetupScriptVerifiesTheContent(t *testing.T) {
+	nm, _ := _testRenderCIDManager(t)
+	script := _testMockIPFS(t, []byte(testSetupScript))
+	_writeTrustedScripts(t, TrustedScriptSettings{TrustedScripts: []string{script}})
+
+	// the trusted script is downloaded with its content
+	path, err := nm.FetchSetupScript(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != testSetupScript {
+		t.Fatalf("got %q (%v), want the content of the script", content, err)
+	}
+
+	// a modified local copy is refused and removed
+	if err := os.WriteFile(path, []byte("import os\nos.system('rm -rf ~')\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nm.FetchSetupScript(script); !errors.Is(err, ErrUntrustedScript) {
+		t.Errorf("got %v, want the modified script to be refused", err)
+	}
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the modified script to be removed: %v", err)
+	}
+
+	// the next call downloads the script again
+	if _, err := nm.FetchSetupScript(script); err != nil {
+		t.Errorf("expected the script to be downloaded again: %v", err)
+	}
+
+	// a symlink to another file is refused
+	target := filepath.Join(t.TempDir(), "script.py")
+	if err := os.WriteFile(target, []byte(testSetupScript), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(path)
+	if err := os.Symlink(target, path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nm.FetchSetupScript(script); !errors.Is(err, ErrUntrustedScript) {
+		t.Errorf("got %v, want a symlinked script to be refused", err)
+	}
+}
+
+func TestSetSetupScript(t *testing.T) {
+	request := &RenderRequest{}
+
+	if err := request.SetSetupScript("not-a-cid"); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("got %v, want an invalid CID to be rejected", err)
+	}
+	if err := request.SetSetupScript(testResultBlendCID); err != nil || request.SetupScriptCID != testResultBlendCID {
+		t.Errorf("got %q (%v), want the setup script to be set", request.SetupScriptCID, err)
+	}
+	if err := request.SetSetupScript(""); err != nil || request.SetupScriptCID != "" {
+		t.Errorf("got %q (%v), want the setup script to be removed", request.SetupScriptCID, err)
+	}
+}
+
+func TestWithSetupScript(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"before a frame", []string{"-b", "scene.blend", "-o", "out", "-f", "1"}, []string{"-b", "scene.blend", "-o", "out", "--python", "setup.py", "--python-exit-code", "1", "-f", "1"}},
+		{"before an animation", []string{"-b", "scene.blend", "-a"}, []string{"-b", "scene.blend", "--python", "setup.py", "--python-exit-code", "1", "-a"}},
+		{"without render argument", []string{"-b", "scene.blend"}, []string{"-b", "scene.blend", "--python", "setup.py", "--python-exit-code", "1"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := _withSetupScript(test.args, "setup.py"); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	// without a script the arguments are unchanged
+	args := []string{"-b", "scene.blend", "-f", "1"}
+	if got := _withSetupScript(args, ""); !reflect.DeepEqual(got, args) {
+		t.Errorf("got %v, want %v", got, args)
+	}
+}
@@ -0,0 +1,584 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the decomposition of animation render requests into
+subtasks, which are rendered independently by different nodes.
+
+A requester can set the number of frames per subtask of a render request. When
+the render request is submitted, each node splits its frame range into chunks
+of this size. Each chunk is a render job of its own, which is claimed,
+rendered, released, and submitted independently of the other chunks. The
+subtasks are numbered from 1 in the order of their frames, while the subtask
+number 0 denotes a render request that is rendered as a single job.
+
+Alternatively, a requester can split each frame into a grid of regions (see
+regions.go). Then, each region is a subtask of its own, which renders the full
+frame range but only its part of each frame. The region subtasks are numbered
+from 1 in region order. A render request is either split into frame chunks or
+into regions, but not both.
+
+Subtask life cycle:
+  A node claims a subtask and announces the claim on the job queue topic, so
+  the other nodes skip it. When the node submits the render result of the
+  subtask, the subtask is completed. If the node abandons the subtask, it is
+  released and can be claimed by another node. When all subtasks are
+  completed, the requester aggregates the subtask results into the result of
+  the full animation. The regions of each frame are composited into the full
+  frame.
+
+Pricing:
+  The price of a render request is a price per work unit (BBP). It applies to
+  each subtask, which is paid for the work units it consumed. The share of a
+  subtask is its fraction of the frames of the render request and is used to
+  estimate the cost and the render time of the subtask. The share of a region
+  subtask is its fraction of the regions of a frame.
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	// external
+	"github.com/spf13/cobra"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/ipfs"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// Subtask of a render request (a chunk of its frame range)
+type RenderSubtask struct {
+	Index      int     // Number of the subtask (starting at 1)
+	FrameStart int     // First frame of the subtask
+	FrameEnd   int     // Last frame of the subtask
+	FrameStep  int     // Number of frames between two rendered frames
+	Share      float64 // Fraction of the frames of the render request (0 ... 1)
+
+	// Region of each frame rendered by the subtask
+	Region *RenderRegion `json:",omitempty"` // nil, if the full frames are rendered
+}
+
+// Progress of the subtasks of a render request
+type RenderSubtaskStatus struct {
+	Total     int // Number of subtasks
+	Queued    int // Subtasks waiting for a node
+	Claimed   int // Subtasks claimed or rendered by a node
+	Completed int // Subtasks with a submitted render result
+	Flagged   int // Subtasks that timed out too often
+}
+
+// RENDER SUBTASKS
+// #############################################################################
+// Set the number of frames per subtask of the render request (0 = single job)
+func (request *RenderRequest) SetFramesPerTask(frames int) error {
+
+	// check if the render request was already submitted
+	if request._isSubmitted() {
+		return newRenderError(ErrAlreadySubmitted, "Render request was already submitted and cannot be modified.")
+	}
+
+	if frames < 0 {
+		return newRenderError(ErrInvalidArgument, "Number of frames per subtask '%v' must not be negative.", frames)
+	}
+	if frames > 0 && request.RegionRows*request.RegionColumns > 0 {
+		return newRenderError(ErrInvalidArgument, "Render request cannot be split into frame chunks and regions at the same time.")
+	}
+
+	request.FramesPerTask = frames
+	request._updateModifiedTimestamp()
+
+	return nil
+
+}
+
+// Set the grid of regions each frame of the render request is split into (0 = full frame)
+// NOTE: If only one dimension is given, the other dimension is not split.
+func (request *RenderRequest) SetRegionGrid(rows int, columns int) error {
+
+	// check if the render request was already submitted
+	if request._isSubmitted() {
+		return newRenderError(ErrAlreadySubmitted, "Render request was already submitted and cannot be modified.")
+	}
+
+	if rows < 0 || columns < 0 {
+		return newRenderError(ErrInvalidArgument, "Region grid '%vx%v' must not be negative.", rows, columns)
+	}
+
+	// do not split the frames
+	if rows == 0 && columns == 0 {
+		request.RegionRows = 0
+		request.RegionColumns = 0
+		request._updateModifiedTimestamp()
+		return nil
+	}
+
+	if request.FramesPerTask > 0 {
+		return newRenderError(ErrInvalidArgument, "Render request cannot be split into frame chunks and regions at the same time.")
+	}
+	if rows == 0 {
+		rows = 1
+	}
+	if columns == 0 {
+		columns = 1
+	}
+
+	request.RegionRows = rows
+	request.RegionColumns = columns
+	request._updateModifiedTimestamp()
+
+	return nil
+
+}
+
+// Split the frames of a frame range into region subtasks
+// NOTE: Returns no subtasks, if the frames are not split into regions.
+func SplitFrameRegions(start int, end int, step int, rows int, columns int) []RenderSubtask {
+
+	subtasks := []RenderSubtask{}
+	if rows*columns <= 1 {
+		return subtasks
+	}
+	regions, err := SplitFrame(rows, columns)
+	if err != nil {
+		return subtasks
+	}
+
+	// each subtask renders one region of all frames of the range
+	for i := range regions {
+		subtasks = append(subtasks, RenderSubtask{
+			Index:      regions[i].Index + 1,
+			FrameStart: start,
+			FrameEnd:   end,
+			FrameStep:  step,
+			Share:      1.0 / float64(len(regions)),
+			Region:     &regions[i],
+		})
+	}
+
+	return subtasks
+
+}
+
+// Split a frame range into subtasks with the given number of frames
+// NOTE: Returns no subtasks, if the frame range is invalid or not split.
+func SplitFrameRange(start int, end int, step int, framesPerTask int) []RenderSubtask {
+
+	subtasks := []RenderSubtask{}
+	if framesPerTask < 1 || end < start {
+		return subtasks
+	}
+	if step < 1 {
+		step = 1
+	}
+
+	// each subtask covers 'framesPerTask' rendered frames of the range
+	total := (end-start)/step + 1
+	for first, index := start, 1; first <= end; first, index = first+framesPerTask*step, index+1 {
+		last := first + (framesPerTask-1)*step
+		if last > end {
+			last = end
+		}
+		subtasks = append(subtasks, RenderSubtask{
+			Index:      index,
+			FrameStart: first,
+			FrameEnd:   last,
+			FrameStep:  step,
+			Share:      float64((last-first)/step+1) / float64(total),
+		})
+	}
+
+	return subtasks
+
+}
+
+// Get the number of the subtask of the render job (0, if the job is not split)
+func (job *RenderJob) SubtaskIndex() int {
+
+	if job.Subtask == nil {
+		return 0
+	}
+
+	return job.Subtask.Index
+
+}
+
+// Create the render jobs of a render request submitted to the render hive
+func (nm *PackageManager) CreateRenderJobs(args *SubmitRenderRequestArgs, submitted time.Time) []*RenderJob {
+
+	// the render request shared by all subtasks
+	request := &RenderRequest{
+		DocumentCID:        args.RenderRequestCID,
+		SubmittedTimestamp: submitted,
+		Version:            args.BlenderVersion,
+		Priority:           args.Priority,
+		Deadline:           _timeFromUnix(args.Deadline),
+		FramesPerTask:      args.FramesPerTask,
+		RegionRows:         args.RegionRows,
+		RegionColumns:      args.RegionColumns,
+		SetupScriptCID:     args.SetupScriptCID,
+	}
+	request.BlenderFile.CID = args.BlenderFileCID
+	request.BlenderFile.Settings.FrameStart = args.FrameStart
+	request.BlenderFile.Settings.FrameEnd = args.FrameEnd
+	request.BlenderFile.Settings.FrameStep = args.FrameStep
+	request.BlenderFile.Settings.ResolutionX = args.ResolutionX
+	request.BlenderFile.Settings.ResolutionY = args.ResolutionY
+	request.BlenderFile.Settings.Samples = args.Samples
+	request.BlenderFile.Size = args.BlenderFileSize
+
+	// render the request as a single job, if it is not split
+	subtasks := SplitFrameRegions(args.FrameStart, args.FrameEnd, args.FrameStep, args.RegionRows, args.RegionColumns)
+	if len(subtasks) == 0 {
+		subtasks = SplitFrameRange(args.FrameStart, args.FrameEnd, args.FrameStep, args.FramesPerTask)
+	}
+	if len(subtasks) == 0 {
+		return []*RenderJob{{Request: request}}
+	}
+
+	jobs := []*RenderJob{}
+	for i := range subtasks {
+		jobs = append(jobs, &RenderJob{Request: request, Subtask: &subtasks[i]})
+	}
+
+	return jobs
+
+}
+
+// Get the render settings of the render job with the frame range and region of its subtask
+// NOTE: Regions are rendered as PNG files, which are composited into the full frames.
+func (job *RenderJob) FrameSettings() RenderSettings {
+
+	settings := job.Request.BlenderFile.Settings
+	if job.Subtask != nil {
+		settings.FrameStart = job.Subtask.FrameStart
+		settings.FrameEnd = job.Subtask.FrameEnd
+		settings.FrameStep = job.Subtask.FrameStep
+		if job.Subtask.Region != nil {
+			region := *job.Subtask.Region
+			settings.Region = &region
+			settings.FileFormat = "PNG"
+		}
+	}
+
+	return settings
+
+}
+
+// Get a render job of the render hive queue by its render request and subtask
+func (nm *PackageManager) GetNetworkJob(requestCID string, subtask int) (*RenderJob, bool) {
+
+	for _, job := range nm.NetworkQueue {
+		if job.Request.DocumentCID == requestCID && job.SubtaskIndex() == subtask {
+			return job, true
+		}
+	}
+
+	return nil, false
+
+}
+
+// Get the progress of the subtasks of a render request in the render hive queue
+func (nm *PackageManager) GetSubtaskStatus(requestCID string) RenderSubtaskStatus {
+
+	status := RenderSubtaskStatus{}
+	for _, job := range nm.NetworkQueue {
+		if job.Request.DocumentCID != requestCID {
+			continue
+		}
+
+		status.Total += 1
+		switch job.State {
+		case RENDER_JOB_STATE_CLAIMED, RENDER_JOB_STATE_RENDERING:
+			status.Claimed += 1
+		case RENDER_JOB_STATE_COMPLETED:
+			status.Completed += 1
+		default:
+			status.Queued += 1
+		}
+		if job.Flagged {
+			status.Flagged += 1
+		}
+	}
+
+	return status
+
+}
+
+// Announce the claim of a render job on the job queue topic
+func (nm *PackageManager) AnnounceRenderJobClaim(job *RenderJob) error {
+	var err error
+
+	jsonMessage, err := nm.EncodeCommand(
+		[]string{},
+		SERVICE_NODE,
+		METHOD_NODE_ANNOUNCE_RENDER_JOB_CLAIM,
+		&AnnounceRenderJobClaimArgs{
+			RenderRequestCID: job.Request.DocumentCID,
+			Subtask:          job.SubtaskIndex(),
+			Deadline:         _unixTime(job.Deadline),
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if nm.JobQueueTopic == nil {
+		return newRenderError(ErrNetworkUnavailable, "Render job claim could not be announced: Not subscribed to the job queue topic.")
+	}
+	receipt, _, err := nm.JobQueueTopic.SubmitMessage(jsonMessage, "renderhive-v0.1.0::claim-render-job", nil)
+	if err != nil {
+		return newRenderError(ErrTransactionFailed, "Render job claim could not be announced: %w.", err)
+	}
+	_accountJobTransaction(job, receipt)
+
+	return err
+
+}
+
+// RESULT AGGREGATION
+// #############################################################################
+// Aggregate the render results of all subtasks into the render result of the render request
+// NOTE: Each subtask result is verified against the frames of its subtask. The
+// regions of each frame are composited into the full frame. The aggregated
+// result directory is added to the local IPFS node.
+func (nm *PackageManager) AggregateRenderResults(requestCID string) (string, *RenderResultDocument, error) {
+	var err error
+
+	// check the CID
+	if _, err = ParseCID(requestCID); err != nil {
+		return "", nil, newRenderError(ErrInvalidArgument, "Invalid render request CID: %w", err)
+	}
+
+	// all subtasks must be completed
+	jobs := []*RenderJob{}
+	for _, job := range nm.NetworkQueue {
+		if _sameCID(job.Request.DocumentCID, requestCID) && job.Subtask != nil {
+			jobs = append(jobs, job)
+		}
+	}
+	if len(jobs) == 0 {
+		return "", nil, newRenderError(ErrRequestNotFound, "Render request '%v' has no subtasks in the render hive queue.", requestCID)
+	}
+	for _, job := range jobs {
+		if job.State != RENDER_JOB_STATE_COMPLETED || job.Result == nil {
+			return "", nil, newRenderError(ErrInvalidArgument, "Subtask %v of render request '%v' is not completed yet.", job.Subtask.Index, requestCID)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Subtask.Index < jobs[j].Subtask.Index })
+
+	// log event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Aggregating the render results of %v subtasks of render request '%v' ...", len(jobs), requestCID))
+
+	// download into a temporary directory
+	directory, err := os.MkdirTemp(RENDERHIVE_APP_DIRECTORY_TEMP, "renderhive_aggregate_*")
+	if err != nil {
+		return "", nil, err
+	}
+	defer os.RemoveAll(directory)
+	aggregatePath := filepath.Join(directory, "result")
+	err = os.Mkdir(aggregatePath, 0700)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// get the render request
+	request, err := nm._verificationRequest(requestCID, directory)
+	if err != nil {
+		return "", nil, err
+	}
+	aggregate := &RenderResultDocument{
+		RenderRequestCID:  requestCID,
+		BlenderFileCID:    request.BlenderFile.CID,
+		OperatorAccountID: nm.User.UserAccount.AccountID.String(),
+		CreatedTimestamp:  time.Now(),
+		Frames:            []RenderResultFrame{},
+	}
+
+	// collect the frames of each subtask result
+	regions := make(map[int][]RenderRegion)
+	for _, job := range jobs {
+		subtaskPath := filepath.Join(directory, fmt.Sprintf("subtask-%v", job.Subtask.Index))
+		_, err = ipfs.Manager.GetObject(job.Result.ResultCID, subtaskPath)
+		if err != nil {
+			return "", nil, newRenderError(ErrDocumentNotFound, "Render result of subtask %v could not be downloaded: %w", job.Subtask.Index, err)
+		}
+
+		// verify the result against the frames of the subtask
+		document, err := _readResultDocument(subtaskPath)
+		if err != nil {
+			return "", nil, err
+		}
+		subtaskRequest := *request
+		subtaskRequest.BlenderFile.Settings.FrameStart = job.Subtask.FrameStart
+		subtaskRequest.BlenderFile.Settings.FrameEnd = job.Subtask.FrameEnd
+		subtaskRequest.BlenderFile.Settings.FrameStep = job.Subtask.FrameStep
+		verification, err := VerifyRenderResultFiles(&subtaskRequest, document, subtaskPath, ipfs.Manager.GetHashFromPath)
+		if err != nil {
+			return "", nil, err
+		}
+		if !verification.Passed {
+			return "", nil, newRenderError(ErrDocumentMismatch, "Render result '%v' of subtask %v did not pass the verification.", job.Result.ResultCID, job.Subtask.Index)
+		}
+
+		// collect the regions of each frame to composite them afterwards
+		if job.Subtask.Region != nil {
+			for _, frame := range document.Frames {
+				region := *job.Subtask.Region
+				region.CID = frame.CID
+				region.Path = filepath.Join(subtaskPath, frame.File)
+				regions[frame.Frame] = append(regions[frame.Frame], region)
+			}
+			continue
+		}
+
+		// move the frames into the aggregated result directory
+		for _, frame := range document.Frames {
+			file := fmt.Sprintf("%v-%v", job.Subtask.Index, filepath.Base(frame.File))
+			err = os.Rename(filepath.Join(subtaskPath, frame.File), filepath.Join(aggregatePath, file))
+			if err != nil {
+				return "", nil, err
+			}
+			aggregated := RenderResultFrame{Frame: frame.Frame, File: file, CID: frame.CID}
+
+			// keep the preview of the frame (if any)
+			if frame.Preview != "" && filepath.IsLocal(frame.Preview) {
+				preview := filepath.ToSlash(filepath.Join(RENDERHIVE_RESULT_PREVIEW_DIRECTORY, fmt.Sprintf("%v-%v", job.Subtask.Index, filepath.Base(frame.Preview))))
+				err = os.MkdirAll(filepath.Join(aggregatePath, RENDERHIVE_RESULT_PREVIEW_DIRECTORY), 0700)
+				if err == nil {
+					err = os.Rename(filepath.Join(subtaskPath, frame.Preview), filepath.Join(aggregatePath, preview))
+				}
+				if err != nil {
+					logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Could not keep the preview of frame %v: %v", frame.Frame, err))
+				} else {
+					aggregated.Preview = preview
+					aggregated.PreviewCID = frame.PreviewCID
+				}
+			}
+			aggregate.Frames = append(aggregate.Frames, aggregated)
+		}
+	}
+
+	// composite the regions of each frame into the full frame
+	for number, frameRegions := range regions {
+		if len(frameRegions) != len(jobs) {
+			return "", nil, newRenderError(ErrDocumentMismatch, "Frame %v of render request '%v' has %v of %v regions.", number, requestCID, len(frameRegions), len(jobs))
+		}
+		settings := request.BlenderFile.Settings
+		if settings.ResolutionX < 1 || settings.ResolutionY < 1 {
+			return "", nil, newRenderError(ErrInvalidArgument, "Regions of render request '%v' could not be composited: Unknown resolution.", requestCID)
+		}
+		file := fmt.Sprintf("frame-%04d.png", number)
+		hash, err := CompositeRegions(frameRegions, settings.ResolutionX, settings.ResolutionY, filepath.Join(aggregatePath, file))
+		if err != nil {
+			return "", nil, newRenderError(ErrDocumentMismatch, "Regions of frame %v could not be composited: %w", number, err)
+		}
+		cid, err := ipfs.Manager.GetHashFromPath(filepath.Join(aggregatePath, file))
+		if err != nil {
+			return "", nil, err
+		}
+		aggregate.Frames = append(aggregate.Frames, RenderResultFrame{Frame: number, File: file, CID: cid, RegionsHash: hash})
+	}
+	sort.Slice(aggregate.Frames, func(i, j int) bool { return aggregate.Frames[i].Frame < aggregate.Frames[j].Frame })
+
+	// write the result document and add the directory to IPFS
+	data, err := json.MarshalIndent(aggregate, "", "  ")
+	if err != nil {
+		return "", nil, err
+	}
+	err = os.WriteFile(filepath.Join(aggregatePath, RENDERHIVE_RESULT_DOCUMENT_FILENAME), data, 0644)
+	if err != nil {
+		return "", nil, err
+	}
+	resultCID, err := ipfs.Manager.AddObjectFromPath(aggregatePath, true)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// log event
+	logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] Aggregated render result of %v frames: %v", len(aggregate.Frames), resultCID))
+
+	return resultCID, aggregate, nil
+
+}
+
+// COMMAND LINE INTERFACE - RESULT AGGREGATION
+// #############################################################################
+// Create the CLI command to aggregate the subtask results of a render request
+func (nm *PackageManager) CreateCommandRequest_Aggregate() *cobra.Command {
+
+	// flags for the 'request aggregate' command
+	var requestCID string
+
+	// create a 'request aggregate' command for the node
+	command := &cobra.Command{
+		Use:   "aggregate",
+		Short: "Aggregate the subtask results of a render request",
+		Long:  "This command is for aggregating the render results of all subtasks of a render request into the render result of the full animation. Each subtask result is verified before it is added.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// check the required parameters
+			if requestCID == "" {
+
+				logger.Manager.Println("")
+				return fmt.Errorf("Failed to aggregate the render results: Missing a required parameter: Render request CID (--request).")
+
+			}
+
+			// print the progress of the subtasks
+			status := nm.GetSubtaskStatus(requestCID)
+			logger.Manager.Println("")
+			logger.Manager.Printf("Subtasks of render request '%v':\n", requestCID)
+			logger.Manager.Resultf(" [#] Total: %v (queued: %v, claimed: %v, completed: %v, flagged: %v) \n", status.Total, status.Queued, status.Claimed, status.Completed, status.Flagged)
+
+			// aggregate the render results
+			resultCID, document, err := nm.AggregateRenderResults(requestCID)
+			if err != nil {
+
+				return fmt.Errorf("Could not aggregate the render results: %w", err)
+
+			}
+
+			logger.Manager.Resultf(" [#] Aggregated render result (CID): %v \n", resultCID)
+			logger.Manager.Resultf(" [#] Frames: %v \n", len(document.Frames))
+			logger.Manager.Println("")
+
+			return nil
+
+		},
+	}
+
+	// add command flag parameters
+	command.Flags().StringVarP(&requestCID, "request", "r", "", "The CID of the render request document")
+
+	return command
+
+}
@@ -0,0 +1,183 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the processing of the messages of a topic subscription.
+
+The subscription handler of the Hedera SDK must return quickly, so that the
+subscription stream does not back up. Therefore, the handler only puts the
+received messages into a buffer and returns. The messages are processed in two
+stages:
+
+  (1) A single dispatcher processes the messages one by one in the order of
+      their consensus timestamps. It updates the state of the node (e.g., the
+      network queue), which depends on the order of the messages (e.g., a
+      claim follows the render request it claims).
+
+  (2) The slow background tasks of a message (e.g., pinning the documents to
+      the local IPFS node) are handed to a fixed number of workers, which run
+      the tasks concurrently and in any order. Each task is canceled after the
+      message timeout.
+
+The number of goroutines is bounded by the number of workers. When the buffers
+are full, the subscription handler waits, until the dispatcher caught up.
+
+*/
+
+import (
+
+	// standard
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	"renderhive/logger"
+)
+
+// Processor of the messages of a topic subscription
+type TopicMessageProcessor struct {
+	ctx      context.Context                      // stops the processing (e.g., when the app shuts down)
+	messages chan hederasdk.TopicMessage          // received messages, which wait for the dispatcher
+	tasks    chan topicTask                       // background tasks, which wait for a worker
+	process  func(message hederasdk.TopicMessage) // processes a message in the dispatcher
+	timeout  time.Duration                        // time limit of a background task
+	workers  int                                  // number of workers
+}
+
+// Background task of a topic message
+type topicTask struct {
+	name string                          // description of the task for the log
+	run  func(ctx context.Context) error // the task (must stop, when the context is canceled)
+}
+
+// TOPIC MESSAGE PROCESSING
+// #############################################################################
+// Create a processor and start its dispatcher and workers
+func NewTopicMessageProcessor(ctx context.Context, workers int, timeout time.Duration, buffer int, process func(message hederasdk.TopicMessage)) *TopicMessageProcessor {
+
+	if workers < 1 {
+		workers = 1
+	}
+	processor := &TopicMessageProcessor{
+		ctx:      ctx,
+		messages: make(chan hederasdk.TopicMessage, buffer),
+		tasks:    make(chan topicTask, buffer),
+		process:  process,
+		timeout:  timeout,
+		workers:  workers,
+	}
+
+	go processor._dispatch()
+	for i := 0; i < workers; i++ {
+		go processor._work()
+	}
+
+	return processor
+
+}
+
+// Put a received message into the buffer of the processor
+// NOTE: This is the subscription handler. It only waits, if the buffer is full.
+func (processor *TopicMessageProcessor) Handle(message hederasdk.TopicMessage) {
+
+	select {
+	case processor.messages <- message:
+		return
+	default:
+	}
+
+	// log event
+	logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("The buffer of %v topic messages is full. Waiting for the processing ...", cap(processor.messages)))
+
+	select {
+	case processor.messages <- message:
+	case <-processor.ctx.Done():
+	}
+
+}
+
+// Hand a background task of the current message to the workers
+// NOTE: Waits, if the task buffer is full, so that the dispatcher slows down.
+func (processor *TopicMessageProcessor) Submit(name string, run func(ctx context.Context) error) {
+
+	select {
+	case processor.tasks <- topicTask{name: name, run: run}:
+	case <-processor.ctx.Done():
+	}
+
+}
+
+// Get the number of messages and tasks, which wait for processing
+func (processor *TopicMessageProcessor) Pending() (int, int) {
+
+	return len(processor.messages), len(processor.tasks)
+
+}
+
+// helper function to process the messages in the order they were received
+func (processor *TopicMessageProcessor) _dispatch() {
+
+	for {
+		select {
+		case message := <-processor.messages:
+			processor.process(message)
+		case <-processor.ctx.Done():
+			return
+		}
+	}
+
+}
+
+// helper function to run the background tasks
+func (processor *TopicMessageProcessor) _work() {
+
+	for {
+		select {
+		case task := <-processor.tasks:
+			processor._run(task)
+		case <-processor.ctx.Done():
+			return
+		}
+	}
+
+}
+
+// helper function to run a background task with the time limit
+func (processor *TopicMessageProcessor) _run(task topicTask) {
+
+	ctx, cancel := context.WithTimeout(processor.ctx, processor.timeout)
+	defer cancel()
+
+	err := task.run(ctx)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("Canceled the task '%v' of a topic message after %v.", task.name, processor.timeout))
+	} else if err != nil {
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf("The task '%v' of a topic message failed: %v", task.name, err))
+	}
+
+}
@@ -0,0 +1,169 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	"renderhive/logger"
+)
+
+func TestTopicMessageBurstDoesNotBlockOrGrowGoroutines(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const workers, burst = 4, 200
+	before := runtime.NumGoroutine()
+
+	// each message has a slow background task, which waits for the release
+	release := make(chan struct{})
+	var running, maxRunning, done atomic.Int32
+	var mutex sync.Mutex
+	var order []uint64
+	var processor *TopicMessageProcessor
+	processor = NewTopicMessageProcessor(ctx, workers, time.Minute, burst, func(message hederasdk.TopicMessage) {
+		mutex.Lock()
+		order = append(order, message.SequenceNumber)
+		mutex.Unlock()
+		processor.Submit("pin", func(ctx context.Context) error {
+			n := running.Add(1)
+			for {
+				max := maxRunning.Load()
+				if n <= max || maxRunning.CompareAndSwap(max, n) {
+					break
+				}
+			}
+			<-release
+			running.Add(-1)
+			done.Add(1)
+			return nil
+		})
+	})
+
+	// the subscription handler returns quickly for the whole burst
+	started := time.Now()
+	for i := 1; i <= burst; i++ {
+		processor.Handle(hederasdk.TopicMessage{SequenceNumber: uint64(i)})
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("the subscription handler was blocked for %v", elapsed)
+	}
+
+	// the goroutines are bounded by the number of workers
+	deadline := time.Now().Add(5 * time.Second)
+	for running.Load() < workers && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if growth := runtime.NumGoroutine() - before; growth > workers+1 {
+		t.Errorf("expected at most %v new goroutines, got %v", workers+1, growth)
+	}
+
+	// all tasks finish after the release (at most one per worker at a time)
+	close(release)
+	for done.Load() < burst && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if done.Load() != burst {
+		t.Fatalf("expected %v finished tasks, got %v", burst, done.Load())
+	}
+	if maxRunning.Load() > workers {
+		t.Errorf("expected at most %v concurrent tasks, got %v", workers, maxRunning.Load())
+	}
+
+	// the messages were processed in the order they were received
+	mutex.Lock()
+	defer mutex.Unlock()
+	for i, sequence := range order {
+		if sequence != uint64(i+1) {
+			t.Fatalf("expected message %v at position %v, got %v", i+1, i, sequence)
+		}
+	}
+}
+
+func TestTopicMessageTaskTimeout(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// a task, which does not finish by itself, is canceled after the timeout
+	result := make(chan error, 1)
+	var processor *TopicMessageProcessor
+	processor = NewTopicMessageProcessor(ctx, 1, 10*time.Millisecond, 1, func(message hederasdk.TopicMessage) {
+		processor.Submit("hanging", func(ctx context.Context) error {
+			<-ctx.Done()
+			result <- ctx.Err()
+			return ctx.Err()
+		})
+	})
+	processor.Handle(hederasdk.TopicMessage{SequenceNumber: 1})
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the task to time out, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the task was not canceled after the timeout")
+	}
+}
+
+func TestTopicMessageHandlerStopsWithTheContext(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	logger.Manager.Init()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// the dispatcher is blocked, so the buffer fills up
+	release := make(chan struct{})
+	defer close(release)
+	processor := NewTopicMessageProcessor(ctx, 1, time.Minute, 1, func(message hederasdk.TopicMessage) {
+		<-release
+	})
+	processor.Handle(hederasdk.TopicMessage{SequenceNumber: 1})
+	processor.Handle(hederasdk.TopicMessage{SequenceNumber: 2})
+
+	// a full buffer blocks the handler only until the processing stops
+	returned := make(chan struct{})
+	go func() {
+		processor.Handle(hederasdk.TopicMessage{SequenceNumber: 3})
+		close(returned)
+	}()
+	cancel()
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the handler was still blocked after the processing stopped")
+	}
+}
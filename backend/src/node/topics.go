@@ -0,0 +1,372 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the settings of the render job queue topic.
+
+The job queue topic of each Hedera network can be configured in 'jobqueue.json'
+(e.g., to test against an isolated topic), together with the time from which
+the node replays the messages of the topic, when it subscribes:
+
+  - "beginning": all messages since the creation of the topic (default)
+  - "now":       only messages sent after the subscription
+  - "last":      messages after the last message processed by this node
+  - a time in RFC 3339 format (e.g., "2024-05-01T00:00:00Z")
+
+The settings are validated when the node starts and an invalid topic ID or
+start time stops the start. The topic itself is queried, when the operator signs
+in, since the query is paid by the operator account.
+
+The consensus timestamp of the last processed message is stored in the data
+directory. It is written at most every few seconds and when the node shuts
+down, so a crash may only cause a few messages to be processed again. The
+network queue and the render offers of the other nodes are only kept in memory.
+Therefore, with "last", the messages up to the last processed message are
+replayed to rebuild this state, but their background tasks (e.g., pinning) and
+events are skipped.
+
+The background tasks of the messages (e.g., pinning the documents) run on a
+bounded number of workers with a time limit per message:
+
+    {"workers": 4, "message_timeout": "2m"}
+
+(see topic_processing.go).
+
+*/
+
+import (
+
+	// standard
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	// external
+	hederasdk "github.com/hashgraph/hedera-sdk-go/v2"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/hedera"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// start times of the job queue subscription
+const (
+	JOB_QUEUE_START_BEGINNING = "beginning" // all messages of the topic
+	JOB_QUEUE_START_NOW       = "now"       // only new messages
+	JOB_QUEUE_START_LAST      = "last"      // messages after the last processed message
+)
+
+// Settings of the render job queue topic
+type JobQueueSettings struct {
+	Topics         map[string]string `json:"topics"`          // topic ID by Hedera network ("testnet", "previewnet", or "mainnet")
+	Start          string            `json:"start"`           // start time of the subscription ("beginning", "now", "last", or a time in RFC 3339 format)
+	Workers        int               `json:"workers"`         // number of workers for the background tasks of the messages (e.g., pinning)
+	MessageTimeout string            `json:"message_timeout"` // time limit of the background tasks of a message (e.g., "2m")
+}
+
+// Last processed message of the job queue topic
+type jobQueueState struct {
+	LastConsensusTimestamp time.Time `json:"last_consensus_timestamp"`
+
+	mutex          sync.Mutex
+	replayUntil    time.Time // messages up to this time were processed before the start
+	savedTimestamp time.Time // time of the last write of the state
+	unsaved        bool      // the last processed message was not written yet
+}
+
+// JOB QUEUE SETTINGS
+// #############################################################################
+// Get the default settings of the render job queue topic
+func DefaultJobQueueSettings() JobQueueSettings {
+	return JobQueueSettings{
+		Topics:         map[string]string{"testnet": RENDERHIVE_TESTNET_RENDER_JOB_QUEUE},
+		Start:          JOB_QUEUE_START_BEGINNING,
+		Workers:        RENDERHIVE_CONFIG_JOB_QUEUE_WORKERS,
+		MessageTimeout: RENDERHIVE_CONFIG_JOB_QUEUE_MESSAGE_TIMEOUT.String(),
+	}
+}
+
+// Read the job queue settings from the configuration file
+func (settings *JobQueueSettings) Read() error {
+	var err error
+
+	// Open the configuration file
+	file, err := os.Open(filepath.Join(RENDERHIVE_APP_DIRECTORY_CONFIG, "jobqueue.json"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Read the file content
+	fileData, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	// get the file content into a structure
+	err = json.Unmarshal(fileData, settings)
+	if err != nil {
+		return err
+	}
+
+	return settings.Validate()
+
+}
+
+// Check the job queue settings for invalid values
+func (settings *JobQueueSettings) Validate() error {
+
+	for network, topicID := range settings.Topics {
+		if network != "testnet" && network != "previewnet" && network != "mainnet" {
+			return newRenderError(ErrInvalidArgument, "Unknown Hedera network '%v' of the job queue topic.", network)
+		}
+		if topicID == "" {
+			continue
+		}
+		if _, err := hederasdk.TopicIDFromString(topicID); err != nil {
+			return newRenderError(ErrInvalidArgument, "Invalid job queue topic ID '%v' for the %v: %w", topicID, network, err)
+		}
+	}
+
+	if settings.Workers < 0 {
+		return newRenderError(ErrInvalidArgument, "The number of job queue workers must not be negative.")
+	}
+	if _, err := settings.Timeout(); err != nil {
+		return err
+	}
+
+	_, err := settings.StartTime()
+
+	return err
+
+}
+
+// Get the number of workers for the background tasks of the messages
+func (settings *JobQueueSettings) WorkerCount() int {
+
+	if settings.Workers == 0 {
+		return RENDERHIVE_CONFIG_JOB_QUEUE_WORKERS
+	}
+
+	return settings.Workers
+
+}
+
+// Get the time limit of the background tasks of a message
+func (settings *JobQueueSettings) Timeout() (time.Duration, error) {
+
+	if settings.MessageTimeout == "" {
+		return RENDERHIVE_CONFIG_JOB_QUEUE_MESSAGE_TIMEOUT, nil
+	}
+
+	timeout, err := time.ParseDuration(settings.MessageTimeout)
+	if err != nil {
+		return 0, newRenderError(ErrInvalidArgument, "Invalid job queue message timeout '%v': %w", settings.MessageTimeout, err)
+	}
+	if timeout <= 0 {
+		return 0, newRenderError(ErrInvalidArgument, "The job queue message timeout must be positive.")
+	}
+
+	return timeout, nil
+
+}
+
+// Get the topic ID of the job queue topic of a Hedera network (empty, if there is none)
+func (settings *JobQueueSettings) TopicID(network string) string {
+
+	return strings.TrimSpace(settings.Topics[network])
+
+}
+
+// Get the start time of the subscription
+// NOTE: With "last", the subscription starts at the beginning, so that the
+// processed messages rebuild the state of the node (see _isReplayedJobQueueMessage).
+func (settings *JobQueueSettings) StartTime() (time.Time, error) {
+
+	switch settings.Start {
+	case "", JOB_QUEUE_START_BEGINNING, JOB_QUEUE_START_LAST:
+		return time.Unix(0, 0), nil
+	case JOB_QUEUE_START_NOW:
+		return time.Now(), nil
+	}
+
+	start, err := time.Parse(time.RFC3339, settings.Start)
+	if err != nil {
+		return time.Time{}, newRenderError(ErrInvalidArgument, "Invalid start time '%v' of the job queue subscription (expected '%v', '%v', '%v', or a time in RFC 3339 format).", settings.Start, JOB_QUEUE_START_BEGINNING, JOB_QUEUE_START_NOW, JOB_QUEUE_START_LAST)
+	}
+
+	return start, nil
+
+}
+
+// Load the job queue settings of this node (the default settings, if none are configured)
+func (nm *PackageManager) LoadJobQueueSettings() error {
+
+	settings := DefaultJobQueueSettings()
+	err := settings.Read()
+	if errors.Is(err, os.ErrNotExist) {
+		settings = DefaultJobQueueSettings()
+	} else if err != nil {
+		return err
+	}
+	nm.JobQueueSettings = settings
+
+	// log event
+	topicID := settings.TopicID(hedera.Manager.NetworkName())
+	if topicID == "" {
+		logger.Manager.Package["node"].Warn().Msg(fmt.Sprintf("No job queue topic is configured for the %v.", hedera.Manager.NetworkName()))
+	} else {
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf("Using the job queue topic %v (start: %v).", topicID, settings.Start))
+	}
+
+	return nil
+
+}
+
+// Get the topic ID of the job queue topic on the Hedera network of this node
+func (nm *PackageManager) JobQueueTopicID() string {
+
+	return nm.JobQueueSettings.TopicID(hedera.Manager.NetworkName())
+
+}
+
+// Get the start time of the job queue subscription
+func (nm *PackageManager) JobQueueStartTime() (time.Time, error) {
+
+	return nm.JobQueueSettings.StartTime()
+
+}
+
+// LAST PROCESSED MESSAGE
+// #############################################################################
+// helper function to get the path of the file with the last processed message
+func _jobQueueStatePath() string {
+	return filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_TOPICS, "jobqueue.json")
+}
+
+// helper function to load the last processed message of the job queue topic
+func (nm *PackageManager) _loadJobQueueState() error {
+
+	data, err := os.ReadFile(_jobQueueStatePath())
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// lock the state
+	nm.jobQueue.mutex.Lock()
+	defer nm.jobQueue.mutex.Unlock()
+
+	err = json.Unmarshal(data, &nm.jobQueue)
+	if err != nil {
+		return err
+	}
+
+	// the messages processed before the start are replayed with "last"
+	if nm.JobQueueSettings.Start == JOB_QUEUE_START_LAST {
+		nm.jobQueue.replayUntil = nm.jobQueue.LastConsensusTimestamp
+	}
+
+	return nil
+
+}
+
+// helper function to check if a message of the job queue topic was processed
+// before the start (i.e., it only rebuilds the state of the node)
+func (nm *PackageManager) _isReplayedJobQueueMessage(timestamp time.Time) bool {
+
+	// lock the state
+	nm.jobQueue.mutex.Lock()
+	defer nm.jobQueue.mutex.Unlock()
+
+	return !nm.jobQueue.replayUntil.IsZero() && !timestamp.After(nm.jobQueue.replayUntil)
+
+}
+
+// helper function to store the consensus timestamp of a processed message of the job queue topic
+func (nm *PackageManager) _recordJobQueueMessage(timestamp time.Time) {
+
+	// lock the state
+	nm.jobQueue.mutex.Lock()
+	defer nm.jobQueue.mutex.Unlock()
+
+	// messages may be received again after a reconnect
+	if !timestamp.After(nm.jobQueue.LastConsensusTimestamp) {
+		return
+	}
+	nm.jobQueue.LastConsensusTimestamp = timestamp
+	nm.jobQueue.unsaved = true
+
+	// the state is not written for every message
+	if time.Since(nm.jobQueue.savedTimestamp) < RENDERHIVE_CONFIG_JOB_QUEUE_STATE_INTERVAL {
+		return
+	}
+	nm._writeJobQueueState()
+
+}
+
+// helper function to store the last processed message of the job queue topic,
+// if it was not written yet (e.g., on shutdown)
+func (nm *PackageManager) _saveJobQueueState() {
+
+	// lock the state
+	nm.jobQueue.mutex.Lock()
+	defer nm.jobQueue.mutex.Unlock()
+
+	if nm.jobQueue.unsaved {
+		nm._writeJobQueueState()
+	}
+
+}
+
+// helper function to write the last processed message of the job queue topic
+// NOTE: The caller must hold the mutex of the state.
+func (nm *PackageManager) _writeJobQueueState() {
+
+	data, err := json.Marshal(&nm.jobQueue)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(_jobQueueStatePath()), 0700)
+	}
+	if err == nil {
+		err = os.WriteFile(_jobQueueStatePath()+".tmp", data, 0600)
+	}
+	if err == nil {
+		err = os.Rename(_jobQueueStatePath()+".tmp", _jobQueueStatePath())
+	}
+	if err != nil {
+		logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not store the last processed message of the job queue: %v", err))
+		return
+	}
+	nm.jobQueue.savedTimestamp = time.Now()
+	nm.jobQueue.unsaved = false
+
+}
@@ -0,0 +1,113 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	// internal
+	"renderhive/logger"
+)
+
+func TestJobQueueStateIsNotWrittenPerMessage(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	nm := &PackageManager{}
+
+	// the first message is written, the following messages are not
+	first := time.Unix(1700000000, 0)
+	nm._recordJobQueueMessage(first)
+	for i := 1; i <= 100; i++ {
+		nm._recordJobQueueMessage(first.Add(time.Duration(i) * time.Second))
+	}
+	if stored := _readTestJobQueueState(t); !stored.Equal(first) {
+		t.Fatalf("stored %v, want the first message %v", stored, first)
+	}
+
+	// the last message is written on shutdown
+	nm._saveJobQueueState()
+	if stored := _readTestJobQueueState(t); !stored.Equal(first.Add(100 * time.Second)) {
+		t.Fatalf("stored %v, want the last message", stored)
+	}
+}
+
+func TestJobQueueStartLastReplaysTheProcessedMessages(t *testing.T) {
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// a node processed messages up to the last timestamp before the restart
+	last := time.Unix(1700000000, 0)
+	nm := &PackageManager{}
+	nm._recordJobQueueMessage(last)
+
+	// after the restart, the subscription starts at the beginning
+	restarted := &PackageManager{JobQueueSettings: JobQueueSettings{Start: JOB_QUEUE_START_LAST}}
+	if err := restarted._loadJobQueueState(); err != nil {
+		t.Fatal(err)
+	}
+	start, err := restarted.JobQueueStartTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !start.Equal(time.Unix(0, 0)) {
+		t.Fatalf("got start time %v, want the beginning", start)
+	}
+
+	// the processed messages are replayed, the new messages are processed
+	if !restarted._isReplayedJobQueueMessage(last.Add(-time.Hour)) || !restarted._isReplayedJobQueueMessage(last) {
+		t.Fatal("a processed message is not replayed")
+	}
+	if restarted._isReplayedJobQueueMessage(last.Add(time.Second)) {
+		t.Fatal("a new message is replayed")
+	}
+
+	// with the other start times, no message is replayed
+	beginning := &PackageManager{JobQueueSettings: JobQueueSettings{Start: JOB_QUEUE_START_BEGINNING}}
+	if err := beginning._loadJobQueueState(); err != nil {
+		t.Fatal(err)
+	}
+	if beginning._isReplayedJobQueueMessage(last) {
+		t.Fatal("a message is replayed without 'last'")
+	}
+}
+
+// helper function to read the stored timestamp of the last processed message
+func _readTestJobQueueState(t *testing.T) time.Time {
+	t.Helper()
+
+	data, err := os.ReadFile(_jobQueueStatePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state struct {
+		LastConsensusTimestamp time.Time `json:"last_consensus_timestamp"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+
+	return state.LastConsensusTimestamp
+}
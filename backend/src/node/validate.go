@@ -0,0 +1,267 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the dry-run validation of the Blender files of render jobs.
+Before this node claims a render job, a headless instance of the requested
+Blender version opens the Blender file and executes a vetted internal python
+script, which reports if the file was loaded and what it is missing, and exits
+without rendering. A job is not claimed, if its file:
+
+  - has no Blender file header (the file is not passed to Blender at all),
+  - cannot be opened by the Blender version (e.g., a corrupt file),
+  - was saved with a newer major version of Blender,
+  - links libraries that do not exist, or
+  - has no camera in its active scene.
+
+Missing images and files saved with a newer minor version of Blender are only
+reported as warnings. The file of a render request is validated once and the
+result is kept with the request.
+
+*/
+
+import (
+
+	// standard
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/ipfs"
+	"renderhive/logger"
+	. "renderhive/utility"
+)
+
+// python script to validate a Blender file without rendering it
+//
+//go:embed scripts/validate_blend_file.py
+var validateBlendFileScript []byte
+
+// prefix of the output line, which contains the validation report
+const validateBlendFilePrefix = "RENDERHIVE_VALIDATION:"
+
+// Result of the validation of a Blender file
+type BlendFileValidation struct {
+	Version    string   // Blender version the file was validated with
+	Renderable bool     // True, if the file can be rendered with the Blender version
+	Warnings   []string // Problems of the file (including the reasons, if it cannot be rendered)
+}
+
+// JSON output of the validation script
+type blendFileValidationJSON struct {
+	Loaded           bool     `json:"loaded"`
+	BlenderVersion   string   `json:"blender_version"`
+	FileVersion      string   `json:"file_version"`
+	MissingLibraries []string `json:"missing_libraries"`
+	MissingImages    []string `json:"missing_images"`
+	Scene            string   `json:"scene"`
+	Camera           bool     `json:"camera"`
+}
+
+// BLENDER FILE VALIDATION
+// #############################################################################
+// Check if a Blender file opens and can be rendered with a Blender version of this node
+// NOTE: Returns an error, if the check could not run. A file that Blender
+// cannot open is not renderable.
+func (nm *PackageManager) ValidateBlendFile(path string, version string) (bool, []string, error) {
+	var err error
+	var report blendFileValidationJSON
+
+	// get the Blender version of this node
+	blender, ok := nm.GetBlender(version)
+	if !ok {
+		return false, nil, newRenderError(ErrUnsupportedVersion, "Blender v'%v' is not available on this node for validating the Blender file.", version)
+	}
+	if _, err = os.Stat(blender.Path); err != nil {
+		return false, nil, err
+	}
+	if _, err = os.Stat(path); err != nil {
+		return false, nil, err
+	}
+
+	// files that are no Blender files are not passed to Blender
+	if err = _checkBlendFileHeader(path); err != nil {
+		return false, []string{fmt.Sprintf("The file is not a valid Blender file: %v", err)}, nil
+	}
+
+	// log event
+	logger.Manager.Package["node"].Trace().Msg(fmt.Sprintf("Validating Blender file '%v' with Blender v%v ...", path, version))
+
+	// open the file and execute the validation script
+	// NOTE: Blender exits with an error, if it cannot open the file.
+	output, err := blender._executeScript(path, validateBlendFileScript, BLENDER_SCRIPT_VALIDATE_BLEND_FILE_CID, validateBlendFilePrefix, path)
+	if err != nil {
+		return false, []string{fmt.Sprintf("Blender v%v could not open the file: %v", version, err)}, nil
+	}
+	err = json.Unmarshal(output, &report)
+	if err != nil {
+		return false, nil, fmt.Errorf("Could not read the validation report: %w", err)
+	}
+
+	renderable, warnings := _checkValidationReport(report)
+
+	// log event
+	for _, warning := range warnings {
+		logger.Manager.Package["node"].Debug().Msg(fmt.Sprintf(" [#] %v", warning))
+	}
+
+	return renderable, warnings, nil
+
+}
+
+// Validate the Blender file of a render job before it is claimed
+// NOTE: The Blender file is downloaded from IPFS, if it is not available on
+// this node. The result is kept with the render request.
+func (nm *PackageManager) ValidateRenderJob(job *RenderJob) (*BlendFileValidation, error) {
+	var err error
+
+	request := job.Request
+	if request.Validation != nil && request.Validation.Version == request.Version {
+		return request.Validation, nil
+	}
+	if request.Version == "" {
+		return nil, newRenderError(ErrUnsupportedVersion, "The Blender version of render request '%v' is not known.", request.DocumentCID)
+	}
+
+	// get the Blender file of the render request
+	err = request._fetchBlenderFile()
+	if err != nil {
+		return nil, err
+	}
+
+	// validate the file
+	renderable, warnings, err := nm.ValidateBlendFile(request.BlenderFile.Path, request.Version)
+	if err != nil {
+		return nil, err
+	}
+	request.Validation = &BlendFileValidation{Version: request.Version, Renderable: renderable, Warnings: warnings}
+
+	return request.Validation, nil
+
+}
+
+// helper function to download the Blender file of a render request of the render hive
+// NOTE: The file is only downloaded, if it is not available on this node.
+func (request *RenderRequest) _fetchBlenderFile() error {
+	var err error
+
+	if request.BlenderFile.Path != "" {
+		return nil
+	}
+	if request.BlenderFile.CID == "" {
+		return newRenderError(ErrInvalidArgument, "Render request '%v' has no Blender file.", request.DocumentCID)
+	}
+
+	path := filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_NETWORK_REQUESTS, request.BlenderFile.CID+".blend")
+	if _, err = os.Stat(path); os.IsNotExist(err) {
+		err = os.MkdirAll(filepath.Dir(path), 0700)
+		if err != nil {
+			return err
+		}
+		_, err = ipfs.Manager.GetObject(request.BlenderFile.CID, path)
+		if err != nil {
+			return err
+		}
+	}
+	request.BlenderFile.Path = path
+
+	return nil
+
+}
+
+// helper function to check the file header of a Blender file
+// NOTE: Compressed Blender files are only checked for the magic bytes of their
+// compression format. Blender itself checks the rest of the file.
+func _checkBlendFileHeader(path string) error {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// read the header ("BLENDER", pointer size, endianness, and version)
+	header := make([]byte, 12)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}), bytes.HasPrefix(header, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return nil
+	case bytes.HasPrefix(header, []byte("BLENDER")):
+		if n < len("BLENDER-v401") {
+			return errors.New("The file header is truncated.")
+		}
+		return nil
+	}
+
+	return errors.New("The file has no Blender file header.")
+
+}
+
+// helper function to check the validation report of a Blender file
+func _checkValidationReport(report blendFileValidationJSON) (bool, []string) {
+
+	renderable, warnings := true, []string{}
+
+	// the file could not be opened
+	if !report.Loaded {
+		return false, []string{fmt.Sprintf("Blender v%v could not open the file.", report.BlenderVersion)}
+	}
+
+	// the file was saved with a newer version
+	if CompareBlenderVersions(report.FileVersion, report.BlenderVersion) > 0 {
+		fileMajor, blenderMajor := strings.SplitN(report.FileVersion, ".", 2)[0], strings.SplitN(report.BlenderVersion, ".", 2)[0]
+		if CompareBlenderVersions(fileMajor, blenderMajor) > 0 {
+			renderable = false
+		}
+		warnings = append(warnings, fmt.Sprintf("The file was saved with the newer Blender v%v and may not open correctly in Blender v%v.", report.FileVersion, report.BlenderVersion))
+	}
+
+	// missing data
+	if len(report.MissingLibraries) > 0 {
+		renderable = false
+		warnings = append(warnings, fmt.Sprintf("The file links missing libraries: %v", strings.Join(report.MissingLibraries, ", ")))
+	}
+	if len(report.MissingImages) > 0 {
+		warnings = append(warnings, fmt.Sprintf("The file uses missing images: %v", strings.Join(report.MissingImages, ", ")))
+	}
+	if !report.Camera {
+		renderable = false
+		warnings = append(warnings, fmt.Sprintf("The active scene '%v' has no camera.", report.Scene))
+	}
+
+	return renderable, warnings
+
+}
@@ -0,0 +1,171 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fake Blender binary, which opens the Blender file like Blender does: a file
+// without the end block ("ENDB") cannot be read and the file version is taken
+// from the file header
+const testValidateBlender = `#!/bin/sh
+dir="$(dirname "$0")"
+touch "$dir/executed"
+if ! grep -q ENDB "$4"; then
+	echo "Error: Failed to read blend file '$4': File incomplete"
+	exit 1
+fi
+v="$(head -c 12 "$4" | tail -c 3)"
+echo "RENDERHIVE_VALIDATION:{\"loaded\":true,\"blender_version\":\"4.1.0\",\"file_version\":\"${v%??}.$(expr "$v" : '.\(..\)' + 0).0\",\"scene\":\"Scene\",\"camera\":true}"
+`
+
+// helper function to create a node with the fake Blender version and a file
+func _testValidateBlendFile(t *testing.T, content []byte) (*PackageManager, string, string) {
+	t.Helper()
+	nm, directory := _testRenderCIDManager(t)
+	_testMockIPFS(t, nil)
+	if err := os.WriteFile(filepath.Join(directory, "blender"), []byte(testValidateBlender), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "scene.blend")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return nm, path, filepath.Join(directory, "executed")
+}
+
+func TestValidateBlendFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    []byte
+		renderable bool
+		executed   bool
+		warning    string
+	}{
+		{"valid header", []byte("BLENDER-v401REND....ENDB"), true, true, ""},
+		{"newer major version", []byte("BLENDER-v501REND....ENDB"), false, true, "newer Blender v5.1.0"},
+		{"truncated header", []byte("BLENDER-v4"), false, false, "header is truncated"},
+		{"truncated file", []byte("BLENDER-v401REND...."), false, true, "could not open the file"},
+		{"empty file", []byte{}, false, false, "no Blender file header"},
+		{"non-blend file", []byte("\x89PNG\r\n\x1a\n....IEND"), false, false, "no Blender file header"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nm, path, executed := _testValidateBlendFile(t, test.content)
+
+			renderable, warnings, err := nm.ValidateBlendFile(path, "4.1.0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if renderable != test.renderable {
+				t.Errorf("got renderable %v, want %v (warnings: %v)", renderable, test.renderable, warnings)
+			}
+			if test.warning == "" && len(warnings) != 0 {
+				t.Errorf("unexpected warnings: %v", warnings)
+			}
+			if test.warning != "" && (len(warnings) != 1 || !strings.Contains(warnings[0], test.warning)) {
+				t.Errorf("expected a warning containing %q, got %v", test.warning, warnings)
+			}
+
+			// files without a valid header are not passed to Blender
+			if _, err := os.Stat(executed); (err == nil) != test.executed {
+				t.Errorf("got Blender executed %v, want %v", err == nil, test.executed)
+			}
+		})
+	}
+}
+
+func TestValidateBlendFileRejectsInvalidInput(t *testing.T) {
+	nm, path, _ := _testValidateBlendFile(t, []byte("BLENDER-v401REND....ENDB"))
+
+	if _, _, err := nm.ValidateBlendFile(path, "9.9.9"); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected an unknown Blender version to be rejected, got %v", err)
+	}
+	if _, _, err := nm.ValidateBlendFile(filepath.Join(t.TempDir(), "missing.blend"), "4.1.0"); !os.IsNotExist(err) {
+		t.Errorf("expected an error for a missing file, got %v", err)
+	}
+}
+
+func TestCheckBlendFileHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		valid   bool
+	}{
+		{"64-bit little-endian", []byte("BLENDER-v401REND"), true},
+		{"32-bit big-endian", []byte("BLENDER_V279REND"), true},
+		{"gzip compressed", []byte("\x1f\x8b\x08\x00...."), true},
+		{"zstd compressed", []byte("\x28\xb5\x2f\xfd...."), true},
+		{"truncated header", []byte("BLENDER-v"), false},
+		{"magic only", []byte("BLENDER"), false},
+		{"text file", []byte("not a Blender file"), false},
+		{"empty file", []byte{}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "scene.blend")
+			if err := os.WriteFile(path, test.content, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := _checkBlendFileHeader(path); (err == nil) != test.valid {
+				t.Errorf("got error %v, want valid %v", err, test.valid)
+			}
+		})
+	}
+}
+
+func TestCheckValidationReport(t *testing.T) {
+	valid := blendFileValidationJSON{Loaded: true, BlenderVersion: "4.1.0", FileVersion: "4.1.0", Scene: "Scene", Camera: true}
+
+	tests := []struct {
+		name       string
+		change     func(report *blendFileValidationJSON)
+		renderable bool
+		warnings   int
+	}{
+		{"valid file", func(report *blendFileValidationJSON) {}, true, 0},
+		{"not loaded", func(report *blendFileValidationJSON) { report.Loaded = false }, false, 1},
+		{"older version", func(report *blendFileValidationJSON) { report.FileVersion = "3.6.0" }, true, 0},
+		{"newer minor version", func(report *blendFileValidationJSON) { report.FileVersion = "4.2.0" }, true, 1},
+		{"newer major version", func(report *blendFileValidationJSON) { report.FileVersion = "5.0.0" }, false, 1},
+		{"missing libraries", func(report *blendFileValidationJSON) { report.MissingLibraries = []string{"//lib.blend"} }, false, 1},
+		{"missing images", func(report *blendFileValidationJSON) { report.MissingImages = []string{"//texture.png"} }, true, 1},
+		{"no camera", func(report *blendFileValidationJSON) { report.Camera = false }, false, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report := valid
+			test.change(&report)
+			renderable, warnings := _checkValidationReport(report)
+			if renderable != test.renderable || len(warnings) != test.warnings {
+				t.Errorf("got renderable %v with warnings %v, want %v with %v warnings", renderable, warnings, test.renderable, test.warnings)
+			}
+		})
+	}
+}
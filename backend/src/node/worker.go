@@ -0,0 +1,202 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the render worker of this node. The worker runs in the
+background of the service app and claims the next render job of the render
+hive queue, whenever the node is idle (see scheduling.go). It downloads the
+Blender file of the job, renders the frame range of the job with the Blender
+version of the matching render offer (restarting Blender after crashes, see
+crashes.go), collects the rendered frames into a render result, and announces
+the result to the network.
+
+A job, which cannot be rendered, is released to the network. On shutdown, the
+job in progress is released as well, so another node can render it.
+
+*/
+
+import (
+
+	// standard
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/logger"
+)
+
+// stages of the render worker, which can be replaced (e.g., in tests)
+// NOTE: A nil stage is the default stage of the node.
+type renderWorkerStages struct {
+	collect func(job *RenderJob) (*RenderResult, error)      // collects the rendered frames of the job
+	submit  func(job *RenderJob, result *RenderResult) error // announces the render result of the job
+}
+
+// RENDER WORKER
+// #############################################################################
+// Claim and render the render jobs of the render hive queue until the context is done
+func (nm *PackageManager) RunRenderWorker(ctx context.Context) {
+
+	// a requester-only node does not render
+	if nm.IsRequesterOnly() {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(RENDERHIVE_CONFIG_RENDER_WORKER_INTERVAL):
+		}
+
+		_, err := nm.RenderNextJob(ctx)
+		if err != nil {
+			logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Error in render worker: %v", err))
+		}
+	}
+
+}
+
+// Claim the next render job of the render hive queue and render it on this node
+// NOTE: Returns nil, if the node is busy or there is no job it can render.
+func (nm *PackageManager) RenderNextJob(ctx context.Context) (*RenderJob, error) {
+	var err error
+
+	// claim the next job, if the node is idle
+	nm.Renderer.Mutex.Lock()
+	var job *RenderJob
+	if !nm.Renderer.Busy {
+		job = nm.ClaimNextRenderJob()
+	}
+	nm.Renderer.Mutex.Unlock()
+	if job == nil {
+		return nil, nil
+	}
+
+	// release the job, if the node is stopped while it is rendered
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			nm._releaseWorkerJob(job, "shutdown")
+		case <-done:
+		}
+	}()
+
+	// render the job
+	err = nm._renderWorkerJob(job)
+	if err != nil {
+		if job._state() == RENDER_JOB_STATE_CLAIMED {
+			nm._releaseWorkerJob(job, "render failed")
+		}
+		return job, fmt.Errorf("Could not render render job '%v': %w", job.Request.DocumentCID, err)
+	}
+	if job._state() != RENDER_JOB_STATE_RENDERING {
+		return job, nil
+	}
+
+	// collect the rendered frames
+	collect := nm.workerStages.collect
+	if collect == nil {
+		collect = func(job *RenderJob) (*RenderResult, error) {
+			result, _, err := nm.CollectRenderResult(job)
+			return result, err
+		}
+	}
+	result, err := collect(job)
+	if err != nil {
+		nm._releaseWorkerJob(job, "collecting failed")
+		return job, fmt.Errorf("Could not collect the render result of render job '%v': %w", job.Request.DocumentCID, err)
+	}
+
+	// announce the render result
+	submit := nm.workerStages.submit
+	if submit == nil {
+		submit = nm.SubmitRenderResult
+	}
+	nm.Renderer.Mutex.Lock()
+	err = submit(job, result)
+	nm.Renderer.Mutex.Unlock()
+	if err != nil {
+		return job, fmt.Errorf("Could not submit the render result of render job '%v': %w", job.Request.DocumentCID, err)
+	}
+
+	return job, nil
+
+}
+
+// helper function to render a claimed render job with the Blender version of its render offer
+func (nm *PackageManager) _renderWorkerJob(job *RenderJob) error {
+	var err error
+
+	// get the Blender version of the render offer
+	if job.Offer == nil {
+		return newRenderError(ErrOfferNotFound, "The render job has no render offer.")
+	}
+	blender, ok := job.Offer.Blender[job.Request.Version]
+	if !ok {
+		return newRenderError(ErrUnsupportedVersion, "Render offer '%v' does not support Blender v%v.", _activeOfferName(job.Offer), job.Request.Version)
+	}
+	nm.Renderer.Mutex.Lock()
+	job.Blender = &blender
+	nm.Renderer.Mutex.Unlock()
+
+	// get the Blender file of the render request
+	err = job.Request._fetchBlenderFile()
+	if err != nil {
+		return err
+	}
+
+	// render the frame range of the job into its output directory
+	output := filepath.Join(job.OutputDirectory(), "frame_####")
+	frames := job.FrameSettings()
+
+	return nm.RenderJobWithRestarts(job, func(settings RenderSettings) ([]string, error) {
+		settings.FrameStart, settings.FrameEnd, settings.FrameStep = frames.FrameStart, frames.FrameEnd, frames.FrameStep
+		return BlenderRenderArguments(job.Request.BlenderFile.Path, output, settings)
+	})
+
+}
+
+// helper function to release a render job of the render worker
+func (nm *PackageManager) _releaseWorkerJob(job *RenderJob, reason string) {
+
+	nm.Renderer.Mutex.Lock()
+	if state := job._state(); state != RENDER_JOB_STATE_CLAIMED && state != RENDER_JOB_STATE_RENDERING {
+		nm.Renderer.Mutex.Unlock()
+		return
+	}
+	unpin := nm._releaseRenderJob(job, reason)
+	nm.Renderer.Mutex.Unlock()
+
+	// notify the network without the lock of the render data
+	err := nm._announceRenderJobRelease(job, reason, unpin)
+	if err != nil {
+		logger.Manager.Package["node"].Error().Msg(fmt.Sprintf("Could not release render job '%v': %v", job.Request.DocumentCID, err))
+	}
+
+}
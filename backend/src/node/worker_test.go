@@ -0,0 +1,175 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	// internal
+	"renderhive/logger"
+)
+
+// fake Blender binary, which records its arguments and writes the first frame
+const testFakeBlender = `#!/bin/sh
+echo "$@" > "$(dirname "$0")/args.txt"
+while [ $# -gt 0 ]; do
+	if [ "$1" = "-o" ]; then out="$2"; fi
+	shift
+done
+mkdir -p "$(dirname "$out")" && touch "$(dirname "$out")/frame_0001.png"
+`
+
+// helper function to run a test in a temporary working directory, which holds
+// the configuration directory
+func _chdirTemp(t *testing.T) string {
+	dir := t.TempDir()
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+
+	return dir
+}
+
+// helper function to create a render node with a queued render job
+func _testWorkerManager(t *testing.T) (*PackageManager, *RenderJob) {
+	t.Helper()
+	logger.Manager.Init()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	_chdirTemp(t)
+
+	// create the fake Blender binary and the Blender file
+	directory := t.TempDir()
+	blender := filepath.Join(directory, "blender")
+	if err := os.WriteFile(blender, []byte(testFakeBlender), 0755); err != nil {
+		t.Fatal(err)
+	}
+	blendFile := filepath.Join(directory, "scene.blend")
+	if err := os.WriteFile(blendFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// a render offer of this node for Blender v4.1.0
+	offer := &RenderOffer{
+		DocumentCID:     "offer",
+		BlenderVersions: []RenderOfferBlenderVersions{{Version: "4.1.0"}},
+		Blender:         map[string]BlenderAppData{"4.1.0": {Path: blender}},
+	}
+
+	// a render job of the render hive queue
+	request := &RenderRequest{DocumentCID: testOfferCID, Version: "4.1.0"}
+	request.BlenderFile.Path = blendFile
+	request.BlenderFile.Settings = RenderSettings{FrameStart: 1, FrameEnd: 1, FrameStep: 1}
+	request.Validation = &BlendFileValidation{Version: "4.1.0", Renderable: true}
+	job := &RenderJob{Request: request, State: RENDER_JOB_STATE_QUEUED}
+
+	nm := &PackageManager{}
+	nm.Renderer.ActiveOffers = []*RenderOffer{offer}
+	nm.NetworkQueue = []*RenderJob{job}
+
+	return nm, job
+}
+
+func TestRenderNextJob(t *testing.T) {
+	nm, job := _testWorkerManager(t)
+
+	var submitted *RenderResult
+	nm.workerStages.collect = func(job *RenderJob) (*RenderResult, error) {
+		return &RenderResult{ResultCID: "result"}, nil
+	}
+	nm.workerStages.submit = func(job *RenderJob, result *RenderResult) error {
+		submitted = result
+		job.State = RENDER_JOB_STATE_COMPLETED
+		nm.Renderer.Busy = false
+		return nil
+	}
+
+	rendered, err := nm.RenderNextJob(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the queued job was claimed
+	if rendered != job || len(nm.Renderer.NodeQueue) != 1 || nm.Renderer.NodeQueue[0] != job {
+		t.Fatal("the queued render job was not claimed")
+	}
+	if job.Offer == nil || job.Offer.DocumentCID != "offer" {
+		t.Error("the render job must be rendered for the matching render offer")
+	}
+
+	// the job was rendered with the Blender version of the offer
+	args, err := os.ReadFile(filepath.Join(filepath.Dir(job.Offer.Blender["4.1.0"].Path), "args.txt"))
+	if err != nil {
+		t.Fatalf("Blender was not started: %v", err)
+	}
+	if !strings.Contains(string(args), job.Request.BlenderFile.Path) || !strings.Contains(string(args), job.OutputDirectory()) {
+		t.Errorf("unexpected Blender arguments: %s", args)
+	}
+	if len(job.RenderAttempts) != 1 || job.RenderAttempts[0].Error != "" {
+		t.Errorf("got %+v, want one successful render attempt", job.RenderAttempts)
+	}
+	if _, err := os.Stat(filepath.Join(job.OutputDirectory(), "frame_0001.png")); err != nil {
+		t.Errorf("the frame was not rendered into the output directory: %v", err)
+	}
+
+	// the render result was submitted
+	if submitted == nil || submitted.ResultCID != "result" {
+		t.Fatal("the render result was not submitted")
+	}
+	if job.State != RENDER_JOB_STATE_COMPLETED || nm.Renderer.Busy {
+		t.Errorf("got state %v (busy: %v), want a completed job on an idle node", job.State, nm.Renderer.Busy)
+	}
+}
+
+func TestRenderNextJobWaitsWhileBusy(t *testing.T) {
+	nm, job := _testWorkerManager(t)
+	nm.Renderer.Busy = true
+
+	rendered, err := nm.RenderNextJob(context.Background())
+	if err != nil || rendered != nil {
+		t.Fatalf("got %v, %v, want no job on a busy node", rendered, err)
+	}
+	if job.State != RENDER_JOB_STATE_QUEUED || len(nm.Renderer.NodeQueue) != 0 {
+		t.Error("a busy node must not claim a render job")
+	}
+}
+
+func TestRunRenderWorkerRequesterOnly(t *testing.T) {
+	nm, job := _testWorkerManager(t)
+	nm.RequesterOnly = true
+
+	// returns immediately, although the context is never done
+	nm.RunRenderWorker(context.Background())
+	if job.State != RENDER_JOB_STATE_QUEUED {
+		t.Error("a requester-only node must not claim a render job")
+	}
+}
@@ -0,0 +1,333 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+/*
+
+This file contains the work proof of a render job.
+
+When a render job is collected, the node creates a work proof document, which
+proves the work for the claim and settlement of the render job in the smart
+contract: the rendered frames with their CIDs, the render timing, a summary of
+the Blender render status, the device info, and the benchmark the render offer
+referenced. The work proof is added to IPFS and its CID and job root are part
+of the render result.
+
+The job root is the Merkle root, which is passed as 'jobRoot' to the
+'claimRenderJob' function of the smart contract. A verifier reconstructs it
+from the work proof (or from the result document) as follows:
+
+  (1) The leaves are UTF-8 strings in this order:
+
+        "renderhive-workproof:v1:<render request CID>:<subtask>:<operator>"
+        "frame:<frame number>:<frame CID>"   (one per frame, by frame number)
+
+      where <subtask> is the decimal subtask number (0 = the whole request)
+      and <operator> the account ID of the operator (e.g., "0.0.1234").
+
+  (2) The hash of a leaf is SHA-256(0x00 || leaf) and the hash of an inner
+      node is SHA-256(0x01 || left || right).
+
+  (3) The hashes of a level are paired from left to right. An unpaired last
+      hash is moved to the next level unchanged. The root is the single hash
+      of the last level (hex encoded with a "0x" prefix).
+
+Only the leaves are part of the job root. The other data of the work proof is
+bound to it by the CID of the work proof document.
+
+*/
+
+import (
+
+	// standard
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	// internal
+	. "renderhive/globals"
+	"renderhive/ipfs"
+	. "renderhive/utility"
+)
+
+// version of the job root construction
+const WORK_PROOF_ROOT_VERSION = "v1"
+
+// Work proof document of a render job
+type WorkProof struct {
+	SchemaVersion     int                 // Schema version of the work proof document
+	RenderRequestCID  string              // CID of the rendered render request document
+	Subtask           int                 // Number of the rendered subtask (0 = the whole request)
+	OperatorAccountID string              // Account ID of the operator who rendered the job
+	RenderOfferCID    string              // CID of the render offer the job was rendered for (if any)
+	ResultCID         string              // CID of the render result directory
+	ClaimedTimestamp  time.Time           // The datetime the job was claimed
+	CreatedTimestamp  time.Time           // The datetime the work proof was created (after the rendering)
+	Render            WorkProofRender     // Summary of the Blender render status
+	Device            WorkProofDevice     // Device info of the render node
+	Benchmark         *WorkProofBenchmark `json:",omitempty"` // Benchmark of the render offer (nil, if not benchmarked)
+	Frames            []RenderResultFrame // The rendered frames (by frame number)
+	MissingFrames     []int               `json:",omitempty"` // Frames of the frame range, which were not rendered
+	JobRoot           string              // Merkle root of the work proof ("0x" + hex)
+}
+
+// Summary of the Blender render status of a work proof
+type WorkProofRender struct {
+	LastFrame   string // Last frame reported by Blender
+	PeakMemory  string // Peak memory usage reported by Blender
+	RenderTime  string // Render time of the last frame reported by Blender
+	ExitCode    int    // Exit code of the Blender process
+	OutOfMemory bool   // True, if Blender ran out of memory
+	Note        string `json:",omitempty"` // Last render status note of Blender
+}
+
+// Device info of a work proof
+type WorkProofDevice struct {
+	BlenderVersion string   // Blender version the job was rendered with
+	BuildHash      string   // Build hash of the Blender app
+	Verified       bool     // True, if the build info of the Blender app was verified
+	Devices        []string // Devices of the Blender app
+	Threads        uint8    // Threads of the Blender app
+	GPUs           []string `json:",omitempty"` // GPUs assigned to the job (empty = all GPUs)
+	OS             string   // Operating system of the render node
+	Arch           string   // Architecture of the render node
+}
+
+// Benchmark reference of a work proof
+type WorkProofBenchmark struct {
+	BlenderVersion   string  // Blender version of the benchmark
+	SamplesPerMinute float64 // Benchmark throughput of the render offer
+}
+
+// WORK PROOF
+// #############################################################################
+// Create the work proof of a collected render job
+func NewWorkProof(job *RenderJob, result *RenderResult, document *RenderResultDocument) (*WorkProof, error) {
+	var err error
+
+	proof := &WorkProof{
+		SchemaVersion:     RENDERHIVE_DOCUMENT_SCHEMA_VERSION,
+		RenderRequestCID:  job.Request.DocumentCID,
+		Subtask:           job.SubtaskIndex(),
+		OperatorAccountID: result.OperatorAccountID,
+		ResultCID:         result.ResultCID,
+		ClaimedTimestamp:  job.ClaimedTimestamp,
+		CreatedTimestamp:  time.Now().UTC(),
+		Frames:            append([]RenderResultFrame(nil), document.Frames...),
+		MissingFrames:     document.MissingFrames,
+	}
+	sort.Slice(proof.Frames, func(i, j int) bool { return proof.Frames[i].Frame < proof.Frames[j].Frame })
+	if job.Offer != nil {
+		proof.RenderOfferCID = job.Offer.DocumentCID
+	}
+
+	// the device and the render status of the Blender app
+	proof.Device = WorkProofDevice{
+		BlenderVersion: job.Request.Version,
+		GPUs:           job.GPUs,
+		OS:             runtime.GOOS,
+		Arch:           runtime.GOARCH,
+	}
+	if job.Blender != nil {
+		if proof.Device.BlenderVersion == "" {
+			proof.Device.BlenderVersion = job.Blender.BuildVersion
+		}
+		proof.Device.BuildHash = job.Blender.BuildHash
+		proof.Device.Verified = job.Blender.Verified
+		proof.Device.Devices = job.Blender.Devices
+		proof.Device.Threads = job.Blender.Threads
+		proof.Render = WorkProofRender{
+			LastFrame:   job.Blender.Frame,
+			PeakMemory:  job.Blender.Peak,
+			RenderTime:  job.Blender.Time,
+			ExitCode:    job.Blender.ExitCode,
+			OutOfMemory: job.Blender.OutOfMemory,
+			Note:        job.Blender.Note,
+		}
+		if job.Blender.BenchmarkTool != nil {
+			if throughput, ok := _benchmarkThroughput(job.Blender.BenchmarkTool.GetResult()); ok {
+				proof.Benchmark = &WorkProofBenchmark{BlenderVersion: job.Request.Version, SamplesPerMinute: throughput}
+			}
+		}
+	}
+
+	proof.JobRoot, err = proof.ComputeJobRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	return proof, nil
+
+}
+
+// Get the leaves of the job root (see the description of this file)
+func (proof *WorkProof) Leaves() []string {
+
+	leaves := []string{fmt.Sprintf("renderhive-workproof:%v:%v:%v:%v", WORK_PROOF_ROOT_VERSION, proof.RenderRequestCID, proof.Subtask, proof.OperatorAccountID)}
+
+	frames := append([]RenderResultFrame(nil), proof.Frames...)
+	sort.SliceStable(frames, func(i, j int) bool { return frames[i].Frame < frames[j].Frame })
+	for _, frame := range frames {
+		leaves = append(leaves, fmt.Sprintf("frame:%v:%v", frame.Frame, frame.CID))
+	}
+
+	return leaves
+
+}
+
+// Compute the job root of the work proof ("0x" + hex)
+func (proof *WorkProof) ComputeJobRoot() (string, error) {
+
+	if proof.RenderRequestCID == "" || proof.OperatorAccountID == "" {
+		return "", newRenderError(ErrInvalidArgument, "The work proof needs a render request and an operator.")
+	}
+
+	return "0x" + hex.EncodeToString(WorkProofMerkleRoot(proof.Leaves())), nil
+
+}
+
+// Check if the job root matches the content of the work proof
+func (proof *WorkProof) Verify() error {
+
+	root, err := proof.ComputeJobRoot()
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(root, proof.JobRoot) {
+		return newRenderError(ErrDocumentMismatch, "The job root '%v' of the work proof does not match its content (expected: '%v').", proof.JobRoot, root)
+	}
+
+	return nil
+
+}
+
+// Get the Merkle root of the leaves (see the description of this file)
+func WorkProofMerkleRoot(leaves []string) []byte {
+
+	if len(leaves) == 0 {
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	}
+
+	// hash the leaves
+	level := make([][]byte, 0, len(leaves))
+	for _, leaf := range leaves {
+		sum := sha256.Sum256(append([]byte{0x00}, leaf...))
+		level = append(level, sum[:])
+	}
+
+	// hash the pairs of each level until the root is left
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			sum := sha256.Sum256(bytes.Join([][]byte{{0x01}, level[i], level[i+1]}, nil))
+			next = append(next, sum[:])
+		}
+		level = next
+	}
+
+	return level[0]
+
+}
+
+// Get the job root of the collected render result of a render request on this node
+// NOTE: Returns an error, if no render result was collected or if this node
+// collected several subtasks of the render request with different job roots.
+func (nm *PackageManager) RenderJobRoot(requestCID string) (string, error) {
+
+	// a requester-only node does not claim render jobs
+	err := nm._requireRenderNode("claim a render job")
+	if err != nil {
+		return "", err
+	}
+
+	root := ""
+	for _, job := range nm.Renderer.NodeQueue {
+		if job.Request == nil || !_sameCID(job.Request.DocumentCID, requestCID) || job.Result == nil || job.Result.JobRoot == "" {
+			continue
+		}
+		if root != "" && !strings.EqualFold(root, job.Result.JobRoot) {
+			return "", newRenderError(ErrInvalidArgument, "Render request '%v' has several collected subtasks with different job roots.", requestCID)
+		}
+		root = job.Result.JobRoot
+	}
+	if root == "" {
+		return "", newRenderError(ErrRequestNotFound, "No work proof was collected for render request '%v'.", requestCID)
+	}
+
+	return root, nil
+
+}
+
+// Write the work proof document to the given path and add it to IPFS
+func (proof *WorkProof) Deploy(path string) (string, error) {
+
+	data, err := json.MarshalIndent(proof, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	return ipfs.Manager.AddObjectFromPath(path, true)
+
+}
+
+// Get the local path of the work proof document of a render job
+func (job *RenderJob) WorkProofPath() string {
+
+	return filepath.Join(GetAppDataPath(), RENDERHIVE_APP_DIRECTORY_WORK_PROOFS, filepath.Base(job.OutputDirectory())+".json")
+
+}
+
+// Decode a work proof document and check its job root
+func DecodeWorkProof(data []byte) (*WorkProof, error) {
+
+	proof := &WorkProof{}
+	err := json.Unmarshal(data, proof)
+	if err != nil {
+		return nil, newRenderError(ErrInvalidArgument, "Work proof could not be decoded: %w", err)
+	}
+	if proof.SchemaVersion > RENDERHIVE_DOCUMENT_SCHEMA_VERSION {
+		return nil, newRenderError(ErrUnsupportedSchema, "Work proof has schema version %v (supported: %v).", proof.SchemaVersion, RENDERHIVE_DOCUMENT_SCHEMA_VERSION)
+	}
+
+	return proof, proof.Verify()
+
+}
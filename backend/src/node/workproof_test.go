@@ -0,0 +1,117 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package node
+
+import (
+
+	// standard
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// helper function to create a work proof of two frames
+func _testWorkProof() *WorkProof {
+	return &WorkProof{
+		RenderRequestCID:  testOfferCID,
+		Subtask:           2,
+		OperatorAccountID: "0.0.1001",
+		Frames: []RenderResultFrame{
+			{Frame: 2, CID: "frame-2"},
+			{Frame: 1, CID: "frame-1"},
+		},
+	}
+}
+
+func TestWorkProofJobRootIsDeterministic(t *testing.T) {
+	proof := _testWorkProof()
+	root, err := proof.ComputeJobRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the root is built from the header leaf and the frames by frame number
+	leaf := func(s string) []byte {
+		sum := sha256.Sum256(append([]byte{0x00}, s...))
+		return sum[:]
+	}
+	inner := func(left []byte, right []byte) []byte {
+		sum := sha256.Sum256(append(append([]byte{0x01}, left...), right...))
+		return sum[:]
+	}
+	header := leaf("renderhive-workproof:v1:" + testOfferCID + ":2:0.0.1001")
+	expected := inner(inner(header, leaf("frame:1:frame-1")), leaf("frame:2:frame-2"))
+	if root != "0x"+hex.EncodeToString(expected) {
+		t.Fatalf("got job root %v, want 0x%v", root, hex.EncodeToString(expected))
+	}
+
+	// the order of the frames in the document does not matter
+	reordered := _testWorkProof()
+	reordered.Frames[0], reordered.Frames[1] = reordered.Frames[1], reordered.Frames[0]
+	if other, _ := reordered.ComputeJobRoot(); other != root {
+		t.Errorf("got job root %v for reordered frames, want %v", other, root)
+	}
+
+	// another operator gets another root
+	reordered.OperatorAccountID = "0.0.1002"
+	if other, _ := reordered.ComputeJobRoot(); other == root {
+		t.Error("the job root must depend on the operator")
+	}
+}
+
+func TestWorkProofVerify(t *testing.T) {
+	proof := _testWorkProof()
+	proof.JobRoot, _ = proof.ComputeJobRoot()
+	if err := proof.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a changed frame CID does not match the job root
+	proof.Frames[0].CID = "tampered"
+	if err := proof.Verify(); !errors.Is(err, ErrDocumentMismatch) {
+		t.Errorf("got %v, want a document mismatch", err)
+	}
+}
+
+func TestRenderJobRoot(t *testing.T) {
+	nm := &PackageManager{}
+	request := &RenderRequest{DocumentCID: testOfferCID}
+
+	// no render result was collected yet
+	nm.Renderer.NodeQueue = []*RenderJob{{Request: request}}
+	if _, err := nm.RenderJobRoot(testOfferCID); !errors.Is(err, ErrRequestNotFound) {
+		t.Fatalf("got %v, want an error without a collected result", err)
+	}
+
+	// the job root of the collected render result is used for the claim
+	nm.Renderer.NodeQueue[0].Result = &RenderResult{JobRoot: "0xabc"}
+	root, err := nm.RenderJobRoot(testOfferCID)
+	if err != nil || root != "0xabc" {
+		t.Fatalf("got %v (%v), want 0xabc", root, err)
+	}
+
+	// subtasks with different job roots are ambiguous
+	nm.Renderer.NodeQueue = append(nm.Renderer.NodeQueue, &RenderJob{Request: request, Result: &RenderResult{JobRoot: "0xdef"}})
+	if _, err := nm.RenderJobRoot(testOfferCID); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("got %v, want an error for different job roots", err)
+	}
+}
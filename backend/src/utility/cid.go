@@ -0,0 +1,82 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package utility
+
+/*
+
+This file contains the validation and normalization of CIDs.
+
+The same IPFS object can be addressed by a CIDv0 ('Qm...') and a CIDv1
+('bafy...'). The local IPFS node creates CIDv0s, so CIDv1s that can be
+expressed as a CIDv0 (dag-pb objects with a SHA-256 hash) are converted to
+their CIDv0. Thus, the CID strings of the same object match, e.g., when they
+are used as keys of the render offers and requests.
+
+*/
+
+import (
+
+	// standard
+	"errors"
+	"fmt"
+	"strings"
+
+	// external
+	gocid "github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// CIDS
+// #############################################################################
+// Parse a CID string and normalize it to its CIDv0 (if possible)
+func ParseCID(cid string) (gocid.Cid, error) {
+
+	cid = strings.TrimSpace(cid)
+	if cid == "" {
+		return gocid.Undef, errors.New("No CID was given.")
+	}
+
+	parsed, err := gocid.Decode(cid)
+	if err != nil {
+		return gocid.Undef, errors.New(fmt.Sprintf("'%v' is not a valid CID: %v", cid, err))
+	}
+
+	// convert to a CIDv0, if the object can be addressed by one
+	prefix := parsed.Prefix()
+	if prefix.Version == 1 && prefix.Codec == gocid.DagProtobuf && prefix.MhType == mh.SHA2_256 && prefix.MhLength == 32 {
+		return gocid.NewCidV0(parsed.Hash()), nil
+	}
+
+	return parsed, nil
+
+}
+
+// Get the normalized string of a CID (see ParseCID)
+func NormalizeCID(cid string) (string, error) {
+
+	parsed, err := ParseCID(cid)
+	if err != nil {
+		return "", err
+	}
+
+	return parsed.String(), nil
+
+}
@@ -0,0 +1,73 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package utility
+
+import (
+	// standard
+	"testing"
+)
+
+func TestParseCID(t *testing.T) {
+	const v0 = "QmWWP6qFQ9y43KvWxY3sqtn2VR4s5RwGso7cUT2ZQHXo5t"
+	const raw = "bafkreidzlot4bs7cjmpz2fj54xzyzt3dixdlcttenfyhf4amlr2npxbxem"
+	tests := []struct {
+		cid        string
+		normalized string
+	}{
+		{v0, v0},
+		{" " + v0 + "\n", v0},
+		{"bafybeidzlot4bs7cjmpz2fj54xzyzt3dixdlcttenfyhf4amlr2npxbxem", v0}, // CIDv1 (dag-pb, base32) of the same object
+		{"zdj7WdbdUGKFdSev3EShH1ViGcb7dGJnyMQk7X99FREDj6hFp", v0},           // CIDv1 (dag-pb, base58)
+		{raw, raw}, // CIDv1 of a raw block cannot be expressed as a CIDv0
+	}
+	for _, test := range tests {
+		parsed, err := ParseCID(test.cid)
+		if err != nil {
+			t.Errorf("ParseCID(%q) = %v, want no error", test.cid, err)
+			continue
+		}
+		if parsed.String() != test.normalized {
+			t.Errorf("ParseCID(%q) = %v, want %v", test.cid, parsed, test.normalized)
+		}
+		if normalized, _ := NormalizeCID(test.cid); normalized != test.normalized {
+			t.Errorf("NormalizeCID(%q) = %v, want %v", test.cid, normalized, test.normalized)
+		}
+	}
+}
+
+func TestParseCIDRejectsMalformedInput(t *testing.T) {
+	for _, cid := range []string{
+		"",
+		"   ",
+		"not a cid",
+		"QmWWP6qFQ9y43KvWxY3sqtn2VR4s5RwGso7cUT2ZQHXo5",  // truncated CIDv0
+		"QmWWP6qFQ9y43KvWxY3sqtn2VR4s5RwGso7cUT2ZQHXo50", // invalid base58 character
+		"bafybeidzlot4bs7cjmpz2fj54xzyzt3dixdlcttenfyhf4aml",
+		"/ipfs/QmWWP6qFQ9y43KvWxY3sqtn2VR4s5RwGso7cUT2ZQHXo5t",
+	} {
+		if parsed, err := ParseCID(cid); err == nil {
+			t.Errorf("ParseCID(%q) = %v, want an error", cid, parsed)
+		}
+		if _, err := NormalizeCID(cid); err == nil {
+			t.Errorf("NormalizeCID(%q) returned no error", cid)
+		}
+	}
+}
@@ -29,11 +29,14 @@ This package contains utility/helper functions for the service app.
 import (
 
 	// standard
-	// "fmt"
+	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
 	// "time"
 	// "sync"
@@ -79,43 +82,60 @@ func GetAppDataPath() string {
 
 }
 
-// Query the public IPv4 address of this computer from an external service
+// Query the public IPv4 address of this computer from the external services
 func GetPublicIPv4() (string, error) {
 
-	// Make a GET request to an external service that returns the public IP address
-	resp, err := http.Get("https://api.ipify.org")
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
+	return _queryPublicIP(RENDERHIVE_CONFIG_PUBLIC_IPV4_SERVICES, false)
 
-	// Read the response body and print the IP address
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
+}
 
-	return string(body), nil
+// Query the public IPv6 address of this computer from the external services
+func GetPublicIPv6() (string, error) {
+
+	return _queryPublicIP(RENDERHIVE_CONFIG_PUBLIC_IPV6_SERVICES, true)
 
 }
 
-// Query the public IPv6 address of this computer from an external service
-func GetPublicIPv6() (string, error) {
+// helper function to query the public IP address from the first service that responds
+// NOTE: Responses that are no IP address of the requested family are skipped.
+func _queryPublicIP(services []string, ipv6 bool) (string, error) {
+	var errs []error
 
-	// Make a GET request to an external service that returns the public IP address
-	resp, err := http.Get("https://api64.ipify.org")
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
+	client := &http.Client{Timeout: RENDERHIVE_CONFIG_PUBLIC_IP_TIMEOUT}
+	for _, service := range services {
 
-	// Read the response body and print the IP address
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
+		// Make a GET request to an external service that returns the public IP address
+		resp, err := client.Get(service)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+		resp.Body.Close()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			errs = append(errs, fmt.Errorf("%v responded with status %v", service, resp.Status))
+			continue
+		}
+
+		// check the IP address
+		ip := net.ParseIP(strings.TrimSpace(string(body)))
+		if ip == nil || (ip.To4() == nil) != ipv6 {
+			errs = append(errs, fmt.Errorf("%v responded with no valid IP address", service))
+			continue
+		}
+
+		return ip.String(), nil
+
+	}
+	if len(errs) == 0 {
+		return "", errors.New("No service to query the public IP address")
 	}
 
-	return string(body), nil
+	return "", errors.Join(errs...)
 
 }
 
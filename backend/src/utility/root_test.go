@@ -0,0 +1,94 @@
+/*
+ * ************************** BEGIN LICENSE BLOCK ******************************
+ *
+ * Copyright © 2024 Christian Stolze
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * ************************** END LICENSE BLOCK ********************************
+ */
+
+package utility
+
+import (
+	// standard
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	// internal
+	. "renderhive/globals"
+)
+
+// helper function to start an IP lookup service responding with the body and status
+func _testIPService(t *testing.T, status int, body string) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+// helper function to replace the IP lookup services for a test
+func _setIPServices(t *testing.T, ipv4 []string, ipv6 []string) {
+	t.Helper()
+
+	defaultIPv4, defaultIPv6 := RENDERHIVE_CONFIG_PUBLIC_IPV4_SERVICES, RENDERHIVE_CONFIG_PUBLIC_IPV6_SERVICES
+	RENDERHIVE_CONFIG_PUBLIC_IPV4_SERVICES, RENDERHIVE_CONFIG_PUBLIC_IPV6_SERVICES = ipv4, ipv6
+	t.Cleanup(func() {
+		RENDERHIVE_CONFIG_PUBLIC_IPV4_SERVICES, RENDERHIVE_CONFIG_PUBLIC_IPV6_SERVICES = defaultIPv4, defaultIPv6
+	})
+}
+
+func TestGetPublicIPFallsBackToTheNextService(t *testing.T) {
+	unreachable := _testIPService(t, http.StatusOK, "")
+	_setIPServices(t, []string{
+		unreachable + "/404",
+		_testIPService(t, http.StatusServiceUnavailable, "203.0.113.1"),
+		_testIPService(t, http.StatusOK, "2001:db8::1"),
+		_testIPService(t, http.StatusOK, "no address"),
+		_testIPService(t, http.StatusOK, "203.0.113.7\n"),
+	}, []string{
+		_testIPService(t, http.StatusOK, "203.0.113.7"),
+		_testIPService(t, http.StatusOK, " 2001:db8::7 "),
+	})
+
+	// failed services and addresses of the other family are skipped
+	ipv4, err := GetPublicIPv4()
+	if err != nil || ipv4 != "203.0.113.7" {
+		t.Errorf("got IPv4 %q (%v), want 203.0.113.7", ipv4, err)
+	}
+	ipv6, err := GetPublicIPv6()
+	if err != nil || ipv6 != "2001:db8::7" {
+		t.Errorf("got IPv6 %q (%v), want 2001:db8::7", ipv6, err)
+	}
+}
+
+func TestGetPublicIPFailsWithoutAnyService(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	closed := server.URL
+	server.Close()
+	_setIPServices(t, []string{closed, _testIPService(t, http.StatusInternalServerError, "")}, nil)
+
+	if ipv4, err := GetPublicIPv4(); err == nil || ipv4 != "" {
+		t.Errorf("got IPv4 %q, want an error", ipv4)
+	}
+	if ipv6, err := GetPublicIPv6(); err == nil || ipv6 != "" {
+		t.Errorf("got IPv6 %q, want an error", ipv6)
+	}
+}